@@ -3,11 +3,13 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"github.com/hashicorp/go-hclog"
 	"github.com/spf13/cobra"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"scheduler0/pkg/certificates"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/constants/headers"
@@ -47,7 +49,17 @@ var credentialCmd = &cobra.Command{
 			logger.Fatalln(err)
 		}
 
+		// The node certificate is presented as the client certificate, nodes with PeerMTLS require one from the cmd
+		certs, err := certificates.NewReloaderFromConfig(hclog.New(&hclog.LoggerOptions{
+			Name:  "scheduler0-cmd",
+			Level: hclog.LevelFromString(configs.LogLevel),
+		}), config.NewScheduler0Config())
+		if err != nil {
+			logger.Fatalln(err)
+		}
+
 		client := &http.Client{
+			Transport: certs.Transport(),
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				req.Method = http.MethodPost
 				body := bytes.NewReader(data)
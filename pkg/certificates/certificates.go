@@ -0,0 +1,214 @@
+// Package certificates loads the TLS certificate of a node and the CA verifying its peers, and reloads
+// them when their files change, so certificates are renewed without restarting the node.
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"net"
+	"net/http"
+	"os"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"sync"
+	"time"
+)
+
+// Reloader serves the certificate and CA loaded from files, and reloads them once their files changed.
+// The files are checked at most once every check interval, on the handshakes using them.
+type Reloader struct {
+	logger        hclog.Logger
+	certFile      string
+	keyFile       string
+	caFile        string
+	checkInterval time.Duration
+
+	mtx         sync.Mutex
+	certificate *tls.Certificate
+	caPool      *x509.CertPool
+	modTimes    map[string]time.Time
+	lastCheck   time.Time
+}
+
+// NewReloader loads the certificate and key, and the CA when caFile is not empty
+func NewReloader(logger hclog.Logger, certFile string, keyFile string, caFile string) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both the certificate and the key files are required")
+	}
+
+	reloader := &Reloader{
+		logger:        logger.Named("certificates"),
+		certFile:      certFile,
+		keyFile:       keyFile,
+		caFile:        caFile,
+		checkInterval: time.Duration(constants.CertificateReloadCheckIntervalSeconds) * time.Second,
+		modTimes:      map[string]time.Time{},
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// NewReloaderFromConfig returns the reloader of the TLS files of the configurations, or nil when TLS is not configured
+func NewReloaderFromConfig(logger hclog.Logger, scheduler0Config config.Scheduler0Config) (*Reloader, error) {
+	configs := scheduler0Config.GetConfigurations()
+	if configs.TLSCertFile == "" && configs.TLSKeyFile == "" {
+		if configs.PeerMTLS || configs.RaftTLS {
+			return nil, errors.New("PeerMTLS and RaftTLS require TLSCertFile, TLSKeyFile and TLSClientCAFile")
+		}
+		return nil, nil
+	}
+	if (configs.PeerMTLS || configs.RaftTLS) && configs.TLSClientCAFile == "" {
+		return nil, errors.New("PeerMTLS and RaftTLS require TLSClientCAFile")
+	}
+	return NewReloader(logger, configs.TLSCertFile, configs.TLSKeyFile, configs.TLSClientCAFile)
+}
+
+// Reload loads the files again, the certificate and CA served before are kept when the files cannot be loaded
+func (reloader *Reloader) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the certificate %s: %w", reloader.certFile, err)
+	}
+
+	var caPool *x509.CertPool
+	if reloader.caFile != "" {
+		caPEM, readErr := os.ReadFile(reloader.caFile)
+		if readErr != nil {
+			return fmt.Errorf("failed to read the CA %s: %w", reloader.caFile, readErr)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no certificates found in the CA %s", reloader.caFile)
+		}
+	}
+
+	modTimes := reloader.fileModTimes()
+
+	reloader.mtx.Lock()
+	defer reloader.mtx.Unlock()
+	reloader.certificate = &certificate
+	reloader.caPool = caPool
+	reloader.modTimes = modTimes
+	reloader.lastCheck = time.Now()
+	return nil
+}
+
+// GetCertificate returns the certificate served to clients
+func (reloader *Reloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.reloadIfChanged()
+
+	reloader.mtx.Lock()
+	defer reloader.mtx.Unlock()
+	return reloader.certificate, nil
+}
+
+// GetClientCertificate returns the certificate presented to servers asking for one
+func (reloader *Reloader) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return reloader.GetCertificate(nil)
+}
+
+// CAPool returns the pool of the CA, nil without a CA
+func (reloader *Reloader) CAPool() *x509.CertPool {
+	reloader.reloadIfChanged()
+
+	reloader.mtx.Lock()
+	defer reloader.mtx.Unlock()
+	return reloader.caPool
+}
+
+// ServerConfig returns the TLS configuration of a listener. Clients are asked for a certificate verified by the CA
+// with clientAuth, every handshake uses the CA loaded last.
+func (reloader *Reloader) ServerConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: reloader.GetCertificate,
+				ClientAuth:     clientAuth,
+				ClientCAs:      reloader.CAPool(),
+			}, nil
+		},
+	}
+}
+
+// ClientConfig returns the TLS configuration of a connection to a peer, which presents the certificate
+// and verifies the peer with the CA, or with the CAs of the system without a CA
+func (reloader *Reloader) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: reloader.GetClientCertificate,
+		RootCAs:              reloader.CAPool(),
+	}
+}
+
+// Transport returns an http transport presenting the certificate to its servers, every connection
+// uses the certificate and CA loaded last. It is the default transport when the reloader is nil.
+func (reloader *Reloader) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if reloader == nil {
+		return transport
+	}
+
+	transport.DialTLSContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		tlsConfig := reloader.ClientConfig()
+		host, _, splitErr := net.SplitHostPort(addr)
+		if splitErr != nil {
+			return nil, splitErr
+		}
+		tlsConfig.ServerName = host
+		dialer := &tls.Dialer{Config: tlsConfig}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+// reloadIfChanged reloads the files once their modification time changed, failures keep the files loaded before
+func (reloader *Reloader) reloadIfChanged() {
+	reloader.mtx.Lock()
+	if time.Since(reloader.lastCheck) < reloader.checkInterval {
+		reloader.mtx.Unlock()
+		return
+	}
+	reloader.lastCheck = time.Now()
+	loadedModTimes := reloader.modTimes
+	reloader.mtx.Unlock()
+
+	changed := false
+	for file, modTime := range reloader.fileModTimes() {
+		if !modTime.Equal(loadedModTimes[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := reloader.Reload(); err != nil {
+		reloader.logger.Error("failed to reload the certificates, serving the certificates loaded before", "error", err.Error())
+		return
+	}
+	reloader.logger.Info("reloaded the certificates", "certificate", reloader.certFile)
+}
+
+// fileModTimes returns the modification time of the files, files that cannot be read are left out
+func (reloader *Reloader) fileModTimes() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{reloader.certFile, reloader.keyFile, reloader.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes
+}
@@ -0,0 +1,219 @@
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA signs the certificates of the tests
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate the CA key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "scheduler0-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("failed to create the CA:", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("failed to parse the CA:", err)
+	}
+	return &testCA{
+		certificate: certificate,
+		key:         key,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// writeFiles issues a certificate for the loopback address with the common name, and writes it with its key and the CA in the dir
+func (ca *testCA) writeFiles(t *testing.T, dir string, commonName string) (string, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate the key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal("failed to create the certificate:", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("failed to marshal the key:", err)
+	}
+
+	certFile := filepath.Join(dir, "node.crt")
+	keyFile := filepath.Join(dir, "node.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	files := map[string][]byte{
+		certFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyFile:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		caFile:   ca.pem,
+	}
+	for file, content := range files {
+		if writeErr := os.WriteFile(file, content, 0600); writeErr != nil {
+			t.Fatal("failed to write", file, writeErr)
+		}
+	}
+	return certFile, keyFile, caFile
+}
+
+// touch moves the modification time of the files forward, so they are seen as changed within the same second
+func touch(t *testing.T, files ...string) {
+	modTime := time.Now().Add(time.Minute)
+	for _, file := range files {
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal("failed to touch", file, err)
+		}
+	}
+}
+
+func servedCommonName(t *testing.T, reloader *Reloader) string {
+	certificate, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatal("failed to get the certificate:", err)
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatal("failed to parse the certificate:", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func testLogger() hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:  "certificates-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+}
+
+func Test_Reloader_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey, caFile := ca.writeFiles(t, t.TempDir(), "server")
+	clientCert, clientKey, _ := ca.writeFiles(t, t.TempDir(), "client")
+
+	serverReloader, err := NewReloader(testLogger(), serverCert, serverKey, caFile)
+	if err != nil {
+		t.Fatal("failed to load the server certificate:", err)
+	}
+	clientReloader, err := NewReloader(testLogger(), clientCert, clientKey, caFile)
+	if err != nil {
+		t.Fatal("failed to load the client certificate:", err)
+	}
+
+	var clientCommonName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCommonName = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = serverReloader.ServerConfig(tls.RequireAndVerifyClientCert)
+	server.StartTLS()
+	defer server.Close()
+
+	client := &http.Client{Transport: clientReloader.Transport()}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal("failed to make the request:", err)
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "client", clientCommonName)
+
+	// A client trusting the server without a certificate of its own is rejected
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: serverReloader.CAPool()}
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	assert.NotNil(t, err)
+}
+
+func Test_Reloader_ReloadsChangedFiles(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile, caFile := ca.writeFiles(t, dir, "first")
+
+	reloader, err := NewReloader(testLogger(), certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal("failed to load the certificate:", err)
+	}
+	reloader.checkInterval = 0
+	assert.Equal(t, "first", servedCommonName(t, reloader))
+
+	ca.writeFiles(t, dir, "second")
+	touch(t, certFile, keyFile, caFile)
+	assert.Equal(t, "second", servedCommonName(t, reloader))
+
+	// A certificate that cannot be loaded keeps the one loaded before
+	if writeErr := os.WriteFile(certFile, []byte("not a certificate"), 0600); writeErr != nil {
+		t.Fatal("failed to write the certificate:", writeErr)
+	}
+	touch(t, certFile)
+	assert.Equal(t, "second", servedCommonName(t, reloader))
+}
+
+func Test_Reloader_ChecksFilesOncePerInterval(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile, caFile := ca.writeFiles(t, dir, "first")
+
+	reloader, err := NewReloader(testLogger(), certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal("failed to load the certificate:", err)
+	}
+
+	ca.writeFiles(t, dir, "second")
+	touch(t, certFile, keyFile, caFile)
+	assert.Equal(t, "first", servedCommonName(t, reloader))
+}
+
+func Test_NewReloader_RequiresCertificateAndKey(t *testing.T) {
+	_, err := NewReloader(testLogger(), "", "", "")
+	assert.NotNil(t, err)
+
+	_, err = NewReloader(testLogger(), filepath.Join(t.TempDir(), "missing.crt"), filepath.Join(t.TempDir(), "missing.key"), "")
+	assert.NotNil(t, err)
+}
+
+func Test_Reloader_NilTransport(t *testing.T) {
+	var reloader *Reloader
+	transport := reloader.Transport()
+	assert.NotNil(t, transport)
+	assert.Nil(t, transport.DialTLSContext)
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+// GetTask returns the async task of the request. The server holds the request until the task succeeds.
+func (c *client) GetTask(ctx context.Context, requestId string) (*models.AsyncTask, *utils.GenericError) {
+	task := models.AsyncTask{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/async-tasks/%s", requestId)}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// WaitForTask polls the async task of the request until it succeeds or fails, or the context is done.
+// A failed task is returned with its output, which holds the error of the task.
+func (c *client) WaitForTask(ctx context.Context, requestId string) (*models.AsyncTask, *utils.GenericError) {
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, c.options.TaskPollTimeout)
+		task, err := c.GetTask(pollCtx, requestId)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if pollCtx.Err() == nil {
+				return nil, err
+			}
+			// The task did not complete within the poll, poll again
+			continue
+		}
+
+		if task.State == models.AsyncTaskSuccess || task.State == models.AsyncTaskFail {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, utils.HTTPGenericError(http.StatusRequestTimeout, ctx.Err().Error())
+		case <-time.After(c.options.RetryBackoff):
+		}
+	}
+}
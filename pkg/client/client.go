@@ -0,0 +1,342 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a client of the scheduler0 api
+type Options struct {
+	Endpoints       []string      // Base urls of the replicas of the cluster, such as http://localhost:9090
+	APIKey          string        // Api key of the credential authenticating the requests
+	APISecret       string        // Api secret of the credential authenticating the requests
+	MaxRetries      int           // Number of times a failed request is retried on the next replica, negative disables retries
+	RetryBackoff    time.Duration // Delay before the first retry, doubled on each retry
+	TaskPollTimeout time.Duration // Time WaitForTask waits on a single poll of an async task
+	HTTPClient      *http.Client  // Client sending the requests, its redirect policy is replaced to handle leader redirects
+	ReadConsistency string        // Consistency of reads, stale, leader or linearizable, defaults to the stale reads of the server
+	MaxStaleness    time.Duration // Longest time since a replica last heard from the leader for stale reads, zero for no bound
+}
+
+type Client interface {
+	CreateProject(ctx context.Context, project models.Project) (*models.Project, *utils.GenericError)
+	GetProject(ctx context.Context, projectId uint64) (*models.Project, *utils.GenericError)
+	ListProjects(ctx context.Context, page models.PageRequest) (*models.PaginatedProject, *utils.GenericError)
+	UpdateProject(ctx context.Context, project models.Project) (*models.Project, *utils.GenericError)
+	DeleteProject(ctx context.Context, projectId uint64, version uint64) *utils.GenericError
+
+	BatchCreateJobs(ctx context.Context, jobs []models.Job, idempotencyKey string) (*AsyncTaskRef, *utils.GenericError)
+	BatchUpdateJobs(ctx context.Context, jobs []models.Job) (*AsyncTaskRef, *utils.GenericError)
+	BatchDeleteJobs(ctx context.Context, jobIds []uint64) (*AsyncTaskRef, *utils.GenericError)
+	GetJob(ctx context.Context, jobId uint64) (*models.Job, *utils.GenericError)
+	ListJobs(ctx context.Context, filter models.JobFilter) (*models.PaginatedJob, *utils.GenericError)
+	UpdateJob(ctx context.Context, job models.Job) (*models.Job, *utils.GenericError)
+	DeleteJob(ctx context.Context, jobId uint64, version uint64) *utils.GenericError
+	PauseJobs(ctx context.Context, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError)
+	ResumeJobs(ctx context.Context, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError)
+	DeleteJobs(ctx context.Context, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError)
+
+	CreateCredential(ctx context.Context, credential models.Credential) (*models.Credential, *utils.GenericError)
+	GetCredential(ctx context.Context, credentialId uint64) (*models.Credential, *utils.GenericError)
+	ListCredentials(ctx context.Context, page models.PageRequest) (*models.PaginatedCredential, *utils.GenericError)
+	UpdateCredential(ctx context.Context, credential models.Credential) (*models.Credential, *utils.GenericError)
+	DeleteCredential(ctx context.Context, credentialId uint64, version uint64) *utils.GenericError
+	RotateCredential(ctx context.Context, credentialId uint64, gracePeriod time.Duration, version uint64) (*models.Credential, *utils.GenericError)
+
+	GetTask(ctx context.Context, requestId string) (*models.AsyncTask, *utils.GenericError)
+	WaitForTask(ctx context.Context, requestId string) (*models.AsyncTask, *utils.GenericError)
+
+	Leader() string
+}
+
+// client sends the requests to the replica it believes is the leader. Followers answer writes with a redirect
+// to the leader, which the client follows and remembers, and unreachable or unavailable replicas are skipped.
+type client struct {
+	logger     hclog.Logger
+	options    Options
+	httpClient *http.Client
+	mtx        sync.Mutex
+	endpoint   int    // Index of the replica the requests are sent to
+	leader     string // Base url of the leader learned from a redirect, empty until a follower redirects a request
+}
+
+type request struct {
+	method    string
+	path      string
+	query     url.Values
+	body      interface{}
+	headers   map[string]string
+	retryPost bool // The request is safe to send twice, such as a create with an idempotency key
+}
+
+type response struct {
+	Data    json.RawMessage `json:"data"`
+	Success bool            `json:"success"`
+}
+
+func NewClient(logger hclog.Logger, options Options) (Client, error) {
+	if len(options.Endpoints) < 1 {
+		return nil, errors.New("at least one endpoint is required")
+	}
+
+	endpoints := make([]string, 0, len(options.Endpoints))
+	for _, endpoint := range options.Endpoints {
+		endpoints = append(endpoints, strings.TrimSuffix(endpoint, "/"))
+	}
+	options.Endpoints = endpoints
+
+	if options.MaxRetries == 0 {
+		options.MaxRetries = constants.DefaultClientMaxRetries
+	}
+	if options.RetryBackoff == 0 {
+		options.RetryBackoff = time.Duration(constants.DefaultClientRetryBackoffMs) * time.Millisecond
+	}
+	if options.TaskPollTimeout == 0 {
+		options.TaskPollTimeout = time.Duration(constants.DefaultClientTaskPollTimeoutSecs) * time.Second
+	}
+
+	httpClient := &http.Client{}
+	if options.HTTPClient != nil {
+		copied := *options.HTTPClient
+		httpClient = &copied
+	}
+	// Redirects are followed by the client, as following them with net/http turns writes into GETs
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &client{
+		logger:     logger.Named("scheduler0-client"),
+		options:    options,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Leader returns the base url of the replica the requests are sent to
+func (c *client) Leader() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.currentEndpoint()
+}
+
+func (c *client) currentEndpoint() string {
+	if c.leader != "" {
+		return c.leader
+	}
+	return c.options.Endpoints[c.endpoint]
+}
+
+// failover moves the requests to the next replica after the endpoint failed
+func (c *client) failover(endpoint string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.currentEndpoint() != endpoint {
+		// Another request already moved on
+		return
+	}
+	if c.leader != "" {
+		c.leader = ""
+		return
+	}
+	c.endpoint = (c.endpoint + 1) % len(c.options.Endpoints)
+}
+
+func (c *client) setLeader(leader string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.leader = leader
+}
+
+// do sends the request to the leader and decodes the data of the response into out.
+// Redirects to the leader are always followed. Failed connections and 502, 503 and 504 responses are retried
+// on the next replica, apart from POST requests without an idempotency key, which may have been applied by the
+// leader before the connection or the proxy failed. Those are only retried when the replica could not be dialed.
+func (c *client) do(ctx context.Context, req request, out interface{}) (http.Header, *utils.GenericError) {
+	var body []byte
+	if req.body != nil {
+		encoded, marshalErr := json.Marshal(req.body)
+		if marshalErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, marshalErr.Error())
+		}
+		body = encoded
+	}
+
+	retryOnConnectionError := req.method != http.MethodPost || req.retryPost
+	backoff := c.options.RetryBackoff
+	retries := 0
+	redirects := 0
+
+	for {
+		c.mtx.Lock()
+		endpoint := c.currentEndpoint()
+		c.mtx.Unlock()
+
+		res, sendErr := c.send(ctx, endpoint, req, body)
+		if sendErr != nil {
+			if ctx.Err() != nil {
+				return nil, utils.HTTPGenericError(http.StatusRequestTimeout, ctx.Err().Error())
+			}
+			c.logger.Warn("request to replica failed", "endpoint", endpoint, "path", req.path, "error", sendErr.Error())
+			c.failover(endpoint)
+			if !(retryOnConnectionError || isDialError(sendErr)) || retries >= c.options.MaxRetries {
+				return nil, utils.HTTPGenericError(http.StatusServiceUnavailable, sendErr.Error())
+			}
+		} else {
+			resBody, readErr := io.ReadAll(res.Body)
+			res.Body.Close()
+			if readErr != nil {
+				return nil, utils.HTTPGenericError(http.StatusBadGateway, readErr.Error())
+			}
+
+			switch res.StatusCode {
+			case http.StatusFound, http.StatusMovedPermanently:
+				leader, leaderErr := leaderFromLocation(res.Header.Get("Location"))
+				if leaderErr != nil {
+					return nil, utils.HTTPGenericError(http.StatusBadGateway, leaderErr.Error())
+				}
+				redirects += 1
+				if redirects > len(c.options.Endpoints)+1 {
+					return nil, utils.HTTPGenericError(http.StatusLoopDetected, fmt.Sprintf("too many redirects, last redirected to %s", leader))
+				}
+				c.logger.Debug("following redirect to the leader", "leader", leader)
+				c.setLeader(leader)
+				continue
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				c.logger.Warn("replica is unavailable", "endpoint", endpoint, "path", req.path, "status", res.StatusCode)
+				c.failover(endpoint)
+				if !retryOnConnectionError || retries >= c.options.MaxRetries {
+					return nil, decodeError(res.StatusCode, resBody)
+				}
+			default:
+				if decodeErr := decodeResponse(res.StatusCode, resBody, out); decodeErr != nil {
+					return res.Header, decodeErr
+				}
+				return res.Header, nil
+			}
+		}
+
+		retries += 1
+		select {
+		case <-ctx.Done():
+			return nil, utils.HTTPGenericError(http.StatusRequestTimeout, ctx.Err().Error())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *client) send(ctx context.Context, endpoint string, req request, body []byte) (*http.Response, error) {
+	requestUrl := fmt.Sprintf("%s%s%s", endpoint, constants.APIV1Base, req.path)
+	if len(req.query) > 0 {
+		requestUrl = fmt.Sprintf("%s?%s", requestUrl, req.query.Encode())
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, req.method, requestUrl, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+	httpRequest.Header.Set(headers.APIKeyHeader, c.options.APIKey)
+	httpRequest.Header.Set(headers.SecretKeyHeader, c.options.APISecret)
+	if req.method == http.MethodGet {
+		if c.options.ReadConsistency != "" {
+			httpRequest.Header.Set(headers.ReadConsistencyHeader, c.options.ReadConsistency)
+		}
+		if c.options.MaxStaleness > 0 {
+			httpRequest.Header.Set(headers.MaxStalenessHeader, c.options.MaxStaleness.String())
+		}
+	}
+	for key, value := range req.headers {
+		httpRequest.Header.Set(key, value)
+	}
+
+	return c.httpClient.Do(httpRequest)
+}
+
+// ifMatch makes an update or delete apply only to the version, updates and deletes with version 0 apply to any version
+func ifMatch(version uint64) map[string]string {
+	if version == 0 {
+		return nil
+	}
+	return map[string]string{headers.IfMatchHeader: fmt.Sprintf("\"%d\"", version)}
+}
+
+// isDialError reports whether the request failed before reaching the replica
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// leaderFromLocation returns the base url of the leader a follower redirected a request to
+func leaderFromLocation(location string) (string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("redirect location %q is not the address of a replica", location)
+	}
+	return fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host), nil
+}
+
+func decodeResponse(status int, body []byte, out interface{}) *utils.GenericError {
+	if status < 200 || status >= 300 {
+		return decodeError(status, body)
+	}
+	if len(body) < 1 {
+		return nil
+	}
+
+	res := response{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return utils.HTTPGenericError(http.StatusBadGateway, fmt.Sprintf("failed to decode response: %s", err.Error()))
+	}
+	if !res.Success {
+		// A few handlers report errors with a success status
+		return decodeError(http.StatusBadRequest, body)
+	}
+	if out == nil || len(res.Data) < 1 || string(res.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(res.Data, out); err != nil {
+		return utils.HTTPGenericError(http.StatusBadGateway, fmt.Sprintf("failed to decode response data: %s", err.Error()))
+	}
+	return nil
+}
+
+// decodeError returns the error message in the data of the response, or the status text when there is none
+func decodeError(status int, body []byte) *utils.GenericError {
+	res := response{}
+	if err := json.Unmarshal(body, &res); err != nil || len(res.Data) < 1 || string(res.Data) == "null" {
+		return utils.HTTPGenericError(status, http.StatusText(status))
+	}
+
+	message := ""
+	if err := json.Unmarshal(res.Data, &message); err != nil {
+		message = string(res.Data)
+	}
+	return utils.HTTPGenericError(status, message)
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/db"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/http/server"
+	"scheduler0/pkg/models"
+	async_task_repo "scheduler0/pkg/repository/async_task"
+	credential_repo "scheduler0/pkg/repository/credential"
+	job_repo "scheduler0/pkg/repository/job"
+	job_queue_repo "scheduler0/pkg/repository/job_queue"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/secrets"
+	"scheduler0/pkg/service"
+	"scheduler0/pkg/service/async_task"
+	"scheduler0/pkg/service/credential"
+	"scheduler0/pkg/service/job"
+	"scheduler0/pkg/service/project"
+	"scheduler0/pkg/service/queue"
+	"scheduler0/pkg/shared_repo"
+	"scheduler0/pkg/utils"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testNode stands in for the node service of a leader or a follower of the in-process cluster
+type testNode struct {
+	isLeader bool
+}
+
+func (node *testNode) Start()                              {}
+func (node *testNode) GetUncommittedLogs(requestId string) {}
+func (node *testNode) CanAcceptClientWriteRequest() bool   { return node.isLeader }
+func (node *testNode) StopJobs()                           {}
+func (node *testNode) StartJobs()                          {}
+func (node *testNode) GetRaftStats() map[string]string     { return map[string]string{} }
+func (node *testNode) CanAcceptRequest() bool              { return true }
+func (node *testNode) ReadIndex(ctx context.Context) (uint64, *utils.GenericError) {
+	return 0, nil
+}
+func (node *testNode) EnsureReadConsistency(ctx context.Context, consistency string, maxStaleness time.Duration) *utils.GenericError {
+	return nil
+}
+func (node *testNode) ForwardCredentialUsage(ctx context.Context, usages []models.CredentialUsage) *utils.GenericError {
+	return nil
+}
+func (node *testNode) GetRaftLeaderWithId() (raft.ServerAddress, raft.ServerID) {
+	return "leader-raft-address", "1"
+}
+
+type testCluster struct {
+	leader     *httptest.Server
+	follower   *httptest.Server
+	down       string
+	apiKey     string
+	apiSecret  string
+	leaderHits int64
+}
+
+// newTestCluster serves the services of a single raft node through a leader and a follower api server.
+// The follower redirects writes to the leader like a follower of a real cluster.
+func newTestCluster(t *testing.T) *testCluster {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "client-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+	t.Setenv("SCHEDULER0_SECRET_KEY", "AB551DED82B93DC8035D624A625920E2121367C7538C02277D2D4DB3C0BFFE94")
+
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tempFile.Name()) })
+
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+
+	scheduler0config := config.NewScheduler0Config()
+	scheduler0Secrets := secrets.NewScheduler0Secrets()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	t.Cleanup(cluster.Close)
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	ctx, canceler := context.WithCancel(context.Background())
+	t.Cleanup(canceler)
+
+	dispatcher := utils.NewDispatcher(ctx, int64(1), int64(1))
+	dispatcher.Run()
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	credentialRepo := credential_repo.NewCredentialRepo(logger, scheduler0RaftActions, scheduler0Store)
+	asyncTaskRepo := async_task_repo.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
+	jobQueueRepo := job_queue_repo.NewJobQueuesRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	asyncTaskService := async_task.NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskRepo, scheduler0config)
+	asyncTaskService.SetSingleNodeMode(true)
+	asyncTaskService.ListenForNotifications()
+	queueService := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
+	credentialService := credential.NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
+
+	services := service.Service{
+		Dispatcher:        dispatcher,
+		JobService:        job.NewJobService(ctx, logger, jobRepo, queueService, projectRepo, dispatcher, asyncTaskService, nil),
+		ProjectService:    project.NewProjectService(logger, projectRepo),
+		CredentialService: credentialService,
+		AsyncTaskService:  asyncTaskService,
+	}
+
+	testCluster := &testCluster{}
+
+	leaderServices := services
+	leaderServices.NodeService = &testNode{isLeader: true}
+	leaderRouter := server.NewRouter(logger, scheduler0config, scheduler0Secrets, &leaderServices)
+	testCluster.leader = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&testCluster.leaderHits, 1)
+		leaderRouter.ServeHTTP(w, r)
+	}))
+	t.Cleanup(testCluster.leader.Close)
+
+	followerServices := services
+	followerServices.NodeService = &testNode{isLeader: false}
+	testCluster.follower = httptest.NewServer(server.NewRouter(logger, scheduler0config, scheduler0Secrets, &followerServices))
+	t.Cleanup(testCluster.follower.Close)
+
+	// A replica that is not running
+	down := httptest.NewServer(http.NotFoundHandler())
+	testCluster.down = down.URL
+	down.Close()
+
+	replicas, err := json.Marshal([]config.RaftNode{
+		{Address: testCluster.leader.URL, RaftAddress: "leader-raft-address", NodeId: 1},
+		{Address: testCluster.follower.URL, RaftAddress: "follower-raft-address", NodeId: 2},
+	})
+	assert.Nil(t, err)
+	t.Setenv("SCHEDULER0_REPLICAS", string(replicas))
+
+	apiCredential, createErr := credentialService.CreateNewCredential(models.Credential{})
+	if createErr != nil {
+		t.Fatalf("Failed to create credential: %v", createErr)
+	}
+	testCluster.apiKey = apiCredential.ApiKey
+	testCluster.apiSecret = apiCredential.ApiSecret
+
+	return testCluster
+}
+
+func (testCluster *testCluster) newClient(t *testing.T, endpoints ...string) Client {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "client-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+	apiClient, err := NewClient(logger, Options{
+		Endpoints:    endpoints,
+		APIKey:       testCluster.apiKey,
+		APISecret:    testCluster.apiSecret,
+		RetryBackoff: time.Millisecond * 10,
+	})
+	assert.Nil(t, err)
+	return apiClient
+}
+
+func Test_Client_FailsOverToTheLeader(t *testing.T) {
+	testCluster := newTestCluster(t)
+	apiClient := testCluster.newClient(t, testCluster.down, testCluster.follower.URL)
+	ctx := context.Background()
+
+	// The first replica is down and the second is a follower redirecting the write to the leader
+	createdProject, createErr := apiClient.CreateProject(ctx, models.Project{Name: "client project", Description: "project created by the client"})
+	assert.Nil(t, createErr)
+	assert.NotEqual(t, uint64(0), createdProject.ID)
+	assert.Equal(t, testCluster.leader.URL, apiClient.Leader())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&testCluster.leaderHits))
+
+	// Requests go straight to the leader once it is known
+	fetchedProject, getErr := apiClient.GetProject(ctx, createdProject.ID)
+	assert.Nil(t, getErr)
+	assert.Equal(t, "client project", fetchedProject.Name)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&testCluster.leaderHits))
+
+	createdProject.Description = "updated by the client"
+	updatedProject, updateErr := apiClient.UpdateProject(ctx, *createdProject)
+	assert.Nil(t, updateErr)
+	assert.Equal(t, "updated by the client", updatedProject.Description)
+	assert.Equal(t, createdProject.Version+1, updatedProject.Version)
+
+	// Updates and deletes made with the version of the project before the update are rejected
+	createdProject.Description = "overwritten by the client"
+	_, updateErr = apiClient.UpdateProject(ctx, *createdProject)
+	assert.NotNil(t, updateErr)
+	assert.Equal(t, http.StatusPreconditionFailed, updateErr.Type)
+	deleteErr := apiClient.DeleteProject(ctx, createdProject.ID, createdProject.Version)
+	assert.NotNil(t, deleteErr)
+	assert.Equal(t, http.StatusPreconditionFailed, deleteErr.Type)
+
+	projects, listErr := apiClient.ListProjects(ctx, models.PageRequest{Limit: 10})
+	assert.Nil(t, listErr)
+	assert.Equal(t, 1, len(projects.Data))
+	assert.Equal(t, "updated by the client", projects.Data[0].Description)
+
+	assert.Nil(t, apiClient.DeleteProject(ctx, createdProject.ID, updatedProject.Version))
+	_, getErr = apiClient.GetProject(ctx, createdProject.ID)
+	assert.NotNil(t, getErr)
+	assert.Equal(t, http.StatusNotFound, getErr.Type)
+}
+
+func Test_Client_CreatesJobsAndWaitsForTask(t *testing.T) {
+	testCluster := newTestCluster(t)
+	apiClient := testCluster.newClient(t, testCluster.follower.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	createdProject, createErr := apiClient.CreateProject(ctx, models.Project{Name: "jobs project", Description: "project of the client jobs"})
+	assert.Nil(t, createErr)
+
+	jobs := []models.Job{
+		{ProjectID: createdProject.ID, Spec: "@every 1h", CallbackUrl: "http://localhost/callback", ExecutionType: "http", Timezone: "UTC", Labels: map[string]string{"team": "payments"}},
+		{ProjectID: createdProject.ID, Spec: "@every 1h", CallbackUrl: "http://localhost/callback", ExecutionType: "http", Timezone: "UTC", Labels: map[string]string{"team": "search"}},
+	}
+	task, batchErr := apiClient.BatchCreateJobs(ctx, jobs, "client-test-key")
+	assert.Nil(t, batchErr)
+	assert.NotEqual(t, "", task.RequestId)
+	assert.Equal(t, 1, len(task.TaskIds))
+
+	completedTask, waitErr := apiClient.WaitForTask(ctx, task.RequestId)
+	assert.Nil(t, waitErr)
+	assert.Equal(t, models.AsyncTaskSuccess, completedTask.State)
+
+	createdJobs := []models.Job{}
+	assert.Nil(t, json.Unmarshal([]byte(completedTask.Output), &createdJobs))
+	assert.Equal(t, 2, len(createdJobs))
+
+	firstPage, listErr := apiClient.ListJobs(ctx, models.JobFilter{ProjectID: createdProject.ID, Limit: 1, OrderBy: "id"})
+	assert.Nil(t, listErr)
+	assert.Equal(t, uint64(2), firstPage.Total)
+	assert.NotEmpty(t, firstPage.Next)
+	secondPage, listErr := apiClient.ListJobs(ctx, models.JobFilter{ProjectID: createdProject.ID, Limit: 1, Cursor: firstPage.Next})
+	assert.Nil(t, listErr)
+	assert.Equal(t, 1, len(secondPage.Data))
+	assert.Greater(t, secondPage.Data[0].ID, firstPage.Data[0].ID)
+	assert.Empty(t, secondPage.Next)
+	assert.NotEmpty(t, secondPage.Prev)
+
+	filter := models.JobFilter{
+		ProjectID:     createdProject.ID,
+		LabelSelector: []models.LabelRequirement{{Key: "team", Operator: models.LabelSelectorEquals, Value: "payments"}},
+		Limit:         10,
+	}
+	listedJobs, listErr := apiClient.ListJobs(ctx, filter)
+	assert.Nil(t, listErr)
+	assert.Equal(t, 1, len(listedJobs.Data))
+
+	paused, pauseErr := apiClient.PauseJobs(ctx, filter)
+	assert.Nil(t, pauseErr)
+	assert.Equal(t, uint64(1), paused.Affected)
+
+	pausedJob, getErr := apiClient.GetJob(ctx, listedJobs.Data[0].ID)
+	assert.Nil(t, getErr)
+	assert.Equal(t, models.JobStatusPaused, pausedJob.Status)
+}
+
+func Test_Client_RetriesUnavailableReplicas(t *testing.T) {
+	testCluster := newTestCluster(t)
+
+	unavailable := int64(2)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&unavailable, -1) >= 0 {
+			utils.SendJSON(w, "peer cannot accept requests", false, http.StatusServiceUnavailable, nil)
+			return
+		}
+		testCluster.leader.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	apiClient := testCluster.newClient(t, flaky.URL)
+	ctx := context.Background()
+
+	credentials, listErr := apiClient.ListCredentials(ctx, models.PageRequest{Limit: 10})
+	assert.Nil(t, listErr)
+	assert.Equal(t, 1, len(credentials.Data))
+
+	// Errors of the api are returned without retries
+	_, getErr := apiClient.GetCredential(ctx, 100)
+	assert.NotNil(t, getErr)
+	assert.Equal(t, int64(-2), atomic.LoadInt64(&unavailable))
+
+	// Retries give up after the configured number of attempts
+	unavailableClient, err := NewClient(hclog.NewNullLogger(), Options{Endpoints: []string{testCluster.down}, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	assert.Nil(t, err)
+	_, listErr = unavailableClient.ListCredentials(ctx, models.PageRequest{Limit: 10})
+	assert.NotNil(t, listErr)
+	assert.Equal(t, http.StatusServiceUnavailable, listErr.Type)
+	assert.Contains(t, fmt.Sprint(listErr.Message), "connection refused")
+}
+
+func Test_Client_DoesNotRetryWritesAppliedBeforeTheProxyFailed(t *testing.T) {
+	testCluster := newTestCluster(t)
+
+	// The proxy passes the write to the leader, which applies it, and then fails to return the response
+	proxyHits := int64(0)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&proxyHits, 1)
+		testCluster.leader.Config.Handler.ServeHTTP(httptest.NewRecorder(), r)
+		utils.SendJSON(w, "bad gateway", false, http.StatusBadGateway, nil)
+	}))
+	defer proxy.Close()
+
+	apiClient := testCluster.newClient(t, proxy.URL, testCluster.leader.URL)
+	ctx := context.Background()
+
+	_, createErr := apiClient.CreateProject(ctx, models.Project{Name: "proxied project", Description: "project created through a failing proxy"})
+	assert.NotNil(t, createErr)
+	assert.Equal(t, http.StatusBadGateway, createErr.Type)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&proxyHits))
+
+	leaderClient := testCluster.newClient(t, testCluster.leader.URL)
+	projects, listErr := leaderClient.ListProjects(ctx, models.PageRequest{Limit: 10})
+	assert.Nil(t, listErr)
+	assert.Equal(t, 1, len(projects.Data))
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+func (c *client) CreateCredential(ctx context.Context, credential models.Credential) (*models.Credential, *utils.GenericError) {
+	createdCredential := models.Credential{}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/credentials", body: credential}, &createdCredential); err != nil {
+		return nil, err
+	}
+	return &createdCredential, nil
+}
+
+func (c *client) GetCredential(ctx context.Context, credentialId uint64) (*models.Credential, *utils.GenericError) {
+	credential := models.Credential{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/credentials/%d", credentialId)}, &credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (c *client) ListCredentials(ctx context.Context, page models.PageRequest) (*models.PaginatedCredential, *utils.GenericError) {
+	credentials := models.PaginatedCredential{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/credentials", query: paginationQuery(page)}, &credentials); err != nil {
+		return nil, err
+	}
+	return &credentials, nil
+}
+
+func (c *client) UpdateCredential(ctx context.Context, credential models.Credential) (*models.Credential, *utils.GenericError) {
+	updatedCredential := models.Credential{}
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/credentials/%d", credential.ID), body: credential, headers: ifMatch(credential.Version)}, &updatedCredential); err != nil {
+		return nil, err
+	}
+	return &updatedCredential, nil
+}
+
+func (c *client) DeleteCredential(ctx context.Context, credentialId uint64, version uint64) *utils.GenericError {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/credentials/%d", credentialId), headers: ifMatch(version)}, nil)
+	return err
+}
+
+// RotateCredential issues a new api secret for the credential, the current secret stays valid for the grace period
+func (c *client) RotateCredential(ctx context.Context, credentialId uint64, gracePeriod time.Duration, version uint64) (*models.Credential, *utils.GenericError) {
+	rotatedCredential := models.Credential{}
+	query := url.Values{"gracePeriod": []string{gracePeriod.String()}}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/credentials/%d/rotate", credentialId), query: query, headers: ifMatch(version)}, &rotatedCredential); err != nil {
+		return nil, err
+	}
+	return &rotatedCredential, nil
+}
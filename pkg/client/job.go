@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+// AsyncTaskRef the async task processing a batch of jobs, pass the request id to WaitForTask to wait for the jobs
+type AsyncTaskRef struct {
+	RequestId string
+	TaskIds   []uint64
+}
+
+// BatchCreateJobs creates the jobs in the background. Requests with an idempotency key are retried like reads,
+// as the leader creates the jobs of a key at most once.
+func (c *client) BatchCreateJobs(ctx context.Context, jobs []models.Job, idempotencyKey string) (*AsyncTaskRef, *utils.GenericError) {
+	req := request{method: http.MethodPost, path: "/jobs", body: jobs}
+	if idempotencyKey != "" {
+		req.headers = map[string]string{headers.IdempotencyKeyHeader: idempotencyKey}
+		req.retryPost = true
+	}
+	return c.doAsync(ctx, req)
+}
+
+func (c *client) BatchUpdateJobs(ctx context.Context, jobs []models.Job) (*AsyncTaskRef, *utils.GenericError) {
+	return c.doAsync(ctx, request{method: http.MethodPut, path: "/jobs", body: jobs})
+}
+
+func (c *client) BatchDeleteJobs(ctx context.Context, jobIds []uint64) (*AsyncTaskRef, *utils.GenericError) {
+	return c.doAsync(ctx, request{method: http.MethodPost, path: "/jobs/batch-delete", body: jobIds})
+}
+
+func (c *client) GetJob(ctx context.Context, jobId uint64) (*models.Job, *utils.GenericError) {
+	job := models.Job{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/jobs/%d", jobId)}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobs lists the jobs of the filter's project matching the filter
+func (c *client) ListJobs(ctx context.Context, filter models.JobFilter) (*models.PaginatedJob, *utils.GenericError) {
+	query := jobFilterQuery(filter)
+	for key, values := range paginationQuery(filter.PageRequest()) {
+		query[key] = values
+	}
+
+	jobs := models.PaginatedJob{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/jobs", query: query}, &jobs); err != nil {
+		return nil, err
+	}
+	return &jobs, nil
+}
+
+func (c *client) UpdateJob(ctx context.Context, job models.Job) (*models.Job, *utils.GenericError) {
+	updatedJob := models.Job{}
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/jobs/%d", job.ID), body: job, headers: ifMatch(job.Version)}, &updatedJob); err != nil {
+		return nil, err
+	}
+	return &updatedJob, nil
+}
+
+func (c *client) DeleteJob(ctx context.Context, jobId uint64, version uint64) *utils.GenericError {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/jobs/%d", jobId), headers: ifMatch(version)}, nil)
+	return err
+}
+
+func (c *client) PauseJobs(ctx context.Context, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError) {
+	return c.doBulk(ctx, http.MethodPost, "/jobs/pause", filter)
+}
+
+func (c *client) ResumeJobs(ctx context.Context, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError) {
+	return c.doBulk(ctx, http.MethodPost, "/jobs/resume", filter)
+}
+
+func (c *client) DeleteJobs(ctx context.Context, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError) {
+	return c.doBulk(ctx, http.MethodDelete, "/jobs", filter)
+}
+
+func (c *client) doBulk(ctx context.Context, method string, jobsPath string, filter models.JobFilter) (*models.BulkJobsResult, *utils.GenericError) {
+	result := models.BulkJobsResult{}
+	if _, err := c.do(ctx, request{method: method, path: jobsPath, query: jobFilterQuery(filter)}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// doAsync sends a batch request and returns the async task from the Location header of the response
+func (c *client) doAsync(ctx context.Context, req request) (*AsyncTaskRef, *utils.GenericError) {
+	taskIds := []uint64{}
+	resHeaders, err := c.do(ctx, req, &taskIds)
+	if err != nil {
+		return nil, err
+	}
+	return &AsyncTaskRef{
+		RequestId: path.Base(resHeaders.Get("Location")),
+		TaskIds:   taskIds,
+	}, nil
+}
+
+// jobFilterQuery encodes the filter in the query parameters parsed by the jobs endpoints
+func jobFilterQuery(filter models.JobFilter) url.Values {
+	query := url.Values{}
+	query.Set("projectId", strconv.FormatUint(filter.ProjectID, 10))
+
+	if len(filter.LabelSelector) > 0 {
+		requirements := make([]string, 0, len(filter.LabelSelector))
+		for _, requirement := range filter.LabelSelector {
+			switch requirement.Operator {
+			case models.LabelSelectorExists:
+				requirements = append(requirements, requirement.Key)
+			case models.LabelSelectorNotExists:
+				requirements = append(requirements, fmt.Sprintf("!%s", requirement.Key))
+			default:
+				requirements = append(requirements, fmt.Sprintf("%s%s%s", requirement.Key, requirement.Operator, requirement.Value))
+			}
+		}
+		query.Set("labels", strings.Join(requirements, ","))
+	}
+	if filter.CallbackUrlPrefix != "" {
+		query.Set("callbackUrlPrefix", filter.CallbackUrlPrefix)
+	}
+	if filter.ExecutionType != "" {
+		query.Set("executionType", filter.ExecutionType)
+	}
+	if filter.Status != "" {
+		query.Set("status", string(filter.Status))
+	}
+
+	return query
+}
+
+// paginationQuery the query parameters of a page, the cursor replaces the offset
+func paginationQuery(page models.PageRequest) url.Values {
+	query := url.Values{}
+	query.Set("limit", strconv.FormatUint(page.Limit, 10))
+	if page.Cursor != "" {
+		query.Set("cursor", page.Cursor)
+	} else {
+		query.Set("offset", strconv.FormatUint(page.Offset, 10))
+	}
+	if page.OrderBy != "" {
+		query.Set("orderBy", page.OrderBy)
+	}
+	if page.Order != "" {
+		query.Set("order", page.Order)
+	}
+	return query
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+)
+
+func (c *client) CreateProject(ctx context.Context, project models.Project) (*models.Project, *utils.GenericError) {
+	createdProject := models.Project{}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/projects", body: project}, &createdProject); err != nil {
+		return nil, err
+	}
+	return &createdProject, nil
+}
+
+func (c *client) GetProject(ctx context.Context, projectId uint64) (*models.Project, *utils.GenericError) {
+	project := models.Project{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/projects/%d", projectId)}, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (c *client) ListProjects(ctx context.Context, page models.PageRequest) (*models.PaginatedProject, *utils.GenericError) {
+	projects := models.PaginatedProject{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/projects", query: paginationQuery(page)}, &projects); err != nil {
+		return nil, err
+	}
+	return &projects, nil
+}
+
+func (c *client) UpdateProject(ctx context.Context, project models.Project) (*models.Project, *utils.GenericError) {
+	updatedProject := models.Project{}
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: fmt.Sprintf("/projects/%d", project.ID), body: project, headers: ifMatch(project.Version)}, &updatedProject); err != nil {
+		return nil, err
+	}
+	return &updatedProject, nil
+}
+
+func (c *client) DeleteProject(ctx context.Context, projectId uint64, version uint64) *utils.GenericError {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/projects/%d", projectId), headers: ifMatch(version)}, nil)
+	return err
+}
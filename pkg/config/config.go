@@ -30,35 +30,57 @@ func NewScheduler0Config() Scheduler0Config {
 
 // Scheduler0Configurations global configurations
 type Scheduler0Configurations struct {
-	LogLevel                         string     `json:"logLevel" yaml:"LogLevel"`                                                 // Logging verbosity level
-	Protocol                         string     `json:"protocol" yaml:"Protocol"`                                                 // Communication protocol used
-	Host                             string     `json:"host" yaml:"Host"`                                                         // Host address
-	Port                             string     `json:"port" yaml:"Port"`                                                         // Port number
-	Replicas                         []RaftNode `json:"replicas" yaml:"Replicas"`                                                 // List of replicas in the raft cluster
-	PeerAuthRequestTimeoutMs         uint64     `json:"PeerAuthRequestTimeoutMs" yaml:"PeerAuthRequestTimeoutMs"`                 // Peer authentication request timeout in milliseconds
-	PeerConnectRetryMax              uint64     `json:"peerConnectRetryMax" yaml:"PeerConnectRetryMax"`                           // Maximum number of retries for connecting to peers
-	PeerConnectRetryDelaySeconds     uint64     `json:"peerConnectRetryDelay" yaml:"PeerConnectRetryDelaySeconds"`                // Delay between retries for connecting to peers, in seconds
-	Bootstrap                        bool       `json:"bootstrap" yaml:"Bootstrap"`                                               // Whether the scheduler should start in bootstrap mode
-	NodeId                           uint64     `json:"nodeId" yaml:"NodeId"`                                                     // Unique identifier for the scheduler node
-	NodeAdvAddress                   string     `json:"nodeAdvAddress" yaml:"NodeAdvAddress"`                                     // Node Advertised Address
-	RaftAddress                      string     `json:"raftAddress" yaml:"RaftAddress"`                                           // Address used for raft communication
-	RaftTransportMaxPool             uint64     `json:"raftTransportMaxPool" yaml:"RaftTransportMaxPool"`                         // Maximum size of the raft transport pool
-	RaftTransportTimeout             uint64     `json:"raftTransportTimeout" yaml:"RaftTransportTimeout"`                         // Timeout for raft transport operations
-	RaftSnapshotInterval             uint64     `json:"raftSnapshotInterval" yaml:"RaftSnapshotInterval"`                         // Interval between raft snapshots
-	RaftSnapshotThreshold            uint64     `json:"raftSnapshotThreshold" yaml:"RaftSnapshotThreshold"`                       // Threshold for raft snapshot creation
-	RaftHeartbeatTimeout             uint64     `json:"raftHeartbeatTimeout" yaml:"RaftHeartbeatTimeout"`                         // Timeout for raft heartbeat
-	RaftElectionTimeout              uint64     `json:"raftElectionTimeout" yaml:"RaftElectionTimeout"`                           // Timeout for raft leader election
-	RaftCommitTimeout                uint64     `json:"raftCommitTimeout" yaml:"RaftCommitTimeout"`                               // Timeout for raft commit operation
-	RaftMaxAppendEntries             uint64     `json:"raftMaxAppendEntries" yaml:"RaftMaxAppendEntries"`                         // Maximum number of entries to append in a single raft operation
-	JobExecutionTimeout              uint64     `json:"jobExecutionTimeout" yaml:"JobExecutionTimeout"`                           // Timeout for job execution
-	JobExecutionRetryDelay           uint64     `json:"jobExecutionRetryDelay" yaml:"JobExecutionRetryDelay"`                     // Delay between retries for job execution
-	JobExecutionRetryMax             uint64     `json:"jobExecutionRetryMax" yaml:"JobExecutionRetryMax"`                         // Maximum number of retries for job execution
-	MaxWorkers                       uint64     `json:"maxWorkers" yaml:"MaxWorkers"`                                             // Maximum number of concurrent workers
-	MaxQueue                         uint64     `json:"maxQueue" yaml:"MaxQueue"`                                                 // Maximum size of the job queue
-	MaxMemory                        uint64     `json:"maxMemory" yaml:"MaxMemory"`                                               // Maximum amount of memory to be used by the scheduler
-	ExecutionLogFetchFanIn           uint64     `json:"executionLogFetchFanIn" yaml:"ExecutionLogFetchFanIn"`                     // Fan-in factor for fetching execution logs
-	ExecutionLogFetchIntervalSeconds uint64     `json:"executionLogFetchIntervalSeconds" yaml:"ExecutionLogFetchIntervalSeconds"` // Interval between log fetches, in seconds
-	HTTPExecutorPayloadMaxSizeMb     uint64     `json:"httpExecutorPayloadMaxSizeMb" yaml:"HTTPExecutorPayloadMaxSizeMb"`         // Maximum payload size for HTTP executor, in megabytes
+	LogLevel                                string     `json:"logLevel" yaml:"LogLevel"`                                                               // Logging verbosity level
+	LogFormat                               string     `json:"logFormat" yaml:"LogFormat"`                                                             // Format of the log lines, text or json
+	Protocol                                string     `json:"protocol" yaml:"Protocol"`                                                               // Communication protocol used, http or https when TLSCertFile is set
+	Host                                    string     `json:"host" yaml:"Host"`                                                                       // Host address
+	Port                                    string     `json:"port" yaml:"Port"`                                                                       // Port number
+	Replicas                                []RaftNode `json:"replicas" yaml:"Replicas"`                                                               // List of replicas in the raft cluster
+	PeerAuthRequestTimeoutMs                uint64     `json:"PeerAuthRequestTimeoutMs" yaml:"PeerAuthRequestTimeoutMs"`                               // Peer authentication request timeout in milliseconds
+	PeerConnectRetryMax                     uint64     `json:"peerConnectRetryMax" yaml:"PeerConnectRetryMax"`                                         // Maximum number of retries for connecting to peers
+	PeerConnectRetryDelaySeconds            uint64     `json:"peerConnectRetryDelay" yaml:"PeerConnectRetryDelaySeconds"`                              // Delay between retries for connecting to peers, in seconds
+	Bootstrap                               bool       `json:"bootstrap" yaml:"Bootstrap"`                                                             // Whether the scheduler should start in bootstrap mode
+	NodeId                                  uint64     `json:"nodeId" yaml:"NodeId"`                                                                   // Unique identifier for the scheduler node
+	NodeAdvAddress                          string     `json:"nodeAdvAddress" yaml:"NodeAdvAddress"`                                                   // Node Advertised Address
+	RaftAddress                             string     `json:"raftAddress" yaml:"RaftAddress"`                                                         // Address used for raft communication
+	RaftTransportMaxPool                    uint64     `json:"raftTransportMaxPool" yaml:"RaftTransportMaxPool"`                                       // Maximum size of the raft transport pool
+	RaftTransportTimeout                    uint64     `json:"raftTransportTimeout" yaml:"RaftTransportTimeout"`                                       // Timeout for raft transport operations
+	RaftSnapshotInterval                    uint64     `json:"raftSnapshotInterval" yaml:"RaftSnapshotInterval"`                                       // Interval between raft snapshots
+	RaftSnapshotThreshold                   uint64     `json:"raftSnapshotThreshold" yaml:"RaftSnapshotThreshold"`                                     // Threshold for raft snapshot creation
+	RaftHeartbeatTimeout                    uint64     `json:"raftHeartbeatTimeout" yaml:"RaftHeartbeatTimeout"`                                       // Timeout for raft heartbeat
+	RaftElectionTimeout                     uint64     `json:"raftElectionTimeout" yaml:"RaftElectionTimeout"`                                         // Timeout for raft leader election
+	RaftCommitTimeout                       uint64     `json:"raftCommitTimeout" yaml:"RaftCommitTimeout"`                                             // Timeout for raft commit operation
+	RaftMaxAppendEntries                    uint64     `json:"raftMaxAppendEntries" yaml:"RaftMaxAppendEntries"`                                       // Maximum number of entries to append in a single raft operation
+	JobExecutionTimeout                     uint64     `json:"jobExecutionTimeout" yaml:"JobExecutionTimeout"`                                         // Timeout for job execution
+	JobExecutionRetryDelay                  uint64     `json:"jobExecutionRetryDelay" yaml:"JobExecutionRetryDelay"`                                   // Delay between retries for job execution
+	JobExecutionRetryMax                    uint64     `json:"jobExecutionRetryMax" yaml:"JobExecutionRetryMax"`                                       // Maximum number of retries for job execution
+	MaxWorkers                              uint64     `json:"maxWorkers" yaml:"MaxWorkers"`                                                           // Maximum number of concurrent workers
+	MaxQueue                                uint64     `json:"maxQueue" yaml:"MaxQueue"`                                                               // Maximum size of the job queue
+	MaxMemory                               uint64     `json:"maxMemory" yaml:"MaxMemory"`                                                             // Maximum amount of memory to be used by the scheduler
+	ExecutionLogFetchFanIn                  uint64     `json:"executionLogFetchFanIn" yaml:"ExecutionLogFetchFanIn"`                                   // Fan-in factor for fetching execution logs
+	ExecutionLogFetchIntervalSeconds        uint64     `json:"executionLogFetchIntervalSeconds" yaml:"ExecutionLogFetchIntervalSeconds"`               // Interval between log fetches, in seconds
+	HTTPExecutorPayloadMaxSizeMb            uint64     `json:"httpExecutorPayloadMaxSizeMb" yaml:"HTTPExecutorPayloadMaxSizeMb"`                       // Maximum payload size for HTTP executor, in megabytes
+	ExecutionLogRetentionIntervalSeconds    uint64     `json:"executionLogRetentionIntervalSeconds" yaml:"ExecutionLogRetentionIntervalSeconds"`       // Interval between execution log compactions run by the leader, in seconds. Zero disables compaction
+	ExecutionLogRetentionMaxAgeSeconds      uint64     `json:"executionLogRetentionMaxAgeSeconds" yaml:"ExecutionLogRetentionMaxAgeSeconds"`           // Age after which execution logs are compacted, in seconds
+	ExecutionLogRetentionMaxPerJob          uint64     `json:"executionLogRetentionMaxPerJob" yaml:"ExecutionLogRetentionMaxPerJob"`                   // Number of most recent execution logs kept per job
+	ExecutionLogRetentionMaxScheduledPerJob uint64     `json:"executionLogRetentionMaxScheduledPerJob" yaml:"ExecutionLogRetentionMaxScheduledPerJob"` // Number of most recent scheduled execution logs kept per job
+	ExecutionLogRetentionMaxSuccessPerJob   uint64     `json:"executionLogRetentionMaxSuccessPerJob" yaml:"ExecutionLogRetentionMaxSuccessPerJob"`     // Number of most recent successful execution logs kept per job
+	ExecutionLogRetentionMaxFailedPerJob    uint64     `json:"executionLogRetentionMaxFailedPerJob" yaml:"ExecutionLogRetentionMaxFailedPerJob"`       // Number of most recent failed execution logs kept per job
+	ExecutionLogRetentionDailyRollup        bool       `json:"executionLogRetentionDailyRollup" yaml:"ExecutionLogRetentionDailyRollup"`               // Whether daily per job execution counts are kept for compacted execution logs
+	AlertEvaluationIntervalSeconds          uint64     `json:"alertEvaluationIntervalSeconds" yaml:"AlertEvaluationIntervalSeconds"`                   // Interval between evaluations of the alert rules by the leader, in seconds
+	TracingExporter                         string     `json:"tracingExporter" yaml:"TracingExporter"`                                                 // Exporter of the trace spans, stdout or otlp. Empty disables tracing
+	TracingOTLPEndpoint                     string     `json:"tracingOTLPEndpoint" yaml:"TracingOTLPEndpoint"`                                         // Host and port of the OTLP/HTTP collector receiving the trace spans
+	EventBufferSize                         uint64     `json:"eventBufferSize" yaml:"EventBufferSize"`                                                 // Number of recent events each node keeps for clients resuming the event stream
+	ForwardWritesToLeader                   bool       `json:"forwardWritesToLeader" yaml:"ForwardWritesToLeader"`                                     // Whether followers proxy client writes to the leader instead of redirecting them
+	CredentialUsageFlushIntervalSeconds     uint64     `json:"credentialUsageFlushIntervalSeconds" yaml:"CredentialUsageFlushIntervalSeconds"`         // Interval between writes of the last use of the credentials by the leader, in seconds
+	AuditLogRetentionIntervalSeconds        uint64     `json:"auditLogRetentionIntervalSeconds" yaml:"AuditLogRetentionIntervalSeconds"`               // Interval between audit log compactions run by the leader, in seconds
+	AuditLogRetentionMaxAgeSeconds          uint64     `json:"auditLogRetentionMaxAgeSeconds" yaml:"AuditLogRetentionMaxAgeSeconds"`                   // Age after which audit events are compacted, in seconds. Zero keeps audit events forever
+	TLSCertFile                             string     `json:"tlsCertFile" yaml:"TLSCertFile"`                                                         // Certificate of the node served by the API, the API is served in plain text when empty
+	TLSKeyFile                              string     `json:"tlsKeyFile" yaml:"TLSKeyFile"`                                                           // Private key of the certificate of the node
+	TLSClientCAFile                         string     `json:"tlsClientCAFile" yaml:"TLSClientCAFile"`                                                 // CA verifying the certificates of clients and peers
+	PeerMTLS                                bool       `json:"peerMTLS" yaml:"PeerMTLS"`                                                               // Whether peer requests need a client certificate verified by TLSClientCAFile
+	RaftTLS                                 bool       `json:"raftTLS" yaml:"RaftTLS"`                                                                 // Whether raft connections use TLS, with certificates verified by TLSClientCAFile and issued for a replica
+	DataEncryptionKeyId                     string     `json:"dataEncryptionKeyId" yaml:"DataEncryptionKeyId"`                                         // Id of the key of DataEncryptionKeys encrypting the data of jobs, job data is stored in plain text when empty
 }
 
 var cachedConfig *Scheduler0Configurations
@@ -109,6 +131,11 @@ func getConfigFromEnv() *Scheduler0Configurations {
 		config.LogLevel = val
 	}
 
+	// Set LogFormat
+	if val, ok := os.LookupEnv("SCHEDULER0_LOG_FORMAT"); ok {
+		config.LogFormat = val
+	}
+
 	// Set Protocol
 	if val, ok := os.LookupEnv("SCHEDULER0_PROTOCOL"); ok {
 		config.Protocol = val
@@ -333,6 +360,171 @@ func getConfigFromEnv() *Scheduler0Configurations {
 		config.HTTPExecutorPayloadMaxSizeMb = parsed
 	}
 
+	// Set ExecutionLogRetentionIntervalSeconds
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_INTERVAL_SECONDS"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_INTERVAL_SECONDS: %v", err)
+		}
+		config.ExecutionLogRetentionIntervalSeconds = parsed
+	}
+
+	// Set ExecutionLogRetentionMaxAgeSeconds
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_AGE_SECONDS"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_AGE_SECONDS: %v", err)
+		}
+		config.ExecutionLogRetentionMaxAgeSeconds = parsed
+	}
+
+	// Set ExecutionLogRetentionMaxPerJob
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_PER_JOB"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_PER_JOB: %v", err)
+		}
+		config.ExecutionLogRetentionMaxPerJob = parsed
+	}
+
+	// Set ExecutionLogRetentionMaxScheduledPerJob
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_SCHEDULED_PER_JOB"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_SCHEDULED_PER_JOB: %v", err)
+		}
+		config.ExecutionLogRetentionMaxScheduledPerJob = parsed
+	}
+
+	// Set ExecutionLogRetentionMaxSuccessPerJob
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_SUCCESS_PER_JOB"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_SUCCESS_PER_JOB: %v", err)
+		}
+		config.ExecutionLogRetentionMaxSuccessPerJob = parsed
+	}
+
+	// Set ExecutionLogRetentionMaxFailedPerJob
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_FAILED_PER_JOB"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_FAILED_PER_JOB: %v", err)
+		}
+		config.ExecutionLogRetentionMaxFailedPerJob = parsed
+	}
+
+	// Set ExecutionLogRetentionDailyRollup
+	if val, ok := os.LookupEnv("SCHEDULER0_EXECUTION_LOG_RETENTION_DAILY_ROLLUP"); ok {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EXECUTION_LOG_RETENTION_DAILY_ROLLUP: %v", err)
+		}
+		config.ExecutionLogRetentionDailyRollup = parsed
+	}
+
+	// Set AlertEvaluationIntervalSeconds
+	if val, ok := os.LookupEnv("SCHEDULER0_ALERT_EVALUATION_INTERVAL_SECONDS"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_ALERT_EVALUATION_INTERVAL_SECONDS: %v", err)
+		}
+		config.AlertEvaluationIntervalSeconds = parsed
+	}
+
+	// Set TracingExporter
+	if val, ok := os.LookupEnv("SCHEDULER0_TRACING_EXPORTER"); ok {
+		config.TracingExporter = val
+	}
+
+	// Set TracingOTLPEndpoint
+	if val, ok := os.LookupEnv("SCHEDULER0_TRACING_OTLP_ENDPOINT"); ok {
+		config.TracingOTLPEndpoint = val
+	}
+
+	// Set EventBufferSize
+	if val, ok := os.LookupEnv("SCHEDULER0_EVENT_BUFFER_SIZE"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_EVENT_BUFFER_SIZE: %v", err)
+		}
+		config.EventBufferSize = parsed
+	}
+
+	// Set ForwardWritesToLeader
+	if val, ok := os.LookupEnv("SCHEDULER0_FORWARD_WRITES_TO_LEADER"); ok {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_FORWARD_WRITES_TO_LEADER: %v", err)
+		}
+		config.ForwardWritesToLeader = parsed
+	}
+
+	// Set CredentialUsageFlushIntervalSeconds
+	if val, ok := os.LookupEnv("SCHEDULER0_CREDENTIAL_USAGE_FLUSH_INTERVAL_SECONDS"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_CREDENTIAL_USAGE_FLUSH_INTERVAL_SECONDS: %v", err)
+		}
+		config.CredentialUsageFlushIntervalSeconds = parsed
+	}
+
+	// Set AuditLogRetentionIntervalSeconds
+	if val, ok := os.LookupEnv("SCHEDULER0_AUDIT_LOG_RETENTION_INTERVAL_SECONDS"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_AUDIT_LOG_RETENTION_INTERVAL_SECONDS: %v", err)
+		}
+		config.AuditLogRetentionIntervalSeconds = parsed
+	}
+
+	// Set AuditLogRetentionMaxAgeSeconds
+	if val, ok := os.LookupEnv("SCHEDULER0_AUDIT_LOG_RETENTION_MAX_AGE_SECONDS"); ok {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_AUDIT_LOG_RETENTION_MAX_AGE_SECONDS: %v", err)
+		}
+		config.AuditLogRetentionMaxAgeSeconds = parsed
+	}
+
+	// Set TLSCertFile
+	if val, ok := os.LookupEnv("SCHEDULER0_TLS_CERT_FILE"); ok {
+		config.TLSCertFile = val
+	}
+
+	// Set TLSKeyFile
+	if val, ok := os.LookupEnv("SCHEDULER0_TLS_KEY_FILE"); ok {
+		config.TLSKeyFile = val
+	}
+
+	// Set TLSClientCAFile
+	if val, ok := os.LookupEnv("SCHEDULER0_TLS_CLIENT_CA_FILE"); ok {
+		config.TLSClientCAFile = val
+	}
+
+	// Set PeerMTLS
+	if val, ok := os.LookupEnv("SCHEDULER0_PEER_MTLS"); ok {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_PEER_MTLS: %v", err)
+		}
+		config.PeerMTLS = parsed
+	}
+
+	// Set RaftTLS
+	if val, ok := os.LookupEnv("SCHEDULER0_RAFT_TLS"); ok {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("Error parsing SCHEDULER0_RAFT_TLS: %v", err)
+		}
+		config.RaftTLS = parsed
+	}
+
+	// Set DataEncryptionKeyId
+	if val, ok := os.LookupEnv("SCHEDULER0_DATA_ENCRYPTION_KEY_ID"); ok {
+		config.DataEncryptionKeyId = val
+	}
+
 	return config
 }
 
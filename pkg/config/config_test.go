@@ -24,6 +24,8 @@ func TestGetConfigFromEnv(t *testing.T) {
 	// Set environment variables
 	os.Setenv("SCHEDULER0_LOGLEVEL", "info")
 	defer os.Unsetenv("SCHEDULER0_LOGLEVEL")
+	os.Setenv("SCHEDULER0_LOG_FORMAT", "json")
+	defer os.Unsetenv("SCHEDULER0_LOG_FORMAT")
 	os.Setenv("SCHEDULER0_PROTOCOL", "http")
 	defer os.Unsetenv("SCHEDULER0_PROTOCOL")
 	os.Setenv("SCHEDULER0_HOST", "localhost")
@@ -80,6 +82,42 @@ func TestGetConfigFromEnv(t *testing.T) {
 	defer os.Unsetenv("SCHEDULER0_JOB_INVOCATION_DEBOUNCE_DELAY")
 	os.Setenv("SCHEDULER0_HTTP_EXECUTOR_PAYLOAD_MAX_SIZE_MB", "5")
 	defer os.Unsetenv("SCHEDULER0_HTTP_EXECUTOR_PAYLOAD_MAX_SIZE_MB")
+	os.Setenv("SCHEDULER0_EXECUTION_LOG_RETENTION_INTERVAL_SECONDS", "60")
+	defer os.Unsetenv("SCHEDULER0_EXECUTION_LOG_RETENTION_INTERVAL_SECONDS")
+	os.Setenv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_PER_JOB", "100")
+	defer os.Unsetenv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_PER_JOB")
+	os.Setenv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_FAILED_PER_JOB", "20")
+	defer os.Unsetenv("SCHEDULER0_EXECUTION_LOG_RETENTION_MAX_FAILED_PER_JOB")
+	os.Setenv("SCHEDULER0_EXECUTION_LOG_RETENTION_DAILY_ROLLUP", "true")
+	defer os.Unsetenv("SCHEDULER0_EXECUTION_LOG_RETENTION_DAILY_ROLLUP")
+	os.Setenv("SCHEDULER0_ALERT_EVALUATION_INTERVAL_SECONDS", "30")
+	defer os.Unsetenv("SCHEDULER0_ALERT_EVALUATION_INTERVAL_SECONDS")
+	os.Setenv("SCHEDULER0_TRACING_EXPORTER", "otlp")
+	defer os.Unsetenv("SCHEDULER0_TRACING_EXPORTER")
+	os.Setenv("SCHEDULER0_TRACING_OTLP_ENDPOINT", "localhost:4318")
+	defer os.Unsetenv("SCHEDULER0_TRACING_OTLP_ENDPOINT")
+	os.Setenv("SCHEDULER0_EVENT_BUFFER_SIZE", "500")
+	defer os.Unsetenv("SCHEDULER0_EVENT_BUFFER_SIZE")
+	os.Setenv("SCHEDULER0_FORWARD_WRITES_TO_LEADER", "true")
+	defer os.Unsetenv("SCHEDULER0_FORWARD_WRITES_TO_LEADER")
+	os.Setenv("SCHEDULER0_CREDENTIAL_USAGE_FLUSH_INTERVAL_SECONDS", "15")
+	defer os.Unsetenv("SCHEDULER0_CREDENTIAL_USAGE_FLUSH_INTERVAL_SECONDS")
+	os.Setenv("SCHEDULER0_AUDIT_LOG_RETENTION_INTERVAL_SECONDS", "600")
+	defer os.Unsetenv("SCHEDULER0_AUDIT_LOG_RETENTION_INTERVAL_SECONDS")
+	os.Setenv("SCHEDULER0_AUDIT_LOG_RETENTION_MAX_AGE_SECONDS", "2592000")
+	defer os.Unsetenv("SCHEDULER0_AUDIT_LOG_RETENTION_MAX_AGE_SECONDS")
+	os.Setenv("SCHEDULER0_TLS_CERT_FILE", "/etc/scheduler0/node.crt")
+	defer os.Unsetenv("SCHEDULER0_TLS_CERT_FILE")
+	os.Setenv("SCHEDULER0_TLS_KEY_FILE", "/etc/scheduler0/node.key")
+	defer os.Unsetenv("SCHEDULER0_TLS_KEY_FILE")
+	os.Setenv("SCHEDULER0_TLS_CLIENT_CA_FILE", "/etc/scheduler0/ca.crt")
+	defer os.Unsetenv("SCHEDULER0_TLS_CLIENT_CA_FILE")
+	os.Setenv("SCHEDULER0_PEER_MTLS", "true")
+	defer os.Unsetenv("SCHEDULER0_PEER_MTLS")
+	os.Setenv("SCHEDULER0_RAFT_TLS", "true")
+	defer os.Unsetenv("SCHEDULER0_RAFT_TLS")
+	os.Setenv("SCHEDULER0_DATA_ENCRYPTION_KEY_ID", "2026-10")
+	defer os.Unsetenv("SCHEDULER0_DATA_ENCRYPTION_KEY_ID")
 
 	// Get configuration from environment variables
 	config := getConfigFromEnv()
@@ -87,6 +125,7 @@ func TestGetConfigFromEnv(t *testing.T) {
 	// Check if the values are set correctly
 	assert.NotNil(t, config)
 	assert.Equal(t, "info", config.LogLevel)
+	assert.Equal(t, "json", config.LogFormat)
 	assert.Equal(t, "http", config.Protocol)
 	assert.Equal(t, "localhost", config.Host)
 	assert.Equal(t, "8080", config.Port)
@@ -116,4 +155,22 @@ func TestGetConfigFromEnv(t *testing.T) {
 	assert.Equal(t, uint64(2), config.ExecutionLogFetchFanIn)
 	assert.Equal(t, uint64(10), config.ExecutionLogFetchIntervalSeconds)
 	assert.Equal(t, uint64(5), config.HTTPExecutorPayloadMaxSizeMb)
+	assert.Equal(t, uint64(60), config.ExecutionLogRetentionIntervalSeconds)
+	assert.Equal(t, uint64(100), config.ExecutionLogRetentionMaxPerJob)
+	assert.Equal(t, uint64(20), config.ExecutionLogRetentionMaxFailedPerJob)
+	assert.Equal(t, true, config.ExecutionLogRetentionDailyRollup)
+	assert.Equal(t, uint64(30), config.AlertEvaluationIntervalSeconds)
+	assert.Equal(t, "otlp", config.TracingExporter)
+	assert.Equal(t, "localhost:4318", config.TracingOTLPEndpoint)
+	assert.Equal(t, uint64(500), config.EventBufferSize)
+	assert.Equal(t, true, config.ForwardWritesToLeader)
+	assert.Equal(t, uint64(15), config.CredentialUsageFlushIntervalSeconds)
+	assert.Equal(t, uint64(600), config.AuditLogRetentionIntervalSeconds)
+	assert.Equal(t, uint64(2592000), config.AuditLogRetentionMaxAgeSeconds)
+	assert.Equal(t, "/etc/scheduler0/node.crt", config.TLSCertFile)
+	assert.Equal(t, "/etc/scheduler0/node.key", config.TLSKeyFile)
+	assert.Equal(t, "/etc/scheduler0/ca.crt", config.TLSClientCAFile)
+	assert.Equal(t, true, config.PeerMTLS)
+	assert.Equal(t, true, config.RaftTLS)
+	assert.Equal(t, "2026-10", config.DataEncryptionKeyId)
 }
@@ -1,5 +1,10 @@
 package constants
 
+import (
+	"math"
+	"time"
+)
+
 // These constants define file and directory names used in the application.
 const (
 	SqliteDbFileName   = "db.db"       // The name of the SQLite database file
@@ -28,6 +33,7 @@ const (
 	CommandActionQueueJob                       CommandAction = 0
 	CommandActionCleanUncommittedAsyncTasksLogs CommandAction = 1
 	CommandActionCleanUncommittedExecutionLogs  CommandAction = 2
+	CommandActionRecordJobRevisions             CommandAction = 3 // Every node refreshes the schedules of the jobs the command records revisions for
 )
 
 // These constants define the maximum size of certain data structures used in the application.
@@ -41,6 +47,8 @@ const (
 const (
 	CreateJobAsyncTaskService   = "create_job"   // The name of the asynchronous task service for creating jobs
 	JobExecutorAsyncTaskService = "job_executor" // The name of the asynchronous task service for executing jobs
+	UpdateJobAsyncTaskService   = "update_job"   // The name of the asynchronous task service for updating jobs
+	DeleteJobAsyncTaskService   = "delete_job"   // The name of the asynchronous task service for deleting jobs
 )
 
 const (
@@ -57,6 +65,107 @@ const (
 	JobsTimezoneColumn       = "timezone"
 	JobsTimezoneOffsetColumn = "timezone_offset"
 	JobsDateCreatedColumn    = "date_created"
+	JobsStatusColumn         = "status"
+	JobsExternalKeyColumn    = "external_key"
+	JobsVersionColumn        = "version"
+	JobsDataKeyIdColumn      = "data_key_id" // Id of the key encrypting the data of the job, NULL when the data is not encrypted
+)
+
+const (
+	IdempotencyKeysTableName          = "idempotency_keys"
+	IdempotencyKeysCredentialIdColumn = "credential_id"
+	IdempotencyKeysKeyColumn          = "idempotency_key"
+	IdempotencyKeysRequestIdColumn    = "request_id"
+	IdempotencyKeysRequestHashColumn  = "request_hash"
+	IdempotencyKeysTaskIdColumn       = "task_id"
+	IdempotencyKeysDateCreatedColumn  = "date_created"
+)
+
+// UnscopedIdempotencyKeyCredentialId is the credential of the idempotency keys stored before the keys were scoped to
+// credentials, which are taken for every credential until they expire
+const UnscopedIdempotencyKeyCredentialId uint64 = math.MaxInt64
+
+// IdempotencyKeyTTL is how long an idempotency key is remembered before it can be reused
+const IdempotencyKeyTTL = time.Hour * 24
+
+// IdempotencyKeysPruneInterval is how often the leader deletes expired idempotency keys
+const IdempotencyKeysPruneInterval = time.Hour
+
+// JobsDataResealInterval is how often a new leader tries to encrypt the data of jobs with the active key until it succeeds
+const JobsDataResealInterval = time.Minute
+
+// JobsDataResealBatchSize is how many jobs are encrypted with the active key by a single raft command
+const JobsDataResealBatchSize = 100
+
+const (
+	JobLabelsTableName   = "job_labels"
+	JobLabelsJobIdColumn = "job_id"
+	JobLabelsKeyColumn   = "label_key"
+	JobLabelsValueColumn = "label_value"
+
+	JobMetadataTableName      = "job_metadata"
+	JobMetadataJobIdColumn    = "job_id"
+	JobMetadataMetadataColumn = "metadata"
+)
+
+const (
+	JobRevisionsTableName          = "job_revisions"
+	JobRevisionsJobIdColumn        = "job_id"
+	JobRevisionsRevisionColumn     = "revision"
+	JobRevisionsActionColumn       = "action"
+	JobRevisionsSnapshotColumn     = "snapshot"
+	JobRevisionsCredentialIdColumn = "credential_id"
+	JobRevisionsDateCreatedColumn  = "date_created"
+)
+
+const (
+	JobRevisionEventsTableName      = "job_revision_events"
+	JobRevisionEventsJobIdColumn    = "job_id"
+	JobRevisionEventsRevisionColumn = "revision"
+)
+
+const (
+	JobExecutionEventsTableName         = "job_execution_events"
+	JobExecutionEventsExecutionIdColumn = "execution_id"
+)
+
+const (
+	AuditEventsTableName          = "audit_events"
+	AuditEventsIdColumn           = "id"
+	AuditEventsActionColumn       = "action"
+	AuditEventsResourceColumn     = "resource"
+	AuditEventsResourceIdColumn   = "resource_id"
+	AuditEventsCredentialIdColumn = "credential_id"
+	AuditEventsPeerColumn         = "peer"
+	AuditEventsRequestIdColumn    = "request_id"
+	AuditEventsSourceIpColumn     = "source_ip"
+	AuditEventsBeforeColumn       = "before_state"
+	AuditEventsAfterColumn        = "after_state"
+	AuditEventsDateCreatedColumn  = "date_created"
+
+	AuditContextTableName          = "audit_context"
+	AuditContextIdColumn           = "id"
+	AuditContextActionColumn       = "action"
+	AuditContextCredentialIdColumn = "credential_id"
+	AuditContextPeerColumn         = "peer"
+	AuditContextRequestIdColumn    = "request_id"
+	AuditContextSourceIpColumn     = "source_ip"
+	AuditContextDateCreatedColumn  = "date_created"
+)
+
+const (
+	AlertRulesTableName           = "alert_rules"
+	AlertRulesIdColumn            = "id"
+	AlertRulesProjectIdColumn     = "project_id"
+	AlertRulesTypeColumn          = "type"
+	AlertRulesThresholdColumn     = "threshold"
+	AlertRulesPeriodSecondsColumn = "period_seconds"
+	AlertRulesWebhookUrlColumn    = "webhook_url"
+	AlertRulesDateCreatedColumn   = "date_created"
+	FiringAlertsTableName         = "firing_alerts"
+	FiringAlertsRuleIdColumn      = "rule_id"
+	FiringAlertsJobIdColumn       = "job_id"
+	FiringAlertsDateCreatedColumn = "date_created"
 )
 
 const (
@@ -65,6 +174,7 @@ const (
 	ProjectsNameColumn        = "name"
 	ProjectsDescriptionColumn = "description"
 	ProjectsDateCreatedColumn = "date_created"
+	ProjectsVersionColumn     = "version"
 )
 
 const (
@@ -78,6 +188,7 @@ const (
 
 	ExecutionsUnCommittedTableName    = "job_executions_uncommitted"
 	ExecutionsCommittedTableName      = "job_executions_committed"
+	ExecutionsIdColumn                = "id"
 	ExecutionsUniqueIdColumn          = "unique_id"
 	ExecutionsStateColumn             = "state"
 	ExecutionsNodeIdColumn            = "node_id"
@@ -87,6 +198,8 @@ const (
 	ExecutionsDateCreatedColumn       = "date_created"
 	ExecutionsJobQueueVersion         = "job_queue_version"
 	ExecutionsVersion                 = "execution_version"
+	ExecutionsErrorColumn             = "error"
+	ExecutionsLatencyMsColumn         = "latency_ms"
 )
 
 const (
@@ -102,6 +215,7 @@ const (
 	AsyncTasksStateColumn       = "state"
 	AsyncTasksServiceColumn     = "service"
 	AsyncTasksDateCreatedColumn = "date_created"
+	AsyncTasksProjectIdsColumn  = "project_ids"
 )
 
 const (
@@ -113,7 +227,16 @@ const (
 	CredentialsArchivedColumn    = "archived"
 	CredentialsApiKeyColumn      = "api_key"
 	CredentialsApiSecretColumn   = "api_secret"
+	CredentialsProjectIdsColumn  = "project_ids"
+	CredentialsPermissionsColumn = "permissions"
 	CredentialsDateCreatedColumn = "date_created"
+	CredentialsVersionColumn     = "version"
+
+	CredentialsExpiresAtColumn                  = "expires_at"
+	CredentialsPreviousApiSecretColumn          = "previous_api_secret"
+	CredentialsPreviousApiSecretExpiresAtColumn = "previous_api_secret_expires_at"
+	CredentialsLastUsedAtColumn                 = "last_used_at"
+	CredentialsLastUsedFromColumn               = "last_used_from"
 )
 
 const (
@@ -122,4 +245,46 @@ const (
 	DefaultMaxConnectedPeers   = 4
 )
 
+const (
+	DefaultAlertEvaluationIntervalSeconds = 60 // The default number of seconds between evaluations of the alert rules
+	AlertWebhookTimeoutSeconds            = 10 // The number of seconds to wait for an alert webhook to respond
+)
+
+const (
+	DefaultCredentialRotationGracePeriod       = time.Hour * 24 // The default time the secret replaced by a rotation stays valid
+	DefaultCredentialUsageFlushIntervalSeconds = 60             // The default number of seconds between writes of the last use of the credentials
+)
+
+const (
+	DefaultAuditLogRetentionIntervalSeconds = 3600 // The default number of seconds between compactions of the audit log
+)
+
+const (
+	CertificateReloadCheckIntervalSeconds = 10 // Number of seconds between checks of the certificate files for changes
+)
+
+const (
+	DefaultEventBufferSize      = 1000 // The default number of recent events kept by a node for clients resuming the event stream
+	EventSubscriberBufferSize   = 64   // The number of events queued for a subscriber before it is disconnected as too slow
+	EventStreamKeepAliveSeconds = 15   // The number of seconds between keep alive comments written to idle event streams
+)
+
+const (
+	DefaultClientMaxRetries          = 3   // The default number of times the client retries a failed request on the next replica
+	DefaultClientRetryBackoffMs      = 200 // The default number of milliseconds before the client retries a request, doubled on each retry
+	DefaultClientTaskPollTimeoutSecs = 30  // The default number of seconds the client waits on an async task before polling it again
+)
+
+// These constants define the consistency levels of reads, requested with the headers.ReadConsistencyHeader
+const (
+	ReadConsistencyStale        = "stale"        // Read the local database, optionally bounded by a maximum staleness
+	ReadConsistencyLeader       = "leader"       // Read on a leader that verified it is still the leader
+	ReadConsistencyLinearizable = "linearizable" // Read once the node applied the commit index of the leader
+)
+
+const (
+	ReadIndexTimeoutMs      = 5000 // The number of milliseconds a read waits on the read index of the leader to be applied
+	ReadIndexPollIntervalMs = 5    // The number of milliseconds between checks of the applied index while waiting on a read index
+)
+
 const APIV1Base = "/api/v1"
@@ -2,10 +2,20 @@ package headers
 
 // These constants define the keys for headers used in API requests.
 const (
-	APIKeyHeader      = "x-api-key"    // The API key used for authentication
-	SecretKeyHeader   = "x-secret-key" // The secret key used for authentication
-	PeerHeader        = "x-peer"       // Information about the requesting peer
-	PeerAddressHeader = "peer-address" // The address of the requesting peer
+	APIKeyHeader             = "x-api-key"           // The API key used for authentication
+	SecretKeyHeader          = "x-secret-key"        // The secret key used for authentication
+	PeerHeader               = "x-peer"              // Information about the requesting peer
+	PeerAddressHeader        = "peer-address"        // The address of the requesting peer
+	IdempotencyKeyHeader     = "Idempotency-Key"     // Client supplied key that makes a create request safe to retry
+	IdempotentReplayedHeader = "Idempotent-Replayed" // Set on responses that replay the result of an earlier request
+	RequestIDHeader          = "X-Request-Id"        // Id of the request, added to the log lines of the request
+	LastEventIDHeader        = "Last-Event-ID"       // Id of the last event received by a client resuming the event stream
+	ForwardedByHeader        = "X-Forwarded-By-Node" // Id of the follower that forwarded a client write to the leader
+	ServedByHeader           = "X-Served-By-Node"    // Id of the node that served the request
+	ReadConsistencyHeader    = "X-Read-Consistency"  // Consistency level of a read, stale, leader or linearizable
+	MaxStalenessHeader       = "X-Max-Staleness"     // Longest time since a follower last heard from the leader for a stale read, such as 5s
+	ETagHeader               = "ETag"                // Version of the job, project or credential returned by a request
+	IfMatchHeader            = "If-Match"            // ETag an update or delete expects the job, project or credential to still have
 )
 
 // These constants define the values for the PeerHeader key.
@@ -170,6 +170,11 @@ func CreateConnectionFromNewDbIfNonExists(logger hclog.Logger) DataStore {
 		logger.Error("ping error: failed to create file db: %v", err)
 	}
 
+	// The db file may have been written by an older version
+	if err := MigrateSchema(dbConnection); err != nil {
+		log.Fatalln(fmt.Errorf("Fatal failed to migrate db: %s \n", err))
+	}
+
 	return sqliteDb
 }
 
@@ -192,7 +197,15 @@ CREATE TABLE IF NOT EXISTS credentials
     archived                         boolean   NOT NULL,
     api_key                          TEXT,
     api_secret                       TEXT,
-    date_created                     datetime NOT NULL
+    project_ids                      TEXT     NOT NULL DEFAULT '[]',
+    permissions                      TEXT     NOT NULL DEFAULT '[]',
+    expires_at                       datetime,
+    previous_api_secret              TEXT     NOT NULL DEFAULT '',
+    previous_api_secret_expires_at   datetime,
+    last_used_at                     datetime,
+    last_used_from                   TEXT     NOT NULL DEFAULT '',
+    date_created                     datetime NOT NULL,
+    version                          INTEGER  NOT NULL DEFAULT 1
 );
 
 CREATE TABLE IF NOT EXISTS projects
@@ -200,7 +213,8 @@ CREATE TABLE IF NOT EXISTS projects
     id           INTEGER PRIMARY KEY AUTOINCREMENT,
     name         TEXT      NOT NULL UNIQUE,
     description  TEXT      NOT NULL,
-    date_created datetime NOT NULL
+    date_created datetime NOT NULL,
+    version      INTEGER   NOT NULL DEFAULT 1
 );
 
 CREATE TABLE IF NOT EXISTS jobs
@@ -209,16 +223,81 @@ CREATE TABLE IF NOT EXISTS jobs
     project_id     INTEGER   NOT NULL,
     spec           TEXT      NOT NULL,
     data           TEXT,
+    data_key_id    TEXT,
     callback_url   TEXT      NOT NULL,
     execution_type TEXT      NOT NULL DEFAULT "http",
     date_created   datetime NOT NULL,
 	timezone 	   TEXT NOT NULL,
 	timezone_offset INTEGER NOT NULL,
+    status         TEXT      NOT NULL DEFAULT "active",
+    external_key   TEXT,
+    version        INTEGER   NOT NULL DEFAULT 1,
     FOREIGN KEY (project_id)
         REFERENCES projects (id)
         ON DELETE CASCADE
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_project_id_external_key ON jobs (project_id, external_key);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    credential_id   INTEGER  NOT NULL DEFAULT 0,
+    idempotency_key TEXT     NOT NULL,
+    request_id      TEXT     NOT NULL,
+    request_hash    TEXT     NOT NULL,
+    task_id         INTEGER,
+    date_created    datetime NOT NULL,
+    PRIMARY KEY (credential_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_date_created ON idempotency_keys (date_created);
+
+CREATE TABLE IF NOT EXISTS job_labels
+(
+    job_id         INTEGER NOT NULL,
+    label_key      TEXT    NOT NULL,
+    label_value    TEXT    NOT NULL,
+    PRIMARY KEY (job_id, label_key),
+    FOREIGN KEY (job_id)
+        REFERENCES jobs (id)
+        ON DELETE CASCADE
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS job_labels_key_value ON job_labels (label_key, label_value);
+
+CREATE TABLE IF NOT EXISTS job_metadata
+(
+    job_id         INTEGER PRIMARY KEY,
+    metadata       TEXT    NOT NULL,
+    FOREIGN KEY (job_id)
+        REFERENCES jobs (id)
+        ON DELETE CASCADE
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS job_revisions
+(
+    job_id         INTEGER  NOT NULL,
+    revision       INTEGER  NOT NULL,
+    action         TEXT     NOT NULL,
+    snapshot       TEXT     NOT NULL,
+    credential_id  INTEGER,
+    date_created   datetime NOT NULL,
+    PRIMARY KEY (job_id, revision)
+) WITHOUT ROWID;
+
+-- Revisions recorded by the raft command being applied, read back and cleared in the same transaction to publish job events
+CREATE TABLE IF NOT EXISTS job_revision_events
+(
+    job_id         INTEGER  NOT NULL,
+    revision       INTEGER  NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS job_revisions_events
+AFTER INSERT ON job_revisions
+BEGIN
+    INSERT INTO job_revision_events (job_id, revision) VALUES (NEW.job_id, NEW.revision);
+END;
+
 CREATE TABLE IF NOT EXISTS job_executions_committed
 (
 	id						INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -231,11 +310,163 @@ CREATE TABLE IF NOT EXISTS job_executions_committed
     date_created   			datetime NOT NULL,
 	job_queue_version 		INTEGER NOT NULL,
 	execution_version 		INTEGER NOT NULL,
+	error					TEXT,
+	latency_ms				INTEGER,
     FOREIGN KEY (job_id)
         REFERENCES jobs (id)
         ON DELETE CASCADE
 );
 
+-- Execution logs committed by the raft command being applied, read back and cleared in the same transaction to publish execution events
+CREATE TABLE IF NOT EXISTS job_execution_events
+(
+    execution_id   INTEGER  NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS job_executions_committed_events
+AFTER INSERT ON job_executions_committed
+BEGIN
+    INSERT INTO job_execution_events (execution_id) VALUES (NEW.id);
+END;
+
+CREATE TABLE IF NOT EXISTS alert_rules
+(
+	id						INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id				INTEGER NOT NULL,
+	type					TEXT NOT NULL,
+	threshold				INTEGER NOT NULL,
+	period_seconds			INTEGER NOT NULL,
+	webhook_url				TEXT NOT NULL,
+	date_created			datetime NOT NULL,
+    FOREIGN KEY (project_id)
+        REFERENCES projects (id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS firing_alerts
+(
+	rule_id					INTEGER NOT NULL,
+	job_id					INTEGER NOT NULL,
+	date_created			datetime NOT NULL,
+	PRIMARY KEY (rule_id, job_id),
+    FOREIGN KEY (rule_id)
+        REFERENCES alert_rules (id)
+        ON DELETE CASCADE
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS job_execution_daily_counts
+(
+	job_id					INTEGER NOT NULL,
+	day						TEXT NOT NULL,
+	state					INTEGER NOT NULL,
+	count					INTEGER NOT NULL,
+	PRIMARY KEY (job_id, day, state)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS job_execution_stats
+(
+	job_id					INTEGER NOT NULL,
+	project_id				INTEGER NOT NULL,
+	hour					TEXT NOT NULL,
+	success_count			INTEGER NOT NULL,
+	failed_count			INTEGER NOT NULL,
+	latency_max_ms			INTEGER NOT NULL,
+	PRIMARY KEY (job_id, hour)
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS job_execution_stats_project_id_hour ON job_execution_stats (project_id, hour);
+
+CREATE TABLE IF NOT EXISTS job_execution_latency_histogram
+(
+	job_id					INTEGER NOT NULL,
+	project_id				INTEGER NOT NULL,
+	hour					TEXT NOT NULL,
+	bucket_ms				INTEGER NOT NULL,
+	count					INTEGER NOT NULL,
+	PRIMARY KEY (job_id, hour, bucket_ms)
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS job_execution_latency_histogram_project_id_hour ON job_execution_latency_histogram (project_id, hour);
+
+CREATE TABLE IF NOT EXISTS job_execution_summaries
+(
+	job_id					INTEGER PRIMARY KEY,
+	project_id				INTEGER NOT NULL,
+	last_success			datetime,
+	last_failure			datetime,
+	consecutive_failures	INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS job_execution_summaries_project_id ON job_execution_summaries (project_id);
+
+-- The execution stats counters are maintained as committed execution logs are applied, so every node keeps the same counters
+CREATE TRIGGER IF NOT EXISTS job_executions_committed_stats
+AFTER INSERT ON job_executions_committed
+WHEN NEW.state IN (1, 2)
+BEGIN
+	INSERT INTO job_execution_stats (job_id, project_id, hour, success_count, failed_count, latency_max_ms)
+	VALUES (
+		NEW.job_id,
+		IFNULL((SELECT project_id FROM jobs WHERE id = NEW.job_id), 0),
+		strftime('%Y-%m-%dT%H:00:00Z', NEW.date_created),
+		NEW.state = 1,
+		NEW.state = 2,
+		IFNULL(NEW.latency_ms, 0)
+	)
+	ON CONFLICT (job_id, hour) DO UPDATE SET
+		success_count = success_count + excluded.success_count,
+		failed_count = failed_count + excluded.failed_count,
+		latency_max_ms = max(latency_max_ms, excluded.latency_max_ms);
+
+	INSERT INTO job_execution_latency_histogram (job_id, project_id, hour, bucket_ms, count)
+	SELECT
+		NEW.job_id,
+		IFNULL((SELECT project_id FROM jobs WHERE id = NEW.job_id), 0),
+		strftime('%Y-%m-%dT%H:00:00Z', NEW.date_created),
+		CASE
+			WHEN NEW.latency_ms <= 10 THEN 10
+			WHEN NEW.latency_ms <= 25 THEN 25
+			WHEN NEW.latency_ms <= 50 THEN 50
+			WHEN NEW.latency_ms <= 100 THEN 100
+			WHEN NEW.latency_ms <= 250 THEN 250
+			WHEN NEW.latency_ms <= 500 THEN 500
+			WHEN NEW.latency_ms <= 1000 THEN 1000
+			WHEN NEW.latency_ms <= 2500 THEN 2500
+			WHEN NEW.latency_ms <= 5000 THEN 5000
+			WHEN NEW.latency_ms <= 10000 THEN 10000
+			WHEN NEW.latency_ms <= 30000 THEN 30000
+			WHEN NEW.latency_ms <= 60000 THEN 60000
+			WHEN NEW.latency_ms <= 300000 THEN 300000
+			ELSE 9223372036854775807
+		END,
+		1
+	WHERE NEW.latency_ms IS NOT NULL
+	ON CONFLICT (job_id, hour, bucket_ms) DO UPDATE SET count = count + 1;
+
+	INSERT INTO job_execution_summaries (job_id, project_id, last_success, last_failure, consecutive_failures)
+	VALUES (
+		NEW.job_id,
+		IFNULL((SELECT project_id FROM jobs WHERE id = NEW.job_id), 0),
+		CASE WHEN NEW.state = 1 THEN NEW.date_created END,
+		CASE WHEN NEW.state = 2 THEN NEW.date_created END,
+		NEW.state = 2
+	)
+	ON CONFLICT (job_id) DO UPDATE SET
+		consecutive_failures = CASE
+			WHEN excluded.last_failure IS NOT NULL AND (last_success IS NULL OR julianday(excluded.last_failure) > julianday(last_success)) THEN consecutive_failures + 1
+			WHEN excluded.last_success IS NOT NULL AND (last_failure IS NULL OR julianday(excluded.last_success) > julianday(last_failure)) THEN 0
+			ELSE consecutive_failures
+		END,
+		last_success = CASE
+			WHEN last_success IS NULL OR julianday(excluded.last_success) > julianday(last_success) THEN IFNULL(excluded.last_success, last_success)
+			ELSE last_success
+		END,
+		last_failure = CASE
+			WHEN last_failure IS NULL OR julianday(excluded.last_failure) > julianday(last_failure) THEN IFNULL(excluded.last_failure, last_failure)
+			ELSE last_failure
+		END;
+END;
+
 CREATE TABLE IF NOT EXISTS job_executions_uncommitted
 (
 	id						INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -248,6 +479,8 @@ CREATE TABLE IF NOT EXISTS job_executions_uncommitted
     date_created   			datetime NOT NULL,
 	job_queue_version 		INTEGER NOT NULL,
 	execution_version 		INTEGER NOT NULL,
+	error					TEXT,
+	latency_ms				INTEGER,
     FOREIGN KEY (job_id)
         REFERENCES jobs (id)
         ON DELETE CASCADE
@@ -278,7 +511,8 @@ CREATE TABLE IF NOT EXISTS async_tasks_committed
 	output  				TEXT,
 	state					INTEGER NOT NULL,
 	service					TEXT NOT NULL,
-    date_created  		 	datetime NOT NULL
+    date_created  		 	datetime NOT NULL,
+	project_ids				TEXT NOT NULL DEFAULT '[]'
 );
 
 CREATE TABLE IF NOT EXISTS async_tasks_uncommitted
@@ -289,8 +523,149 @@ CREATE TABLE IF NOT EXISTS async_tasks_uncommitted
 	output  				TEXT,
 	state					INTEGER NOT NULL,
 	service					TEXT NOT NULL,
-    date_created  		 	datetime NOT NULL
+    date_created  		 	datetime NOT NULL,
+	project_ids				TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    action         TEXT     NOT NULL,
+    resource       TEXT     NOT NULL,
+    resource_id    INTEGER  NOT NULL,
+    credential_id  INTEGER,
+    peer           boolean  NOT NULL,
+    request_id     TEXT     NOT NULL,
+    source_ip      TEXT     NOT NULL,
+    before_state   TEXT,
+    after_state    TEXT,
+    date_created   datetime NOT NULL
 );
+
+CREATE INDEX IF NOT EXISTS audit_events_resource ON audit_events (resource, resource_id);
+CREATE INDEX IF NOT EXISTS audit_events_date_created ON audit_events (date_created);
+
+-- Who makes the changes of the raft command being applied, set at its start and cleared at its end.
+-- The audit triggers record the changes made while it is set, changes the nodes make on their own are not audited.
+CREATE TABLE IF NOT EXISTS audit_context
+(
+    id             INTEGER PRIMARY KEY CHECK (id = 1),
+    action         TEXT,
+    credential_id  INTEGER,
+    peer           boolean  NOT NULL,
+    request_id     TEXT     NOT NULL,
+    source_ip      TEXT     NOT NULL,
+    date_created   datetime NOT NULL
+) WITHOUT ROWID;
+
+CREATE TRIGGER IF NOT EXISTS audit_credentials_create
+AFTER INSERT ON credentials
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'create'), 'credential', NEW.id, credential_id, peer, request_id, source_ip,
+        NULL,
+        json_object('id', NEW.id, 'archived', NEW.archived, 'apiKey', NEW.api_key, 'projectIds', json(NEW.project_ids), 'permissions', json(NEW.permissions), 'expiresAt', NEW.expires_at, 'previousApiSecretExpiresAt', NEW.previous_api_secret_expires_at, 'version', NEW.version),
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_credentials_update
+AFTER UPDATE ON credentials
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'update'), 'credential', NEW.id, credential_id, peer, request_id, source_ip,
+        json_object('id', OLD.id, 'archived', OLD.archived, 'apiKey', OLD.api_key, 'projectIds', json(OLD.project_ids), 'permissions', json(OLD.permissions), 'expiresAt', OLD.expires_at, 'previousApiSecretExpiresAt', OLD.previous_api_secret_expires_at, 'version', OLD.version),
+        json_object('id', NEW.id, 'archived', NEW.archived, 'apiKey', NEW.api_key, 'projectIds', json(NEW.project_ids), 'permissions', json(NEW.permissions), 'expiresAt', NEW.expires_at, 'previousApiSecretExpiresAt', NEW.previous_api_secret_expires_at, 'version', NEW.version),
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_credentials_delete
+AFTER DELETE ON credentials
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'delete'), 'credential', OLD.id, credential_id, peer, request_id, source_ip,
+        json_object('id', OLD.id, 'archived', OLD.archived, 'apiKey', OLD.api_key, 'projectIds', json(OLD.project_ids), 'permissions', json(OLD.permissions), 'expiresAt', OLD.expires_at, 'previousApiSecretExpiresAt', OLD.previous_api_secret_expires_at, 'version', OLD.version),
+        NULL,
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_projects_create
+AFTER INSERT ON projects
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'create'), 'project', NEW.id, credential_id, peer, request_id, source_ip,
+        NULL,
+        json_object('id', NEW.id, 'name', NEW.name, 'description', NEW.description, 'version', NEW.version),
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_projects_update
+AFTER UPDATE ON projects
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'update'), 'project', NEW.id, credential_id, peer, request_id, source_ip,
+        json_object('id', OLD.id, 'name', OLD.name, 'description', OLD.description, 'version', OLD.version),
+        json_object('id', NEW.id, 'name', NEW.name, 'description', NEW.description, 'version', NEW.version),
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_projects_delete
+AFTER DELETE ON projects
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'delete'), 'project', OLD.id, credential_id, peer, request_id, source_ip,
+        json_object('id', OLD.id, 'name', OLD.name, 'description', OLD.description, 'version', OLD.version),
+        NULL,
+        date_created
+    FROM audit_context;
+END;
+
+-- The job states leave out the data, which the job revisions keep for rollbacks
+CREATE TRIGGER IF NOT EXISTS audit_jobs_create
+AFTER INSERT ON jobs
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'create'), 'job', NEW.id, credential_id, peer, request_id, source_ip,
+        NULL,
+        json_object('id', NEW.id, 'projectId', NEW.project_id, 'spec', NEW.spec, 'callbackUrl', NEW.callback_url, 'dataKeyId', NEW.data_key_id, 'executionType', NEW.execution_type, 'status', NEW.status, 'externalKey', NEW.external_key, 'timezone', NEW.timezone, 'timezoneOffset', NEW.timezone_offset, 'version', NEW.version),
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_jobs_update
+AFTER UPDATE ON jobs
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'update'), 'job', NEW.id, credential_id, peer, request_id, source_ip,
+        json_object('id', OLD.id, 'projectId', OLD.project_id, 'spec', OLD.spec, 'callbackUrl', OLD.callback_url, 'dataKeyId', OLD.data_key_id, 'executionType', OLD.execution_type, 'status', OLD.status, 'externalKey', OLD.external_key, 'timezone', OLD.timezone, 'timezoneOffset', OLD.timezone_offset, 'version', OLD.version),
+        json_object('id', NEW.id, 'projectId', NEW.project_id, 'spec', NEW.spec, 'callbackUrl', NEW.callback_url, 'dataKeyId', NEW.data_key_id, 'executionType', NEW.execution_type, 'status', NEW.status, 'externalKey', NEW.external_key, 'timezone', NEW.timezone, 'timezoneOffset', NEW.timezone_offset, 'version', NEW.version),
+        date_created
+    FROM audit_context;
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_jobs_delete
+AFTER DELETE ON jobs
+WHEN EXISTS (SELECT 1 FROM audit_context)
+BEGIN
+    INSERT INTO audit_events (action, resource, resource_id, credential_id, peer, request_id, source_ip, before_state, after_state, date_created)
+    SELECT IFNULL(action, 'delete'), 'job', OLD.id, credential_id, peer, request_id, source_ip,
+        json_object('id', OLD.id, 'projectId', OLD.project_id, 'spec', OLD.spec, 'callbackUrl', OLD.callback_url, 'dataKeyId', OLD.data_key_id, 'executionType', OLD.execution_type, 'status', OLD.status, 'externalKey', OLD.external_key, 'timezone', OLD.timezone, 'timezoneOffset', OLD.timezone_offset, 'version', OLD.version),
+        NULL,
+        date_created
+    FROM audit_context;
+END;
 `
 }
 
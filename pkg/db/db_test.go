@@ -2,8 +2,10 @@ package db
 
 import (
 	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
+	"scheduler0/pkg/constants"
 	"scheduler0/pkg/utils"
 	"testing"
 )
@@ -82,3 +84,139 @@ func TestGetDBConnection(t *testing.T) {
 
 	utils.RemoveSqliteDbDir()
 }
+
+// olderSchemaSQL the tables as the first release created them, with the idempotency keys of a later release
+const olderSchemaSQL = `
+CREATE TABLE credentials
+(
+    id                               INTEGER PRIMARY KEY AUTOINCREMENT,
+    archived                         boolean   NOT NULL,
+    api_key                          TEXT,
+    api_secret                       TEXT,
+    date_created                     datetime NOT NULL
+);
+
+CREATE TABLE projects
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    name         TEXT      NOT NULL UNIQUE,
+    description  TEXT      NOT NULL,
+    date_created datetime NOT NULL
+);
+
+CREATE TABLE jobs
+(
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id     INTEGER   NOT NULL,
+    spec           TEXT      NOT NULL,
+    data           TEXT,
+    callback_url   TEXT      NOT NULL,
+    execution_type TEXT      NOT NULL DEFAULT "http",
+    date_created   datetime NOT NULL,
+    timezone       TEXT NOT NULL,
+    timezone_offset INTEGER NOT NULL,
+    FOREIGN KEY (project_id)
+        REFERENCES projects (id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE async_tasks_committed
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    request_id    TEXT NOT NULL,
+    input         TEXT NOT NULL,
+    output        TEXT,
+    state         INTEGER NOT NULL,
+    service       TEXT NOT NULL,
+    date_created  datetime NOT NULL
+);
+
+CREATE TABLE idempotency_keys
+(
+    idempotency_key TEXT PRIMARY KEY,
+    request_id      TEXT     NOT NULL,
+    request_hash    TEXT     NOT NULL,
+    task_id         INTEGER,
+    date_created    datetime NOT NULL
+);
+
+CREATE INDEX idempotency_keys_date_created ON idempotency_keys (date_created);
+
+INSERT INTO projects (name, description, date_created) VALUES ('project', 'description', '2023-01-01 00:00:00');
+INSERT INTO jobs (project_id, spec, callback_url, date_created, timezone, timezone_offset)
+VALUES (1, '@every 1m', 'http://localhost', '2023-01-01 00:00:00', 'UTC', 0);
+INSERT INTO idempotency_keys (idempotency_key, request_id, request_hash, date_created) VALUES ('key', 'request', 'hash', '2023-01-01 00:00:00');
+`
+
+func TestMigrateSchema(t *testing.T) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "db-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	sqliteDb := NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.OpenConnectionToExistingDB()
+	connection := sqliteDb.GetOpenConnection()
+	if _, err := connection.Exec(olderSchemaSQL); err != nil {
+		t.Fatalf("Failed to create older schema: %v", err)
+	}
+
+	// Migrating twice leaves the schema as the first migration did
+	for i := 0; i < 2; i++ {
+		if err := MigrateSchema(connection); err != nil {
+			t.Fatalf("Failed to migrate schema: %v", err)
+		}
+	}
+
+	var status string
+	var version uint64
+	if err := connection.QueryRow("SELECT status, version FROM jobs WHERE id = 1").Scan(&status, &version); err != nil {
+		t.Fatalf("Failed to read migrated job: %v", err)
+	}
+	assert.Equal(t, "active", status)
+	assert.Equal(t, uint64(1), version)
+
+	_, err = connection.Exec("SELECT project_ids, permissions, expires_at, last_used_at, version FROM credentials")
+	assert.Nil(t, err)
+	_, err = connection.Exec("SELECT project_ids FROM async_tasks_committed")
+	assert.Nil(t, err)
+
+	// The idempotency keys are copied into the table created again with their credential, as keys of every credential
+	var keys int
+	if err := connection.QueryRow("SELECT count(*) FROM idempotency_keys WHERE credential_id = ? AND idempotency_key = 'key' AND request_hash = 'hash'",
+		constants.UnscopedIdempotencyKeyCredentialId).Scan(&keys); err != nil {
+		t.Fatalf("Failed to read migrated idempotency keys: %v", err)
+	}
+	assert.Equal(t, 1, keys)
+	var indexes int
+	if err := connection.QueryRow("SELECT count(*) FROM sqlite_master WHERE name = 'idempotency_keys_date_created' AND tbl_name = 'idempotency_keys'").Scan(&indexes); err != nil {
+		t.Fatalf("Failed to read the indexes of migrated idempotency keys: %v", err)
+	}
+	assert.Equal(t, 1, indexes)
+
+	// Tables added after the older schema are created
+	_, err = connection.Exec("INSERT INTO job_labels (job_id, label_key, label_value) VALUES (1, 'team', 'core')")
+	assert.Nil(t, err)
+
+	// The data of jobs is removed from the audit events recorded by older versions
+	_, err = connection.Exec(`INSERT INTO audit_events (action, resource, resource_id, peer, request_id, source_ip, before_state, after_state, date_created)
+		VALUES ('update', 'job', 1, false, 'request', '127.0.0.1', '{"id":1,"data":"old"}', '{"id":1,"data":"new","dataKeyId":"2026-10"}', '2023-01-01 00:00:00')`)
+	if err != nil {
+		t.Fatalf("Failed to insert audit event: %v", err)
+	}
+	if err := MigrateSchema(connection); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	var beforeState, afterState string
+	if err := connection.QueryRow("SELECT before_state, after_state FROM audit_events WHERE resource = 'job'").Scan(&beforeState, &afterState); err != nil {
+		t.Fatalf("Failed to read migrated audit event: %v", err)
+	}
+	assert.Equal(t, `{"id":1}`, beforeState)
+	assert.Equal(t, `{"id":1,"dataKeyId":"2026-10"}`, afterState)
+}
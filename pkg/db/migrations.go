@@ -0,0 +1,213 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"scheduler0/pkg/constants"
+	"sort"
+	"strings"
+)
+
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations the columns added to the tables after they were first released, in the order they were added.
+// The defaults are the values the rows written by older versions get.
+var columnMigrations = []columnMigration{
+	{table: "jobs", column: "status", definition: "TEXT NOT NULL DEFAULT 'active'"},
+	{table: "jobs", column: "external_key", definition: "TEXT"},
+	{table: "job_executions_committed", column: "error", definition: "TEXT"},
+	{table: "job_executions_uncommitted", column: "error", definition: "TEXT"},
+	{table: "job_executions_committed", column: "latency_ms", definition: "INTEGER"},
+	{table: "job_executions_uncommitted", column: "latency_ms", definition: "INTEGER"},
+	{table: "credentials", column: "version", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "projects", column: "version", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "jobs", column: "version", definition: "INTEGER NOT NULL DEFAULT 1"},
+	{table: "credentials", column: "project_ids", definition: "TEXT NOT NULL DEFAULT '[]'"},
+	{table: "credentials", column: "permissions", definition: "TEXT NOT NULL DEFAULT '[]'"},
+	{table: "credentials", column: "expires_at", definition: "datetime"},
+	{table: "credentials", column: "previous_api_secret", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "credentials", column: "previous_api_secret_expires_at", definition: "datetime"},
+	{table: "credentials", column: "last_used_at", definition: "datetime"},
+	{table: "credentials", column: "last_used_from", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "jobs", column: "data_key_id", definition: "TEXT"},
+	{table: "async_tasks_committed", column: "project_ids", definition: "TEXT NOT NULL DEFAULT '[]'"},
+	{table: "async_tasks_uncommitted", column: "project_ids", definition: "TEXT NOT NULL DEFAULT '[]'"},
+}
+
+type recreatedTable struct {
+	table  string
+	column string
+	value  interface{}
+}
+
+// recreatedTables the tables whose primary key changed, which sqlite cannot alter, with the column that tells the
+// current version of the table apart. They are created again by the setup sql and their rows copied over, with the
+// value in the column and the defaults of the other new columns.
+var recreatedTables = []recreatedTable{
+	{table: "idempotency_keys", column: "credential_id", value: constants.UnscopedIdempotencyKeyCredentialId},
+}
+
+// MigrateSchema brings the schema of a database written by an older version up to date, and does nothing on a current one.
+// The missing columns are added to the existing tables, then the setup sql creates the missing tables and indexes.
+// The triggers are dropped and created again, so they always have their latest definitions.
+func MigrateSchema(connection *sql.DB) error {
+	trx, err := connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to open migration transaction: %v", err)
+	}
+
+	if err := migrateSchema(trx); err != nil {
+		if rollbackErr := trx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("failed to roll back migration: %v, after: %v", rollbackErr, err)
+		}
+		return err
+	}
+
+	if err := trx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %v", err)
+	}
+	return nil
+}
+
+func migrateSchema(trx *sql.Tx) error {
+	previousTables := map[recreatedTable]map[string]bool{}
+	for _, recreated := range recreatedTables {
+		columns, err := tableColumns(trx, recreated.table)
+		if err != nil {
+			return err
+		}
+		if len(columns) > 0 && !columns[recreated.column] {
+			if err := renameToPrevious(trx, recreated.table); err != nil {
+				return err
+			}
+			previousTables[recreated] = columns
+		}
+	}
+
+	for _, migration := range columnMigrations {
+		columns, err := tableColumns(trx, migration.table)
+		if err != nil {
+			return err
+		}
+		// Tables that do not exist yet are created by the setup sql with all their columns
+		if len(columns) == 0 || columns[migration.column] {
+			continue
+		}
+		if _, err := trx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", migration.table, migration.column, migration.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s to %s: %v", migration.column, migration.table, err)
+		}
+	}
+
+	triggers, err := trx.Query("SELECT name FROM sqlite_master WHERE type = 'trigger'")
+	if err != nil {
+		return fmt.Errorf("failed to list triggers: %v", err)
+	}
+	var triggerNames []string
+	for triggers.Next() {
+		var name string
+		if err := triggers.Scan(&name); err != nil {
+			triggers.Close()
+			return fmt.Errorf("failed to list triggers: %v", err)
+		}
+		triggerNames = append(triggerNames, name)
+	}
+	triggers.Close()
+	for _, name := range triggerNames {
+		if _, err := trx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name)); err != nil {
+			return fmt.Errorf("failed to drop trigger %s: %v", name, err)
+		}
+	}
+
+	if _, err := trx.Exec(GetSetupSQL()); err != nil {
+		return fmt.Errorf("failed to run setup sql: %v", err)
+	}
+
+	for recreated, columns := range previousTables {
+		if err := copyFromPrevious(trx, recreated, columns); err != nil {
+			return err
+		}
+	}
+
+	// The audit events of jobs recorded by older versions hold their data
+	if _, err := trx.Exec("UPDATE audit_events SET before_state = json_remove(before_state, '$.data'), after_state = json_remove(after_state, '$.data') " +
+		"WHERE resource = 'job' AND (json_type(before_state, '$.data') IS NOT NULL OR json_type(after_state, '$.data') IS NOT NULL)"); err != nil {
+		return fmt.Errorf("failed to remove the data of jobs from the audit events: %v", err)
+	}
+	return nil
+}
+
+// renameToPrevious moves a table out of the way of the setup sql, along with its indexes
+func renameToPrevious(trx *sql.Tx, table string) error {
+	rows, err := trx.Query("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table)
+	if err != nil {
+		return fmt.Errorf("failed to list the indexes of %s: %v", table, err)
+	}
+	var indexNames []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list the indexes of %s: %v", table, err)
+		}
+		indexNames = append(indexNames, name)
+	}
+	rows.Close()
+	for _, name := range indexNames {
+		if _, err := trx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS %s", name)); err != nil {
+			return fmt.Errorf("failed to drop index %s: %v", name, err)
+		}
+	}
+
+	if _, err := trx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s_previous", table, table)); err != nil {
+		return fmt.Errorf("failed to rename table %s: %v", table, err)
+	}
+	return nil
+}
+
+// copyFromPrevious copies the rows of the previous version of a table into the table the setup sql created, and drops it
+func copyFromPrevious(trx *sql.Tx, recreated recreatedTable, previousColumns map[string]bool) error {
+	columns, err := tableColumns(trx, recreated.table)
+	if err != nil {
+		return err
+	}
+	var copiedColumns []string
+	for column := range previousColumns {
+		if columns[column] {
+			copiedColumns = append(copiedColumns, column)
+		}
+	}
+	sort.Strings(copiedColumns)
+
+	copiedColumnsList := strings.Join(copiedColumns, ", ")
+	if _, err := trx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s) SELECT %s, ? FROM %s_previous",
+		recreated.table, copiedColumnsList, recreated.column, copiedColumnsList, recreated.table), recreated.value); err != nil {
+		return fmt.Errorf("failed to copy the rows of table %s: %v", recreated.table, err)
+	}
+	if _, err := trx.Exec(fmt.Sprintf("DROP TABLE %s_previous", recreated.table)); err != nil {
+		return fmt.Errorf("failed to drop the previous table %s: %v", recreated.table, err)
+	}
+	return nil
+}
+
+// tableColumns returns the columns of the table from PRAGMA table_info, none when the table does not exist
+func tableColumns(trx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := trx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read the columns of %s: %v", table, err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,110 @@
+// Package encryption encrypts the sensitive fields of jobs at rest with envelope encryption. Every value is encrypted
+// with a data key of its own, which is stored encrypted by a key encryption key of the keyring. Values record the id
+// of their key encryption key, so new keys can be rotated in while values encrypted by earlier keys are still read.
+package encryption
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/secrets"
+	"scheduler0/pkg/utils"
+)
+
+// Keyring holds the key encryption keys by id, and the id of the key encrypting new values
+type Keyring struct {
+	activeKeyID string
+	keys        map[string]string
+}
+
+// NewKeyring returns the keyring of the hex encoded AES-256 keys. Values are not encrypted when activeKeyID is empty,
+// values encrypted before are still decrypted with their key.
+func NewKeyring(activeKeyID string, keys map[string]string) (*Keyring, error) {
+	for keyID, key := range keys {
+		decoded, err := hex.DecodeString(key)
+		if err != nil || len(decoded) != 32 {
+			return nil, fmt.Errorf("data encryption key %s should be a hex encoded 32 bytes key", keyID)
+		}
+	}
+	if _, ok := keys[activeKeyID]; activeKeyID != "" && !ok {
+		return nil, fmt.Errorf("data encryption key %s is not in the data encryption keys", activeKeyID)
+	}
+
+	return &Keyring{
+		activeKeyID: activeKeyID,
+		keys:        keys,
+	}, nil
+}
+
+// NewKeyringFromConfig returns the keyring of the data encryption keys of the secrets, or nil when there are none.
+// The keys of the secrets file are used before the keys of SCHEDULER0_DATA_ENCRYPTION_KEYS.
+func NewKeyringFromConfig(scheduler0Config config.Scheduler0Config, scheduler0Secrets secrets.Scheduler0Secrets) (*Keyring, error) {
+	activeKeyID := scheduler0Config.GetConfigurations().DataEncryptionKeyId
+	keys := scheduler0Secrets.GetSecrets().DataEncryptionKeys
+	if len(keys) < 1 {
+		parsedKeys, err := ParseKeys(scheduler0Secrets.GetSecrets().DataEncryptionKeysJSON)
+		if err != nil {
+			return nil, err
+		}
+		keys = parsedKeys
+	}
+	if activeKeyID == "" && len(keys) < 1 {
+		return nil, nil
+	}
+	return NewKeyring(activeKeyID, keys)
+}
+
+// ParseKeys returns the keys of the json object of key ids to hex encoded keys of SCHEDULER0_DATA_ENCRYPTION_KEYS, none when it is empty
+func ParseKeys(keysJSON string) (map[string]string, error) {
+	if keysJSON == "" {
+		return nil, nil
+	}
+	keys := map[string]string{}
+	if err := json.Unmarshal([]byte(keysJSON), &keys); err != nil {
+		return nil, fmt.Errorf("SCHEDULER0_DATA_ENCRYPTION_KEYS should be a json object of key ids to keys: %w", err)
+	}
+	return keys, nil
+}
+
+// ActiveKeyID returns the id of the key encrypting new values, empty when values are not encrypted
+func (keyring *Keyring) ActiveKeyID() string {
+	if keyring == nil {
+		return ""
+	}
+	return keyring.activeKeyID
+}
+
+// Seal returns the envelope of the plaintext and the id of the key encrypting it.
+// The plaintext is returned as is with an empty key id by a nil keyring, or one without an active key.
+func (keyring *Keyring) Seal(plaintext string) (string, string, error) {
+	if keyring == nil || keyring.activeKeyID == "" || plaintext == "" {
+		return plaintext, "", nil
+	}
+
+	envelope, err := utils.SealEnvelope(plaintext, keyring.keys[keyring.activeKeyID])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt with data encryption key %s: %w", keyring.activeKeyID, err)
+	}
+	return envelope, keyring.activeKeyID, nil
+}
+
+// Open returns the plaintext of a value sealed by the key with the key id. Values without a key id are not encrypted and returned as is.
+func (keyring *Keyring) Open(value string, keyID string) (string, error) {
+	if keyID == "" {
+		return value, nil
+	}
+	if keyring == nil {
+		return "", fmt.Errorf("data encryption key %s is not configured", keyID)
+	}
+	key, ok := keyring.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("data encryption key %s is not configured", keyID)
+	}
+
+	plaintext, err := utils.OpenEnvelope(value, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt with data encryption key %s: %w", keyID, err)
+	}
+	return plaintext, nil
+}
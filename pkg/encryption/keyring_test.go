@@ -0,0 +1,104 @@
+package encryption
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	testOldKey = "6368616e676520746869732070617373776f726420746f206120736563726574"
+	testNewKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
+func TestKeyring_SealOpen(t *testing.T) {
+	keyring, err := NewKeyring("old", map[string]string{"old": testOldKey})
+	assert.Nil(t, err)
+
+	envelope, keyID, err := keyring.Seal(`{"payload":"secret"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", keyID)
+	assert.NotContains(t, envelope, "secret")
+
+	plaintext, err := keyring.Open(envelope, keyID)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"payload":"secret"}`, plaintext)
+
+	// The same plaintext is encrypted with a new data key every time
+	otherEnvelope, _, err := keyring.Seal(`{"payload":"secret"}`)
+	assert.Nil(t, err)
+	assert.NotEqual(t, envelope, otherEnvelope)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKeyring, err := NewKeyring("old", map[string]string{"old": testOldKey})
+	assert.Nil(t, err)
+	envelope, keyID, err := oldKeyring.Seal("secret")
+	assert.Nil(t, err)
+
+	// Values of the previous key are still opened after a new key is made active
+	keyring, err := NewKeyring("new", map[string]string{"old": testOldKey, "new": testNewKey})
+	assert.Nil(t, err)
+	plaintext, err := keyring.Open(envelope, keyID)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	_, newKeyID, err := keyring.Seal("secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "new", newKeyID)
+
+	// Values of a removed key cannot be opened
+	withoutOldKey, err := NewKeyring("new", map[string]string{"new": testNewKey})
+	assert.Nil(t, err)
+	_, err = withoutOldKey.Open(envelope, keyID)
+	assert.NotNil(t, err)
+
+	// Values cannot be opened by another key with the same id
+	_, err = (&Keyring{keys: map[string]string{"old": testNewKey}}).Open(envelope, keyID)
+	assert.NotNil(t, err)
+}
+
+func TestKeyring_Plaintext(t *testing.T) {
+	var keyring *Keyring
+
+	value, keyID, err := keyring.Seal("data")
+	assert.Nil(t, err)
+	assert.Equal(t, "data", value)
+	assert.Equal(t, "", keyID)
+
+	value, err = keyring.Open("data", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "data", value)
+
+	_, err = keyring.Open("data", "old")
+	assert.NotNil(t, err)
+
+	decryptOnly, err := NewKeyring("", map[string]string{"old": testOldKey})
+	assert.Nil(t, err)
+	_, keyID, err = decryptOnly.Seal("data")
+	assert.Nil(t, err)
+	assert.Equal(t, "", keyID)
+}
+
+func TestNewKeyring_InvalidKeys(t *testing.T) {
+	_, err := NewKeyring("old", map[string]string{"old": "not-hex"})
+	assert.NotNil(t, err)
+
+	_, err = NewKeyring("old", map[string]string{"old": "0011"})
+	assert.NotNil(t, err)
+
+	_, err = NewKeyring("missing", map[string]string{"old": testOldKey})
+	assert.NotNil(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(`{"old": "` + testOldKey + `"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"old": testOldKey}, keys)
+
+	keys, err = ParseKeys("")
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseKeys("old=" + testOldKey)
+	assert.NotNil(t, err)
+}
@@ -2,17 +2,23 @@ package fsm
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"google.golang.org/protobuf/proto"
 	"net/http"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/db"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/protobuffs"
 	"scheduler0/pkg/shared_repo"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"time"
 )
@@ -20,6 +26,7 @@ import (
 //go:generate mockery --name Scheduler0RaftActions --output ./ --inpackage
 type Scheduler0RaftActions interface {
 	WriteCommandToRaftLog(
+		ctx context.Context,
 		rft *raft.Raft,
 		commandType constants.Command,
 		sqlString string,
@@ -48,12 +55,16 @@ func NewScheduler0RaftActions(sharedRepo shared_repo.SharedRepo, postProcessChan
 }
 
 func (_ *scheduler0RaftActions) WriteCommandToRaftLog(
+	ctx context.Context,
 	rft *raft.Raft,
 	commandType constants.Command,
 	sqlString string,
 	params []interface{},
 	nodeIds []uint64,
 	action constants.CommandAction) (*models.FSMResponse, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "raft.Apply", attribute.Int64("scheduler0.command_action", int64(action)))
+	defer span.End()
+
 	data, err := json.Marshal(params)
 	if err != nil {
 		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
@@ -65,6 +76,7 @@ func (_ *scheduler0RaftActions) WriteCommandToRaftLog(
 		Data:         data,
 		TargetNodes:  nodeIds,
 		TargetAction: uint64(action),
+		TraceParent:  tracing.TraceParent(ctx),
 	}
 
 	if err != nil {
@@ -76,12 +88,16 @@ func (_ *scheduler0RaftActions) WriteCommandToRaftLog(
 		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
+	start := time.Now()
 	af := rft.Apply(commandData, time.Duration(15)*time.Second).(raft.ApplyFuture)
-	if af.Error() != nil {
-		if errors.Is(af.Error(), raft.ErrNotLeader) {
+	applyErr := af.Error()
+	metrics.RaftApplyLatency.Observe(time.Since(start).Seconds())
+	if applyErr != nil {
+		span.SetStatus(codes.Error, applyErr.Error())
+		if errors.Is(applyErr, raft.ErrNotLeader) {
 			return nil, utils.HTTPGenericError(http.StatusInternalServerError, "server not raft leader")
 		}
-		return nil, utils.HTTPGenericError(http.StatusInternalServerError, af.Error().Error())
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, applyErr.Error())
 	}
 
 	if af.Response() != nil {
@@ -119,7 +135,7 @@ func (raftActions *scheduler0RaftActions) ApplyRaftLog(
 		}
 	}
 
-	result := dbExecute(logger, command, db)
+	result, jobRevisions, executionEvents := dbExecute(logger, command, db)
 
 	if raftActions.postProcessChannel != nil && !ignorePostProcessChannel && result.Error == "" {
 		switch command.TargetAction {
@@ -128,18 +144,40 @@ func (raftActions *scheduler0RaftActions) ApplyRaftLog(
 				Action:      constants.CommandActionQueueJob,
 				TargetNodes: command.TargetNodes,
 				Data:        result.Data,
+				TraceParent: command.TraceParent,
 			}
 		case uint64(constants.CommandActionCleanUncommittedExecutionLogs):
 			raftActions.postProcessChannel <- models.PostProcess{
-				Action:      constants.CommandActionCleanUncommittedExecutionLogs,
-				TargetNodes: command.TargetNodes,
-				Data:        result.Data,
+				Action:          constants.CommandActionCleanUncommittedExecutionLogs,
+				TargetNodes:     command.TargetNodes,
+				Data:            result.Data,
+				TraceParent:     command.TraceParent,
+				ExecutionEvents: executionEvents,
 			}
 		case uint64(constants.CommandActionCleanUncommittedAsyncTasksLogs):
 			raftActions.postProcessChannel <- models.PostProcess{
 				Action:      constants.CommandActionCleanUncommittedAsyncTasksLogs,
 				TargetNodes: command.TargetNodes,
 				Data:        result.Data,
+				TraceParent: command.TraceParent,
+			}
+		case uint64(constants.CommandActionRecordJobRevisions):
+			// Every job change records a revision, so the revisions name the jobs to refresh
+			jobIds := make([]uint64, 0, len(jobRevisions))
+			seenJobIds := map[uint64]bool{}
+			for _, jobRevision := range jobRevisions {
+				if !seenJobIds[jobRevision.JobID] {
+					seenJobIds[jobRevision.JobID] = true
+					jobIds = append(jobIds, jobRevision.JobID)
+				}
+			}
+			raftActions.postProcessChannel <- models.PostProcess{
+				Action:       constants.CommandActionRecordJobRevisions,
+				TargetNodes:  command.TargetNodes,
+				Data:         result.Data,
+				JobIds:       jobIds,
+				TraceParent:  command.TraceParent,
+				JobRevisions: jobRevisions,
 			}
 		}
 	}
@@ -147,7 +185,10 @@ func (raftActions *scheduler0RaftActions) ApplyRaftLog(
 	return result
 }
 
-func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore) models.FSMResponse {
+// dbExecute runs the sql of the command in a transaction, and returns the job revisions it recorded
+// when the command is marked with constants.CommandActionRecordJobRevisions, and the events of the
+// execution logs it committed when the command is marked with constants.CommandActionCleanUncommittedExecutionLogs
+func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore) (models.FSMResponse, []models.JobRevision, []models.Event) {
 	db.ConnectionLock()
 	defer db.ConnectionUnlock()
 
@@ -156,7 +197,7 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 	if err != nil {
 		return models.FSMResponse{
 			Error: err.Error(),
-		}
+		}, nil, nil
 	}
 	ctx := context.Background()
 
@@ -165,7 +206,7 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 		logger.Error("failed to execute sql command", "error", err.Error())
 		return models.FSMResponse{
 			Error: err.Error(),
-		}
+		}, nil, nil
 	}
 
 	exec, err := tx.Exec(command.Sql, params...)
@@ -175,19 +216,62 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 		if rollBackErr != nil {
 			return models.FSMResponse{
 				Error: err.Error(),
-			}
+			}, nil, nil
 		}
 		return models.FSMResponse{
 			Error: err.Error(),
+		}, nil, nil
+	}
+
+	var jobRevisions []models.JobRevision
+	if command.TargetAction == uint64(constants.CommandActionRecordJobRevisions) {
+		jobRevisions, err = takeJobRevisionEvents(ctx, tx)
+		if err != nil {
+			logger.Error("failed to read job revisions recorded by sql command", "error", err.Error())
+			rollBackErr := tx.Rollback()
+			if rollBackErr != nil {
+				logger.Error("failed to roll back transaction", "error", rollBackErr.Error())
+			}
+			return models.FSMResponse{
+				Error: err.Error(),
+			}, nil, nil
+		}
+	}
+
+	var executionEvents []models.Event
+	if command.TargetAction == uint64(constants.CommandActionCleanUncommittedExecutionLogs) {
+		executionEvents, err = takeJobExecutionEvents(ctx, tx)
+		if err != nil {
+			logger.Error("failed to read execution logs committed by sql command", "error", err.Error())
+			rollBackErr := tx.Rollback()
+			if rollBackErr != nil {
+				logger.Error("failed to roll back transaction", "error", rollBackErr.Error())
+			}
+			return models.FSMResponse{
+				Error: err.Error(),
+			}, nil, nil
 		}
 	}
 
+	// The actor a command sets only applies to its own changes, it is cleared in the transaction of every command
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", constants.AuditContextTableName))
+	if err != nil {
+		logger.Error("failed to clear the audit context of sql command", "error", err.Error())
+		rollBackErr := tx.Rollback()
+		if rollBackErr != nil {
+			logger.Error("failed to roll back transaction", "error", rollBackErr.Error())
+		}
+		return models.FSMResponse{
+			Error: err.Error(),
+		}, nil, nil
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		logger.Error("failed to commit transaction", "error", err.Error())
 		return models.FSMResponse{
 			Error: err.Error(),
-		}
+		}, nil, nil
 	}
 
 	lastInsertedId, err := exec.LastInsertId()
@@ -198,11 +282,11 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 			logger.Error("failed to roll back transaction", "error", rollBackErr.Error())
 			return models.FSMResponse{
 				Error: rollBackErr.Error(),
-			}
+			}, nil, nil
 		}
 		return models.FSMResponse{
 			Error: err.Error(),
-		}
+		}, nil, nil
 	}
 	rowsAffected, err := exec.RowsAffected()
 
@@ -214,11 +298,11 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 			logger.Error("failed to roll back transaction", "error", rollBackErr.Error())
 			return models.FSMResponse{
 				Error: rollBackErr.Error(),
-			}
+			}, nil, nil
 		}
 		return models.FSMResponse{
 			Error: err.Error(),
-		}
+		}, nil, nil
 	}
 
 	return models.FSMResponse{
@@ -227,7 +311,7 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 			RowsAffected:   rowsAffected,
 		},
 		Error: "",
-	}
+	}, jobRevisions, executionEvents
 }
 
 //func localDataCommit(logger hclog.Logger, command *protobuffs.Command, db db.DataStore, shardRepo shared_repo.SharedRepo) models.FSMResponse {
@@ -284,3 +368,113 @@ func dbExecute(logger hclog.Logger, command *protobuffs.Command, db db.DataStore
 //		Error: "",
 //	}
 //}
+
+// takeJobRevisionEvents returns the job revisions staged by the job_revisions trigger in the transaction, and clears them
+func takeJobRevisionEvents(ctx context.Context, tx *sql.Tx) ([]models.JobRevision, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		"SELECT r.%s, r.%s, r.%s, r.%s, IFNULL(r.%s, 0), r.%s FROM %s e JOIN %s r ON r.%s = e.%s AND r.%s = e.%s ORDER BY e.rowid",
+		constants.JobRevisionsJobIdColumn,
+		constants.JobRevisionsRevisionColumn,
+		constants.JobRevisionsActionColumn,
+		constants.JobRevisionsSnapshotColumn,
+		constants.JobRevisionsCredentialIdColumn,
+		constants.JobRevisionsDateCreatedColumn,
+		constants.JobRevisionEventsTableName,
+		constants.JobRevisionsTableName,
+		constants.JobRevisionsJobIdColumn, constants.JobRevisionEventsJobIdColumn,
+		constants.JobRevisionsRevisionColumn, constants.JobRevisionEventsRevisionColumn,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobRevisions := []models.JobRevision{}
+	for rows.Next() {
+		jobRevision := models.JobRevision{}
+		snapshot := ""
+		err = rows.Scan(
+			&jobRevision.JobID,
+			&jobRevision.Revision,
+			&jobRevision.Action,
+			&snapshot,
+			&jobRevision.CredentialID,
+			&jobRevision.DateCreated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(snapshot), &jobRevision.Job); err != nil {
+			return nil, err
+		}
+		jobRevisions = append(jobRevisions, jobRevision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", constants.JobRevisionEventsTableName))
+	if err != nil {
+		return nil, err
+	}
+
+	return jobRevisions, nil
+}
+
+// takeJobExecutionEvents returns the events of the execution logs staged by the job_executions_committed trigger
+// in the transaction, and clears them
+func takeJobExecutionEvents(ctx context.Context, tx *sql.Tx) ([]models.Event, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		"SELECT IFNULL(x.%s, ''), x.%s, x.%s, IFNULL(j.%s, 0), x.%s, IFNULL(x.%s, ''), IFNULL(x.%s, 0), x.%s FROM %s e JOIN %s x ON x.%s = e.%s LEFT JOIN %s j ON j.%s = x.%s ORDER BY e.rowid",
+		constants.ExecutionsUniqueIdColumn,
+		constants.ExecutionsStateColumn,
+		constants.ExecutionsJobIdColumn,
+		constants.JobsProjectIdColumn,
+		constants.ExecutionsLastExecutionTimeColumn,
+		constants.ExecutionsErrorColumn,
+		constants.ExecutionsLatencyMsColumn,
+		constants.ExecutionsDateCreatedColumn,
+		constants.JobExecutionEventsTableName,
+		constants.ExecutionsCommittedTableName,
+		constants.ExecutionsIdColumn, constants.JobExecutionEventsExecutionIdColumn,
+		constants.JobsTableName,
+		constants.JobsIdColumn, constants.ExecutionsJobIdColumn,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		event := models.Event{}
+		executionEvent := models.ExecutionEvent{}
+		state := models.JobExecutionLogState(0)
+		err = rows.Scan(
+			&executionEvent.ExecutionId,
+			&state,
+			&event.JobID,
+			&event.ProjectID,
+			&executionEvent.LastExecutionDatetime,
+			&executionEvent.Error,
+			&executionEvent.LatencyMs,
+			&event.DateCreated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		event.Type = models.ExecutionEventTypes[state]
+		event.Data = executionEvent
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", constants.JobExecutionEventsTableName))
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
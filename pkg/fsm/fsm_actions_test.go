@@ -1,6 +1,7 @@
 package fsm
 
 import (
+	"context"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
@@ -67,7 +68,7 @@ func Test_WriteCommandToRaftLog_Executes_SQL(t *testing.T) {
 		t.Fatalf("failed to create sql to insert into raft log %v", err)
 	}
 
-	res, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, params, nil, 0)
+	res, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, params, nil, 0)
 	if writeErr != nil {
 		t.Fatalf("failed to write to raft log %v", writeErr)
 	}
@@ -173,7 +174,7 @@ func Test_WriteCommandToRaftLog_PostProcessChannel(t *testing.T) {
 				t.Fatalf("failed to create sql to insert into raft log %v", err)
 			}
 
-			res, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{1}, test.CommandPostProcessActionType)
+			res, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{1}, test.CommandPostProcessActionType)
 			if writeErr != nil {
 				t.Fatalf("failed to write to raft log %v", writeErr)
 			}
@@ -219,3 +220,134 @@ func Test_WriteCommandToRaftLog_PostProcessChannel(t *testing.T) {
 		})
 	}
 }
+
+func Test_WriteCommandToRaftLog_ClearsAuditContext(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "fsm-actions-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+
+	scheduler0RaftActions := NewScheduler0RaftActions(sharedRepo, nil)
+
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, sharedRepo)
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	// The audit context is cleared whatever the sql that sets it looks like
+	query := "INSERT INTO AUDIT_CONTEXT (id, peer, request_id, source_ip, date_created) VALUES (1, 0, 'request-1', '10.0.0.1', ?);" +
+		"INSERT INTO projects (name, description, date_created) VALUES ('audited', 'description', ?);"
+	res, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, []interface{}{time.Now().UTC(), time.Now().UTC()}, nil, 0)
+	if writeErr != nil {
+		t.Fatalf("failed to write to raft log %v", writeErr)
+	}
+	assert.Equal(t, int64(1), res.Data.LastInsertedId)
+	assert.Equal(t, int64(1), res.Data.RowsAffected)
+
+	query = "INSERT INTO projects (name, description, date_created) VALUES ('not audited', 'description', ?);"
+	if _, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, []interface{}{time.Now().UTC()}, nil, 0); writeErr != nil {
+		t.Fatalf("failed to write to raft log %v", writeErr)
+	}
+
+	conn := sqliteDb.GetOpenConnection()
+	var contexts, events int
+	if err := conn.QueryRow(fmt.Sprintf("select count(*) from %s", constants.AuditContextTableName)).Scan(&contexts); err != nil {
+		t.Fatalf("failed to count audit contexts %v", err)
+	}
+	if err := conn.QueryRow(fmt.Sprintf("select count(*) from %s where %s = 'request-1'", constants.AuditEventsTableName, constants.AuditEventsRequestIdColumn)).Scan(&events); err != nil {
+		t.Fatalf("failed to count audit events %v", err)
+	}
+	assert.Equal(t, 0, contexts)
+	assert.Equal(t, 1, events)
+	if err := conn.QueryRow(fmt.Sprintf("select count(*) from %s", constants.AuditEventsTableName)).Scan(&events); err != nil {
+		t.Fatalf("failed to count audit events %v", err)
+	}
+	assert.Equal(t, 1, events)
+}
+
+func Test_WriteCommandToRaftLog_PostProcessChannel_ExecutionEvents(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "fsm-actions-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+
+	postProcessChannel := make(chan models.PostProcess, 1)
+	scheduler0RaftActions := NewScheduler0RaftActions(sharedRepo, postProcessChannel)
+
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, sharedRepo)
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	now := time.Now().UTC()
+	query := "INSERT INTO projects (name, description, date_created) VALUES ('project', 'description', ?);" +
+		"INSERT INTO jobs (project_id, spec, callback_url, date_created, timezone, timezone_offset) VALUES (1, '* * * * *', 'http://localhost', ?, 'UTC', 0);"
+	if _, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, []interface{}{now, now}, nil, constants.CommandActionQueueJob); writeErr != nil {
+		t.Fatalf("failed to write to raft log %v", writeErr)
+	}
+	<-postProcessChannel
+
+	query = fmt.Sprintf("INSERT INTO %s (unique_id, state, node_id, last_execution_time, next_execution_time, job_id, date_created, job_queue_version, execution_version, error, latency_ms) VALUES "+
+		"('execution-1', 1, 1, ?, ?, 1, ?, 1, 1, NULL, 12), ('execution-2', 2, 1, ?, ?, 1, ?, 1, 2, 'callback failed', 30);", constants.ExecutionsCommittedTableName)
+	params := []interface{}{now, now.Add(time.Minute), now, now, now.Add(time.Minute), now}
+	if _, writeErr := scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), scheduler0Store.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{1}, constants.CommandActionCleanUncommittedExecutionLogs); writeErr != nil {
+		t.Fatalf("failed to write to raft log %v", writeErr)
+	}
+
+	postProcess := <-postProcessChannel
+	assert.Equal(t, constants.CommandActionCleanUncommittedExecutionLogs, postProcess.Action)
+	assert.Equal(t, 2, len(postProcess.ExecutionEvents))
+	assert.Equal(t, models.EventTypeExecutionSucceeded, postProcess.ExecutionEvents[0].Type)
+	assert.Equal(t, uint64(1), postProcess.ExecutionEvents[0].ProjectID)
+	assert.Equal(t, uint64(1), postProcess.ExecutionEvents[0].JobID)
+	assert.Equal(t, "execution-1", postProcess.ExecutionEvents[0].Data.(models.ExecutionEvent).ExecutionId)
+	assert.Equal(t, uint64(12), postProcess.ExecutionEvents[0].Data.(models.ExecutionEvent).LatencyMs)
+	assert.Equal(t, models.EventTypeExecutionFailed, postProcess.ExecutionEvents[1].Type)
+	assert.Equal(t, "callback failed", postProcess.ExecutionEvents[1].Data.(models.ExecutionEvent).Error)
+
+	var staged int
+	if err := sqliteDb.GetOpenConnection().QueryRow(fmt.Sprintf("select count(*) from %s", constants.JobExecutionEventsTableName)).Scan(&staged); err != nil {
+		t.Fatalf("failed to count staged execution events %v", err)
+	}
+	assert.Equal(t, 0, staged)
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/hashicorp/raft"
 	"math"
 	"scheduler0/pkg/db"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/utils"
+	"time"
 )
 
 type fsmSnapshot struct {
@@ -21,6 +23,11 @@ func NewFSMSnapshot(db db.DataStore) *fsmSnapshot {
 }
 
 func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	start := time.Now()
+	defer func() {
+		metrics.SnapshotDuration.Observe(time.Since(start).Seconds())
+	}()
+
 	dbSerialized := s.db.Serialize()
 
 	err := func() error {
@@ -13,6 +13,7 @@ import (
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/db"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/shared_repo"
 	utils "scheduler0/pkg/utils"
 	"strconv"
@@ -128,6 +129,8 @@ func (s *store) ApplyBatch(logs []*raft.Log) []interface{} {
 	s.rwMtx.Lock()
 	defer s.rwMtx.Unlock()
 
+	metrics.FSMBatchSize.Observe(float64(len(logs)))
+
 	results := []interface{}{}
 
 	for _, l := range logs {
@@ -168,18 +171,23 @@ func (s *store) Restore(r io.ReadCloser) error {
 		}
 	}
 
-	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=1", filePath))
+	connection, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=1", filePath))
 	if err != nil {
 		return fmt.Errorf("restore failed to create db: %v", err)
 	}
 
-	err = db.Ping()
+	err = connection.Ping()
 	if err != nil {
 		return fmt.Errorf("ping error: restore failed to create db: %v", err)
 	}
 
+	// The snapshot may have been taken by a node running an older version
+	if err := db.MigrateSchema(connection); err != nil {
+		return fmt.Errorf("restore failed to migrate db: %v", err)
+	}
+
 	s.dataStore.ConnectionLock()
-	s.dataStore.UpdateOpenConnection(db)
+	s.dataStore.UpdateOpenConnection(connection)
 	s.dataStore.ConnectionUnlock()
 
 	return nil
@@ -3,6 +3,7 @@
 package fsm
 
 import (
+	context "context"
 	constants "scheduler0/pkg/constants"
 	db "scheduler0/pkg/db"
 
@@ -38,25 +39,25 @@ func (_m *MockScheduler0RaftActions) ApplyRaftLog(logger hclog.Logger, l *raft.L
 	return r0
 }
 
-// WriteCommandToRaftLog provides a mock function with given fields: rft, commandType, sqlString, params, nodeIds, action
-func (_m *MockScheduler0RaftActions) WriteCommandToRaftLog(rft *raft.Raft, commandType constants.Command, sqlString string, params []interface{}, nodeIds []uint64, action constants.CommandAction) (*models.FSMResponse, *utils.GenericError) {
-	ret := _m.Called(rft, commandType, sqlString, params, nodeIds, action)
+// WriteCommandToRaftLog provides a mock function with given fields: ctx, rft, commandType, sqlString, params, nodeIds, action
+func (_m *MockScheduler0RaftActions) WriteCommandToRaftLog(ctx context.Context, rft *raft.Raft, commandType constants.Command, sqlString string, params []interface{}, nodeIds []uint64, action constants.CommandAction) (*models.FSMResponse, *utils.GenericError) {
+	ret := _m.Called(ctx, rft, commandType, sqlString, params, nodeIds, action)
 
 	var r0 *models.FSMResponse
 	var r1 *utils.GenericError
-	if rf, ok := ret.Get(0).(func(*raft.Raft, constants.Command, string, []interface{}, []uint64, constants.CommandAction) (*models.FSMResponse, *utils.GenericError)); ok {
-		return rf(rft, commandType, sqlString, params, nodeIds, action)
+	if rf, ok := ret.Get(0).(func(context.Context, *raft.Raft, constants.Command, string, []interface{}, []uint64, constants.CommandAction) (*models.FSMResponse, *utils.GenericError)); ok {
+		return rf(ctx, rft, commandType, sqlString, params, nodeIds, action)
 	}
-	if rf, ok := ret.Get(0).(func(*raft.Raft, constants.Command, string, []interface{}, []uint64, constants.CommandAction) *models.FSMResponse); ok {
-		r0 = rf(rft, commandType, sqlString, params, nodeIds, action)
+	if rf, ok := ret.Get(0).(func(context.Context, *raft.Raft, constants.Command, string, []interface{}, []uint64, constants.CommandAction) *models.FSMResponse); ok {
+		r0 = rf(ctx, rft, commandType, sqlString, params, nodeIds, action)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.FSMResponse)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*raft.Raft, constants.Command, string, []interface{}, []uint64, constants.CommandAction) *utils.GenericError); ok {
-		r1 = rf(rft, commandType, sqlString, params, nodeIds, action)
+	if rf, ok := ret.Get(1).(func(context.Context, *raft.Raft, constants.Command, string, []interface{}, []uint64, constants.CommandAction) *utils.GenericError); ok {
+		r1 = rf(ctx, rft, commandType, sqlString, params, nodeIds, action)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(*utils.GenericError)
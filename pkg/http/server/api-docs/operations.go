@@ -0,0 +1,248 @@
+package apidocs
+
+import (
+	"net/http"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+)
+
+// Auth the credentials an operation accepts
+type Auth int
+
+const (
+	// AuthNone operations are served without credentials
+	AuthNone Auth = iota
+	// AuthClient operations accept an api key and secret, or the credentials of a peer
+	AuthClient
+	// AuthPeer operations are only served to the peers of the cluster
+	AuthPeer
+)
+
+// Parameter a path, query or header parameter of an operation
+type Parameter struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// Operation documents a route of the api router. Request and Response are zero values of the models
+// the schemas of the request and response bodies are derived from, a nil Response documents an empty body.
+type Operation struct {
+	Method      string
+	Path        string // Relative to constants.APIV1Base, with the path templates of the router
+	Tag         string
+	Summary     string
+	Auth        Auth
+	Parameters  []Parameter
+	Request     interface{}
+	Response    interface{}
+	Status      int
+	ContentType string // Content type of the response, application/json when empty
+	Unwrapped   bool   // The response is not wrapped in the success and data envelope
+}
+
+func pathParam(name string, description string) Parameter {
+	return Parameter{Name: name, In: "path", Type: "integer", Required: true, Description: description}
+}
+
+func queryParam(name string, paramType string, required bool, description string) Parameter {
+	return Parameter{Name: name, In: "query", Type: paramType, Required: required, Description: description}
+}
+
+func headerParam(name string, description string) Parameter {
+	return Parameter{Name: name, In: "header", Type: "string", Description: description}
+}
+
+var paginationParams = []Parameter{
+	queryParam("limit", "integer", true, "Maximum number of items returned"),
+	queryParam("offset", "integer", true, "Number of items skipped"),
+}
+
+var cursorPaginationParams = []Parameter{
+	queryParam("limit", "integer", true, "Maximum number of items returned"),
+	queryParam("offset", "integer", false, "Number of items skipped, ignored with a cursor"),
+	queryParam("cursor", "string", false, "Next or prev cursor of an earlier page, pages read with a cursor have no total"),
+	queryParam("orderBy", "string", false, "Field the items are ordered by, id, dateCreated or a field specific to the items, id by default"),
+	queryParam("order", "string", false, "ASC or DESC, ASC by default"),
+}
+
+var jobFilterParams = []Parameter{
+	queryParam("projectId", "integer", true, "Id of the project of the jobs"),
+	queryParam("labels", "string", false, "Label selector such as team=payments,env!=dev"),
+	queryParam("callbackUrlPrefix", "string", false, "Prefix of the callback url of the jobs"),
+	queryParam("executionType", "string", false, "Execution type of the jobs"),
+	queryParam("status", "string", false, "Status of the jobs, active or paused"),
+}
+
+var statsParams = []Parameter{
+	queryParam("from", "string", false, "Start of the window in RFC3339"),
+	queryParam("to", "string", false, "End of the window in RFC3339, defaults to now"),
+	queryParam("window", "string", false, "Duration of the window before to such as 1h, defaults to 24h"),
+}
+
+var executionFilterParams = append(append([]Parameter{}, paginationParams...),
+	queryParam("state", "integer", false, "Execution state, 0 scheduled, 1 succeeded and 2 failed"),
+	queryParam("nodeId", "integer", false, "Id of the node that executed the job"),
+	queryParam("from", "string", false, "Earliest execution time in RFC3339"),
+	queryParam("to", "string", false, "Latest execution time in RFC3339"),
+)
+
+var readConsistencyParams = []Parameter{
+	headerParam(headers.ReadConsistencyHeader, "Consistency of the read, stale (default), leader or linearizable"),
+	headerParam(headers.MaxStalenessHeader, "Longest time since a follower last heard from the leader for a stale read, such as 5s"),
+}
+
+// ifMatchParam the version an update or delete expects, the ETag of the response of a get or update
+var ifMatchParam = headerParam(headers.IfMatchHeader, "ETag the item must still have, the request fails with 412 otherwise")
+
+func params(groups ...[]Parameter) []Parameter {
+	merged := []Parameter{}
+	for _, group := range groups {
+		merged = append(merged, group...)
+	}
+	return merged
+}
+
+// Operations every route served under constants.APIV1Base
+var Operations = []Operation{
+	// Credentials
+	{Method: http.MethodPost, Path: "/credentials", Tag: "credentials", Summary: "Create a credential, the only response with its api secret", Auth: AuthClient,
+		Request: models.Credential{}, Response: models.Credential{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/credentials", Tag: "credentials", Summary: "List credentials", Auth: AuthClient,
+		Parameters: params(cursorPaginationParams, readConsistencyParams), Response: models.PaginatedCredential{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/credentials/{id}", Tag: "credentials", Summary: "Get a credential", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the credential")}, readConsistencyParams), Response: models.Credential{}, Status: http.StatusOK},
+	{Method: http.MethodPut, Path: "/credentials/{id}", Tag: "credentials", Summary: "Update a credential", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the credential"), ifMatchParam}, Request: models.Credential{}, Response: models.Credential{}, Status: http.StatusOK},
+	{Method: http.MethodDelete, Path: "/credentials/{id}", Tag: "credentials", Summary: "Delete a credential", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the credential"), ifMatchParam}, Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/credentials/{id}/rotate", Tag: "credentials", Summary: "Issue a new api secret for a credential, returned in this response only", Auth: AuthClient,
+		Parameters: []Parameter{
+			pathParam("id", "Id of the credential"),
+			queryParam("gracePeriod", "string", false, "Duration such as 1h the current secret stays valid for, defaults to 24h"),
+			ifMatchParam,
+		}, Response: models.Credential{}, Status: http.StatusOK},
+
+	// Jobs
+	{Method: http.MethodPost, Path: "/jobs", Tag: "jobs", Summary: "Create jobs in a batch, returns the ids of the jobs and the async task in the Location header", Auth: AuthClient,
+		Parameters: []Parameter{headerParam(headers.IdempotencyKeyHeader, "Key that makes the request safe to retry")},
+		Request:    []models.Job{}, Response: []uint64{}, Status: http.StatusAccepted},
+	{Method: http.MethodGet, Path: "/jobs", Tag: "jobs", Summary: "List the jobs matching the filters", Auth: AuthClient,
+		Parameters: params(jobFilterParams, cursorPaginationParams, readConsistencyParams),
+		Response:   models.PaginatedJob{}, Status: http.StatusOK},
+	{Method: http.MethodPut, Path: "/jobs", Tag: "jobs", Summary: "Update jobs in a batch", Auth: AuthClient,
+		Request: []models.Job{}, Response: []uint64{}, Status: http.StatusAccepted},
+	{Method: http.MethodDelete, Path: "/jobs", Tag: "jobs", Summary: "Delete the jobs matching the filters", Auth: AuthClient,
+		Parameters: jobFilterParams, Response: models.BulkJobsResult{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/jobs/batch-delete", Tag: "jobs", Summary: "Delete jobs by id in a batch", Auth: AuthClient,
+		Request: []uint64{}, Response: []uint64{}, Status: http.StatusAccepted},
+	{Method: http.MethodPost, Path: "/jobs/pause", Tag: "jobs", Summary: "Pause the jobs matching the filters", Auth: AuthClient,
+		Parameters: jobFilterParams, Response: models.BulkJobsResult{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/jobs/resume", Tag: "jobs", Summary: "Resume the jobs matching the filters", Auth: AuthClient,
+		Parameters: jobFilterParams, Response: models.BulkJobsResult{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/jobs/{id}", Tag: "jobs", Summary: "Get a job", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the job")}, readConsistencyParams), Response: models.Job{}, Status: http.StatusOK},
+	{Method: http.MethodPut, Path: "/jobs/{id}", Tag: "jobs", Summary: "Update a job", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the job"), ifMatchParam}, Request: models.Job{}, Response: models.Job{}, Status: http.StatusOK},
+	{Method: http.MethodDelete, Path: "/jobs/{id}", Tag: "jobs", Summary: "Delete a job", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the job"), ifMatchParam}, Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/jobs/{id}/revisions", Tag: "jobs", Summary: "List the revisions of a job", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the job")}, paginationParams, readConsistencyParams), Response: models.PaginatedJobRevisions{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/jobs/{id}/revisions/{revision}/rollback", Tag: "jobs", Summary: "Restore a job to one of its revisions", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the job"), pathParam("revision", "Revision the job is restored to")}, Response: models.Job{}, Status: http.StatusOK},
+
+	// Executions
+	{Method: http.MethodGet, Path: "/jobs/{id}/executions", Tag: "executions", Summary: "List the executions of a job", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the job")}, executionFilterParams, readConsistencyParams), Response: models.PaginatedJobExecutionLogs{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/jobs/{id}/stats", Tag: "executions", Summary: "Execution stats of a job", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the job")}, statsParams, readConsistencyParams), Response: models.JobExecutionStats{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/executions", Tag: "executions", Summary: "List executions across jobs", Auth: AuthClient,
+		Parameters: params(executionFilterParams, []Parameter{
+			queryParam("jobId", "integer", false, "Id of the job of the executions"),
+			queryParam("projectId", "integer", false, "Id of the project of the executions"),
+		}),
+		Response: models.PaginatedJobExecutionLogs{}, Status: http.StatusOK},
+
+	// Projects
+	{Method: http.MethodPost, Path: "/projects", Tag: "projects", Summary: "Create a project", Auth: AuthClient,
+		Request: models.Project{}, Response: models.Project{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/projects", Tag: "projects", Summary: "List projects", Auth: AuthClient,
+		Parameters: params(cursorPaginationParams, readConsistencyParams), Response: models.PaginatedProject{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/projects/{id}", Tag: "projects", Summary: "Get a project", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the project")}, readConsistencyParams), Response: models.Project{}, Status: http.StatusOK},
+	{Method: http.MethodPut, Path: "/projects/{id}", Tag: "projects", Summary: "Update a project", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the project"), ifMatchParam}, Request: models.Project{}, Response: models.Project{}, Status: http.StatusOK},
+	{Method: http.MethodDelete, Path: "/projects/{id}", Tag: "projects", Summary: "Delete a project", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the project"), ifMatchParam}, Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/projects/{id}/stats", Tag: "executions", Summary: "Execution stats of the jobs of a project", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the project")}, statsParams, readConsistencyParams), Response: models.JobExecutionStats{}, Status: http.StatusOK},
+
+	// Alerts
+	{Method: http.MethodPost, Path: "/projects/{id}/alerts", Tag: "alerts", Summary: "Create an alert rule", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the project")}, Request: models.AlertRule{}, Response: models.AlertRule{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/projects/{id}/alerts", Tag: "alerts", Summary: "List the alert rules of a project", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the project")}, paginationParams, readConsistencyParams), Response: models.PaginatedAlertRules{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/projects/{id}/alerts/{alertId}", Tag: "alerts", Summary: "Get an alert rule", Auth: AuthClient,
+		Parameters: params([]Parameter{pathParam("id", "Id of the project"), pathParam("alertId", "Id of the alert rule")}, readConsistencyParams), Response: models.AlertRule{}, Status: http.StatusOK},
+	{Method: http.MethodPut, Path: "/projects/{id}/alerts/{alertId}", Tag: "alerts", Summary: "Update an alert rule", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the project"), pathParam("alertId", "Id of the alert rule")}, Request: models.AlertRule{}, Response: models.AlertRule{}, Status: http.StatusOK},
+	{Method: http.MethodDelete, Path: "/projects/{id}/alerts/{alertId}", Tag: "alerts", Summary: "Delete an alert rule", Auth: AuthClient,
+		Parameters: []Parameter{pathParam("id", "Id of the project"), pathParam("alertId", "Id of the alert rule")}, Status: http.StatusNoContent},
+
+	// Audit
+	{Method: http.MethodGet, Path: "/audit", Tag: "audit", Summary: "List the audit log of changes to credentials, projects and jobs, latest first", Auth: AuthClient,
+		Parameters: params([]Parameter{
+			queryParam("limit", "integer", true, "Maximum number of items returned"),
+			queryParam("offset", "integer", false, "Number of items skipped, ignored with a cursor"),
+			queryParam("cursor", "string", false, "Next or prev cursor of an earlier page, pages read with a cursor have no total"),
+			queryParam("order", "string", false, "ASC or DESC"),
+			queryParam("resource", "string", false, "Resource of the events, credential, project, job or node"),
+			queryParam("resourceId", "integer", false, "Id of the resource of the events"),
+			queryParam("action", "string", false, "Action of the events such as create, update, delete, rotate, pause or rollback"),
+			queryParam("credentialId", "integer", false, "Id of the credential that made the changes"),
+			queryParam("requestId", "string", false, "Id of the request that made the changes"),
+			queryParam("since", "string", false, "Earliest time of the events in RFC3339"),
+			queryParam("until", "string", false, "Time the events were recorded before in RFC3339"),
+		}, readConsistencyParams), Response: models.PaginatedAuditEvents{}, Status: http.StatusOK},
+
+	// Events
+	{Method: http.MethodGet, Path: "/events", Tag: "events", Summary: "Stream job, execution and leader events as server-sent events", Auth: AuthClient,
+		Parameters: []Parameter{
+			queryParam("projectId", "integer", false, "Only stream the events of the project"),
+			queryParam("jobId", "integer", false, "Only stream the events of the job"),
+			queryParam("cursor", "string", false, "Server-sent event id of the last event received, used when Last-Event-ID is not set"),
+			headerParam(headers.LastEventIDHeader, "Server-sent event id of the last event received by a client resuming the stream"),
+		},
+		Response: models.Event{}, Status: http.StatusOK, ContentType: "text/event-stream", Unwrapped: true},
+
+	// Async tasks
+	{Method: http.MethodGet, Path: "/async-tasks/{id}", Tag: "async-tasks", Summary: "Wait for an async task to complete", Auth: AuthClient,
+		Parameters: []Parameter{{Name: "id", In: "path", Type: "string", Required: true, Description: "Request id of the async task"}},
+		Response:   models.AsyncTask{}, Status: http.StatusOK},
+
+	// Healthcheck
+	{Method: http.MethodGet, Path: "/healthcheck", Tag: "healthcheck", Summary: "Raft leader and stats of the node", Auth: AuthNone,
+		Response: models.HealthCheck{}, Status: http.StatusOK},
+
+	// Peers
+	{Method: http.MethodGet, Path: "/peer-handshake", Tag: "peers", Summary: "Handshake of a peer joining the cluster", Auth: AuthPeer,
+		Response: models.PeerHandshake{}, Status: http.StatusOK},
+	{Method: http.MethodGet, Path: "/execution-logs", Tag: "peers", Summary: "Collect the uncommitted execution logs of the node", Auth: AuthPeer,
+		Status: http.StatusAccepted},
+	{Method: http.MethodPost, Path: "/start-jobs", Tag: "peers", Summary: "Start executing jobs on the node", Auth: AuthPeer,
+		Status: http.StatusAccepted},
+	{Method: http.MethodPost, Path: "/stop-jobs", Tag: "peers", Summary: "Stop executing jobs on the node", Auth: AuthPeer,
+		Status: http.StatusAccepted},
+	{Method: http.MethodGet, Path: "/read-index", Tag: "peers", Summary: "Index a follower applies before serving a linearizable read", Auth: AuthPeer,
+		Response: models.ReadIndex{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/credential-usage", Tag: "peers", Summary: "Usage of the credentials a follower recorded, written by the leader", Auth: AuthPeer,
+		Request: []models.CredentialUsage{}, Status: http.StatusAccepted},
+
+	// API docs
+	{Method: http.MethodGet, Path: "/api-docs", Tag: "api-docs", Summary: "API reference", Auth: AuthNone,
+		Status: http.StatusOK, ContentType: "text/html", Unwrapped: true},
+	{Method: http.MethodGet, Path: "/api-docs/openapi.json", Tag: "api-docs", Summary: "This OpenAPI document", Auth: AuthNone,
+		Status: http.StatusOK, Unwrapped: true},
+}
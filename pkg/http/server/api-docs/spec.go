@@ -0,0 +1,220 @@
+package apidocs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/constants/headers"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IndexHTML renders the OpenAPI document served at /api-docs/openapi.json
+//
+//go:embed index.html
+var IndexHTML []byte
+
+const schemaRefPrefix = "#/components/schemas/"
+
+var timeType = reflect.TypeOf(time.Time{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Spec builds the OpenAPI 3 document of the operations, with the schemas of the request and response bodies
+// derived from the json tags of their models.
+func Spec() map[string]interface{} {
+	builder := schemaBuilder{components: map[string]interface{}{}}
+
+	paths := map[string]interface{}{}
+	for _, operation := range Operations {
+		pathItem, ok := paths[operation.Path].(map[string]interface{})
+		if !ok {
+			pathItem = map[string]interface{}{}
+			paths[operation.Path] = pathItem
+		}
+		pathItem[strings.ToLower(operation.Method)] = builder.operation(operation)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Scheduler0 API",
+			"description": "Every response body other than the event stream and the api docs is wrapped in a success and data envelope.",
+			"version":     "v1",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": constants.APIV1Base},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": builder.components,
+			"securitySchemes": map[string]interface{}{
+				"ApiKeyAuth":    map[string]interface{}{"type": "apiKey", "in": "header", "name": headers.APIKeyHeader},
+				"ApiSecretAuth": map[string]interface{}{"type": "apiKey", "in": "header", "name": headers.SecretKeyHeader},
+				"PeerAuth":      map[string]interface{}{"type": "http", "scheme": "basic"},
+				"PeerHeader":    map[string]interface{}{"type": "apiKey", "in": "header", "name": headers.PeerHeader},
+			},
+		},
+	}
+}
+
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+func (builder *schemaBuilder) operation(operation Operation) map[string]interface{} {
+	parameters := []interface{}{}
+	for _, parameter := range operation.Parameters {
+		parameters = append(parameters, map[string]interface{}{
+			"name":        parameter.Name,
+			"in":          parameter.In,
+			"required":    parameter.Required,
+			"description": parameter.Description,
+			"schema":      map[string]interface{}{"type": parameter.Type},
+		})
+	}
+
+	contentType := operation.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	response := map[string]interface{}{"description": http.StatusText(operation.Status)}
+	if operation.Status != http.StatusNoContent {
+		var schema map[string]interface{}
+		switch {
+		case operation.Response != nil:
+			schema = builder.schema(reflect.TypeOf(operation.Response))
+		case contentType == "application/json":
+			schema = map[string]interface{}{"type": "object"}
+		default:
+			schema = map[string]interface{}{"type": "string"}
+		}
+		if !operation.Unwrapped {
+			schema = envelope(schema)
+		}
+		response["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+	}
+
+	doc := map[string]interface{}{
+		"tags":       []string{operation.Tag},
+		"summary":    operation.Summary,
+		"parameters": parameters,
+		"responses": map[string]interface{}{
+			strconv.Itoa(operation.Status): response,
+			"default": map[string]interface{}{
+				"description": "Error message",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": envelope(map[string]interface{}{"type": "string"})},
+				},
+			},
+		},
+		"security": security(operation.Auth),
+	}
+
+	if operation.Request != nil {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": builder.schema(reflect.TypeOf(operation.Request))},
+			},
+		}
+	}
+
+	return doc
+}
+
+// schema returns the schema of values of the type encoded as json. Structs are added to the
+// components once and referenced by the name of their type.
+func (builder *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == rawMessageType {
+		return map[string]interface{}{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return builder.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": builder.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": builder.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := builder.components[t.Name()]; !ok {
+			// Reserve the name first so that recursive types reference the component being built
+			builder.components[t.Name()] = map[string]interface{}{}
+			builder.components[t.Name()] = builder.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": schemaRefPrefix + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (builder *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		properties[name] = builder.schema(field.Type)
+	}
+
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func envelope(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"success": map[string]interface{}{"type": "boolean"},
+			"data":    data,
+		},
+	}
+}
+
+func security(auth Auth) []interface{} {
+	switch auth {
+	case AuthClient:
+		return []interface{}{
+			map[string]interface{}{"ApiKeyAuth": []string{}, "ApiSecretAuth": []string{}},
+			map[string]interface{}{"PeerAuth": []string{}, "PeerHeader": []string{}},
+		}
+	case AuthPeer:
+		return []interface{}{
+			map[string]interface{}{"PeerAuth": []string{}, "PeerHeader": []string{}},
+		}
+	default:
+		return []interface{}{}
+	}
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"io/ioutil"
+	"net/http"
+	"scheduler0/pkg/logging"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/service/alert"
+	"scheduler0/pkg/utils"
+	"strconv"
+)
+
+type alertController struct {
+	alertService alert.AlertService
+	logger       hclog.Logger
+}
+
+type AlertHTTPController interface {
+	CreateOneAlert(w http.ResponseWriter, r *http.Request)
+	GetOneAlert(w http.ResponseWriter, r *http.Request)
+	ListAlerts(w http.ResponseWriter, r *http.Request)
+	UpdateOneAlert(w http.ResponseWriter, r *http.Request)
+	DeleteOneAlert(w http.ResponseWriter, r *http.Request)
+}
+
+func NewAlertController(logger hclog.Logger, alertService alert.AlertService) AlertHTTPController {
+	return &alertController{
+		alertService: alertService,
+		logger:       logger,
+	}
+}
+
+func (controller *alertController) CreateOneAlert(w http.ResponseWriter, r *http.Request) {
+	projectId, convertErr := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if convertErr != nil {
+		utils.SendJSON(w, "project id is required", false, http.StatusBadRequest, nil)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logging.FromContext(r.Context(), controller.logger).Error("failed to read request body", "error", err)
+		utils.SendJSON(w, "failed to read request body", false, http.StatusBadRequest, nil)
+		return
+	}
+
+	alertRule := models.AlertRule{}
+	err = alertRule.FromJSON(body)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+	alertRule.ProjectID = projectId
+
+	createdAlertRule, createError := controller.alertService.CreateOne(alertRule)
+	if createError != nil {
+		utils.SendJSON(w, createError.Message, false, createError.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, createdAlertRule, true, http.StatusCreated, nil)
+}
+
+func (controller *alertController) GetOneAlert(w http.ResponseWriter, r *http.Request) {
+	alertRule, ok := alertRuleFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := controller.alertService.GetOneByID(&alertRule)
+	if err != nil {
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, alertRule, true, http.StatusOK, nil)
+}
+
+func (controller *alertController) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	projectId, convertErr := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if convertErr != nil {
+		utils.SendJSON(w, "project id is required", false, http.StatusBadRequest, nil)
+		return
+	}
+
+	limitParam, err := utils.ValidateQueryString("limit", r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	offsetParam, err := utils.ValidateQueryString("offset", r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetParam)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	alertRules, listError := controller.alertService.List(projectId, uint64(offset), uint64(limit))
+	if listError != nil {
+		utils.SendJSON(w, listError.Message, false, listError.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, alertRules, true, http.StatusOK, nil)
+}
+
+func (controller *alertController) UpdateOneAlert(w http.ResponseWriter, r *http.Request) {
+	pathAlertRule, ok := alertRuleFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logging.FromContext(r.Context(), controller.logger).Error("failed to read request body", "error", err)
+		utils.SendJSON(w, "failed to read request body", false, http.StatusBadRequest, nil)
+		return
+	}
+
+	alertRule := models.AlertRule{}
+	err = alertRule.FromJSON(body)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+	alertRule.ID = pathAlertRule.ID
+	alertRule.ProjectID = pathAlertRule.ProjectID
+
+	updateError := controller.alertService.UpdateOneByID(&alertRule)
+	if updateError != nil {
+		utils.SendJSON(w, updateError.Message, false, updateError.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, alertRule, true, http.StatusOK, nil)
+}
+
+func (controller *alertController) DeleteOneAlert(w http.ResponseWriter, r *http.Request) {
+	alertRule, ok := alertRuleFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := controller.alertService.DeleteOneByID(alertRule)
+	if err != nil {
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, nil, true, http.StatusNoContent, nil)
+}
+
+// alertRuleFromPath reads the project and alert ids of the request path, responding with a bad request when either is invalid
+func alertRuleFromPath(w http.ResponseWriter, r *http.Request) (models.AlertRule, bool) {
+	params := mux.Vars(r)
+
+	projectId, convertErr := strconv.ParseUint(params["id"], 10, 64)
+	if convertErr != nil {
+		utils.SendJSON(w, "project id is required", false, http.StatusBadRequest, nil)
+		return models.AlertRule{}, false
+	}
+
+	alertId, convertErr := strconv.ParseUint(params["alertId"], 10, 64)
+	if convertErr != nil {
+		utils.SendJSON(w, "alert id is required", false, http.StatusBadRequest, nil)
+		return models.AlertRule{}, false
+	}
+
+	return models.AlertRule{ID: alertId, ProjectID: projectId}, true
+}
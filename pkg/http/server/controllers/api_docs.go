@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	apidocs "scheduler0/pkg/http/server/api-docs"
+	"scheduler0/pkg/utils"
+)
+
+type APIDocsController interface {
+	Index(w http.ResponseWriter, r *http.Request)
+	OpenAPISpec(w http.ResponseWriter, r *http.Request)
+}
+
+type apiDocsController struct {
+	logger hclog.Logger
+	spec   []byte
+}
+
+func NewAPIDocsController(logger hclog.Logger) APIDocsController {
+	spec, err := json.Marshal(apidocs.Spec())
+	if err != nil {
+		logger.Error("failed to marshal the openapi document", "error", err.Error())
+	}
+	return &apiDocsController{
+		logger: logger,
+		spec:   spec,
+	}
+}
+
+// Index serves the api reference rendering the OpenAPI document
+func (controller *apiDocsController) Index(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(apidocs.IndexHTML)
+}
+
+// OpenAPISpec serves the OpenAPI document of the api
+func (controller *apiDocsController) OpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	if controller.spec == nil {
+		utils.SendJSON(w, "openapi document is unavailable", false, http.StatusInternalServerError, nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(controller.spec)
+}
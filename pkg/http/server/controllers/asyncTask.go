@@ -1,10 +1,10 @@
 package controllers
 
 import (
-	"fmt"
 	"github.com/gorilla/mux"
-	"log"
+	"github.com/hashicorp/go-hclog"
 	"net/http"
+	"scheduler0/pkg/logging"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/service/async_task"
 	"scheduler0/pkg/utils"
@@ -15,11 +15,11 @@ type AsyncTaskController interface {
 }
 
 type asyncTaskController struct {
-	logger           *log.Logger
+	logger           hclog.Logger
 	asyncTaskService async_task.AsyncTaskService
 }
 
-func NewAsyncTaskController(logger *log.Logger, asyncTaskService async_task.AsyncTaskService) AsyncTaskController {
+func NewAsyncTaskController(logger hclog.Logger, asyncTaskService async_task.AsyncTaskService) AsyncTaskController {
 	controller := asyncTaskController{
 		logger:           logger,
 		asyncTaskService: asyncTaskService,
@@ -37,6 +37,11 @@ func (controller *asyncTaskController) GetTask(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if accessErr := ensureTaskAccess(r, task); accessErr != nil {
+		utils.SendJSON(w, accessErr.Error(), false, accessErr.Type, nil)
+		return
+	}
+
 	if task.State == models.AsyncTaskSuccess {
 		utils.SendJSON(w, task, true, http.StatusOK, nil)
 		return
@@ -51,13 +56,13 @@ func (controller *asyncTaskController) GetTask(w http.ResponseWriter, r *http.Re
 	for {
 		select {
 		case task := <-taskCh:
-			controller.logger.Println("returning task from channel")
+			logging.FromContext(r.Context(), controller.logger).Debug("returning task from channel")
 			utils.SendJSON(w, task, true, http.StatusOK, nil)
 			return
 		case <-r.Context().Done():
 			taskIdInt, err := controller.asyncTaskService.GetTaskIdWithRequestId(requestID)
 			if err != nil {
-				controller.logger.Println(fmt.Sprintf("failed to delete subscriber for task %d: could not convert taskid str to int", taskIdInt))
+				logging.FromContext(r.Context(), controller.logger).Error("failed to delete subscriber, could not find task for request id", "error", err.Message)
 				return
 			}
 			delErr := controller.asyncTaskService.DeleteSubscriber(taskIdInt, subId)
@@ -65,10 +70,10 @@ func (controller *asyncTaskController) GetTask(w http.ResponseWriter, r *http.Re
 				close(taskCh)
 			}
 			if delErr != nil {
-				controller.logger.Println(fmt.Sprintf("failed to delete subscriber with id %d for task %s: ", taskIdInt, delErr.Error()))
+				logging.FromContext(r.Context(), controller.logger).Error("failed to delete subscriber", "subscriber-id", subId, "task-id", taskIdInt, "error", delErr.Message)
 				return
 			}
-			controller.logger.Println("returning success")
+			logging.FromContext(r.Context(), controller.logger).Debug("deleted subscriber of closed request", "subscriber-id", subId, "task-id", taskIdInt)
 			return
 		}
 	}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/service/audit"
+	"scheduler0/pkg/utils"
+	"strconv"
+	"time"
+)
+
+type auditController struct {
+	auditService audit.AuditService
+	logger       hclog.Logger
+}
+
+type AuditHTTPController interface {
+	ListAuditEvents(w http.ResponseWriter, r *http.Request)
+}
+
+func NewAuditController(logger hclog.Logger, auditService audit.AuditService) AuditHTTPController {
+	return &auditController{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// ListAuditEvents returns a page of the audit log, latest first
+func (controller *auditController) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	events, listErr := controller.auditService.ListEvents(filter)
+	if listErr != nil {
+		utils.SendJSON(w, listErr.Message, false, listErr.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, events, true, http.StatusOK, nil)
+}
+
+// parseAuditFilter extracts the page and the resource, resourceId, action, credentialId, requestId,
+// since and until query parameters. The time range is given in RFC3339.
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	filter := models.AuditFilter{}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		return filter, err
+	}
+	filter.Limit = page.Limit
+	filter.Offset = page.Offset
+	filter.Cursor = page.Cursor
+	filter.Order = page.Order
+
+	query := r.URL.Query()
+	filter.Resource = models.AuditResource(query.Get("resource"))
+	filter.Action = models.AuditAction(query.Get("action"))
+	filter.RequestID = query.Get("requestId")
+
+	if resourceIdParam := query.Get("resourceId"); resourceIdParam != "" {
+		resourceId, parseErr := strconv.ParseUint(resourceIdParam, 10, 64)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.ResourceID = resourceId
+	}
+
+	if credentialIdParam := query.Get("credentialId"); credentialIdParam != "" {
+		credentialId, parseErr := strconv.ParseUint(credentialIdParam, 10, 64)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.CredentialID = credentialId
+	}
+
+	if sinceParam := query.Get("since"); sinceParam != "" {
+		since, parseErr := time.Parse(time.RFC3339, sinceParam)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.Since = &since
+	}
+
+	if untilParam := query.Get("until"); untilParam != "" {
+		until, parseErr := time.Parse(time.RFC3339, untilParam)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.Until = &until
+	}
+
+	return filter, nil
+}
@@ -2,13 +2,16 @@ package controllers
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/logging"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/service/credential"
 	"scheduler0/pkg/utils"
 	"strconv"
+	"time"
 )
 
 type CredentialHTTPController interface {
@@ -17,14 +20,15 @@ type CredentialHTTPController interface {
 	UpdateOneCredential(w http.ResponseWriter, r *http.Request)
 	DeleteOneCredential(w http.ResponseWriter, r *http.Request)
 	ListCredentials(w http.ResponseWriter, r *http.Request)
+	RotateCredential(w http.ResponseWriter, r *http.Request)
 }
 
 type credentialController struct {
 	credentialService credential.CredentialService
-	logger            *log.Logger
+	logger            hclog.Logger
 }
 
-func NewCredentialController(logger *log.Logger, credentialService credential.CredentialService) CredentialHTTPController {
+func NewCredentialController(logger hclog.Logger, credentialService credential.CredentialService) CredentialHTTPController {
 	return &credentialController{
 		credentialService: credentialService,
 		logger:            logger,
@@ -52,15 +56,12 @@ func (credentialController *credentialController) CreateOneCredential(w http.Res
 		return
 	}
 
-	if newCredentialUUID, err := credentialController.credentialService.CreateNewCredential(credentialBody); err != nil {
+	credentialBody.Actor = actorFromRequest(r)
+
+	if credential, err := credentialController.credentialService.CreateNewCredential(credentialBody); err != nil {
 		utils.SendJSON(w, err.Message, false, err.Type, nil)
 	} else {
-		if credential, err := credentialController.credentialService.FindOneCredentialByID(newCredentialUUID); err != nil {
-			credentialController.logger.Println(err, newCredentialUUID)
-			utils.SendJSON(w, err.Error(), false, http.StatusInternalServerError, nil)
-		} else {
-			utils.SendJSON(w, credential, true, http.StatusCreated, nil)
-		}
+		utils.SendJSON(w, credential, true, http.StatusCreated, nil)
 	}
 }
 
@@ -79,7 +80,7 @@ func (credentialController *credentialController) GetOneCredential(w http.Respon
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 	} else {
-		utils.SendJSON(w, credential, true, http.StatusOK, nil)
+		utils.SendJSON(w, credential, true, http.StatusOK, etagHeaders(credential.Version))
 	}
 }
 
@@ -89,7 +90,9 @@ func (credentialController *credentialController) UpdateOneCredential(w http.Res
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		credentialController.logger.Fatalln(err)
+		logging.FromContext(r.Context(), credentialController.logger).Error("failed to read request body", "error", err)
+		utils.SendJSON(w, "failed to read request body", false, http.StatusBadRequest, nil)
+		return
 	}
 	if len(body) < 1 {
 		utils.SendJSON(w, "request body required", false, http.StatusBadRequest, nil)
@@ -110,13 +113,21 @@ func (credentialController *credentialController) UpdateOneCredential(w http.Res
 		return
 	}
 
+	credentialBody.Version, err = parseIfMatch(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	credentialBody.Actor = actorFromRequest(r)
+
 	credentialService := credentialController.credentialService
 	credential, err := credentialService.UpdateOneCredential(credentialBody)
 
 	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusOK, nil)
+		utils.SendJSON(w, err.Error(), false, preconditionStatus(err, http.StatusOK), nil)
 	} else {
-		utils.SendJSON(w, credential, true, http.StatusOK, nil)
+		utils.SendJSON(w, credential, true, http.StatusOK, etagHeaders(credential.Version))
 	}
 }
 
@@ -129,10 +140,15 @@ func (credentialController *credentialController) DeleteOneCredential(w http.Res
 		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
-	_, err := credentialService.DeleteOneCredential(uint64(credentialId))
+	version, err := parseIfMatch(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
+	}
+	_, err = credentialService.DeleteOneCredential(uint64(credentialId), version, actorFromRequest(r))
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, preconditionStatus(err, http.StatusBadRequest), nil)
+		return
 	} else {
 		utils.SendJSON(w, nil, true, http.StatusNoContent, nil)
 		return
@@ -143,43 +159,54 @@ func (credentialController *credentialController) DeleteOneCredential(w http.Res
 func (credentialController *credentialController) ListCredentials(w http.ResponseWriter, r *http.Request) {
 	credentialService := credentialController.credentialService
 
-	offset := 0
-	limit := 50
-
-	// TODO: use constants for ASC and DESC
-	orderBy := "date_created DESC"
-
-	limitParam, err := utils.ValidateQueryString("limit", r)
+	page, err := parsePageRequest(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	offsetParam, err := utils.ValidateQueryString("offset", r)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+	credentials, listCredentialError := credentialService.ListCredentials(page)
+
+	if listCredentialError != nil {
+		utils.SendJSON(w, listCredentialError.Message, false, listCredentialError.Type, nil)
+		return
+	} else {
+		utils.SendJSON(w, credentials, true, http.StatusOK, nil)
 		return
 	}
+}
 
-	offset, err = strconv.Atoi(offsetParam)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+// RotateCredential issues a new api secret for a credential. The current secret stays valid for the grace period
+// given by the gracePeriod query param, a duration such as 1h, and for a day without it.
+func (credentialController *credentialController) RotateCredential(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	credentialId, convertErr := strconv.ParseUint(params["id"], 10, 64)
+	if convertErr != nil {
+		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	limit, err = strconv.Atoi(limitParam)
+	gracePeriod := constants.DefaultCredentialRotationGracePeriod
+	if gracePeriodParam := r.URL.Query().Get("gracePeriod"); gracePeriodParam != "" {
+		parsed, parseErr := time.ParseDuration(gracePeriodParam)
+		if parseErr != nil {
+			utils.SendJSON(w, parseErr.Error(), false, http.StatusBadRequest, nil)
+			return
+		}
+		gracePeriod = parsed
+	}
+
+	version, err := parseIfMatch(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	credentials, listCredentialError := credentialService.ListCredentials(uint64(offset), uint64(limit), orderBy)
-
-	if listCredentialError != nil {
-		utils.SendJSON(w, listCredentialError.Message, false, listCredentialError.Type, nil)
-		return
-	} else {
-		utils.SendJSON(w, credentials, true, http.StatusOK, nil)
+	credential, rotateErr := credentialController.credentialService.RotateCredential(credentialId, gracePeriod, version, actorFromRequest(r))
+	if rotateErr != nil {
+		utils.SendJSON(w, rotateErr.Message, false, rotateErr.Type, nil)
 		return
 	}
+
+	utils.SendJSON(w, credential, true, http.StatusOK, etagHeaders(credential.Version))
 }
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+// etagHeaders returns the ETag header of a job, project or credential at the version
+func etagHeaders(version uint64) map[string]string {
+	return map[string]string{headers.ETagHeader: fmt.Sprintf("\"%d\"", version)}
+}
+
+// parseIfMatch returns the version of the If-Match header of an update or delete.
+// It is 0 when the header is missing or is *, the change then applies to any version.
+func parseIfMatch(r *http.Request) (uint64, error) {
+	value := strings.TrimSpace(r.Header.Get(headers.IfMatchHeader))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(value, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, "\"") || !strings.HasSuffix(tag, "\"") {
+		return 0, fmt.Errorf("invalid %s header %s", headers.IfMatchHeader, value)
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("invalid %s header %s", headers.IfMatchHeader, value)
+	}
+
+	return version, nil
+}
+
+// preconditionStatus returns 412 for an update or delete whose If-Match version is not the current version,
+// and the status otherwise
+func preconditionStatus(err error, status int) int {
+	var genericErr *utils.GenericError
+	if errors.As(err, &genericErr) && genericErr.Type == http.StatusPreconditionFailed {
+		return genericErr.Type
+	}
+	return status
+}
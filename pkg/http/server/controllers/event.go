@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/service/event"
+	"scheduler0/pkg/utils"
+	"strconv"
+	"time"
+)
+
+type EventController interface {
+	StreamEvents(w http.ResponseWriter, r *http.Request)
+}
+
+type eventController struct {
+	logger       hclog.Logger
+	eventService event.EventService
+}
+
+func NewEventController(logger hclog.Logger, eventService event.EventService) EventController {
+	controller := eventController{
+		logger:       logger,
+		eventService: eventService,
+	}
+	return &controller
+}
+
+// StreamEvents streams the events of the node as server-sent events, filtered by the projectId and jobId query params.
+// Clients resume a stream from the id of the last event they received, sent in the Last-Event-ID header or the cursor query param.
+func (controller *eventController) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.SendJSON(w, "streaming is not supported", false, http.StatusInternalServerError, nil)
+		return
+	}
+
+	filter := models.EventFilter{}
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name   string
+		target *uint64
+	}{
+		{"projectId", &filter.ProjectID},
+		{"jobId", &filter.JobID},
+	} {
+		if value := query.Get(param.name); value != "" {
+			parsed, parseErr := strconv.ParseUint(value, 10, 64)
+			if parseErr != nil {
+				utils.SendJSON(w, parseErr.Error(), false, http.StatusBadRequest, nil)
+				return
+			}
+			*param.target = parsed
+		}
+	}
+
+	cursor := models.EventCursor{}
+	lastEventId := r.Header.Get(headers.LastEventIDHeader)
+	if lastEventId == "" {
+		lastEventId = query.Get("cursor")
+	}
+	if lastEventId != "" {
+		parsed, parseErr := models.ParseEventCursor(lastEventId)
+		if parseErr != nil {
+			utils.SendJSON(w, parseErr.Error(), false, http.StatusBadRequest, nil)
+			return
+		}
+		cursor = parsed
+	}
+
+	missedEvents, subscription, subscribeErr := controller.eventService.Subscribe(filter, cursor)
+	if subscribeErr != nil {
+		utils.SendJSON(w, subscribeErr.Message, false, subscribeErr.Type, nil)
+		return
+	}
+	defer controller.eventService.Unsubscribe(subscription)
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, missedEvent := range missedEvents {
+		if writeErr := writeEvent(w, missedEvent); writeErr != nil {
+			controller.logger.Error("failed to write event", "error", writeErr.Error())
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(time.Duration(constants.EventStreamKeepAliveSeconds) * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case nextEvent, open := <-subscription.Events:
+			if !open {
+				// The subscription fell behind, the client resumes from the last event it received
+				return
+			}
+			if writeErr := writeEvent(w, nextEvent); writeErr != nil {
+				controller.logger.Error("failed to write event", "error", writeErr.Error())
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, writeErr := fmt.Fprint(w, ": keep-alive\n\n"); writeErr != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Cursor(), event.Type, data)
+	return err
+}
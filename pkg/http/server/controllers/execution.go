@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/service/job"
+	"scheduler0/pkg/service/job_execution"
+	"scheduler0/pkg/utils"
+	"strconv"
+	"time"
+)
+
+type ExecutionController interface {
+	ListJobExecutions(w http.ResponseWriter, r *http.Request)
+	ListExecutions(w http.ResponseWriter, r *http.Request)
+	JobStats(w http.ResponseWriter, r *http.Request)
+	ProjectStats(w http.ResponseWriter, r *http.Request)
+}
+
+type executionController struct {
+	logger              hclog.Logger
+	jobExecutionService job_execution.JobExecutionService
+	jobService          job.JobService
+}
+
+func NewExecutionController(logger hclog.Logger, jobExecutionService job_execution.JobExecutionService, jobService job.JobService) ExecutionController {
+	controller := executionController{
+		logger:              logger,
+		jobExecutionService: jobExecutionService,
+		jobService:          jobService,
+	}
+	return &controller
+}
+
+// ListJobExecutions returns a paginated list of the execution logs of a job
+func (controller *executionController) ListJobExecutions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	jobID, convertErr := strconv.Atoi(params["id"])
+	if convertErr != nil {
+		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	filter, err := parseExecutionLogFilter(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+	filter.JobId = uint64(jobID)
+
+	if accessErr := ensureJobAccess(r, controller.jobService, filter.JobId); accessErr != nil {
+		utils.SendJSON(w, accessErr.Message, false, accessErr.Type, nil)
+		return
+	}
+
+	controller.listExecutions(w, filter)
+}
+
+// ListExecutions returns a paginated list of execution logs across jobs
+func (controller *executionController) ListExecutions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseExecutionLogFilter(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name   string
+		target *uint64
+	}{
+		{"jobId", &filter.JobId},
+		{"projectId", &filter.ProjectId},
+	} {
+		if value := query.Get(param.name); value != "" {
+			parsed, parseErr := strconv.ParseUint(value, 10, 64)
+			if parseErr != nil {
+				utils.SendJSON(w, parseErr.Error(), false, http.StatusBadRequest, nil)
+				return
+			}
+			*param.target = parsed
+		}
+	}
+
+	controller.listExecutions(w, filter)
+}
+
+func (controller *executionController) listExecutions(w http.ResponseWriter, filter models.JobExecutionLogFilter) {
+	executions, listErr := controller.jobExecutionService.ListExecutions(filter)
+	if listErr != nil {
+		utils.SendJSON(w, listErr.Message, false, listErr.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, executions, true, http.StatusOK, nil)
+}
+
+// JobStats returns the execution stats of a job over a time window
+func (controller *executionController) JobStats(w http.ResponseWriter, r *http.Request) {
+	controller.stats(w, r, func(jobId uint64) *utils.GenericError {
+		return ensureJobAccess(r, controller.jobService, jobId)
+	}, controller.jobExecutionService.GetJobStats)
+}
+
+// ProjectStats returns the execution stats of the jobs of a project over a time window
+func (controller *executionController) ProjectStats(w http.ResponseWriter, r *http.Request) {
+	controller.stats(w, r, func(projectId uint64) *utils.GenericError {
+		return ensureProjectAccess(r, projectId)
+	}, controller.jobExecutionService.GetProjectStats)
+}
+
+func (controller *executionController) stats(
+	w http.ResponseWriter,
+	r *http.Request,
+	ensureAccess func(id uint64) *utils.GenericError,
+	getStats func(id uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError),
+) {
+	params := mux.Vars(r)
+
+	id, convertErr := strconv.ParseUint(params["id"], 10, 64)
+	if convertErr != nil {
+		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	if accessErr := ensureAccess(id); accessErr != nil {
+		utils.SendJSON(w, accessErr.Message, false, accessErr.Type, nil)
+		return
+	}
+
+	from, to, err := parseStatsWindow(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	stats, statsErr := getStats(id, from, to)
+	if statsErr != nil {
+		utils.SendJSON(w, statsErr.Message, false, statsErr.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, stats, true, http.StatusOK, nil)
+}
+
+// parseStatsWindow extracts the time window of the stats endpoints. The window ends at the to query parameter,
+// or now, and starts at the from query parameter or a window duration such as 1h or 168h before the end.
+// Without either the window is the last 24 hours.
+func parseStatsWindow(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	to := time.Now().UTC()
+	if toParam := query.Get("to"); toParam != "" {
+		parsed, parseErr := time.Parse(time.RFC3339, toParam)
+		if parseErr != nil {
+			return time.Time{}, time.Time{}, parseErr
+		}
+		to = parsed
+	}
+
+	fromParam := query.Get("from")
+	windowParam := query.Get("window")
+	if fromParam != "" && windowParam != "" {
+		return time.Time{}, time.Time{}, errors.New("either from or window should be set, not both")
+	}
+
+	if fromParam != "" {
+		from, parseErr := time.Parse(time.RFC3339, fromParam)
+		if parseErr != nil {
+			return time.Time{}, time.Time{}, parseErr
+		}
+		return from, to, nil
+	}
+
+	window := time.Hour * 24
+	if windowParam != "" {
+		parsed, parseErr := time.ParseDuration(windowParam)
+		if parseErr != nil {
+			return time.Time{}, time.Time{}, parseErr
+		}
+		if parsed <= 0 {
+			return time.Time{}, time.Time{}, errors.New("window should be greater than zero")
+		}
+		window = parsed
+	}
+
+	return to.Add(-window), to, nil
+}
+
+// parseExecutionLogFilter extracts the limit, offset, state, node id and from and to query parameters.
+// The state is the numeric execution log state and the time range is given in RFC3339.
+func parseExecutionLogFilter(r *http.Request) (models.JobExecutionLogFilter, error) {
+	filter := models.JobExecutionLogFilter{}
+
+	limitParam, err := utils.ValidateQueryString("limit", r)
+	if err != nil {
+		return filter, err
+	}
+
+	offsetParam, err := utils.ValidateQueryString("offset", r)
+	if err != nil {
+		return filter, err
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil {
+		return filter, err
+	}
+
+	offset, err := strconv.Atoi(offsetParam)
+	if err != nil {
+		return filter, err
+	}
+
+	if limit < 0 || offset < 0 {
+		return filter, errors.New("limit and offset should not be negative")
+	}
+	filter.Limit = uint64(limit)
+	filter.Offset = uint64(offset)
+
+	query := r.URL.Query()
+
+	if stateParam := query.Get("state"); stateParam != "" {
+		state, parseErr := strconv.ParseUint(stateParam, 10, 64)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		executionState := models.JobExecutionLogState(state)
+		filter.State = &executionState
+	}
+
+	if nodeIdParam := query.Get("nodeId"); nodeIdParam != "" {
+		nodeId, parseErr := strconv.ParseUint(nodeIdParam, 10, 64)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.NodeId = nodeId
+	}
+
+	if fromParam := query.Get("from"); fromParam != "" {
+		from, parseErr := time.Parse(time.RFC3339, fromParam)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.From = from
+	}
+
+	if toParam := query.Get("to"); toParam != "" {
+		to, parseErr := time.Parse(time.RFC3339, toParam)
+		if parseErr != nil {
+			return filter, parseErr
+		}
+		filter.To = to
+	}
+
+	return filter, nil
+}
@@ -1,8 +1,9 @@
 package controllers
 
 import (
-	"log"
+	"github.com/hashicorp/go-hclog"
 	"net/http"
+	"scheduler0/pkg/models"
 	"scheduler0/pkg/service/node"
 	"scheduler0/pkg/utils"
 )
@@ -13,16 +14,10 @@ type HealthCheckController interface {
 
 type healthCheckController struct {
 	service node.NodeService
-	logger  *log.Logger
+	logger  hclog.Logger
 }
 
-type healthCheckRes struct {
-	LeaderAddress string            `json:"leaderAddress"`
-	LeaderId      string            `json:"leaderId"`
-	RaftStats     map[string]string `json:"raftStats"`
-}
-
-func NewHealthCheckController(logger *log.Logger, service node.NodeService) HealthCheckController {
+func NewHealthCheckController(logger hclog.Logger, service node.NodeService) HealthCheckController {
 	return &healthCheckController{
 		service: service,
 		logger:  logger,
@@ -31,7 +26,7 @@ func NewHealthCheckController(logger *log.Logger, service node.NodeService) Heal
 
 func (controller *healthCheckController) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	leaderAddress, leaderId := controller.service.GetRaftLeaderWithId()
-	res := healthCheckRes{
+	res := models.HealthCheck{
 		LeaderAddress: string(leaderAddress),
 		LeaderId:      string(leaderId),
 		RaftStats:     controller.service.GetRaftStats(),
@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
-	"log"
+	"github.com/hashicorp/go-hclog"
 	"net/http"
+	"scheduler0/pkg/constants/headers"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/service/job"
 	"scheduler0/pkg/service/project"
@@ -17,18 +18,25 @@ import (
 type jobHTTPController struct {
 	jobService     job.JobService
 	projectService project.ProjectService
-	logger         *log.Logger
+	logger         hclog.Logger
 }
 
 type JobHTTPController interface {
 	ListJobs(w http.ResponseWriter, r *http.Request)
+	PauseJobs(w http.ResponseWriter, r *http.Request)
+	ResumeJobs(w http.ResponseWriter, r *http.Request)
+	DeleteJobs(w http.ResponseWriter, r *http.Request)
 	BatchCreateJobs(w http.ResponseWriter, r *http.Request)
+	BatchUpdateJobs(w http.ResponseWriter, r *http.Request)
+	BatchDeleteJobs(w http.ResponseWriter, r *http.Request)
 	GetOneJob(w http.ResponseWriter, r *http.Request)
 	UpdateOneJob(w http.ResponseWriter, r *http.Request)
 	DeleteOneJob(w http.ResponseWriter, r *http.Request)
+	ListJobRevisions(w http.ResponseWriter, r *http.Request)
+	RollbackJob(w http.ResponseWriter, r *http.Request)
 }
 
-func NewJoBHTTPController(logger *log.Logger, jobService job.JobService, projectService project.ProjectService) JobHTTPController {
+func NewJoBHTTPController(logger hclog.Logger, jobService job.JobService, projectService project.ProjectService) JobHTTPController {
 	controller := &jobHTTPController{
 		jobService:     jobService,
 		projectService: projectService,
@@ -39,49 +47,104 @@ func NewJoBHTTPController(logger *log.Logger, jobService job.JobService, project
 
 // ListJobs returns a paginated list of jobs
 func (jobController *jobHTTPController) ListJobs(w http.ResponseWriter, r *http.Request) {
-	projectIDQueryParam, err := utils.ValidateQueryString("projectId", r)
+	filter, err := parseJobFilter(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	projectID, convertErr := strconv.Atoi(projectIDQueryParam)
-	if convertErr != nil {
-		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	limitParam, err := utils.ValidateQueryString("limit", r)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+	filter.Offset = page.Offset
+	filter.Limit = page.Limit
+	filter.Cursor = page.Cursor
+	filter.OrderBy = page.OrderBy
+	filter.Order = page.Order
+
+	jobs, listJobsError := jobController.jobService.ListJobs(filter)
+	if listJobsError != nil {
+		utils.SendJSON(w, listJobsError.Message, false, listJobsError.Type, nil)
 		return
 	}
 
-	offsetParam, err := utils.ValidateQueryString("offset", r)
+	utils.SendJSON(w, jobs, true, http.StatusOK, nil)
+}
+
+// PauseJobs handles request to pause every job matching the query filters
+func (jobController *jobHTTPController) PauseJobs(w http.ResponseWriter, r *http.Request) {
+	jobController.updateJobsStatus(w, r, models.JobStatusPaused)
+}
+
+// ResumeJobs handles request to resume every job matching the query filters
+func (jobController *jobHTTPController) ResumeJobs(w http.ResponseWriter, r *http.Request) {
+	jobController.updateJobsStatus(w, r, models.JobStatusActive)
+}
+
+func (jobController *jobHTTPController) updateJobsStatus(w http.ResponseWriter, r *http.Request, status models.JobStatus) {
+	filter, err := parseJobFilter(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetParam)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+	affected, updateErr := jobController.jobService.UpdateJobsStatus(filter, status)
+	if updateErr != nil {
+		utils.SendJSON(w, updateErr.Message, false, updateErr.Type, nil)
 		return
 	}
 
-	limit, err := strconv.Atoi(limitParam)
+	utils.SendJSON(w, models.BulkJobsResult{Affected: affected}, true, http.StatusOK, nil)
+}
+
+// DeleteJobs handles request to delete every job matching the query filters
+func (jobController *jobHTTPController) DeleteJobs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseJobFilter(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	jobs, getJobsByProjectIDError := jobController.jobService.GetJobsByProjectID(uint64(projectID), uint64(offset), uint64(limit), "date_created")
-	if getJobsByProjectIDError != nil {
-		utils.SendJSON(w, getJobsByProjectIDError.Message, false, getJobsByProjectIDError.Type, nil)
+	affected, deleteErr := jobController.jobService.DeleteJobs(filter)
+	if deleteErr != nil {
+		utils.SendJSON(w, deleteErr.Message, false, deleteErr.Type, nil)
 		return
 	}
 
-	utils.SendJSON(w, jobs, true, http.StatusOK, nil)
+	utils.SendJSON(w, models.BulkJobsResult{Affected: affected}, true, http.StatusOK, nil)
+}
+
+// parseJobFilter extracts the project id, label selector, callback url prefix, execution type and status query parameters
+func parseJobFilter(r *http.Request) (models.JobFilter, error) {
+	filter := models.JobFilter{}
+
+	projectIDQueryParam, err := utils.ValidateQueryString("projectId", r)
+	if err != nil {
+		return filter, err
+	}
+
+	projectID, err := strconv.Atoi(projectIDQueryParam)
+	if err != nil {
+		return filter, err
+	}
+	filter.ProjectID = uint64(projectID)
+
+	query := r.URL.Query()
+
+	labelSelector, err := utils.ParseLabelSelector(query.Get("labels"))
+	if err != nil {
+		return filter, err
+	}
+	filter.LabelSelector = labelSelector
+	filter.CallbackUrlPrefix = query.Get("callbackUrlPrefix")
+	filter.ExecutionType = query.Get("executionType")
+	filter.Status = models.JobStatus(query.Get("status"))
+	filter.Actor = actorFromRequest(r)
+
+	return filter, nil
 }
 
 // BatchCreateJobs handles request to job in batches
@@ -98,9 +161,35 @@ func (jobController *jobHTTPController) BatchCreateJobs(w http.ResponseWriter, r
 		return
 	}
 
+	actor := actorFromRequest(r)
+	for i := range jobs {
+		if err := ensureProjectAccess(r, jobs[i].ProjectID); err != nil {
+			utils.SendJSON(w, err.Message, false, err.Type, nil)
+			return
+		}
+		jobs[i].Actor = actor
+	}
+
 	requestId := r.Context().Value("RequestID")
 
-	createdJobs, err := jobController.jobService.BatchInsertJobs(requestId.(string), jobs)
+	idempotencyKey := r.Header.Get(headers.IdempotencyKeyHeader)
+	if idempotencyKey != "" {
+		createdJobs, taskRequestId, err := jobController.jobService.BatchInsertJobsWithIdempotencyKey(r.Context(), requestId.(string), idempotencyKey, jobs)
+		if err != nil {
+			utils.SendJSON(w, err.Message, false, err.Type, nil)
+			return
+		}
+
+		if taskRequestId != requestId.(string) {
+			w.Header().Set(headers.IdempotentReplayedHeader, "true")
+		}
+		w.Header().Set("Location", fmt.Sprintf("/async-tasks/%s", taskRequestId))
+
+		utils.SendJSON(w, createdJobs, true, http.StatusAccepted, nil)
+		return
+	}
+
+	createdJobs, err := jobController.jobService.BatchInsertJobs(r.Context(), requestId.(string), jobs)
 	if err != nil {
 		utils.SendJSON(w, err.Message, false, http.StatusBadRequest, nil)
 		return
@@ -112,6 +201,76 @@ func (jobController *jobHTTPController) BatchCreateJobs(w http.ResponseWriter, r
 	return
 }
 
+// BatchUpdateJobs handles request to update jobs in batches
+func (jobController *jobHTTPController) BatchUpdateJobs(w http.ResponseWriter, r *http.Request) {
+	body := utils.ExtractBody(w, r)
+
+	if body == nil {
+		return
+	}
+
+	jobs := []models.Job{}
+	if err := json.Unmarshal(body, &jobs); err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusUnprocessableEntity, nil)
+		return
+	}
+
+	actor := actorFromRequest(r)
+	for i := range jobs {
+		if err := jobController.ensureJobChange(r, jobs[i]); err != nil {
+			utils.SendJSON(w, err.Message, false, err.Type, nil)
+			return
+		}
+		jobs[i].Actor = actor
+	}
+
+	requestId := r.Context().Value("RequestID")
+
+	taskIds, err := jobController.jobService.BatchUpdateJobs(r.Context(), requestId.(string), jobs)
+	if err != nil {
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/async-tasks/%s", requestId))
+
+	utils.SendJSON(w, taskIds, true, http.StatusAccepted, nil)
+}
+
+// BatchDeleteJobs handles request to delete jobs in batches
+func (jobController *jobHTTPController) BatchDeleteJobs(w http.ResponseWriter, r *http.Request) {
+	body := utils.ExtractBody(w, r)
+
+	if body == nil {
+		return
+	}
+
+	jobIds := []uint64{}
+	if err := json.Unmarshal(body, &jobIds); err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusUnprocessableEntity, nil)
+		return
+	}
+
+	for _, jobId := range jobIds {
+		if err := ensureJobAccess(r, jobController.jobService, jobId); err != nil {
+			utils.SendJSON(w, err.Message, false, err.Type, nil)
+			return
+		}
+	}
+
+	requestId := r.Context().Value("RequestID")
+
+	taskIds, err := jobController.jobService.BatchDeleteJobs(r.Context(), requestId.(string), jobIds, actorFromRequest(r))
+	if err != nil {
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/async-tasks/%s", requestId))
+
+	utils.SendJSON(w, taskIds, true, http.StatusAccepted, nil)
+}
+
 // GetOneJob handles request to return a single job
 func (jobController *jobHTTPController) GetOneJob(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
@@ -132,7 +291,12 @@ func (jobController *jobHTTPController) GetOneJob(w http.ResponseWriter, r *http
 		return
 	}
 
-	utils.SendJSON(w, jobT, true, http.StatusOK, nil)
+	if err := ensureProjectAccess(r, jobT.ProjectID); err != nil {
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, jobT, true, http.StatusOK, etagHeaders(jobT.Version))
 }
 
 // UpdateOneJob handles request to update a single job
@@ -150,19 +314,31 @@ func (jobController *jobHTTPController) UpdateOneJob(w http.ResponseWriter, r *h
 	}
 
 	jobBody.ID = uint64(jobID)
+	jobBody.Actor = actorFromRequest(r)
+
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
 
+	jobBody.Version, err = parseIfMatch(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
+	if accessErr := jobController.ensureJobChange(r, jobBody); accessErr != nil {
+		utils.SendJSON(w, accessErr.Message, false, accessErr.Type, nil)
+		return
+	}
+
 	jobT, updateOneJobError := jobController.jobService.UpdateJob(jobBody)
 	if updateOneJobError != nil {
 		utils.SendJSON(w, updateOneJobError.Message, false, updateOneJobError.Type, nil)
 		return
 	}
 
-	utils.SendJSON(w, jobT, true, http.StatusOK, nil)
+	utils.SendJSON(w, jobT, true, http.StatusOK, etagHeaders(jobT.Version))
 }
 
 // DeleteOneJob handles request to delete a single job
@@ -175,8 +351,21 @@ func (jobController *jobHTTPController) DeleteOneJob(w http.ResponseWriter, r *h
 		return
 	}
 
+	version, err := parseIfMatch(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	if accessErr := ensureJobAccess(r, jobController.jobService, uint64(jobID)); accessErr != nil {
+		utils.SendJSON(w, accessErr.Message, false, accessErr.Type, nil)
+		return
+	}
+
 	job := models.Job{
-		ID: uint64(jobID),
+		ID:      uint64(jobID),
+		Actor:   actorFromRequest(r),
+		Version: version,
 	}
 
 	deleteOneJobError := jobController.jobService.DeleteJob(job)
@@ -187,3 +376,92 @@ func (jobController *jobHTTPController) DeleteOneJob(w http.ResponseWriter, r *h
 
 	utils.SendJSON(w, nil, true, http.StatusNoContent, nil)
 }
+
+// ListJobRevisions handles request to return the revisions of a job
+func (jobController *jobHTTPController) ListJobRevisions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	jobID, convertErr := strconv.Atoi(params["id"])
+	if convertErr != nil {
+		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	limitParam, err := utils.ValidateQueryString("limit", r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	offsetParam, err := utils.ValidateQueryString("offset", r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetParam)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	if accessErr := ensureJobAccess(r, jobController.jobService, uint64(jobID)); accessErr != nil {
+		utils.SendJSON(w, accessErr.Message, false, accessErr.Type, nil)
+		return
+	}
+
+	revisions, getRevisionsErr := jobController.jobService.GetJobRevisions(uint64(jobID), uint64(offset), uint64(limit))
+	if getRevisionsErr != nil {
+		utils.SendJSON(w, getRevisionsErr.Message, false, getRevisionsErr.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, revisions, true, http.StatusOK, nil)
+}
+
+// RollbackJob handles request to restore a job to one of its revisions
+func (jobController *jobHTTPController) RollbackJob(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	jobID, convertErr := strconv.Atoi(params["id"])
+	if convertErr != nil {
+		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	revision, convertErr := strconv.Atoi(params["revision"])
+	if convertErr != nil {
+		utils.SendJSON(w, convertErr.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	if accessErr := ensureJobAccess(r, jobController.jobService, uint64(jobID)); accessErr != nil {
+		utils.SendJSON(w, accessErr.Message, false, accessErr.Type, nil)
+		return
+	}
+
+	jobT, rollbackErr := jobController.jobService.RollbackJob(uint64(jobID), uint64(revision), actorFromRequest(r))
+	if rollbackErr != nil {
+		utils.SendJSON(w, rollbackErr.Message, false, rollbackErr.Type, nil)
+		return
+	}
+
+	utils.SendJSON(w, jobT, true, http.StatusOK, etagHeaders(jobT.Version))
+}
+
+// ensureJobChange fails when the principal of the request cannot access the job, or the project the job is moved to
+func (jobController *jobHTTPController) ensureJobChange(r *http.Request, jobT models.Job) *utils.GenericError {
+	if err := ensureJobAccess(r, jobController.jobService, jobT.ID); err != nil {
+		return err
+	}
+	if jobT.ProjectID != 0 {
+		return ensureProjectAccess(r, jobT.ProjectID)
+	}
+	return nil
+}
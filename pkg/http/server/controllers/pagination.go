@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"strconv"
+)
+
+// parsePageRequest extracts the limit, offset, cursor, orderBy and order query parameters of a list.
+// The offset is optional and ignored when a cursor is given.
+func parsePageRequest(r *http.Request) (models.PageRequest, error) {
+	page := models.PageRequest{}
+	query := r.URL.Query()
+
+	limitParam, err := utils.ValidateQueryString("limit", r)
+	if err != nil {
+		return page, err
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = uint64(limit)
+
+	page.Cursor = query.Get("cursor")
+	if offsetParam := query.Get("offset"); offsetParam != "" && page.Cursor == "" {
+		offset, err := strconv.Atoi(offsetParam)
+		if err != nil {
+			return page, err
+		}
+		page.Offset = uint64(offset)
+	}
+
+	page.OrderBy = query.Get("orderBy")
+	page.Order = query.Get("order")
+
+	return page, nil
+}
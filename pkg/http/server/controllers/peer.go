@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"github.com/hashicorp/go-hclog"
 	"net/http"
 	"scheduler0/pkg/config"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/service/audit"
+	"scheduler0/pkg/service/credential"
 	"scheduler0/pkg/service/node"
 	"scheduler0/pkg/utils"
 )
@@ -14,19 +18,25 @@ type PeerController interface {
 	ExecutionLogs(w http.ResponseWriter, r *http.Request)
 	StopJobs(w http.ResponseWriter, r *http.Request)
 	StartJobs(w http.ResponseWriter, r *http.Request)
+	ReadIndex(w http.ResponseWriter, r *http.Request)
+	CredentialUsage(w http.ResponseWriter, r *http.Request)
 }
 
 type peerController struct {
-	scheduler0Config config.Scheduler0Config
-	logger           *log.Logger
-	peer             node.NodeService
+	scheduler0Config  config.Scheduler0Config
+	logger            hclog.Logger
+	peer              node.NodeService
+	auditService      audit.AuditService
+	credentialService credential.CredentialService
 }
 
-func NewPeerController(logger *log.Logger, scheduler0Config config.Scheduler0Config, peer node.NodeService) PeerController {
+func NewPeerController(logger hclog.Logger, scheduler0Config config.Scheduler0Config, peer node.NodeService, auditService audit.AuditService, credentialService credential.CredentialService) PeerController {
 	controller := peerController{
-		scheduler0Config: scheduler0Config,
-		logger:           logger,
-		peer:             peer,
+		scheduler0Config:  scheduler0Config,
+		logger:            logger,
+		peer:              peer,
+		auditService:      auditService,
+		credentialService: credentialService,
 	}
 	return &controller
 }
@@ -54,12 +64,48 @@ func (controller *peerController) ExecutionLogs(w http.ResponseWriter, r *http.R
 
 func (controller *peerController) StopJobs(w http.ResponseWriter, r *http.Request) {
 	controller.peer.StopJobs()
+	controller.recordNodeAction(r, models.AuditActionStopJobs)
 	utils.SendJSON(w, nil, true, http.StatusAccepted, nil)
 	return
 }
 
 func (controller *peerController) StartJobs(w http.ResponseWriter, r *http.Request) {
 	controller.peer.StartJobs()
+	controller.recordNodeAction(r, models.AuditActionStartJobs)
 	utils.SendJSON(w, nil, true, http.StatusAccepted, nil)
 	return
 }
+
+func (controller *peerController) ReadIndex(w http.ResponseWriter, r *http.Request) {
+	readIndex, err := controller.peer.ReadIndex(r.Context())
+	if err != nil {
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+	utils.SendJSON(w, models.ReadIndex{Index: readIndex}, true, http.StatusOK, nil)
+	return
+}
+
+// CredentialUsage notes the usage of the credentials a follower recorded, it is written with the usage of the next flush.
+// A node that is no longer the leader sends it on to the new leader.
+func (controller *peerController) CredentialUsage(w http.ResponseWriter, r *http.Request) {
+	var usages []models.CredentialUsage
+	if err := json.NewDecoder(r.Body).Decode(&usages); err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
+	controller.credentialService.MergeUsage(usages)
+	utils.SendJSON(w, nil, true, http.StatusAccepted, nil)
+	return
+}
+
+// recordNodeAction audits an admin action taken on this node, the action is taken even when it cannot be recorded
+func (controller *peerController) recordNodeAction(r *http.Request, action models.AuditAction) {
+	controller.auditService.Record(models.AuditEvent{
+		Action:     action,
+		Resource:   models.AuditResourceNode,
+		ResourceID: controller.scheduler0Config.GetConfigurations().NodeId,
+		Actor:      actorFromRequest(r),
+	})
+}
@@ -3,9 +3,10 @@ package controllers
 import (
 	"errors"
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"scheduler0/pkg/logging"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/service/project"
 	"scheduler0/pkg/utils"
@@ -14,7 +15,7 @@ import (
 
 type projectController struct {
 	projectService project.ProjectService
-	logger         *log.Logger
+	logger         hclog.Logger
 }
 
 type ProjectHTTPController interface {
@@ -25,7 +26,7 @@ type ProjectHTTPController interface {
 	UpdateOneProject(w http.ResponseWriter, r *http.Request)
 }
 
-func NewProjectController(logger *log.Logger, projectService project.ProjectService) ProjectHTTPController {
+func NewProjectController(logger hclog.Logger, projectService project.ProjectService) ProjectHTTPController {
 	return &projectController{
 		projectService: projectService,
 		logger:         logger,
@@ -35,7 +36,9 @@ func NewProjectController(logger *log.Logger, projectService project.ProjectServ
 func (controller *projectController) CreateOneProject(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		controller.logger.Fatalln(err)
+		logging.FromContext(r.Context(), controller.logger).Error("failed to read request body", "error", err)
+		utils.SendJSON(w, "failed to read request body", false, http.StatusBadRequest, nil)
+		return
 	}
 
 	project := models.Project{}
@@ -44,6 +47,7 @@ func (controller *projectController) CreateOneProject(w http.ResponseWriter, r *
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
+	project.Actor = actorFromRequest(r)
 
 	projectTransformer, createOneError := controller.projectService.CreateOne(project)
 	if createOneError != nil {
@@ -73,35 +77,28 @@ func (controller *projectController) GetOneProject(w http.ResponseWriter, r *htt
 		return
 	}
 
-	utils.SendJSON(w, project, true, http.StatusOK, nil)
+	utils.SendJSON(w, project, true, http.StatusOK, etagHeaders(project.Version))
 }
 
 func (controller *projectController) ListProjects(w http.ResponseWriter, r *http.Request) {
-	limitParam, err := utils.ValidateQueryString("limit", r)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
-		return
-	}
-
-	offsetParam, err := utils.ValidateQueryString("offset", r)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
-		return
-	}
-
-	offset, err := strconv.Atoi(offsetParam)
+	page, err := parsePageRequest(r)
 	if err != nil {
 		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
 		return
 	}
 
-	limit, err := strconv.Atoi(limitParam)
-	if err != nil {
-		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+	// Credentials limited to some projects list those projects, in a single page
+	if principal := principalFromRequest(r); !principal.AllProjects() {
+		scopedProjects, batchErr := controller.projectService.BatchGetProjects(principal.ProjectIDs)
+		if batchErr != nil {
+			utils.SendJSON(w, batchErr.Message, false, batchErr.Type, nil)
+			return
+		}
+		utils.SendJSON(w, models.PaginatedProject{Total: uint64(len(scopedProjects)), Limit: uint64(len(scopedProjects)), Data: scopedProjects}, true, http.StatusOK, nil)
 		return
 	}
 
-	projects, listError := controller.projectService.List(uint64(offset), uint64(limit))
+	projects, listError := controller.projectService.List(page)
 	if listError != nil {
 		utils.SendJSON(w, listError.Message, false, listError.Type, nil)
 		return
@@ -119,8 +116,16 @@ func (controller *projectController) DeleteOneProject(w http.ResponseWriter, r *
 		return
 	}
 
+	version, ifMatchErr := parseIfMatch(r)
+	if ifMatchErr != nil {
+		utils.SendJSON(w, ifMatchErr.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
+
 	project := models.Project{
-		ID: uint64(projectId),
+		ID:      uint64(projectId),
+		Version: version,
+		Actor:   actorFromRequest(r),
 	}
 
 	err := controller.projectService.DeleteOneByID(project)
@@ -143,7 +148,9 @@ func (controller *projectController) UpdateOneProject(w http.ResponseWriter, r *
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		controller.logger.Fatalln(err)
+		logging.FromContext(r.Context(), controller.logger).Error("failed to read request body", "error", err)
+		utils.SendJSON(w, "failed to read request body", false, http.StatusBadRequest, nil)
+		return
 	}
 	project := models.Project{}
 
@@ -154,6 +161,12 @@ func (controller *projectController) UpdateOneProject(w http.ResponseWriter, r *
 	}
 
 	project.ID = uint64(projectId)
+	project.Actor = actorFromRequest(r)
+	project.Version, err = parseIfMatch(r)
+	if err != nil {
+		utils.SendJSON(w, err.Error(), false, http.StatusBadRequest, nil)
+		return
+	}
 
 	updateError := controller.projectService.UpdateOneByID(&project)
 	if updateError != nil {
@@ -161,5 +174,5 @@ func (controller *projectController) UpdateOneProject(w http.ResponseWriter, r *
 		return
 	}
 
-	utils.SendJSON(w, project, true, http.StatusOK, nil)
+	utils.SendJSON(w, project, true, http.StatusOK, etagHeaders(project.Version))
 }
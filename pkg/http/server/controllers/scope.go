@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"scheduler0/pkg/logging"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/service/job"
+	"scheduler0/pkg/utils"
+)
+
+// principalFromRequest returns the principal the auth middleware attached to the request.
+// Requests served without the middleware can access every project.
+func principalFromRequest(r *http.Request) models.Principal {
+	principal, ok := models.PrincipalFromContext(r.Context())
+	if !ok {
+		return models.PeerPrincipal()
+	}
+	return principal
+}
+
+// actorFromRequest returns the actor the changes made by the request are audited as
+func actorFromRequest(r *http.Request) models.Actor {
+	return principalFromRequest(r).Actor(logging.RequestIDFromContext(r.Context()))
+}
+
+// ensureProjectAccess fails with 403 when the principal of the request cannot access the project
+func ensureProjectAccess(r *http.Request, projectId uint64) *utils.GenericError {
+	if !principalFromRequest(r).CanAccessProject(projectId) {
+		return utils.HTTPGenericError(http.StatusForbidden, fmt.Sprintf("credential cannot access project %d", projectId))
+	}
+	return nil
+}
+
+// ensureJobAccess fails with 403 when the principal of the request cannot access the project of the job.
+// The job is only read for principals limited to some projects.
+func ensureJobAccess(r *http.Request, jobService job.JobService, jobId uint64) *utils.GenericError {
+	if principalFromRequest(r).AllProjects() {
+		return nil
+	}
+
+	jobT, err := jobService.GetJob(models.Job{ID: jobId})
+	if err != nil {
+		return err
+	}
+	return ensureProjectAccess(r, jobT.ProjectID)
+}
+
+// ensureTaskAccess fails with 403 when the principal of the request cannot access every project the async task changes.
+// Tasks that are not tied to projects can only be read by principals that can access every project.
+func ensureTaskAccess(r *http.Request, task *models.AsyncTask) *utils.GenericError {
+	if principalFromRequest(r).AllProjects() {
+		return nil
+	}
+
+	if len(task.ProjectIDs) == 0 {
+		return utils.HTTPGenericError(http.StatusForbidden, fmt.Sprintf("credential cannot access task %d", task.Id))
+	}
+	for _, projectId := range task.ProjectIDs {
+		if err := ensureProjectAccess(r, projectId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,26 +3,36 @@ package middlewares
 import (
 	"context"
 	"fmt"
+	"github.com/hashicorp/go-hclog"
 	"github.com/segmentio/ksuid"
-	"log"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"scheduler0/pkg/certificates"
 	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
 	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/logging"
+	"scheduler0/pkg/models"
 	"scheduler0/pkg/secrets"
 	"scheduler0/pkg/service/credential"
 	"scheduler0/pkg/service/node"
 	"scheduler0/pkg/utils"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // middlewareHandler middleware type
 type middlewareHandler struct {
-	logger           *log.Logger
+	logger           hclog.Logger
 	doOnce           sync.Once
 	ctx              context.Context
 	scheduler0Secret secrets.Scheduler0Secrets
 	scheduler0Config config.Scheduler0Config
+	servedBy         string
+	forwardTransport http.RoundTripper
 }
 
 type MiddlewareHandler interface {
@@ -31,21 +41,58 @@ type MiddlewareHandler interface {
 	EnsureRaftLeaderMiddleware(peer node.NodeService) func(next http.Handler) http.Handler
 }
 
-func NewMiddlewareHandler(logger *log.Logger, scheduler0Secret secrets.Scheduler0Secrets, scheduler0Config config.Scheduler0Config) MiddlewareHandler {
+// NewMiddlewareHandler returns the middlewares of the api, requests forwarded to the leader present the certificate of the node when certs is not nil
+func NewMiddlewareHandler(logger hclog.Logger, scheduler0Secret secrets.Scheduler0Secrets, scheduler0Config config.Scheduler0Config, certs *certificates.Reloader) MiddlewareHandler {
 	return &middlewareHandler{
 		logger:           logger,
 		scheduler0Secret: scheduler0Secret,
 		scheduler0Config: scheduler0Config,
+		forwardTransport: certs.Transport(),
 	}
 }
 
-// ContextMiddleware context middleware
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ContextMiddleware gives every request an id, returned in the X-Request-Id header and added to the log lines of the request
 func (m *middlewareHandler) ContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only nodes can tell the leader they forwarded a request, clients cannot make it trust their X-Forwarded-For
+		if r.Header.Get(headers.ForwardedByHeader) != "" && !m.isForwardedByPeer(r) {
+			r.Header.Del(headers.ForwardedByHeader)
+		}
+
 		id := ksuid.New().String()
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, "RequestID", id)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		ctx := logging.ContextWithRequestID(r.Context(), id)
+		w.Header().Set(headers.RequestIDHeader, id)
+		w.Header().Set(headers.ServedByHeader, m.nodeId())
+
+		startTime := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		logging.FromContext(ctx, m.logger).Info(
+			"served request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration-ms", time.Since(startTime).Milliseconds(),
+			"remote-address", r.RemoteAddr,
+		)
 	})
 }
 
@@ -65,9 +112,14 @@ func (m *middlewareHandler) AuthMiddleware(credentialService credential.Credenti
 				return
 			}
 
+			if m.scheduler0Config.GetConfigurations().PeerMTLS && (IsPeerClient(r) || IsPeerEndpoint(paths[3])) && !HasVerifiedClientCertificate(r) {
+				utils.SendJSON(w, "peer requests require a verified client certificate", false, http.StatusUnauthorized, nil)
+				return
+			}
+
 			if IsServerClient(r) {
-				if validity, _ := IsAuthorizedServerClient(r, credentialService); validity {
-					next.ServeHTTP(w, r)
+				if credential, validity := IsAuthorizedServerClient(r, credentialService); validity {
+					m.serveAuthorized(w, r, next, models.CredentialPrincipal(*credential), paths)
 					return
 				} else {
 					utils.SendJSON(w, "unauthorized requests", false, http.StatusUnauthorized, nil)
@@ -77,7 +129,7 @@ func (m *middlewareHandler) AuthMiddleware(credentialService credential.Credenti
 
 			if IsPeerClient(r) {
 				if validity := IsAuthorizedPeerClient(r, m.scheduler0Secret); validity {
-					next.ServeHTTP(w, r)
+					m.serveAuthorized(w, r, next, models.PeerPrincipal(), paths)
 					return
 				} else {
 					utils.SendJSON(w, "unauthorized requests", false, http.StatusUnauthorized, nil)
@@ -91,6 +143,22 @@ func (m *middlewareHandler) AuthMiddleware(credentialService credential.Credenti
 	}
 }
 
+// serveAuthorized serves the request with the principal in its context if the principal is allowed to make it
+func (m *middlewareHandler) serveAuthorized(w http.ResponseWriter, r *http.Request, next http.Handler, principal models.Principal, paths []string) {
+	if err := authorize(principal, r, paths); err != nil {
+		logging.FromContext(r.Context(), m.logger).Debug("rejecting request", "credential-id", principal.CredentialID, "error", err.Message)
+		utils.SendJSON(w, err.Message, false, err.Type, nil)
+		return
+	}
+
+	principal.Address = clientAddress(r)
+	ctx := models.ContextWithPrincipal(r.Context(), principal)
+	if !principal.Peer {
+		ctx = context.WithValue(ctx, "CredentialID", principal.CredentialID)
+	}
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // EnsureRaftLeaderMiddleware ensures that the current node is the leader of the raft cluster
 func (m *middlewareHandler) EnsureRaftLeaderMiddleware(peer node.NodeService) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -102,7 +170,7 @@ func (m *middlewareHandler) EnsureRaftLeaderMiddleware(peer node.NodeService) fu
 				return
 			}
 
-			if paths[3] == "peer-handshake" {
+			if paths[3] == "peer-handshake" || paths[3] == "api-docs" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -113,42 +181,41 @@ func (m *middlewareHandler) EnsureRaftLeaderMiddleware(peer node.NodeService) fu
 			}
 
 			if !peer.CanAcceptClientWriteRequest() && (r.Method == http.MethodPost || r.Method == http.MethodDelete || r.Method == http.MethodPut) {
-				if paths[3] == "start-jobs" || paths[3] == "stop-jobs" {
+				if paths[3] == "start-jobs" || paths[3] == "stop-jobs" || paths[3] == "credential-usage" {
 					next.ServeHTTP(w, r)
 					return
 				}
 
-				configs := m.scheduler0Config.GetConfigurations()
-				serverAddr, _ := peer.GetRaftLeaderWithId()
+				m.sendToLeader(w, r, peer)
+				return
+			}
 
-				redirectUrl := ""
+			if r.Method == http.MethodGet && (paths[3] == "jobs" || paths[3] == "projects" || paths[3] == "credentials") {
+				consistency := r.Header.Get(headers.ReadConsistencyHeader)
+				if consistency == "" {
+					consistency = constants.ReadConsistencyStale
+				}
 
-				for _, leaderPeer := range configs.Replicas {
-					if leaderPeer.RaftAddress == string(serverAddr) {
-						redirectUrl = leaderPeer.Address
-						break
+				var maxStaleness time.Duration
+				if staleness := r.Header.Get(headers.MaxStalenessHeader); staleness != "" {
+					parsed, parseErr := time.ParseDuration(staleness)
+					if parseErr != nil {
+						utils.SendJSON(w, fmt.Sprintf("invalid %s %s", headers.MaxStalenessHeader, staleness), false, http.StatusBadRequest, nil)
+						return
 					}
+					maxStaleness = parsed
 				}
 
-				if redirectUrl == "" {
-					m.logger.Println("failed to get redirect url from replicas")
-					utils.SendJSON(w, "service is unavailable", false, http.StatusServiceUnavailable, nil)
+				if consistency == constants.ReadConsistencyLeader && !peer.CanAcceptClientWriteRequest() {
+					m.sendToLeader(w, r, peer)
 					return
 				}
 
-				redirectUrl = fmt.Sprintf("%s%s", redirectUrl, r.URL.Path)
-
-				w.Header().Set("Location", redirectUrl)
-				requester := r.Header.Get(headers.PeerHeader)
-
-				if requester == headers.PeerHeaderCMDValue || requester == headers.PeerHeaderValue {
-					m.logger.Println("Redirecting request to leader", redirectUrl)
-					http.Redirect(w, r, redirectUrl, 301)
-				} else {
-					utils.SendJSON(w, nil, false, http.StatusFound, nil)
+				if consistencyErr := peer.EnsureReadConsistency(r.Context(), consistency, maxStaleness); consistencyErr != nil {
+					logging.FromContext(r.Context(), m.logger).Debug("cannot serve read", "consistency", consistency, "error", consistencyErr.Message)
+					utils.SendJSON(w, consistencyErr.Message, false, consistencyErr.Type, nil)
+					return
 				}
-
-				return
 			}
 
 			next.ServeHTTP(w, r)
@@ -156,3 +223,106 @@ func (m *middlewareHandler) EnsureRaftLeaderMiddleware(peer node.NodeService) fu
 		})
 	}
 }
+
+// sendToLeader redirects the request to the leader, or forwards the requests of clients when ForwardWritesToLeader is enabled
+func (m *middlewareHandler) sendToLeader(w http.ResponseWriter, r *http.Request, peer node.NodeService) {
+	if forwardedBy := r.Header.Get(headers.ForwardedByHeader); forwardedBy != "" {
+		// The leader changed while the request was forwarded, forwarding it again could loop between followers
+		logging.FromContext(r.Context(), m.logger).Warn("rejecting request forwarded to a node that is not the leader", "forwarded-by", forwardedBy)
+		utils.SendJSON(w, "request was forwarded to a node that is not the leader", false, http.StatusServiceUnavailable, nil)
+		return
+	}
+
+	configs := m.scheduler0Config.GetConfigurations()
+	serverAddr, _ := peer.GetRaftLeaderWithId()
+
+	redirectUrl := ""
+
+	for _, leaderPeer := range configs.Replicas {
+		if leaderPeer.RaftAddress == string(serverAddr) {
+			redirectUrl = leaderPeer.Address
+			break
+		}
+	}
+
+	if redirectUrl == "" {
+		logging.FromContext(r.Context(), m.logger).Error("failed to get redirect url from replicas")
+		utils.SendJSON(w, "service is unavailable", false, http.StatusServiceUnavailable, nil)
+		return
+	}
+
+	requester := r.Header.Get(headers.PeerHeader)
+	isPeer := requester == headers.PeerHeaderCMDValue || requester == headers.PeerHeaderValue
+
+	if !isPeer && configs.ForwardWritesToLeader {
+		m.forwardToLeader(w, r, redirectUrl)
+		return
+	}
+
+	redirectUrl = fmt.Sprintf("%s%s", redirectUrl, r.URL.Path)
+	if r.URL.RawQuery != "" {
+		redirectUrl = fmt.Sprintf("%s?%s", redirectUrl, r.URL.RawQuery)
+	}
+
+	w.Header().Set("Location", redirectUrl)
+
+	if isPeer {
+		logging.FromContext(r.Context(), m.logger).Debug("redirecting request to leader", "redirect-url", redirectUrl)
+		http.Redirect(w, r, redirectUrl, 301)
+	} else {
+		utils.SendJSON(w, nil, false, http.StatusFound, nil)
+	}
+}
+
+// forwardToLeader proxies the request, body included, to the leader and copies its response back to the client.
+// The leader answers with its own request id and node id, and rejects the request if it is no longer the leader.
+func (m *middlewareHandler) forwardToLeader(w http.ResponseWriter, r *http.Request, leaderAddress string) {
+	logger := logging.FromContext(r.Context(), m.logger)
+
+	leaderUrl, parseErr := url.Parse(leaderAddress)
+	if parseErr != nil {
+		logger.Error("failed to parse leader address", "leader-address", leaderAddress, "error", parseErr.Error())
+		utils.SendJSON(w, "service is unavailable", false, http.StatusServiceUnavailable, nil)
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(leaderUrl)
+	proxy.Transport = m.forwardTransport
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = leaderUrl.Host
+		req.Header.Set(headers.ForwardedByHeader, m.nodeId())
+		// The client authenticates with its api key, the peer credentials authenticate the forwarding node
+		credentials := m.scheduler0Secret.GetSecrets()
+		req.SetBasicAuth(credentials.AuthUsername, credentials.AuthPassword)
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		logger.Error("failed to forward request to leader", "leader-address", leaderAddress, "error", err.Error())
+		utils.SendJSON(w, "failed to forward request to the leader", false, http.StatusBadGateway, nil)
+	}
+
+	logger.Debug("forwarding request to leader", "leader-address", leaderAddress)
+
+	// The response headers of the leader replace the ones set by this node
+	w.Header().Del(headers.RequestIDHeader)
+	w.Header().Del(headers.ServedByHeader)
+	proxy.ServeHTTP(w, r)
+}
+
+// isForwardedByPeer returns true if the request was forwarded by a node, which authenticates with the peer
+// credentials and, when PeerMTLS is set, a verified client certificate
+func (m *middlewareHandler) isForwardedByPeer(r *http.Request) bool {
+	if m.scheduler0Config.GetConfigurations().PeerMTLS && !HasVerifiedClientCertificate(r) {
+		return false
+	}
+	return IsAuthorizedPeerClient(r, m.scheduler0Secret)
+}
+
+// nodeId returns the id of this node, read once as the configurations are read from disk
+func (m *middlewareHandler) nodeId() string {
+	m.doOnce.Do(func() {
+		m.servedBy = strconv.FormatUint(m.scheduler0Config.GetConfigurations().NodeId, 10)
+	})
+	return m.servedBy
+}
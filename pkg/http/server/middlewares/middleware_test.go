@@ -0,0 +1,248 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/secrets"
+	"scheduler0/pkg/utils"
+	"testing"
+	"time"
+)
+
+// testNode stands in for the node service of a follower of the cluster, it records the consistency of the last read
+type testNode struct {
+	isLeader       bool
+	consistencyErr *utils.GenericError
+	consistency    string
+	maxStaleness   time.Duration
+}
+
+func (node *testNode) Start()                              {}
+func (node *testNode) GetUncommittedLogs(requestId string) {}
+func (node *testNode) CanAcceptClientWriteRequest() bool   { return node.isLeader }
+func (node *testNode) StopJobs()                           {}
+func (node *testNode) StartJobs()                          {}
+func (node *testNode) GetRaftStats() map[string]string     { return map[string]string{} }
+func (node *testNode) CanAcceptRequest() bool              { return true }
+func (node *testNode) ReadIndex(ctx context.Context) (uint64, *utils.GenericError) {
+	return 0, nil
+}
+func (node *testNode) EnsureReadConsistency(ctx context.Context, consistency string, maxStaleness time.Duration) *utils.GenericError {
+	node.consistency = consistency
+	node.maxStaleness = maxStaleness
+	return node.consistencyErr
+}
+func (node *testNode) ForwardCredentialUsage(ctx context.Context, usages []models.CredentialUsage) *utils.GenericError {
+	return nil
+}
+func (node *testNode) GetRaftLeaderWithId() (raft.ServerAddress, raft.ServerID) {
+	return "leader-raft-address", "1"
+}
+
+type forwardedRequest struct {
+	method      string
+	path        string
+	query       string
+	body        string
+	forwardedBy string
+	peerUser    string
+}
+
+// newFollower serves the handler behind the middlewares of a follower whose leader records the requests it receives
+func newFollower(t *testing.T, forwardWrites bool, follower *testNode, handler http.Handler) (*httptest.Server, *[]forwardedRequest) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "middleware-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+
+	received := []forwardedRequest{}
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		peerUser, _, _ := r.BasicAuth()
+		received = append(received, forwardedRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			query:       r.URL.RawQuery,
+			body:        string(body),
+			forwardedBy: r.Header.Get(headers.ForwardedByHeader),
+			peerUser:    peerUser,
+		})
+		w.Header().Set(headers.ServedByHeader, "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	t.Cleanup(leader.Close)
+
+	replicas, err := json.Marshal([]config.RaftNode{
+		{Address: leader.URL, RaftAddress: "leader-raft-address", NodeId: 1},
+		{Address: "http://127.0.0.1:1", RaftAddress: "follower-raft-address", NodeId: 2},
+	})
+	assert.Nil(t, err)
+	t.Setenv("SCHEDULER0_REPLICAS", string(replicas))
+	t.Setenv("SCHEDULER0_NODE_ID", "2")
+	t.Setenv("SCHEDULER0_AUTH_USERNAME", "peer-user")
+	t.Setenv("SCHEDULER0_AUTH_PASSWORD", "peer-password")
+	if forwardWrites {
+		t.Setenv("SCHEDULER0_FORWARD_WRITES_TO_LEADER", "true")
+	} else {
+		t.Setenv("SCHEDULER0_FORWARD_WRITES_TO_LEADER", "false")
+	}
+
+	middleware := NewMiddlewareHandler(logger, secrets.NewScheduler0Secrets(), config.NewScheduler0Config(), nil)
+	followerServer := httptest.NewServer(middleware.ContextMiddleware(middleware.EnsureRaftLeaderMiddleware(follower)(handler)))
+	t.Cleanup(followerServer.Close)
+
+	return followerServer, &received
+}
+
+func noRedirectClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func Test_EnsureRaftLeaderMiddleware_ForwardsWritesToLeader(t *testing.T) {
+	follower, received := newFollower(t, true, &testNode{}, http.NotFoundHandler())
+
+	res, err := noRedirectClient().Post(follower.URL+"/api/v1/jobs?projectId=1", "application/json", bytes.NewBufferString(`[{"spec":"@every 1m"}]`))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, `[{"spec":"@every 1m"}]`, string(body))
+	assert.Equal(t, "1", res.Header.Get(headers.ServedByHeader))
+
+	assert.Equal(t, 1, len(*received))
+	assert.Equal(t, http.MethodPost, (*received)[0].method)
+	assert.Equal(t, "/api/v1/jobs", (*received)[0].path)
+	assert.Equal(t, "projectId=1", (*received)[0].query)
+	assert.Equal(t, `[{"spec":"@every 1m"}]`, (*received)[0].body)
+	assert.Equal(t, "2", (*received)[0].forwardedBy)
+	assert.Equal(t, "peer-user", (*received)[0].peerUser)
+}
+
+func Test_EnsureRaftLeaderMiddleware_RejectsForwardedWritesOnFollowers(t *testing.T) {
+	follower, received := newFollower(t, true, &testNode{}, http.NotFoundHandler())
+
+	req, err := http.NewRequest(http.MethodDelete, follower.URL+"/api/v1/jobs/1", nil)
+	assert.Nil(t, err)
+	req.Header.Set(headers.ForwardedByHeader, "3")
+	req.SetBasicAuth("peer-user", "peer-password")
+
+	res, err := noRedirectClient().Do(req)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, 0, len(*received))
+}
+
+func Test_EnsureRaftLeaderMiddleware_IgnoresForwardedByFromClients(t *testing.T) {
+	follower, received := newFollower(t, true, &testNode{}, http.NotFoundHandler())
+
+	req, err := http.NewRequest(http.MethodDelete, follower.URL+"/api/v1/jobs/1", nil)
+	assert.Nil(t, err)
+	req.Header.Set(headers.ForwardedByHeader, "3")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	res, err := noRedirectClient().Do(req)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	// The header of the client is dropped, so the write is forwarded as any other client write
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, 1, len(*received))
+	assert.Equal(t, "2", (*received)[0].forwardedBy)
+}
+
+func Test_EnsureRaftLeaderMiddleware_RedirectsWritesWhenForwardingIsDisabled(t *testing.T) {
+	follower, received := newFollower(t, false, &testNode{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	res, err := noRedirectClient().Post(follower.URL+"/api/v1/jobs", "application/json", bytes.NewBufferString(`[]`))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusFound, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Location"), "/api/v1/jobs")
+	assert.Equal(t, "2", res.Header.Get(headers.ServedByHeader))
+	assert.Equal(t, 0, len(*received))
+
+	// Reads are served by the follower
+	res, err = noRedirectClient().Get(follower.URL + "/api/v1/jobs")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func Test_EnsureRaftLeaderMiddleware_ReadConsistency(t *testing.T) {
+	node := &testNode{}
+	follower, received := newFollower(t, true, node, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	read := func(path string, consistency string, maxStaleness string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, follower.URL+path, nil)
+		assert.Nil(t, err)
+		if consistency != "" {
+			req.Header.Set(headers.ReadConsistencyHeader, consistency)
+		}
+		if maxStaleness != "" {
+			req.Header.Set(headers.MaxStalenessHeader, maxStaleness)
+		}
+		res, err := noRedirectClient().Do(req)
+		assert.Nil(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("stale reads are served by the follower", func(t *testing.T) {
+		res := read("/api/v1/projects/1", "", "5s")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, constants.ReadConsistencyStale, node.consistency)
+		assert.Equal(t, 5*time.Second, node.maxStaleness)
+	})
+
+	t.Run("linearizable reads are served by the follower", func(t *testing.T) {
+		res := read("/api/v1/credentials", constants.ReadConsistencyLinearizable, "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, constants.ReadConsistencyLinearizable, node.consistency)
+	})
+
+	t.Run("leader reads are forwarded to the leader", func(t *testing.T) {
+		res := read("/api/v1/jobs?projectId=1", constants.ReadConsistencyLeader, "")
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, "1", res.Header.Get(headers.ServedByHeader))
+		assert.Equal(t, 1, len(*received))
+		assert.Equal(t, http.MethodGet, (*received)[0].method)
+		assert.Equal(t, "projectId=1", (*received)[0].query)
+	})
+
+	t.Run("reads the follower cannot serve are rejected", func(t *testing.T) {
+		node.consistencyErr = utils.HTTPGenericError(http.StatusServiceUnavailable, "node is too stale")
+		defer func() { node.consistencyErr = nil }()
+
+		res := read("/api/v1/jobs/1", constants.ReadConsistencyStale, "1s")
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+
+	t.Run("invalid max staleness is rejected", func(t *testing.T) {
+		res := read("/api/v1/jobs/1", "", "soon")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
@@ -8,11 +8,31 @@ import (
 	"scheduler0/pkg/secrets"
 )
 
+// peerEndpoints the endpoints nodes call on each other, they require a verified client certificate when PeerMTLS is set
+var peerEndpoints = map[string]bool{
+	"peer-handshake":   true,
+	"execution-logs":   true,
+	"start-jobs":       true,
+	"stop-jobs":        true,
+	"read-index":       true,
+	"credential-usage": true,
+}
+
 func IsPeerClient(req *http.Request) bool {
 	peerHeaderVal := req.Header.Get(headers.PeerHeader)
 	return peerHeaderVal == "cmd" || peerHeaderVal == "peer"
 }
 
+// IsPeerEndpoint returns true if the first path segment after the api version is an endpoint nodes call on each other
+func IsPeerEndpoint(endpoint string) bool {
+	return peerEndpoints[endpoint]
+}
+
+// HasVerifiedClientCertificate returns true if the request was made over TLS with a client certificate verified by the CA
+func HasVerifiedClientCertificate(req *http.Request) bool {
+	return req.TLS != nil && len(req.TLS.VerifiedChains) > 0
+}
+
 func IsAuthorizedPeerClient(req *http.Request, scheduler0Secrets secrets.Scheduler0Secrets) bool {
 	credentials := scheduler0Secrets.GetSecrets()
 	username, password, ok := req.BasicAuth()
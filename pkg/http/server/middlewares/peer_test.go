@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/secrets"
+	"testing"
+)
+
+// serveAsPeer serves the request of a peer behind the auth middleware, with a verified client certificate when verified is true
+func serveAsPeer(t *testing.T, method string, path string, verified bool) int {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "middleware-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+	middleware := NewMiddlewareHandler(logger, secrets.NewScheduler0Secrets(), config.NewScheduler0Config(), nil)
+
+	handler := middleware.AuthMiddleware(&testCredentials{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(method, path, nil)
+	req.SetBasicAuth("peer-user", "peer-password")
+	req.Header.Set(headers.PeerHeader, headers.PeerHeaderValue)
+	if verified {
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	return res.Code
+}
+
+func Test_AuthMiddleware_PeerMTLS(t *testing.T) {
+	t.Setenv("SCHEDULER0_AUTH_USERNAME", "peer-user")
+	t.Setenv("SCHEDULER0_AUTH_PASSWORD", "peer-password")
+
+	// Without PeerMTLS basic auth is enough
+	assert.Equal(t, http.StatusOK, serveAsPeer(t, http.MethodGet, "/api/v1/peer-handshake", false))
+
+	t.Setenv("SCHEDULER0_PEER_MTLS", "true")
+	assert.Equal(t, http.StatusUnauthorized, serveAsPeer(t, http.MethodGet, "/api/v1/peer-handshake", false))
+	assert.Equal(t, http.StatusUnauthorized, serveAsPeer(t, http.MethodPost, "/api/v1/credentials", false))
+	assert.Equal(t, http.StatusOK, serveAsPeer(t, http.MethodGet, "/api/v1/peer-handshake", true))
+	assert.Equal(t, http.StatusOK, serveAsPeer(t, http.MethodPost, "/api/v1/stop-jobs", true))
+
+	// Admin credentials calling a peer endpoint need a verified client certificate as well
+	status, _ := serveAs(t, models.Credential{ID: 7}, http.MethodPost, "/api/v1/stop-jobs")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = serveAs(t, models.Credential{ID: 7}, http.MethodGet, "/api/v1/jobs")
+	assert.NotEqual(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"strconv"
+)
+
+// authorize checks the permission and the projects a request needs against the principal that made it.
+// Requests on a job by id are checked by the controllers, the project of the job is only known once the job is read.
+func authorize(principal models.Principal, r *http.Request, paths []string) *utils.GenericError {
+	switch paths[3] {
+	case "credentials", "audit", "peer-handshake", "execution-logs", "start-jobs", "stop-jobs", "read-index", "credential-usage":
+		return require(principal, models.PermissionAdmin, true)
+	case "projects":
+		return authorizeProjects(principal, r, paths)
+	case "jobs":
+		permission := models.PermissionWrite
+		if r.Method == http.MethodGet {
+			permission = models.PermissionRead
+		} else if len(paths) == 5 && (paths[4] == "pause" || paths[4] == "resume") {
+			permission = models.PermissionTrigger
+		}
+		if err := require(principal, permission, false); err != nil {
+			return err
+		}
+		return requireProjectParam(principal, r, false)
+	case "executions", "events":
+		if err := require(principal, models.PermissionRead, false); err != nil {
+			return err
+		}
+		return requireProjectParam(principal, r, true)
+	default:
+		return require(principal, models.PermissionRead, false)
+	}
+}
+
+// authorizeProjects creating and deleting projects needs admin, reading them read and changing them or their alerts write
+func authorizeProjects(principal models.Principal, r *http.Request, paths []string) *utils.GenericError {
+	if len(paths) == 4 || paths[4] == "" {
+		if r.Method == http.MethodGet {
+			return require(principal, models.PermissionRead, false)
+		}
+		return require(principal, models.PermissionAdmin, true)
+	}
+
+	permission := models.PermissionWrite
+	if r.Method == http.MethodGet {
+		permission = models.PermissionRead
+	} else if r.Method == http.MethodDelete && len(paths) == 5 {
+		permission = models.PermissionAdmin
+	}
+	if err := require(principal, permission, false); err != nil {
+		return err
+	}
+
+	projectId, err := strconv.ParseUint(paths[4], 10, 64)
+	if err != nil {
+		return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("invalid project id %s", paths[4]))
+	}
+	if !principal.CanAccessProject(projectId) {
+		return forbidden(fmt.Sprintf("credential cannot access project %d", projectId))
+	}
+	return nil
+}
+
+// require fails when the principal does not have the permission, or is limited to some projects when allProjects is set
+func require(principal models.Principal, permission models.Permission, allProjects bool) *utils.GenericError {
+	if !principal.Can(permission) {
+		return forbidden(fmt.Sprintf("credential does not have the %s permission", permission))
+	}
+	if allProjects && !principal.AllProjects() {
+		return forbidden("credential is limited to some projects")
+	}
+	return nil
+}
+
+// requireProjectParam fails when the projectId query param is a project the principal cannot access.
+// Principals limited to some projects must give the param when required is set.
+func requireProjectParam(principal models.Principal, r *http.Request, required bool) *utils.GenericError {
+	projectIdParam := r.URL.Query().Get("projectId")
+	if projectIdParam == "" {
+		if required && !principal.AllProjects() {
+			return forbidden("projectId is required for credentials limited to some projects")
+		}
+		return nil
+	}
+
+	projectId, err := strconv.ParseUint(projectIdParam, 10, 64)
+	if err != nil {
+		return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("invalid projectId %s", projectIdParam))
+	}
+	if !principal.CanAccessProject(projectId) {
+		return forbidden(fmt.Sprintf("credential cannot access project %d", projectId))
+	}
+	return nil
+}
+
+func forbidden(message string) *utils.GenericError {
+	return utils.HTTPGenericError(http.StatusForbidden, message)
+}
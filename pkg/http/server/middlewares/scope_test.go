@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/secrets"
+	"scheduler0/pkg/service/credential"
+	"scheduler0/pkg/utils"
+	"testing"
+)
+
+// testCredentials stands in for the credential service, it authenticates every api key as the credential
+type testCredentials struct {
+	credential.CredentialService
+	credential models.Credential
+}
+
+func (credentials *testCredentials) AuthenticateServerAPIKey(apiKey string, apiSecret string) (*models.Credential, *utils.GenericError) {
+	return &credentials.credential, nil
+}
+
+func (credentials *testCredentials) RecordUsage(credentialId uint64, usedFrom string) {}
+
+// serveAs serves the request behind the auth middleware as the credential, and returns the status and the principal the handler saw
+func serveAs(t *testing.T, credentialModel models.Credential, method string, path string) (int, *models.Principal) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "middleware-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+	middleware := NewMiddlewareHandler(logger, secrets.NewScheduler0Secrets(), config.NewScheduler0Config(), nil)
+
+	var served *models.Principal
+	handler := middleware.AuthMiddleware(&testCredentials{credential: credentialModel})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := models.PrincipalFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, credentialModel.ID, r.Context().Value("CredentialID"))
+		served = &principal
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(headers.APIKeyHeader, "api-key")
+	req.Header.Set(headers.SecretKeyHeader, "api-secret")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	return res.Code, served
+}
+
+func Test_AuthMiddleware_AttachesThePrincipal(t *testing.T) {
+	status, principal := serveAs(t, models.Credential{ID: 7}, http.MethodDelete, "/api/v1/credentials/3")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, uint64(7), principal.CredentialID)
+	assert.True(t, principal.AllProjects())
+	assert.True(t, principal.Can(models.PermissionAdmin))
+}
+
+func Test_AuthMiddleware_EnforcesPermissions(t *testing.T) {
+	reader := models.Credential{ID: 1, Permissions: []models.Permission{models.PermissionRead}}
+	trigger := models.Credential{ID: 2, Permissions: []models.Permission{models.PermissionTrigger}}
+	writer := models.Credential{ID: 3, Permissions: []models.Permission{models.PermissionWrite}}
+
+	for _, tc := range []struct {
+		name       string
+		credential models.Credential
+		method     string
+		path       string
+		status     int
+	}{
+		{"reader lists jobs", reader, http.MethodGet, "/api/v1/jobs?projectId=1&limit=10", http.StatusOK},
+		{"reader cannot create jobs", reader, http.MethodPost, "/api/v1/jobs", http.StatusForbidden},
+		{"reader cannot pause jobs", reader, http.MethodPost, "/api/v1/jobs/pause?projectId=1", http.StatusForbidden},
+		{"trigger pauses jobs", trigger, http.MethodPost, "/api/v1/jobs/pause?projectId=1", http.StatusOK},
+		{"trigger reads executions", trigger, http.MethodGet, "/api/v1/executions", http.StatusOK},
+		{"trigger cannot update jobs", trigger, http.MethodPut, "/api/v1/jobs/4", http.StatusForbidden},
+		{"writer updates jobs", writer, http.MethodPut, "/api/v1/jobs/4", http.StatusOK},
+		{"writer updates projects", writer, http.MethodPut, "/api/v1/projects/1", http.StatusOK},
+		{"writer cannot create projects", writer, http.MethodPost, "/api/v1/projects", http.StatusForbidden},
+		{"writer cannot delete projects", writer, http.MethodDelete, "/api/v1/projects/1", http.StatusForbidden},
+		{"writer deletes alerts", writer, http.MethodDelete, "/api/v1/projects/1/alerts/2", http.StatusOK},
+		{"writer cannot read credentials", writer, http.MethodGet, "/api/v1/credentials", http.StatusForbidden},
+		{"writer cannot stop jobs of the node", writer, http.MethodPost, "/api/v1/stop-jobs", http.StatusForbidden},
+		{"writer cannot read the audit log", writer, http.MethodGet, "/api/v1/audit?limit=10", http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, _ := serveAs(t, tc.credential, tc.method, tc.path)
+			assert.Equal(t, tc.status, status)
+		})
+	}
+}
+
+func Test_AuthMiddleware_EnforcesProjects(t *testing.T) {
+	scoped := models.Credential{ID: 1, ProjectIDs: []uint64{1, 2}}
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"reads a project in scope", http.MethodGet, "/api/v1/projects/2", http.StatusOK},
+		{"cannot read a project out of scope", http.MethodGet, "/api/v1/projects/3", http.StatusForbidden},
+		{"deletes a project in scope", http.MethodDelete, "/api/v1/projects/1", http.StatusOK},
+		{"cannot create projects", http.MethodPost, "/api/v1/projects", http.StatusForbidden},
+		{"lists jobs in scope", http.MethodGet, "/api/v1/jobs?projectId=1", http.StatusOK},
+		{"cannot list jobs out of scope", http.MethodGet, "/api/v1/jobs?projectId=3", http.StatusForbidden},
+		{"cannot delete jobs out of scope", http.MethodDelete, "/api/v1/jobs?projectId=3", http.StatusForbidden},
+		{"lists executions in scope", http.MethodGet, "/api/v1/executions?projectId=2", http.StatusOK},
+		{"cannot list executions of every project", http.MethodGet, "/api/v1/executions", http.StatusForbidden},
+		{"cannot stream events of every project", http.MethodGet, "/api/v1/events", http.StatusForbidden},
+		{"cannot manage credentials", http.MethodPost, "/api/v1/credentials", http.StatusForbidden},
+		{"cannot read the audit log", http.MethodGet, "/api/v1/audit?limit=10", http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, _ := serveAs(t, scoped, tc.method, tc.path)
+			assert.Equal(t, tc.status, status)
+		})
+	}
+}
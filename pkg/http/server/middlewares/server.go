@@ -1,10 +1,13 @@
 package middlewares
 
 import (
+	"net"
 	"net/http"
 	"scheduler0/pkg/constants/headers"
+	"scheduler0/pkg/models"
 	"scheduler0/pkg/service/credential"
-	"scheduler0/pkg/utils"
+	"strings"
+	"time"
 )
 
 // IsServerClient returns true is the request is coming from a server side
@@ -14,10 +17,37 @@ func IsServerClient(req *http.Request) bool {
 	return apiKey != "" && apiSecret != ""
 }
 
-// IsAuthorizedServerClient returns true if the credential is authorized server side
-func IsAuthorizedServerClient(req *http.Request, credentialService credential.CredentialService) (bool, *utils.GenericError) {
+// IsAuthorizedServerClient returns the credential of the request if it is authorized server side.
+// Archived and expired credentials are not authorized, the use of authorized credentials is recorded.
+func IsAuthorizedServerClient(req *http.Request, credentialService credential.CredentialService) (*models.Credential, bool) {
 	apiKey := req.Header.Get(headers.APIKeyHeader)
 	apiSecret := req.Header.Get(headers.SecretKeyHeader)
 
-	return credentialService.ValidateServerAPIKey(apiKey, apiSecret)
+	credential, err := credentialService.AuthenticateServerAPIKey(apiKey, apiSecret)
+	if err != nil || credential == nil {
+		return nil, false
+	}
+	if credential.Archived || credential.Expired(time.Now()) {
+		return nil, false
+	}
+
+	credentialService.RecordUsage(credential.ID, clientAddress(req))
+
+	return credential, true
+}
+
+// clientAddress returns the address of the client of the request, as seen by the follower for requests it forwarded to the leader
+func clientAddress(req *http.Request) string {
+	if req.Header.Get(headers.ForwardedByHeader) != "" {
+		if forwardedFor := req.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			addresses := strings.Split(forwardedFor, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
 }
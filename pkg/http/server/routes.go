@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"github.com/unrolled/secure"
+	"net/http"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/http/server/controllers"
+	"scheduler0/pkg/http/server/middlewares"
+	"scheduler0/pkg/secrets"
+	"scheduler0/pkg/service"
+	"scheduler0/pkg/tracing"
+)
+
+// NewRouter returns the api router serving the services, behind the tracing, security, authentication and raft leader middlewares
+func NewRouter(logger hclog.Logger, scheduler0Config config.Scheduler0Config, scheduler0Secrets secrets.Scheduler0Secrets, serv *service.Service) *mux.Router {
+	router := mux.NewRouter()
+
+	// Security middleware
+	secureMiddleware := secure.New(secure.Options{FrameDeny: true})
+
+	// Mount middleware
+	middleware := middlewares.NewMiddlewareHandler(logger, scheduler0Secrets, scheduler0Config, serv.Certificates)
+
+	router.Use(tracing.Middleware)
+	router.Use(secureMiddleware.Handler)
+	router.Use(mux.CORSMethodMiddleware(router))
+	router.Use(middleware.ContextMiddleware)
+	router.Use(middleware.AuthMiddleware(serv.CredentialService))
+	router.Use(middleware.EnsureRaftLeaderMiddleware(serv.NodeService))
+
+	registerRoutes(router, logger, scheduler0Config, serv)
+
+	return router
+}
+
+// registerRoutes mounts the controllers of the services on the api router.
+// Every route registered here needs an entry in the operations of the api docs.
+func registerRoutes(router *mux.Router, logger hclog.Logger, scheduler0Config config.Scheduler0Config, serv *service.Service) {
+	// Initialize controllers
+	jobController := controllers.NewJoBHTTPController(logger, serv.JobService, serv.ProjectService)
+	projectController := controllers.NewProjectController(logger, serv.ProjectService)
+	credentialController := controllers.NewCredentialController(logger, serv.CredentialService)
+	healthCheckController := controllers.NewHealthCheckController(logger, serv.NodeService)
+	peerController := controllers.NewPeerController(logger, scheduler0Config, serv.NodeService, serv.AuditService, serv.CredentialService)
+	asyncTaskController := controllers.NewAsyncTaskController(logger, serv.AsyncTaskService)
+	executionController := controllers.NewExecutionController(logger, serv.JobExecutionService, serv.JobService)
+	alertController := controllers.NewAlertController(logger, serv.AlertService)
+	eventController := controllers.NewEventController(logger, serv.EventService)
+	auditController := controllers.NewAuditController(logger, serv.AuditService)
+	apiDocsController := controllers.NewAPIDocsController(logger)
+
+	// Credentials Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/credentials", constants.APIV1Base), credentialController.CreateOneCredential).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/credentials", constants.APIV1Base), credentialController.ListCredentials).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/credentials/{id}", constants.APIV1Base), credentialController.GetOneCredential).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/credentials/{id}", constants.APIV1Base), credentialController.UpdateOneCredential).Methods(http.MethodPut)
+	router.HandleFunc(fmt.Sprintf("%s/credentials/{id}", constants.APIV1Base), credentialController.DeleteOneCredential).Methods(http.MethodDelete)
+	router.HandleFunc(fmt.Sprintf("%s/credentials/{id}/rotate", constants.APIV1Base), credentialController.RotateCredential).Methods(http.MethodPost)
+
+	// JobService Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/jobs", constants.APIV1Base), jobController.BatchCreateJobs).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/jobs", constants.APIV1Base), jobController.ListJobs).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/jobs", constants.APIV1Base), jobController.DeleteJobs).Methods(http.MethodDelete)
+	router.HandleFunc(fmt.Sprintf("%s/jobs", constants.APIV1Base), jobController.BatchUpdateJobs).Methods(http.MethodPut)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/batch-delete", constants.APIV1Base), jobController.BatchDeleteJobs).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/pause", constants.APIV1Base), jobController.PauseJobs).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/resume", constants.APIV1Base), jobController.ResumeJobs).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}", constants.APIV1Base), jobController.GetOneJob).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}", constants.APIV1Base), jobController.UpdateOneJob).Methods(http.MethodPut)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}", constants.APIV1Base), jobController.DeleteOneJob).Methods(http.MethodDelete)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}/revisions", constants.APIV1Base), jobController.ListJobRevisions).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}/revisions/{revision}/rollback", constants.APIV1Base), jobController.RollbackJob).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}/executions", constants.APIV1Base), executionController.ListJobExecutions).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/jobs/{id}/stats", constants.APIV1Base), executionController.JobStats).Methods(http.MethodGet)
+
+	// Executions Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/executions", constants.APIV1Base), executionController.ListExecutions).Methods(http.MethodGet)
+
+	// Projects Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/projects", constants.APIV1Base), projectController.CreateOneProject).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/projects", constants.APIV1Base), projectController.ListProjects).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}", constants.APIV1Base), projectController.GetOneProject).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}", constants.APIV1Base), projectController.UpdateOneProject).Methods(http.MethodPut)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}", constants.APIV1Base), projectController.DeleteOneProject).Methods(http.MethodDelete)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}/stats", constants.APIV1Base), executionController.ProjectStats).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}/alerts", constants.APIV1Base), alertController.CreateOneAlert).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}/alerts", constants.APIV1Base), alertController.ListAlerts).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}/alerts/{alertId}", constants.APIV1Base), alertController.GetOneAlert).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}/alerts/{alertId}", constants.APIV1Base), alertController.UpdateOneAlert).Methods(http.MethodPut)
+	router.HandleFunc(fmt.Sprintf("%s/projects/{id}/alerts/{alertId}", constants.APIV1Base), alertController.DeleteOneAlert).Methods(http.MethodDelete)
+
+	// Events Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/events", constants.APIV1Base), eventController.StreamEvents).Methods(http.MethodGet)
+
+	// Audit Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/audit", constants.APIV1Base), auditController.ListAuditEvents).Methods(http.MethodGet)
+
+	// API docs Endpoints
+	router.HandleFunc(fmt.Sprintf("%s/api-docs", constants.APIV1Base), apiDocsController.Index).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/api-docs/openapi.json", constants.APIV1Base), apiDocsController.OpenAPISpec).Methods(http.MethodGet)
+
+	// Healthcheck Endpoint
+	router.HandleFunc(fmt.Sprintf("%s/healthcheck", constants.APIV1Base), healthCheckController.HealthCheck).Methods(http.MethodGet)
+
+	// NodeService Endpoints
+	router.HandleFunc(fmt.Sprintf("%s/peer-handshake", constants.APIV1Base), peerController.Handshake).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/execution-logs", constants.APIV1Base), peerController.ExecutionLogs).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/start-jobs", constants.APIV1Base), peerController.StartJobs).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/stop-jobs", constants.APIV1Base), peerController.StopJobs).Methods(http.MethodPost)
+	router.HandleFunc(fmt.Sprintf("%s/read-index", constants.APIV1Base), peerController.ReadIndex).Methods(http.MethodGet)
+	router.HandleFunc(fmt.Sprintf("%s/credential-usage", constants.APIV1Base), peerController.CredentialUsage).Methods(http.MethodPost)
+
+	// AsyncTask
+	router.HandleFunc(fmt.Sprintf("%s/async-tasks/{id}", constants.APIV1Base), asyncTaskController.GetTask).Methods(http.MethodGet)
+}
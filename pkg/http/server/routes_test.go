@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	apidocs "scheduler0/pkg/http/server/api-docs"
+	"scheduler0/pkg/service"
+	"strings"
+	"testing"
+)
+
+var pathTemplateParam = regexp.MustCompile(`{([^}]+)}`)
+
+func Test_Routes_AreDocumentedInOpenAPISpec(t *testing.T) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "routes-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	router := mux.NewRouter()
+	registerRoutes(router, logger, config.NewScheduler0Config(), &service.Service{})
+
+	routes := map[string]bool{}
+	walkErr := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes[fmt.Sprintf("%s %s", method, strings.TrimPrefix(pathTemplate, constants.APIV1Base))] = true
+		}
+		return nil
+	})
+	assert.Nil(t, walkErr)
+
+	documented := map[string]bool{}
+	for _, operation := range apidocs.Operations {
+		key := fmt.Sprintf("%s %s", operation.Method, operation.Path)
+		assert.False(t, documented[key], "operation %s is documented twice", key)
+		documented[key] = true
+
+		pathParams := map[string]bool{}
+		for _, parameter := range operation.Parameters {
+			if parameter.In == "path" {
+				pathParams[parameter.Name] = true
+			}
+		}
+		for _, match := range pathTemplateParam.FindAllStringSubmatch(operation.Path, -1) {
+			assert.True(t, pathParams[match[1]], "path parameter %s of %s is not documented", match[1], key)
+		}
+	}
+
+	for route := range routes {
+		assert.True(t, documented[route], "route %s has no operation in the openapi document", route)
+	}
+	for operation := range documented {
+		assert.True(t, routes[operation], "operation %s is not served by the router", operation)
+	}
+}
+
+func Test_OpenAPISpec_ReferencesDefinedSchemas(t *testing.T) {
+	spec, err := json.Marshal(apidocs.Spec())
+	assert.Nil(t, err)
+
+	document := struct {
+		OpenAPI    string                     `json:"openapi"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}{}
+	assert.Nil(t, json.Unmarshal(spec, &document))
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Contains(t, document.Paths, "/jobs/{id}")
+	assert.Contains(t, document.Components.Schemas, "Job")
+	assert.Contains(t, document.Components.Schemas, "Credential")
+	assert.Contains(t, document.Components.Schemas, "HealthCheck")
+
+	for _, ref := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(spec), -1) {
+		assert.Contains(t, document.Components.Schemas, ref[1])
+	}
+	// Fields hidden from json are not part of the schemas
+	assert.NotContains(t, string(document.Components.Schemas["Job"]), "CredentialID")
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"scheduler0/pkg/config"
+)
+
+const (
+	// FormatText writes log lines as human-readable text
+	FormatText = "text"
+	// FormatJSON writes every log line as a JSON object
+	FormatJSON = "json"
+)
+
+// Keys of the correlation ids added to log lines
+const (
+	NodeIDKey      = "node_id"
+	RequestIDKey   = "request_id"
+	JobIDKey       = "job_id"
+	ExecutionIDKey = "execution_id"
+)
+
+type contextKey string
+
+// requestIDContextKey is also read by the controllers to tie async tasks to the request creating them
+const requestIDContextKey = "RequestID"
+
+const (
+	jobIDContextKey       contextKey = "JobID"
+	executionIDContextKey contextKey = "ExecutionID"
+)
+
+// NewLogger returns the root logger of a node, writing lines in the configured format labeled with the node id
+func NewLogger(name string, configs *config.Scheduler0Configurations, output io.Writer) hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:       name,
+		Level:      hclog.LevelFromString(configs.LogLevel),
+		Output:     output,
+		JSONFormat: configs.LogFormat == FormatJSON,
+	}).With(NodeIDKey, configs.NodeId)
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the id of the http request being served
+func ContextWithRequestID(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestId)
+}
+
+// RequestIDFromContext returns the id of the http request in ctx, or an empty string when ctx has none
+func RequestIDFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIDContextKey).(string)
+	return requestId
+}
+
+// ContextWithJobID returns a copy of ctx carrying the id of the job being worked on
+func ContextWithJobID(ctx context.Context, jobId uint64) context.Context {
+	return context.WithValue(ctx, jobIDContextKey, jobId)
+}
+
+// ContextWithExecutionID returns a copy of ctx carrying the id of the job execution being worked on
+func ContextWithExecutionID(ctx context.Context, executionId string) context.Context {
+	return context.WithValue(ctx, executionIDContextKey, executionId)
+}
+
+// FromContext returns logger labeled with the request, job and execution ids carried by ctx
+func FromContext(ctx context.Context, logger hclog.Logger) hclog.Logger {
+	args := []interface{}{}
+	if requestId := RequestIDFromContext(ctx); requestId != "" {
+		args = append(args, RequestIDKey, requestId)
+	}
+	if jobId, ok := ctx.Value(jobIDContextKey).(uint64); ok {
+		args = append(args, JobIDKey, jobId)
+	}
+	if executionId, ok := ctx.Value(executionIDContextKey).(string); ok && executionId != "" {
+		args = append(args, ExecutionIDKey, executionId)
+	}
+	if len(args) < 1 {
+		return logger
+	}
+	return logger.With(args...)
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"scheduler0/pkg/config"
+	"strings"
+	"testing"
+)
+
+func Test_NewLogger_WritesJSONLinesWithNodeId(t *testing.T) {
+	output := bytes.Buffer{}
+	logger := NewLogger("scheduler0", &config.Scheduler0Configurations{
+		LogLevel:  "INFO",
+		LogFormat: FormatJSON,
+		NodeId:    2,
+	}, &output)
+
+	logger.Debug("not written below the log level")
+	logger.Named("job-service").Info("created jobs", "jobs", 3)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Equal(t, 1, len(lines))
+
+	line := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &line))
+	assert.Equal(t, "created jobs", line["@message"])
+	assert.Equal(t, "scheduler0.job-service", line["@module"])
+	assert.Equal(t, float64(2), line[NodeIDKey])
+	assert.Equal(t, float64(3), line["jobs"])
+}
+
+func Test_NewLogger_WritesTextLinesByDefault(t *testing.T) {
+	output := bytes.Buffer{}
+	logger := NewLogger("scheduler0", &config.Scheduler0Configurations{LogLevel: "INFO", NodeId: 1}, &output)
+
+	logger.Info("started")
+
+	assert.False(t, json.Valid(output.Bytes()))
+	assert.Contains(t, output.String(), "started")
+	assert.Contains(t, output.String(), "node_id=1")
+}
+
+func Test_FromContext_AddsCorrelationIds(t *testing.T) {
+	output := bytes.Buffer{}
+	logger := NewLogger("scheduler0", &config.Scheduler0Configurations{
+		LogLevel:  "INFO",
+		LogFormat: FormatJSON,
+		NodeId:    1,
+	}, &output)
+
+	assert.Equal(t, logger, FromContext(context.Background(), logger))
+
+	ctx := ContextWithRequestID(context.Background(), "request-1")
+	ctx = ContextWithJobID(ctx, 10)
+	ctx = ContextWithExecutionID(ctx, "execution-1")
+	assert.Equal(t, "request-1", RequestIDFromContext(ctx))
+	assert.Equal(t, "request-1", ctx.Value("RequestID"))
+
+	FromContext(ctx, logger).Error("failed to execute job")
+
+	line := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &line))
+	assert.Equal(t, "request-1", line[RequestIDKey])
+	assert.Equal(t, float64(10), line[JobIDKey])
+	assert.Equal(t, "execution-1", line[ExecutionIDKey])
+	assert.Equal(t, float64(1), line[NodeIDKey])
+}
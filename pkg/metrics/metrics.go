@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Namespace prefixes the name of every metric exposed by a node
+const Namespace = "scheduler0"
+
+// NodeIdLabel is set on every metric exposed by a node to the id of the node
+const NodeIdLabel = "node_id"
+
+var (
+	// JobExecutions counts the execution logs written by the node, by state and execution type
+	JobExecutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "job_executions_total",
+		Help:      "Number of job execution logs written by the node by state and execution type.",
+	}, []string{"state", "execution_type"})
+
+	// CallbackLatency measures the time jobs' callbacks took to respond, by execution type and result
+	CallbackLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "callback_latency_seconds",
+		Help:      "Time the callbacks of executed jobs took to respond by execution type and result.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"execution_type", "result"})
+
+	// RaftApplyLatency measures the time commands took to be committed and applied through raft
+	RaftApplyLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "raft_apply_duration_seconds",
+		Help:      "Time commands written to the raft log took to be committed and applied.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// FSMBatchSize measures the number of raft logs applied to the FSM in a batch
+	FSMBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "fsm_apply_batch_size",
+		Help:      "Number of raft logs applied to the FSM in a batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	// SnapshotDuration measures the time snapshots of the FSM took to persist
+	SnapshotDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "raft_snapshot_persist_duration_seconds",
+		Help:      "Time snapshots of the FSM took to persist.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// FanInPeers is the number of peers in each phase of the execution logs fan-in
+	FanInPeers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "fan_in_peers",
+		Help:      "Number of peers in each phase of the fan-in of uncommitted execution logs.",
+	}, []string{"phase"})
+
+	// FanInCompletedPeers is the number of peers the fan-in of execution logs completed for
+	FanInCompletedPeers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "fan_in_completed_peers",
+		Help:      "Number of peers the fan-in of uncommitted execution logs completed for.",
+	})
+
+	// FanInExecutionLogs counts the execution logs fetched from peers and committed by the node
+	FanInExecutionLogs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "fan_in_execution_logs_total",
+		Help:      "Number of uncommitted execution logs fetched from peers and committed.",
+	})
+
+	// MemoryLimitTrips counts the times the memory checker found the node above its memory limit
+	MemoryLimitTrips = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "memory_limit_trips_total",
+		Help:      "Number of times the memory checker found the node above its memory limit.",
+	})
+)
+
+var (
+	registry     = prometheus.NewRegistry()
+	registerOnce sync.Once
+)
+
+// Register registers the metrics of this package, the Go runtime and process metrics and the given collectors,
+// labeling all of them with the id of the node. Only the first call registers metrics.
+func Register(nodeId uint64, nodeCollectors ...prometheus.Collector) {
+	registerOnce.Do(func() {
+		registerer := prometheus.WrapRegistererWith(prometheus.Labels{NodeIdLabel: strconv.FormatUint(nodeId, 10)}, registry)
+		registerer.MustRegister(
+			JobExecutions,
+			CallbackLatency,
+			RaftApplyLatency,
+			FSMBatchSize,
+			SnapshotDuration,
+			FanInPeers,
+			FanInCompletedPeers,
+			FanInExecutionLogs,
+			MemoryLimitTrips,
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+		registerer.MustRegister(nodeCollectors...)
+	})
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// NewDispatcherCollectors returns gauges reporting the number of work items waiting for a worker and the number of busy workers
+func NewDispatcherCollectors(queueDepth func() int64, busyWorkers func() int64) []prometheus.Collector {
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "dispatcher_queue_depth",
+			Help:      "Number of work items waiting for a free dispatcher worker.",
+		}, func() float64 { return float64(queueDepth()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "dispatcher_busy_workers",
+			Help:      "Number of dispatcher workers running a work item.",
+		}, func() float64 { return float64(busyWorkers()) }),
+	}
+}
+
+// NewScheduledJobsCollector returns a gauge reporting the number of jobs scheduled on the node
+func NewScheduledJobsCollector(scheduledJobs func() int) prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "scheduled_jobs",
+		Help:      "Number of jobs scheduled to execute on the node.",
+	}, func() float64 { return float64(scheduledJobs()) })
+}
+
+type jobAllocationsCollector struct {
+	desc           *prometheus.Desc
+	jobAllocations func() map[uint64]uint64
+}
+
+// NewJobAllocationsCollector returns a collector reporting the number of jobs the leader allocated to each node
+func NewJobAllocationsCollector(jobAllocations func() map[uint64]uint64) prometheus.Collector {
+	return &jobAllocationsCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "", "job_allocations"),
+			"Number of jobs allocated to each node by the leader.",
+			[]string{"allocated_node_id"},
+			nil,
+		),
+		jobAllocations: jobAllocations,
+	}
+}
+
+func (collector *jobAllocationsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.desc
+}
+
+func (collector *jobAllocationsCollector) Collect(ch chan<- prometheus.Metric) {
+	for nodeId, allocation := range collector.jobAllocations() {
+		ch <- prometheus.MustNewConstMetric(collector.desc, prometheus.GaugeValue, float64(allocation), strconv.FormatUint(nodeId, 10))
+	}
+}
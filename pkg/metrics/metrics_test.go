@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Metrics_Handler(t *testing.T) {
+	Register(
+		7,
+		append(
+			NewDispatcherCollectors(func() int64 { return 4 }, func() int64 { return 2 }),
+			NewScheduledJobsCollector(func() int { return 3 }),
+			NewJobAllocationsCollector(func() map[uint64]uint64 { return map[uint64]uint64{1: 10, 2: 20} }),
+		)...,
+	)
+
+	JobExecutions.WithLabelValues("success", "http").Add(2)
+	CallbackLatency.WithLabelValues("http", "success").Observe(0.2)
+	MemoryLimitTrips.Inc()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatal("failed to read metrics", err)
+	}
+
+	assert.Contains(t, string(body), `scheduler0_job_executions_total{execution_type="http",node_id="7",state="success"} 2`)
+	assert.Contains(t, string(body), `scheduler0_callback_latency_seconds_count{execution_type="http",node_id="7",result="success"} 1`)
+	assert.Contains(t, string(body), `scheduler0_memory_limit_trips_total{node_id="7"} 1`)
+	assert.Contains(t, string(body), `scheduler0_dispatcher_queue_depth{node_id="7"} 4`)
+	assert.Contains(t, string(body), `scheduler0_dispatcher_busy_workers{node_id="7"} 2`)
+	assert.Contains(t, string(body), `scheduler0_scheduled_jobs{node_id="7"} 3`)
+	assert.Contains(t, string(body), `scheduler0_job_allocations{allocated_node_id="1",node_id="7"} 10`)
+	assert.Contains(t, string(body), `scheduler0_job_allocations{allocated_node_id="2",node_id="7"} 20`)
+}
@@ -41,25 +41,25 @@ func (_m *Credential) CreateNewCredential(credentialTransformer models.Credentia
 	return r0, r1
 }
 
-// DeleteOneCredential provides a mock function with given fields: id
-func (_m *Credential) DeleteOneCredential(id uint64) (*models.Credential, error) {
-	ret := _m.Called(id)
+// DeleteOneCredential provides a mock function with given fields: id, version
+func (_m *Credential) DeleteOneCredential(id uint64, version uint64) (*models.Credential, error) {
+	ret := _m.Called(id, version)
 
 	var r0 *models.Credential
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint64) (*models.Credential, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(uint64, uint64) (*models.Credential, error)); ok {
+		return rf(id, version)
 	}
-	if rf, ok := ret.Get(0).(func(uint64) *models.Credential); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(uint64, uint64) *models.Credential); ok {
+		r0 = rf(id, version)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Credential)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uint64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(uint64, uint64) error); ok {
+		r1 = rf(id, version)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -177,6 +177,92 @@ func (_m *CredentialRepo) UpdateOneByID(credential models.Credential) (uint64, *
 	return r0, r1
 }
 
+// RotateOneByID provides a mock function with given fields: credential
+func (_m *CredentialRepo) RotateOneByID(credential models.Credential) (uint64, *utils.GenericError) {
+	ret := _m.Called(credential)
+
+	var r0 uint64
+	var r1 *utils.GenericError
+	if rf, ok := ret.Get(0).(func(models.Credential) (uint64, *utils.GenericError)); ok {
+		return rf(credential)
+	}
+	if rf, ok := ret.Get(0).(func(models.Credential) uint64); ok {
+		r0 = rf(credential)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.Credential) *utils.GenericError); ok {
+		r1 = rf(credential)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*utils.GenericError)
+		}
+	}
+
+	return r0, r1
+}
+
+// UpdateLastUsed provides a mock function with given fields: usages
+func (_m *CredentialRepo) UpdateLastUsed(usages []models.CredentialUsage) *utils.GenericError {
+	ret := _m.Called(usages)
+
+	var r0 *utils.GenericError
+	if rf, ok := ret.Get(0).(func([]models.CredentialUsage) *utils.GenericError); ok {
+		r0 = rf(usages)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.GenericError)
+		}
+	}
+
+	return r0
+}
+
+// ListPlainTextSecrets provides a mock function with given fields:
+func (_m *CredentialRepo) ListPlainTextSecrets() ([]models.Credential, *utils.GenericError) {
+	ret := _m.Called()
+
+	var r0 []models.Credential
+	var r1 *utils.GenericError
+	if rf, ok := ret.Get(0).(func() ([]models.Credential, *utils.GenericError)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.Credential); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Credential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() *utils.GenericError); ok {
+		r1 = rf()
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*utils.GenericError)
+		}
+	}
+
+	return r0, r1
+}
+
+// UpdateSecretHashes provides a mock function with given fields: credentials
+func (_m *CredentialRepo) UpdateSecretHashes(credentials []models.Credential) *utils.GenericError {
+	ret := _m.Called(credentials)
+
+	var r0 *utils.GenericError
+	if rf, ok := ret.Get(0).(func([]models.Credential) *utils.GenericError); ok {
+		r0 = rf(credentials)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.GenericError)
+		}
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewCredentialRepo interface {
 	mock.TestingT
 	Cleanup(func())
@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	time "time"
+
 	utils "scheduler0/pkg/utils"
 )
 
@@ -16,18 +18,20 @@ type CredentialService struct {
 }
 
 // CreateNewCredential provides a mock function with given fields: credentialTransformer
-func (_m *CredentialService) CreateNewCredential(credentialTransformer models.Credential) (uint64, *utils.GenericError) {
+func (_m *CredentialService) CreateNewCredential(credentialTransformer models.Credential) (*models.Credential, *utils.GenericError) {
 	ret := _m.Called(credentialTransformer)
 
-	var r0 uint64
+	var r0 *models.Credential
 	var r1 *utils.GenericError
-	if rf, ok := ret.Get(0).(func(models.Credential) (uint64, *utils.GenericError)); ok {
+	if rf, ok := ret.Get(0).(func(models.Credential) (*models.Credential, *utils.GenericError)); ok {
 		return rf(credentialTransformer)
 	}
-	if rf, ok := ret.Get(0).(func(models.Credential) uint64); ok {
+	if rf, ok := ret.Get(0).(func(models.Credential) *models.Credential); ok {
 		r0 = rf(credentialTransformer)
 	} else {
-		r0 = ret.Get(0).(uint64)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Credential)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(models.Credential) *utils.GenericError); ok {
@@ -41,25 +45,25 @@ func (_m *CredentialService) CreateNewCredential(credentialTransformer models.Cr
 	return r0, r1
 }
 
-// DeleteOneCredential provides a mock function with given fields: id
-func (_m *CredentialService) DeleteOneCredential(id uint64) (*models.Credential, error) {
-	ret := _m.Called(id)
+// DeleteOneCredential provides a mock function with given fields: id, version, actor
+func (_m *CredentialService) DeleteOneCredential(id uint64, version uint64, actor models.Actor) (*models.Credential, error) {
+	ret := _m.Called(id, version, actor)
 
 	var r0 *models.Credential
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint64) (*models.Credential, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(uint64, uint64, models.Actor) (*models.Credential, error)); ok {
+		return rf(id, version, actor)
 	}
-	if rf, ok := ret.Get(0).(func(uint64) *models.Credential); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(uint64, uint64, models.Actor) *models.Credential); ok {
+		r0 = rf(id, version, actor)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Credential)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uint64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(uint64, uint64, models.Actor) error); ok {
+		r1 = rf(id, version, actor)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -173,6 +177,70 @@ func (_m *CredentialService) ValidateServerAPIKey(apiKey string, apiSecret strin
 	return r0, r1
 }
 
+// FlushUsage provides a mock function with given fields:
+func (_m *CredentialService) FlushUsage() {
+	_m.Called()
+}
+
+// FlushUsagePeriodically provides a mock function with given fields:
+func (_m *CredentialService) FlushUsagePeriodically() {
+	_m.Called()
+}
+
+// HashPlainTextSecrets provides a mock function with given fields:
+func (_m *CredentialService) HashPlainTextSecrets() *utils.GenericError {
+	ret := _m.Called()
+
+	var r0 *utils.GenericError
+	if rf, ok := ret.Get(0).(func() *utils.GenericError); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.GenericError)
+		}
+	}
+
+	return r0
+}
+
+// MergeUsage provides a mock function with given fields: usages
+func (_m *CredentialService) MergeUsage(usages []models.CredentialUsage) {
+	_m.Called(usages)
+}
+
+// RecordUsage provides a mock function with given fields: credentialId, usedFrom
+func (_m *CredentialService) RecordUsage(credentialId uint64, usedFrom string) {
+	_m.Called(credentialId, usedFrom)
+}
+
+// RotateCredential provides a mock function with given fields: id, gracePeriod, version, actor
+func (_m *CredentialService) RotateCredential(id uint64, gracePeriod time.Duration, version uint64, actor models.Actor) (*models.Credential, *utils.GenericError) {
+	ret := _m.Called(id, gracePeriod, version, actor)
+
+	var r0 *models.Credential
+	var r1 *utils.GenericError
+	if rf, ok := ret.Get(0).(func(uint64, time.Duration, uint64, models.Actor) (*models.Credential, *utils.GenericError)); ok {
+		return rf(id, gracePeriod, version, actor)
+	}
+	if rf, ok := ret.Get(0).(func(uint64, time.Duration, uint64, models.Actor) *models.Credential); ok {
+		r0 = rf(id, gracePeriod, version, actor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Credential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint64, time.Duration, uint64, models.Actor) *utils.GenericError); ok {
+		r1 = rf(id, gracePeriod, version, actor)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*utils.GenericError)
+		}
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewCredentialService interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AlertRuleType string
+
+const (
+	// AlertRuleTypeConsecutiveFailures fires when a job failed at least threshold times in a row
+	AlertRuleTypeConsecutiveFailures AlertRuleType = "consecutive_failures"
+	// AlertRuleTypeNoSuccess fires when an active job has not succeeded for periodSeconds
+	AlertRuleTypeNoSuccess AlertRuleType = "no_success"
+	// AlertRuleTypeDeadLettered fires when the last execution of a job failed on every retry
+	AlertRuleTypeDeadLettered AlertRuleType = "dead_lettered"
+)
+
+type AlertStatus string
+
+const (
+	AlertStatusFiring   AlertStatus = "firing"
+	AlertStatusResolved AlertStatus = "resolved"
+)
+
+// AlertRule a project level rule notifying a webhook about failing jobs
+type AlertRule struct {
+	ID            uint64        `json:"id,omitempty"`
+	ProjectID     uint64        `json:"projectId,omitempty"`
+	Type          AlertRuleType `json:"type,omitempty"`
+	Threshold     uint64        `json:"threshold,omitempty"`
+	PeriodSeconds uint64        `json:"periodSeconds,omitempty"`
+	WebhookUrl    string        `json:"webhookUrl,omitempty"`
+	DateCreated   time.Time     `json:"dateCreated,omitempty"`
+}
+
+// PaginatedAlertRules paginated container of alert rules
+type PaginatedAlertRules struct {
+	Total  uint64      `json:"total,omitempty"`
+	Offset uint64      `json:"offset,omitempty"`
+	Limit  uint64      `json:"limit,omitempty"`
+	Data   []AlertRule `json:"alerts,omitempty"`
+}
+
+// AlertNotification is the payload posted to the webhook of an alert rule when it fires or resolves for a job
+type AlertNotification struct {
+	Status    AlertStatus   `json:"status"`
+	RuleID    uint64        `json:"ruleId"`
+	ProjectID uint64        `json:"projectId"`
+	JobID     uint64        `json:"jobId"`
+	Type      AlertRuleType `json:"type"`
+	Message   string        `json:"message"`
+	Date      time.Time     `json:"date"`
+}
+
+// FromJSON extracts content of JSON object into alert rule
+func (alertRule *AlertRule) FromJSON(body []byte) error {
+	if err := json.Unmarshal(body, &alertRule); err != nil {
+		return err
+	}
+	return nil
+}
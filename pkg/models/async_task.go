@@ -19,6 +19,7 @@ type AsyncTask struct {
 	Service     string         `json:"service" fake:"{regex:[abcdef]{15}}"`
 	State       AsyncTaskState `json:"state" fake:"{number:1,100}"`
 	DateCreated time.Time      `json:"dateCreated"`
+	ProjectIDs  []uint64       `json:"projectIds,omitempty"` // Projects of the jobs the task changes, a credential must be able to access them all to read the task
 }
 
 type AsyncTaskRes struct {
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction what an audited change did
+type AuditAction string
+
+const (
+	AuditActionCreate    AuditAction = "create"
+	AuditActionUpdate    AuditAction = "update"
+	AuditActionDelete    AuditAction = "delete"
+	AuditActionRotate    AuditAction = "rotate"     // A credential was given a new api secret
+	AuditActionPause     AuditAction = "pause"      // Jobs were paused
+	AuditActionResume    AuditAction = "resume"     // Jobs were resumed
+	AuditActionRollback  AuditAction = "rollback"   // A job was rolled back to one of its revisions
+	AuditActionStartJobs AuditAction = "start-jobs" // A node was told to start executing jobs
+	AuditActionStopJobs  AuditAction = "stop-jobs"  // A node was told to stop executing jobs
+)
+
+// AuditResource what an audited change was made to
+type AuditResource string
+
+const (
+	AuditResourceCredential AuditResource = "credential"
+	AuditResourceProject    AuditResource = "project"
+	AuditResourceJob        AuditResource = "job"
+	AuditResourceNode       AuditResource = "node"
+)
+
+// Actor the credential or peer making a change, and the request it is made with
+type Actor struct {
+	CredentialID uint64 `json:"credentialId,omitempty"` // Zero for peers
+	Peer         bool   `json:"peer,omitempty"`
+	RequestID    string `json:"requestId,omitempty"`
+	SourceIP     string `json:"sourceIp,omitempty"`
+}
+
+// AuditEvent a change made to a credential, project or job, or an admin action taken on a node
+type AuditEvent struct {
+	ID          uint64          `json:"id"`
+	Action      AuditAction     `json:"action"`
+	Resource    AuditResource   `json:"resource"`
+	ResourceID  uint64          `json:"resourceId"`
+	Actor       Actor           `json:"actor"`
+	Before      json.RawMessage `json:"before,omitempty"` // The resource before the change, empty for creates
+	After       json.RawMessage `json:"after,omitempty"`  // The resource after the change, empty for deletes
+	DateCreated time.Time       `json:"dateCreated"`
+}
+
+// AuditFilter criteria used to list audit events, zero values match every event
+type AuditFilter struct {
+	Resource     AuditResource `json:"resource,omitempty"`
+	ResourceID   uint64        `json:"resourceId,omitempty"`
+	Action       AuditAction   `json:"action,omitempty"`
+	CredentialID uint64        `json:"credentialId,omitempty"`
+	RequestID    string        `json:"requestId,omitempty"`
+	Since        *time.Time    `json:"since,omitempty"`
+	Until        *time.Time    `json:"until,omitempty"`
+	Order        string        `json:"order,omitempty"`
+	Offset       uint64        `json:"offset,omitempty"`
+	Limit        uint64        `json:"limit,omitempty"`
+	Cursor       string        `json:"-"`
+}
+
+// PageRequest returns the ordering and position of the page of audit events listed with the filter
+func (filter AuditFilter) PageRequest() PageRequest {
+	return PageRequest{
+		Order:  filter.Order,
+		Offset: filter.Offset,
+		Limit:  filter.Limit,
+		Cursor: filter.Cursor,
+	}
+}
+
+// PaginatedAuditEvents paginated container of audit events
+type PaginatedAuditEvents struct {
+	Total  uint64       `json:"total,omitempty"`
+	Offset uint64       `json:"offset,omitempty"`
+	Limit  uint64       `json:"limit,omitempty"`
+	Next   string       `json:"next,omitempty"`
+	Prev   string       `json:"prev,omitempty"`
+	Data   []AuditEvent `json:"events,omitempty"`
+}
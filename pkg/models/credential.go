@@ -5,13 +5,50 @@ import (
 	"time"
 )
 
+// Permission what a credential is allowed to do in the projects it can access
+type Permission string
+
+const (
+	PermissionRead    Permission = "read"    // Read projects, jobs, executions and events
+	PermissionWrite   Permission = "write"   // Create, update and delete jobs and alerts, and update projects
+	PermissionTrigger Permission = "trigger" // Pause and resume jobs
+	PermissionAdmin   Permission = "admin"   // Everything, including creating and deleting projects and managing credentials
+)
+
+// Permissions every permission a credential can be given
+var Permissions = []Permission{PermissionRead, PermissionWrite, PermissionTrigger, PermissionAdmin}
+
 // Credential credential model
 type Credential struct {
-	ID          uint64    `json:"id,omitempty" fake:"{number:1,100}"`
-	Archived    bool      `json:"archived,omitempty"`
-	ApiKey      string    `json:"apiKey,omitempty" fake:"{regex:[abcdef]{15}}"`
-	ApiSecret   string    `json:"apiSecret,omitempty" fake:"{regex:[abcdef]{15}}"`
-	DateCreated time.Time `json:"dateCreated,omitempty"`
+	ID          uint64       `json:"id,omitempty" fake:"{number:1,100}"`
+	Archived    bool         `json:"archived,omitempty"`
+	ApiKey      string       `json:"apiKey,omitempty" fake:"{regex:[abcdef]{15}}"`
+	ApiSecret   string       `json:"apiSecret,omitempty" fake:"{regex:[abcdef]{15}}"` // Only set when the credential is created or rotated, the secret itself is never stored
+	ProjectIDs  []uint64     `json:"projectIds,omitempty" fake:"skip"`                // Projects the credential can access, every project when empty
+	Permissions []Permission `json:"permissions,omitempty" fake:"skip"`               // What the credential can do, everything when empty
+	DateCreated time.Time    `json:"dateCreated,omitempty"`
+	Version     uint64       `json:"version,omitempty" fake:"skip"` // Incremented by every change, a change made with a version only applies to the credential at that version
+
+	ApiSecretHash              string     `json:"-" fake:"skip"`                                    // Salted hash of the api secret, see utils.HashSecret
+	ExpiresAt                  *time.Time `json:"expiresAt,omitempty" fake:"skip"`                  // Requests with the credential are rejected from then on, never when nil
+	PreviousApiSecretHash      string     `json:"-" fake:"skip"`                                    // Hash of the secret replaced by the last rotation
+	PreviousApiSecretExpiresAt *time.Time `json:"previousApiSecretExpiresAt,omitempty" fake:"skip"` // The secret replaced by the last rotation is accepted until then
+	LastUsedAt                 *time.Time `json:"lastUsedAt,omitempty" fake:"skip"`                 // Last request authenticated with the credential, written by the leader every CredentialUsageFlushIntervalSeconds
+	LastUsedFrom               string     `json:"lastUsedFrom,omitempty" fake:"skip"`               // Address of the client of that request
+
+	Actor Actor `json:"-" fake:"skip"` // Who is making a change to the credential, recorded in the audit events
+}
+
+// CredentialUsage a request authenticated with a credential
+type CredentialUsage struct {
+	CredentialID uint64    `json:"credentialId"`
+	UsedAt       time.Time `json:"usedAt"`
+	UsedFrom     string    `json:"usedFrom"`
+}
+
+// Expired reports whether the credential expired at now
+func (credentialModel *Credential) Expired(now time.Time) bool {
+	return credentialModel.ExpiresAt != nil && !now.Before(*credentialModel.ExpiresAt)
 }
 
 // PaginatedCredential paginated container of credential transformer
@@ -19,6 +56,8 @@ type PaginatedCredential struct {
 	Total  uint64       `json:"total,omitempty"`
 	Offset uint64       `json:"offset,omitempty"`
 	Limit  uint64       `json:"limit,omitempty"`
+	Next   string       `json:"next,omitempty"`
+	Prev   string       `json:"prev,omitempty"`
 	Data   []Credential `json:"credentials,omitempty"`
 }
 
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTypeJobCreated         EventType = "job.created"
+	EventTypeJobUpdated         EventType = "job.updated"
+	EventTypeJobDeleted         EventType = "job.deleted"
+	EventTypeExecutionScheduled EventType = "execution.scheduled"
+	EventTypeExecutionSucceeded EventType = "execution.succeeded"
+	EventTypeExecutionFailed    EventType = "execution.failed"
+	EventTypeLeaderChanged      EventType = "leader.changed"
+)
+
+// Event is a change in the cluster streamed to clients of the events endpoint.
+// The id is assigned by the node publishing the event and orders the events it streams since it started, at its epoch.
+type Event struct {
+	ID          uint64      `json:"id"`
+	Type        EventType   `json:"type"`
+	NodeID      uint64      `json:"nodeId"`
+	Epoch       int64       `json:"epoch"`
+	ProjectID   uint64      `json:"projectId,omitempty"`
+	JobID       uint64      `json:"jobId,omitempty"`
+	Data        interface{} `json:"data,omitempty"`
+	DateCreated time.Time   `json:"dateCreated"`
+}
+
+// Cursor returns the position of the event in the events of its node
+func (event Event) Cursor() EventCursor {
+	return EventCursor{
+		NodeID:  event.NodeID,
+		Epoch:   event.Epoch,
+		EventID: event.ID,
+	}
+}
+
+// EventCursor is the position of a client in the events of a node. Event ids restart with the node,
+// so the cursor names the node and the epoch it started at for the id to be compared with its events.
+type EventCursor struct {
+	NodeID  uint64
+	Epoch   int64
+	EventID uint64
+}
+
+// String formats the cursor as sent in the id of server-sent events, node id, epoch and event id separated by dashes
+func (cursor EventCursor) String() string {
+	return fmt.Sprintf("%d-%d-%d", cursor.NodeID, cursor.Epoch, cursor.EventID)
+}
+
+// ParseEventCursor parses a cursor formatted by EventCursor.String
+func ParseEventCursor(value string) (EventCursor, error) {
+	cursor := EventCursor{}
+	if _, err := fmt.Sscanf(value, "%d-%d-%d", &cursor.NodeID, &cursor.Epoch, &cursor.EventID); err != nil {
+		return EventCursor{}, fmt.Errorf("invalid event cursor %q, expected the id of an event", value)
+	}
+	if cursor.String() != value {
+		return EventCursor{}, fmt.Errorf("invalid event cursor %q, expected the id of an event", value)
+	}
+	return cursor, nil
+}
+
+// EventFilter narrows down the events streamed to a client, zero values match every event
+type EventFilter struct {
+	ProjectID uint64
+	JobID     uint64
+}
+
+// Matches reports whether the event passes the filter. Cluster events, which belong to no project, pass every filter.
+func (filter EventFilter) Matches(event Event) bool {
+	if event.ProjectID == 0 && event.JobID == 0 {
+		return true
+	}
+	if filter.ProjectID != 0 && event.ProjectID != filter.ProjectID {
+		return false
+	}
+	if filter.JobID != 0 && event.JobID != filter.JobID {
+		return false
+	}
+	return true
+}
+
+// ExecutionEventTypes are the types of the events published for the execution logs in each state
+var ExecutionEventTypes = map[JobExecutionLogState]EventType{
+	ExecutionLogScheduleState: EventTypeExecutionScheduled,
+	ExecutionLogSuccessState:  EventTypeExecutionSucceeded,
+	ExecutionLogFailedState:   EventTypeExecutionFailed,
+}
+
+// ExecutionEvent is the data of execution events
+type ExecutionEvent struct {
+	ExecutionId           string    `json:"executionId"`
+	LastExecutionDatetime time.Time `json:"lastExecutionDatetime"`
+	Error                 string    `json:"error,omitempty"`
+	LatencyMs             uint64    `json:"latencyMs,omitempty"`
+}
+
+// LeaderEvent is the data of leader change events
+type LeaderEvent struct {
+	LeaderAddress string `json:"leaderAddress"`
+	LeaderId      string `json:"leaderId"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// HealthCheck raft leader and stats of the node answering the healthcheck
+type HealthCheck struct {
+	LeaderAddress string            `json:"leaderAddress"`
+	LeaderId      string            `json:"leaderId"`
+	RaftStats     map[string]string `json:"raftStats"`
+}
+
+// PeerHandshake response of a node to the handshake of a peer joining the cluster
+type PeerHandshake struct {
+	IsLeader bool `json:"IsLeader"`
+}
+
+// ReadIndex commit index of the leader that a node applies before serving a linearizable read
+type ReadIndex struct {
+	Index uint64 `json:"index"`
+}
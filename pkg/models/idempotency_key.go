@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyKey records the async task created for a request sent with an Idempotency-Key header
+// Keys are scoped to the credential that sent the request.
+type IdempotencyKey struct {
+	CredentialId uint64    `json:"credentialId"`
+	Key          string    `json:"key"`
+	RequestId    string    `json:"requestId"`
+	RequestHash  string    `json:"requestHash"`
+	TaskId       uint64    `json:"taskId,omitempty"`
+	DateCreated  time.Time `json:"dateCreated"`
+}
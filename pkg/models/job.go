@@ -17,21 +17,35 @@ const (
 	ExecutionTypeHTTP ExecutionTypes = "http"
 )
 
+type JobStatus string
+
+const (
+	JobStatusActive JobStatus = "active"
+	JobStatusPaused JobStatus = "paused"
+)
+
 // Job job model
 type Job struct {
-	ID                uint64    `json:"id,omitempty" fake:"{number:1,100}"`
-	ProjectID         uint64    `json:"projectId,omitempty" fake:"{number:1,100}"`
-	Spec              string    `json:"spec,omitempty"`
-	CallbackUrl       string    `json:"callbackUrl,omitempty" fake:"{randomstring:[https://hello.com,https://world.com]}"`
-	Data              string    `json:"data,omitempty"`
-	ExecutionType     string    `json:"executionType,omitempty"`
-	StartDate         time.Time `json:"startDate,omitempty"`
-	EndDate           time.Time `json:"endDate,omitempty"`
-	LastExecutionDate time.Time `json:"lastExecutionDate,omitempty"`
-	Timezone          string    `json:"timezone,omitempty" fake:"{randomstring:[utc, America_NewYork]}"`
-	TimezoneOffset    int64     `json:"timezoneOffset,omitempty"`
-	ExecutionId       string    `json:"executionId,omitempty"`
-	DateCreated       time.Time `json:"dateCreated,omitempty"`
+	ID                uint64                 `json:"id,omitempty" fake:"{number:1,100}"`
+	ProjectID         uint64                 `json:"projectId,omitempty" fake:"{number:1,100}"`
+	Spec              string                 `json:"spec,omitempty"`
+	CallbackUrl       string                 `json:"callbackUrl,omitempty" fake:"{randomstring:[https://hello.com,https://world.com]}"`
+	Data              string                 `json:"data,omitempty"`
+	DataKeyID         string                 `json:"dataKeyId,omitempty" fake:"skip"` // Id of the key encrypting Data, Data is not encrypted when empty
+	ExecutionType     string                 `json:"executionType,omitempty"`
+	Status            JobStatus              `json:"status,omitempty" fake:"{randomstring:[active]}"`
+	ExternalKey       string                 `json:"externalKey,omitempty" fake:"skip"`
+	StartDate         time.Time              `json:"startDate,omitempty"`
+	EndDate           time.Time              `json:"endDate,omitempty"`
+	LastExecutionDate time.Time              `json:"lastExecutionDate,omitempty"`
+	Timezone          string                 `json:"timezone,omitempty" fake:"{randomstring:[utc, America_NewYork]}"`
+	TimezoneOffset    int64                  `json:"timezoneOffset,omitempty"`
+	ExecutionId       string                 `json:"executionId,omitempty"`
+	DateCreated       time.Time              `json:"dateCreated,omitempty"`
+	Labels            map[string]string      `json:"labels,omitempty" fake:"skip"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty" fake:"skip"`
+	Version           uint64                 `json:"version,omitempty" fake:"skip"` // Incremented by every change, a change made with a version only applies to the job at that version
+	Actor             Actor                  `json:"-" fake:"skip"`                 // Who is making a change to the job, its credential is recorded in the revisions of the job
 }
 
 // PaginatedJob paginated container of job transformer
@@ -39,6 +53,8 @@ type PaginatedJob struct {
 	Total  uint64 `json:"total,omitempty"`
 	Offset uint64 `json:"offset,omitempty"`
 	Limit  uint64 `json:"limit,omitempty"`
+	Next   string `json:"next,omitempty"`
+	Prev   string `json:"prev,omitempty"`
 	Data   []Job  `json:"jobs,omitempty"`
 }
 
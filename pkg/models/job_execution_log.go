@@ -21,6 +21,53 @@ type JobExecutionLog struct {
 	JobQueueVersion       uint64               `json:"jobQueueVersion" fake:"{number:1,100}"`
 	ExecutionVersion      uint64               `json:"executionVersion" fake:"{number:1,100}"`
 	DataCreated           time.Time            `json:"dataCreated"`
+	Error                 string               `json:"error,omitempty" fake:"skip"`
+	LatencyMs             uint64               `json:"latencyMs,omitempty" fake:"skip"`
+	AttemptCount          uint64               `json:"attemptCount,omitempty" fake:"skip"`
+	Committed             bool                 `json:"committed" fake:"skip"`
+}
+
+// JobExecutionResult is the outcome of executing a batch of jobs, recorded on their execution logs
+type JobExecutionResult struct {
+	Error   string
+	Latency time.Duration
+}
+
+// JobExecutionLogFilter narrows down the execution logs returned by the executions endpoints
+type JobExecutionLogFilter struct {
+	JobId     uint64
+	ProjectId uint64
+	State     *JobExecutionLogState
+	NodeId    uint64
+	From      time.Time
+	To        time.Time
+	Offset    uint64
+	Limit     uint64
+}
+
+// PaginatedJobExecutionLogs paginated container of execution logs
+type PaginatedJobExecutionLogs struct {
+	Total  uint64            `json:"total,omitempty"`
+	Offset uint64            `json:"offset,omitempty"`
+	Limit  uint64            `json:"limit,omitempty"`
+	Data   []JobExecutionLog `json:"executions,omitempty"`
+}
+
+// ExecutionLogRetentionPolicy decides which committed execution logs are removed when the leader compacts them.
+// Zero values disable the corresponding limit.
+type ExecutionLogRetentionPolicy struct {
+	MaxAge      time.Duration
+	MaxPerJob   uint64
+	MaxPerState map[JobExecutionLogState]uint64
+	DailyRollup bool
+}
+
+// JobExecutionDailyCount is the number of compacted execution logs of a job in a state on a day
+type JobExecutionDailyCount struct {
+	JobId uint64               `json:"jobId"`
+	Day   string               `json:"day"`
+	State JobExecutionLogState `json:"state"`
+	Count uint64               `json:"count"`
 }
 
 type MemJobExecution struct {
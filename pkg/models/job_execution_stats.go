@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// JobExecutionStats aggregates the execution logs of a job, or of every job in a project, over a time window.
+// Latency percentiles are the upper bound of the latency histogram bucket the percentile falls in.
+type JobExecutionStats struct {
+	JobId               uint64     `json:"jobId,omitempty"`
+	ProjectId           uint64     `json:"projectId,omitempty"`
+	From                time.Time  `json:"from"`
+	To                  time.Time  `json:"to"`
+	SuccessCount        uint64     `json:"successCount"`
+	FailedCount         uint64     `json:"failedCount"`
+	SuccessRate         float64    `json:"successRate"`
+	LatencyP50Ms        uint64     `json:"latencyP50Ms"`
+	LatencyP95Ms        uint64     `json:"latencyP95Ms"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure         *time.Time `json:"lastFailure,omitempty"`
+	ConsecutiveFailures uint64     `json:"consecutiveFailures"`
+	FailingJobs         uint64     `json:"failingJobs,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+type LabelSelectorOperator string
+
+const (
+	LabelSelectorEquals    LabelSelectorOperator = "="
+	LabelSelectorNotEquals LabelSelectorOperator = "!="
+	LabelSelectorExists    LabelSelectorOperator = "exists"
+	LabelSelectorNotExists LabelSelectorOperator = "!exists"
+)
+
+// LabelRequirement a single term of a label selector, e.g. env=prod, tier!=db, team or !legacy
+type LabelRequirement struct {
+	Key      string                `json:"key"`
+	Operator LabelSelectorOperator `json:"operator"`
+	Value    string                `json:"value,omitempty"`
+}
+
+// JobFilter criteria used to list, pause, resume or delete jobs in a project
+type JobFilter struct {
+	ProjectID         uint64             `json:"projectId"`
+	LabelSelector     []LabelRequirement `json:"labelSelector,omitempty"`
+	CallbackUrlPrefix string             `json:"callbackUrlPrefix,omitempty"`
+	ExecutionType     string             `json:"executionType,omitempty"`
+	Status            JobStatus          `json:"status,omitempty"`
+	OrderBy           string             `json:"orderBy,omitempty"`
+	Order             string             `json:"order,omitempty"`
+	Offset            uint64             `json:"offset,omitempty"`
+	Limit             uint64             `json:"limit,omitempty"`
+	Cursor            string             `json:"-"`
+	Actor             Actor              `json:"-"` // Who is making a bulk change, its credential is recorded in the job revisions
+}
+
+// PageRequest returns the ordering and position of the page of jobs listed with the filter
+func (filter JobFilter) PageRequest() PageRequest {
+	return PageRequest{
+		OrderBy: filter.OrderBy,
+		Order:   filter.Order,
+		Offset:  filter.Offset,
+		Limit:   filter.Limit,
+		Cursor:  filter.Cursor,
+	}
+}
+
+// BulkJobsResult number of jobs affected by a bulk pause, resume or delete
+type BulkJobsResult struct {
+	Affected uint64 `json:"affected"`
+}
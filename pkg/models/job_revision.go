@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type JobRevisionAction string
+
+const (
+	JobRevisionActionCreate   JobRevisionAction = "create"
+	JobRevisionActionUpdate   JobRevisionAction = "update"
+	JobRevisionActionDelete   JobRevisionAction = "delete"
+	JobRevisionActionRollback JobRevisionAction = "rollback"
+)
+
+// JobRevision snapshot of a job recorded when the job is created, updated or deleted
+type JobRevision struct {
+	JobID        uint64            `json:"jobId"`
+	Revision     uint64            `json:"revision"`
+	Action       JobRevisionAction `json:"action"`
+	Job          Job               `json:"job"`
+	CredentialID uint64            `json:"credentialId,omitempty"`
+	DateCreated  time.Time         `json:"dateCreated"`
+}
+
+// PaginatedJobRevisions paginated container of job revisions
+type PaginatedJobRevisions struct {
+	Total  uint64        `json:"total,omitempty"`
+	Offset uint64        `json:"offset,omitempty"`
+	Limit  uint64        `json:"limit,omitempty"`
+	Data   []JobRevision `json:"revisions,omitempty"`
+}
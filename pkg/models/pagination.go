@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Cursor position in a list ordered by a sort field, ties are broken by id.
+// A cursor is handed to clients as an opaque string, see EncodeCursor.
+type Cursor struct {
+	OrderBy string `json:"orderBy"`
+	Order   string `json:"order"`
+	Key     string `json:"key"`              // Sort key of the row the page starts after, or ends before
+	ID      uint64 `json:"id"`               // Id of the row the page starts after, or ends before
+	Before  bool   `json:"before,omitempty"` // Whether the page ends before the row instead of starting after it
+}
+
+// PageRequest ordering and position of a page of a list. Pages are read from the cursor when it is set,
+// otherwise after skipping offset items.
+type PageRequest struct {
+	OrderBy string
+	Order   string
+	Offset  uint64
+	Limit   uint64
+	Cursor  string // Next or prev cursor of an earlier page
+}
+
+// PageCursors cursors of the pages next to a page, empty when there is no such page
+type PageCursors struct {
+	Next string
+	Prev string
+}
+
+// EncodeCursor returns the opaque string of the cursor
+func EncodeCursor(cursor Cursor) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the cursor of a string returned by EncodeCursor
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	cursor := Cursor{}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
@@ -3,7 +3,11 @@ package models
 import "scheduler0/pkg/constants"
 
 type PostProcess struct {
-	Action      constants.CommandAction
-	TargetNodes []uint64
-	Data        SQLResponse
+	Action          constants.CommandAction
+	TargetNodes     []uint64
+	Data            SQLResponse
+	JobIds          []uint64
+	TraceParent     string        // The W3C traceparent of the span that wrote the command, empty when it was not traced
+	JobRevisions    []JobRevision // The job revisions recorded by the command, published as job events by every node
+	ExecutionEvents []Event       // The events of the execution logs committed by the command, published by every node
 }
@@ -0,0 +1,83 @@
+package models
+
+import "context"
+
+type principalContextKey struct{}
+
+// Principal the credential or peer making a request, and the projects and permissions it is allowed
+type Principal struct {
+	CredentialID uint64       `json:"credentialId,omitempty"`
+	Peer         bool         `json:"peer,omitempty"`
+	ProjectIDs   []uint64     `json:"projectIds,omitempty"`  // Projects the principal can access, every project when empty
+	Permissions  []Permission `json:"permissions,omitempty"` // What the principal can do, everything when empty
+	Address      string       `json:"address,omitempty"`     // Address of the client of the request
+}
+
+// CredentialPrincipal returns the principal of a request authenticated with the credential
+func CredentialPrincipal(credential Credential) Principal {
+	return Principal{
+		CredentialID: credential.ID,
+		ProjectIDs:   credential.ProjectIDs,
+		Permissions:  credential.Permissions,
+	}
+}
+
+// PeerPrincipal returns the principal of a request made by another node of the cluster, peers can do everything
+func PeerPrincipal() Principal {
+	return Principal{Peer: true}
+}
+
+// Can reports whether the principal has the permission. Admins can do everything, and writing or triggering jobs implies reading them.
+func (principal Principal) Can(permission Permission) bool {
+	if principal.Peer || len(principal.Permissions) == 0 {
+		return true
+	}
+	for _, granted := range principal.Permissions {
+		if granted == permission || granted == PermissionAdmin {
+			return true
+		}
+		if permission == PermissionRead && (granted == PermissionWrite || granted == PermissionTrigger) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllProjects reports whether the principal can access every project
+func (principal Principal) AllProjects() bool {
+	return principal.Peer || len(principal.ProjectIDs) == 0
+}
+
+// CanAccessProject reports whether the principal can access the project
+func (principal Principal) CanAccessProject(projectId uint64) bool {
+	if principal.AllProjects() {
+		return true
+	}
+	for _, allowed := range principal.ProjectIDs {
+		if allowed == projectId {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor returns the principal as the actor of the changes made by its request
+func (principal Principal) Actor(requestId string) Actor {
+	return Actor{
+		CredentialID: principal.CredentialID,
+		Peer:         principal.Peer,
+		RequestID:    requestId,
+		SourceIP:     principal.Address,
+	}
+}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal of the http request being served
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the http request in ctx, and false when the request was not authenticated
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
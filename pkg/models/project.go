@@ -11,6 +11,8 @@ type Project struct {
 	Name        string    `json:"name,omitempty" fake:"{regex:[abcdef]{5}}"`
 	Description string    `json:"description,omitempty" fake:"{regex:[abcdef]{5}}"`
 	DateCreated time.Time `json:"dateCreated,omitempty"`
+	Version     uint64    `json:"version,omitempty" fake:"skip"` // Incremented by every change, a change made with a version only applies to the project at that version
+	Actor       Actor     `json:"-" fake:"skip"`                 // Who is making a change to the project, recorded in the audit events
 }
 
 // PaginatedProject paginated container of project transformer
@@ -18,6 +20,8 @@ type PaginatedProject struct {
 	Total  uint64    `json:"total,omitempty"`
 	Offset uint64    `json:"offset,omitempty"`
 	Limit  uint64    `json:"limit,omitempty"`
+	Next   string    `json:"next,omitempty"`
+	Prev   string    `json:"prev,omitempty"`
 	Data   []Project `json:"projects,omitempty"`
 }
 
@@ -13,9 +13,18 @@ func NewDialer(header byte) *Dialer {
 	}
 }
 
+// NewSecureDialer returns a Dialer securing its connections with security
+func NewSecureDialer(header byte, security Security) *Dialer {
+	return &Dialer{
+		header:   header,
+		security: security,
+	}
+}
+
 // Dialer supports dialing a cluster service.
 type Dialer struct {
-	header byte
+	header   byte
+	security Security
 }
 
 // Dial dials the cluster service at the given addr and returns a connection.
@@ -33,11 +42,17 @@ func (d *Dialer) Dial(addr string, timeout time.Duration) (conn net.Conn, retErr
 	}()
 
 	// Write a marker byte to indicate message type.
-	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
-		return nil, fmt.Errorf("failed to set WriteDeadline for header: %s", err.Error())
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set Deadline for header: %s", err.Error())
+	}
+	if conn, retErr = d.security.secureClient(conn, addr); retErr != nil {
+		return nil, retErr
 	}
 	if _, err := conn.Write([]byte{d.header}); err != nil {
 		return nil, err
 	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to reset Deadline: %s", err.Error())
+	}
 	return conn, nil
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"os"
 	"sync"
 	"time"
 )
@@ -20,20 +21,28 @@ type Mux struct {
 	m    map[byte]*listener
 	addr net.Addr
 
-	logger *log.Logger
-	wg     sync.WaitGroup
+	logger   *log.Logger
+	wg       sync.WaitGroup
+	security Security
 }
 
 func NewMux(ln net.Listener, adv net.Addr) *Mux {
+	return NewSecureMux(ln, adv, Security{})
+}
+
+// NewSecureMux returns a Mux securing the connections it accepts, and the connections of the dialers of its layers, with security
+func NewSecureMux(ln net.Listener, adv net.Addr, security Security) *Mux {
 	addr := adv
 	if addr == nil {
 		addr = ln.Addr()
 	}
 
 	return &Mux{
-		ln:   ln,
-		addr: addr,
-		m:    make(map[byte]*listener),
+		ln:       ln,
+		addr:     addr,
+		m:        make(map[byte]*listener),
+		logger:   log.New(os.Stderr, "[mux] ", log.LstdFlags),
+		security: security,
 	}
 }
 
@@ -111,7 +120,7 @@ func (mux *Mux) Listen(header byte) *Layer {
 		ln:   ln,
 		addr: mux.addr,
 	}
-	layer.dialer = NewDialer(header)
+	layer.dialer = NewSecureDialer(header, mux.security)
 
 	return layer
 }
@@ -119,13 +128,22 @@ func (mux *Mux) Listen(header byte) *Layer {
 func (mux *Mux) handleConn(conn net.Conn) {
 
 	defer mux.wg.Done()
-	// Set a read deadline so connections with no data don't timeout.
-	if err := conn.SetReadDeadline(time.Now().Add(DefaultTimeout)); err != nil {
+	// Set a deadline so connections with no data don't timeout.
+	if err := conn.SetDeadline(time.Now().Add(DefaultTimeout)); err != nil {
 		log.Println("closing connection due to error", err.Error())
 		conn.Close()
 		return
 	}
 
+	// Secure the connection before reading the header, peers failing the handshakes are never handed to a listener
+	securedConn, err := mux.security.secureServer(conn)
+	if err != nil {
+		log.Println("closing connection from", conn.RemoteAddr().String(), "due to error", err.Error())
+		conn.Close()
+		return
+	}
+	conn = securedConn
+
 	// Read first byte from connection to determine handler.
 	var typ [1]byte
 	if _, err := io.ReadFull(conn, typ[:]); err != nil {
@@ -134,8 +152,8 @@ func (mux *Mux) handleConn(conn net.Conn) {
 		return
 	}
 
-	// Reset read deadline and let the listener handle that.
-	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+	// Reset deadline and let the listener handle that.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
 		log.Println("closing connection due to error", err.Error())
 		conn.Close()
 		return
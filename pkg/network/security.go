@@ -0,0 +1,165 @@
+package network
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"scheduler0/pkg/certificates"
+)
+
+const (
+	// sharedKeyNonceSize is the size of the nonces exchanged by the shared key handshake
+	sharedKeyNonceSize = 32
+)
+
+// Security secures the connections between nodes. The zero value leaves connections in plain TCP.
+type Security struct {
+	// Certificates wraps connections in TLS when not nil, both ends present their certificate and verify the other with the CA
+	Certificates *certificates.Reloader
+	// PeerHosts are the hosts of the replicas, the certificates of peers must be issued for one of them. Any certificate
+	// verified by the CA is accepted when empty.
+	PeerHosts []string
+	// SharedKey makes both ends prove they know the key before a connection is used when not empty, without sending the key
+	SharedKey []byte
+}
+
+// secureServer secures a connection accepted from a peer
+func (security Security) secureServer(conn net.Conn) (net.Conn, error) {
+	if security.Certificates != nil {
+		tlsConn := tls.Server(conn, security.Certificates.ServerConfig(tls.RequireAndVerifyClientCert))
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("tls handshake failed: %w", err)
+		}
+		if err := security.verifyPeer(tlsConn.ConnectionState()); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if len(security.SharedKey) > 0 {
+		if err := security.sharedKeyServerHandshake(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// secureClient secures a connection made to the peer at addr
+func (security Security) secureClient(conn net.Conn, addr string) (net.Conn, error) {
+	if security.Certificates != nil {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := security.Certificates.ClientConfig()
+		tlsConfig.ServerName = host
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("tls handshake failed: %w", err)
+		}
+		if err := security.verifyPeer(tlsConn.ConnectionState()); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if len(security.SharedKey) > 0 {
+		if err := security.sharedKeyClientHandshake(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// verifyPeer checks that the certificate of the peer was issued for one of the replicas
+func (security Security) verifyPeer(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+	if len(security.PeerHosts) == 0 {
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	for _, host := range security.PeerHosts {
+		if leaf.VerifyHostname(host) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate of %s is not issued for a replica", leaf.Subject.CommonName)
+}
+
+// sharedKeyServerHandshake reads the nonce of the client, answers with its nonce and proof of the key, and checks the proof of the client
+func (security Security) sharedKeyServerHandshake(conn net.Conn) error {
+	clientNonce := make([]byte, sharedKeyNonceSize)
+	if _, err := io.ReadFull(conn, clientNonce); err != nil {
+		return fmt.Errorf("failed to read the nonce of the client: %w", err)
+	}
+
+	serverNonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	serverProof := security.sharedKeyProof("server", clientNonce, serverNonce)
+	if _, err := conn.Write(append(serverNonce, serverProof...)); err != nil {
+		return fmt.Errorf("failed to write the proof of the server: %w", err)
+	}
+
+	clientProof := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, clientProof); err != nil {
+		return fmt.Errorf("failed to read the proof of the client: %w", err)
+	}
+	if !hmac.Equal(clientProof, security.sharedKeyProof("client", clientNonce, serverNonce)) {
+		return errors.New("client does not know the shared key")
+	}
+	return nil
+}
+
+// sharedKeyClientHandshake sends a nonce, checks the proof of the server and answers with the proof of the client
+func (security Security) sharedKeyClientHandshake(conn net.Conn) error {
+	clientNonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(clientNonce); err != nil {
+		return fmt.Errorf("failed to write the nonce of the client: %w", err)
+	}
+
+	serverReply := make([]byte, sharedKeyNonceSize+sha256.Size)
+	if _, err := io.ReadFull(conn, serverReply); err != nil {
+		return fmt.Errorf("failed to read the proof of the server: %w", err)
+	}
+	serverNonce := serverReply[:sharedKeyNonceSize]
+	if !hmac.Equal(serverReply[sharedKeyNonceSize:], security.sharedKeyProof("server", clientNonce, serverNonce)) {
+		return errors.New("server does not know the shared key")
+	}
+
+	if _, err := conn.Write(security.sharedKeyProof("client", clientNonce, serverNonce)); err != nil {
+		return fmt.Errorf("failed to write the proof of the client: %w", err)
+	}
+	return nil
+}
+
+// sharedKeyProof returns the HMAC of the nonces of a handshake by the shared key, labeled with the side giving the proof
+func (security Security) sharedKeyProof(side string, clientNonce []byte, serverNonce []byte) []byte {
+	mac := hmac.New(sha256.New, security.SharedKey)
+	mac.Write([]byte("scheduler0-raft-" + side))
+	mac.Write(clientNonce)
+	mac.Write(serverNonce)
+	return mac.Sum(nil)
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, sharedKeyNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate a nonce: %w", err)
+	}
+	return nonce, nil
+}
@@ -0,0 +1,199 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/hashicorp/go-hclog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"scheduler0/pkg/certificates"
+	"testing"
+	"time"
+)
+
+// testCertificates issues certificates signed by a CA generated for the test
+type testCertificates struct {
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caFile string
+}
+
+func newTestCertificates(t *testing.T) *testCertificates {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate the CA key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "scheduler0-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("failed to create the CA:", err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("failed to parse the CA:", err)
+	}
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal("failed to write the CA:", err)
+	}
+	return &testCertificates{dir: dir, ca: ca, caKey: key, caFile: caFile}
+}
+
+// reloader issues a certificate for the hosts and returns the reloader serving it
+func (certs *testCertificates) reloader(t *testing.T, name string, ips []net.IP, dnsNames []string) *certificates.Reloader {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate the key:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  ips,
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, certs.ca, &key.PublicKey, certs.caKey)
+	if err != nil {
+		t.Fatal("failed to create the certificate:", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("failed to marshal the key:", err)
+	}
+
+	certFile := filepath.Join(certs.dir, name+".crt")
+	keyFile := filepath.Join(certs.dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal("failed to write the certificate:", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal("failed to write the key:", err)
+	}
+
+	reloader, err := certificates.NewReloader(hclog.NewNullLogger(), certFile, keyFile, certs.caFile)
+	if err != nil {
+		t.Fatal("failed to load the certificate:", err)
+	}
+	return reloader
+}
+
+// serveMux serves a secure mux on the loopback address and returns the layer of the header 1
+func serveMux(t *testing.T, security Security) *Layer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("failed to listen:", err)
+	}
+	mux := NewSecureMux(ln, nil, security)
+	layer := mux.Listen(1)
+	go mux.Serve()
+	t.Cleanup(func() { ln.Close() })
+	return layer
+}
+
+// exchange dials the layer with the dialer, and returns whether the layer accepted the connection and read what was written on it
+func exchange(t *testing.T, layer *Layer, dialer *Dialer) bool {
+	accepted := make(chan string, 1)
+	go func() {
+		conn, err := layer.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4)
+		if _, err := conn.Read(buf); err == nil {
+			accepted <- string(buf)
+		}
+	}()
+
+	conn, err := dialer.Dial(layer.Addr().String(), time.Second)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		return false
+	}
+
+	select {
+	case message := <-accepted:
+		return message == "ping"
+	case <-time.After(500 * time.Millisecond):
+		return false
+	}
+}
+
+func Test_SecureMux_TLS(t *testing.T) {
+	certs := newTestCertificates(t)
+	replicaHosts := []string{"127.0.0.1"}
+	node := certs.reloader(t, "node", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+
+	layer := serveMux(t, Security{Certificates: node, PeerHosts: replicaHosts})
+
+	if !exchange(t, layer, NewSecureDialer(1, Security{Certificates: node, PeerHosts: replicaHosts})) {
+		t.Fatal("expected a replica to connect")
+	}
+
+	// A certificate of the CA that is not issued for a replica is rejected
+	intruder := certs.reloader(t, "intruder", nil, []string{"intruder.example"})
+	if exchange(t, layer, NewSecureDialer(1, Security{Certificates: intruder})) {
+		t.Fatal("expected a certificate not issued for a replica to be rejected")
+	}
+
+	// Connections without TLS are rejected
+	if exchange(t, layer, NewDialer(1)) {
+		t.Fatal("expected a plain connection to be rejected")
+	}
+
+	// Peers with a certificate of another CA are rejected
+	otherCA := newTestCertificates(t)
+	stranger := otherCA.reloader(t, "stranger", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+	if exchange(t, layer, NewSecureDialer(1, Security{Certificates: stranger})) {
+		t.Fatal("expected a certificate of another CA to be rejected")
+	}
+}
+
+func Test_SecureMux_SharedKey(t *testing.T) {
+	layer := serveMux(t, Security{SharedKey: []byte("shared-key")})
+
+	if !exchange(t, layer, NewSecureDialer(1, Security{SharedKey: []byte("shared-key")})) {
+		t.Fatal("expected a peer knowing the shared key to connect")
+	}
+
+	if exchange(t, layer, NewSecureDialer(1, Security{SharedKey: []byte("another-key")})) {
+		t.Fatal("expected a peer with another key to be rejected")
+	}
+}
+
+func Test_SecureMux_TLSAndSharedKey(t *testing.T) {
+	certs := newTestCertificates(t)
+	node := certs.reloader(t, "node", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+	security := Security{Certificates: node, PeerHosts: []string{"127.0.0.1"}, SharedKey: []byte("shared-key")}
+
+	layer := serveMux(t, security)
+	if !exchange(t, layer, NewSecureDialer(1, security)) {
+		t.Fatal("expected a replica knowing the shared key to connect")
+	}
+
+	if exchange(t, layer, NewSecureDialer(1, Security{Certificates: node, SharedKey: []byte("another-key")})) {
+		t.Fatal("expected a replica with another key to be rejected")
+	}
+}
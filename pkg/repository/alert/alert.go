@@ -0,0 +1,375 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/scheduler0time"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+//go:generate mockery --name AlertRepo --output ../mocks
+type AlertRepo interface {
+	CreateOne(alertRule *models.AlertRule) (uint64, *utils.GenericError)
+	GetOneByID(alertRule *models.AlertRule) *utils.GenericError
+	List(projectId uint64, offset uint64, limit uint64) ([]models.AlertRule, *utils.GenericError)
+	Count(projectId uint64) (uint64, *utils.GenericError)
+	ListAll() ([]models.AlertRule, *utils.GenericError)
+	UpdateOneByID(alertRule models.AlertRule) (uint64, *utils.GenericError)
+	DeleteOneByID(alertRule models.AlertRule) (uint64, *utils.GenericError)
+	GetMatchingJobIds(alertRule models.AlertRule, now time.Time, retryMax uint64) ([]uint64, *utils.GenericError)
+	GetFiringJobIds(ruleId uint64) ([]uint64, *utils.GenericError)
+	InsertFiringAlerts(ruleId uint64, jobIds []uint64) *utils.GenericError
+	DeleteFiringAlerts(ruleId uint64, jobIds []uint64) *utils.GenericError
+}
+
+type alertRepo struct {
+	fsmStore              fsm.Scheduler0RaftStore
+	logger                hclog.Logger
+	scheduler0RaftActions fsm.Scheduler0RaftActions
+}
+
+func NewAlertRepo(logger hclog.Logger, scheduler0RaftActions fsm.Scheduler0RaftActions, store fsm.Scheduler0RaftStore) AlertRepo {
+	return &alertRepo{
+		fsmStore:              store,
+		scheduler0RaftActions: scheduler0RaftActions,
+		logger:                logger.Named("alert-repo"),
+	}
+}
+
+var alertRuleColumns = []string{
+	constants.AlertRulesIdColumn,
+	constants.AlertRulesProjectIdColumn,
+	constants.AlertRulesTypeColumn,
+	constants.AlertRulesThresholdColumn,
+	constants.AlertRulesPeriodSecondsColumn,
+	constants.AlertRulesWebhookUrlColumn,
+	constants.AlertRulesDateCreatedColumn,
+}
+
+// CreateOne creates a single alert rule
+func (alertRepo *alertRepo) CreateOne(alertRule *models.AlertRule) (uint64, *utils.GenericError) {
+	schedulerTime := scheduler0time.GetSchedulerTime()
+	now := schedulerTime.GetTime(time.Now())
+
+	query, params, err := sq.Insert(constants.AlertRulesTableName).
+		Columns(
+			constants.AlertRulesProjectIdColumn,
+			constants.AlertRulesTypeColumn,
+			constants.AlertRulesThresholdColumn,
+			constants.AlertRulesPeriodSecondsColumn,
+			constants.AlertRulesWebhookUrlColumn,
+			constants.AlertRulesDateCreatedColumn,
+		).
+		Values(
+			alertRule.ProjectID,
+			alertRule.Type,
+			alertRule.Threshold,
+			alertRule.PeriodSeconds,
+			alertRule.WebhookUrl,
+			now,
+		).ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	res, applyErr := alertRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), alertRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	alertRule.ID = uint64(res.Data.LastInsertedId)
+
+	getErr := alertRepo.GetOneByID(alertRule)
+	if getErr != nil {
+		return 0, getErr
+	}
+
+	return alertRule.ID, nil
+}
+
+// GetOneByID returns the alert rule of a project that matches the id
+func (alertRepo *alertRepo) GetOneByID(alertRule *models.AlertRule) *utils.GenericError {
+	alertRules, err := alertRepo.selectAlertRules(
+		sq.Eq{
+			constants.AlertRulesIdColumn:        alertRule.ID,
+			constants.AlertRulesProjectIdColumn: alertRule.ProjectID,
+		},
+		0,
+		1,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(alertRules) < 1 {
+		return utils.HTTPGenericError(http.StatusNotFound, "alert does not exist")
+	}
+
+	*alertRule = alertRules[0]
+	return nil
+}
+
+// List returns a paginated set of the alert rules of a project
+func (alertRepo *alertRepo) List(projectId uint64, offset uint64, limit uint64) ([]models.AlertRule, *utils.GenericError) {
+	return alertRepo.selectAlertRules(sq.Eq{constants.AlertRulesProjectIdColumn: projectId}, offset, limit)
+}
+
+// Count returns the number of alert rules of a project
+func (alertRepo *alertRepo) Count(projectId uint64) (uint64, *utils.GenericError) {
+	alertRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer alertRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	var count uint64
+	err := sq.Select("count(*)").
+		From(constants.AlertRulesTableName).
+		Where(fmt.Sprintf("%s = ?", constants.AlertRulesProjectIdColumn), projectId).
+		RunWith(alertRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		QueryRow().
+		Scan(&count)
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	return count, nil
+}
+
+// ListAll returns the alert rules of every project
+func (alertRepo *alertRepo) ListAll() ([]models.AlertRule, *utils.GenericError) {
+	return alertRepo.selectAlertRules(nil, 0, 0)
+}
+
+// UpdateOneByID updates the threshold, period and webhook of an alert rule
+func (alertRepo *alertRepo) UpdateOneByID(alertRule models.AlertRule) (uint64, *utils.GenericError) {
+	query, params, err := sq.Update(constants.AlertRulesTableName).
+		Set(constants.AlertRulesThresholdColumn, alertRule.Threshold).
+		Set(constants.AlertRulesPeriodSecondsColumn, alertRule.PeriodSeconds).
+		Set(constants.AlertRulesWebhookUrlColumn, alertRule.WebhookUrl).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", constants.AlertRulesIdColumn, constants.AlertRulesProjectIdColumn), alertRule.ID, alertRule.ProjectID).
+		ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	res, applyErr := alertRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), alertRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// DeleteOneByID deletes an alert rule together with its firing alerts
+func (alertRepo *alertRepo) DeleteOneByID(alertRule models.AlertRule) (uint64, *utils.GenericError) {
+	query, params, err := sq.Delete(constants.AlertRulesTableName).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", constants.AlertRulesIdColumn, constants.AlertRulesProjectIdColumn), alertRule.ID, alertRule.ProjectID).
+		ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	res, applyErr := alertRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), alertRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// GetMatchingJobIds returns the ids of the jobs of the project of the alert rule that the rule currently fires for.
+// Paused jobs never match. A job is dead-lettered when the last of its executions that ran failed more than
+// retryMax times without succeeding.
+func (alertRepo *alertRepo) GetMatchingJobIds(alertRule models.AlertRule, now time.Time, retryMax uint64) ([]uint64, *utils.GenericError) {
+	var query string
+	var params []interface{}
+
+	switch alertRule.Type {
+	case models.AlertRuleTypeConsecutiveFailures:
+		query = "SELECT jobs.id FROM jobs JOIN job_execution_summaries ON job_execution_summaries.job_id = jobs.id " +
+			"WHERE jobs.project_id = ? AND jobs.status != ? AND job_execution_summaries.consecutive_failures >= ?"
+		params = []interface{}{alertRule.ProjectID, models.JobStatusPaused, alertRule.Threshold}
+	case models.AlertRuleTypeNoSuccess:
+		query = "SELECT jobs.id FROM jobs LEFT JOIN job_execution_summaries ON job_execution_summaries.job_id = jobs.id " +
+			"WHERE jobs.project_id = ? AND jobs.status != ? " +
+			"AND julianday(IFNULL(job_execution_summaries.last_success, jobs.date_created)) < julianday(?)"
+		params = []interface{}{
+			alertRule.ProjectID,
+			models.JobStatusPaused,
+			now.Add(-time.Duration(alertRule.PeriodSeconds) * time.Second).UTC().Format(time.RFC3339Nano),
+		}
+	case models.AlertRuleTypeDeadLettered:
+		query = "SELECT job_id FROM (" +
+			"SELECT job_id, execution_version, state, max(execution_version) OVER (PARTITION BY job_id) AS last_execution_version " +
+			"FROM job_executions_committed " +
+			"WHERE state != ? AND job_id IN (SELECT id FROM jobs WHERE project_id = ? AND status != ?)" +
+			") WHERE execution_version = last_execution_version GROUP BY job_id " +
+			"HAVING count(CASE WHEN state = ? THEN 1 END) > ? AND count(CASE WHEN state = ? THEN 1 END) = 0"
+		params = []interface{}{
+			models.ExecutionLogScheduleState,
+			alertRule.ProjectID,
+			models.JobStatusPaused,
+			models.ExecutionLogFailedState,
+			retryMax,
+			models.ExecutionLogSuccessState,
+		}
+	default:
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("unknown alert type %s", alertRule.Type))
+	}
+
+	return alertRepo.selectIds(query, params)
+}
+
+// GetFiringJobIds returns the ids of the jobs an alert rule has fired for and not resolved yet
+func (alertRepo *alertRepo) GetFiringJobIds(ruleId uint64) ([]uint64, *utils.GenericError) {
+	return alertRepo.selectIds(
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", constants.FiringAlertsJobIdColumn, constants.FiringAlertsTableName, constants.FiringAlertsRuleIdColumn),
+		[]interface{}{ruleId},
+	)
+}
+
+// InsertFiringAlerts records that an alert rule fired for the jobs, so it does not fire for them again until resolved
+func (alertRepo *alertRepo) InsertFiringAlerts(ruleId uint64, jobIds []uint64) *utils.GenericError {
+	if len(jobIds) < 1 {
+		return nil
+	}
+
+	schedulerTime := scheduler0time.GetSchedulerTime()
+	now := schedulerTime.GetTime(time.Now())
+
+	for _, batch := range utils.Batch[uint64](jobIds, 3) {
+		insertBuilder := sq.Insert(constants.FiringAlertsTableName).
+			Options("OR IGNORE").
+			Columns(
+				constants.FiringAlertsRuleIdColumn,
+				constants.FiringAlertsJobIdColumn,
+				constants.FiringAlertsDateCreatedColumn,
+			)
+		for _, jobId := range batch {
+			insertBuilder = insertBuilder.Values(ruleId, jobId, now)
+		}
+
+		query, params, err := insertBuilder.ToSql()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+
+		_, applyErr := alertRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), alertRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+		if applyErr != nil {
+			return applyErr
+		}
+	}
+
+	return nil
+}
+
+// DeleteFiringAlerts records that an alert rule resolved for the jobs
+func (alertRepo *alertRepo) DeleteFiringAlerts(ruleId uint64, jobIds []uint64) *utils.GenericError {
+	if len(jobIds) < 1 {
+		return nil
+	}
+
+	for _, batch := range utils.Batch[uint64](jobIds, 2) {
+		query, params, err := sq.Delete(constants.FiringAlertsTableName).
+			Where(sq.Eq{
+				constants.FiringAlertsRuleIdColumn: ruleId,
+				constants.FiringAlertsJobIdColumn:  batch,
+			}).
+			ToSql()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+
+		_, applyErr := alertRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), alertRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+		if applyErr != nil {
+			return applyErr
+		}
+	}
+
+	return nil
+}
+
+func (alertRepo *alertRepo) selectAlertRules(condition sq.Sqlizer, offset uint64, limit uint64) ([]models.AlertRule, *utils.GenericError) {
+	alertRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer alertRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	selectBuilder := sq.Select(alertRuleColumns...).
+		From(constants.AlertRulesTableName).
+		OrderBy(constants.AlertRulesIdColumn).
+		RunWith(alertRepo.fsmStore.GetDataStore().GetOpenConnection())
+	if condition != nil {
+		selectBuilder = selectBuilder.Where(condition)
+	}
+	if limit > 0 {
+		selectBuilder = selectBuilder.Offset(offset).Limit(limit)
+	}
+
+	rows, err := selectBuilder.Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	alertRules := []models.AlertRule{}
+	for rows.Next() {
+		alertRule := models.AlertRule{}
+		scanErr := rows.Scan(
+			&alertRule.ID,
+			&alertRule.ProjectID,
+			&alertRule.Type,
+			&alertRule.Threshold,
+			&alertRule.PeriodSeconds,
+			&alertRule.WebhookUrl,
+			&alertRule.DateCreated,
+		)
+		if scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		alertRules = append(alertRules, alertRule)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return alertRules, nil
+}
+
+func (alertRepo *alertRepo) selectIds(query string, params []interface{}) ([]uint64, *utils.GenericError) {
+	alertRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer alertRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	rows, err := alertRepo.fsmStore.GetDataStore().GetOpenConnection().Query(query, params...)
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	ids := []uint64{}
+	for rows.Next() {
+		var id uint64
+		scanErr := rows.Scan(&id)
+		if scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		ids = append(ids, id)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return ids, nil
+}
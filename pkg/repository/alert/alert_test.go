@@ -0,0 +1,181 @@
+package alert
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/db"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/job"
+	"scheduler0/pkg/repository/job_execution"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/shared_repo"
+	"testing"
+	"time"
+)
+
+func Test_AlertRepo(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "alert-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	alertRepo := NewAlertRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobExecutionsRepo := job_execution.NewExecutionsRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	project := models.Project{
+		ID:          1,
+		Name:        "Test Project",
+		Description: "Test project description",
+	}
+	_, pcreateErr := projectRepo.CreateOne(&project)
+	if pcreateErr != nil {
+		t.Fatal("failed to create project:", pcreateErr)
+	}
+
+	jobs := []models.Job{
+		{ID: 1, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+		{ID: 2, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+		{ID: 3, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+	}
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if insertErr != nil {
+		t.Fatal("failed to insert job", insertErr)
+	}
+
+	now := time.Now()
+	executionLog := func(jobId uint64, state models.JobExecutionLogState, executionVersion uint64, minutesAgo int) models.JobExecutionLog {
+		return models.JobExecutionLog{
+			JobId:                 jobId,
+			UniqueId:              "1",
+			State:                 state,
+			NodeId:                1,
+			LastExecutionDatetime: now,
+			NextExecutionDatetime: now,
+			JobQueueVersion:       1,
+			ExecutionVersion:      executionVersion,
+			DataCreated:           now.Add(-time.Duration(minutesAgo) * time.Minute),
+		}
+	}
+	jobExecutionsRepo.RaftInsertExecutionLogs([]models.JobExecutionLog{
+		executionLog(1, models.ExecutionLogSuccessState, 1, 30),
+		executionLog(1, models.ExecutionLogFailedState, 2, 20),
+		executionLog(1, models.ExecutionLogFailedState, 3, 10),
+		executionLog(2, models.ExecutionLogFailedState, 1, 20),
+		executionLog(2, models.ExecutionLogSuccessState, 1, 10),
+		executionLog(3, models.ExecutionLogScheduleState, 1, 4),
+		executionLog(3, models.ExecutionLogFailedState, 1, 3),
+		executionLog(3, models.ExecutionLogFailedState, 1, 2),
+		executionLog(3, models.ExecutionLogFailedState, 1, 1),
+	}, 1)
+
+	consecutiveFailures := models.AlertRule{ProjectID: 1, Type: models.AlertRuleTypeConsecutiveFailures, Threshold: 2, WebhookUrl: "http://localhost/alerts"}
+	noSuccess := models.AlertRule{ProjectID: 1, Type: models.AlertRuleTypeNoSuccess, PeriodSeconds: 20 * 60, WebhookUrl: "http://localhost/alerts"}
+	deadLettered := models.AlertRule{ProjectID: 1, Type: models.AlertRuleTypeDeadLettered, WebhookUrl: "http://localhost/alerts"}
+	for _, alertRule := range []*models.AlertRule{&consecutiveFailures, &noSuccess, &deadLettered} {
+		_, createErr := alertRepo.CreateOne(alertRule)
+		if createErr != nil {
+			t.Fatal("failed to create alert rule", createErr)
+		}
+	}
+
+	count, countErr := alertRepo.Count(1)
+	if countErr != nil {
+		t.Fatal("failed to count alert rules", countErr)
+	}
+	assert.Equal(t, uint64(3), count)
+
+	fetched := models.AlertRule{ID: noSuccess.ID, ProjectID: 1}
+	getErr := alertRepo.GetOneByID(&fetched)
+	if getErr != nil {
+		t.Fatal("failed to get alert rule", getErr)
+	}
+	assert.Equal(t, models.AlertRuleTypeNoSuccess, fetched.Type)
+	assert.Equal(t, uint64(20*60), fetched.PeriodSeconds)
+
+	matchingJobIds, matchErr := alertRepo.GetMatchingJobIds(consecutiveFailures, now, 2)
+	if matchErr != nil {
+		t.Fatal("failed to match consecutive failures", matchErr)
+	}
+	assert.ElementsMatch(t, []uint64{1, 3}, matchingJobIds)
+
+	matchingJobIds, matchErr = alertRepo.GetMatchingJobIds(noSuccess, now, 2)
+	if matchErr != nil {
+		t.Fatal("failed to match no success", matchErr)
+	}
+	assert.ElementsMatch(t, []uint64{1}, matchingJobIds)
+
+	matchingJobIds, matchErr = alertRepo.GetMatchingJobIds(deadLettered, now, 2)
+	if matchErr != nil {
+		t.Fatal("failed to match dead lettered", matchErr)
+	}
+	assert.ElementsMatch(t, []uint64{3}, matchingJobIds)
+
+	insertFiringErr := alertRepo.InsertFiringAlerts(consecutiveFailures.ID, []uint64{1, 3})
+	if insertFiringErr != nil {
+		t.Fatal("failed to insert firing alerts", insertFiringErr)
+	}
+	insertFiringErr = alertRepo.InsertFiringAlerts(consecutiveFailures.ID, []uint64{3})
+	if insertFiringErr != nil {
+		t.Fatal("failed to insert firing alerts twice", insertFiringErr)
+	}
+	firingJobIds, firingErr := alertRepo.GetFiringJobIds(consecutiveFailures.ID)
+	if firingErr != nil {
+		t.Fatal("failed to get firing alerts", firingErr)
+	}
+	assert.ElementsMatch(t, []uint64{1, 3}, firingJobIds)
+
+	deleteFiringErr := alertRepo.DeleteFiringAlerts(consecutiveFailures.ID, []uint64{1})
+	if deleteFiringErr != nil {
+		t.Fatal("failed to delete firing alerts", deleteFiringErr)
+	}
+	firingJobIds, firingErr = alertRepo.GetFiringJobIds(consecutiveFailures.ID)
+	if firingErr != nil {
+		t.Fatal("failed to get firing alerts", firingErr)
+	}
+	assert.ElementsMatch(t, []uint64{3}, firingJobIds)
+
+	deleted, deleteErr := alertRepo.DeleteOneByID(consecutiveFailures)
+	if deleteErr != nil {
+		t.Fatal("failed to delete alert rule", deleteErr)
+	}
+	assert.Equal(t, uint64(1), deleted)
+	firingJobIds, firingErr = alertRepo.GetFiringJobIds(consecutiveFailures.ID)
+	if firingErr != nil {
+		t.Fatal("failed to get firing alerts", firingErr)
+	}
+	assert.Empty(t, firingJobIds)
+}
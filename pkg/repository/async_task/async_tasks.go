@@ -2,14 +2,17 @@ package async_task
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/scheduler0time"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"time"
 )
@@ -24,8 +27,8 @@ type asyncTasksRepo struct {
 //go:generate mockery --name AsyncTasksRepo --output ../mocks
 type AsyncTasksRepo interface {
 	BatchInsert(tasks []models.AsyncTask, committed bool) ([]uint64, *utils.GenericError)
-	RaftBatchInsert(tasks []models.AsyncTask, fromNodeId uint64) ([]uint64, *utils.GenericError)
-	RaftUpdateTaskState(task models.AsyncTask, state models.AsyncTaskState, output string) *utils.GenericError
+	RaftBatchInsert(ctx context.Context, tasks []models.AsyncTask, fromNodeId uint64) ([]uint64, *utils.GenericError)
+	RaftUpdateTaskState(ctx context.Context, task models.AsyncTask, state models.AsyncTaskState, output string) *utils.GenericError
 	UpdateTaskState(task models.AsyncTask, state models.AsyncTaskState, output string) *utils.GenericError
 	GetTask(taskId uint64) (*models.AsyncTask, *utils.GenericError)
 	GetAllTasks(committed bool) ([]models.AsyncTask, *utils.GenericError)
@@ -44,7 +47,7 @@ func (repo *asyncTasksRepo) BatchInsert(tasks []models.AsyncTask, committed bool
 	repo.fsmStore.GetDataStore().ConnectionLock()
 	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
 
-	batches := utils.Batch[models.AsyncTask](tasks, 5)
+	batches := utils.Batch[models.AsyncTask](tasks, 7)
 	results := make([]uint64, 0, len(tasks))
 
 	schedulerTime := scheduler0time.GetSchedulerTime()
@@ -56,7 +59,7 @@ func (repo *asyncTasksRepo) BatchInsert(tasks []models.AsyncTask, committed bool
 	}
 
 	for _, batch := range batches {
-		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?)",
+		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?, ?)",
 			table,
 			constants.AsyncTasksRequestIdColumn,
 			constants.AsyncTasksInputColumn,
@@ -64,6 +67,7 @@ func (repo *asyncTasksRepo) BatchInsert(tasks []models.AsyncTask, committed bool
 			constants.AsyncTasksStateColumn,
 			constants.AsyncTasksServiceColumn,
 			constants.AsyncTasksDateCreatedColumn,
+			constants.AsyncTasksProjectIdsColumn,
 		)
 		params := []interface{}{
 			batch[0].RequestId,
@@ -72,11 +76,12 @@ func (repo *asyncTasksRepo) BatchInsert(tasks []models.AsyncTask, committed bool
 			0,
 			batch[0].Service,
 			now,
+			projectIdsParam(batch[0].ProjectIDs),
 		}
 
 		for _, row := range batch[1:] {
-			query += ",(?, ?, ?, ?, ?, ?)"
-			params = append(params, row.RequestId, row.Input, row.Output, 0, row.Service, now)
+			query += ",(?, ?, ?, ?, ?, ?, ?)"
+			params = append(params, row.RequestId, row.Input, row.Output, 0, row.Service, now, projectIdsParam(row.ProjectIDs))
 		}
 
 		ids := make([]uint64, 0, len(batch))
@@ -106,8 +111,11 @@ func (repo *asyncTasksRepo) BatchInsert(tasks []models.AsyncTask, committed bool
 	return results, nil
 }
 
-func (repo *asyncTasksRepo) RaftBatchInsert(tasks []models.AsyncTask, fromNodeId uint64) ([]uint64, *utils.GenericError) {
-	batches := utils.Batch[models.AsyncTask](tasks, 6)
+func (repo *asyncTasksRepo) RaftBatchInsert(ctx context.Context, tasks []models.AsyncTask, fromNodeId uint64) ([]uint64, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "AsyncTasksRepo.RaftBatchInsert", attribute.Int("scheduler0.async_tasks", len(tasks)))
+	defer span.End()
+
+	batches := utils.Batch[models.AsyncTask](tasks, 7)
 	results := make([]uint64, 0, len(tasks))
 	schedulerTime := scheduler0time.GetSchedulerTime()
 	now := schedulerTime.GetTime(time.Now())
@@ -116,7 +124,7 @@ func (repo *asyncTasksRepo) RaftBatchInsert(tasks []models.AsyncTask, fromNodeId
 
 	for _, batch := range batches {
 
-		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?)",
+		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?, ?)",
 			table,
 			constants.AsyncTasksRequestIdColumn,
 			constants.AsyncTasksInputColumn,
@@ -124,6 +132,7 @@ func (repo *asyncTasksRepo) RaftBatchInsert(tasks []models.AsyncTask, fromNodeId
 			constants.AsyncTasksStateColumn,
 			constants.AsyncTasksServiceColumn,
 			constants.AsyncTasksDateCreatedColumn,
+			constants.AsyncTasksProjectIdsColumn,
 		)
 		params := []interface{}{
 			batch[0].RequestId,
@@ -132,10 +141,11 @@ func (repo *asyncTasksRepo) RaftBatchInsert(tasks []models.AsyncTask, fromNodeId
 			0,
 			batch[0].Service,
 			now,
+			projectIdsParam(batch[0].ProjectIDs),
 		}
 		for _, row := range batch[1:] {
-			query += ",(?, ?, ?, ?, ?, ?)"
-			params = append(params, row.RequestId, row.Input, row.Output, 0, row.Service, now)
+			query += ",(?, ?, ?, ?, ?, ?, ?)"
+			params = append(params, row.RequestId, row.Input, row.Output, 0, row.Service, now, projectIdsParam(row.ProjectIDs))
 		}
 
 		ids := make([]uint64, 0, len(batch))
@@ -143,6 +153,7 @@ func (repo *asyncTasksRepo) RaftBatchInsert(tasks []models.AsyncTask, fromNodeId
 		query += ";"
 
 		res, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(
+			ctx,
 			repo.fsmStore.GetRaft(),
 			constants.CommandTypeDbExecute,
 			query,
@@ -168,7 +179,10 @@ func (repo *asyncTasksRepo) RaftBatchInsert(tasks []models.AsyncTask, fromNodeId
 	return results, nil
 }
 
-func (repo *asyncTasksRepo) RaftUpdateTaskState(task models.AsyncTask, state models.AsyncTaskState, output string) *utils.GenericError {
+func (repo *asyncTasksRepo) RaftUpdateTaskState(ctx context.Context, task models.AsyncTask, state models.AsyncTaskState, output string) *utils.GenericError {
+	ctx, span := tracing.StartSpan(ctx, "AsyncTasksRepo.RaftUpdateTaskState", attribute.Int64("scheduler0.async_task_id", int64(task.Id)))
+	defer span.End()
+
 	updateQuery := sq.Update(constants.CommittedAsyncTableName).
 		Set(constants.AsyncTasksStateColumn, state).
 		Set(constants.AsyncTasksOutputColumn, output).
@@ -179,7 +193,7 @@ func (repo *asyncTasksRepo) RaftUpdateTaskState(task models.AsyncTask, state mod
 		return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
-	_, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(repo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	_, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(ctx, repo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
 	if err != nil {
 		return applyErr
 	}
@@ -214,7 +228,7 @@ func (repo *asyncTasksRepo) GetTask(taskId uint64) (*models.AsyncTask, *utils.Ge
 	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
 
 	query := fmt.Sprintf(
-		"select %s, %s, %s, %s, %s, %s, %s from %s where %s = ? union select %s, %s, %s, %s, %s, %s, %s from %s where %s = ?",
+		"select %s, %s, %s, %s, %s, %s, %s, %s from %s where %s = ? union select %s, %s, %s, %s, %s, %s, %s, %s from %s where %s = ?",
 		constants.AsyncTasksIdColumn,
 		constants.AsyncTasksRequestIdColumn,
 		constants.AsyncTasksInputColumn,
@@ -222,6 +236,7 @@ func (repo *asyncTasksRepo) GetTask(taskId uint64) (*models.AsyncTask, *utils.Ge
 		constants.AsyncTasksStateColumn,
 		constants.AsyncTasksServiceColumn,
 		constants.AsyncTasksDateCreatedColumn,
+		constants.AsyncTasksProjectIdsColumn,
 		constants.CommittedAsyncTableName,
 		constants.AsyncTasksIdColumn,
 		constants.AsyncTasksIdColumn,
@@ -231,6 +246,7 @@ func (repo *asyncTasksRepo) GetTask(taskId uint64) (*models.AsyncTask, *utils.Ge
 		constants.AsyncTasksStateColumn,
 		constants.AsyncTasksServiceColumn,
 		constants.AsyncTasksDateCreatedColumn,
+		constants.AsyncTasksProjectIdsColumn,
 		constants.UnCommittedAsyncTableName,
 		constants.AsyncTasksIdColumn,
 	)
@@ -250,6 +266,7 @@ func (repo *asyncTasksRepo) GetTask(taskId uint64) (*models.AsyncTask, *utils.Ge
 			&asyncTask.State,
 			&asyncTask.Service,
 			&asyncTask.DateCreated,
+			projectIdsColumn{&asyncTask.ProjectIDs},
 		)
 		if scanErr != nil {
 			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
@@ -350,7 +367,7 @@ func (repo *asyncTasksRepo) GetAllTasks(committed bool) ([]models.AsyncTask, *ut
 		}
 
 		query := fmt.Sprintf(
-			"select %s, %s, %s, %s, %s, %s, %s from %s where id in (%s)",
+			"select %s, %s, %s, %s, %s, %s, %s, %s from %s where id in (%s)",
 			constants.AsyncTasksIdColumn,
 			constants.AsyncTasksRequestIdColumn,
 			constants.AsyncTasksInputColumn,
@@ -358,6 +375,7 @@ func (repo *asyncTasksRepo) GetAllTasks(committed bool) ([]models.AsyncTask, *ut
 			constants.AsyncTasksStateColumn,
 			constants.AsyncTasksServiceColumn,
 			constants.AsyncTasksDateCreatedColumn,
+			constants.AsyncTasksProjectIdsColumn,
 			table,
 			paramPlaceholders,
 		)
@@ -375,6 +393,7 @@ func (repo *asyncTasksRepo) GetAllTasks(committed bool) ([]models.AsyncTask, *ut
 				&asyncTask.State,
 				&asyncTask.Service,
 				&asyncTask.DateCreated,
+				projectIdsColumn{&asyncTask.ProjectIDs},
 			)
 			if scanErr != nil {
 				return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
@@ -392,3 +411,30 @@ func (repo *asyncTasksRepo) GetAllTasks(committed bool) ([]models.AsyncTask, *ut
 
 	return results, nil
 }
+
+// projectIdsParam stores the project ids of a task as a json array
+func projectIdsParam(projectIds []uint64) string {
+	if projectIds == nil {
+		projectIds = []uint64{}
+	}
+	data, _ := json.Marshal(projectIds)
+	return string(data)
+}
+
+// projectIdsColumn scans the json array of the project ids of a task
+type projectIdsColumn struct {
+	dest *[]uint64
+}
+
+func (column projectIdsColumn) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), column.dest)
+	case []byte:
+		return json.Unmarshal(data, column.dest)
+	default:
+		return fmt.Errorf("cannot scan %T into project ids", value)
+	}
+}
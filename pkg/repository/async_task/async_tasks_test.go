@@ -92,6 +92,7 @@ func Test_AsyncTask_GetTask(t *testing.T) {
 			if err != nil {
 				t.Fatal("failed to create async task", err)
 			}
+			mockAsyncTask.ProjectIDs = []uint64{2, 5}
 			// Insert the mock task into the mockAsyncTask
 			ids, createErr := asyncTasksRepo.BatchInsert([]models.AsyncTask{mockAsyncTask}, testCase.committed)
 			if createErr != nil {
@@ -110,6 +111,7 @@ func Test_AsyncTask_GetTask(t *testing.T) {
 			assert.Equal(t, mockAsyncTask.Output, task.Output)
 			assert.Equal(t, models.AsyncTaskNotStated, task.State)
 			assert.Equal(t, mockAsyncTask.Service, task.Service)
+			assert.Equal(t, []uint64{2, 5}, task.ProjectIDs)
 		})
 	}
 }
@@ -162,7 +164,7 @@ func Test_AsyncTask_RaftBatchInsert(t *testing.T) {
 	}
 
 	// Execute RaftBatchInsert
-	_, rinsererr := asyncTasksRepo.RaftBatchInsert(mockAsyncTasks, 1)
+	_, rinsererr := asyncTasksRepo.RaftBatchInsert(context.Background(), mockAsyncTasks, 1)
 	if rinsererr != nil {
 		t.Fatal("failed to raft insert async task", rinsererr)
 	}
@@ -223,7 +225,7 @@ func Test_AsyncTask_RaftUpdateTaskState(t *testing.T) {
 	id := ids[0]
 	mockAsyncTask.Id = id
 	// Update the task state using RaftUpdateTaskState
-	ruerr := asyncTasksRepo.RaftUpdateTaskState(mockAsyncTask, models.AsyncTaskInProgress, "")
+	ruerr := asyncTasksRepo.RaftUpdateTaskState(context.Background(), mockAsyncTask, models.AsyncTaskInProgress, "")
 	if ruerr != nil {
 		t.Fatal("failed to update task state", ruerr)
 	}
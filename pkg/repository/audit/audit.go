@@ -0,0 +1,268 @@
+// Package audit records who changed credentials, projects and jobs. A raft command making changes for a request
+// starts with ContextSQL, and the audit triggers of the tables record every change the command makes with the actor
+// of the request, in the same transaction as the changes.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/pagination"
+	"scheduler0/pkg/scheduler0time"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+//go:generate mockery --name AuditRepo --output ../mocks
+type AuditRepo interface {
+	Record(event models.AuditEvent) *utils.GenericError
+	List(filter models.AuditFilter) ([]models.AuditEvent, models.PageCursors, *utils.GenericError)
+	Count(filter models.AuditFilter) (uint64, *utils.GenericError)
+	Compact(before time.Time) (uint64, *utils.GenericError)
+}
+
+type auditRepo struct {
+	fsmStore              fsm.Scheduler0RaftStore
+	logger                hclog.Logger
+	scheduler0RaftActions fsm.Scheduler0RaftActions
+}
+
+func NewAuditRepo(logger hclog.Logger, scheduler0RaftActions fsm.Scheduler0RaftActions, store fsm.Scheduler0RaftStore) AuditRepo {
+	return &auditRepo{
+		fsmStore:              store,
+		logger:                logger.Named("audit-repo"),
+		scheduler0RaftActions: scheduler0RaftActions,
+	}
+}
+
+// ContextSQL returns the statement that makes the actor the author of the changes of the statements after it in a raft command.
+// The action names the changes, they are named after the statement that made them when it is empty.
+// It must follow the version checks of the command, these update the rows they check.
+// The fsm clears the actor at the end of every command, in the transaction of the command.
+func ContextSQL(actor models.Actor, action models.AuditAction) (string, []interface{}) {
+	var actionParam interface{}
+	if action != "" {
+		actionParam = action
+	}
+	var credentialIdParam interface{}
+	if actor.CredentialID != 0 {
+		credentialIdParam = actor.CredentialID
+	}
+
+	schedulerTime := scheduler0time.GetSchedulerTime()
+	now := schedulerTime.GetTime(time.Now())
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES (1, ?, ?, ?, ?, ?, ?);",
+		constants.AuditContextTableName,
+		constants.AuditContextIdColumn,
+		constants.AuditContextActionColumn,
+		constants.AuditContextCredentialIdColumn,
+		constants.AuditContextPeerColumn,
+		constants.AuditContextRequestIdColumn,
+		constants.AuditContextSourceIpColumn,
+		constants.AuditContextDateCreatedColumn,
+	)
+	return query, []interface{}{actionParam, credentialIdParam, actor.Peer, actor.RequestID, actor.SourceIP, now}
+}
+
+// Record writes an audit event that is not the change of a row, such as an admin action taken on a node
+func (repo *auditRepo) Record(event models.AuditEvent) *utils.GenericError {
+	schedulerTime := scheduler0time.GetSchedulerTime()
+	now := schedulerTime.GetTime(time.Now())
+
+	var credentialIdParam interface{}
+	if event.Actor.CredentialID != 0 {
+		credentialIdParam = event.Actor.CredentialID
+	}
+
+	query, params, err := sq.Insert(constants.AuditEventsTableName).
+		Columns(
+			constants.AuditEventsActionColumn,
+			constants.AuditEventsResourceColumn,
+			constants.AuditEventsResourceIdColumn,
+			constants.AuditEventsCredentialIdColumn,
+			constants.AuditEventsPeerColumn,
+			constants.AuditEventsRequestIdColumn,
+			constants.AuditEventsSourceIpColumn,
+			constants.AuditEventsDateCreatedColumn,
+		).
+		Values(
+			event.Action,
+			event.Resource,
+			event.ResourceID,
+			credentialIdParam,
+			event.Actor.Peer,
+			event.Actor.RequestID,
+			event.Actor.SourceIP,
+			now,
+		).ToSql()
+	if err != nil {
+		return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	res, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), repo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+	if res == nil {
+		return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return nil
+}
+
+// List returns a page of the audit events matching the filter, latest first unless the order is asc
+func (repo *auditRepo) List(filter models.AuditFilter) ([]models.AuditEvent, models.PageCursors, *utils.GenericError) {
+	plan, planErr := pagination.NewPlan(constants.AuditEventsTableName, constants.AuditEventsIdColumn, auditSortColumns, "id", "DESC", filter.PageRequest())
+	if planErr != nil {
+		return nil, models.PageCursors{}, planErr
+	}
+
+	repo.fsmStore.GetDataStore().ConnectionLock()
+	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	selectBuilder := sq.Select(
+		constants.AuditEventsIdColumn,
+		constants.AuditEventsActionColumn,
+		constants.AuditEventsResourceColumn,
+		constants.AuditEventsResourceIdColumn,
+		fmt.Sprintf("IFNULL(%s, 0)", constants.AuditEventsCredentialIdColumn),
+		constants.AuditEventsPeerColumn,
+		constants.AuditEventsRequestIdColumn,
+		constants.AuditEventsSourceIpColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.AuditEventsBeforeColumn),
+		fmt.Sprintf("IFNULL(%s, '')", constants.AuditEventsAfterColumn),
+		constants.AuditEventsDateCreatedColumn,
+	).
+		From(constants.AuditEventsTableName).
+		Where(auditFilterConditions(filter))
+
+	rows, err := plan.Apply(selectBuilder).
+		RunWith(repo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
+	if err != nil {
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		event, scanErr := scanAuditEvent(rows)
+		if scanErr != nil {
+			return nil, models.PageCursors{}, scanErr
+		}
+		events = append(events, event)
+	}
+	if rows.Err() != nil {
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+	rows.Close()
+
+	return pagination.Page(plan, repo.fsmStore.GetDataStore().GetOpenConnection(), events, func(event models.AuditEvent) uint64 { return event.ID })
+}
+
+// Count returns the number of audit events matching the filter
+func (repo *auditRepo) Count(filter models.AuditFilter) (uint64, *utils.GenericError) {
+	repo.fsmStore.GetDataStore().ConnectionLock()
+	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	var count uint64
+	scanErr := sq.Select("count(*)").
+		From(constants.AuditEventsTableName).
+		Where(auditFilterConditions(filter)).
+		RunWith(repo.fsmStore.GetDataStore().GetOpenConnection()).
+		QueryRow().
+		Scan(&count)
+	if scanErr != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+	}
+
+	return count, nil
+}
+
+// Compact deletes the audit events recorded before the time and returns the number of deleted events
+func (repo *auditRepo) Compact(before time.Time) (uint64, *utils.GenericError) {
+	query, params, err := sq.Delete(constants.AuditEventsTableName).
+		Where(sq.Lt{constants.AuditEventsDateCreatedColumn: before.UTC()}).
+		ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	res, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), repo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// auditSortColumns the fields audit events can be ordered by, ids follow the order the events were recorded in
+var auditSortColumns = map[string]string{
+	"id": constants.AuditEventsIdColumn,
+}
+
+// auditFilterConditions returns the where clause of the audit events matching the filter
+func auditFilterConditions(filter models.AuditFilter) sq.And {
+	conditions := sq.And{}
+	if filter.Resource != "" {
+		conditions = append(conditions, sq.Eq{constants.AuditEventsResourceColumn: filter.Resource})
+	}
+	if filter.ResourceID != 0 {
+		conditions = append(conditions, sq.Eq{constants.AuditEventsResourceIdColumn: filter.ResourceID})
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, sq.Eq{constants.AuditEventsActionColumn: filter.Action})
+	}
+	if filter.CredentialID != 0 {
+		conditions = append(conditions, sq.Eq{constants.AuditEventsCredentialIdColumn: filter.CredentialID})
+	}
+	if filter.RequestID != "" {
+		conditions = append(conditions, sq.Eq{constants.AuditEventsRequestIdColumn: filter.RequestID})
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, sq.GtOrEq{constants.AuditEventsDateCreatedColumn: filter.Since.UTC()})
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, sq.Lt{constants.AuditEventsDateCreatedColumn: filter.Until.UTC()})
+	}
+	return conditions
+}
+
+func scanAuditEvent(rows *sql.Rows) (models.AuditEvent, *utils.GenericError) {
+	event := models.AuditEvent{}
+	var before, after string
+	scanErr := rows.Scan(
+		&event.ID,
+		&event.Action,
+		&event.Resource,
+		&event.ResourceID,
+		&event.Actor.CredentialID,
+		&event.Actor.Peer,
+		&event.Actor.RequestID,
+		&event.Actor.SourceIP,
+		&before,
+		&after,
+		&event.DateCreated,
+	)
+	if scanErr != nil {
+		return event, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+	}
+	if before != "" {
+		event.Before = json.RawMessage(before)
+	}
+	if after != "" {
+		event.After = json.RawMessage(after)
+	}
+	return event, nil
+}
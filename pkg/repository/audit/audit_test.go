@@ -0,0 +1,170 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/db"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/audit"
+	credential_repo "scheduler0/pkg/repository/credential"
+	job_repo "scheduler0/pkg/repository/job"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/shared_repo"
+	"testing"
+	"time"
+)
+
+func Test_AuditRepo_RecordsChanges(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "audit-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	auditRepo := audit.NewAuditRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	credentialRepo := credential_repo.NewCredentialRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	actor := models.Actor{CredentialID: 3, RequestID: "request-1", SourceIP: "10.0.0.1"}
+
+	project := models.Project{Name: "audited", Description: "first", Actor: actor}
+	projectId, createErr := projectRepo.CreateOne(&project)
+	if createErr != nil {
+		t.Fatal("failed to create project:", createErr)
+	}
+	project.Description = "second"
+	project.Actor = models.Actor{CredentialID: 4, RequestID: "request-2", SourceIP: "10.0.0.2"}
+	if _, updateErr := projectRepo.UpdateOneByID(project); updateErr != nil {
+		t.Fatal("failed to update project:", updateErr)
+	}
+
+	jobIds, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{{
+		ProjectID:     projectId,
+		Spec:          "0 * * * *",
+		CallbackUrl:   "https://example.com/callback",
+		ExecutionType: "http",
+		Timezone:      "UTC",
+		Actor:         actor,
+	}})
+	if insertErr != nil {
+		t.Fatal("failed to insert jobs:", insertErr)
+	}
+	if _, pauseErr := jobRepo.UpdateStatusByFilter(models.JobFilter{ProjectID: projectId, Actor: actor}, models.JobStatusPaused); pauseErr != nil {
+		t.Fatal("failed to pause jobs:", pauseErr)
+	}
+
+	credentialId, credentialErr := credentialRepo.CreateOne(models.Credential{ApiKey: "api-key", ApiSecretHash: "sha256$salt$hash", Actor: models.Actor{Peer: true}})
+	if credentialErr != nil {
+		t.Fatal("failed to create credential:", credentialErr)
+	}
+	// Changes made without an actor, such as the last use of credentials, are not audited
+	if usageErr := credentialRepo.UpdateLastUsed([]models.CredentialUsage{{CredentialID: credentialId, UsedAt: time.Now(), UsedFrom: "10.0.0.3"}}); usageErr != nil {
+		t.Fatal("failed to update the last use of the credential:", usageErr)
+	}
+
+	count, countErr := auditRepo.Count(models.AuditFilter{})
+	if countErr != nil {
+		t.Fatal("failed to count audit events:", countErr)
+	}
+	assert.Equal(t, uint64(5), count)
+
+	projectEvents, _, listErr := auditRepo.List(models.AuditFilter{Resource: models.AuditResourceProject, ResourceID: projectId, Order: "asc", Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list audit events:", listErr)
+	}
+	assert.Equal(t, 2, len(projectEvents))
+	assert.Equal(t, models.AuditActionCreate, projectEvents[0].Action)
+	assert.Equal(t, actor, projectEvents[0].Actor)
+	assert.Nil(t, projectEvents[0].Before)
+	assert.Equal(t, models.AuditActionUpdate, projectEvents[1].Action)
+	assert.Equal(t, "request-2", projectEvents[1].Actor.RequestID)
+	before := map[string]interface{}{}
+	after := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(projectEvents[1].Before, &before))
+	assert.Nil(t, json.Unmarshal(projectEvents[1].After, &after))
+	assert.Equal(t, "first", before["description"])
+	assert.Equal(t, "second", after["description"])
+
+	pauseEvents, _, listErr := auditRepo.List(models.AuditFilter{Action: models.AuditActionPause, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list audit events:", listErr)
+	}
+	assert.Equal(t, 1, len(pauseEvents))
+	assert.Equal(t, models.AuditResourceJob, pauseEvents[0].Resource)
+	assert.Equal(t, jobIds[0], pauseEvents[0].ResourceID)
+
+	credentialEvents, _, listErr := auditRepo.List(models.AuditFilter{Resource: models.AuditResourceCredential, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list audit events:", listErr)
+	}
+	assert.Equal(t, 1, len(credentialEvents))
+	assert.True(t, credentialEvents[0].Actor.Peer)
+	assert.NotContains(t, string(credentialEvents[0].After), "sha256$")
+
+	requestEvents, _, listErr := auditRepo.List(models.AuditFilter{CredentialID: 3, RequestID: "request-1", Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list audit events:", listErr)
+	}
+	assert.Equal(t, 3, len(requestEvents))
+
+	if recordErr := auditRepo.Record(models.AuditEvent{Action: models.AuditActionStopJobs, Resource: models.AuditResourceNode, ResourceID: 2, Actor: actor}); recordErr != nil {
+		t.Fatal("failed to record audit event:", recordErr)
+	}
+	nodeEvents, _, listErr := auditRepo.List(models.AuditFilter{Resource: models.AuditResourceNode, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list audit events:", listErr)
+	}
+	assert.Equal(t, 1, len(nodeEvents))
+	assert.Equal(t, uint64(2), nodeEvents[0].ResourceID)
+
+	until := time.Now().Add(-time.Hour)
+	olderEvents, _, listErr := auditRepo.List(models.AuditFilter{Until: &until, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list audit events:", listErr)
+	}
+	assert.Equal(t, 0, len(olderEvents))
+
+	compacted, compactErr := auditRepo.Compact(time.Now().Add(-time.Hour))
+	if compactErr != nil {
+		t.Fatal("failed to compact audit events:", compactErr)
+	}
+	assert.Equal(t, uint64(0), compacted)
+	compacted, compactErr = auditRepo.Compact(time.Now().Add(time.Hour))
+	if compactErr != nil {
+		t.Fatal("failed to compact audit events:", compactErr)
+	}
+	assert.Equal(t, uint64(6), compacted)
+}
@@ -1,6 +1,8 @@
 package credential
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
@@ -8,6 +10,9 @@ import (
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/audit"
+	"scheduler0/pkg/repository/pagination"
+	"scheduler0/pkg/repository/version"
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/utils"
 	"time"
@@ -19,9 +24,13 @@ type CredentialRepo interface {
 	GetOneID(credential *models.Credential) error
 	GetByAPIKey(credential *models.Credential) *utils.GenericError
 	Count() (uint64, *utils.GenericError)
-	List(offset uint64, limit uint64, orderBy string) ([]models.Credential, *utils.GenericError)
+	List(page models.PageRequest) ([]models.Credential, models.PageCursors, *utils.GenericError)
 	UpdateOneByID(credential models.Credential) (uint64, *utils.GenericError)
+	RotateOneByID(credential models.Credential) (uint64, *utils.GenericError)
 	DeleteOneByID(credential models.Credential) (uint64, *utils.GenericError)
+	UpdateLastUsed(usages []models.CredentialUsage) *utils.GenericError
+	ListPlainTextSecrets() ([]models.Credential, *utils.GenericError)
+	UpdateSecretHashes(credentials []models.Credential) *utils.GenericError
 }
 
 // CredentialRepo CredentialRepo
@@ -45,27 +54,40 @@ func (credentialRepo *credentialRepo) CreateOne(credential models.Credential) (u
 	now := schedulerTime.GetTime(time.Now())
 
 	credential.DateCreated = now
+	projectIds, permissions, scopeErr := scopeParams(credential)
+	if scopeErr != nil {
+		return 0, scopeErr
+	}
 	insertBuilder := sq.Insert(constants.CredentialTableName).
 		Columns(
 			constants.CredentialsArchivedColumn,
 			constants.CredentialsApiKeyColumn,
 			constants.CredentialsApiSecretColumn,
+			constants.CredentialsProjectIdsColumn,
+			constants.CredentialsPermissionsColumn,
+			constants.CredentialsExpiresAtColumn,
 			constants.CredentialsDateCreatedColumn,
 		).
 		Values(
 			credential.Archived,
 			credential.ApiKey,
-			credential.ApiSecret,
+			credential.ApiSecretHash,
+			projectIds,
+			permissions,
+			credential.ExpiresAt,
 			credential.DateCreated,
 		)
 
-	query, params, err := insertBuilder.ToSql()
+	insertSql, insertParams, err := insertBuilder.ToSql()
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
+	query, params := audit.ContextSQL(credential.Actor, "")
+	query += insertSql
+	params = append(params, insertParams...)
 
-	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
-	if err != nil {
+	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
 		return 0, applyErr
 	}
 
@@ -83,36 +105,18 @@ func (credentialRepo *credentialRepo) GetOneID(credential *models.Credential) er
 	credentialRepo.fsmStore.GetDataStore().ConnectionLock()
 	defer credentialRepo.fsmStore.GetDataStore().ConnectionUnlock()
 
-	sqlr := sq.Expr(fmt.Sprintf(
-		"select %s, %s, %s, %s, %s from %s where %s = ?",
-		constants.CredentialsIdColumn,
-		constants.CredentialsArchivedColumn,
-		constants.CredentialsApiKeyColumn,
-		constants.CredentialsApiSecretColumn,
-		constants.CredentialsDateCreatedColumn,
-		constants.CredentialTableName,
-		constants.CredentialsIdColumn,
-	), credential.ID)
-
-	sqlString, args, err := sqlr.ToSql()
-	if err != nil {
-		return err
-	}
-
-	rows, err := credentialRepo.fsmStore.GetDataStore().GetOpenConnection().Query(sqlString, args...)
+	rows, err := sq.Select(credentialColumns...).
+		From(constants.CredentialTableName).
+		Where(fmt.Sprintf("%s = ?", constants.CredentialsIdColumn), credential.ID).
+		RunWith(credentialRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 	var count = 0
 	for rows.Next() {
-		scanErr := rows.Scan(
-			&credential.ID,
-			&credential.Archived,
-			&credential.ApiKey,
-			&credential.ApiSecret,
-			&credential.DateCreated,
-		)
+		scanErr := rows.Scan(credentialFields(credential)...)
 		if scanErr != nil {
 			return scanErr
 		}
@@ -132,13 +136,7 @@ func (credentialRepo *credentialRepo) GetByAPIKey(credential *models.Credential)
 	credentialRepo.fsmStore.GetDataStore().ConnectionLock()
 	defer credentialRepo.fsmStore.GetDataStore().ConnectionUnlock()
 
-	selectBuilder := sq.Select(
-		constants.CredentialsIdColumn,
-		constants.CredentialsArchivedColumn,
-		constants.CredentialsApiKeyColumn,
-		constants.CredentialsApiSecretColumn,
-		constants.CredentialsDateCreatedColumn,
-	).
+	selectBuilder := sq.Select(credentialColumns...).
 		From(constants.CredentialTableName).
 		Where(fmt.Sprintf("%s = ?", constants.CredentialsApiKeyColumn), credential.ApiKey).
 		RunWith(credentialRepo.fsmStore.GetDataStore().GetOpenConnection())
@@ -150,13 +148,7 @@ func (credentialRepo *credentialRepo) GetByAPIKey(credential *models.Credential)
 	defer rows.Close()
 	var count = 0
 	for rows.Next() {
-		scanErr := rows.Scan(
-			&credential.ID,
-			&credential.Archived,
-			&credential.ApiKey,
-			&credential.ApiSecret,
-			&credential.DateCreated,
-		)
+		scanErr := rows.Scan(credentialFields(credential)...)
 		if scanErr != nil {
 			return utils.HTTPGenericError(500, err.Error())
 		}
@@ -199,65 +191,189 @@ func (credentialRepo *credentialRepo) Count() (uint64, *utils.GenericError) {
 	return uint64(count), nil
 }
 
-// List returns a paginated set of credentials
-func (credentialRepo *credentialRepo) List(offset uint64, limit uint64, orderBy string) ([]models.Credential, *utils.GenericError) {
+// List returns a page of credentials, and the cursors of the pages around it
+func (credentialRepo *credentialRepo) List(page models.PageRequest) ([]models.Credential, models.PageCursors, *utils.GenericError) {
+	plan, planErr := pagination.NewPlan(constants.CredentialTableName, constants.CredentialsIdColumn, credentialSortColumns, pagination.DefaultOrderBy, pagination.DefaultOrder, page)
+	if planErr != nil {
+		return nil, models.PageCursors{}, planErr
+	}
+
 	credentialRepo.fsmStore.GetDataStore().ConnectionLock()
 	defer credentialRepo.fsmStore.GetDataStore().ConnectionUnlock()
 
-	selectBuilder := sq.Select(
-		constants.CredentialsIdColumn,
-		constants.CredentialsArchivedColumn,
-		constants.CredentialsApiKeyColumn,
-		constants.CredentialsApiSecretColumn,
-		constants.CredentialsDateCreatedColumn,
-	).
-		From(constants.CredentialTableName).
-		Offset(offset).
-		Limit(limit).
-		OrderBy(orderBy).
-		RunWith(credentialRepo.fsmStore.GetDataStore().GetOpenConnection())
+	selectBuilder := sq.Select(credentialColumns...).
+		From(constants.CredentialTableName)
 
-	rows, err := selectBuilder.Query()
+	rows, err := plan.Apply(selectBuilder).
+		RunWith(credentialRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
 	if err != nil {
-		return nil, utils.HTTPGenericError(404, err.Error())
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 	credentials := []models.Credential{}
 	defer rows.Close()
 	for rows.Next() {
 		credential := models.Credential{}
-		err = rows.Scan(
-			&credential.ID,
-			&credential.Archived,
-			&credential.ApiKey,
-			&credential.ApiSecret,
-			&credential.DateCreated,
-		)
+		err = rows.Scan(credentialFields(&credential)...)
+		if err != nil {
+			return nil, models.PageCursors{}, utils.HTTPGenericError(500, err.Error())
+		}
+		credentials = append(credentials, credential)
+	}
+	if rows.Err() != nil {
+		return nil, models.PageCursors{}, utils.HTTPGenericError(500, rows.Err().Error())
+	}
+	rows.Close()
+
+	return pagination.Page(plan, credentialRepo.fsmStore.GetDataStore().GetOpenConnection(), credentials, func(credential models.Credential) uint64 { return credential.ID })
+}
+
+// UpdateLastUsed writes the last use of the credentials in a single raft command.
+// The version of the credentials is left as it is, tracking their use is not a change clients make.
+func (credentialRepo *credentialRepo) UpdateLastUsed(usages []models.CredentialUsage) *utils.GenericError {
+	if len(usages) < 1 {
+		return nil
+	}
+
+	query := ""
+	params := []interface{}{}
+	for _, usage := range usages {
+		updateSql, updateParams, err := sq.Update(constants.CredentialTableName).
+			Set(constants.CredentialsLastUsedAtColumn, usage.UsedAt).
+			Set(constants.CredentialsLastUsedFromColumn, usage.UsedFrom).
+			Where(fmt.Sprintf("%s = ?", constants.CredentialsIdColumn), usage.CredentialID).
+			ToSql()
 		if err != nil {
-			return nil, utils.HTTPGenericError(500, err.Error())
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		query += updateSql + ";"
+		params = append(params, updateParams...)
+	}
+
+	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+	if res == nil {
+		return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return nil
+}
+
+// ListPlainTextSecrets returns the credentials whose api secret, or secret replaced by the last rotation, is stored in plain text.
+// Their ApiSecretHash and PreviousApiSecretHash hold the secrets as stored.
+func (credentialRepo *credentialRepo) ListPlainTextSecrets() ([]models.Credential, *utils.GenericError) {
+	credentialRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer credentialRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	rows, err := sq.Select(credentialColumns...).
+		From(constants.CredentialTableName).
+		Where(sq.Or{
+			sq.Expr(fmt.Sprintf("%s NOT LIKE ?", constants.CredentialsApiSecretColumn), utils.SecretHashPattern()),
+			sq.And{
+				sq.NotEq{constants.CredentialsPreviousApiSecretColumn: ""},
+				sq.Expr(fmt.Sprintf("%s NOT LIKE ?", constants.CredentialsPreviousApiSecretColumn), utils.SecretHashPattern()),
+			},
+		}).
+		RunWith(credentialRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+	credentials := []models.Credential{}
+	for rows.Next() {
+		credential := models.Credential{}
+		if scanErr := rows.Scan(credentialFields(&credential)...); scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
 		}
 		credentials = append(credentials, credential)
 	}
 	if rows.Err() != nil {
-		return nil, utils.HTTPGenericError(500, err.Error())
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
 	}
+
 	return credentials, nil
 }
 
+// UpdateSecretHashes writes the secret hashes of the credentials in a single raft command, without changing their version.
+// Credentials changed since they were read are left as they are.
+func (credentialRepo *credentialRepo) UpdateSecretHashes(credentials []models.Credential) *utils.GenericError {
+	if len(credentials) < 1 {
+		return nil
+	}
+
+	query := ""
+	params := []interface{}{}
+	for _, credential := range credentials {
+		updateSql, updateParams, err := sq.Update(constants.CredentialTableName).
+			Set(constants.CredentialsApiSecretColumn, credential.ApiSecretHash).
+			Set(constants.CredentialsPreviousApiSecretColumn, credential.PreviousApiSecretHash).
+			Where(sq.Eq{
+				constants.CredentialsIdColumn:      credential.ID,
+				constants.CredentialsVersionColumn: credential.Version,
+			}).
+			ToSql()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		query += updateSql + ";"
+		params = append(params, updateParams...)
+	}
+
+	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+	if res == nil {
+		return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return nil
+}
+
+// credentialSortColumns the fields credentials can be ordered by
+var credentialSortColumns = pagination.SortColumns(constants.CredentialsIdColumn, constants.CredentialsDateCreatedColumn, nil)
+
 // UpdateOneByID updates a single credential
 func (credentialRepo *credentialRepo) UpdateOneByID(credential models.Credential) (uint64, *utils.GenericError) {
+	return credentialRepo.updateOneByID(credential, "")
+}
+
+// RotateOneByID updates a single credential given a new api secret, the update is audited as a rotation
+func (credentialRepo *credentialRepo) RotateOneByID(credential models.Credential) (uint64, *utils.GenericError) {
+	return credentialRepo.updateOneByID(credential, models.AuditActionRotate)
+}
+
+func (credentialRepo *credentialRepo) updateOneByID(credential models.Credential, action models.AuditAction) (uint64, *utils.GenericError) {
+	projectIds, permissions, scopeErr := scopeParams(credential)
+	if scopeErr != nil {
+		return 0, scopeErr
+	}
 	updateQuery := sq.Update(constants.CredentialTableName).
 		Set(constants.CredentialsArchivedColumn, credential.Archived).
 		Set(constants.CredentialsApiKeyColumn, credential.ApiKey).
-		Set(constants.CredentialsApiSecretColumn, credential.ApiSecret).
-		Where(fmt.Sprintf("%s = ?", constants.CredentialsIdColumn), credential.ID)
-
-	query, params, err := updateQuery.ToSql()
+		Set(constants.CredentialsApiSecretColumn, credential.ApiSecretHash).
+		Set(constants.CredentialsProjectIdsColumn, projectIds).
+		Set(constants.CredentialsPermissionsColumn, permissions).
+		Set(constants.CredentialsExpiresAtColumn, credential.ExpiresAt).
+		Set(constants.CredentialsPreviousApiSecretColumn, credential.PreviousApiSecretHash).
+		Set(constants.CredentialsPreviousApiSecretExpiresAtColumn, credential.PreviousApiSecretExpiresAt).
+		Set(constants.CredentialsVersionColumn, version.Next(constants.CredentialsVersionColumn)).
+		Where(version.Condition(constants.CredentialsIdColumn, constants.CredentialsVersionColumn, credential.ID, credential.Version))
+
+	updateSql, updateParams, err := updateQuery.ToSql()
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
-	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
-	if err != nil {
+	query, params := audit.ContextSQL(credential.Actor, action)
+	query += updateSql
+	params = append(params, updateParams...)
+
+	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
 		return 0, applyErr
 	}
 
@@ -266,20 +382,29 @@ func (credentialRepo *credentialRepo) UpdateOneByID(credential models.Credential
 	}
 
 	count := res.Data.RowsAffected
+	if count == 0 && credential.Version != 0 {
+		if getErr := credentialRepo.GetOneID(&models.Credential{ID: credential.ID}); getErr == nil {
+			return 0, version.PreconditionFailed(credential.Version)
+		}
+	}
 	return uint64(count), nil
 }
 
 // DeleteOneByID deletes a single credential
 func (credentialRepo *credentialRepo) DeleteOneByID(credential models.Credential) (uint64, *utils.GenericError) {
-	deleteQuery := sq.Delete(constants.CredentialTableName).Where(fmt.Sprintf("%s = ?", constants.CredentialsIdColumn), credential.ID)
+	deleteQuery := sq.Delete(constants.CredentialTableName).Where(version.Condition(constants.CredentialsIdColumn, constants.CredentialsVersionColumn, credential.ID, credential.Version))
 
-	query, params, err := deleteQuery.ToSql()
+	deleteSql, deleteParams, err := deleteQuery.ToSql()
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
-	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
-	if err != nil {
+	query, params := audit.ContextSQL(credential.Actor, "")
+	query += deleteSql
+	params = append(params, deleteParams...)
+
+	res, applyErr := credentialRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), credentialRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
 		return 0, applyErr
 	}
 
@@ -288,6 +413,88 @@ func (credentialRepo *credentialRepo) DeleteOneByID(credential models.Credential
 	}
 
 	count := res.Data.RowsAffected
+	if count == 0 && credential.Version != 0 {
+		if getErr := credentialRepo.GetOneID(&models.Credential{ID: credential.ID}); getErr == nil {
+			return 0, version.PreconditionFailed(credential.Version)
+		}
+	}
 
 	return uint64(count), nil
 }
+
+// credentialColumns the columns of the credentials read by the repository, in the order of credentialFields
+var credentialColumns = []string{
+	constants.CredentialsIdColumn,
+	constants.CredentialsArchivedColumn,
+	constants.CredentialsApiKeyColumn,
+	constants.CredentialsApiSecretColumn,
+	constants.CredentialsProjectIdsColumn,
+	constants.CredentialsPermissionsColumn,
+	constants.CredentialsDateCreatedColumn,
+	constants.CredentialsVersionColumn,
+	constants.CredentialsExpiresAtColumn,
+	constants.CredentialsPreviousApiSecretColumn,
+	constants.CredentialsPreviousApiSecretExpiresAtColumn,
+	constants.CredentialsLastUsedAtColumn,
+	constants.CredentialsLastUsedFromColumn,
+}
+
+// credentialFields returns the scan destinations of the credentialColumns of the credential
+func credentialFields(credential *models.Credential) []interface{} {
+	return []interface{}{
+		&credential.ID,
+		&credential.Archived,
+		&credential.ApiKey,
+		&credential.ApiSecretHash,
+		jsonColumn{&credential.ProjectIDs},
+		jsonColumn{&credential.Permissions},
+		&credential.DateCreated,
+		&credential.Version,
+		&credential.ExpiresAt,
+		&credential.PreviousApiSecretHash,
+		&credential.PreviousApiSecretExpiresAt,
+		&credential.LastUsedAt,
+		&credential.LastUsedFrom,
+	}
+}
+
+// scopeParams returns the project ids and permissions of the credential as stored, json arrays that are empty for unrestricted credentials
+func scopeParams(credential models.Credential) (string, string, *utils.GenericError) {
+	projectIds := credential.ProjectIDs
+	if projectIds == nil {
+		projectIds = []uint64{}
+	}
+	permissions := credential.Permissions
+	if permissions == nil {
+		permissions = []models.Permission{}
+	}
+
+	projectIdsJson, err := json.Marshal(projectIds)
+	if err != nil {
+		return "", "", utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	permissionsJson, err := json.Marshal(permissions)
+	if err != nil {
+		return "", "", utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	return string(projectIdsJson), string(permissionsJson), nil
+}
+
+// jsonColumn scans a json column into dest
+type jsonColumn struct {
+	dest interface{}
+}
+
+func (column jsonColumn) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), column.dest)
+	case []byte:
+		return json.Unmarshal(data, column.dest)
+	default:
+		return fmt.Errorf("cannot scan %T into a json column", value)
+	}
+}
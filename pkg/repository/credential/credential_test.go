@@ -1,10 +1,13 @@
 package credential
 
 import (
+	"context"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/db"
@@ -54,10 +57,10 @@ func Test_CredentialRepo_CreateOne(t *testing.T) {
 
 	// Create a mock credential
 	mockCredential := models.Credential{
-		Archived:    false,
-		ApiKey:      "mock-api-key",
-		ApiSecret:   "mock-api-secret",
-		DateCreated: time.Now(),
+		Archived:      false,
+		ApiKey:        "mock-api-key",
+		ApiSecretHash: "mock-api-secret",
+		DateCreated:   time.Now(),
 	}
 
 	// Call the CreateOne function
@@ -106,11 +109,11 @@ func Test_CredentialRepo_UpdateOneByID(t *testing.T) {
 
 	// Create a mock credential
 	mockCredential := models.Credential{
-		ID:          1,
-		Archived:    false,
-		ApiKey:      "mock-api-key",
-		ApiSecret:   "mock-api-secret",
-		DateCreated: time.Now(),
+		ID:            1,
+		Archived:      false,
+		ApiKey:        "mock-api-key",
+		ApiSecretHash: "mock-api-secret",
+		DateCreated:   time.Now(),
 	}
 
 	// Insert the initial credential using CreateOne
@@ -122,7 +125,7 @@ func Test_CredentialRepo_UpdateOneByID(t *testing.T) {
 	// Update the credential
 	mockCredential.Archived = true
 	mockCredential.ApiKey = "updated-api-key"
-	mockCredential.ApiSecret = "updated-api-secret"
+	mockCredential.ApiSecretHash = "updated-api-secret"
 	count, updateErr := credentialRepo.UpdateOneByID(mockCredential)
 	if updateErr != nil {
 		t.Fatal("failed to update the credential", updateErr)
@@ -143,7 +146,7 @@ func Test_CredentialRepo_UpdateOneByID(t *testing.T) {
 	// Assert the updated fields
 	assert.Equal(t, true, updatedCredential.Archived)
 	assert.Equal(t, "updated-api-key", updatedCredential.ApiKey)
-	assert.Equal(t, "updated-api-secret", updatedCredential.ApiSecret)
+	assert.Equal(t, "updated-api-secret", updatedCredential.ApiSecretHash)
 }
 
 func Test_CredentialRepo_DeleteOneByID(t *testing.T) {
@@ -183,11 +186,11 @@ func Test_CredentialRepo_DeleteOneByID(t *testing.T) {
 
 	// Create a mock credential
 	mockCredential := models.Credential{
-		ID:          1,
-		Archived:    false,
-		ApiKey:      "mock-api-key",
-		ApiSecret:   "mock-api-secret",
-		DateCreated: time.Now(),
+		ID:            1,
+		Archived:      false,
+		ApiKey:        "mock-api-key",
+		ApiSecretHash: "mock-api-secret",
+		DateCreated:   time.Now(),
 	}
 
 	// Insert the initial credential using CreateOne
@@ -214,7 +217,7 @@ func Test_CredentialRepo_DeleteOneByID(t *testing.T) {
 		t.Fatal("failed to get the credential", gerErr)
 	}
 	assert.Equal(t, deletedCredential.ApiKey, "")
-	assert.Equal(t, deletedCredential.ApiSecret, "")
+	assert.Equal(t, deletedCredential.ApiSecretHash, "")
 }
 
 func Test_CredentialRepo_List(t *testing.T) {
@@ -255,25 +258,25 @@ func Test_CredentialRepo_List(t *testing.T) {
 	// Create mock credentials
 	mockCredentials := []models.Credential{
 		{
-			ID:          1,
-			Archived:    false,
-			ApiKey:      "mock-api-key1",
-			ApiSecret:   "mock-api-secret1",
-			DateCreated: time.Now(),
+			ID:            1,
+			Archived:      false,
+			ApiKey:        "mock-api-key1",
+			ApiSecretHash: "mock-api-secret1",
+			DateCreated:   time.Now(),
 		},
 		{
-			ID:          2,
-			Archived:    false,
-			ApiKey:      "mock-api-key2",
-			ApiSecret:   "mock-api-secret2",
-			DateCreated: time.Now(),
+			ID:            2,
+			Archived:      false,
+			ApiKey:        "mock-api-key2",
+			ApiSecretHash: "mock-api-secret2",
+			DateCreated:   time.Now(),
 		},
 		{
-			ID:          3,
-			Archived:    false,
-			ApiKey:      "mock-api-key3",
-			ApiSecret:   "mock-api-secret3",
-			DateCreated: time.Now(),
+			ID:            3,
+			Archived:      false,
+			ApiKey:        "mock-api-key3",
+			ApiSecretHash: "mock-api-secret3",
+			DateCreated:   time.Now(),
 		},
 	}
 
@@ -288,8 +291,7 @@ func Test_CredentialRepo_List(t *testing.T) {
 	// Call the List function with offset, limit, and orderBy
 	offset := uint64(0)
 	limit := uint64(2)
-	orderBy := "id ASC"
-	credentials, listErr := credentialRepo.List(offset, limit, orderBy)
+	credentials, _, listErr := credentialRepo.List(models.PageRequest{Offset: offset, Limit: limit, OrderBy: "id", Order: "ASC"})
 	if listErr != nil {
 		t.Fatal("failed to retrieve credentials", listErr)
 	}
@@ -302,6 +304,118 @@ func Test_CredentialRepo_List(t *testing.T) {
 	assert.Equal(t, mockCredentials[1].ID, credentials[1].ID)
 }
 
+func Test_CredentialRepo_ListWithCursor(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "credential-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	credentialRepo := NewCredentialRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	for i := 1; i <= 5; i++ {
+		_, createErr := credentialRepo.CreateOne(models.Credential{
+			ApiKey:        fmt.Sprintf("mock-api-key%d", i),
+			ApiSecretHash: fmt.Sprintf("mock-api-secret%d", i),
+		})
+		if createErr != nil {
+			t.Fatal("failed to create a credential", createErr)
+		}
+	}
+
+	ids := func(credentials []models.Credential) []uint64 {
+		credentialIds := []uint64{}
+		for _, credential := range credentials {
+			credentialIds = append(credentialIds, credential.ID)
+		}
+		return credentialIds
+	}
+
+	// Lists read without a sort field or order are ordered by id ascending
+	defaultCredentials, _, listErr := credentialRepo.List(models.PageRequest{Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list credentials:", listErr)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, ids(defaultCredentials))
+
+	tests := []struct {
+		orderBy string
+		order   string
+	}{
+		{orderBy: "id", order: "ASC"},
+		{orderBy: "id", order: "DESC"},
+		{orderBy: "dateCreated", order: "ASC"},
+		{orderBy: "dateCreated", order: "DESC"},
+	}
+	for _, test := range tests {
+		allCredentials, _, listErr := credentialRepo.List(models.PageRequest{Limit: 10, OrderBy: test.orderBy, Order: test.order})
+		if listErr != nil {
+			t.Fatal("failed to list credentials:", listErr)
+		}
+		assert.Equal(t, 5, len(allCredentials))
+
+		// Page forward with the next cursors
+		forward := []uint64{}
+		page := models.PageRequest{Limit: 2, OrderBy: test.orderBy, Order: test.order}
+		var cursors models.PageCursors
+		for {
+			credentials, pageCursors, listErr := credentialRepo.List(page)
+			if listErr != nil {
+				t.Fatal("failed to list credentials:", listErr)
+			}
+			forward = append(forward, ids(credentials)...)
+			cursors = pageCursors
+			if cursors.Next == "" {
+				break
+			}
+			page = models.PageRequest{Limit: 2, Cursor: cursors.Next}
+		}
+		assert.Equal(t, ids(allCredentials), forward, "%s %s", test.orderBy, test.order)
+
+		// Page back from the last page with the prev cursors
+		backward := []uint64{}
+		for cursors.Prev != "" {
+			credentials, pageCursors, listErr := credentialRepo.List(models.PageRequest{Limit: 2, Cursor: cursors.Prev})
+			if listErr != nil {
+				t.Fatal("failed to list credentials:", listErr)
+			}
+			backward = append(ids(credentials), backward...)
+			cursors = pageCursors
+		}
+		assert.Equal(t, ids(allCredentials)[:4], backward, "%s %s", test.orderBy, test.order)
+	}
+
+	// Credentials can only be ordered by the fields of every list
+	_, _, orderErr := credentialRepo.List(models.PageRequest{Limit: 2, OrderBy: "apiKey"})
+	assert.NotNil(t, orderErr)
+	assert.Equal(t, http.StatusBadRequest, orderErr.Type)
+}
+
 func Test_CredentialRepo_Count(t *testing.T) {
 	scheduler0config := config.NewScheduler0Config()
 	logger := hclog.New(&hclog.LoggerOptions{
@@ -340,25 +454,25 @@ func Test_CredentialRepo_Count(t *testing.T) {
 	// Create mock credentials
 	mockCredentials := []models.Credential{
 		{
-			ID:          1,
-			Archived:    false,
-			ApiKey:      "mock-api-key1",
-			ApiSecret:   "mock-api-secret1",
-			DateCreated: time.Now(),
+			ID:            1,
+			Archived:      false,
+			ApiKey:        "mock-api-key1",
+			ApiSecretHash: "mock-api-secret1",
+			DateCreated:   time.Now(),
 		},
 		{
-			ID:          2,
-			Archived:    false,
-			ApiKey:      "mock-api-key2",
-			ApiSecret:   "mock-api-secret2",
-			DateCreated: time.Now(),
+			ID:            2,
+			Archived:      false,
+			ApiKey:        "mock-api-key2",
+			ApiSecretHash: "mock-api-secret2",
+			DateCreated:   time.Now(),
 		},
 		{
-			ID:          3,
-			Archived:    false,
-			ApiKey:      "mock-api-key3",
-			ApiSecret:   "mock-api-secret3",
-			DateCreated: time.Now(),
+			ID:            3,
+			Archived:      false,
+			ApiKey:        "mock-api-key3",
+			ApiSecretHash: "mock-api-secret3",
+			DateCreated:   time.Now(),
 		},
 	}
 
@@ -417,10 +531,10 @@ func Test_CredentialRepo_GetByAPIKey(t *testing.T) {
 
 	// Create a mock credential
 	mockCredential := models.Credential{
-		Archived:    false,
-		ApiKey:      "mock-api-key",
-		ApiSecret:   "mock-api-secret",
-		DateCreated: time.Now(),
+		Archived:      false,
+		ApiKey:        "mock-api-key",
+		ApiSecretHash: "mock-api-secret",
+		DateCreated:   time.Now(),
 	}
 
 	// Insert the mock credential using CreateOne
@@ -428,7 +542,7 @@ func Test_CredentialRepo_GetByAPIKey(t *testing.T) {
 	if createErr != nil {
 		t.Fatal("failed to create a credential", createErr)
 	}
-	mockCredential.ApiSecret = ""
+	mockCredential.ApiSecretHash = ""
 	mockCredential.Archived = true
 	// Call the GetByAPIKey function
 	getErr := credentialRepo.GetByAPIKey(&mockCredential)
@@ -440,7 +554,90 @@ func Test_CredentialRepo_GetByAPIKey(t *testing.T) {
 	assert.Equal(t, mockCredential.ID, uint64(1))
 	assert.Equal(t, false, mockCredential.Archived)
 	assert.Equal(t, "mock-api-key", mockCredential.ApiKey)
-	assert.Equal(t, "mock-api-secret", mockCredential.ApiSecret)
+	assert.Equal(t, "mock-api-secret", mockCredential.ApiSecretHash)
+}
+
+func Test_CredentialRepo_Scopes(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "credential-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	credentialRepo := NewCredentialRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	// A credential limited to two projects, and one without a scope
+	scopedId, createErr := credentialRepo.CreateOne(models.Credential{
+		ApiKey:        "scoped-api-key",
+		ApiSecretHash: "scoped-api-secret",
+		ProjectIDs:    []uint64{1, 2},
+		Permissions:   []models.Permission{models.PermissionRead, models.PermissionTrigger},
+	})
+	if createErr != nil {
+		t.Fatal("failed to create a credential", createErr)
+	}
+	_, createErr = credentialRepo.CreateOne(models.Credential{ApiKey: "api-key", ApiSecretHash: "api-secret"})
+	if createErr != nil {
+		t.Fatal("failed to create a credential", createErr)
+	}
+
+	scoped := models.Credential{ID: scopedId}
+	if getErr := credentialRepo.GetOneID(&scoped); getErr != nil {
+		t.Fatal("failed to get the credential", getErr)
+	}
+	assert.Equal(t, []uint64{1, 2}, scoped.ProjectIDs)
+	assert.Equal(t, []models.Permission{models.PermissionRead, models.PermissionTrigger}, scoped.Permissions)
+
+	// The scope is read with the api key, as the auth middleware reads it
+	unscoped := models.Credential{ApiKey: "api-key"}
+	if getErr := credentialRepo.GetByAPIKey(&unscoped); getErr != nil {
+		t.Fatal("failed to get the credential by API key", getErr)
+	}
+	assert.Empty(t, unscoped.ProjectIDs)
+	assert.Empty(t, unscoped.Permissions)
+
+	// Updates replace the scope
+	scoped.ProjectIDs = []uint64{3}
+	scoped.Permissions = []models.Permission{models.PermissionAdmin}
+	if _, updateErr := credentialRepo.UpdateOneByID(scoped); updateErr != nil {
+		t.Fatal("failed to update the credential", updateErr)
+	}
+	credentials, _, listErr := credentialRepo.List(models.PageRequest{Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list the credentials", listErr)
+	}
+	assert.Equal(t, 2, len(credentials))
+	for _, listed := range credentials {
+		if listed.ID == scopedId {
+			assert.Equal(t, []uint64{3}, listed.ProjectIDs)
+			assert.Equal(t, []models.Permission{models.PermissionAdmin}, listed.Permissions)
+		}
+	}
 }
 
 func Test_JobRepo_GetJobsTotalCount(t *testing.T) {
@@ -520,7 +717,7 @@ func Test_JobRepo_GetJobsTotalCount(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
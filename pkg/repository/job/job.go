@@ -1,15 +1,25 @@
 package job
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/audit"
+	"scheduler0/pkg/repository/pagination"
+	"scheduler0/pkg/repository/version"
 	"scheduler0/pkg/scheduler0time"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -31,7 +41,27 @@ type JobRepo interface {
 	DeleteOneByID(jobModel models.Job) (uint64, *utils.GenericError)
 	UpdateOneByID(jobModel models.Job) (uint64, *utils.GenericError)
 	GetAllByProjectID(projectID uint64, offset uint64, limit uint64, orderBy string) ([]models.Job, *utils.GenericError)
-	BatchInsertJobs(jobRepos []models.Job) ([]uint64, *utils.GenericError)
+	BatchInsertJobs(ctx context.Context, jobRepos []models.Job) ([]uint64, *utils.GenericError)
+	BatchUpdateJobs(jobs []models.Job) *utils.GenericError
+	BatchDeleteJobs(jobIds []uint64, actor models.Actor) (uint64, *utils.GenericError)
+	RollbackOneByID(jobModel models.Job) (uint64, *utils.GenericError)
+	GetJobRevisions(jobId uint64, offset uint64, limit uint64) ([]models.JobRevision, *utils.GenericError)
+	CountJobRevisions(jobId uint64) (uint64, *utils.GenericError)
+	GetJobRevision(jobId uint64, revision uint64) (*models.JobRevision, *utils.GenericError)
+	ListJobs(filter models.JobFilter) ([]models.Job, models.PageCursors, *utils.GenericError)
+	CountJobs(filter models.JobFilter) (uint64, *utils.GenericError)
+	UpdateStatusByFilter(filter models.JobFilter, status models.JobStatus) (uint64, *utils.GenericError)
+	DeleteByFilter(filter models.JobFilter) (uint64, *utils.GenericError)
+	GetJobsByExternalKeys(jobs []models.Job) ([]models.Job, *utils.GenericError)
+	ClaimIdempotencyKey(idempotencyKey models.IdempotencyKey) (bool, *utils.GenericError)
+	GetIdempotencyKey(credentialId uint64, key string) (*models.IdempotencyKey, *utils.GenericError)
+	SetIdempotencyKeyTaskId(credentialId uint64, key string, taskId uint64) *utils.GenericError
+	ReleaseIdempotencyKey(credentialId uint64, key string) *utils.GenericError
+	DeleteExpiredIdempotencyKeys(before time.Time) (uint64, *utils.GenericError)
+	ListJobsToReseal(activeKeyID string, afterId uint64, limit uint64) ([]models.Job, *utils.GenericError)
+	UpdateJobsData(jobs []models.Job) *utils.GenericError
+	ListJobRevisionsToReseal(activeKeyID string, afterJobId uint64, afterRevision uint64, limit uint64) ([]models.JobRevision, *utils.GenericError)
+	UpdateJobRevisionsData(jobRevisions []models.JobRevision) *utils.GenericError
 }
 
 func NewJobRepo(logger hclog.Logger, scheduler0RaftActions fsm.Scheduler0RaftActions, store fsm.Scheduler0RaftStore) JobRepo {
@@ -57,6 +87,10 @@ func (jobRepo *jobRepo) GetOneByID(jobModel *models.Job) *utils.GenericError {
 		constants.JobsTimezoneColumn,
 		constants.JobsTimezoneOffsetColumn,
 		constants.JobsDataColumn,
+		constants.JobsStatusColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsExternalKeyColumn),
+		constants.JobsVersionColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsDataKeyIdColumn),
 	).
 		From(constants.JobsTableName).
 		Where(fmt.Sprintf("%s = ?", constants.JobsIdColumn), jobModel.ID).
@@ -79,6 +113,10 @@ func (jobRepo *jobRepo) GetOneByID(jobModel *models.Job) *utils.GenericError {
 			&jobModel.Timezone,
 			&jobModel.TimezoneOffset,
 			&jobModel.Data,
+			&jobModel.Status,
+			&jobModel.ExternalKey,
+			&jobModel.Version,
+			&jobModel.DataKeyID,
 		)
 		if scanErr != nil {
 			return utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
@@ -91,6 +129,14 @@ func (jobRepo *jobRepo) GetOneByID(jobModel *models.Job) *utils.GenericError {
 	if count == 0 {
 		return utils.HTTPGenericError(http.StatusNotFound, "job cannot be found")
 	}
+	rows.Close()
+
+	jobs := []models.Job{*jobModel}
+	if attachErr := jobRepo.attachLabelsAndMetadata(jobs); attachErr != nil {
+		return attachErr
+	}
+	*jobModel = jobs[0]
+
 	return nil
 }
 
@@ -126,6 +172,10 @@ func (jobRepo *jobRepo) BatchGetJobsByID(jobIDs []uint64) ([]models.Job, *utils.
 			constants.JobsTimezoneColumn,
 			constants.JobsTimezoneOffsetColumn,
 			constants.JobsDataColumn,
+			constants.JobsStatusColumn,
+			fmt.Sprintf("IFNULL(%s, '')", constants.JobsExternalKeyColumn),
+			constants.JobsVersionColumn,
+			fmt.Sprintf("IFNULL(%s, '')", constants.JobsDataKeyIdColumn),
 		).
 			From(constants.JobsTableName).
 			Where(fmt.Sprintf("%s IN (%s)", constants.JobsIdColumn, paramsPlaceholder), ids...).
@@ -147,6 +197,10 @@ func (jobRepo *jobRepo) BatchGetJobsByID(jobIDs []uint64) ([]models.Job, *utils.
 				&job.Timezone,
 				&job.TimezoneOffset,
 				&job.Data,
+				&job.Status,
+				&job.ExternalKey,
+				&job.Version,
+				&job.DataKeyID,
 			)
 			if scanErr != nil {
 				return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
@@ -176,6 +230,10 @@ func (jobRepo *jobRepo) BatchGetJobsWithIDRange(lowerBound, upperBound int64) ([
 		constants.JobsTimezoneColumn,
 		constants.JobsTimezoneOffsetColumn,
 		constants.JobsDataColumn,
+		constants.JobsStatusColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsExternalKeyColumn),
+		constants.JobsVersionColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsDataKeyIdColumn),
 	).
 		From(constants.JobsTableName).
 		Where(fmt.Sprintf("%s BETWEEN ? and ?", constants.JobsIdColumn), lowerBound, upperBound).
@@ -200,6 +258,10 @@ func (jobRepo *jobRepo) BatchGetJobsWithIDRange(lowerBound, upperBound int64) ([
 			&job.Timezone,
 			&job.TimezoneOffset,
 			&job.Data,
+			&job.Status,
+			&job.ExternalKey,
+			&job.Version,
+			&job.DataKeyID,
 		)
 		if scanErr != nil {
 			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
@@ -230,6 +292,10 @@ func (jobRepo *jobRepo) GetAllByProjectID(projectID uint64, offset uint64, limit
 		constants.JobsTimezoneColumn,
 		constants.JobsTimezoneOffsetColumn,
 		constants.JobsDataColumn,
+		constants.JobsStatusColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsExternalKeyColumn),
+		constants.JobsVersionColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsDataKeyIdColumn),
 	).
 		From(constants.JobsTableName).
 		Offset(offset).
@@ -255,6 +321,10 @@ func (jobRepo *jobRepo) GetAllByProjectID(projectID uint64, offset uint64, limit
 			&job.Timezone,
 			&job.TimezoneOffset,
 			&job.Data,
+			&job.Status,
+			&job.ExternalKey,
+			&job.Version,
+			&job.DataKeyID,
 		)
 		if err != nil {
 			return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
@@ -264,12 +334,26 @@ func (jobRepo *jobRepo) GetAllByProjectID(projectID uint64, offset uint64, limit
 	if rows.Err() != nil {
 		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
+	rows.Close()
+
+	if attachErr := jobRepo.attachLabelsAndMetadata(jobs); attachErr != nil {
+		return nil, attachErr
+	}
 
 	return jobs, nil
 }
 
 // UpdateOneByID updates a job and returns number of affected rows
 func (jobRepo *jobRepo) UpdateOneByID(jobModel models.Job) (uint64, *utils.GenericError) {
+	return jobRepo.updateOneByID(jobModel, models.JobRevisionActionUpdate)
+}
+
+// RollbackOneByID updates a job to the state of one of its revisions, recording the change as a rollback
+func (jobRepo *jobRepo) RollbackOneByID(jobModel models.Job) (uint64, *utils.GenericError) {
+	return jobRepo.updateOneByID(jobModel, models.JobRevisionActionRollback)
+}
+
+func (jobRepo *jobRepo) updateOneByID(jobModel models.Job, action models.JobRevisionAction) (uint64, *utils.GenericError) {
 	jobPlaceholder := models.Job{
 		ID: jobModel.ID,
 	}
@@ -282,21 +366,67 @@ func (jobRepo *jobRepo) UpdateOneByID(jobModel models.Job) (uint64, *utils.Gener
 		return 0, utils.HTTPGenericError(http.StatusBadRequest, "cannot update cron spec")
 	}
 
+	if jobModel.Status == "" {
+		jobModel.Status = models.JobStatusActive
+	}
+
 	updateQuery := sq.Update(constants.JobsTableName).
 		Set(constants.JobsCallbackURLColumn, jobModel.CallbackUrl).
 		Set(constants.JobsExecutionTypeColumn, jobModel.ExecutionType).
 		Set(constants.JobsTimezoneColumn, jobModel.Timezone).
 		Set(constants.JobsTimezoneOffsetColumn, jobModel.TimezoneOffset).
 		Set(constants.JobsDataColumn, jobModel.Data).
-		Where(fmt.Sprintf("%s = ?", constants.JobsIdColumn), jobModel.ID)
+		Set(constants.JobsDataKeyIdColumn, dataKeyIdParam(jobModel.DataKeyID)).
+		Set(constants.JobsStatusColumn, jobModel.Status).
+		Set(constants.JobsVersionColumn, version.Next(constants.JobsVersionColumn)).
+		Where(version.Condition(constants.JobsIdColumn, constants.JobsVersionColumn, jobModel.ID, jobModel.Version))
 
-	query, params, err := updateQuery.ToSql()
+	updateSql, updateParams, err := updateQuery.ToSql()
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
-	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
-	if err != nil {
+	// The side tables are rewritten while the job is still at the version, before the jobs row
+	// is updated. The revision is recorded last and only if the update changed the job, so the
+	// rows affected reported by the raft command still refers to the job.
+	auditAction := models.AuditAction("")
+	if action == models.JobRevisionActionRollback {
+		auditAction = models.AuditActionRollback
+	}
+	query, params := audit.ContextSQL(jobModel.Actor, auditAction)
+
+	guard := version.ExistsCondition(constants.JobsTableName, constants.JobsIdColumn, constants.JobsVersionColumn, jobModel.ID, jobModel.Version)
+	for _, sideTable := range [][2]string{
+		{constants.JobLabelsTableName, constants.JobLabelsJobIdColumn},
+		{constants.JobMetadataTableName, constants.JobMetadataJobIdColumn},
+	} {
+		deleteSql, deleteParams, err := sq.Delete(sideTable[0]).Where(sq.Eq{sideTable[1]: jobModel.ID}).Where(guard).ToSql()
+		if err != nil {
+			return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		query += deleteSql + ";"
+		params = append(params, deleteParams...)
+	}
+
+	sideTablesSql, sideTablesParams, sideTablesErr := labelsAndMetadataInsertSQL(jobModel, "?", jobModel.ID, guard)
+	if sideTablesErr != nil {
+		return 0, sideTablesErr
+	}
+	var revisionCondition sq.Sqlizer = sq.Eq{constants.JobsIdColumn: jobModel.ID}
+	if jobModel.Version != 0 {
+		revisionCondition = sq.And{revisionCondition, sq.Expr("changes() > 0")}
+	}
+	revisionSql, revisionParams, revisionErr := jobRevisionsInsertSQL(action, jobModel.Actor.CredentialID, revisionCondition, "")
+	if revisionErr != nil {
+		return 0, revisionErr
+	}
+	query += sideTablesSql + updateSql + ";" + revisionSql
+	params = append(params, sideTablesParams...)
+	params = append(params, updateParams...)
+	params = append(params, revisionParams...)
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
+	if applyErr != nil {
 		return 0, applyErr
 	}
 
@@ -305,21 +435,32 @@ func (jobRepo *jobRepo) UpdateOneByID(jobModel models.Job) (uint64, *utils.Gener
 	}
 
 	count := res.Data.RowsAffected
+	if count == 0 && jobModel.Version != 0 {
+		return 0, jobRepo.versionError(jobModel)
+	}
 
 	return uint64(count), nil
 }
 
 // DeleteOneByID deletes a job with uuid and returns number of affected row
 func (jobRepo *jobRepo) DeleteOneByID(jobModel models.Job) (uint64, *utils.GenericError) {
-	deleteQuery := sq.Delete(constants.JobsTableName).Where(fmt.Sprintf("%s = ?", constants.JobsIdColumn), jobModel.ID)
-
-	query, params, err := deleteQuery.ToSql()
+	condition := version.Condition(constants.JobsIdColumn, constants.JobsVersionColumn, jobModel.ID, jobModel.Version)
+	revisionSql, revisionParams, revisionErr := jobRevisionsInsertSQL(models.JobRevisionActionDelete, jobModel.Actor.CredentialID, condition, "")
+	if revisionErr != nil {
+		return 0, revisionErr
+	}
+	deleteSql, deleteParams, err := sq.Delete(constants.JobsTableName).Where(condition).ToSql()
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
-	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
-	if err != nil {
+	query, params := audit.ContextSQL(jobModel.Actor, "")
+	query += revisionSql + deleteSql + ";"
+	params = append(params, revisionParams...)
+	params = append(params, deleteParams...)
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
+	if applyErr != nil {
 		return 0, applyErr
 	}
 
@@ -328,10 +469,22 @@ func (jobRepo *jobRepo) DeleteOneByID(jobModel models.Job) (uint64, *utils.Gener
 	}
 
 	count := res.Data.RowsAffected
+	if count == 0 && jobModel.Version != 0 {
+		return 0, jobRepo.versionError(jobModel)
+	}
 
 	return uint64(count), nil
 }
 
+// versionError returns the error of a change made against a version of a job that affected no rows,
+// which is nil if the job no longer exists and a precondition failed error if it is at another version.
+func (jobRepo *jobRepo) versionError(jobModel models.Job) *utils.GenericError {
+	if getErr := jobRepo.GetOneByID(&models.Job{ID: jobModel.ID}); getErr != nil {
+		return nil
+	}
+	return version.PreconditionFailed(jobModel.Version)
+}
+
 // GetJobsTotalCount returns total number of jobs
 func (jobRepo *jobRepo) GetJobsTotalCount() (uint64, *utils.GenericError) {
 	jobRepo.fsmStore.GetDataStore().ConnectionLock()
@@ -406,8 +559,17 @@ func (jobRepo *jobRepo) GetJobsPaginated(projectID uint64, offset uint64, limit
 }
 
 // BatchInsertJobs inserts n number of jobs
-func (jobRepo *jobRepo) BatchInsertJobs(jobs []models.Job) ([]uint64, *utils.GenericError) {
-	batches := utils.Batch[models.Job](jobs, 8)
+func (jobRepo *jobRepo) BatchInsertJobs(ctx context.Context, jobs []models.Job) ([]uint64, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "JobRepo.BatchInsertJobs", attribute.Int("scheduler0.jobs", len(jobs)))
+	defer span.End()
+
+	batches := batchJobsByVariables(jobs, func(job models.Job) int {
+		jobVariables := 11 + len(job.Labels)*3
+		if len(job.Metadata) > 0 {
+			jobVariables += 2
+		}
+		return jobVariables
+	})
 
 	returningIds := []uint64{}
 
@@ -415,7 +577,8 @@ func (jobRepo *jobRepo) BatchInsertJobs(jobs []models.Job) ([]uint64, *utils.Gen
 	now := schedulerTime.GetTime(time.Now())
 
 	for _, batch := range batches {
-		query := fmt.Sprintf("INSERT INTO jobs (%s, %s, %s, %s, %s, %s, %s, %s) VALUES ",
+		query, params := audit.ContextSQL(batch[0].Actor, "")
+		query += fmt.Sprintf("INSERT INTO jobs (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s) VALUES ",
 			constants.JobsProjectIdColumn,
 			constants.JobsSpecColumn,
 			constants.JobsCallbackURLColumn,
@@ -424,13 +587,18 @@ func (jobRepo *jobRepo) BatchInsertJobs(jobs []models.Job) ([]uint64, *utils.Gen
 			constants.JobsTimezoneColumn,
 			constants.JobsTimezoneOffsetColumn,
 			constants.JobsDataColumn,
+			constants.JobsDataKeyIdColumn,
+			constants.JobsStatusColumn,
+			constants.JobsExternalKeyColumn,
 		)
-		params := []interface{}{}
 		ids := []uint64{}
 
 		for i, job := range batch {
-			query += fmt.Sprint("(?, ?, ?, ?, ?, ?, ?, ?)")
+			query += fmt.Sprint("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 			job.DateCreated = now
+			if job.Status == "" {
+				job.Status = models.JobStatusActive
+			}
 			params = append(params,
 				job.ProjectID,
 				job.Spec,
@@ -440,6 +608,9 @@ func (jobRepo *jobRepo) BatchInsertJobs(jobs []models.Job) ([]uint64, *utils.Gen
 				job.Timezone,
 				job.TimezoneOffset,
 				job.Data,
+				dataKeyIdParam(job.DataKeyID),
+				job.Status,
+				externalKeyParam(job.ExternalKey),
 			)
 
 			if i < len(batch)-1 {
@@ -449,12 +620,43 @@ func (jobRepo *jobRepo) BatchInsertJobs(jobs []models.Job) ([]uint64, *utils.Gen
 
 		query += ";"
 
-		res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+		// Labels, metadata and revisions are written in the same raft command as the jobs.
+		// The rows of a single multi-row insert get consecutive ids ending at last_insert_rowid(),
+		// so each job is addressed relative to it. The side tables and the revisions are WITHOUT
+		// ROWID tables and the rows inserted by triggers do not count, which leaves the last
+		// inserted id pointing at the last job of the batch for every statement of the command.
+		for i, job := range batch {
+			jobIdExpr := "last_insert_rowid() - ?"
+			sideTablesSql, sideTablesParams, sideTablesErr := labelsAndMetadataInsertSQL(job, jobIdExpr, len(batch)-1-i, nil)
+			if sideTablesErr != nil {
+				return nil, sideTablesErr
+			}
+			query += sideTablesSql
+			params = append(params, sideTablesParams...)
+		}
+
+		revisionSql, revisionParams, revisionErr := jobRevisionsInsertSQL(
+			models.JobRevisionActionCreate,
+			batch[0].Actor.CredentialID,
+			sq.Expr(fmt.Sprintf("%s > last_insert_rowid() - ?", constants.JobsIdColumn), len(batch)),
+			"",
+		)
+		if revisionErr != nil {
+			return nil, revisionErr
+		}
+		query += revisionSql
+		params = append(params, revisionParams...)
+
+		res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(ctx, jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
 		if applyErr != nil {
 			return nil, applyErr
 		}
 
-		lastInsertedId := uint64(res.Data.RowsAffected)
+		if res == nil {
+			return nil, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+		}
+
+		lastInsertedId := uint64(res.Data.LastInsertedId)
 
 		for i := lastInsertedId - uint64(len(batch)) + 1; i <= lastInsertedId; i++ {
 			ids = append(ids, i)
@@ -465,3 +667,1105 @@ func (jobRepo *jobRepo) BatchInsertJobs(jobs []models.Job) ([]uint64, *utils.Gen
 
 	return returningIds, nil
 }
+
+// BatchUpdateJobs writes the given jobs in chunks bounded by the number of sql variables.
+// Labels and metadata are only rewritten for jobs that carry them. Every node is
+// notified to refresh the schedules of the jobs when a chunk is applied.
+func (jobRepo *jobRepo) BatchUpdateJobs(jobs []models.Job) *utils.GenericError {
+	batches := batchJobsByVariables(jobs, func(job models.Job) int {
+		jobVariables := 8 + jobRevisionsInsertVariables
+		if job.Labels != nil {
+			jobVariables += 1 + len(job.Labels)*3
+		}
+		if job.Metadata != nil {
+			jobVariables += 3
+		}
+		return jobVariables
+	})
+
+	for _, batch := range batches {
+		query, params := audit.ContextSQL(batch[0].Actor, "")
+
+		for _, job := range batch {
+			if job.Status == "" {
+				job.Status = models.JobStatusActive
+			}
+
+			if job.Labels != nil {
+				query += fmt.Sprintf("DELETE FROM %s WHERE %s = ?;", constants.JobLabelsTableName, constants.JobLabelsJobIdColumn)
+				params = append(params, job.ID)
+			}
+			if job.Metadata != nil {
+				query += fmt.Sprintf("DELETE FROM %s WHERE %s = ?;", constants.JobMetadataTableName, constants.JobMetadataJobIdColumn)
+				params = append(params, job.ID)
+			}
+
+			sideTablesSql, sideTablesParams, sideTablesErr := labelsAndMetadataInsertSQL(job, "?", job.ID, nil)
+			if sideTablesErr != nil {
+				return sideTablesErr
+			}
+			query += sideTablesSql
+			params = append(params, sideTablesParams...)
+
+			updateSql, updateParams, err := sq.Update(constants.JobsTableName).
+				Set(constants.JobsCallbackURLColumn, job.CallbackUrl).
+				Set(constants.JobsExecutionTypeColumn, job.ExecutionType).
+				Set(constants.JobsTimezoneColumn, job.Timezone).
+				Set(constants.JobsTimezoneOffsetColumn, job.TimezoneOffset).
+				Set(constants.JobsDataColumn, job.Data).
+				Set(constants.JobsDataKeyIdColumn, dataKeyIdParam(job.DataKeyID)).
+				Set(constants.JobsStatusColumn, job.Status).
+				Set(constants.JobsVersionColumn, version.Next(constants.JobsVersionColumn)).
+				Where(fmt.Sprintf("%s = ?", constants.JobsIdColumn), job.ID).
+				ToSql()
+			if err != nil {
+				return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+			}
+			query += updateSql + ";"
+			params = append(params, updateParams...)
+
+			revisionSql, revisionParams, revisionErr := jobRevisionsInsertSQL(models.JobRevisionActionUpdate, job.Actor.CredentialID, sq.Eq{constants.JobsIdColumn: job.ID}, "")
+			if revisionErr != nil {
+				return revisionErr
+			}
+			query += revisionSql
+			params = append(params, revisionParams...)
+		}
+
+		res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
+		if applyErr != nil {
+			return applyErr
+		}
+
+		if res == nil {
+			return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+		}
+	}
+
+	return nil
+}
+
+// BatchDeleteJobs deletes the jobs with the given ids in chunks bounded by the number of sql variables
+// and returns the number of deleted jobs. Every node is notified to drop the schedules of the jobs.
+func (jobRepo *jobRepo) BatchDeleteJobs(jobIds []uint64, actor models.Actor) (uint64, *utils.GenericError) {
+	var count uint64 = 0
+
+	// The ids are bound twice per statement, once to record the revisions and once to delete the jobs
+	for _, batch := range utils.Batch[uint64](jobIds, 2) {
+		deleteSql, deleteParams, err := jobRevisionsDeleteSQL(actor.CredentialID, batch)
+		if err != nil {
+			return count, err
+		}
+		query, params := audit.ContextSQL(actor, "")
+		query += deleteSql
+		params = append(params, deleteParams...)
+
+		res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
+		if applyErr != nil {
+			return count, applyErr
+		}
+
+		if res == nil {
+			return count, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+		}
+
+		count += uint64(res.Data.RowsAffected)
+	}
+
+	return count, nil
+}
+
+// jobIdsQuery appends a where clause matching the job ids to the statement.
+// The job ids are the only parameters of the returned query.
+func jobIdsQuery(statement string, jobIds []uint64) (string, []interface{}) {
+	placeholders := make([]string, 0, len(jobIds))
+	params := make([]interface{}, 0, len(jobIds))
+	for _, jobId := range jobIds {
+		placeholders = append(placeholders, "?")
+		params = append(params, jobId)
+	}
+
+	return fmt.Sprintf("%s WHERE %s IN (%s);", statement, constants.JobsIdColumn, strings.Join(placeholders, ",")), params
+}
+
+// GetJobsByExternalKeys returns the stored jobs that have the same project id and external key as the given jobs
+func (jobRepo *jobRepo) GetJobsByExternalKeys(jobs []models.Job) ([]models.Job, *utils.GenericError) {
+	keyedJobs := []models.Job{}
+	for _, job := range jobs {
+		if job.ExternalKey != "" {
+			keyedJobs = append(keyedJobs, job)
+		}
+	}
+
+	existingJobs := []models.Job{}
+	if len(keyedJobs) < 1 {
+		return existingJobs, nil
+	}
+
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	for _, batch := range utils.Batch[models.Job](keyedJobs, 2) {
+		keys := sq.Or{}
+		for _, job := range batch {
+			keys = append(keys, sq.Eq{
+				constants.JobsProjectIdColumn:   job.ProjectID,
+				constants.JobsExternalKeyColumn: job.ExternalKey,
+			})
+		}
+
+		rows, err := sq.Select(
+			constants.JobsIdColumn,
+			constants.JobsProjectIdColumn,
+			constants.JobsExternalKeyColumn,
+		).
+			From(constants.JobsTableName).
+			Where(keys).
+			RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+			Query()
+		if err != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		for rows.Next() {
+			job := models.Job{}
+			scanErr := rows.Scan(&job.ID, &job.ProjectID, &job.ExternalKey)
+			if scanErr != nil {
+				rows.Close()
+				return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+			}
+			existingJobs = append(existingJobs, job)
+		}
+		if rows.Err() != nil {
+			rows.Close()
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+		}
+		rows.Close()
+	}
+
+	return existingJobs, nil
+}
+
+// ClaimIdempotencyKey stores an idempotency key unless the same credential stored a key with the same value within constants.IdempotencyKeyTTL.
+// It returns false when the key is already taken.
+func (jobRepo *jobRepo) ClaimIdempotencyKey(idempotencyKey models.IdempotencyKey) (bool, *utils.GenericError) {
+	schedulerTime := scheduler0time.GetSchedulerTime()
+	now := schedulerTime.GetTime(time.Now())
+
+	// Expired keys are overwritten by the upsert, live keys are left untouched
+	// and the statement reports no affected rows. The live keys stored before the keys
+	// were scoped to credentials are taken for every credential, their credential is written in the
+	// sql since it is too large for the json encoding of the params.
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s, %s, %s, %s, %s, %s) SELECT ?, ?, ?, ?, NULL, ? WHERE NOT EXISTS (SELECT 1 FROM %s WHERE %s = %d AND %s = ? AND %s >= ?) "+
+			"ON CONFLICT(%s, %s) DO UPDATE SET %s = excluded.%s, %s = excluded.%s, %s = NULL, %s = excluded.%s WHERE %s.%s < ?",
+		constants.IdempotencyKeysTableName,
+		constants.IdempotencyKeysCredentialIdColumn,
+		constants.IdempotencyKeysKeyColumn,
+		constants.IdempotencyKeysRequestIdColumn,
+		constants.IdempotencyKeysRequestHashColumn,
+		constants.IdempotencyKeysTaskIdColumn,
+		constants.IdempotencyKeysDateCreatedColumn,
+		constants.IdempotencyKeysTableName,
+		constants.IdempotencyKeysCredentialIdColumn,
+		constants.UnscopedIdempotencyKeyCredentialId,
+		constants.IdempotencyKeysKeyColumn,
+		constants.IdempotencyKeysDateCreatedColumn,
+		constants.IdempotencyKeysCredentialIdColumn,
+		constants.IdempotencyKeysKeyColumn,
+		constants.IdempotencyKeysRequestIdColumn,
+		constants.IdempotencyKeysRequestIdColumn,
+		constants.IdempotencyKeysRequestHashColumn,
+		constants.IdempotencyKeysRequestHashColumn,
+		constants.IdempotencyKeysTaskIdColumn,
+		constants.IdempotencyKeysDateCreatedColumn,
+		constants.IdempotencyKeysDateCreatedColumn,
+		constants.IdempotencyKeysTableName,
+		constants.IdempotencyKeysDateCreatedColumn,
+	)
+	params := []interface{}{
+		idempotencyKey.CredentialId,
+		idempotencyKey.Key,
+		idempotencyKey.RequestId,
+		idempotencyKey.RequestHash,
+		now,
+		idempotencyKey.Key,
+		now.Add(-constants.IdempotencyKeyTTL),
+		now.Add(-constants.IdempotencyKeyTTL),
+	}
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return false, applyErr
+	}
+
+	if res == nil {
+		return false, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return res.Data.RowsAffected > 0, nil
+}
+
+// GetIdempotencyKey returns an idempotency key stored by a credential, or stored before the keys were scoped to credentials
+func (jobRepo *jobRepo) GetIdempotencyKey(credentialId uint64, key string) (*models.IdempotencyKey, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	idempotencyKey := models.IdempotencyKey{}
+	err := sq.Select(
+		constants.IdempotencyKeysCredentialIdColumn,
+		constants.IdempotencyKeysKeyColumn,
+		constants.IdempotencyKeysRequestIdColumn,
+		constants.IdempotencyKeysRequestHashColumn,
+		fmt.Sprintf("IFNULL(%s, 0)", constants.IdempotencyKeysTaskIdColumn),
+		constants.IdempotencyKeysDateCreatedColumn,
+	).
+		From(constants.IdempotencyKeysTableName).
+		Where(sq.Eq{
+			constants.IdempotencyKeysCredentialIdColumn: []uint64{credentialId, constants.UnscopedIdempotencyKeyCredentialId},
+			constants.IdempotencyKeysKeyColumn:          key,
+		}).
+		OrderBy(fmt.Sprintf("%s DESC", constants.IdempotencyKeysDateCreatedColumn)).
+		Limit(1).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		QueryRow().
+		Scan(
+			&idempotencyKey.CredentialId,
+			&idempotencyKey.Key,
+			&idempotencyKey.RequestId,
+			&idempotencyKey.RequestHash,
+			&idempotencyKey.TaskId,
+			&idempotencyKey.DateCreated,
+		)
+	if err == sql.ErrNoRows {
+		return nil, utils.HTTPGenericError(http.StatusNotFound, "idempotency key cannot be found")
+	}
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	return &idempotencyKey, nil
+}
+
+// SetIdempotencyKeyTaskId records the async task created for an idempotency key
+func (jobRepo *jobRepo) SetIdempotencyKeyTaskId(credentialId uint64, key string, taskId uint64) *utils.GenericError {
+	query, params, err := sq.Update(constants.IdempotencyKeysTableName).
+		Set(constants.IdempotencyKeysTaskIdColumn, taskId).
+		Where(sq.Eq{
+			constants.IdempotencyKeysCredentialIdColumn: credentialId,
+			constants.IdempotencyKeysKeyColumn:          key,
+		}).
+		ToSql()
+	if err != nil {
+		return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	_, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes an idempotency key so a request using it can be retried
+func (jobRepo *jobRepo) ReleaseIdempotencyKey(credentialId uint64, key string) *utils.GenericError {
+	query, params, err := sq.Delete(constants.IdempotencyKeysTableName).
+		Where(sq.Eq{
+			constants.IdempotencyKeysCredentialIdColumn: credentialId,
+			constants.IdempotencyKeysKeyColumn:          key,
+		}).
+		ToSql()
+	if err != nil {
+		return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	_, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes idempotency keys stored before a time and returns how many were deleted
+func (jobRepo *jobRepo) DeleteExpiredIdempotencyKeys(before time.Time) (uint64, *utils.GenericError) {
+	query, params, err := sq.Delete(constants.IdempotencyKeysTableName).
+		Where(sq.Lt{constants.IdempotencyKeysDateCreatedColumn: before}).
+		ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// ListJobsToReseal returns the jobs after the id with data in plain text or encrypted by a key other than the active key, by id.
+// Only the id, data, key id and version of the jobs are read.
+func (jobRepo *jobRepo) ListJobsToReseal(activeKeyID string, afterId uint64, limit uint64) ([]models.Job, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	rows, err := sq.Select(
+		constants.JobsIdColumn,
+		constants.JobsDataColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsDataKeyIdColumn),
+		constants.JobsVersionColumn,
+	).
+		From(constants.JobsTableName).
+		Where(sq.Gt{constants.JobsIdColumn: afterId}).
+		Where(sq.NotEq{constants.JobsDataColumn: nil}).
+		Where(sq.NotEq{constants.JobsDataColumn: ""}).
+		Where(sq.Expr(fmt.Sprintf("IFNULL(%s, '') != ?", constants.JobsDataKeyIdColumn), activeKeyID)).
+		OrderBy(constants.JobsIdColumn).
+		Limit(limit).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job := models.Job{}
+		if scanErr := rows.Scan(&job.ID, &job.Data, &job.DataKeyID, &job.Version); scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		jobs = append(jobs, job)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return jobs, nil
+}
+
+// UpdateJobsData writes the data and key id of the jobs in a single raft command, without changing their version or recording revisions.
+// Jobs changed since they were read are left as they are.
+func (jobRepo *jobRepo) UpdateJobsData(jobs []models.Job) *utils.GenericError {
+	if len(jobs) < 1 {
+		return nil
+	}
+
+	query := ""
+	params := []interface{}{}
+	for _, job := range jobs {
+		updateSql, updateParams, err := sq.Update(constants.JobsTableName).
+			Set(constants.JobsDataColumn, job.Data).
+			Set(constants.JobsDataKeyIdColumn, dataKeyIdParam(job.DataKeyID)).
+			Where(sq.Eq{
+				constants.JobsIdColumn:      job.ID,
+				constants.JobsVersionColumn: job.Version,
+			}).
+			ToSql()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		query += updateSql + ";"
+		params = append(params, updateParams...)
+	}
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+	if res == nil {
+		return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return nil
+}
+
+// ListJobRevisionsToReseal returns the job revisions after the revision of the job whose snapshot has data in plain text
+// or encrypted by a key other than the active key, by job id and revision. Only the job id, revision, data and key id are read.
+func (jobRepo *jobRepo) ListJobRevisionsToReseal(activeKeyID string, afterJobId uint64, afterRevision uint64, limit uint64) ([]models.JobRevision, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	rows, err := sq.Select(
+		constants.JobRevisionsJobIdColumn,
+		constants.JobRevisionsRevisionColumn,
+		fmt.Sprintf("json_extract(%s, '$.data')", constants.JobRevisionsSnapshotColumn),
+		fmt.Sprintf("IFNULL(json_extract(%s, '$.dataKeyId'), '')", constants.JobRevisionsSnapshotColumn),
+	).
+		From(constants.JobRevisionsTableName).
+		Where(sq.Or{
+			sq.Gt{constants.JobRevisionsJobIdColumn: afterJobId},
+			sq.And{
+				sq.Eq{constants.JobRevisionsJobIdColumn: afterJobId},
+				sq.Gt{constants.JobRevisionsRevisionColumn: afterRevision},
+			},
+		}).
+		Where(sq.Expr(fmt.Sprintf("IFNULL(json_extract(%s, '$.data'), '') != ''", constants.JobRevisionsSnapshotColumn))).
+		Where(sq.Expr(fmt.Sprintf("IFNULL(json_extract(%s, '$.dataKeyId'), '') != ?", constants.JobRevisionsSnapshotColumn), activeKeyID)).
+		OrderBy(constants.JobRevisionsJobIdColumn, constants.JobRevisionsRevisionColumn).
+		Limit(limit).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	jobRevisions := []models.JobRevision{}
+	for rows.Next() {
+		jobRevision := models.JobRevision{}
+		if scanErr := rows.Scan(&jobRevision.JobID, &jobRevision.Revision, &jobRevision.Job.Data, &jobRevision.Job.DataKeyID); scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		jobRevisions = append(jobRevisions, jobRevision)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return jobRevisions, nil
+}
+
+// UpdateJobRevisionsData writes the data and key id of the job revisions into their snapshots in a single raft command.
+// The snapshots are otherwise never changed, so they are written without checking what they hold.
+func (jobRepo *jobRepo) UpdateJobRevisionsData(jobRevisions []models.JobRevision) *utils.GenericError {
+	if len(jobRevisions) < 1 {
+		return nil
+	}
+
+	query := ""
+	params := []interface{}{}
+	for _, jobRevision := range jobRevisions {
+		updateSql, updateParams, err := sq.Update(constants.JobRevisionsTableName).
+			Set(constants.JobRevisionsSnapshotColumn, sq.Expr(
+				fmt.Sprintf("json_set(%s, '$.data', ?, '$.dataKeyId', ?)", constants.JobRevisionsSnapshotColumn),
+				jobRevision.Job.Data,
+				dataKeyIdParam(jobRevision.Job.DataKeyID),
+			)).
+			Where(sq.Eq{
+				constants.JobRevisionsJobIdColumn:    jobRevision.JobID,
+				constants.JobRevisionsRevisionColumn: jobRevision.Revision,
+			}).
+			ToSql()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		query += updateSql + ";"
+		params = append(params, updateParams...)
+	}
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	if applyErr != nil {
+		return applyErr
+	}
+	if res == nil {
+		return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return nil
+}
+
+// GetJobRevisions returns the revisions of a job, latest first
+func (jobRepo *jobRepo) GetJobRevisions(jobId uint64, offset uint64, limit uint64) ([]models.JobRevision, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	selectBuilder := jobRevisionsSelect().
+		Where(fmt.Sprintf("%s = ?", constants.JobRevisionsJobIdColumn), jobId).
+		OrderBy(fmt.Sprintf("%s DESC", constants.JobRevisionsRevisionColumn)).
+		Offset(offset).
+		Limit(limit).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection())
+
+	rows, err := selectBuilder.Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	revisions := []models.JobRevision{}
+	for rows.Next() {
+		revision, scanErr := scanJobRevision(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		revisions = append(revisions, *revision)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return revisions, nil
+}
+
+// CountJobRevisions returns the number of revisions of a job
+func (jobRepo *jobRepo) CountJobRevisions(jobId uint64) (uint64, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	countQuery := sq.Select("count(*)").
+		From(constants.JobRevisionsTableName).
+		Where(fmt.Sprintf("%s = ?", constants.JobRevisionsJobIdColumn), jobId).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection())
+
+	var count uint64
+	if err := countQuery.QueryRow().Scan(&count); err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	return count, nil
+}
+
+// GetJobRevision returns a single revision of a job
+func (jobRepo *jobRepo) GetJobRevision(jobId uint64, revision uint64) (*models.JobRevision, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	selectBuilder := jobRevisionsSelect().
+		Where(fmt.Sprintf("%s = ? AND %s = ?", constants.JobRevisionsJobIdColumn, constants.JobRevisionsRevisionColumn), jobId, revision).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection())
+
+	rows, err := selectBuilder.Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+		}
+		return nil, utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("cannot find revision %d of job with id %d", revision, jobId))
+	}
+
+	return scanJobRevision(rows)
+}
+
+func jobRevisionsSelect() sq.SelectBuilder {
+	return sq.Select(
+		constants.JobRevisionsJobIdColumn,
+		constants.JobRevisionsRevisionColumn,
+		constants.JobRevisionsActionColumn,
+		constants.JobRevisionsSnapshotColumn,
+		fmt.Sprintf("IFNULL(%s, 0)", constants.JobRevisionsCredentialIdColumn),
+		constants.JobRevisionsDateCreatedColumn,
+	).From(constants.JobRevisionsTableName)
+}
+
+func scanJobRevision(rows *sql.Rows) (*models.JobRevision, *utils.GenericError) {
+	revision := models.JobRevision{}
+	snapshot := ""
+	scanErr := rows.Scan(
+		&revision.JobID,
+		&revision.Revision,
+		&revision.Action,
+		&snapshot,
+		&revision.CredentialID,
+		&revision.DateCreated,
+	)
+	if scanErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+	}
+	if err := json.Unmarshal([]byte(snapshot), &revision.Job); err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("job revision snapshot is not valid: %v", err.Error()))
+	}
+	return &revision, nil
+}
+
+// jobRevisionsInsertVariables is the number of variables of a revisions insert besides the ones of its condition
+const jobRevisionsInsertVariables = 4
+
+// jobRevisionsInsertSQL returns the statement that records a revision of every job matching the condition.
+// The snapshot is read back from the jobs, labels and metadata tables, so it reflects the statements that
+// run before it in the same raft command. A non empty status replaces the status in the snapshot.
+func jobRevisionsInsertSQL(action models.JobRevisionAction, credentialId uint64, condition sq.Sqlizer, status models.JobStatus) (string, []interface{}, *utils.GenericError) {
+	conditionSql, conditionParams, err := condition.ToSql()
+	if err != nil {
+		return "", nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	snapshot := fmt.Sprintf("json_object('id', %s.%s, 'projectId', %s, 'spec', %s, 'callbackUrl', %s, 'data', %s, 'dataKeyId', %s, 'executionType', %s, 'status', %s, 'externalKey', %s, 'timezone', %s, 'timezoneOffset', %s, 'dateCreated', %s, "+
+		"'labels', json((SELECT json_group_object(%s, %s) FROM %s WHERE %s.%s = %s.%s)), "+
+		"'metadata', json((SELECT %s FROM %s WHERE %s.%s = %s.%s)))",
+		constants.JobsTableName, constants.JobsIdColumn,
+		constants.JobsProjectIdColumn,
+		constants.JobsSpecColumn,
+		constants.JobsCallbackURLColumn,
+		constants.JobsDataColumn,
+		constants.JobsDataKeyIdColumn,
+		constants.JobsExecutionTypeColumn,
+		constants.JobsStatusColumn,
+		constants.JobsExternalKeyColumn,
+		constants.JobsTimezoneColumn,
+		constants.JobsTimezoneOffsetColumn,
+		constants.JobsDateCreatedColumn,
+		constants.JobLabelsKeyColumn, constants.JobLabelsValueColumn, constants.JobLabelsTableName,
+		constants.JobLabelsTableName, constants.JobLabelsJobIdColumn, constants.JobsTableName, constants.JobsIdColumn,
+		constants.JobMetadataMetadataColumn, constants.JobMetadataTableName,
+		constants.JobMetadataTableName, constants.JobMetadataJobIdColumn, constants.JobsTableName, constants.JobsIdColumn,
+	)
+
+	params := []interface{}{action}
+	if status != "" {
+		snapshot = fmt.Sprintf("json_set(%s, '$.status', ?)", snapshot)
+		params = append(params, status)
+	}
+
+	schedulerTime := scheduler0time.GetSchedulerTime()
+	now := schedulerTime.GetTime(time.Now())
+	params = append(params, credentialIdParam(credentialId), now)
+	params = append(params, conditionParams...)
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s) SELECT %s.%s, (SELECT IFNULL(max(%s), 0) + 1 FROM %s WHERE %s.%s = %s.%s), ?, %s, ?, ? FROM %s WHERE %s;",
+		constants.JobRevisionsTableName,
+		constants.JobRevisionsJobIdColumn,
+		constants.JobRevisionsRevisionColumn,
+		constants.JobRevisionsActionColumn,
+		constants.JobRevisionsSnapshotColumn,
+		constants.JobRevisionsCredentialIdColumn,
+		constants.JobRevisionsDateCreatedColumn,
+		constants.JobsTableName, constants.JobsIdColumn,
+		constants.JobRevisionsRevisionColumn,
+		constants.JobRevisionsTableName,
+		constants.JobRevisionsTableName, constants.JobRevisionsJobIdColumn, constants.JobsTableName, constants.JobsIdColumn,
+		snapshot,
+		constants.JobsTableName,
+		conditionSql,
+	)
+
+	return query, params, nil
+}
+
+// jobRevisionsDeleteSQL returns the statements that record the delete revisions of the jobs and then delete them
+func jobRevisionsDeleteSQL(credentialId uint64, jobIds []uint64) (string, []interface{}, *utils.GenericError) {
+	revisionSql, params, err := jobRevisionsInsertSQL(models.JobRevisionActionDelete, credentialId, sq.Eq{constants.JobsIdColumn: jobIds}, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	deleteSql, deleteParams := jobIdsQuery(fmt.Sprintf("DELETE FROM %s", constants.JobsTableName), jobIds)
+
+	return revisionSql + deleteSql, append(params, deleteParams...), nil
+}
+
+// credentialIdParam stores changes that are not made with a credential as NULL
+func credentialIdParam(credentialId uint64) interface{} {
+	if credentialId == 0 {
+		return nil
+	}
+	return credentialId
+}
+
+// dataKeyIdParam stores the key id of jobs whose data is not encrypted as NULL
+func dataKeyIdParam(dataKeyId string) interface{} {
+	if dataKeyId == "" {
+		return nil
+	}
+	return dataKeyId
+}
+
+// externalKeyParam stores jobs without an external key as NULL so they are not covered by the unique index
+func externalKeyParam(externalKey string) interface{} {
+	if externalKey == "" {
+		return nil
+	}
+	return externalKey
+}
+
+// batchJobsByVariables splits jobs into batches whose job, label and metadata
+// variables fit in a single statement. Jobs of different actors are never in the
+// same batch, since a batch is written by a raft command made for a single actor.
+func batchJobsByVariables(jobs []models.Job, variablesPerJob func(job models.Job) int) [][]models.Job {
+	batches := [][]models.Job{}
+	batch := []models.Job{}
+	batchVariables := 0
+
+	for _, job := range jobs {
+		jobVariables := variablesPerJob(job)
+		if len(batch) > 0 && (batchVariables+jobVariables > constants.DBMaxVariableSize-jobRevisionsInsertVariables || job.Actor != batch[0].Actor) {
+			batches = append(batches, batch)
+			batch = []models.Job{}
+			batchVariables = 0
+		}
+		batch = append(batch, job)
+		batchVariables += jobVariables
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// ListJobs returns a page of the jobs in a project that match the filter, and the cursors of the pages around it
+func (jobRepo *jobRepo) ListJobs(filter models.JobFilter) ([]models.Job, models.PageCursors, *utils.GenericError) {
+	plan, planErr := pagination.NewPlan(constants.JobsTableName, constants.JobsIdColumn, jobSortColumns, pagination.DefaultOrderBy, pagination.DefaultOrder, filter.PageRequest())
+	if planErr != nil {
+		return nil, models.PageCursors{}, planErr
+	}
+
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	selectBuilder := sq.Select(
+		constants.JobsIdColumn,
+		constants.JobsProjectIdColumn,
+		constants.JobsSpecColumn,
+		constants.JobsCallbackURLColumn,
+		constants.JobsExecutionTypeColumn,
+		constants.JobsDateCreatedColumn,
+		constants.JobsTimezoneColumn,
+		constants.JobsTimezoneOffsetColumn,
+		constants.JobsDataColumn,
+		constants.JobsStatusColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsExternalKeyColumn),
+		constants.JobsVersionColumn,
+		fmt.Sprintf("IFNULL(%s, '')", constants.JobsDataKeyIdColumn),
+	).
+		From(constants.JobsTableName).
+		Where(jobFilterConditions(filter))
+
+	rows, err := plan.Apply(selectBuilder).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
+	if err != nil {
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job := models.Job{}
+		scanErr := rows.Scan(
+			&job.ID,
+			&job.ProjectID,
+			&job.Spec,
+			&job.CallbackUrl,
+			&job.ExecutionType,
+			&job.DateCreated,
+			&job.Timezone,
+			&job.TimezoneOffset,
+			&job.Data,
+			&job.Status,
+			&job.ExternalKey,
+			&job.Version,
+			&job.DataKeyID,
+		)
+		if scanErr != nil {
+			return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		jobs = append(jobs, job)
+	}
+	if rows.Err() != nil {
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+	rows.Close()
+
+	jobs, cursors, pageErr := pagination.Page(plan, jobRepo.fsmStore.GetDataStore().GetOpenConnection(), jobs, func(job models.Job) uint64 { return job.ID })
+	if pageErr != nil {
+		return nil, cursors, pageErr
+	}
+
+	if attachErr := jobRepo.attachLabelsAndMetadata(jobs); attachErr != nil {
+		return nil, cursors, attachErr
+	}
+
+	return jobs, cursors, nil
+}
+
+// CountJobs returns the number of jobs in a project that match the filter
+func (jobRepo *jobRepo) CountJobs(filter models.JobFilter) (uint64, *utils.GenericError) {
+	jobRepo.fsmStore.GetDataStore().ConnectionLock()
+	defer jobRepo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	countQuery := sq.Select("count(*)").
+		From(constants.JobsTableName).
+		Where(jobFilterConditions(filter)).
+		RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection())
+
+	var count uint64
+	scanErr := countQuery.QueryRow().Scan(&count)
+	if scanErr != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+	}
+
+	return count, nil
+}
+
+// UpdateStatusByFilter sets the status of every job that matches the filter and returns the number of affected rows
+func (jobRepo *jobRepo) UpdateStatusByFilter(filter models.JobFilter, status models.JobStatus) (uint64, *utils.GenericError) {
+	updateQuery := sq.Update(constants.JobsTableName).
+		Set(constants.JobsStatusColumn, status).
+		Set(constants.JobsVersionColumn, version.Next(constants.JobsVersionColumn)).
+		Where(jobFilterConditions(filter))
+
+	updateSql, updateParams, err := updateQuery.ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	// The status filter may no longer match once the jobs are updated, so the
+	// revisions are recorded first with the new status in their snapshot
+	revisionSql, revisionParams, revisionErr := jobRevisionsInsertSQL(models.JobRevisionActionUpdate, filter.Actor.CredentialID, jobFilterConditions(filter), status)
+	if revisionErr != nil {
+		return 0, revisionErr
+	}
+	auditAction := models.AuditActionResume
+	if status == models.JobStatusPaused {
+		auditAction = models.AuditActionPause
+	}
+	query, params := audit.ContextSQL(filter.Actor, auditAction)
+	query += revisionSql + updateSql + ";"
+	params = append(params, revisionParams...)
+	params = append(params, updateParams...)
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// DeleteByFilter deletes every job that matches the filter and returns the number of affected rows
+func (jobRepo *jobRepo) DeleteByFilter(filter models.JobFilter) (uint64, *utils.GenericError) {
+	deleteQuery := sq.Delete(constants.JobsTableName).Where(jobFilterConditions(filter))
+
+	deleteSql, deleteParams, err := deleteQuery.ToSql()
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	revisionSql, revisionParams, revisionErr := jobRevisionsInsertSQL(models.JobRevisionActionDelete, filter.Actor.CredentialID, jobFilterConditions(filter), "")
+	if revisionErr != nil {
+		return 0, revisionErr
+	}
+	query, params := audit.ContextSQL(filter.Actor, "")
+	query += revisionSql + deleteSql + ";"
+	params = append(params, revisionParams...)
+	params = append(params, deleteParams...)
+
+	res, applyErr := jobRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), jobRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, constants.CommandActionRecordJobRevisions)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// attachLabelsAndMetadata loads labels and metadata of the jobs from their side tables.
+// The caller must hold the connection lock.
+func (jobRepo *jobRepo) attachLabelsAndMetadata(jobs []models.Job) *utils.GenericError {
+	if len(jobs) < 1 {
+		return nil
+	}
+
+	positions := map[uint64]int{}
+	jobIds := []uint64{}
+	for i, job := range jobs {
+		positions[job.ID] = i
+		jobIds = append(jobIds, job.ID)
+	}
+
+	for _, batch := range utils.Batch[uint64](jobIds, 1) {
+		ids := []interface{}{}
+		for _, id := range batch {
+			ids = append(ids, id)
+		}
+		placeholders := sq.Placeholders(len(ids))
+
+		labelRows, err := sq.Select(
+			constants.JobLabelsJobIdColumn,
+			constants.JobLabelsKeyColumn,
+			constants.JobLabelsValueColumn,
+		).
+			From(constants.JobLabelsTableName).
+			Where(fmt.Sprintf("%s IN (%s)", constants.JobLabelsJobIdColumn, placeholders), ids...).
+			RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+			Query()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		for labelRows.Next() {
+			var jobId uint64
+			var key, value string
+			if scanErr := labelRows.Scan(&jobId, &key, &value); scanErr != nil {
+				labelRows.Close()
+				return utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+			}
+			job := &jobs[positions[jobId]]
+			if job.Labels == nil {
+				job.Labels = map[string]string{}
+			}
+			job.Labels[key] = value
+		}
+		if labelRows.Err() != nil {
+			labelRows.Close()
+			return utils.HTTPGenericError(http.StatusInternalServerError, labelRows.Err().Error())
+		}
+		labelRows.Close()
+
+		metadataRows, err := sq.Select(
+			constants.JobMetadataJobIdColumn,
+			constants.JobMetadataMetadataColumn,
+		).
+			From(constants.JobMetadataTableName).
+			Where(fmt.Sprintf("%s IN (%s)", constants.JobMetadataJobIdColumn, placeholders), ids...).
+			RunWith(jobRepo.fsmStore.GetDataStore().GetOpenConnection()).
+			Query()
+		if err != nil {
+			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		for metadataRows.Next() {
+			var jobId uint64
+			var metadata string
+			if scanErr := metadataRows.Scan(&jobId, &metadata); scanErr != nil {
+				metadataRows.Close()
+				return utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+			}
+			job := &jobs[positions[jobId]]
+			if unmarshalErr := json.Unmarshal([]byte(metadata), &job.Metadata); unmarshalErr != nil {
+				metadataRows.Close()
+				return utils.HTTPGenericError(http.StatusInternalServerError, unmarshalErr.Error())
+			}
+		}
+		if metadataRows.Err() != nil {
+			metadataRows.Close()
+			return utils.HTTPGenericError(http.StatusInternalServerError, metadataRows.Err().Error())
+		}
+		metadataRows.Close()
+	}
+
+	return nil
+}
+
+// labelsAndMetadataInsertSQL returns the statements that write the labels and metadata of a job.
+// jobIdExpr is the sql expression resolving to the job id and is bound with jobIdParam.
+// The rows are only written while guard holds, when it is not nil.
+func labelsAndMetadataInsertSQL(job models.Job, jobIdExpr string, jobIdParam interface{}, guard sq.Sqlizer) (string, []interface{}, *utils.GenericError) {
+	query := ""
+	params := []interface{}{}
+
+	guardSql := ""
+	guardParams := []interface{}{}
+	if guard != nil {
+		sql, args, err := guard.ToSql()
+		if err != nil {
+			return "", nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		}
+		guardSql = sql
+		guardParams = args
+	}
+	// rowsSql writes the values as they are, or selects them from a values clause filtered by the guard
+	rowsSql := func(values string) string {
+		if guardSql == "" {
+			return "VALUES " + values
+		}
+		return fmt.Sprintf("SELECT * FROM (VALUES %s) WHERE %s", values, guardSql)
+	}
+
+	if len(job.Labels) > 0 {
+		keys := make([]string, 0, len(job.Labels))
+		for key := range job.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, fmt.Sprintf("(%s, ?, ?)", jobIdExpr))
+			params = append(params, jobIdParam, key, job.Labels[key])
+		}
+		query += fmt.Sprintf("INSERT INTO %s (%s, %s, %s) %s;",
+			constants.JobLabelsTableName,
+			constants.JobLabelsJobIdColumn,
+			constants.JobLabelsKeyColumn,
+			constants.JobLabelsValueColumn,
+			rowsSql(strings.Join(values, ",")),
+		)
+		params = append(params, guardParams...)
+	}
+
+	if len(job.Metadata) > 0 {
+		metadata, err := json.Marshal(job.Metadata)
+		if err != nil {
+			return "", nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job metadata is not valid: %v", err.Error()))
+		}
+		query += fmt.Sprintf("INSERT INTO %s (%s, %s) %s;",
+			constants.JobMetadataTableName,
+			constants.JobMetadataJobIdColumn,
+			constants.JobMetadataMetadataColumn,
+			rowsSql(fmt.Sprintf("(%s, ?)", jobIdExpr)),
+		)
+		params = append(params, jobIdParam, string(metadata))
+		params = append(params, guardParams...)
+	}
+
+	return query, params, nil
+}
+
+// jobFilterConditions translates a job filter into sql conditions on the jobs table
+func jobFilterConditions(filter models.JobFilter) sq.And {
+	conditions := sq.And{
+		sq.Eq{constants.JobsProjectIdColumn: filter.ProjectID},
+	}
+
+	if filter.CallbackUrlPrefix != "" {
+		conditions = append(conditions, sq.Expr(fmt.Sprintf("substr(%s, 1, length(?)) = ?", constants.JobsCallbackURLColumn), filter.CallbackUrlPrefix, filter.CallbackUrlPrefix))
+	}
+
+	if filter.ExecutionType != "" {
+		conditions = append(conditions, sq.Eq{constants.JobsExecutionTypeColumn: filter.ExecutionType})
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, sq.Eq{constants.JobsStatusColumn: filter.Status})
+	}
+
+	labelExists := fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.%s AND %s = ?",
+		constants.JobLabelsTableName,
+		constants.JobLabelsTableName,
+		constants.JobLabelsJobIdColumn,
+		constants.JobsTableName,
+		constants.JobsIdColumn,
+		constants.JobLabelsKeyColumn,
+	)
+
+	for _, requirement := range filter.LabelSelector {
+		switch requirement.Operator {
+		case models.LabelSelectorEquals:
+			conditions = append(conditions, sq.Expr(fmt.Sprintf("%s AND %s = ?)", labelExists, constants.JobLabelsValueColumn), requirement.Key, requirement.Value))
+		case models.LabelSelectorNotEquals:
+			conditions = append(conditions, sq.Expr(fmt.Sprintf("NOT %s AND %s = ?)", labelExists, constants.JobLabelsValueColumn), requirement.Key, requirement.Value))
+		case models.LabelSelectorExists:
+			conditions = append(conditions, sq.Expr(fmt.Sprintf("%s)", labelExists), requirement.Key))
+		case models.LabelSelectorNotExists:
+			conditions = append(conditions, sq.Expr(fmt.Sprintf("NOT %s)", labelExists), requirement.Key))
+		}
+	}
+
+	return conditions
+}
+
+// jobSortColumns the fields jobs can be ordered by, next to the id and dateCreated fields of every list
+var jobSortColumns = pagination.SortColumns(constants.JobsIdColumn, constants.JobsDateCreatedColumn, map[string]string{
+	"callbackUrl":   constants.JobsCallbackURLColumn,
+	"executionType": constants.JobsExecutionTypeColumn,
+	"status":        constants.JobsStatusColumn,
+	"spec":          constants.JobsSpecColumn,
+	"timezone":      constants.JobsTimezoneColumn,
+})
@@ -1,12 +1,16 @@
 package job_test
 
 import (
+	"context"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
 	"scheduler0/pkg/db"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
@@ -92,7 +96,7 @@ func Test_JobRepo_BatchInsertJobs(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method
-	jobIDs, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	jobIDs, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -100,6 +104,37 @@ func Test_JobRepo_BatchInsertJobs(t *testing.T) {
 	// Assert the returned job IDs
 	expectedJobIDs := []uint64{1, 2}
 	assert.Equal(t, expectedJobIDs, jobIDs)
+
+	// Ids are not reused after the last job is deleted, and jobs of different actors are written by different commands
+	_, deleteErr := jobRepo.DeleteOneByID(models.Job{ID: 2})
+	if deleteErr != nil {
+		t.Fatal("failed to delete job:", deleteErr)
+	}
+	labeledJobs := []models.Job{
+		{ProjectID: projectID, Spec: "0 * * * *", CallbackUrl: "http://example.com/first", Timezone: "UTC", Labels: map[string]string{"name": "first"}, Actor: models.Actor{CredentialID: 1}},
+		{ProjectID: projectID, Spec: "0 * * * *", CallbackUrl: "http://example.com/second", Timezone: "UTC", Labels: map[string]string{"name": "second"}, Actor: models.Actor{CredentialID: 2}},
+	}
+	labeledJobIDs, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), labeledJobs)
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+	assert.Equal(t, []uint64{3, 4}, labeledJobIDs)
+
+	for i, jobID := range labeledJobIDs {
+		job := models.Job{ID: jobID}
+		if getErr := jobRepo.GetOneByID(&job); getErr != nil {
+			t.Fatal("failed to get job:", getErr)
+		}
+		assert.Equal(t, labeledJobs[i].CallbackUrl, job.CallbackUrl)
+		assert.Equal(t, labeledJobs[i].Labels, job.Labels)
+
+		revisions, revisionsErr := jobRepo.GetJobRevisions(jobID, 0, 10)
+		if revisionsErr != nil {
+			t.Fatal("failed to get job revisions:", revisionsErr)
+		}
+		assert.Equal(t, 1, len(revisions))
+		assert.Equal(t, labeledJobs[i].Actor.CredentialID, revisions[0].CredentialID)
+	}
 }
 
 func Test_JobRepo_UpdateOneByID(t *testing.T) {
@@ -179,7 +214,7 @@ func Test_JobRepo_UpdateOneByID(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -274,7 +309,7 @@ func Test_JobRepo_DeleteOneByID(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the job
-	_, batchInsertErr := jobRepo.BatchInsertJobs([]models.Job{job})
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{job})
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert job:", batchInsertErr)
 	}
@@ -299,6 +334,118 @@ func Test_JobRepo_DeleteOneByID(t *testing.T) {
 	assert.Equal(t, deletedJob.CallbackUrl, "")
 }
 
+func Test_JobRepo_UpdateAndDeleteWithVersion(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	project := models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	}
+	projectID, createProjectErr := projectRepo.CreateOne(&project)
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{
+		{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   "http://example.com/callback",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Labels:        map[string]string{"team": "payments"},
+		},
+	})
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert job:", batchInsertErr)
+	}
+
+	job := models.Job{ID: 1}
+	assert.Nil(t, jobRepo.GetOneByID(&job))
+	assert.Equal(t, uint64(1), job.Version)
+
+	// An update at the current version applies and increments the version
+	job.CallbackUrl = "http://example.com/first"
+	_, updateErr := jobRepo.UpdateOneByID(job)
+	assert.Nil(t, updateErr)
+
+	current := models.Job{ID: 1}
+	assert.Nil(t, jobRepo.GetOneByID(&current))
+	assert.Equal(t, uint64(2), current.Version)
+	assert.Equal(t, "http://example.com/first", current.CallbackUrl)
+
+	// An update at an older version changes neither the job nor its labels
+	job.CallbackUrl = "http://example.com/second"
+	job.Labels = map[string]string{"team": "billing"}
+	job.Metadata = map[string]interface{}{"owner": "billing"}
+	_, updateErr = jobRepo.UpdateOneByID(job)
+	assert.NotNil(t, updateErr)
+	assert.Equal(t, http.StatusPreconditionFailed, updateErr.Type)
+
+	current = models.Job{ID: 1}
+	assert.Nil(t, jobRepo.GetOneByID(&current))
+	assert.Equal(t, uint64(2), current.Version)
+	assert.Equal(t, "http://example.com/first", current.CallbackUrl)
+	assert.Equal(t, map[string]string{"team": "payments"}, current.Labels)
+	assert.Empty(t, current.Metadata)
+
+	// Nor does it record a revision
+	jobRevisions, revisionsErr := jobRepo.GetJobRevisions(1, 0, 10)
+	assert.Nil(t, revisionsErr)
+	assert.Equal(t, 2, len(jobRevisions))
+
+	// Updates without a version apply to any version
+	current.Version = 0
+	current.Data = "some data"
+	_, updateErr = jobRepo.UpdateOneByID(current)
+	assert.Nil(t, updateErr)
+
+	current = models.Job{ID: 1}
+	assert.Nil(t, jobRepo.GetOneByID(&current))
+	assert.Equal(t, uint64(3), current.Version)
+
+	_, deleteErr := jobRepo.DeleteOneByID(models.Job{ID: 1, Version: 2})
+	assert.NotNil(t, deleteErr)
+	assert.Equal(t, http.StatusPreconditionFailed, deleteErr.Type)
+	assert.Nil(t, jobRepo.GetOneByID(&models.Job{ID: 1}))
+
+	deletedCount, deleteErr := jobRepo.DeleteOneByID(models.Job{ID: 1, Version: 3})
+	assert.Nil(t, deleteErr)
+	assert.Equal(t, uint64(1), deletedCount)
+	assert.NotNil(t, jobRepo.GetOneByID(&models.Job{ID: 1}))
+}
+
 func Test_JobRepo_BatchGetJobsByID(t *testing.T) {
 	scheduler0config := config.NewScheduler0Config()
 	logger := hclog.New(&hclog.LoggerOptions{
@@ -376,7 +523,7 @@ func Test_JobRepo_BatchGetJobsByID(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -499,7 +646,7 @@ func Test_JobRepo_BatchGetJobsWithIDRange(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -630,7 +777,7 @@ func Test_JobRepo_GetAllByProjectID(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -768,7 +915,7 @@ func Test_JobRepo_GetJobsTotalCountByProjectID(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -882,7 +1029,7 @@ func Test_JobRepo_GetJobsPaginated(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method to insert the jobs
-	_, batchInsertErr := jobRepo.BatchInsertJobs(jobs)
+	_, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if batchInsertErr != nil {
 		t.Fatal("failed to insert jobs:", batchInsertErr)
 	}
@@ -903,3 +1050,1095 @@ func Test_JobRepo_GetJobsPaginated(t *testing.T) {
 	assert.Contains(t, []uint64{1, 2}, paginatedJobs[1].ID)
 	assert.NotEqual(t, paginatedJobs[0].ID, paginatedJobs[1].ID)
 }
+
+func Test_JobRepo_ListJobs_LabelsAndFilters(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	jobs := []models.Job{
+		{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   "https://billing.example.com/charge",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Labels:        map[string]string{"env": "prod", "team": "billing"},
+			Metadata:      map[string]interface{}{"owner": "payments"},
+		},
+		{
+			ProjectID:     projectID,
+			Spec:          "0 12 * * *",
+			CallbackUrl:   "https://billing.example.com/invoice",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Labels:        map[string]string{"env": "staging", "team": "billing"},
+		},
+		{
+			ProjectID:     projectID,
+			Spec:          "0 */2 * * *",
+			CallbackUrl:   "https://reports.example.com/daily",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Labels:        map[string]string{"env": "prod"},
+		},
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, ids)
+
+	job := models.Job{ID: ids[0]}
+	getErr := jobRepo.GetOneByID(&job)
+	if getErr != nil {
+		t.Fatal("failed to get job:", getErr)
+	}
+	assert.Equal(t, models.JobStatusActive, job.Status)
+	assert.Equal(t, jobs[0].Labels, job.Labels)
+	assert.Equal(t, jobs[0].Metadata, job.Metadata)
+
+	filteredJobs, _, listErr := jobRepo.ListJobs(models.JobFilter{
+		ProjectID:     projectID,
+		LabelSelector: []models.LabelRequirement{{Key: "env", Operator: models.LabelSelectorEquals, Value: "prod"}},
+		OrderBy:       "id",
+		Order:         "desc",
+		Limit:         10,
+	})
+	if listErr != nil {
+		t.Fatal("failed to list jobs:", listErr)
+	}
+	assert.Equal(t, 2, len(filteredJobs))
+	assert.Equal(t, ids[2], filteredJobs[0].ID)
+	assert.Equal(t, ids[0], filteredJobs[1].ID)
+
+	billingFilter := models.JobFilter{
+		ProjectID:         projectID,
+		CallbackUrlPrefix: "https://billing.",
+		LabelSelector:     []models.LabelRequirement{{Key: "env", Operator: models.LabelSelectorNotEquals, Value: "prod"}},
+	}
+	count, countErr := jobRepo.CountJobs(billingFilter)
+	if countErr != nil {
+		t.Fatal("failed to count jobs:", countErr)
+	}
+	assert.Equal(t, uint64(1), count)
+
+	affected, pauseErr := jobRepo.UpdateStatusByFilter(billingFilter, models.JobStatusPaused)
+	if pauseErr != nil {
+		t.Fatal("failed to pause jobs:", pauseErr)
+	}
+	assert.Equal(t, uint64(1), affected)
+
+	pausedCount, countErr := jobRepo.CountJobs(models.JobFilter{ProjectID: projectID, Status: models.JobStatusPaused})
+	if countErr != nil {
+		t.Fatal("failed to count jobs:", countErr)
+	}
+	assert.Equal(t, uint64(1), pausedCount)
+
+	affected, deleteErr := jobRepo.DeleteByFilter(models.JobFilter{
+		ProjectID:     projectID,
+		LabelSelector: []models.LabelRequirement{{Key: "team", Operator: models.LabelSelectorNotExists}},
+	})
+	if deleteErr != nil {
+		t.Fatal("failed to delete jobs:", deleteErr)
+	}
+	assert.Equal(t, uint64(1), affected)
+
+	total, countErr := jobRepo.GetJobsTotalCountByProjectID(projectID)
+	if countErr != nil {
+		t.Fatal("failed to count jobs:", countErr)
+	}
+	assert.Equal(t, uint64(2), total)
+}
+
+func Test_JobRepo_ListJobs_WithCursor(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	jobs := []models.Job{}
+	for i := 1; i <= 5; i++ {
+		jobs = append(jobs, models.Job{
+			ProjectID:     projectID,
+			Spec:          fmt.Sprintf("0 %d * * *", i%3),
+			CallbackUrl:   fmt.Sprintf("https://example.com/callback-%d", 6-i),
+			ExecutionType: "http",
+			Timezone:      []string{"UTC", "Europe/Berlin"}[i%2],
+		})
+	}
+	if _, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs); batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+
+	ids := func(jobs []models.Job) []uint64 {
+		jobIds := []uint64{}
+		for _, job := range jobs {
+			jobIds = append(jobIds, job.ID)
+		}
+		return jobIds
+	}
+
+	// Lists read without a sort field or order are ordered by id ascending
+	defaultJobs, _, listErr := jobRepo.ListJobs(models.JobFilter{ProjectID: projectID, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list jobs:", listErr)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, ids(defaultJobs))
+
+	tests := []struct {
+		orderBy string
+		order   string
+	}{
+		{orderBy: "id", order: "ASC"},
+		{orderBy: "id", order: "DESC"},
+		{orderBy: "dateCreated", order: "ASC"},
+		{orderBy: "dateCreated", order: "DESC"},
+		{orderBy: "callbackUrl", order: "ASC"},
+		{orderBy: "callbackUrl", order: "DESC"},
+		{orderBy: "spec", order: "ASC"},
+		{orderBy: "spec", order: "DESC"},
+		{orderBy: "timezone", order: "ASC"},
+		{orderBy: "timezone", order: "DESC"},
+		{orderBy: "status", order: "ASC"},
+		{orderBy: "executionType", order: "DESC"},
+	}
+	for _, test := range tests {
+		allJobs, _, listErr := jobRepo.ListJobs(models.JobFilter{ProjectID: projectID, Limit: 10, OrderBy: test.orderBy, Order: test.order})
+		if listErr != nil {
+			t.Fatal("failed to list jobs:", listErr)
+		}
+		assert.Equal(t, 5, len(allJobs))
+
+		// Page forward with the next cursors
+		forward := []uint64{}
+		filter := models.JobFilter{ProjectID: projectID, Limit: 2, OrderBy: test.orderBy, Order: test.order}
+		var cursors models.PageCursors
+		for {
+			jobs, pageCursors, listErr := jobRepo.ListJobs(filter)
+			if listErr != nil {
+				t.Fatal("failed to list jobs:", listErr)
+			}
+			forward = append(forward, ids(jobs)...)
+			cursors = pageCursors
+			if cursors.Next == "" {
+				break
+			}
+			filter = models.JobFilter{ProjectID: projectID, Limit: 2, Cursor: cursors.Next}
+		}
+		assert.Equal(t, ids(allJobs), forward, "%s %s", test.orderBy, test.order)
+
+		// Page back from the last page with the prev cursors
+		backward := []uint64{}
+		for cursors.Prev != "" {
+			jobs, pageCursors, listErr := jobRepo.ListJobs(models.JobFilter{ProjectID: projectID, Limit: 2, Cursor: cursors.Prev})
+			if listErr != nil {
+				t.Fatal("failed to list jobs:", listErr)
+			}
+			backward = append(ids(jobs), backward...)
+			cursors = pageCursors
+		}
+		assert.Equal(t, ids(allJobs)[:4], backward, "%s %s", test.orderBy, test.order)
+	}
+
+	_, _, orderErr := jobRepo.ListJobs(models.JobFilter{ProjectID: projectID, Limit: 2, OrderBy: "data"})
+	assert.NotNil(t, orderErr)
+	assert.Equal(t, http.StatusBadRequest, orderErr.Type)
+}
+
+func Test_JobRepo_BatchUpdateJobs_And_BatchDeleteJobs(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	jobs := []models.Job{}
+	for i := 0; i < 3; i++ {
+		jobs = append(jobs, models.Job{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   fmt.Sprintf("https://example.com/%d", i),
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Labels:        map[string]string{"env": "prod"},
+		})
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+
+	updateErr := jobRepo.BatchUpdateJobs([]models.Job{
+		{
+			ID:            ids[0],
+			CallbackUrl:   "https://example.com/updated",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Status:        models.JobStatusPaused,
+			Labels:        map[string]string{"env": "staging"},
+		},
+		{
+			ID:            ids[1],
+			CallbackUrl:   "https://example.com/1",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Data:          "payload",
+		},
+	})
+	if updateErr != nil {
+		t.Fatal("failed to update jobs:", updateErr)
+	}
+
+	updatedJob := models.Job{ID: ids[0]}
+	if getErr := jobRepo.GetOneByID(&updatedJob); getErr != nil {
+		t.Fatal("failed to get job:", getErr)
+	}
+	assert.Equal(t, "https://example.com/updated", updatedJob.CallbackUrl)
+	assert.Equal(t, models.JobStatusPaused, updatedJob.Status)
+	assert.Equal(t, map[string]string{"env": "staging"}, updatedJob.Labels)
+
+	// Labels are kept when the update does not carry them
+	unlabelledUpdateJob := models.Job{ID: ids[1]}
+	if getErr := jobRepo.GetOneByID(&unlabelledUpdateJob); getErr != nil {
+		t.Fatal("failed to get job:", getErr)
+	}
+	assert.Equal(t, "payload", unlabelledUpdateJob.Data)
+	assert.Equal(t, map[string]string{"env": "prod"}, unlabelledUpdateJob.Labels)
+
+	deleted, deleteErr := jobRepo.BatchDeleteJobs([]uint64{ids[0], ids[2], 1000}, models.Actor{})
+	if deleteErr != nil {
+		t.Fatal("failed to delete jobs:", deleteErr)
+	}
+	assert.Equal(t, uint64(2), deleted)
+
+	remainingJobs, batchGetErr := jobRepo.BatchGetJobsByID(ids)
+	if batchGetErr != nil {
+		t.Fatal("failed to get jobs:", batchGetErr)
+	}
+	assert.Equal(t, 1, len(remainingJobs))
+	assert.Equal(t, ids[1], remainingJobs[0].ID)
+}
+
+func Test_JobRepo_JobRevisions(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{
+		{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   "https://example.com/first",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Labels:        map[string]string{"env": "prod"},
+			Actor:         models.Actor{CredentialID: 7},
+		},
+		{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   "https://example.com/second",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+			Actor:         models.Actor{CredentialID: 7},
+		},
+	})
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+	assert.Equal(t, []uint64{1, 2}, ids)
+
+	job := models.Job{ID: ids[0]}
+	if getErr := jobRepo.GetOneByID(&job); getErr != nil {
+		t.Fatal("failed to get job:", getErr)
+	}
+	job.CallbackUrl = "https://example.com/changed"
+	job.Labels = map[string]string{"env": "staging"}
+	job.Actor = models.Actor{CredentialID: 8}
+	updatedCount, updateErr := jobRepo.UpdateOneByID(job)
+	if updateErr != nil {
+		t.Fatal("failed to update job:", updateErr)
+	}
+	assert.Equal(t, uint64(1), updatedCount)
+
+	affected, pauseErr := jobRepo.UpdateStatusByFilter(models.JobFilter{ProjectID: projectID, Status: models.JobStatusActive, Actor: models.Actor{CredentialID: 9}}, models.JobStatusPaused)
+	if pauseErr != nil {
+		t.Fatal("failed to pause jobs:", pauseErr)
+	}
+	assert.Equal(t, uint64(2), affected)
+
+	deletedCount, deleteErr := jobRepo.DeleteOneByID(models.Job{ID: ids[1], Actor: models.Actor{CredentialID: 7}})
+	if deleteErr != nil {
+		t.Fatal("failed to delete job:", deleteErr)
+	}
+	assert.Equal(t, uint64(1), deletedCount)
+
+	count, countErr := jobRepo.CountJobRevisions(ids[0])
+	if countErr != nil {
+		t.Fatal("failed to count job revisions:", countErr)
+	}
+	assert.Equal(t, uint64(3), count)
+
+	revisions, getRevisionsErr := jobRepo.GetJobRevisions(ids[0], 0, 10)
+	if getRevisionsErr != nil {
+		t.Fatal("failed to get job revisions:", getRevisionsErr)
+	}
+	assert.Equal(t, 3, len(revisions))
+	assert.Equal(t, uint64(3), revisions[0].Revision)
+	assert.Equal(t, models.JobRevisionActionUpdate, revisions[0].Action)
+	assert.Equal(t, models.JobStatusPaused, revisions[0].Job.Status)
+	assert.Equal(t, uint64(9), revisions[0].CredentialID)
+	assert.Equal(t, models.JobRevisionActionUpdate, revisions[1].Action)
+	assert.Equal(t, "https://example.com/changed", revisions[1].Job.CallbackUrl)
+	assert.Equal(t, map[string]string{"env": "staging"}, revisions[1].Job.Labels)
+	assert.Equal(t, uint64(8), revisions[1].CredentialID)
+	assert.Equal(t, models.JobRevisionActionCreate, revisions[2].Action)
+	assert.Equal(t, ids[0], revisions[2].Job.ID)
+	assert.Equal(t, "https://example.com/first", revisions[2].Job.CallbackUrl)
+	assert.Equal(t, map[string]string{"env": "prod"}, revisions[2].Job.Labels)
+	assert.Equal(t, uint64(7), revisions[2].CredentialID)
+
+	// Revisions of deleted jobs are kept
+	deletedRevisions, getDeletedRevisionsErr := jobRepo.GetJobRevisions(ids[1], 0, 10)
+	if getDeletedRevisionsErr != nil {
+		t.Fatal("failed to get job revisions:", getDeletedRevisionsErr)
+	}
+	assert.Equal(t, 3, len(deletedRevisions))
+	assert.Equal(t, models.JobRevisionActionDelete, deletedRevisions[0].Action)
+	assert.Equal(t, "https://example.com/second", deletedRevisions[0].Job.CallbackUrl)
+
+	createRevision, getRevisionErr := jobRepo.GetJobRevision(ids[0], 1)
+	if getRevisionErr != nil {
+		t.Fatal("failed to get job revision:", getRevisionErr)
+	}
+	rollbackJob := models.Job{
+		ID:            ids[0],
+		CallbackUrl:   createRevision.Job.CallbackUrl,
+		ExecutionType: createRevision.Job.ExecutionType,
+		Timezone:      createRevision.Job.Timezone,
+		Status:        createRevision.Job.Status,
+		Labels:        createRevision.Job.Labels,
+	}
+	if _, rollbackErr := jobRepo.RollbackOneByID(rollbackJob); rollbackErr != nil {
+		t.Fatal("failed to rollback job:", rollbackErr)
+	}
+
+	rolledBackJob := models.Job{ID: ids[0]}
+	if getErr := jobRepo.GetOneByID(&rolledBackJob); getErr != nil {
+		t.Fatal("failed to get job:", getErr)
+	}
+	assert.Equal(t, "https://example.com/first", rolledBackJob.CallbackUrl)
+	assert.Equal(t, models.JobStatusActive, rolledBackJob.Status)
+	assert.Equal(t, map[string]string{"env": "prod"}, rolledBackJob.Labels)
+
+	latestRevision, getLatestErr := jobRepo.GetJobRevision(ids[0], 4)
+	if getLatestErr != nil {
+		t.Fatal("failed to get job revision:", getLatestErr)
+	}
+	assert.Equal(t, models.JobRevisionActionRollback, latestRevision.Action)
+
+	_, missingErr := jobRepo.GetJobRevision(ids[0], 10)
+	assert.NotNil(t, missingErr)
+	assert.Equal(t, http.StatusNotFound, missingErr.Type)
+}
+
+func Test_JobRepo_JobRevisions_ArePostProcessed(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	postProcessChannel := make(chan models.PostProcess, 10)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, postProcessChannel)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{
+		{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   "https://example.com/first",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+		},
+		{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   "https://example.com/second",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+		},
+	})
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+
+	if _, deleteErr := jobRepo.DeleteOneByID(models.Job{ID: ids[1]}); deleteErr != nil {
+		t.Fatal("failed to delete job:", deleteErr)
+	}
+
+	postProcesses := []models.PostProcess{}
+	jobRevisions := []models.JobRevision{}
+	for len(postProcessChannel) > 0 {
+		postProcess := <-postProcessChannel
+		postProcesses = append(postProcesses, postProcess)
+		jobRevisions = append(jobRevisions, postProcess.JobRevisions...)
+	}
+
+	// The delete refreshes the schedule of the job in the same command that deletes it
+	assert.Equal(t, 3, len(postProcesses))
+	assert.Equal(t, constants.CommandActionRecordJobRevisions, postProcesses[2].Action)
+	assert.Equal(t, []uint64{ids[1]}, postProcesses[2].JobIds)
+
+	assert.Equal(t, 3, len(jobRevisions))
+	assert.Equal(t, ids[0], jobRevisions[0].JobID)
+	assert.Equal(t, models.JobRevisionActionCreate, jobRevisions[0].Action)
+	assert.Equal(t, projectID, jobRevisions[0].Job.ProjectID)
+	assert.Equal(t, "https://example.com/first", jobRevisions[0].Job.CallbackUrl)
+	assert.Equal(t, ids[1], jobRevisions[1].JobID)
+	assert.Equal(t, models.JobRevisionActionCreate, jobRevisions[1].Action)
+	assert.Equal(t, ids[1], jobRevisions[2].JobID)
+	assert.Equal(t, uint64(2), jobRevisions[2].Revision)
+	assert.Equal(t, models.JobRevisionActionDelete, jobRevisions[2].Action)
+	assert.Equal(t, projectID, jobRevisions[2].Job.ProjectID)
+
+	// Changes made with a filter refresh the schedules of the jobs they match as well
+	if _, pauseErr := jobRepo.UpdateStatusByFilter(models.JobFilter{ProjectID: projectID}, models.JobStatusPaused); pauseErr != nil {
+		t.Fatal("failed to pause jobs:", pauseErr)
+	}
+	pausePostProcess := <-postProcessChannel
+	assert.Equal(t, constants.CommandActionRecordJobRevisions, pausePostProcess.Action)
+	assert.Equal(t, []uint64{ids[0]}, pausePostProcess.JobIds)
+
+	// The revisions staged for the job events are cleared once they are read
+	var stagedRevisions int
+	if scanErr := sqliteDb.GetOpenConnection().QueryRow(fmt.Sprintf("SELECT count(*) FROM %s", constants.JobRevisionEventsTableName)).Scan(&stagedRevisions); scanErr != nil {
+		t.Fatal("failed to count staged job revisions:", scanErr)
+	}
+	assert.Equal(t, 0, stagedRevisions)
+}
+
+func Test_JobRepo_IdempotencyKeys(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	claimed, claimErr := jobRepo.ClaimIdempotencyKey(models.IdempotencyKey{CredentialId: 1, Key: "key-1", RequestId: "request-1", RequestHash: "hash-1"})
+	if claimErr != nil {
+		t.Fatal("failed to claim idempotency key:", claimErr)
+	}
+	assert.True(t, claimed)
+
+	// The same key is taken for its credential but free for another one
+	claimed, claimErr = jobRepo.ClaimIdempotencyKey(models.IdempotencyKey{CredentialId: 1, Key: "key-1", RequestId: "request-2", RequestHash: "hash-2"})
+	if claimErr != nil {
+		t.Fatal("failed to claim idempotency key:", claimErr)
+	}
+	assert.False(t, claimed)
+	claimed, claimErr = jobRepo.ClaimIdempotencyKey(models.IdempotencyKey{CredentialId: 2, Key: "key-1", RequestId: "request-3", RequestHash: "hash-3"})
+	if claimErr != nil {
+		t.Fatal("failed to claim idempotency key:", claimErr)
+	}
+	assert.True(t, claimed)
+
+	storedKey, getErr := jobRepo.GetIdempotencyKey(1, "key-1")
+	if getErr != nil {
+		t.Fatal("failed to get idempotency key:", getErr)
+	}
+	assert.Equal(t, "request-1", storedKey.RequestId)
+	storedKey, getErr = jobRepo.GetIdempotencyKey(2, "key-1")
+	if getErr != nil {
+		t.Fatal("failed to get idempotency key:", getErr)
+	}
+	assert.Equal(t, "request-3", storedKey.RequestId)
+
+	// Keys stored before the keys were scoped to credentials are taken for every credential
+	_, err = sqliteDb.GetOpenConnection().Exec(fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s) VALUES (?, 'key-2', 'request-4', 'hash-4', ?)",
+		constants.IdempotencyKeysTableName,
+		constants.IdempotencyKeysCredentialIdColumn,
+		constants.IdempotencyKeysKeyColumn,
+		constants.IdempotencyKeysRequestIdColumn,
+		constants.IdempotencyKeysRequestHashColumn,
+		constants.IdempotencyKeysDateCreatedColumn,
+	), constants.UnscopedIdempotencyKeyCredentialId, time.Now().UTC())
+	if err != nil {
+		t.Fatal("failed to store unscoped idempotency key:", err)
+	}
+	claimed, claimErr = jobRepo.ClaimIdempotencyKey(models.IdempotencyKey{CredentialId: 3, Key: "key-2", RequestId: "request-5", RequestHash: "hash-5"})
+	if claimErr != nil {
+		t.Fatal("failed to claim idempotency key:", claimErr)
+	}
+	assert.False(t, claimed)
+	storedKey, getErr = jobRepo.GetIdempotencyKey(3, "key-2")
+	if getErr != nil {
+		t.Fatal("failed to get idempotency key:", getErr)
+	}
+	assert.Equal(t, "request-4", storedKey.RequestId)
+
+	// Nothing has expired yet
+	deleted, deleteErr := jobRepo.DeleteExpiredIdempotencyKeys(time.Now().Add(-constants.IdempotencyKeyTTL))
+	if deleteErr != nil {
+		t.Fatal("failed to delete expired idempotency keys:", deleteErr)
+	}
+	assert.Equal(t, uint64(0), deleted)
+
+	deleted, deleteErr = jobRepo.DeleteExpiredIdempotencyKeys(time.Now().Add(time.Minute))
+	if deleteErr != nil {
+		t.Fatal("failed to delete expired idempotency keys:", deleteErr)
+	}
+	assert.Equal(t, uint64(3), deleted)
+
+	_, getErr = jobRepo.GetIdempotencyKey(1, "key-1")
+	assert.NotNil(t, getErr)
+	assert.Equal(t, http.StatusNotFound, getErr.Type)
+}
+
+func Test_JobRepo_ListJobsToReseal_And_UpdateJobsData(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	// Jobs without data, with data in plain text, encrypted by an old key and by the active key
+	jobs := []models.Job{
+		{Data: ""},
+		{Data: "plain"},
+		{Data: "sealed-by-old", DataKeyID: "old"},
+		{Data: "sealed-by-new", DataKeyID: "new"},
+	}
+	for i := range jobs {
+		jobs[i].ProjectID = projectID
+		jobs[i].Spec = "0 * * * *"
+		jobs[i].CallbackUrl = fmt.Sprintf("https://example.com/%d", i)
+		jobs[i].ExecutionType = "http"
+		jobs[i].Timezone = "UTC"
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+
+	jobsToReseal, listErr := jobRepo.ListJobsToReseal("new", 0, 10)
+	if listErr != nil {
+		t.Fatal("failed to list jobs to reseal:", listErr)
+	}
+	assert.Equal(t, 2, len(jobsToReseal))
+	assert.Equal(t, ids[1], jobsToReseal[0].ID)
+	assert.Equal(t, "plain", jobsToReseal[0].Data)
+	assert.Equal(t, "", jobsToReseal[0].DataKeyID)
+	assert.Equal(t, ids[2], jobsToReseal[1].ID)
+	assert.Equal(t, "old", jobsToReseal[1].DataKeyID)
+
+	// Batches continue after the last job of the previous one
+	jobsToReseal, listErr = jobRepo.ListJobsToReseal("new", 0, 1)
+	if listErr != nil {
+		t.Fatal("failed to list jobs to reseal:", listErr)
+	}
+	assert.Equal(t, 1, len(jobsToReseal))
+	jobsToReseal, listErr = jobRepo.ListJobsToReseal("new", jobsToReseal[0].ID, 1)
+	if listErr != nil {
+		t.Fatal("failed to list jobs to reseal:", listErr)
+	}
+	assert.Equal(t, 1, len(jobsToReseal))
+	assert.Equal(t, ids[2], jobsToReseal[0].ID)
+
+	// The job changed since it was read is left as it is
+	staleJob := jobsToReseal[0]
+	staleJob.Version++
+	updateErr := jobRepo.UpdateJobsData([]models.Job{
+		{ID: ids[1], Data: "resealed-plain", DataKeyID: "new", Version: 1},
+		{ID: staleJob.ID, Data: "resealed-old", DataKeyID: "new", Version: staleJob.Version},
+	})
+	if updateErr != nil {
+		t.Fatal("failed to update jobs data:", updateErr)
+	}
+
+	jobsToReseal, listErr = jobRepo.ListJobsToReseal("new", 0, 10)
+	if listErr != nil {
+		t.Fatal("failed to list jobs to reseal:", listErr)
+	}
+	assert.Equal(t, 1, len(jobsToReseal))
+	assert.Equal(t, ids[2], jobsToReseal[0].ID)
+
+	updatedJobs, getErr := jobRepo.BatchGetJobsByID([]uint64{ids[1]})
+	if getErr != nil {
+		t.Fatal("failed to get jobs:", getErr)
+	}
+	assert.Equal(t, "resealed-plain", updatedJobs[0].Data)
+	assert.Equal(t, "new", updatedJobs[0].DataKeyID)
+	assert.Equal(t, uint64(1), updatedJobs[0].Version)
+}
+
+func Test_JobRepo_ListJobRevisionsToReseal_And_UpdateJobRevisionsData(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	// Revisions of jobs without data, with data in plain text, encrypted by an old key and by the active key
+	jobs := []models.Job{
+		{Data: ""},
+		{Data: "plain"},
+		{Data: "sealed-by-old", DataKeyID: "old"},
+		{Data: "sealed-by-new", DataKeyID: "new"},
+	}
+	for i := range jobs {
+		jobs[i].ProjectID = projectID
+		jobs[i].Spec = "0 * * * *"
+		jobs[i].CallbackUrl = fmt.Sprintf("https://example.com/%d", i)
+		jobs[i].ExecutionType = "http"
+		jobs[i].Timezone = "UTC"
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+
+	jobRevisionsToReseal, listErr := jobRepo.ListJobRevisionsToReseal("new", 0, 0, 10)
+	if listErr != nil {
+		t.Fatal("failed to list job revisions to reseal:", listErr)
+	}
+	assert.Equal(t, 2, len(jobRevisionsToReseal))
+	assert.Equal(t, ids[1], jobRevisionsToReseal[0].JobID)
+	assert.Equal(t, uint64(1), jobRevisionsToReseal[0].Revision)
+	assert.Equal(t, "plain", jobRevisionsToReseal[0].Job.Data)
+	assert.Equal(t, "", jobRevisionsToReseal[0].Job.DataKeyID)
+	assert.Equal(t, ids[2], jobRevisionsToReseal[1].JobID)
+	assert.Equal(t, "old", jobRevisionsToReseal[1].Job.DataKeyID)
+
+	// Batches continue after the last revision of the previous one
+	jobRevisionsToReseal, listErr = jobRepo.ListJobRevisionsToReseal("new", 0, 0, 1)
+	if listErr != nil {
+		t.Fatal("failed to list job revisions to reseal:", listErr)
+	}
+	assert.Equal(t, 1, len(jobRevisionsToReseal))
+	jobRevisionsToReseal, listErr = jobRepo.ListJobRevisionsToReseal("new", jobRevisionsToReseal[0].JobID, jobRevisionsToReseal[0].Revision, 1)
+	if listErr != nil {
+		t.Fatal("failed to list job revisions to reseal:", listErr)
+	}
+	assert.Equal(t, 1, len(jobRevisionsToReseal))
+	assert.Equal(t, ids[2], jobRevisionsToReseal[0].JobID)
+
+	updateErr := jobRepo.UpdateJobRevisionsData([]models.JobRevision{
+		{JobID: ids[1], Revision: 1, Job: models.Job{Data: "resealed-plain", DataKeyID: "new"}},
+	})
+	if updateErr != nil {
+		t.Fatal("failed to update job revisions data:", updateErr)
+	}
+
+	jobRevisionsToReseal, listErr = jobRepo.ListJobRevisionsToReseal("new", 0, 0, 10)
+	if listErr != nil {
+		t.Fatal("failed to list job revisions to reseal:", listErr)
+	}
+	assert.Equal(t, 1, len(jobRevisionsToReseal))
+	assert.Equal(t, ids[2], jobRevisionsToReseal[0].JobID)
+
+	// The rest of the snapshot is left as it is
+	jobRevision, getErr := jobRepo.GetJobRevision(ids[1], 1)
+	if getErr != nil {
+		t.Fatal("failed to get job revision:", getErr)
+	}
+	assert.Equal(t, "resealed-plain", jobRevision.Job.Data)
+	assert.Equal(t, "new", jobRevision.Job.DataKeyID)
+	assert.Equal(t, "https://example.com/1", jobRevision.Job.CallbackUrl)
+	assert.Equal(t, models.JobRevisionActionCreate, jobRevision.Action)
+}
+
+func Test_JobRepo_JobChanges_RefreshJobSchedules(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	postProcessChannel := make(chan models.PostProcess, 10)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, postProcessChannel)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	projectID, createProjectErr := projectRepo.CreateOne(&models.Project{
+		Name:        "Test Project",
+		Description: "Test project description",
+	})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	jobs := []models.Job{}
+	for i := 0; i < 3; i++ {
+		jobs = append(jobs, models.Job{
+			ProjectID:     projectID,
+			Spec:          "0 * * * *",
+			CallbackUrl:   fmt.Sprintf("https://example.com/%d", i),
+			ExecutionType: "http",
+			Timezone:      "UTC",
+		})
+	}
+
+	ids, batchInsertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if batchInsertErr != nil {
+		t.Fatal("failed to insert jobs:", batchInsertErr)
+	}
+
+	// refreshedJobIds returns the job ids of the refresh post process written by the last command
+	refreshedJobIds := func() []uint64 {
+		var jobIds []uint64
+		refreshes := 0
+		for len(postProcessChannel) > 0 {
+			postProcess := <-postProcessChannel
+			if postProcess.Action == constants.CommandActionRecordJobRevisions {
+				jobIds = postProcess.JobIds
+				refreshes++
+			}
+		}
+		assert.Equal(t, 1, refreshes)
+		return jobIds
+	}
+	for len(postProcessChannel) > 0 {
+		<-postProcessChannel
+	}
+
+	updateErr := jobRepo.BatchUpdateJobs([]models.Job{
+		{
+			ID:            ids[0],
+			CallbackUrl:   "https://example.com/updated",
+			ExecutionType: "http",
+			Timezone:      "UTC",
+		},
+	})
+	if updateErr != nil {
+		t.Fatal("failed to update jobs:", updateErr)
+	}
+	assert.Equal(t, []uint64{ids[0]}, refreshedJobIds())
+
+	if _, pauseErr := jobRepo.UpdateStatusByFilter(models.JobFilter{ProjectID: projectID, CallbackUrlPrefix: "https://example.com/1"}, models.JobStatusPaused); pauseErr != nil {
+		t.Fatal("failed to pause jobs:", pauseErr)
+	}
+	assert.Equal(t, []uint64{ids[1]}, refreshedJobIds())
+
+	if _, deleteErr := jobRepo.BatchDeleteJobs([]uint64{ids[2]}, models.Actor{}); deleteErr != nil {
+		t.Fatal("failed to delete jobs:", deleteErr)
+	}
+	assert.Equal(t, []uint64{ids[2]}, refreshedJobIds())
+
+	// The revisions recorded by a command are only read once
+	var stagedRevisions int
+	if scanErr := sqliteDb.GetOpenConnection().QueryRow(fmt.Sprintf("SELECT count(*) FROM %s", constants.JobRevisionEventsTableName)).Scan(&stagedRevisions); scanErr != nil {
+		t.Fatal("failed to count staged revisions:", scanErr)
+	}
+	assert.Equal(t, 0, stagedRevisions)
+}
@@ -0,0 +1,214 @@
+package job_execution
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+const (
+	ExecutionStatsTableName             = "job_execution_stats"
+	ExecutionLatencyHistogramTableName  = "job_execution_latency_histogram"
+	ExecutionSummariesTableName         = "job_execution_summaries"
+	ExecutionStatsProjectIdColumn       = "project_id"
+	ExecutionStatsHourColumn            = "hour"
+	ExecutionStatsSuccessCountColumn    = "success_count"
+	ExecutionStatsFailedCountColumn     = "failed_count"
+	ExecutionStatsLatencyMaxMsColumn    = "latency_max_ms"
+	ExecutionLatencyBucketMsColumn      = "bucket_ms"
+	ExecutionLatencyCountColumn         = "count"
+	ExecutionSummaryLastSuccessColumn   = "last_success"
+	ExecutionSummaryLastFailureColumn   = "last_failure"
+	ExecutionSummaryConsecutiveFailures = "consecutive_failures"
+)
+
+// executionStatsHourLayout is the layout of the hours the execution stats counters are kept for
+const executionStatsHourLayout = "2006-01-02T15:00:00Z"
+
+// GetJobExecutionStats returns the execution stats of a job between from and to
+func (repo *executionsRepo) GetJobExecutionStats(jobId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError) {
+	stats, err := repo.getExecutionStats(
+		fmt.Sprintf("%s = ?", ExecutionsJobIdColumn),
+		[]interface{}{jobId},
+		fmt.Sprintf("%s = ?", ExecutionsJobIdColumn),
+		[]interface{}{jobId},
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.JobId = jobId
+	return stats, nil
+}
+
+// GetProjectExecutionStats returns the execution stats of the jobs of a project between from and to.
+// The consecutive failures are the most of any job in the project and the failing jobs are the jobs whose last execution failed.
+func (repo *executionsRepo) GetProjectExecutionStats(projectId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError) {
+	stats, err := repo.getExecutionStats(
+		fmt.Sprintf("%s = ?", ExecutionStatsProjectIdColumn),
+		[]interface{}{projectId},
+		fmt.Sprintf("%s = ? AND %s IN (SELECT id FROM jobs WHERE project_id = ?)", ExecutionStatsProjectIdColumn, ExecutionsJobIdColumn),
+		[]interface{}{projectId, projectId},
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.ProjectId = projectId
+	return stats, nil
+}
+
+// getExecutionStats reads the counters kept by the job_executions_committed_stats trigger. The counters are
+// selected with the counters condition and the last success, last failure and failures with the summaries condition.
+func (repo *executionsRepo) getExecutionStats(
+	countersCondition string,
+	countersParams []interface{},
+	summariesCondition string,
+	summariesParams []interface{},
+	from time.Time,
+	to time.Time,
+) (*models.JobExecutionStats, *utils.GenericError) {
+	repo.fsmStore.GetDataStore().ConnectionLock()
+	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	connection := repo.fsmStore.GetDataStore().GetOpenConnection()
+
+	stats := models.JobExecutionStats{From: from, To: to}
+
+	hoursCondition := fmt.Sprintf("%s AND %s >= ? AND %s <= ?", countersCondition, ExecutionStatsHourColumn, ExecutionStatsHourColumn)
+	hoursParams := append(append([]interface{}{}, countersParams...),
+		from.UTC().Truncate(time.Hour).Format(executionStatsHourLayout),
+		to.UTC().Truncate(time.Hour).Format(executionStatsHourLayout),
+	)
+
+	var latencyMaxMs uint64
+	err := connection.QueryRow(fmt.Sprintf(
+		"SELECT IFNULL(sum(%s), 0), IFNULL(sum(%s), 0), IFNULL(max(%s), 0) FROM %s WHERE %s",
+		ExecutionStatsSuccessCountColumn,
+		ExecutionStatsFailedCountColumn,
+		ExecutionStatsLatencyMaxMsColumn,
+		ExecutionStatsTableName,
+		hoursCondition,
+	), hoursParams...).Scan(&stats.SuccessCount, &stats.FailedCount, &latencyMaxMs)
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	if executions := stats.SuccessCount + stats.FailedCount; executions > 0 {
+		stats.SuccessRate = float64(stats.SuccessCount) / float64(executions)
+	}
+
+	rows, err := connection.Query(fmt.Sprintf(
+		"SELECT %s, sum(%s) FROM %s WHERE %s GROUP BY %s ORDER BY %s",
+		ExecutionLatencyBucketMsColumn,
+		ExecutionLatencyCountColumn,
+		ExecutionLatencyHistogramTableName,
+		hoursCondition,
+		ExecutionLatencyBucketMsColumn,
+		ExecutionLatencyBucketMsColumn,
+	), hoursParams...)
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	var buckets []latencyBucket
+	for rows.Next() {
+		bucket := latencyBucket{}
+		scanErr := rows.Scan(&bucket.upperBoundMs, &bucket.count)
+		if scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		buckets = append(buckets, bucket)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+	stats.LatencyP50Ms = latencyPercentile(buckets, 0.5, latencyMaxMs)
+	stats.LatencyP95Ms = latencyPercentile(buckets, 0.95, latencyMaxMs)
+
+	latestQuery := func(column string) string {
+		return fmt.Sprintf(
+			"(SELECT %s FROM %s WHERE %s AND %s IS NOT NULL ORDER BY julianday(%s) DESC LIMIT 1)",
+			column,
+			ExecutionSummariesTableName,
+			summariesCondition,
+			column,
+			column,
+		)
+	}
+	var summariesQueryParams []interface{}
+	for i := 0; i < 3; i++ {
+		summariesQueryParams = append(summariesQueryParams, summariesParams...)
+	}
+
+	var lastSuccess, lastFailure sql.NullString
+	err = connection.QueryRow(fmt.Sprintf(
+		"SELECT %s, %s, IFNULL(max(%s), 0), count(CASE WHEN %s > 0 THEN 1 END) FROM %s WHERE %s",
+		latestQuery(ExecutionSummaryLastSuccessColumn),
+		latestQuery(ExecutionSummaryLastFailureColumn),
+		ExecutionSummaryConsecutiveFailures,
+		ExecutionSummaryConsecutiveFailures,
+		ExecutionSummariesTableName,
+		summariesCondition,
+	), summariesQueryParams...).Scan(&lastSuccess, &lastFailure, &stats.ConsecutiveFailures, &stats.FailingJobs)
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	for _, datetime := range []struct {
+		value  sql.NullString
+		target **time.Time
+	}{
+		{lastSuccess, &stats.LastSuccess},
+		{lastFailure, &stats.LastFailure},
+	} {
+		if !datetime.value.Valid {
+			continue
+		}
+		parsed, parseErr := parseExecutionLogTime(datetime.value.String)
+		if parseErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, parseErr.Error())
+		}
+		*datetime.target = &parsed
+	}
+
+	return &stats, nil
+}
+
+type latencyBucket struct {
+	upperBoundMs int64
+	count        uint64
+}
+
+// latencyPercentile returns the upper bound of the bucket the percentile falls in. Latencies above
+// the largest bucket are reported as the highest latency seen.
+func latencyPercentile(buckets []latencyBucket, percentile float64, latencyMaxMs uint64) uint64 {
+	var total uint64
+	for _, bucket := range buckets {
+		total += bucket.count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(percentile * float64(total)))
+	var seen uint64
+	for _, bucket := range buckets {
+		seen += bucket.count
+		if seen < rank {
+			continue
+		}
+		if bucket.upperBoundMs == math.MaxInt64 || uint64(bucket.upperBoundMs) > latencyMaxMs {
+			return latencyMaxMs
+		}
+		return uint64(bucket.upperBoundMs)
+	}
+
+	return latencyMaxMs
+}
@@ -0,0 +1,138 @@
+package job_execution
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/db"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/job"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/shared_repo"
+	"testing"
+	"time"
+)
+
+func Test_JobExecutionsRepo_ExecutionStats(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-executions-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobExecutionsRepo := NewExecutionsRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	project := models.Project{
+		ID:          1,
+		Name:        "Test Project",
+		Description: "Test project description",
+	}
+	_, pcreateErr := projectRepo.CreateOne(&project)
+	if pcreateErr != nil {
+		t.Fatal("failed to create project:", pcreateErr)
+	}
+
+	jobs := []models.Job{
+		{ID: 1, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+		{ID: 2, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+	}
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if insertErr != nil {
+		t.Fatal("failed to insert job", insertErr)
+	}
+
+	now := time.Now()
+	executionLog := func(jobId uint64, state models.JobExecutionLogState, latencyMs uint64, minutesAgo int) models.JobExecutionLog {
+		return models.JobExecutionLog{
+			JobId:                 jobId,
+			UniqueId:              "1",
+			State:                 state,
+			NodeId:                1,
+			LastExecutionDatetime: now,
+			NextExecutionDatetime: now,
+			JobQueueVersion:       1,
+			ExecutionVersion:      1,
+			LatencyMs:             latencyMs,
+			DataCreated:           now.Add(-time.Duration(minutesAgo) * time.Minute),
+		}
+	}
+	jobExecutionsRepo.RaftInsertExecutionLogs([]models.JobExecutionLog{
+		executionLog(1, models.ExecutionLogScheduleState, 0, 70),
+		executionLog(1, models.ExecutionLogSuccessState, 5, 60),
+		executionLog(1, models.ExecutionLogSuccessState, 20, 50),
+		executionLog(1, models.ExecutionLogSuccessState, 40, 40),
+		executionLog(1, models.ExecutionLogSuccessState, 200, 30),
+		executionLog(1, models.ExecutionLogFailedState, 3000, 20),
+		executionLog(1, models.ExecutionLogFailedState, 400000, 10),
+		executionLog(2, models.ExecutionLogFailedState, 100, 20),
+		executionLog(2, models.ExecutionLogSuccessState, 100, 10),
+	}, 1)
+
+	jobStats, statsErr := jobExecutionsRepo.GetJobExecutionStats(1, now.Add(-24*time.Hour), now)
+	if statsErr != nil {
+		t.Fatal("failed to get job execution stats", statsErr)
+	}
+	assert.Equal(t, uint64(1), jobStats.JobId)
+	assert.Equal(t, uint64(4), jobStats.SuccessCount)
+	assert.Equal(t, uint64(2), jobStats.FailedCount)
+	assert.InDelta(t, 4.0/6.0, jobStats.SuccessRate, 0.0001)
+	assert.Equal(t, uint64(50), jobStats.LatencyP50Ms)
+	assert.Equal(t, uint64(400000), jobStats.LatencyP95Ms)
+	assert.Equal(t, uint64(2), jobStats.ConsecutiveFailures)
+	assert.NotNil(t, jobStats.LastSuccess)
+	assert.NotNil(t, jobStats.LastFailure)
+	assert.WithinDuration(t, now.Add(-30*time.Minute), *jobStats.LastSuccess, time.Second)
+	assert.WithinDuration(t, now.Add(-10*time.Minute), *jobStats.LastFailure, time.Second)
+
+	projectStats, statsErr := jobExecutionsRepo.GetProjectExecutionStats(1, now.Add(-24*time.Hour), now)
+	if statsErr != nil {
+		t.Fatal("failed to get project execution stats", statsErr)
+	}
+	assert.Equal(t, uint64(1), projectStats.ProjectId)
+	assert.Equal(t, uint64(5), projectStats.SuccessCount)
+	assert.Equal(t, uint64(3), projectStats.FailedCount)
+	assert.Equal(t, uint64(2), projectStats.ConsecutiveFailures)
+	assert.Equal(t, uint64(1), projectStats.FailingJobs)
+	assert.WithinDuration(t, now.Add(-10*time.Minute), *projectStats.LastSuccess, time.Second)
+
+	futureStats, statsErr := jobExecutionsRepo.GetJobExecutionStats(1, now.Add(2*time.Hour), now.Add(3*time.Hour))
+	if statsErr != nil {
+		t.Fatal("failed to get job execution stats", statsErr)
+	}
+	assert.Equal(t, uint64(0), futureStats.SuccessCount)
+	assert.Equal(t, uint64(0), futureStats.FailedCount)
+	assert.Equal(t, uint64(0), futureStats.LatencyP50Ms)
+	assert.Equal(t, uint64(2), futureStats.ConsecutiveFailures)
+}
@@ -6,11 +6,13 @@ import (
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
 	"github.com/robfig/cron"
+	"net/http"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/utils"
+	"strings"
 	"time"
 )
 
@@ -30,11 +32,21 @@ const (
 	ExecutionsJobIdColumn             = "job_id"
 	ExecutionsDateCreatedColumn       = "date_created"
 	ExecutionsVersion                 = "execution_version"
+	ExecutionsErrorColumn             = "error"
+	ExecutionsLatencyMsColumn         = "latency_ms"
+)
+
+const (
+	ExecutionDailyCountsTableName   = "job_execution_daily_counts"
+	ExecutionDailyCountsJobIdColumn = "job_id"
+	ExecutionDailyCountsDayColumn   = "day"
+	ExecutionDailyCountsStateColumn = "state"
+	ExecutionDailyCountsCountColumn = "count"
 )
 
 //go:generate mockery --name JobExecutionsRepo --output ../mocks
 type JobExecutionsRepo interface {
-	BatchInsert(jobs []models.Job, nodeId uint64, state models.JobExecutionLogState, jobQueueVersion uint64, executionVersions map[uint64]uint64)
+	BatchInsert(jobs []models.Job, nodeId uint64, state models.JobExecutionLogState, jobQueueVersion uint64, executionVersions map[uint64]uint64, result models.JobExecutionResult)
 	CountLastFailedExecutionLogs(jobId uint64, nodeId uint64, executionVersion uint64) uint64
 	CountExecutionLogs(committed bool) uint64
 	GetUncommittedExecutionsLogForNode(nodeId uint64) []models.JobExecutionLog
@@ -45,8 +57,15 @@ type JobExecutionsRepo interface {
 		executionVersions map[uint64]uint64,
 		lastVersion uint64,
 		nodeId uint64,
+		result models.JobExecutionResult,
 	)
 	RaftInsertExecutionLogs(executionLogs []models.JobExecutionLog, nodeId uint64)
+	ListExecutionLogs(filter models.JobExecutionLogFilter) ([]models.JobExecutionLog, *utils.GenericError)
+	CountExecutionLogsByFilter(filter models.JobExecutionLogFilter) (uint64, *utils.GenericError)
+	CompactExecutionLogs(policy models.ExecutionLogRetentionPolicy, now time.Time) (uint64, *utils.GenericError)
+	GetExecutionDailyCounts(jobId uint64) ([]models.JobExecutionDailyCount, *utils.GenericError)
+	GetJobExecutionStats(jobId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError)
+	GetProjectExecutionStats(projectId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError)
 }
 
 type executionsRepo struct {
@@ -63,7 +82,7 @@ func NewExecutionsRepo(logger hclog.Logger, scheduler0RaftActions fsm.Scheduler0
 	}
 }
 
-func (repo *executionsRepo) BatchInsert(jobs []models.Job, nodeId uint64, state models.JobExecutionLogState, jobQueueVersion uint64, jobExecutionVersions map[uint64]uint64) {
+func (repo *executionsRepo) BatchInsert(jobs []models.Job, nodeId uint64, state models.JobExecutionLogState, jobQueueVersion uint64, jobExecutionVersions map[uint64]uint64, result models.JobExecutionResult) {
 	repo.fsmStore.GetDataStore().ConnectionLock()
 	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
 
@@ -71,11 +90,11 @@ func (repo *executionsRepo) BatchInsert(jobs []models.Job, nodeId uint64, state
 		return
 	}
 
-	batches := utils.Batch[models.Job](jobs, 9)
+	batches := utils.Batch[models.Job](jobs, 10)
 	var returningIds []uint64
 
 	for _, batch := range batches {
-		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s , %s, %s) VALUES ",
+		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s , %s, %s, %s, %s) VALUES ",
 			ExecutionsUnCommittedTableName,
 			ExecutionsUniqueIdColumn,
 			ExecutionsStateColumn,
@@ -86,6 +105,8 @@ func (repo *executionsRepo) BatchInsert(jobs []models.Job, nodeId uint64, state
 			ExecutionsJobQueueVersion,
 			ExecutionsDateCreatedColumn,
 			ExecutionsVersion,
+			ExecutionsErrorColumn,
+			ExecutionsLatencyMsColumn,
 		)
 		var params []interface{}
 		var ids []uint64
@@ -97,7 +118,7 @@ func (repo *executionsRepo) BatchInsert(jobs []models.Job, nodeId uint64, state
 				executionVersion = int(jobExecutionVersion)
 			}
 
-			query += fmt.Sprint("(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			query += fmt.Sprint("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 			schedule, parseErr := cron.Parse(job.Spec)
 			if parseErr != nil {
 				repo.logger.Error(fmt.Sprintf("failed to parse job cron spec %s", parseErr.Error()))
@@ -115,6 +136,8 @@ func (repo *executionsRepo) BatchInsert(jobs []models.Job, nodeId uint64, state
 				jobQueueVersion,
 				now,
 				executionVersion,
+				executionErrorParam(result.Error),
+				executionLatencyParam(uint64(result.Latency.Milliseconds())),
 			)
 			if i < len(batch)-1 {
 				query += ","
@@ -427,6 +450,8 @@ func (repo *executionsRepo) GetUncommittedExecutionsLogForNode(nodeId uint64) []
 			ExecutionsDateCreatedColumn,
 			ExecutionsJobQueueVersion,
 			ExecutionsVersion,
+			fmt.Sprintf("IFNULL(%s, '')", ExecutionsErrorColumn),
+			fmt.Sprintf("IFNULL(%s, 0)", ExecutionsLatencyMsColumn),
 		).
 			From(ExecutionsUnCommittedTableName).
 			OrderBy(fmt.Sprintf("%s DESC", ExecutionsNextExecutionTime)).
@@ -452,6 +477,8 @@ func (repo *executionsRepo) GetUncommittedExecutionsLogForNode(nodeId uint64) []
 				&lastExecutionLog.DataCreated,
 				&lastExecutionLog.JobQueueVersion,
 				&lastExecutionLog.ExecutionVersion,
+				&lastExecutionLog.Error,
+				&lastExecutionLog.LatencyMs,
 			)
 			if scanErr != nil {
 				repo.logger.Error("failed to scan rows", scanErr)
@@ -476,6 +503,7 @@ func (repo *executionsRepo) LogJobExecutionStateInRaft(
 	executionVersions map[uint64]uint64,
 	lastVersion uint64,
 	nodeId uint64,
+	result models.JobExecutionResult,
 ) {
 	executionLogs := make([]models.JobExecutionLog, 0, len(jobs))
 
@@ -497,6 +525,8 @@ func (repo *executionsRepo) LogJobExecutionStateInRaft(
 			JobQueueVersion:       lastVersion,
 			DataCreated:           now,
 			ExecutionVersion:      executionVersions[job.ID],
+			Error:                 result.Error,
+			LatencyMs:             uint64(result.Latency.Milliseconds()),
 		})
 	}
 
@@ -508,10 +538,10 @@ func (repo *executionsRepo) RaftInsertExecutionLogs(executionLogs []models.JobEx
 		return
 	}
 
-	batches := utils.Batch[models.JobExecutionLog](executionLogs, 9)
+	batches := utils.Batch[models.JobExecutionLog](executionLogs, 10)
 
 	for _, batch := range batches {
-		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s , %s, %s) VALUES ",
+		query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s , %s, %s, %s, %s) VALUES ",
 			ExecutionsCommittedTableName,
 			ExecutionsUniqueIdColumn,
 			ExecutionsStateColumn,
@@ -522,11 +552,13 @@ func (repo *executionsRepo) RaftInsertExecutionLogs(executionLogs []models.JobEx
 			ExecutionsJobQueueVersion,
 			ExecutionsDateCreatedColumn,
 			ExecutionsVersion,
+			ExecutionsErrorColumn,
+			ExecutionsLatencyMsColumn,
 		)
 		var params []interface{}
 
 		for i, executionLog := range batch {
-			query += fmt.Sprint("(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			query += fmt.Sprint("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 			params = append(params,
 				executionLog.UniqueId,
 				executionLog.State,
@@ -537,6 +569,8 @@ func (repo *executionsRepo) RaftInsertExecutionLogs(executionLogs []models.JobEx
 				executionLog.JobQueueVersion,
 				executionLog.DataCreated,
 				executionLog.ExecutionVersion,
+				executionErrorParam(executionLog.Error),
+				executionLatencyParam(executionLog.LatencyMs),
 			)
 			if i < len(batch)-1 {
 				query += ","
@@ -546,6 +580,7 @@ func (repo *executionsRepo) RaftInsertExecutionLogs(executionLogs []models.JobEx
 		query += ";"
 
 		repo.scheduler0RaftActions.WriteCommandToRaftLog(
+			context.Background(),
 			repo.fsmStore.GetRaft(),
 			constants.CommandTypeDbExecute,
 			query,
@@ -555,3 +590,403 @@ func (repo *executionsRepo) RaftInsertExecutionLogs(executionLogs []models.JobEx
 		)
 	}
 }
+
+// ListExecutionLogs returns the execution logs matching the filter, latest first. Logs that
+// are only in the uncommitted table of this node are included with Committed set to false.
+func (repo *executionsRepo) ListExecutionLogs(filter models.JobExecutionLogFilter) ([]models.JobExecutionLog, *utils.GenericError) {
+	repo.fsmStore.GetDataStore().ConnectionLock()
+	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	query, params := executionLogsQuery(filter)
+	query = fmt.Sprintf("SELECT %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, committed, attempt_count FROM (%s) ORDER BY julianday(%s) DESC, %s DESC LIMIT ? OFFSET ?",
+		ExecutionsIdColumn,
+		ExecutionsUniqueIdColumn,
+		ExecutionsStateColumn,
+		ExecutionsNodeIdColumn,
+		ExecutionsLastExecutionTimeColumn,
+		ExecutionsNextExecutionTime,
+		ExecutionsJobIdColumn,
+		ExecutionsDateCreatedColumn,
+		ExecutionsJobQueueVersion,
+		ExecutionsVersion,
+		ExecutionsErrorColumn,
+		ExecutionsLatencyMsColumn,
+		query,
+		ExecutionsDateCreatedColumn,
+		ExecutionsIdColumn,
+	)
+	params = append(params, filter.Limit, filter.Offset)
+
+	rows, err := repo.fsmStore.GetDataStore().GetOpenConnection().Query(query, params...)
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	executionLogs := []models.JobExecutionLog{}
+	for rows.Next() {
+		executionLog := models.JobExecutionLog{}
+		var lastExecutionTime, nextExecutionTime, dateCreated string
+		scanErr := rows.Scan(
+			&executionLog.Id,
+			&executionLog.UniqueId,
+			&executionLog.State,
+			&executionLog.NodeId,
+			&lastExecutionTime,
+			&nextExecutionTime,
+			&executionLog.JobId,
+			&dateCreated,
+			&executionLog.JobQueueVersion,
+			&executionLog.ExecutionVersion,
+			&executionLog.Error,
+			&executionLog.LatencyMs,
+			&executionLog.Committed,
+			&executionLog.AttemptCount,
+		)
+		if scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		// The union of the committed and uncommitted tables loses the column types, so the times are parsed here
+		for _, datetime := range []struct {
+			value  string
+			target *time.Time
+		}{
+			{lastExecutionTime, &executionLog.LastExecutionDatetime},
+			{nextExecutionTime, &executionLog.NextExecutionDatetime},
+			{dateCreated, &executionLog.DataCreated},
+		} {
+			parsed, parseErr := parseExecutionLogTime(datetime.value)
+			if parseErr != nil {
+				return nil, utils.HTTPGenericError(http.StatusInternalServerError, parseErr.Error())
+			}
+			*datetime.target = parsed
+		}
+		executionLogs = append(executionLogs, executionLog)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return executionLogs, nil
+}
+
+// CountExecutionLogsByFilter returns the number of execution logs matching the filter
+func (repo *executionsRepo) CountExecutionLogsByFilter(filter models.JobExecutionLogFilter) (uint64, *utils.GenericError) {
+	repo.fsmStore.GetDataStore().ConnectionLock()
+	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	query, params := executionLogsQuery(filter)
+
+	var count uint64
+	err := repo.fsmStore.GetDataStore().GetOpenConnection().QueryRow(fmt.Sprintf("SELECT count(*) FROM (%s)", query), params...).Scan(&count)
+	if err != nil {
+		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+
+	return count, nil
+}
+
+// executionLogsQuery returns the query selecting the execution logs matching the filter. Uncommitted logs
+// that were already committed through raft are left out. The attempt count of a log is the number of
+// times its execution was run, which is counted before the state, node and time filters are applied.
+func executionLogsQuery(filter models.JobExecutionLogFilter) (string, []interface{}) {
+	columns := []string{
+		ExecutionsIdColumn,
+		ExecutionsUniqueIdColumn,
+		ExecutionsStateColumn,
+		ExecutionsNodeIdColumn,
+		ExecutionsLastExecutionTimeColumn,
+		ExecutionsNextExecutionTime,
+		ExecutionsJobIdColumn,
+		ExecutionsDateCreatedColumn,
+		ExecutionsJobQueueVersion,
+		ExecutionsVersion,
+		fmt.Sprintf("IFNULL(%s, '') AS %s", ExecutionsErrorColumn, ExecutionsErrorColumn),
+		fmt.Sprintf("IFNULL(%s, 0) AS %s", ExecutionsLatencyMsColumn, ExecutionsLatencyMsColumn),
+	}
+
+	jobConditions := []string{"1 = 1"}
+	params := []interface{}{}
+	if filter.JobId > 0 {
+		jobConditions = append(jobConditions, fmt.Sprintf("%s = ?", ExecutionsJobIdColumn))
+		params = append(params, filter.JobId)
+	}
+	if filter.ProjectId > 0 {
+		jobConditions = append(jobConditions, fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s = ?)",
+			ExecutionsJobIdColumn,
+			constants.JobsIdColumn,
+			constants.JobsTableName,
+			constants.JobsProjectIdColumn,
+		))
+		params = append(params, filter.ProjectId)
+	}
+	jobCondition := strings.Join(jobConditions, " AND ")
+
+	executionLogs := fmt.Sprintf("SELECT %s, 1 AS committed FROM %s WHERE %s "+
+		"UNION ALL SELECT %s, 0 AS committed FROM %s AS uncommitted WHERE %s AND NOT EXISTS ("+
+		"SELECT 1 FROM %s AS committed WHERE committed.%s = uncommitted.%s AND committed.%s IS uncommitted.%s AND committed.%s = uncommitted.%s "+
+		"AND committed.%s = uncommitted.%s AND committed.%s = uncommitted.%s AND committed.%s = uncommitted.%s)",
+		strings.Join(columns, ", "),
+		ExecutionsCommittedTableName,
+		jobCondition,
+		strings.Join(columns, ", "),
+		ExecutionsUnCommittedTableName,
+		jobCondition,
+		ExecutionsCommittedTableName,
+		ExecutionsJobIdColumn, ExecutionsJobIdColumn,
+		ExecutionsUniqueIdColumn, ExecutionsUniqueIdColumn,
+		ExecutionsStateColumn, ExecutionsStateColumn,
+		ExecutionsNodeIdColumn, ExecutionsNodeIdColumn,
+		ExecutionsVersion, ExecutionsVersion,
+		ExecutionsJobQueueVersion, ExecutionsJobQueueVersion,
+	)
+	params = append(params, params...)
+
+	query := fmt.Sprintf("SELECT *, count(CASE WHEN %s != %d THEN 1 END) OVER (PARTITION BY %s, %s) AS attempt_count FROM (%s)",
+		ExecutionsStateColumn,
+		models.ExecutionLogScheduleState,
+		ExecutionsJobIdColumn,
+		ExecutionsVersion,
+		executionLogs,
+	)
+
+	conditions := []string{"1 = 1"}
+	if filter.State != nil {
+		conditions = append(conditions, fmt.Sprintf("%s = ?", ExecutionsStateColumn))
+		params = append(params, *filter.State)
+	}
+	if filter.NodeId > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s = ?", ExecutionsNodeIdColumn))
+		params = append(params, filter.NodeId)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("julianday(%s) >= julianday(?)", ExecutionsDateCreatedColumn))
+		params = append(params, filter.From.UTC().Format(time.RFC3339Nano))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("julianday(%s) <= julianday(?)", ExecutionsDateCreatedColumn))
+		params = append(params, filter.To.UTC().Format(time.RFC3339Nano))
+	}
+
+	return fmt.Sprintf("SELECT * FROM (%s) WHERE %s", query, strings.Join(conditions, " AND ")), params
+}
+
+// executionLogTimeLayouts are the layouts of times written by the sqlite driver and by raft commands
+var executionLogTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+}
+
+func parseExecutionLogTime(value string) (time.Time, error) {
+	var parseErr error
+	for _, layout := range executionLogTimeLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+		parseErr = err
+	}
+	return time.Time{}, parseErr
+}
+
+// executionErrorParam stores logs without an error as NULL
+func executionErrorParam(executionError string) interface{} {
+	if executionError == "" {
+		return nil
+	}
+	return executionError
+}
+
+// executionLatencyParam stores logs without a measured latency as NULL
+func executionLatencyParam(latencyMs uint64) interface{} {
+	if latencyMs == 0 {
+		return nil
+	}
+	return latencyMs
+}
+
+// CompactExecutionLogs deletes the committed execution logs that fall outside the retention policy and returns
+// the number of logs deleted. The deletion goes through raft so every node removes the same logs, together with the
+// copies still in its uncommitted table. Logs of the current execution version of a job are always kept because
+// the executor resumes schedules and counts retries from them.
+func (repo *executionsRepo) CompactExecutionLogs(policy models.ExecutionLogRetentionPolicy, now time.Time) (uint64, *utils.GenericError) {
+	compactedIdsQuery, compactedIdsParams := compactedExecutionLogIdsQuery(policy, now)
+	if compactedIdsQuery == "" {
+		return 0, nil
+	}
+
+	var statements []string
+	var params []interface{}
+
+	if policy.DailyRollup {
+		statements = append(statements, fmt.Sprintf(
+			"INSERT INTO %s (%s, %s, %s, %s) SELECT %s, date(%s), %s, count(*) FROM %s WHERE %s IN (%s) GROUP BY %s, date(%s), %s ON CONFLICT (%s, %s, %s) DO UPDATE SET %s = %s + excluded.%s",
+			ExecutionDailyCountsTableName,
+			ExecutionDailyCountsJobIdColumn,
+			ExecutionDailyCountsDayColumn,
+			ExecutionDailyCountsStateColumn,
+			ExecutionDailyCountsCountColumn,
+			ExecutionsJobIdColumn,
+			ExecutionsDateCreatedColumn,
+			ExecutionsStateColumn,
+			ExecutionsCommittedTableName,
+			ExecutionsIdColumn,
+			compactedIdsQuery,
+			ExecutionsJobIdColumn,
+			ExecutionsDateCreatedColumn,
+			ExecutionsStateColumn,
+			ExecutionDailyCountsJobIdColumn,
+			ExecutionDailyCountsDayColumn,
+			ExecutionDailyCountsStateColumn,
+			ExecutionDailyCountsCountColumn,
+			ExecutionDailyCountsCountColumn,
+			ExecutionDailyCountsCountColumn,
+		))
+		params = append(params, compactedIdsParams...)
+	}
+
+	matchingColumns := strings.Join([]string{
+		ExecutionsJobIdColumn,
+		ExecutionsUniqueIdColumn,
+		ExecutionsStateColumn,
+		ExecutionsNodeIdColumn,
+		ExecutionsVersion,
+		ExecutionsJobQueueVersion,
+	}, ", ")
+	statements = append(statements, fmt.Sprintf(
+		"DELETE FROM %s WHERE (%s) IN (SELECT %s FROM %s WHERE %s IN (%s))",
+		ExecutionsUnCommittedTableName,
+		matchingColumns,
+		matchingColumns,
+		ExecutionsCommittedTableName,
+		ExecutionsIdColumn,
+		compactedIdsQuery,
+	))
+	params = append(params, compactedIdsParams...)
+
+	statements = append(statements, fmt.Sprintf(
+		"DELETE FROM %s WHERE %s IN (%s)",
+		ExecutionsCommittedTableName,
+		ExecutionsIdColumn,
+		compactedIdsQuery,
+	))
+	params = append(params, compactedIdsParams...)
+
+	res, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(
+		context.Background(),
+		repo.fsmStore.GetRaft(),
+		constants.CommandTypeDbExecute,
+		strings.Join(statements, "; ")+";",
+		params,
+		nil,
+		0,
+	)
+	if applyErr != nil {
+		return 0, applyErr
+	}
+	if res == nil {
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	}
+
+	return uint64(res.Data.RowsAffected), nil
+}
+
+// GetExecutionDailyCounts returns the daily counts kept for the compacted execution logs of a job
+func (repo *executionsRepo) GetExecutionDailyCounts(jobId uint64) ([]models.JobExecutionDailyCount, *utils.GenericError) {
+	repo.fsmStore.GetDataStore().ConnectionLock()
+	defer repo.fsmStore.GetDataStore().ConnectionUnlock()
+
+	selectBuilder := sq.Select(
+		ExecutionDailyCountsJobIdColumn,
+		ExecutionDailyCountsDayColumn,
+		ExecutionDailyCountsStateColumn,
+		ExecutionDailyCountsCountColumn,
+	).
+		From(ExecutionDailyCountsTableName).
+		Where(fmt.Sprintf("%s = ?", ExecutionDailyCountsJobIdColumn), jobId).
+		OrderBy(ExecutionDailyCountsDayColumn, ExecutionDailyCountsStateColumn).
+		RunWith(repo.fsmStore.GetDataStore().GetOpenConnection())
+
+	rows, err := selectBuilder.Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	dailyCounts := []models.JobExecutionDailyCount{}
+	for rows.Next() {
+		dailyCount := models.JobExecutionDailyCount{}
+		scanErr := rows.Scan(
+			&dailyCount.JobId,
+			&dailyCount.Day,
+			&dailyCount.State,
+			&dailyCount.Count,
+		)
+		if scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		dailyCounts = append(dailyCounts, dailyCount)
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return dailyCounts, nil
+}
+
+// compactedExecutionLogIdsQuery selects the ids of the committed execution logs outside the retention policy.
+// It returns an empty query when the policy has no limits.
+func compactedExecutionLogIdsQuery(policy models.ExecutionLogRetentionPolicy, now time.Time) (string, []interface{}) {
+	var conditions []string
+	var params []interface{}
+
+	if policy.MaxAge > 0 {
+		conditions = append(conditions, fmt.Sprintf("julianday(%s) < julianday(?)", ExecutionsDateCreatedColumn))
+		params = append(params, now.Add(-policy.MaxAge).UTC().Format(time.RFC3339Nano))
+	}
+	if policy.MaxPerJob > 0 {
+		conditions = append(conditions, "job_rank > ?")
+		params = append(params, policy.MaxPerJob)
+	}
+	for _, state := range []models.JobExecutionLogState{
+		models.ExecutionLogScheduleState,
+		models.ExecutionLogSuccessState,
+		models.ExecutionLogFailedState,
+	} {
+		if limit := policy.MaxPerState[state]; limit > 0 {
+			conditions = append(conditions, fmt.Sprintf("(%s = ? AND state_rank > ?)", ExecutionsStateColumn))
+			params = append(params, state, limit)
+		}
+	}
+
+	if len(conditions) < 1 {
+		return "", nil
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM (SELECT %s, %s, %s, %s, "+
+			"row_number() OVER (PARTITION BY %s ORDER BY julianday(%s) DESC, %s DESC) AS job_rank, "+
+			"row_number() OVER (PARTITION BY %s, %s ORDER BY julianday(%s) DESC, %s DESC) AS state_rank, "+
+			"max(%s) OVER (PARTITION BY %s) AS current_execution_version "+
+			"FROM %s) WHERE %s < current_execution_version AND (%s)",
+		ExecutionsIdColumn,
+		ExecutionsIdColumn,
+		ExecutionsStateColumn,
+		ExecutionsDateCreatedColumn,
+		ExecutionsVersion,
+		ExecutionsJobIdColumn,
+		ExecutionsDateCreatedColumn,
+		ExecutionsIdColumn,
+		ExecutionsJobIdColumn,
+		ExecutionsStateColumn,
+		ExecutionsDateCreatedColumn,
+		ExecutionsIdColumn,
+		ExecutionsVersion,
+		ExecutionsJobIdColumn,
+		ExecutionsCommittedTableName,
+		ExecutionsVersion,
+		strings.Join(conditions, " OR "),
+	)
+
+	return query, params
+}
@@ -1,6 +1,7 @@
 package job_execution
 
 import (
+	"context"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
@@ -91,7 +92,7 @@ func Test_JobExecutionsRepo_BatchInsert(t *testing.T) {
 		t.Fatal("failed to create project:", pcreateErr)
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -105,7 +106,7 @@ func Test_JobExecutionsRepo_BatchInsert(t *testing.T) {
 	}
 
 	// Call the BatchInsert method
-	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions)
+	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions, models.JobExecutionResult{})
 
 	// Retrieve the inserted job execution logs from the database
 	query := fmt.Sprintf("SELECT * FROM %s;", ExecutionsUnCommittedTableName)
@@ -202,7 +203,7 @@ func Test_JobExecutionsRepo_GetLastExecutionLogForJobIds(t *testing.T) {
 		},
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -216,7 +217,7 @@ func Test_JobExecutionsRepo_GetLastExecutionLogForJobIds(t *testing.T) {
 	}
 
 	// Call the BatchInsert method
-	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions)
+	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions, models.JobExecutionResult{})
 
 	// Call the GetLastExecutionLogForJobIds method
 	jobExecutionLogs := jobExecutionsRepo.GetLastExecutionLogForJobIds([]uint64{1, 2})
@@ -304,7 +305,7 @@ func Test_JobExecutionsRepo_CountLastFailedExecutionLogs(t *testing.T) {
 		},
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -318,7 +319,7 @@ func Test_JobExecutionsRepo_CountLastFailedExecutionLogs(t *testing.T) {
 	}
 
 	// Call the BatchInsert method
-	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions)
+	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions, models.JobExecutionResult{})
 
 	// Call the CountLastFailedExecutionLogs method
 	count := jobExecutionsRepo.CountLastFailedExecutionLogs(jobs[0].ID, nodeID, 1)
@@ -400,7 +401,7 @@ func Test_JobExecutionsRepo_CountExecutionLogs(t *testing.T) {
 		},
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -414,7 +415,7 @@ func Test_JobExecutionsRepo_CountExecutionLogs(t *testing.T) {
 	}
 
 	// Call the BatchInsert method
-	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions)
+	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions, models.JobExecutionResult{})
 
 	// Call the CountExecutionLogs method for uncommitted execution logs
 	uncommittedCount := jobExecutionsRepo.CountExecutionLogs(false)
@@ -494,7 +495,7 @@ func Test_JobExecutionsRepo_GetUncommittedExecutionsLogForNode(t *testing.T) {
 		},
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -508,7 +509,7 @@ func Test_JobExecutionsRepo_GetUncommittedExecutionsLogForNode(t *testing.T) {
 	}
 
 	// Call the BatchInsert method
-	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions)
+	jobExecutionsRepo.BatchInsert(jobs, nodeID, state, jobQueueVersion, jobExecutionVersions, models.JobExecutionResult{})
 
 	// Call the GetUncommittedExecutionsLogForNode method
 	executionLogs := jobExecutionsRepo.GetUncommittedExecutionsLogForNode(nodeID)
@@ -596,7 +597,7 @@ func Test_LogJobExecutionStateInRaft(t *testing.T) {
 		},
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -609,7 +610,7 @@ func Test_LogJobExecutionStateInRaft(t *testing.T) {
 		2: 2,
 	}
 
-	jobExecutionsRepo.LogJobExecutionStateInRaft(jobs, state, jobExecutionVersions, jobQueueVersion, nodeID)
+	jobExecutionsRepo.LogJobExecutionStateInRaft(jobs, state, jobExecutionVersions, jobQueueVersion, nodeID, models.JobExecutionResult{})
 
 	// Call the GetLastExecutionLogForJobIds method
 	jobExecutionLogs := jobExecutionsRepo.GetLastExecutionLogForJobIds([]uint64{1, 2})
@@ -697,7 +698,7 @@ func Test_RaftInsertExecutionLogs(t *testing.T) {
 		},
 	}
 
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatal("failed to insert job", insertErr)
 	}
@@ -746,3 +747,268 @@ func Test_RaftInsertExecutionLogs(t *testing.T) {
 		assert.Contains(t, []uint64{1, 2}, log.JobId)
 	}
 }
+
+func Test_JobExecutionsRepo_ListExecutionLogs(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-executions-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobExecutionsRepo := NewExecutionsRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	project := models.Project{
+		ID:          1,
+		Name:        "Test Project",
+		Description: "Test project description",
+	}
+	_, pcreateErr := projectRepo.CreateOne(&project)
+	if pcreateErr != nil {
+		t.Fatal("failed to create project:", pcreateErr)
+	}
+
+	jobs := []models.Job{
+		{
+			ID:                1,
+			ExecutionId:       "1",
+			Spec:              "*/5 * * * *",
+			ProjectID:         1,
+			Timezone:          "UTC",
+			LastExecutionDate: time.Now().Add(-time.Hour),
+		},
+		{
+			ID:                2,
+			ExecutionId:       "2",
+			Spec:              "0 0 * * *",
+			ProjectID:         1,
+			Timezone:          "UTC",
+			LastExecutionDate: time.Now().Add(-2 * time.Hour),
+		},
+	}
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if insertErr != nil {
+		t.Fatal("failed to insert job", insertErr)
+	}
+
+	executionVersions := map[uint64]uint64{1: 1, 2: 1}
+
+	// The first job is scheduled and fails twice, and every log is committed
+	for _, state := range []models.JobExecutionLogState{models.ExecutionLogScheduleState, models.ExecutionLogFailedState, models.ExecutionLogFailedState} {
+		result := models.JobExecutionResult{}
+		if state == models.ExecutionLogFailedState {
+			result.Error = "subscriber failed to fully requests status code: 500"
+		}
+		jobExecutionsRepo.BatchInsert(jobs[:1], 1, state, 1, executionVersions, result)
+		jobExecutionsRepo.LogJobExecutionStateInRaft(jobs[:1], state, executionVersions, 1, 1, result)
+	}
+
+	// The second job succeeds on another node that has not committed the log yet
+	jobExecutionsRepo.BatchInsert(jobs[1:], 2, models.ExecutionLogSuccessState, 1, executionVersions, models.JobExecutionResult{})
+
+	allLogs, listErr := jobExecutionsRepo.ListExecutionLogs(models.JobExecutionLogFilter{Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list execution logs", listErr)
+	}
+	assert.Equal(t, 4, len(allLogs))
+	total, countErr := jobExecutionsRepo.CountExecutionLogsByFilter(models.JobExecutionLogFilter{})
+	if countErr != nil {
+		t.Fatal("failed to count execution logs", countErr)
+	}
+	assert.Equal(t, uint64(4), total)
+
+	failedState := models.ExecutionLogFailedState
+	failedLogs, listErr := jobExecutionsRepo.ListExecutionLogs(models.JobExecutionLogFilter{JobId: 1, State: &failedState, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list execution logs", listErr)
+	}
+	assert.Equal(t, 2, len(failedLogs))
+	for _, failedLog := range failedLogs {
+		assert.Equal(t, uint64(1), failedLog.JobId)
+		assert.Equal(t, uint64(2), failedLog.AttemptCount)
+		assert.Equal(t, true, failedLog.Committed)
+		assert.Equal(t, "subscriber failed to fully requests status code: 500", failedLog.Error)
+		assert.False(t, failedLog.DataCreated.IsZero())
+	}
+
+	nodeLogs, listErr := jobExecutionsRepo.ListExecutionLogs(models.JobExecutionLogFilter{NodeId: 2, ProjectId: 1, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list execution logs", listErr)
+	}
+	assert.Equal(t, 1, len(nodeLogs))
+	assert.Equal(t, uint64(2), nodeLogs[0].JobId)
+	assert.Equal(t, models.ExecutionLogSuccessState, nodeLogs[0].State)
+	assert.Equal(t, uint64(1), nodeLogs[0].AttemptCount)
+	assert.Equal(t, false, nodeLogs[0].Committed)
+
+	futureLogs, listErr := jobExecutionsRepo.ListExecutionLogs(models.JobExecutionLogFilter{From: time.Now().Add(time.Hour), Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list execution logs", listErr)
+	}
+	assert.Equal(t, 0, len(futureLogs))
+
+	pastLogs, listErr := jobExecutionsRepo.ListExecutionLogs(models.JobExecutionLogFilter{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour), Offset: 1, Limit: 2})
+	if listErr != nil {
+		t.Fatal("failed to list execution logs", listErr)
+	}
+	assert.Equal(t, 2, len(pastLogs))
+}
+
+func Test_JobExecutionsRepo_CompactExecutionLogs(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-executions-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	jobExecutionsRepo := NewExecutionsRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+
+	project := models.Project{
+		ID:          1,
+		Name:        "Test Project",
+		Description: "Test project description",
+	}
+	_, pcreateErr := projectRepo.CreateOne(&project)
+	if pcreateErr != nil {
+		t.Fatal("failed to create project:", pcreateErr)
+	}
+
+	jobs := []models.Job{
+		{
+			ID:                1,
+			ExecutionId:       "1",
+			Spec:              "*/5 * * * *",
+			ProjectID:         1,
+			Timezone:          "UTC",
+			LastExecutionDate: time.Now().Add(-time.Hour),
+		},
+	}
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
+	if insertErr != nil {
+		t.Fatal("failed to insert job", insertErr)
+	}
+
+	// Five execution versions, one day apart, each scheduled and successful
+	now := time.Now()
+	var executionLogs []models.JobExecutionLog
+	for version := uint64(1); version <= 5; version++ {
+		for _, state := range []models.JobExecutionLogState{models.ExecutionLogScheduleState, models.ExecutionLogSuccessState} {
+			executionLogs = append(executionLogs, models.JobExecutionLog{
+				JobId:                 1,
+				UniqueId:              "1",
+				State:                 state,
+				NodeId:                1,
+				LastExecutionDatetime: now,
+				NextExecutionDatetime: now,
+				JobQueueVersion:       1,
+				ExecutionVersion:      version,
+				DataCreated:           now.Add(-time.Duration(6-version) * 24 * time.Hour),
+			})
+		}
+	}
+	jobExecutionsRepo.RaftInsertExecutionLogs(executionLogs, 1)
+	jobExecutionsRepo.BatchInsert(jobs, 1, models.ExecutionLogScheduleState, 1, map[uint64]uint64{1: 1}, models.JobExecutionResult{})
+	assert.Equal(t, uint64(10), jobExecutionsRepo.CountExecutionLogs(true))
+	assert.Equal(t, uint64(1), jobExecutionsRepo.CountExecutionLogs(false))
+
+	deleted, compactErr := jobExecutionsRepo.CompactExecutionLogs(models.ExecutionLogRetentionPolicy{}, now)
+	if compactErr != nil {
+		t.Fatal("failed to compact execution logs", compactErr)
+	}
+	assert.Equal(t, uint64(0), deleted)
+
+	deleted, compactErr = jobExecutionsRepo.CompactExecutionLogs(models.ExecutionLogRetentionPolicy{MaxPerJob: 4, DailyRollup: true}, now)
+	if compactErr != nil {
+		t.Fatal("failed to compact execution logs", compactErr)
+	}
+	assert.Equal(t, uint64(6), deleted)
+	assert.Equal(t, uint64(4), jobExecutionsRepo.CountExecutionLogs(true))
+	assert.Equal(t, uint64(0), jobExecutionsRepo.CountExecutionLogs(false))
+
+	dailyCounts, countsErr := jobExecutionsRepo.GetExecutionDailyCounts(1)
+	if countsErr != nil {
+		t.Fatal("failed to get execution daily counts", countsErr)
+	}
+	assert.Equal(t, 6, len(dailyCounts))
+	for _, dailyCount := range dailyCounts {
+		assert.Equal(t, uint64(1), dailyCount.Count)
+	}
+	assert.Equal(t, now.Add(-5*24*time.Hour).UTC().Format("2006-01-02"), dailyCounts[0].Day)
+
+	deleted, compactErr = jobExecutionsRepo.CompactExecutionLogs(models.ExecutionLogRetentionPolicy{
+		MaxPerState: map[models.JobExecutionLogState]uint64{models.ExecutionLogSuccessState: 1},
+	}, now)
+	if compactErr != nil {
+		t.Fatal("failed to compact execution logs", compactErr)
+	}
+	assert.Equal(t, uint64(1), deleted)
+
+	// The logs of the current execution version are older than the max age but are kept
+	deleted, compactErr = jobExecutionsRepo.CompactExecutionLogs(models.ExecutionLogRetentionPolicy{MaxAge: 12 * time.Hour}, now)
+	if compactErr != nil {
+		t.Fatal("failed to compact execution logs", compactErr)
+	}
+	assert.Equal(t, uint64(1), deleted)
+
+	remainingLogs, listErr := jobExecutionsRepo.ListExecutionLogs(models.JobExecutionLogFilter{JobId: 1, Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list execution logs", listErr)
+	}
+	assert.Equal(t, 2, len(remainingLogs))
+	for _, remainingLog := range remainingLogs {
+		assert.Equal(t, uint64(5), remainingLog.ExecutionVersion)
+	}
+}
@@ -1,15 +1,18 @@
 package job_queue
 
 import (
+	"context"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
+	"go.opentelemetry.io/otel/attribute"
 	"log"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/scheduler0time"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"time"
 )
@@ -40,7 +43,7 @@ type JobQueuesRepo interface {
 	GetLastJobQueueLogForNode(nodeId uint64, version uint64) []models.JobQueueLog
 	IncrementQueueVersion(numberOfServer int)
 	GetLastVersion() uint64
-	InsertJobQueueLogs(logs []models.JobQueueLog)
+	InsertJobQueueLogs(ctx context.Context, logs []models.JobQueueLog)
 	GetJobQueueByLastInsertedAndRowsAffected(lastInsertedId, rowsAffected int64) []models.JobQueueLog
 }
 
@@ -105,6 +108,7 @@ func (repo *jobQueues) GetLastJobQueueLogForNode(nodeId uint64, version uint64)
 func (repo *jobQueues) IncrementQueueVersion(numberOfServer int) {
 	lastVersion := repo.getLastVersion()
 	_, err := repo.scheduler0RaftActions.WriteCommandToRaftLog(
+		context.Background(),
 		repo.fsmStore.GetRaft(),
 		constants.CommandTypeDbExecute,
 		fmt.Sprintf("insert into %s (%s, %s) values (?, ?)",
@@ -117,7 +121,10 @@ func (repo *jobQueues) IncrementQueueVersion(numberOfServer int) {
 	}
 }
 
-func (repo *jobQueues) InsertJobQueueLogs(logs []models.JobQueueLog) {
+func (repo *jobQueues) InsertJobQueueLogs(ctx context.Context, logs []models.JobQueueLog) {
+	ctx, span := tracing.StartSpan(ctx, "JobQueuesRepo.InsertJobQueueLogs", attribute.Int("scheduler0.job_queue_logs", len(logs)))
+	defer span.End()
+
 	batches := utils.Batch[models.JobQueueLog](logs, 5)
 
 	schedulerTime := scheduler0time.GetSchedulerTime()
@@ -154,6 +161,7 @@ func (repo *jobQueues) InsertJobQueueLogs(logs []models.JobQueueLog) {
 		query += ";"
 
 		_, applyErr := repo.scheduler0RaftActions.WriteCommandToRaftLog(
+			ctx,
 			repo.fsmStore.GetRaft(),
 			constants.CommandTypeDbExecute,
 			query,
@@ -1,6 +1,7 @@
 package job_queue
 
 import (
+	"context"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
@@ -281,7 +282,7 @@ func Test_InsertJobQueueLogs(t *testing.T) {
 		}
 	}
 
-	jobQueuesRepo.InsertJobQueueLogs([]models.JobQueueLog{{
+	jobQueuesRepo.InsertJobQueueLogs(context.Background(), []models.JobQueueLog{{
 		NodeId:          1,
 		LowerBoundJobId: 1,
 		UpperBoundJobId: 20,
@@ -351,7 +352,7 @@ func Test_GetJobQueueByLastInsertedAndRowsAffected(t *testing.T) {
 		}
 	}
 
-	jobQueuesRepo.InsertJobQueueLogs([]models.JobQueueLog{{
+	jobQueuesRepo.InsertJobQueueLogs(context.Background(), []models.JobQueueLog{{
 		NodeId:          1,
 		LowerBoundJobId: 1,
 		UpperBoundJobId: 20,
@@ -3,6 +3,7 @@
 package job_queue
 
 import (
+	"context"
 	models "scheduler0/pkg/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -64,9 +65,9 @@ func (_m *MockJobQueuesRepo) IncrementQueueVersion(numberOfServer int) {
 	_m.Called(numberOfServer)
 }
 
-// InsertJobQueueLogs provides a mock function with given fields: logs
-func (_m *MockJobQueuesRepo) InsertJobQueueLogs(logs []models.JobQueueLog) {
-	_m.Called(logs)
+// InsertJobQueueLogs provides a mock function with given fields: ctx, logs
+func (_m *MockJobQueuesRepo) InsertJobQueueLogs(ctx context.Context, logs []models.JobQueueLog) {
+	_m.Called(ctx, logs)
 }
 
 type mockConstructorTestingTNewMockJobQueuesRepo interface {
@@ -0,0 +1,228 @@
+// Package pagination reads pages of the lists of the repositories by offset or by cursor.
+// Cursors hold the sort key and id of the last row of a page, the next page starts after them
+// so that rows inserted or deleted while a client pages through a list do not shift the pages.
+package pagination
+
+import (
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"net/http"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	ascending  = "ASC"
+	descending = "DESC"
+)
+
+// DefaultOrderBy and DefaultOrder order the job, project and credential lists read without a sort field or order
+const (
+	DefaultOrderBy = "id"
+	DefaultOrder   = ascending
+)
+
+// SortColumns returns the fields a list can be ordered by, the id and dateCreated fields of every list
+// next to the fields specific to its items
+func SortColumns(idColumn string, dateCreatedColumn string, columns map[string]string) map[string]string {
+	sortColumns := map[string]string{
+		"id":          idColumn,
+		"dateCreated": dateCreatedColumn,
+	}
+	for field, column := range columns {
+		sortColumns[field] = column
+	}
+	return sortColumns
+}
+
+// Plan ordering and position of a page of a table
+type Plan struct {
+	page      models.PageRequest
+	cursor    *models.Cursor
+	table     string
+	idColumn  string
+	orderBy   string
+	column    string
+	direction string
+}
+
+// NewPlan validates the sort field and order of the page against the sort fields of the table.
+// Pages read with a cursor keep the sort field and order of the page that returned the cursor.
+func NewPlan(table string, idColumn string, columns map[string]string, defaultOrderBy string, defaultOrder string, page models.PageRequest) (*Plan, *utils.GenericError) {
+	var cursor *models.Cursor
+	orderBy := page.OrderBy
+	direction := strings.ToUpper(page.Order)
+	if page.Cursor != "" {
+		decoded, decodeErr := models.DecodeCursor(page.Cursor)
+		if decodeErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, decodeErr.Error())
+		}
+		cursor = decoded
+		if (orderBy != "" && orderBy != cursor.OrderBy) || (direction != "" && direction != cursor.Order) {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, "cursor was returned for a different order")
+		}
+		orderBy = cursor.OrderBy
+		direction = cursor.Order
+	}
+
+	if orderBy == "" {
+		orderBy = defaultOrderBy
+	}
+	column, ok := columns[orderBy]
+	if !ok {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("cannot order by %s", orderBy))
+	}
+
+	if direction == "" {
+		direction = defaultOrder
+	}
+	if direction != ascending && direction != descending {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("order must be asc or desc, got %s", page.Order))
+	}
+
+	return &Plan{
+		page:      page,
+		cursor:    cursor,
+		table:     table,
+		idColumn:  idColumn,
+		orderBy:   orderBy,
+		column:    column,
+		direction: direction,
+	}, nil
+}
+
+// Apply orders the select, and positions it after the offset or the cursor of the page.
+// One row past the limit is read to find out whether there is a next page.
+func (plan *Plan) Apply(builder sq.SelectBuilder) sq.SelectBuilder {
+	if plan.cursor != nil {
+		builder = builder.Where(plan.afterCursor(plan.cursor))
+	} else if plan.page.Offset > 0 {
+		builder = builder.Offset(plan.page.Offset)
+	}
+
+	// Pages before a cursor are read backwards from the cursor
+	direction := plan.direction
+	if plan.backwards() {
+		direction = reverse(direction)
+	}
+
+	orderBy := []string{fmt.Sprintf("%s %s", plan.column, direction)}
+	if plan.column != plan.idColumn {
+		orderBy = append(orderBy, fmt.Sprintf("%s %s", plan.idColumn, direction))
+	}
+
+	return builder.OrderBy(orderBy...).Limit(plan.page.Limit + 1)
+}
+
+func (plan *Plan) afterCursor(cursor *models.Cursor) sq.Sqlizer {
+	comparison := ">"
+	if (plan.direction == descending) != cursor.Before {
+		comparison = "<"
+	}
+
+	if plan.column == plan.idColumn {
+		return sq.Expr(fmt.Sprintf("%s %s ?", plan.idColumn, comparison), cursor.ID)
+	}
+
+	return sq.Expr(
+		fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", plan.column, comparison, plan.column, plan.idColumn, comparison),
+		cursor.Key, cursor.Key, cursor.ID,
+	)
+}
+
+func (plan *Plan) backwards() bool {
+	return plan.cursor != nil && plan.cursor.Before
+}
+
+// Page trims the row read past the limit, restores the order of a page read backwards and returns
+// the cursors of the pages around it. Runner must see the rows of the page, it reads their sort keys.
+func Page[T any](plan *Plan, runner sq.BaseRunner, rows []T, id func(T) uint64) ([]T, models.PageCursors, *utils.GenericError) {
+	cursors := models.PageCursors{}
+
+	more := uint64(len(rows)) > plan.page.Limit
+	if more {
+		rows = rows[:plan.page.Limit]
+	}
+	if len(rows) == 0 {
+		return rows, cursors, nil
+	}
+
+	hasNext := more
+	hasPrev := plan.cursor != nil || plan.page.Offset > 0
+	if plan.backwards() {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+		hasNext = true
+		hasPrev = more
+	}
+
+	firstId, lastId := id(rows[0]), id(rows[len(rows)-1])
+	keys, err := plan.sortKeys(runner, firstId, lastId)
+	if err != nil {
+		return nil, cursors, err
+	}
+
+	if hasNext {
+		cursors.Next = models.EncodeCursor(plan.cursorAt(keys[lastId], lastId, false))
+	}
+	if hasPrev {
+		cursors.Prev = models.EncodeCursor(plan.cursorAt(keys[firstId], firstId, true))
+	}
+
+	return rows, cursors, nil
+}
+
+func (plan *Plan) cursorAt(key string, id uint64, before bool) models.Cursor {
+	return models.Cursor{
+		OrderBy: plan.orderBy,
+		Order:   plan.direction,
+		Key:     key,
+		ID:      id,
+		Before:  before,
+	}
+}
+
+// sortKeys reads the sort keys of the rows as they are stored, the values compare to the column like the stored values
+func (plan *Plan) sortKeys(runner sq.BaseRunner, ids ...uint64) (map[uint64]string, *utils.GenericError) {
+	keys := map[uint64]string{}
+	if plan.column == plan.idColumn {
+		for _, id := range ids {
+			keys[id] = strconv.FormatUint(id, 10)
+		}
+		return keys, nil
+	}
+
+	rows, err := sq.Select(plan.idColumn, fmt.Sprintf("CAST(%s AS TEXT)", plan.column)).
+		From(plan.table).
+		Where(sq.Eq{plan.idColumn: ids}).
+		RunWith(runner).
+		Query()
+	if err != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		var key string
+		if scanErr := rows.Scan(&id, &key); scanErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, scanErr.Error())
+		}
+		keys[id] = key
+	}
+	if rows.Err() != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+	}
+
+	return keys, nil
+}
+
+func reverse(direction string) string {
+	if direction == ascending {
+		return descending
+	}
+	return ascending
+}
@@ -1,6 +1,7 @@
 package project
 
 import (
+	"context"
 	_ "errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
@@ -9,7 +10,10 @@ import (
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
+	"scheduler0/pkg/repository/audit"
 	job_repo "scheduler0/pkg/repository/job"
+	"scheduler0/pkg/repository/pagination"
+	"scheduler0/pkg/repository/version"
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/utils"
 	"time"
@@ -20,7 +24,7 @@ type ProjectRepo interface {
 	CreateOne(project *models.Project) (uint64, *utils.GenericError)
 	GetOneByName(project *models.Project) *utils.GenericError
 	GetOneByID(project *models.Project) *utils.GenericError
-	List(offset uint64, limit uint64) ([]models.Project, *utils.GenericError)
+	List(page models.PageRequest) ([]models.Project, models.PageCursors, *utils.GenericError)
 	Count() (uint64, *utils.GenericError)
 	UpdateOneByID(project models.Project) (uint64, *utils.GenericError)
 	DeleteOneByID(project models.Project) (uint64, *utils.GenericError)
@@ -65,7 +69,7 @@ func (projectRepo *projectRepo) CreateOne(project *models.Project) (uint64, *uti
 	schedulerTime := scheduler0time.GetSchedulerTime()
 	now := schedulerTime.GetTime(time.Now())
 
-	query, params, err := sq.Insert(constants.ProjectsTableName).
+	insertSql, insertParams, err := sq.Insert(constants.ProjectsTableName).
 		Columns(
 			constants.ProjectsNameColumn,
 			constants.ProjectsDescriptionColumn,
@@ -79,8 +83,11 @@ func (projectRepo *projectRepo) CreateOne(project *models.Project) (uint64, *uti
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
+	query, params := audit.ContextSQL(project.Actor, "")
+	query += insertSql
+	params = append(params, insertParams...)
 
-	res, applyErr := projectRepo.scheduler0RaftActions.WriteCommandToRaftLog(projectRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	res, applyErr := projectRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), projectRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
 	if applyErr != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, applyErr.Error())
 	}
@@ -110,6 +117,7 @@ func (projectRepo *projectRepo) GetOneByName(project *models.Project) *utils.Gen
 		constants.ProjectsNameColumn,
 		constants.ProjectsDescriptionColumn,
 		constants.ProjectsDateCreatedColumn,
+		constants.ProjectsVersionColumn,
 	).
 		From(constants.ProjectsTableName).
 		Where(fmt.Sprintf("%s = ?", constants.ProjectsNameColumn), project.Name).
@@ -127,6 +135,7 @@ func (projectRepo *projectRepo) GetOneByName(project *models.Project) *utils.Gen
 			&project.Name,
 			&project.Description,
 			&project.DateCreated,
+			&project.Version,
 		)
 		if err != nil {
 			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
@@ -153,6 +162,7 @@ func (projectRepo *projectRepo) GetOneByID(project *models.Project) *utils.Gener
 		constants.ProjectsNameColumn,
 		constants.ProjectsDescriptionColumn,
 		constants.ProjectsDateCreatedColumn,
+		constants.ProjectsVersionColumn,
 	).
 		From(constants.ProjectsTableName).
 		Where(fmt.Sprintf("%s = ?", constants.ProjectsIdColumn), project.ID).
@@ -170,6 +180,7 @@ func (projectRepo *projectRepo) GetOneByID(project *models.Project) *utils.Gener
 			&project.Name,
 			&project.Description,
 			&project.DateCreated,
+			&project.Version,
 		)
 		if err != nil {
 			return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
@@ -222,6 +233,7 @@ func (projectRepo *projectRepo) GetBatchProjectsByIDs(projectIds []uint64) ([]mo
 		constants.ProjectsNameColumn,
 		constants.ProjectsDescriptionColumn,
 		constants.ProjectsDateCreatedColumn,
+		constants.ProjectsVersionColumn,
 	).
 		From(constants.ProjectsTableName).
 		Where(fmt.Sprintf("%s in (%s)", constants.ProjectsIdColumn, idParams), projectIdsArgs...).
@@ -241,6 +253,7 @@ func (projectRepo *projectRepo) GetBatchProjectsByIDs(projectIds []uint64) ([]mo
 			&project.Name,
 			&project.Description,
 			&project.DateCreated,
+			&project.Version,
 		)
 		if err != nil {
 			return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
@@ -255,8 +268,13 @@ func (projectRepo *projectRepo) GetBatchProjectsByIDs(projectIds []uint64) ([]mo
 	return projects, nil
 }
 
-// List returns a paginated set of results
-func (projectRepo *projectRepo) List(offset uint64, limit uint64) ([]models.Project, *utils.GenericError) {
+// List returns a page of projects, and the cursors of the pages around it
+func (projectRepo *projectRepo) List(page models.PageRequest) ([]models.Project, models.PageCursors, *utils.GenericError) {
+	plan, planErr := pagination.NewPlan(constants.ProjectsTableName, constants.ProjectsIdColumn, projectSortColumns, pagination.DefaultOrderBy, pagination.DefaultOrder, page)
+	if planErr != nil {
+		return nil, models.PageCursors{}, planErr
+	}
+
 	projectRepo.fsmStore.GetDataStore().ConnectionLock()
 	defer projectRepo.fsmStore.GetDataStore().ConnectionUnlock()
 
@@ -265,18 +283,18 @@ func (projectRepo *projectRepo) List(offset uint64, limit uint64) ([]models.Proj
 		constants.ProjectsNameColumn,
 		constants.ProjectsDescriptionColumn,
 		constants.ProjectsDateCreatedColumn,
+		constants.ProjectsVersionColumn,
 	).
-		From(constants.ProjectsTableName).
-		Offset(offset).
-		Limit(limit).
-		RunWith(projectRepo.fsmStore.GetDataStore().GetOpenConnection())
+		From(constants.ProjectsTableName)
 
 	projects := []models.Project{}
-	rows, err := selectBuilder.Query()
-	defer rows.Close()
+	rows, err := plan.Apply(selectBuilder).
+		RunWith(projectRepo.fsmStore.GetDataStore().GetOpenConnection()).
+		Query()
 	if err != nil {
-		return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
+	defer rows.Close()
 	for rows.Next() {
 		project := models.Project{}
 		err = rows.Scan(
@@ -284,19 +302,26 @@ func (projectRepo *projectRepo) List(offset uint64, limit uint64) ([]models.Proj
 			&project.Name,
 			&project.Description,
 			&project.DateCreated,
+			&project.Version,
 		)
 		if err != nil {
-			return nil, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
+			return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 		}
 		projects = append(projects, project)
 	}
 	if rows.Err() != nil {
-		return nil, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
+		return nil, models.PageCursors{}, utils.HTTPGenericError(http.StatusInternalServerError, rows.Err().Error())
 	}
+	rows.Close()
 
-	return projects, nil
+	return pagination.Page(plan, projectRepo.fsmStore.GetDataStore().GetOpenConnection(), projects, func(project models.Project) uint64 { return project.ID })
 }
 
+// projectSortColumns the fields projects can be ordered by
+var projectSortColumns = pagination.SortColumns(constants.ProjectsIdColumn, constants.ProjectsDateCreatedColumn, map[string]string{
+	"name": constants.ProjectsNameColumn,
+})
+
 // Count return the number of projects
 func (projectRepo *projectRepo) Count() (uint64, *utils.GenericError) {
 	projectRepo.fsmStore.GetDataStore().ConnectionLock()
@@ -328,22 +353,32 @@ func (projectRepo *projectRepo) Count() (uint64, *utils.GenericError) {
 func (projectRepo *projectRepo) UpdateOneByID(project models.Project) (uint64, *utils.GenericError) {
 	updateQuery := sq.Update(constants.ProjectsTableName).
 		Set(constants.ProjectsDescriptionColumn, project.Description).
-		Where(fmt.Sprintf("%s = ?", constants.ProjectsIdColumn), project.ID)
+		Set(constants.ProjectsVersionColumn, version.Next(constants.ProjectsVersionColumn)).
+		Where(version.Condition(constants.ProjectsIdColumn, constants.ProjectsVersionColumn, project.ID, project.Version))
 
-	query, params, err := updateQuery.ToSql()
+	updateSql, updateParams, err := updateQuery.ToSql()
 	if err != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 	}
 
-	res, applyErr := projectRepo.scheduler0RaftActions.WriteCommandToRaftLog(projectRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	query, params := audit.ContextSQL(project.Actor, "")
+	query += updateSql
+	params = append(params, updateParams...)
+
+	res, applyErr := projectRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), projectRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
 	if applyErr != nil {
-		return 0, utils.HTTPGenericError(http.StatusInternalServerError, applyErr.Error())
+		return 0, applyErr
 	}
 	if res == nil {
 		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
 	}
 
 	count := res.Data.RowsAffected
+	if count == 0 && project.Version != 0 {
+		if getErr := projectRepo.GetOneByID(&models.Project{ID: project.ID}); getErr == nil {
+			return 0, version.PreconditionFailed(project.Version)
+		}
+	}
 
 	return uint64(count), nil
 }
@@ -361,14 +396,18 @@ func (projectRepo *projectRepo) DeleteOneByID(project models.Project) (uint64, *
 
 	deleteQuery := sq.
 		Delete(constants.ProjectsTableName).
-		Where(fmt.Sprintf("%s = ?", constants.ProjectsIdColumn), project.ID)
+		Where(version.Condition(constants.ProjectsIdColumn, constants.ProjectsVersionColumn, project.ID, project.Version))
 
-	query, params, deleteErr := deleteQuery.ToSql()
+	deleteSql, deleteParams, deleteErr := deleteQuery.ToSql()
 	if deleteErr != nil {
 		return 0, utils.HTTPGenericError(http.StatusInternalServerError, deleteErr.Error())
 	}
 
-	res, applyErr := projectRepo.scheduler0RaftActions.WriteCommandToRaftLog(projectRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
+	query, params := audit.ContextSQL(project.Actor, "")
+	query += deleteSql
+	params = append(params, deleteParams...)
+
+	res, applyErr := projectRepo.scheduler0RaftActions.WriteCommandToRaftLog(context.Background(), projectRepo.fsmStore.GetRaft(), constants.CommandTypeDbExecute, query, params, []uint64{}, 0)
 	if applyErr != nil {
 		return 0, applyErr
 	}
@@ -377,6 +416,11 @@ func (projectRepo *projectRepo) DeleteOneByID(project models.Project) (uint64, *
 	}
 
 	count := res.Data.RowsAffected
+	if count == 0 && project.Version != 0 {
+		if getErr := projectRepo.GetOneByID(&models.Project{ID: project.ID}); getErr == nil {
+			return 0, version.PreconditionFailed(project.Version)
+		}
+	}
 
 	return uint64(count), nil
 }
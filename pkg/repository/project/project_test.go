@@ -1,10 +1,13 @@
 package project
 
 import (
+	"context"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/db"
@@ -159,7 +162,7 @@ func Test_ProjectRepo_List(t *testing.T) {
 	// Call the List method with offset 1 and limit 2
 	offset := uint64(1)
 	limit := uint64(2)
-	retrievedProjects, listErr := projectRepo.List(offset, limit)
+	retrievedProjects, _, listErr := projectRepo.List(models.PageRequest{Offset: offset, Limit: limit})
 	if listErr != nil {
 		t.Fatal("failed to list projects:", listErr)
 	}
@@ -176,6 +179,134 @@ func Test_ProjectRepo_List(t *testing.T) {
 	assert.Equal(t, "Description 3", retrievedProjects[1].Description)
 }
 
+func Test_ProjectRepo_ListWithCursor(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "project-repo-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	// Create a new ProjectRepo instance
+	projectRepo := NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, nil)
+
+	for i := 1; i <= 5; i++ {
+		_, createErr := projectRepo.CreateOne(&models.Project{
+			Name:        fmt.Sprintf("Project %d", 6-i),
+			Description: fmt.Sprintf("Description %d", i),
+		})
+		if createErr != nil {
+			t.Fatal("failed to create project:", createErr)
+		}
+	}
+
+	ids := func(projects []models.Project) []uint64 {
+		projectIds := []uint64{}
+		for _, project := range projects {
+			projectIds = append(projectIds, project.ID)
+		}
+		return projectIds
+	}
+
+	// Lists read without a sort field or order are ordered by id ascending
+	defaultProjects, _, listErr := projectRepo.List(models.PageRequest{Limit: 10})
+	if listErr != nil {
+		t.Fatal("failed to list projects:", listErr)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, ids(defaultProjects))
+
+	for _, orderBy := range []string{"id", "name", "dateCreated"} {
+		for _, order := range []string{"ASC", "DESC"} {
+			allProjects, _, listErr := projectRepo.List(models.PageRequest{Limit: 10, OrderBy: orderBy, Order: order})
+			if listErr != nil {
+				t.Fatal("failed to list projects:", listErr)
+			}
+			assert.Equal(t, 5, len(allProjects))
+
+			// Page forward with the next cursors
+			forward := []uint64{}
+			page := models.PageRequest{Limit: 2, OrderBy: orderBy, Order: order}
+			var cursors models.PageCursors
+			for {
+				projects, pageCursors, listErr := projectRepo.List(page)
+				if listErr != nil {
+					t.Fatal("failed to list projects:", listErr)
+				}
+				forward = append(forward, ids(projects)...)
+				cursors = pageCursors
+				if cursors.Next == "" {
+					break
+				}
+				page = models.PageRequest{Limit: 2, Cursor: cursors.Next}
+			}
+			assert.Equal(t, ids(allProjects), forward, "%s %s", orderBy, order)
+
+			// Page back from the last page with the prev cursors
+			backward := []uint64{}
+			for cursors.Prev != "" {
+				projects, pageCursors, listErr := projectRepo.List(models.PageRequest{Limit: 2, Cursor: cursors.Prev})
+				if listErr != nil {
+					t.Fatal("failed to list projects:", listErr)
+				}
+				assert.NotEmpty(t, pageCursors.Next)
+				backward = append(ids(projects), backward...)
+				cursors = pageCursors
+			}
+			assert.Equal(t, ids(allProjects)[:len(backward)], backward, "%s %s", orderBy, order)
+			assert.Equal(t, 4, len(backward))
+		}
+	}
+
+	// Projects created while paging do not shift the next page
+	firstPage, cursors, listErr := projectRepo.List(models.PageRequest{Limit: 2, OrderBy: "name", Order: "ASC"})
+	if listErr != nil {
+		t.Fatal("failed to list projects:", listErr)
+	}
+	_, createErr := projectRepo.CreateOne(&models.Project{Name: "Project 0", Description: "Description 0"})
+	if createErr != nil {
+		t.Fatal("failed to create project:", createErr)
+	}
+	secondPage, _, listErr := projectRepo.List(models.PageRequest{Limit: 2, Cursor: cursors.Next})
+	if listErr != nil {
+		t.Fatal("failed to list projects:", listErr)
+	}
+	assert.Equal(t, "Project 1", firstPage[0].Name)
+	assert.Equal(t, "Project 3", secondPage[0].Name)
+
+	// A cursor only pages the order it was returned with
+	_, _, orderErr := projectRepo.List(models.PageRequest{Limit: 2, Cursor: cursors.Next, OrderBy: "id"})
+	assert.NotNil(t, orderErr)
+	assert.Equal(t, http.StatusBadRequest, orderErr.Type)
+
+	_, _, cursorErr := projectRepo.List(models.PageRequest{Limit: 2, Cursor: "not-a-cursor"})
+	assert.NotNil(t, cursorErr)
+	assert.Equal(t, http.StatusBadRequest, cursorErr.Type)
+}
+
 func Test_ProjectRepo_UpdateOneByID(t *testing.T) {
 	scheduler0config := config.NewScheduler0Config()
 	logger := hclog.New(&hclog.LoggerOptions{
@@ -306,7 +437,7 @@ func Test_ProjectRepo_DeleteOneByID(t *testing.T) {
 	}
 
 	// Insert the job into the database
-	_, insertErr := jobRepo.BatchInsertJobs([]models.Job{job})
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{job})
 	if insertErr != nil {
 		t.Fatal("failed to insert job:", insertErr)
 	}
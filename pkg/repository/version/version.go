@@ -0,0 +1,39 @@
+// Package version makes updates and deletes of jobs, projects and credentials conditional on the version of the row.
+// The version is a condition of the statements applied by raft, so every node applies or skips the change the same way.
+// A change that affects no rows although the row exists was made against another version of the row.
+package version
+
+import (
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"net/http"
+	"scheduler0/pkg/utils"
+)
+
+// Next is the value of the version column of an updated row
+func Next(column string) sq.Sqlizer {
+	return sq.Expr(fmt.Sprintf("%s + 1", column))
+}
+
+// Condition matches the row with the id when it is at the version.
+// Changes without a version, version 0, apply to any version of the row.
+func Condition(idColumn string, column string, id uint64, version uint64) sq.Eq {
+	if version == 0 {
+		return sq.Eq{idColumn: id}
+	}
+	return sq.Eq{idColumn: id, column: version}
+}
+
+// ExistsCondition holds while the row with the id is at the version, for statements on the side tables of the row.
+// It is nil for version 0.
+func ExistsCondition(table string, idColumn string, column string, id uint64, version uint64) sq.Sqlizer {
+	if version == 0 {
+		return nil
+	}
+	return sq.Expr(fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s = ? AND %s = ?)", table, idColumn, column), id, version)
+}
+
+// PreconditionFailed is the error of a change made against a version that is not the current version of the row
+func PreconditionFailed(version uint64) *utils.GenericError {
+	return utils.HTTPGenericError(http.StatusPreconditionFailed, fmt.Sprintf("version %d is not the current version", version))
+}
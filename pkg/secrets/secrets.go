@@ -14,9 +14,14 @@ type Scheduler0Secrets interface {
 }
 
 type scheduler0Secrets struct {
-	SecretKey    string `json:"secretKey" yaml:"SecretKey"`
-	AuthUsername string `json:"authUsername" yaml:"AuthUsername"`
-	AuthPassword string `json:"authPassword" yaml:"AuthPassword"`
+	SecretKey     string `json:"secretKey" yaml:"SecretKey"`
+	AuthUsername  string `json:"authUsername" yaml:"AuthUsername"`
+	AuthPassword  string `json:"authPassword" yaml:"AuthPassword"`
+	RaftSharedKey string `json:"raftSharedKey" yaml:"RaftSharedKey"` // Key raft peers prove they know before their connections are used, optional
+	// DataEncryptionKeys hex encoded AES-256 keys encrypting the data of jobs by key id, keys of data not rotated yet are kept
+	DataEncryptionKeys map[string]string `json:"dataEncryptionKeys" yaml:"DataEncryptionKeys"`
+	// DataEncryptionKeysJSON the json object of SCHEDULER0_DATA_ENCRYPTION_KEYS, parsed with the keyring so malformed keys fail there
+	DataEncryptionKeysJSON string `json:"-" yaml:"-"`
 }
 
 func NewScheduler0Secrets() *scheduler0Secrets {
@@ -89,5 +94,13 @@ func getSecretsFromEnv() scheduler0Secrets {
 		secrets.AuthUsername = val
 	}
 
+	if val, ok := os.LookupEnv("SCHEDULER0_RAFT_SHARED_KEY"); ok {
+		secrets.RaftSharedKey = val
+	}
+
+	if val, ok := os.LookupEnv("SCHEDULER0_DATA_ENCRYPTION_KEYS"); ok {
+		secrets.DataEncryptionKeysJSON = val
+	}
+
 	return secrets
 }
@@ -0,0 +1,327 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"net/http"
+	"net/url"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	alert_repo "scheduler0/pkg/repository/alert"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+// alertService manages the alert rules of projects and notifies their webhooks
+type alertService struct {
+	ctx              context.Context
+	logger           hclog.Logger
+	scheduler0Config config.Scheduler0Config
+	fsmStore         fsm.Scheduler0RaftStore
+	alertRepo        alert_repo.AlertRepo
+	projectRepo      project_repo.ProjectRepo
+	httpClient       *http.Client
+}
+
+//go:generate mockery --name AlertService --output ../mocks
+type AlertService interface {
+	CreateOne(alertRule models.AlertRule) (*models.AlertRule, *utils.GenericError)
+	GetOneByID(alertRule *models.AlertRule) *utils.GenericError
+	List(projectId uint64, offset uint64, limit uint64) (*models.PaginatedAlertRules, *utils.GenericError)
+	UpdateOneByID(alertRule *models.AlertRule) *utils.GenericError
+	DeleteOneByID(alertRule models.AlertRule) *utils.GenericError
+	EvaluateRules()
+	EvaluateRulesPeriodically()
+}
+
+func NewAlertService(
+	ctx context.Context,
+	logger hclog.Logger,
+	scheduler0Config config.Scheduler0Config,
+	fsmStore fsm.Scheduler0RaftStore,
+	alertRepo alert_repo.AlertRepo,
+	projectRepo project_repo.ProjectRepo,
+) AlertService {
+	return &alertService{
+		ctx:              ctx,
+		logger:           logger.Named("alert-service"),
+		scheduler0Config: scheduler0Config,
+		fsmStore:         fsmStore,
+		alertRepo:        alertRepo,
+		projectRepo:      projectRepo,
+		httpClient: &http.Client{
+			Timeout: time.Duration(constants.AlertWebhookTimeoutSeconds) * time.Second,
+		},
+	}
+}
+
+// CreateOne creates an alert rule for a project
+func (alertService *alertService) CreateOne(alertRule models.AlertRule) (*models.AlertRule, *utils.GenericError) {
+	if getErr := alertService.projectRepo.GetOneByID(&models.Project{ID: alertRule.ProjectID}); getErr != nil {
+		return nil, getErr
+	}
+
+	if validationErr := validateAlertRule(alertRule); validationErr != nil {
+		return nil, validationErr
+	}
+
+	_, createErr := alertService.alertRepo.CreateOne(&alertRule)
+	if createErr != nil {
+		return nil, createErr
+	}
+
+	return &alertRule, nil
+}
+
+// GetOneByID returns an alert rule of a project
+func (alertService *alertService) GetOneByID(alertRule *models.AlertRule) *utils.GenericError {
+	return alertService.alertRepo.GetOneByID(alertRule)
+}
+
+// List returns a paginated list of the alert rules of a project
+func (alertService *alertService) List(projectId uint64, offset uint64, limit uint64) (*models.PaginatedAlertRules, *utils.GenericError) {
+	if getErr := alertService.projectRepo.GetOneByID(&models.Project{ID: projectId}); getErr != nil {
+		return nil, getErr
+	}
+
+	alertRules, listErr := alertService.alertRepo.List(projectId, offset, limit)
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	count, countErr := alertService.alertRepo.Count(projectId)
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	return &models.PaginatedAlertRules{
+		Total:  count,
+		Offset: offset,
+		Limit:  limit,
+		Data:   alertRules,
+	}, nil
+}
+
+// UpdateOneByID updates the threshold, period and webhook of an alert rule. The type of a rule cannot be changed.
+func (alertService *alertService) UpdateOneByID(alertRule *models.AlertRule) *utils.GenericError {
+	existingAlertRule := models.AlertRule{ID: alertRule.ID, ProjectID: alertRule.ProjectID}
+	if getErr := alertService.alertRepo.GetOneByID(&existingAlertRule); getErr != nil {
+		return getErr
+	}
+
+	if alertRule.Type != "" && alertRule.Type != existingAlertRule.Type {
+		return utils.HTTPGenericError(http.StatusBadRequest, "cannot change the type of an alert")
+	}
+
+	if alertRule.Threshold > 0 {
+		existingAlertRule.Threshold = alertRule.Threshold
+	}
+	if alertRule.PeriodSeconds > 0 {
+		existingAlertRule.PeriodSeconds = alertRule.PeriodSeconds
+	}
+	if alertRule.WebhookUrl != "" {
+		existingAlertRule.WebhookUrl = alertRule.WebhookUrl
+	}
+
+	if validationErr := validateAlertRule(existingAlertRule); validationErr != nil {
+		return validationErr
+	}
+
+	_, updateErr := alertService.alertRepo.UpdateOneByID(existingAlertRule)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	*alertRule = existingAlertRule
+	return nil
+}
+
+// DeleteOneByID deletes an alert rule of a project
+func (alertService *alertService) DeleteOneByID(alertRule models.AlertRule) *utils.GenericError {
+	count, deleteErr := alertService.alertRepo.DeleteOneByID(alertRule)
+	if deleteErr != nil {
+		return deleteErr
+	}
+
+	if count < 1 {
+		return utils.HTTPGenericError(http.StatusNotFound, "alert does not exist")
+	}
+
+	return nil
+}
+
+// EvaluateRulesPeriodically evaluates the alert rules on an interval while this node is the raft leader
+func (alertService *alertService) EvaluateRulesPeriodically() {
+	go func() {
+		configs := alertService.scheduler0Config.GetConfigurations()
+		interval := configs.AlertEvaluationIntervalSeconds
+		if interval == 0 {
+			interval = constants.DefaultAlertEvaluationIntervalSeconds
+		}
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if alertService.fsmStore.GetRaft() == nil || alertService.fsmStore.GetRaft().State() != raft.Leader {
+					continue
+				}
+				alertService.EvaluateRules()
+			case <-alertService.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// EvaluateRules notifies the webhook of every alert rule about the jobs it started or stopped firing for.
+// The firing alerts are replicated through raft, so a rule does not notify twice for the same job, even after
+// a leader change. Notifications that cannot be delivered are retried on the next evaluation.
+func (alertService *alertService) EvaluateRules() {
+	alertRules, listErr := alertService.alertRepo.ListAll()
+	if listErr != nil {
+		alertService.logger.Error("failed to list alert rules", "error", listErr.Message)
+		return
+	}
+
+	retryMax := alertService.scheduler0Config.GetConfigurations().JobExecutionRetryMax
+
+	for _, alertRule := range alertRules {
+		matchingJobIds, matchErr := alertService.alertRepo.GetMatchingJobIds(alertRule, time.Now(), retryMax)
+		if matchErr != nil {
+			alertService.logger.Error("failed to evaluate alert rule", "rule-id", alertRule.ID, "error", matchErr.Message)
+			continue
+		}
+
+		firingJobIds, firingErr := alertService.alertRepo.GetFiringJobIds(alertRule.ID)
+		if firingErr != nil {
+			alertService.logger.Error("failed to get firing alerts", "rule-id", alertRule.ID, "error", firingErr.Message)
+			continue
+		}
+
+		matching := make(map[uint64]bool, len(matchingJobIds))
+		for _, jobId := range matchingJobIds {
+			matching[jobId] = true
+		}
+		firing := make(map[uint64]bool, len(firingJobIds))
+		for _, jobId := range firingJobIds {
+			firing[jobId] = true
+		}
+
+		var firedJobIds []uint64
+		for _, jobId := range matchingJobIds {
+			if firing[jobId] {
+				continue
+			}
+			if notifyErr := alertService.notify(alertRule, jobId, models.AlertStatusFiring); notifyErr != nil {
+				alertService.logger.Error("failed to notify alert webhook", "rule-id", alertRule.ID, "job-id", jobId, "error", notifyErr.Error())
+				continue
+			}
+			firedJobIds = append(firedJobIds, jobId)
+		}
+		if insertErr := alertService.alertRepo.InsertFiringAlerts(alertRule.ID, firedJobIds); insertErr != nil {
+			alertService.logger.Error("failed to record firing alerts", "rule-id", alertRule.ID, "error", insertErr.Message)
+		}
+
+		var resolvedJobIds []uint64
+		for _, jobId := range firingJobIds {
+			if matching[jobId] {
+				continue
+			}
+			if notifyErr := alertService.notify(alertRule, jobId, models.AlertStatusResolved); notifyErr != nil {
+				alertService.logger.Error("failed to notify alert webhook", "rule-id", alertRule.ID, "job-id", jobId, "error", notifyErr.Error())
+				continue
+			}
+			resolvedJobIds = append(resolvedJobIds, jobId)
+		}
+		if deleteErr := alertService.alertRepo.DeleteFiringAlerts(alertRule.ID, resolvedJobIds); deleteErr != nil {
+			alertService.logger.Error("failed to record resolved alerts", "rule-id", alertRule.ID, "error", deleteErr.Message)
+		}
+	}
+}
+
+func (alertService *alertService) notify(alertRule models.AlertRule, jobId uint64, status models.AlertStatus) error {
+	notification := models.AlertNotification{
+		Status:    status,
+		RuleID:    alertRule.ID,
+		ProjectID: alertRule.ProjectID,
+		JobID:     jobId,
+		Type:      alertRule.Type,
+		Message:   alertMessage(alertRule, jobId, status),
+		Date:      time.Now().UTC(),
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(alertService.ctx, http.MethodPost, alertRule.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := alertService.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("alert webhook responded with status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func alertMessage(alertRule models.AlertRule, jobId uint64, status models.AlertStatus) string {
+	if status == models.AlertStatusResolved {
+		return fmt.Sprintf("job %d no longer matches the %s alert", jobId, alertRule.Type)
+	}
+
+	switch alertRule.Type {
+	case models.AlertRuleTypeConsecutiveFailures:
+		return fmt.Sprintf("job %d failed %d or more times in a row", jobId, alertRule.Threshold)
+	case models.AlertRuleTypeNoSuccess:
+		return fmt.Sprintf("job %d has not succeeded in %s", jobId, time.Duration(alertRule.PeriodSeconds)*time.Second)
+	default:
+		return fmt.Sprintf("the last execution of job %d failed on every retry", jobId)
+	}
+}
+
+func validateAlertRule(alertRule models.AlertRule) *utils.GenericError {
+	switch alertRule.Type {
+	case models.AlertRuleTypeConsecutiveFailures:
+		if alertRule.Threshold < 1 {
+			return utils.HTTPGenericError(http.StatusBadRequest, "threshold should be greater than zero")
+		}
+	case models.AlertRuleTypeNoSuccess:
+		if alertRule.PeriodSeconds < 1 {
+			return utils.HTTPGenericError(http.StatusBadRequest, "periodSeconds should be greater than zero")
+		}
+	case models.AlertRuleTypeDeadLettered:
+	default:
+		return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf(
+			"type should be one of %s, %s or %s",
+			models.AlertRuleTypeConsecutiveFailures,
+			models.AlertRuleTypeNoSuccess,
+			models.AlertRuleTypeDeadLettered,
+		))
+	}
+
+	webhookUrl, err := url.ParseRequestURI(alertRule.WebhookUrl)
+	if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+		return utils.HTTPGenericError(http.StatusBadRequest, "webhookUrl should be a valid http url")
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/db"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	alert_repo "scheduler0/pkg/repository/alert"
+	job_repo "scheduler0/pkg/repository/job"
+	"scheduler0/pkg/repository/job_execution"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/shared_repo"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_AlertService_CreateOne(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "alert-service-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alertRepo := alert_repo.NewAlertRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	service := NewAlertService(ctx, logger, scheduler0config, scheduler0Store, alertRepo, projectRepo)
+
+	_, createProjectErr := projectRepo.CreateOne(&models.Project{ID: 1, Name: "Test Project", Description: "Test project description"})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+
+	_, createErr := service.CreateOne(models.AlertRule{ProjectID: 2, Type: models.AlertRuleTypeDeadLettered, WebhookUrl: "http://localhost/alerts"})
+	assert.NotNil(t, createErr)
+	assert.Equal(t, http.StatusNotFound, createErr.Type)
+
+	invalidAlertRules := []models.AlertRule{
+		{ProjectID: 1, Type: "unknown", WebhookUrl: "http://localhost/alerts"},
+		{ProjectID: 1, Type: models.AlertRuleTypeConsecutiveFailures, WebhookUrl: "http://localhost/alerts"},
+		{ProjectID: 1, Type: models.AlertRuleTypeNoSuccess, WebhookUrl: "http://localhost/alerts"},
+		{ProjectID: 1, Type: models.AlertRuleTypeDeadLettered, WebhookUrl: "ftp://localhost/alerts"},
+	}
+	for _, alertRule := range invalidAlertRules {
+		_, createErr = service.CreateOne(alertRule)
+		assert.NotNil(t, createErr)
+		assert.Equal(t, http.StatusBadRequest, createErr.Type)
+	}
+
+	alertRule, createErr := service.CreateOne(models.AlertRule{ProjectID: 1, Type: models.AlertRuleTypeConsecutiveFailures, Threshold: 3, WebhookUrl: "http://localhost/alerts"})
+	if createErr != nil {
+		t.Fatal("failed to create alert rule:", createErr)
+	}
+
+	// The type of a rule cannot be changed
+	typeChangeErr := service.UpdateOneByID(&models.AlertRule{ID: alertRule.ID, ProjectID: 1, Type: models.AlertRuleTypeNoSuccess})
+	assert.NotNil(t, typeChangeErr)
+	assert.Equal(t, http.StatusBadRequest, typeChangeErr.Type)
+
+	update := models.AlertRule{ID: alertRule.ID, ProjectID: 1, Threshold: 5}
+	if updateErr := service.UpdateOneByID(&update); updateErr != nil {
+		t.Fatal("failed to update alert rule:", updateErr)
+	}
+	assert.Equal(t, uint64(5), update.Threshold)
+	assert.Equal(t, "http://localhost/alerts", update.WebhookUrl)
+
+	paginatedAlertRules, listErr := service.List(1, 0, 10)
+	if listErr != nil {
+		t.Fatal("failed to list alert rules:", listErr)
+	}
+	assert.Equal(t, uint64(1), paginatedAlertRules.Total)
+	assert.Equal(t, uint64(5), paginatedAlertRules.Data[0].Threshold)
+}
+
+func Test_AlertService_EvaluateRules(t *testing.T) {
+	scheduler0config := config.NewScheduler0Config()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "alert-service-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mtx sync.Mutex
+	var notifications []models.AlertNotification
+	webhookStatus := http.StatusOK
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if webhookStatus != http.StatusOK {
+			w.WriteHeader(webhookStatus)
+			return
+		}
+		notification := models.AlertNotification{}
+		if decodeErr := json.NewDecoder(r.Body).Decode(&notification); decodeErr != nil {
+			t.Error("failed to decode alert notification:", decodeErr)
+		}
+		notifications = append(notifications, notification)
+	}))
+	defer webhook.Close()
+	takeNotifications := func() []models.AlertNotification {
+		mtx.Lock()
+		defer mtx.Unlock()
+		taken := notifications
+		notifications = nil
+		return taken
+	}
+	setWebhookStatus := func(status int) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		webhookStatus = status
+	}
+
+	alertRepo := alert_repo.NewAlertRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobExecutionsRepo := job_execution.NewExecutionsRepo(logger, scheduler0RaftActions, scheduler0Store)
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	service := NewAlertService(ctx, logger, scheduler0config, scheduler0Store, alertRepo, projectRepo)
+
+	_, createProjectErr := projectRepo.CreateOne(&models.Project{ID: 1, Name: "Test Project", Description: "Test project description"})
+	if createProjectErr != nil {
+		t.Fatal("failed to create project:", createProjectErr)
+	}
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{
+		{ID: 1, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+		{ID: 2, Spec: "*/5 * * * *", ProjectID: 1, Timezone: "UTC"},
+	})
+	if insertErr != nil {
+		t.Fatal("failed to insert jobs:", insertErr)
+	}
+
+	now := time.Now()
+	executionLog := func(jobId uint64, state models.JobExecutionLogState, executionVersion uint64, minutesAgo int) models.JobExecutionLog {
+		return models.JobExecutionLog{
+			JobId:                 jobId,
+			UniqueId:              "1",
+			State:                 state,
+			NodeId:                1,
+			LastExecutionDatetime: now,
+			NextExecutionDatetime: now,
+			JobQueueVersion:       1,
+			ExecutionVersion:      executionVersion,
+			DataCreated:           now.Add(-time.Duration(minutesAgo) * time.Minute),
+		}
+	}
+	// Job 1 fails twice in a row, job 2 fails once before it succeeds
+	jobExecutionsRepo.RaftInsertExecutionLogs([]models.JobExecutionLog{
+		executionLog(1, models.ExecutionLogSuccessState, 1, 30),
+		executionLog(1, models.ExecutionLogFailedState, 2, 20),
+		executionLog(1, models.ExecutionLogFailedState, 3, 10),
+		executionLog(2, models.ExecutionLogFailedState, 1, 20),
+		executionLog(2, models.ExecutionLogSuccessState, 2, 10),
+	}, 1)
+
+	alertRule, createErr := service.CreateOne(models.AlertRule{ProjectID: 1, Type: models.AlertRuleTypeConsecutiveFailures, Threshold: 2, WebhookUrl: webhook.URL})
+	if createErr != nil {
+		t.Fatal("failed to create alert rule:", createErr)
+	}
+
+	// A notification the webhook does not accept is retried on the next evaluation
+	setWebhookStatus(http.StatusInternalServerError)
+	service.EvaluateRules()
+	firingJobIds, firingErr := alertRepo.GetFiringJobIds(alertRule.ID)
+	if firingErr != nil {
+		t.Fatal("failed to get firing alerts:", firingErr)
+	}
+	assert.Empty(t, firingJobIds)
+
+	setWebhookStatus(http.StatusOK)
+	service.EvaluateRules()
+	delivered := takeNotifications()
+	assert.Equal(t, 1, len(delivered))
+	assert.Equal(t, models.AlertStatusFiring, delivered[0].Status)
+	assert.Equal(t, alertRule.ID, delivered[0].RuleID)
+	assert.Equal(t, uint64(1), delivered[0].ProjectID)
+	assert.Equal(t, uint64(1), delivered[0].JobID)
+	assert.Equal(t, models.AlertRuleTypeConsecutiveFailures, delivered[0].Type)
+
+	// A firing alert is only delivered once
+	service.EvaluateRules()
+	assert.Empty(t, takeNotifications())
+
+	// The alert resolves once the job succeeds again
+	jobExecutionsRepo.RaftInsertExecutionLogs([]models.JobExecutionLog{
+		executionLog(1, models.ExecutionLogSuccessState, 4, 0),
+	}, 1)
+	service.EvaluateRules()
+	delivered = takeNotifications()
+	assert.Equal(t, 1, len(delivered))
+	assert.Equal(t, models.AlertStatusResolved, delivered[0].Status)
+	assert.Equal(t, uint64(1), delivered[0].JobID)
+
+	firingJobIds, firingErr = alertRepo.GetFiringJobIds(alertRule.ID)
+	if firingErr != nil {
+		t.Fatal("failed to get firing alerts:", firingErr)
+	}
+	assert.Empty(t, firingJobIds)
+}
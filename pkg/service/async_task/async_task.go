@@ -4,20 +4,23 @@ import (
 	"context"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/logging"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/repository/async_task"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"sync"
 )
 
 //go:generate mockery --name AsyncTaskService --output ./ --inpackage
 type AsyncTaskService interface {
-	AddTasks(input, requestId, service string) ([]uint64, *utils.GenericError)
-	UpdateTasksById(taskId uint64, state models.AsyncTaskState, output string) *utils.GenericError
-	UpdateTasksByRequestId(requestId string, state models.AsyncTaskState, output string) *utils.GenericError
+	AddTasks(ctx context.Context, input, requestId, service string, projectIds []uint64) ([]uint64, *utils.GenericError)
+	UpdateTasksById(ctx context.Context, taskId uint64, state models.AsyncTaskState, output string) *utils.GenericError
+	UpdateTasksByRequestId(ctx context.Context, requestId string, state models.AsyncTaskState, output string) *utils.GenericError
 	AddSubscriber(taskId uint64, subscriber func(task models.AsyncTask)) (uint64, *utils.GenericError)
 	GetTaskBlocking(taskId uint64) (chan models.AsyncTask, uint64, *utils.GenericError)
 	GetTaskWithRequestIdNonBlocking(requestId string) (*models.AsyncTask, *utils.GenericError)
@@ -62,12 +65,16 @@ func NewAsyncTaskManager(
 	}
 }
 
-func (m *asyncTaskService) AddTasks(input, requestId, service string) ([]uint64, *utils.GenericError) {
+func (m *asyncTaskService) AddTasks(ctx context.Context, input, requestId, service string, projectIds []uint64) ([]uint64, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "AsyncTaskService.AddTasks", attribute.String("scheduler0.async_task_service", service))
+	defer span.End()
+
 	tasks := []models.AsyncTask{
 		models.AsyncTask{
-			Input:     input,
-			RequestId: requestId,
-			Service:   service,
+			Input:      input,
+			RequestId:  requestId,
+			Service:    service,
+			ProjectIDs: projectIds,
 		},
 	}
 
@@ -75,7 +82,7 @@ func (m *asyncTaskService) AddTasks(input, requestId, service string) ([]uint64,
 
 	var sids []uint64
 	if m.singleNodeMode {
-		ids, err := m.asyncTaskManagerRepo.RaftBatchInsert(tasks, config.NodeId)
+		ids, err := m.asyncTaskManagerRepo.RaftBatchInsert(ctx, tasks, config.NodeId)
 		if err != nil {
 			return nil, err
 		}
@@ -89,7 +96,7 @@ func (m *asyncTaskService) AddTasks(input, requestId, service string) ([]uint64,
 			}
 			sids = ids
 		} else {
-			ids, err := m.asyncTaskManagerRepo.RaftBatchInsert(tasks, config.NodeId)
+			ids, err := m.asyncTaskManagerRepo.RaftBatchInsert(ctx, tasks, config.NodeId)
 			if err != nil {
 				return nil, err
 			}
@@ -103,19 +110,24 @@ func (m *asyncTaskService) AddTasks(input, requestId, service string) ([]uint64,
 	return sids, nil
 }
 
-func (m *asyncTaskService) UpdateTasksById(taskId uint64, state models.AsyncTaskState, output string) *utils.GenericError {
+func (m *asyncTaskService) UpdateTasksById(ctx context.Context, taskId uint64, state models.AsyncTaskState, output string) *utils.GenericError {
+	ctx, span := tracing.StartSpan(ctx, "AsyncTaskService.UpdateTasksById", attribute.Int64("scheduler0.async_task_id", int64(taskId)))
+	defer span.End()
+	logger := logging.FromContext(ctx, m.logger)
+
 	t, ok := m.task.Load(taskId)
 	if !ok {
-		m.logger.Error("could not find task with id", "taskI-d", taskId)
+		logger.Error("could not find task with id", "taskI-d", taskId)
 		return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not find task with id %v", taskId))
 	}
 	myT := t.(models.AsyncTask)
 	myT.State = state
+	myT.Output = output
 	m.task.Store(taskId, myT)
 	if m.singleNodeMode {
-		err := m.asyncTaskManagerRepo.RaftUpdateTaskState(myT, state, output)
+		err := m.asyncTaskManagerRepo.RaftUpdateTaskState(ctx, myT, state, output)
 		if err != nil {
-			m.logger.Error("could not update task with id", taskId)
+			logger.Error("could not update task with id", taskId)
 			return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not update task with id %v", taskId))
 		}
 	} else {
@@ -123,13 +135,13 @@ func (m *asyncTaskService) UpdateTasksById(taskId uint64, state models.AsyncTask
 		if f.Error() != nil {
 			err := m.asyncTaskManagerRepo.UpdateTaskState(myT, state, output)
 			if err != nil {
-				m.logger.Error("could not update task with id", taskId)
+				logger.Error("could not update task with id", taskId)
 				return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not update task with id %v", taskId))
 			}
 		} else {
-			err := m.asyncTaskManagerRepo.RaftUpdateTaskState(myT, state, output)
+			err := m.asyncTaskManagerRepo.RaftUpdateTaskState(ctx, myT, state, output)
 			if err != nil {
-				m.logger.Error("could not update task with id", taskId)
+				logger.Error("could not update task with id", taskId)
 				return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not update task with id %v", taskId))
 			}
 		}
@@ -138,15 +150,19 @@ func (m *asyncTaskService) UpdateTasksById(taskId uint64, state models.AsyncTask
 	return nil
 }
 
-func (m *asyncTaskService) UpdateTasksByRequestId(requestId string, state models.AsyncTaskState, output string) *utils.GenericError {
+func (m *asyncTaskService) UpdateTasksByRequestId(ctx context.Context, requestId string, state models.AsyncTaskState, output string) *utils.GenericError {
+	ctx, span := tracing.StartSpan(ctx, "AsyncTaskService.UpdateTasksByRequestId", attribute.String("scheduler0.request_id", requestId))
+	defer span.End()
+	logger := logging.FromContext(ctx, m.logger)
+
 	tId, ok := m.taskIdRequestIdMap.Load(requestId)
 	if !ok {
-		m.logger.Error("could not find task id for request id", "request-id", requestId)
+		logger.Error("could not find task id for request id", "request-id", requestId)
 		return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not find task id for request id %v", requestId))
 	}
 	t, ok := m.task.Load(tId)
 	if !ok {
-		m.logger.Error("could not find task with request id task id", "request-id", requestId)
+		logger.Error("could not find task with request id task id", "request-id", requestId)
 		return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not find task with request id task id %v", requestId))
 	}
 	myT := t.(models.AsyncTask)
@@ -154,24 +170,24 @@ func (m *asyncTaskService) UpdateTasksByRequestId(requestId string, state models
 	myT.Output = output
 	m.task.Store(myT.Id, myT)
 	if m.singleNodeMode {
-		err := m.asyncTaskManagerRepo.RaftUpdateTaskState(myT, state, output)
+		err := m.asyncTaskManagerRepo.RaftUpdateTaskState(ctx, myT, state, output)
 		if err != nil {
-			m.logger.Error("could not update task with id", requestId)
+			logger.Error("could not update task with id", requestId)
 			return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not update task with id %v", requestId))
 		}
 	} else {
 		f := m.fsm.GetRaft().VerifyLeader()
 		if f.Error() != nil {
-			m.logger.Error("error updating async task with request id, cannot verify raft leadership.", "raft-error", f.Error())
+			logger.Error("error updating async task with request id, cannot verify raft leadership.", "raft-error", f.Error())
 			err := m.asyncTaskManagerRepo.UpdateTaskState(myT, state, output)
 			if err != nil {
-				m.logger.Error("could not update task with id", "request-id", requestId)
+				logger.Error("could not update task with id", "request-id", requestId)
 				return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not update task with id %v", requestId))
 			}
 		} else {
-			err := m.asyncTaskManagerRepo.RaftUpdateTaskState(myT, state, output)
+			err := m.asyncTaskManagerRepo.RaftUpdateTaskState(ctx, myT, state, output)
 			if err != nil {
-				m.logger.Error("could not update task with id", "request-id", requestId)
+				logger.Error("could not update task with id", "request-id", requestId)
 				return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("could not update task with id %v", requestId))
 			}
 		}
@@ -57,7 +57,7 @@ func Test_AsyncTaskManager_AddTasks(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	asyncTaskIds, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	asyncTaskIds, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
@@ -106,11 +106,11 @@ func Test_AsyncTaskManager_UpdateTasksById(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	asyncTaskIds, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	asyncTaskIds, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
-	updateErr := asyncTaskManager.UpdateTasksById(asyncTaskIds[0], models.AsyncTaskSuccess, output)
+	updateErr := asyncTaskManager.UpdateTasksById(context.Background(), asyncTaskIds[0], models.AsyncTaskSuccess, output)
 	if updateErr != nil {
 		t.Fatal("failed update an async task", updateErr)
 	}
@@ -163,11 +163,11 @@ func Test_AsyncTaskManager_UpdateTasksByRequestId(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	_, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	_, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
-	updateErr := asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskSuccess, output)
+	updateErr := asyncTaskManager.UpdateTasksByRequestId(context.Background(), requestId, models.AsyncTaskSuccess, output)
 	if updateErr != nil {
 		t.Fatal("failed update an async task", updateErr)
 	}
@@ -222,7 +222,7 @@ func Test_AsyncTaskManager_AddSubscriber(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	taskIds, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	taskIds, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
@@ -238,7 +238,7 @@ func Test_AsyncTaskManager_AddSubscriber(t *testing.T) {
 		t.Fatal("failed add a subscriber for an async task", addSubErr)
 	}
 	assert.Equal(t, subscriberId, uint64(1))
-	updateErr := asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskSuccess, output)
+	updateErr := asyncTaskManager.UpdateTasksByRequestId(context.Background(), requestId, models.AsyncTaskSuccess, output)
 	if updateErr != nil {
 		t.Fatal("failed update an async task", updateErr)
 	}
@@ -289,7 +289,7 @@ func Test_AsyncTaskManager_DeleteSubscriber(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	taskIds, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	taskIds, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
@@ -309,7 +309,7 @@ func Test_AsyncTaskManager_DeleteSubscriber(t *testing.T) {
 	if deleteSubErr != nil {
 		t.Fatal("failed delete a subscriber for an async task", deleteSubErr)
 	}
-	updateErr := asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskSuccess, output)
+	updateErr := asyncTaskManager.UpdateTasksByRequestId(context.Background(), requestId, models.AsyncTaskSuccess, output)
 	if updateErr != nil {
 		t.Fatal("failed update an async task", updateErr)
 	}
@@ -359,7 +359,7 @@ func Test_AsyncTaskManager_GetTaskBlocking(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	taskIds, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	taskIds, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
@@ -369,7 +369,7 @@ func Test_AsyncTaskManager_GetTaskBlocking(t *testing.T) {
 	if getTaskBlErr != nil {
 		t.Fatal("failed update an async task", getTaskBlErr)
 	}
-	updateErr := asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskSuccess, output)
+	updateErr := asyncTaskManager.UpdateTasksByRequestId(context.Background(), requestId, models.AsyncTaskSuccess, output)
 	if updateErr != nil {
 		t.Fatal("failed update an async task", updateErr)
 	}
@@ -422,7 +422,7 @@ func Test_AsyncTaskManager_GetTaskWithRequestIdBlocking(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	_, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	_, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
@@ -432,7 +432,7 @@ func Test_AsyncTaskManager_GetTaskWithRequestIdBlocking(t *testing.T) {
 	if getTaskBlErr != nil {
 		t.Fatal("failed update an async task", getTaskBlErr)
 	}
-	updateErr := asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskSuccess, output)
+	updateErr := asyncTaskManager.UpdateTasksByRequestId(context.Background(), requestId, models.AsyncTaskSuccess, output)
 	if updateErr != nil {
 		t.Fatal("failed update an async task", updateErr)
 	}
@@ -482,7 +482,7 @@ func Test_AsyncTaskManager_GetTaskIdWithRequestId(t *testing.T) {
 	asyncTaskManagerRepo := async_task.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
 	asyncTaskManager := NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
 
-	_, getErr := asyncTaskManager.AddTasks(input, requestId, service)
+	_, getErr := asyncTaskManager.AddTasks(context.Background(), input, requestId, service, nil)
 	if getErr != nil {
 		t.Fatal("failed add an async task", getErr)
 	}
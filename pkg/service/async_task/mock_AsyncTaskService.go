@@ -3,6 +3,7 @@
 package async_task
 
 import (
+	"context"
 	models "scheduler0/pkg/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -41,25 +42,25 @@ func (_m *MockAsyncTaskService) AddSubscriber(taskId uint64, subscriber func(mod
 	return r0, r1
 }
 
-// AddTasks provides a mock function with given fields: input, requestId, service
-func (_m *MockAsyncTaskService) AddTasks(input string, requestId string, service string) ([]uint64, *utils.GenericError) {
-	ret := _m.Called(input, requestId, service)
+// AddTasks provides a mock function with given fields: ctx, input, requestId, service, projectIds
+func (_m *MockAsyncTaskService) AddTasks(ctx context.Context, input string, requestId string, service string, projectIds []uint64) ([]uint64, *utils.GenericError) {
+	ret := _m.Called(ctx, input, requestId, service, projectIds)
 
 	var r0 []uint64
 	var r1 *utils.GenericError
-	if rf, ok := ret.Get(0).(func(string, string, string) ([]uint64, *utils.GenericError)); ok {
-		return rf(input, requestId, service)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, []uint64) ([]uint64, *utils.GenericError)); ok {
+		return rf(ctx, input, requestId, service, projectIds)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, string) []uint64); ok {
-		r0 = rf(input, requestId, service)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, []uint64) []uint64); ok {
+		r0 = rf(ctx, input, requestId, service, projectIds)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]uint64)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, string) *utils.GenericError); ok {
-		r1 = rf(input, requestId, service)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, []uint64) *utils.GenericError); ok {
+		r1 = rf(ctx, input, requestId, service, projectIds)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(*utils.GenericError)
@@ -261,13 +262,13 @@ func (_m *MockAsyncTaskService) SetSingleNodeMode(singleNodeMode bool) {
 	_m.Called(singleNodeMode)
 }
 
-// UpdateTasksById provides a mock function with given fields: taskId, state, output
-func (_m *MockAsyncTaskService) UpdateTasksById(taskId uint64, state models.AsyncTaskState, output string) *utils.GenericError {
-	ret := _m.Called(taskId, state, output)
+// UpdateTasksById provides a mock function with given fields: ctx, taskId, state, output
+func (_m *MockAsyncTaskService) UpdateTasksById(ctx context.Context, taskId uint64, state models.AsyncTaskState, output string) *utils.GenericError {
+	ret := _m.Called(ctx, taskId, state, output)
 
 	var r0 *utils.GenericError
-	if rf, ok := ret.Get(0).(func(uint64, models.AsyncTaskState, string) *utils.GenericError); ok {
-		r0 = rf(taskId, state, output)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.AsyncTaskState, string) *utils.GenericError); ok {
+		r0 = rf(ctx, taskId, state, output)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*utils.GenericError)
@@ -277,13 +278,13 @@ func (_m *MockAsyncTaskService) UpdateTasksById(taskId uint64, state models.Asyn
 	return r0
 }
 
-// UpdateTasksByRequestId provides a mock function with given fields: requestId, state, output
-func (_m *MockAsyncTaskService) UpdateTasksByRequestId(requestId string, state models.AsyncTaskState, output string) *utils.GenericError {
-	ret := _m.Called(requestId, state, output)
+// UpdateTasksByRequestId provides a mock function with given fields: ctx, requestId, state, output
+func (_m *MockAsyncTaskService) UpdateTasksByRequestId(ctx context.Context, requestId string, state models.AsyncTaskState, output string) *utils.GenericError {
+	ret := _m.Called(ctx, requestId, state, output)
 
 	var r0 *utils.GenericError
-	if rf, ok := ret.Get(0).(func(string, models.AsyncTaskState, string) *utils.GenericError); ok {
-		r0 = rf(requestId, state, output)
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.AsyncTaskState, string) *utils.GenericError); ok {
+		r0 = rf(ctx, requestId, state, output)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*utils.GenericError)
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"net/http"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/models"
+	audit_repo "scheduler0/pkg/repository/audit"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+// auditService lists the audit log and compacts it
+type auditService struct {
+	ctx              context.Context
+	logger           hclog.Logger
+	scheduler0Config config.Scheduler0Config
+	fsmStore         fsm.Scheduler0RaftStore
+	auditRepo        audit_repo.AuditRepo
+}
+
+//go:generate mockery --name AuditService --output ../mocks
+type AuditService interface {
+	ListEvents(filter models.AuditFilter) (*models.PaginatedAuditEvents, *utils.GenericError)
+	Record(event models.AuditEvent) *utils.GenericError
+	Compact() *utils.GenericError
+	CompactPeriodically()
+}
+
+func NewAuditService(
+	ctx context.Context,
+	logger hclog.Logger,
+	scheduler0Config config.Scheduler0Config,
+	fsmStore fsm.Scheduler0RaftStore,
+	auditRepo audit_repo.AuditRepo,
+) AuditService {
+	return &auditService{
+		ctx:              ctx,
+		logger:           logger.Named("audit-service"),
+		scheduler0Config: scheduler0Config,
+		fsmStore:         fsmStore,
+		auditRepo:        auditRepo,
+	}
+}
+
+// ListEvents returns a page of the audit events matching the filter, pages read with a cursor skip counting the events
+func (auditService *auditService) ListEvents(filter models.AuditFilter) (*models.PaginatedAuditEvents, *utils.GenericError) {
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "since should be before until")
+	}
+
+	paginatedEvents := models.PaginatedAuditEvents{}
+
+	if filter.Cursor == "" {
+		total, err := auditService.auditRepo.Count(filter)
+		if err != nil {
+			return nil, err
+		}
+		paginatedEvents.Total = total
+		paginatedEvents.Offset = filter.Offset
+	}
+
+	events, cursors, err := auditService.auditRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	paginatedEvents.Data = events
+	paginatedEvents.Limit = filter.Limit
+	paginatedEvents.Next = cursors.Next
+	paginatedEvents.Prev = cursors.Prev
+
+	return &paginatedEvents, nil
+}
+
+// Record writes an audit event for an admin action that does not change a credential, project or job.
+// Only the leader can write to raft, actions served by followers are logged instead.
+func (auditService *auditService) Record(event models.AuditEvent) *utils.GenericError {
+	if auditService.fsmStore.GetRaft() == nil || auditService.fsmStore.GetRaft().State() != raft.Leader {
+		auditService.logger.Info("audit event not recorded by follower", "action", event.Action, "resource", event.Resource, "resource-id", event.ResourceID, "request-id", event.Actor.RequestID)
+		return nil
+	}
+	if err := auditService.auditRepo.Record(event); err != nil {
+		auditService.logger.Error("failed to record audit event", "action", event.Action, "resource", event.Resource, "error", err.Message)
+		return err
+	}
+	return nil
+}
+
+// Compact deletes the audit events older than the configured max age, audit events are kept forever when it is zero
+func (auditService *auditService) Compact() *utils.GenericError {
+	maxAge := auditService.scheduler0Config.GetConfigurations().AuditLogRetentionMaxAgeSeconds
+	if maxAge == 0 {
+		return nil
+	}
+
+	deleted, err := auditService.auditRepo.Compact(time.Now().Add(-time.Duration(maxAge) * time.Second))
+	if err != nil {
+		auditService.logger.Error("failed to compact the audit log", "error", err.Message)
+		return err
+	}
+	auditService.logger.Debug("compacted the audit log", "deleted", deleted)
+	return nil
+}
+
+// CompactPeriodically compacts the audit log on an interval while this node is the raft leader
+func (auditService *auditService) CompactPeriodically() {
+	go func() {
+		interval := auditService.scheduler0Config.GetConfigurations().AuditLogRetentionIntervalSeconds
+		if interval == 0 {
+			interval = constants.DefaultAuditLogRetentionIntervalSeconds
+		}
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if auditService.fsmStore.GetRaft() == nil || auditService.fsmStore.GetRaft().State() != raft.Leader {
+					continue
+				}
+				auditService.Compact()
+			case <-auditService.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -2,34 +2,68 @@ package credential
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
 	"net/http"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/repository/credential"
 	"scheduler0/pkg/secrets"
 	"scheduler0/pkg/utils"
+	"sort"
+	"sync"
+	"time"
 )
 
 // CredentialService service layer for credentials
 //
 //go:generate mockery --name CredentialService --output ../mocks
 type CredentialService interface {
-	CreateNewCredential(credentialModel models.Credential) (uint64, *utils.GenericError)
+	CreateNewCredential(credentialModel models.Credential) (*models.Credential, *utils.GenericError)
 	FindOneCredentialByID(id uint64) (*models.Credential, error)
 	UpdateOneCredential(credentialModel models.Credential) (*models.Credential, error)
-	DeleteOneCredential(id uint64) (*models.Credential, error)
-	ListCredentials(offset uint64, limit uint64, orderBy string) (*models.PaginatedCredential, *utils.GenericError)
+	DeleteOneCredential(id uint64, version uint64, actor models.Actor) (*models.Credential, error)
+	ListCredentials(page models.PageRequest) (*models.PaginatedCredential, *utils.GenericError)
 	ValidateServerAPIKey(apiKey string, apiSecret string) (bool, *utils.GenericError)
+	AuthenticateServerAPIKey(apiKey string, apiSecret string) (*models.Credential, *utils.GenericError)
+	RotateCredential(id uint64, gracePeriod time.Duration, version uint64, actor models.Actor) (*models.Credential, *utils.GenericError)
+	RecordUsage(credentialId uint64, usedFrom string)
+	MergeUsage(usages []models.CredentialUsage)
+	FlushUsage()
+	FlushUsagePeriodically()
+	HashPlainTextSecrets() *utils.GenericError
 }
 
-func NewCredentialService(Ctx context.Context, logger hclog.Logger, scheduler0Secret secrets.Scheduler0Secrets, repo credential.CredentialRepo, dispatcher *utils.Dispatcher) CredentialService {
+// UsageForwarder sends the usage of the credentials recorded on a follower to the leader, which writes it
+type UsageForwarder interface {
+	ForwardCredentialUsage(ctx context.Context, usages []models.CredentialUsage) *utils.GenericError
+}
+
+func NewCredentialService(
+	Ctx context.Context,
+	logger hclog.Logger,
+	scheduler0Config config.Scheduler0Config,
+	scheduler0Secret secrets.Scheduler0Secrets,
+	fsmStore fsm.Scheduler0RaftStore,
+	repo credential.CredentialRepo,
+	dispatcher *utils.Dispatcher,
+	usageForwarder UsageForwarder,
+) CredentialService {
 	return &credentialService{
 		CredentialRepo:   repo,
 		Ctx:              Ctx,
 		logger:           logger,
 		dispatcher:       dispatcher,
+		scheduler0Config: scheduler0Config,
 		scheduler0Secret: scheduler0Secret,
+		fsmStore:         fsmStore,
+		usageForwarder:   usageForwarder,
+		usages:           map[uint64]models.CredentialUsage{},
 	}
 }
 
@@ -38,16 +72,26 @@ type credentialService struct {
 	Ctx              context.Context
 	logger           hclog.Logger
 	dispatcher       *utils.Dispatcher
+	scheduler0Config config.Scheduler0Config
 	scheduler0Secret secrets.Scheduler0Secrets
+	fsmStore         fsm.Scheduler0RaftStore
+	usageForwarder   UsageForwarder
+	usageLock        sync.Mutex
+	usages           map[uint64]models.CredentialUsage // Last use of the credentials since the last flush
 }
 
-// CreateNewCredential creates a new credentials
-func (credentialService *credentialService) CreateNewCredential(credential models.Credential) (uint64, *utils.GenericError) {
+// CreateNewCredential creates a new credential and returns it with its api secret, the only time the secret is returned
+func (credentialService *credentialService) CreateNewCredential(credential models.Credential) (*models.Credential, *utils.GenericError) {
+	if err := validateScopes(credential); err != nil {
+		return nil, err
+	}
+
 	credentials := credentialService.scheduler0Secret.GetSecrets()
 
 	apiKey, apiSecret := utils.GenerateApiAndSecretKey(credentials.SecretKey)
 	credential.ApiKey = apiKey
-	credential.ApiSecret = apiSecret
+	credential.ApiSecret = ""
+	credential.ApiSecretHash = utils.HashSecret(apiSecret)
 
 	successData, errorData := credentialService.dispatcher.BlockQueue(func(successChannel chan any, errorChannel chan any) {
 		newCredentialId, err := credentialService.CredentialRepo.CreateOne(credential)
@@ -59,11 +103,34 @@ func (credentialService *credentialService) CreateNewCredential(credential model
 	})
 
 	newCredentialId, successOk := successData.(uint64)
-	if successOk {
-		return newCredentialId, nil
+	if !successOk {
+		errM := errorData.(utils.GenericError)
+		return nil, &errM
+	}
+
+	created := models.Credential{ID: newCredentialId}
+	if err := credentialService.CredentialRepo.GetOneID(&created); err != nil {
+		return nil, toGenericError(err)
 	}
-	errM := errorData.(utils.GenericError)
-	return 0, &errM
+	created.ApiSecret = apiSecret
+	return &created, nil
+}
+
+// validateScopes rejects credentials with permissions that do not exist
+func validateScopes(credential models.Credential) *utils.GenericError {
+	for _, permission := range credential.Permissions {
+		known := false
+		for _, existing := range models.Permissions {
+			if permission == existing {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("unknown permission %s, permissions are %v", permission, models.Permissions))
+		}
+	}
+	return nil
 }
 
 // FindOneCredentialByID searches for credential by uuid
@@ -76,10 +143,14 @@ func (credentialService *credentialService) FindOneCredentialByID(id uint64) (*m
 	}
 }
 
-// UpdateOneCredential updates a single credential
+// UpdateOneCredential updates a single credential, at the version of the credential unless it is 0.
+// The api secret is optional, it is only checked against the stored hash when it is given.
 func (credentialService *credentialService) UpdateOneCredential(credential models.Credential) (*models.Credential, error) {
-	if len(credential.ApiKey) < 1 || len(credential.ApiSecret) < 1 {
-		return nil, utils.HTTPGenericError(http.StatusBadRequest, "api_key or api_secret cannot be empty")
+	if len(credential.ApiKey) < 1 {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "api_key cannot be empty")
+	}
+	if err := validateScopes(credential); err != nil {
+		return nil, err
 	}
 
 	credentialPlaceholder := models.Credential{
@@ -94,24 +165,30 @@ func (credentialService *credentialService) UpdateOneCredential(credential model
 		return nil, errors.New("cannot update api key")
 	}
 
-	if credentialPlaceholder.ApiSecret != credential.ApiSecret && len(credential.ApiSecret) > 1 {
+	if len(credential.ApiSecret) > 0 && !storedSecretMatches(credentialPlaceholder.ApiSecretHash, credential.ApiSecret) {
 		return nil, errors.New("cannot update api secret")
 	}
 
 	credential.ApiKey = credentialPlaceholder.ApiKey
-	credential.ApiSecret = credentialPlaceholder.ApiSecret
+	credential.ApiSecret = ""
+	credential.ApiSecretHash = credentialPlaceholder.ApiSecretHash
+	credential.PreviousApiSecretHash = credentialPlaceholder.PreviousApiSecretHash
+	credential.PreviousApiSecretExpiresAt = credentialPlaceholder.PreviousApiSecretExpiresAt
 	credential.DateCreated = credentialPlaceholder.DateCreated
 
 	if _, err := credentialService.CredentialRepo.UpdateOneByID(credential); err != nil {
 		return nil, err
-	} else {
-		return &credential, nil
 	}
+
+	if err := credentialService.CredentialRepo.GetOneID(&credential); err != nil {
+		return nil, err
+	}
+	return &credential, nil
 }
 
-// DeleteOneCredential deletes a single credential
-func (credentialService *credentialService) DeleteOneCredential(id uint64) (*models.Credential, error) {
-	credentialDto := models.Credential{ID: id}
+// DeleteOneCredential deletes a single credential, at the version unless it is 0
+func (credentialService *credentialService) DeleteOneCredential(id uint64, version uint64, actor models.Actor) (*models.Credential, error) {
+	credentialDto := models.Credential{ID: id, Version: version, Actor: actor}
 	if _, err := credentialService.CredentialRepo.DeleteOneByID(credentialDto); err != nil {
 		return nil, err
 	} else {
@@ -119,39 +196,280 @@ func (credentialService *credentialService) DeleteOneCredential(id uint64) (*mod
 	}
 }
 
-// ListCredentials returns paginated list of credentials
-func (credentialService *credentialService) ListCredentials(offset uint64, limit uint64, orderBy string) (*models.PaginatedCredential, *utils.GenericError) {
-	total, err := credentialService.CredentialRepo.Count()
-	if err != nil {
-		return nil, err
-	}
+// ListCredentials returns paginated list of credentials, pages read with a cursor skip counting the credentials
+func (credentialService *credentialService) ListCredentials(page models.PageRequest) (*models.PaginatedCredential, *utils.GenericError) {
+	paginatedCredentials := models.PaginatedCredential{}
+
+	if page.Cursor == "" {
+		total, err := credentialService.CredentialRepo.Count()
+		if err != nil {
+			return nil, err
+		}
+
+		if total < 1 {
+			return nil, utils.HTTPGenericError(http.StatusNotFound, "there no credentials")
+		}
 
-	if total < 1 {
-		return nil, utils.HTTPGenericError(http.StatusNotFound, "there no credentials")
+		paginatedCredentials.Total = total
+		paginatedCredentials.Offset = page.Offset
 	}
 
-	if credentialManagers, err := credentialService.CredentialRepo.List(offset, limit, orderBy); err != nil {
+	credentialManagers, cursors, err := credentialService.CredentialRepo.List(page)
+	if err != nil {
 		return nil, err
-	} else {
-		return &models.PaginatedCredential{
-			Data:   credentialManagers,
-			Total:  total,
-			Offset: offset,
-			Limit:  limit,
-		}, nil
 	}
+
+	paginatedCredentials.Data = credentialManagers
+	paginatedCredentials.Limit = page.Limit
+	paginatedCredentials.Next = cursors.Next
+	paginatedCredentials.Prev = cursors.Prev
+
+	return &paginatedCredentials, nil
 }
 
 // ValidateServerAPIKey authenticates incoming request from servers
 func (credentialService *credentialService) ValidateServerAPIKey(apiKey string, apiSecret string) (bool, *utils.GenericError) {
+	_, authErr := credentialService.AuthenticateServerAPIKey(apiKey, apiSecret)
+	if authErr != nil {
+		if authErr.Type == http.StatusUnauthorized {
+			return false, nil
+		}
+		return false, authErr
+	}
+
+	return true, nil
+}
+
+// AuthenticateServerAPIKey returns the credential of the api key if the api secret matches
+func (credentialService *credentialService) AuthenticateServerAPIKey(apiKey string, apiSecret string) (*models.Credential, *utils.GenericError) {
 	credentialManager := models.Credential{
 		ApiKey: apiKey,
 	}
 
 	getApIError := credentialService.CredentialRepo.GetByAPIKey(&credentialManager)
 	if getApIError != nil {
-		return false, getApIError
+		return nil, getApIError
+	}
+
+	now := time.Now()
+	if credentialManager.Archived {
+		return nil, utils.HTTPGenericError(http.StatusUnauthorized, "credential is archived")
+	}
+	if credentialManager.Expired(now) {
+		return nil, utils.HTTPGenericError(http.StatusUnauthorized, "credential expired")
+	}
+
+	if !secretMatches(credentialManager, apiSecret, now) {
+		return nil, utils.HTTPGenericError(http.StatusUnauthorized, "api secret is not valid")
+	}
+
+	return &credentialManager, nil
+}
+
+// secretMatches reports whether the api secret is the secret of the credential, or the secret replaced by its last rotation during the grace period
+func secretMatches(credentialModel models.Credential, apiSecret string, now time.Time) bool {
+	if storedSecretMatches(credentialModel.ApiSecretHash, apiSecret) {
+		return true
+	}
+
+	previousValid := credentialModel.PreviousApiSecretExpiresAt != nil && now.Before(*credentialModel.PreviousApiSecretExpiresAt)
+	return previousValid && credentialModel.PreviousApiSecretHash != "" && storedSecretMatches(credentialModel.PreviousApiSecretHash, apiSecret)
+}
+
+// storedSecretMatches compares the api secret in constant time with a stored secret, a hash or
+// a secret stored in plain text that HashPlainTextSecrets has not hashed yet
+func storedSecretMatches(stored string, apiSecret string) bool {
+	if utils.IsSecretHash(stored) {
+		return utils.SecretMatches(stored, apiSecret)
+	}
+	return stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(apiSecret)) == 1
+}
+
+// RotateCredential gives the credential a new api secret and returns it with the secret, the current secret is accepted
+// for the grace period after the rotation. The rotation applies at the version of the credential unless it is 0.
+func (credentialService *credentialService) RotateCredential(id uint64, gracePeriod time.Duration, version uint64, actor models.Actor) (*models.Credential, *utils.GenericError) {
+	if gracePeriod < 0 {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "grace period should not be negative")
+	}
+
+	credentialModel := models.Credential{ID: id}
+	if err := credentialService.CredentialRepo.GetOneID(&credentialModel); err != nil {
+		return nil, toGenericError(err)
+	}
+	if credentialModel.Archived {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "cannot rotate an archived credential")
+	}
+
+	_, apiSecret := utils.GenerateApiAndSecretKey(credentialService.scheduler0Secret.GetSecrets().SecretKey)
+	previousExpiresAt := time.Now().UTC().Add(gracePeriod)
+	credentialModel.PreviousApiSecretHash = credentialModel.ApiSecretHash
+	if !utils.IsSecretHash(credentialModel.PreviousApiSecretHash) {
+		credentialModel.PreviousApiSecretHash = utils.HashSecret(credentialModel.PreviousApiSecretHash)
+	}
+	credentialModel.PreviousApiSecretExpiresAt = &previousExpiresAt
+	credentialModel.ApiSecretHash = utils.HashSecret(apiSecret)
+	credentialModel.Version = version
+	credentialModel.Actor = actor
+
+	if _, err := credentialService.CredentialRepo.RotateOneByID(credentialModel); err != nil {
+		return nil, err
 	}
 
-	return apiSecret == credentialManager.ApiSecret, nil
+	if err := credentialService.CredentialRepo.GetOneID(&credentialModel); err != nil {
+		return nil, toGenericError(err)
+	}
+	credentialModel.ApiSecret = apiSecret
+	return &credentialModel, nil
+}
+
+// RecordUsage notes a request authenticated with the credential. The last use of every credential is written by FlushUsage,
+// so authenticating requests does not write to raft.
+func (credentialService *credentialService) RecordUsage(credentialId uint64, usedFrom string) {
+	credentialService.usageLock.Lock()
+	defer credentialService.usageLock.Unlock()
+
+	credentialService.usages[credentialId] = models.CredentialUsage{
+		CredentialID: credentialId,
+		UsedAt:       time.Now().UTC(),
+		UsedFrom:     usedFrom,
+	}
+}
+
+// MergeUsage notes the usage of the credentials recorded by another node, keeping the latest use of every credential
+func (credentialService *credentialService) MergeUsage(usages []models.CredentialUsage) {
+	credentialService.usageLock.Lock()
+	defer credentialService.usageLock.Unlock()
+
+	for _, usage := range usages {
+		if recorded, ok := credentialService.usages[usage.CredentialID]; ok && recorded.UsedAt.After(usage.UsedAt) {
+			continue
+		}
+		credentialService.usages[usage.CredentialID] = usage
+	}
+}
+
+// FlushUsage writes the last use of the credentials recorded since the last flush in a single raft command.
+// Usages that fail to be written are kept for the next flush, unless the credential was used again since.
+func (credentialService *credentialService) FlushUsage() {
+	credentialService.flushUsage(func(usages []models.CredentialUsage) *utils.GenericError {
+		if err := credentialService.CredentialRepo.UpdateLastUsed(usages); err != nil {
+			credentialService.logger.Error("failed to write the last use of credentials", "credentials", len(usages), "error", err.Message)
+			return err
+		}
+		return nil
+	})
+}
+
+// forwardUsage sends the usage of the credentials recorded since the last flush to the leader.
+// Usages that fail to be sent are kept for the next flush, unless the credential was used again since.
+func (credentialService *credentialService) forwardUsage() {
+	credentialService.flushUsage(func(usages []models.CredentialUsage) *utils.GenericError {
+		if err := credentialService.usageForwarder.ForwardCredentialUsage(credentialService.Ctx, usages); err != nil {
+			credentialService.logger.Error("failed to send the last use of credentials to the leader", "credentials", len(usages), "error", err.Message)
+			return err
+		}
+		return nil
+	})
+}
+
+// flushUsage hands the usages recorded since the last flush to write, and keeps them for the next flush when write fails
+func (credentialService *credentialService) flushUsage(write func(usages []models.CredentialUsage) *utils.GenericError) {
+	credentialService.usageLock.Lock()
+	pending := credentialService.usages
+	credentialService.usages = map[uint64]models.CredentialUsage{}
+	credentialService.usageLock.Unlock()
+
+	if len(pending) < 1 {
+		return
+	}
+
+	usages := make([]models.CredentialUsage, 0, len(pending))
+	for _, usage := range pending {
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].CredentialID < usages[j].CredentialID })
+
+	if err := write(usages); err != nil {
+		credentialService.usageLock.Lock()
+		defer credentialService.usageLock.Unlock()
+		for _, usage := range usages {
+			if _, usedAgain := credentialService.usages[usage.CredentialID]; !usedAgain {
+				credentialService.usages[usage.CredentialID] = usage
+			}
+		}
+	}
+}
+
+// FlushUsagePeriodically flushes the usage of the credentials on an interval. The leader writes the usage it records
+// and the usage followers send it, followers send the usage they record to the leader.
+// The leader also hashes the secrets stored in plain text once it is elected, see HashPlainTextSecrets.
+func (credentialService *credentialService) FlushUsagePeriodically() {
+	go func() {
+		secretsHashed := false
+		interval := credentialService.scheduler0Config.GetConfigurations().CredentialUsageFlushIntervalSeconds
+		if interval == 0 {
+			interval = constants.DefaultCredentialUsageFlushIntervalSeconds
+		}
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if credentialService.fsmStore.GetRaft() == nil {
+					continue
+				}
+				if credentialService.fsmStore.GetRaft().State() != raft.Leader {
+					secretsHashed = false
+					if credentialService.usageForwarder != nil {
+						credentialService.forwardUsage()
+					}
+					continue
+				}
+				if !secretsHashed {
+					secretsHashed = credentialService.HashPlainTextSecrets() == nil
+				}
+				credentialService.FlushUsage()
+			case <-credentialService.Ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// HashPlainTextSecrets replaces the api secrets stored in plain text, by versions that did not hash them, with their hash.
+// Credentials changed while their secrets are hashed are hashed by the next call.
+func (credentialService *credentialService) HashPlainTextSecrets() *utils.GenericError {
+	credentials, err := credentialService.CredentialRepo.ListPlainTextSecrets()
+	if err != nil {
+		credentialService.logger.Error("failed to read the credentials with secrets in plain text", "error", err.Message)
+		return err
+	}
+	if len(credentials) < 1 {
+		return nil
+	}
+
+	for i, credentialModel := range credentials {
+		if !utils.IsSecretHash(credentialModel.ApiSecretHash) {
+			credentials[i].ApiSecretHash = utils.HashSecret(credentialModel.ApiSecretHash)
+		}
+		if credentialModel.PreviousApiSecretHash != "" && !utils.IsSecretHash(credentialModel.PreviousApiSecretHash) {
+			credentials[i].PreviousApiSecretHash = utils.HashSecret(credentialModel.PreviousApiSecretHash)
+		}
+	}
+
+	if err := credentialService.CredentialRepo.UpdateSecretHashes(credentials); err != nil {
+		credentialService.logger.Error("failed to hash the credential secrets stored in plain text", "credentials", len(credentials), "error", err.Message)
+		return err
+	}
+	credentialService.logger.Info("hashed the credential secrets stored in plain text", "credentials", len(credentials))
+	return nil
+}
+
+// toGenericError returns the error of a repository read as a generic error
+func toGenericError(err error) *utils.GenericError {
+	if genericErr, ok := err.(*utils.GenericError); ok {
+		return genericErr
+	}
+	return utils.HTTPGenericError(http.StatusInternalServerError, err.Error())
 }
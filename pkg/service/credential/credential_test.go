@@ -17,6 +17,7 @@ import (
 	"scheduler0/pkg/shared_repo"
 	"scheduler0/pkg/utils"
 	"testing"
+	"time"
 )
 
 func Test_CredentialService_CreateNewCredential(t *testing.T) {
@@ -64,12 +65,13 @@ func Test_CredentialService_CreateNewCredential(t *testing.T) {
 
 	dispatcher.Run()
 
-	service := NewCredentialService(ctx, logger, scheduler0Secrets, credentialRepo, dispatcher)
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
 
-	id, createErr := service.CreateNewCredential(models.Credential{})
+	created, createErr := service.CreateNewCredential(models.Credential{})
 	if createErr != nil {
 		t.Fatal("failed to create new credential", createErr)
 	}
+	id := created.ID
 	assert.Equal(t, id, uint64(1))
 }
 
@@ -118,12 +120,13 @@ func Test_CredentialService_UpdateOneCredential(t *testing.T) {
 
 	dispatcher.Run()
 
-	service := NewCredentialService(ctx, logger, scheduler0Secrets, credentialRepo, dispatcher)
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
 
-	id, createErr := service.CreateNewCredential(models.Credential{})
+	created, createErr := service.CreateNewCredential(models.Credential{})
 	if createErr != nil {
 		t.Fatal("failed to create new credential", createErr)
 	}
+	id := created.ID
 	assert.Equal(t, id, uint64(1))
 
 	_, updateErr := service.UpdateOneCredential(models.Credential{
@@ -209,15 +212,16 @@ func Test_CredentialService_DeleteOneCredential(t *testing.T) {
 
 	dispatcher.Run()
 
-	service := NewCredentialService(ctx, logger, scheduler0Secrets, credentialRepo, dispatcher)
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
 
-	id, createErr := service.CreateNewCredential(models.Credential{})
+	created, createErr := service.CreateNewCredential(models.Credential{})
 	if createErr != nil {
 		t.Fatal("failed to create new credential", createErr)
 	}
+	id := created.ID
 	assert.Equal(t, id, uint64(1))
 
-	deletedCred, deleteErr := service.DeleteOneCredential(id)
+	deletedCred, deleteErr := service.DeleteOneCredential(id, 0, models.Actor{})
 	if deleteErr != nil {
 		t.Fatal("failed to delete credential", deleteErr)
 	}
@@ -270,7 +274,7 @@ func Test_CredentialService_ListCredentials(t *testing.T) {
 
 	dispatcher.Run()
 
-	service := NewCredentialService(ctx, logger, scheduler0Secrets, credentialRepo, dispatcher)
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
 
 	credentials := []models.Credential{
 		{
@@ -291,23 +295,19 @@ func Test_CredentialService_ListCredentials(t *testing.T) {
 	}
 
 	for i, credential := range credentials {
-		_, createErr := service.CreateNewCredential(credential)
+		cred, createErr := service.CreateNewCredential(credential)
 		if createErr != nil {
 			t.Fatalf("Failed to create credential: %v", createErr)
 		}
-		cred, getErr := service.FindOneCredentialByID(credential.ID)
-		if getErr != nil {
-			t.Fatalf("Failed to create credential: %v", getErr)
-		}
+		assert.Equal(t, credential.ID, cred.ID)
 		credentials[i].ApiKey = cred.ApiKey
-		credentials[i].ApiSecret = cred.ApiSecret
 	}
 
 	// Call the ListCredentials method
 	offset := uint64(0)
 	limit := uint64(10)
 	orderBy := "id"
-	result, listErr := service.ListCredentials(offset, limit, orderBy)
+	result, listErr := service.ListCredentials(models.PageRequest{Offset: offset, Limit: limit, OrderBy: orderBy})
 	if listErr != nil {
 		t.Fatalf("Failed to list credentials: %v", listErr)
 	}
@@ -326,7 +326,7 @@ func Test_CredentialService_ListCredentials(t *testing.T) {
 		expectedCredential, ok := credentialMap[credential.ID]
 		assert.True(t, ok, "Unexpected credential with ID:", credential.ID)
 		assert.Equal(t, expectedCredential.ApiKey, credential.ApiKey)
-		assert.Equal(t, expectedCredential.ApiSecret, credential.ApiSecret)
+		assert.Empty(t, credential.ApiSecret)
 	}
 
 	// Assert the total count, offset, and limit
@@ -380,7 +380,7 @@ func Test_CredentialService_ValidateServerAPIKey(t *testing.T) {
 
 	dispatcher.Run()
 
-	service := NewCredentialService(ctx, logger, scheduler0Secrets, credentialRepo, dispatcher)
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
 
 	credential := models.Credential{
 		ID:        1,
@@ -388,14 +388,10 @@ func Test_CredentialService_ValidateServerAPIKey(t *testing.T) {
 		ApiSecret: "api-secret-1",
 	}
 
-	_, createErr := service.CreateNewCredential(credential)
+	cred, createErr := service.CreateNewCredential(credential)
 	if createErr != nil {
 		t.Fatalf("Failed to create credential: %v", createErr)
 	}
-	cred, getErr := service.FindOneCredentialByID(credential.ID)
-	if getErr != nil {
-		t.Fatalf("Failed to create credential: %v", getErr)
-	}
 
 	// Call the ValidateServerAPIKey method with valid credentials
 	isValid, validErr := service.ValidateServerAPIKey(cred.ApiKey, cred.ApiSecret)
@@ -414,3 +410,257 @@ func Test_CredentialService_ValidateServerAPIKey(t *testing.T) {
 	assert.False(t, isValid)
 	assert.Nil(t, invalidErr)
 }
+
+func Test_CredentialService_ExpiryRotationAndUsage(t *testing.T) {
+	ctx := context.Background()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "credential-service-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	scheduler0config := config.NewScheduler0Config()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+	scheduler0Secrets := secrets.NewScheduler0Secrets()
+	credentialRepo := credential_repository.NewCredentialRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	t.Setenv("SCHEDULER0_SECRET_KEY", "AB551DED82B93DC8035D624A625920E2121367C7538C02277D2D4DB3C0BFFE94")
+
+	dispatcher := utils.NewDispatcher(ctx, int64(1), int64(1))
+	dispatcher.Run()
+
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
+
+	// An expired credential is rejected
+	expiredAt := time.Now().Add(-time.Minute)
+	expired, createErr := service.CreateNewCredential(models.Credential{ExpiresAt: &expiredAt})
+	if createErr != nil {
+		t.Fatalf("Failed to create credential: %v", createErr)
+	}
+	_, authErr := service.AuthenticateServerAPIKey(expired.ApiKey, expired.ApiSecret)
+	assert.NotNil(t, authErr)
+	assert.Equal(t, http.StatusUnauthorized, authErr.Type)
+
+	// An archived credential is rejected
+	cred, createErr := service.CreateNewCredential(models.Credential{})
+	if createErr != nil {
+		t.Fatalf("Failed to create credential: %v", createErr)
+	}
+	credentialId := cred.ID
+	cred.Archived = true
+	if _, updateErr := service.UpdateOneCredential(*cred); updateErr != nil {
+		t.Fatalf("Failed to archive credential: %v", updateErr)
+	}
+	_, authErr = service.AuthenticateServerAPIKey(cred.ApiKey, cred.ApiSecret)
+	assert.NotNil(t, authErr)
+	cred.Archived = false
+	cred.Version = 0
+	if _, updateErr := service.UpdateOneCredential(*cred); updateErr != nil {
+		t.Fatalf("Failed to restore credential: %v", updateErr)
+	}
+
+	// The secret replaced by a rotation is accepted during the grace period only
+	rotated, rotateErr := service.RotateCredential(credentialId, time.Hour, 0, models.Actor{})
+	if rotateErr != nil {
+		t.Fatalf("Failed to rotate credential: %v", rotateErr)
+	}
+	assert.NotEqual(t, cred.ApiSecret, rotated.ApiSecret)
+	assert.Equal(t, cred.ApiKey, rotated.ApiKey)
+	_, authErr = service.AuthenticateServerAPIKey(rotated.ApiKey, rotated.ApiSecret)
+	assert.Nil(t, authErr)
+	_, authErr = service.AuthenticateServerAPIKey(rotated.ApiKey, cred.ApiSecret)
+	assert.Nil(t, authErr)
+
+	rotatedAgain, rotateErr := service.RotateCredential(credentialId, 0, rotated.Version, models.Actor{})
+	if rotateErr != nil {
+		t.Fatalf("Failed to rotate credential: %v", rotateErr)
+	}
+	_, authErr = service.AuthenticateServerAPIKey(rotatedAgain.ApiKey, rotated.ApiSecret)
+	assert.NotNil(t, authErr)
+	_, authErr = service.AuthenticateServerAPIKey(rotatedAgain.ApiKey, cred.ApiSecret)
+	assert.NotNil(t, authErr)
+
+	_, rotateErr = service.RotateCredential(credentialId, time.Hour, rotated.Version, models.Actor{})
+	assert.NotNil(t, rotateErr)
+	assert.Equal(t, http.StatusPreconditionFailed, rotateErr.Type)
+
+	// Usage is written on flush, without changing the version
+	service.RecordUsage(credentialId, "10.0.0.1")
+	service.RecordUsage(credentialId, "10.0.0.2")
+	beforeFlush, getErr := service.FindOneCredentialByID(credentialId)
+	if getErr != nil {
+		t.Fatalf("Failed to get credential: %v", getErr)
+	}
+	assert.Nil(t, beforeFlush.LastUsedAt)
+
+	service.FlushUsage()
+	afterFlush, getErr := service.FindOneCredentialByID(credentialId)
+	if getErr != nil {
+		t.Fatalf("Failed to get credential: %v", getErr)
+	}
+	assert.NotNil(t, afterFlush.LastUsedAt)
+	assert.Equal(t, "10.0.0.2", afterFlush.LastUsedFrom)
+	assert.Equal(t, beforeFlush.Version, afterFlush.Version)
+}
+
+func Test_CredentialService_HashedSecrets(t *testing.T) {
+	ctx := context.Background()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "credential-service-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	scheduler0config := config.NewScheduler0Config()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+	scheduler0Secrets := secrets.NewScheduler0Secrets()
+	credentialRepo := credential_repository.NewCredentialRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	t.Setenv("SCHEDULER0_SECRET_KEY", "AB551DED82B93DC8035D624A625920E2121367C7538C02277D2D4DB3C0BFFE94")
+
+	dispatcher := utils.NewDispatcher(ctx, int64(1), int64(1))
+	dispatcher.Run()
+
+	service := NewCredentialService(ctx, logger, scheduler0config, scheduler0Secrets, scheduler0Store, credentialRepo, dispatcher, nil)
+
+	// The secret is returned once, only its hash is stored
+	created, createErr := service.CreateNewCredential(models.Credential{})
+	if createErr != nil {
+		t.Fatalf("Failed to create credential: %v", createErr)
+	}
+	assert.NotEmpty(t, created.ApiSecret)
+	assert.True(t, utils.IsSecretHash(created.ApiSecretHash))
+	assert.NotContains(t, created.ApiSecretHash, created.ApiSecret)
+
+	found, getErr := service.FindOneCredentialByID(created.ID)
+	if getErr != nil {
+		t.Fatalf("Failed to get credential: %v", getErr)
+	}
+	assert.Empty(t, found.ApiSecret)
+	data, jsonErr := found.ToJSON()
+	assert.Nil(t, jsonErr)
+	assert.NotContains(t, string(data), found.ApiSecretHash)
+
+	_, authErr := service.AuthenticateServerAPIKey(created.ApiKey, created.ApiSecret)
+	assert.Nil(t, authErr)
+	_, authErr = service.AuthenticateServerAPIKey(created.ApiKey, found.ApiSecretHash)
+	assert.NotNil(t, authErr)
+
+	// Secrets stored in plain text keep working until they are hashed
+	legacyId, legacyErr := credentialRepo.CreateOne(models.Credential{ApiKey: "legacy-api-key", ApiSecretHash: "legacy-api-secret"})
+	if legacyErr != nil {
+		t.Fatalf("Failed to create credential: %v", legacyErr)
+	}
+	_, authErr = service.AuthenticateServerAPIKey("legacy-api-key", "legacy-api-secret")
+	assert.Nil(t, authErr)
+
+	if hashErr := service.HashPlainTextSecrets(); hashErr != nil {
+		t.Fatalf("Failed to hash secrets: %v", hashErr)
+	}
+	legacy, getErr := service.FindOneCredentialByID(legacyId)
+	if getErr != nil {
+		t.Fatalf("Failed to get credential: %v", getErr)
+	}
+	assert.True(t, utils.SecretMatches(legacy.ApiSecretHash, "legacy-api-secret"))
+	assert.Equal(t, uint64(1), legacy.Version)
+	_, authErr = service.AuthenticateServerAPIKey("legacy-api-key", "legacy-api-secret")
+	assert.Nil(t, authErr)
+
+	plainText, listErr := credentialRepo.ListPlainTextSecrets()
+	assert.Nil(t, listErr)
+	assert.Empty(t, plainText)
+}
+
+// testUsageForwarder stands in for the node of a follower, it fails while err is set and records the usage it sends
+type testUsageForwarder struct {
+	err    *utils.GenericError
+	usages [][]models.CredentialUsage
+}
+
+func (forwarder *testUsageForwarder) ForwardCredentialUsage(ctx context.Context, usages []models.CredentialUsage) *utils.GenericError {
+	if forwarder.err != nil {
+		return forwarder.err
+	}
+	forwarder.usages = append(forwarder.usages, usages)
+	return nil
+}
+
+func Test_CredentialService_ForwardUsage(t *testing.T) {
+	ctx := context.Background()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "credential-service-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	forwarder := &testUsageForwarder{err: utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")}
+	service := NewCredentialService(ctx, logger, config.NewScheduler0Config(), secrets.NewScheduler0Secrets(), nil, nil, nil, forwarder).(*credentialService)
+
+	// Usage that cannot be sent to the leader is kept for the next flush
+	service.RecordUsage(1, "10.0.0.1")
+	service.RecordUsage(2, "10.0.0.2")
+	service.forwardUsage()
+	assert.Empty(t, forwarder.usages)
+
+	forwarder.err = nil
+	service.forwardUsage()
+	assert.Equal(t, 1, len(forwarder.usages))
+	assert.Equal(t, 2, len(forwarder.usages[0]))
+	assert.Equal(t, uint64(1), forwarder.usages[0][0].CredentialID)
+	assert.Equal(t, "10.0.0.2", forwarder.usages[0][1].UsedFrom)
+
+	service.forwardUsage()
+	assert.Equal(t, 1, len(forwarder.usages))
+
+	// The leader keeps the latest use of every credential sent by followers
+	usedAt := time.Now().UTC()
+	service.MergeUsage([]models.CredentialUsage{{CredentialID: 1, UsedAt: usedAt, UsedFrom: "10.0.0.3"}})
+	service.MergeUsage([]models.CredentialUsage{
+		{CredentialID: 1, UsedAt: usedAt.Add(-time.Minute), UsedFrom: "10.0.0.4"},
+		{CredentialID: 2, UsedAt: usedAt, UsedFrom: "10.0.0.5"},
+	})
+	assert.Equal(t, "10.0.0.3", service.usages[1].UsedFrom)
+	assert.Equal(t, "10.0.0.5", service.usages[2].UsedFrom)
+}
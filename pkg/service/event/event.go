@@ -0,0 +1,150 @@
+package event
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
+	"scheduler0/pkg/models"
+	"scheduler0/pkg/utils"
+	"sync"
+	"time"
+)
+
+// Subscription receives the events published after it was created that pass its filter.
+// Events is closed when the subscription is cancelled or falls too far behind.
+type Subscription struct {
+	Events <-chan models.Event
+	events chan models.Event
+	filter models.EventFilter
+}
+
+// eventService keeps the recent events published on the node and streams them to subscribers
+type eventService struct {
+	logger           hclog.Logger
+	scheduler0Config config.Scheduler0Config
+	mtx              sync.Mutex
+	epoch            int64
+	lastEventId      uint64
+	events           []models.Event
+	subscriptions    map[*Subscription]bool
+}
+
+//go:generate mockery --name EventService --output ../mocks
+type EventService interface {
+	Publish(events ...models.Event)
+	Subscribe(filter models.EventFilter, cursor models.EventCursor) ([]models.Event, *Subscription, *utils.GenericError)
+	Unsubscribe(subscription *Subscription)
+}
+
+func NewEventService(logger hclog.Logger, scheduler0Config config.Scheduler0Config) EventService {
+	return &eventService{
+		logger:           logger.Named("event-service"),
+		scheduler0Config: scheduler0Config,
+		epoch:            time.Now().UnixNano(),
+		events:           []models.Event{},
+		subscriptions:    map[*Subscription]bool{},
+	}
+}
+
+// Publish assigns the events their ids and sends them to the subscribers whose filter they pass.
+// Subscribers that cannot keep up are disconnected, so publishing never waits on a client.
+func (eventService *eventService) Publish(events ...models.Event) {
+	eventService.mtx.Lock()
+	defer eventService.mtx.Unlock()
+
+	nodeId := eventService.scheduler0Config.GetConfigurations().NodeId
+	now := time.Now().UTC()
+
+	for _, event := range events {
+		eventService.lastEventId += 1
+		event.ID = eventService.lastEventId
+		event.NodeID = nodeId
+		event.Epoch = eventService.epoch
+		if event.DateCreated.IsZero() {
+			event.DateCreated = now
+		}
+
+		eventService.events = append(eventService.events, event)
+
+		for subscription := range eventService.subscriptions {
+			if !subscription.filter.Matches(event) {
+				continue
+			}
+			select {
+			case subscription.events <- event:
+			default:
+				eventService.logger.Warn("disconnecting event subscriber that fell behind", "event-id", event.ID)
+				eventService.removeSubscription(subscription)
+			}
+		}
+	}
+
+	if bufferSize := eventService.bufferSize(); len(eventService.events) > bufferSize {
+		eventService.events = eventService.events[len(eventService.events)-bufferSize:]
+	}
+}
+
+// Subscribe returns the buffered events after the cursor that pass the filter, and a subscription to the events published next.
+// A zero cursor only subscribes to the events published next. The cursor is rejected when it is from another node or an earlier
+// run of the node, when events after it are no longer buffered, or when it is ahead of the last event published, as the client
+// would otherwise miss events.
+func (eventService *eventService) Subscribe(filter models.EventFilter, cursor models.EventCursor) ([]models.Event, *Subscription, *utils.GenericError) {
+	eventService.mtx.Lock()
+	defer eventService.mtx.Unlock()
+
+	missedEvents := []models.Event{}
+
+	if cursor != (models.EventCursor{}) {
+		nodeId := eventService.scheduler0Config.GetConfigurations().NodeId
+		if cursor.NodeID != nodeId || cursor.Epoch != eventService.epoch {
+			return nil, nil, utils.HTTPGenericError(http.StatusGone, fmt.Sprintf("cursor %v is from another node or an earlier run of this node", cursor))
+		}
+		if cursor.EventID > eventService.lastEventId {
+			return nil, nil, utils.HTTPGenericError(http.StatusGone, fmt.Sprintf("cursor %v is ahead of the last event %v published by this node", cursor, eventService.lastEventId))
+		}
+		if len(eventService.events) > 0 && eventService.events[0].ID > cursor.EventID+1 {
+			return nil, nil, utils.HTTPGenericError(http.StatusGone, fmt.Sprintf("events after cursor %v are no longer buffered, the oldest buffered event is %v", cursor, eventService.events[0].ID))
+		}
+		for _, event := range eventService.events {
+			if event.ID > cursor.EventID && filter.Matches(event) {
+				missedEvents = append(missedEvents, event)
+			}
+		}
+	}
+
+	events := make(chan models.Event, constants.EventSubscriberBufferSize)
+	subscription := &Subscription{
+		Events: events,
+		events: events,
+		filter: filter,
+	}
+	eventService.subscriptions[subscription] = true
+
+	return missedEvents, subscription, nil
+}
+
+// Unsubscribe stops sending events to the subscription and closes its channel
+func (eventService *eventService) Unsubscribe(subscription *Subscription) {
+	eventService.mtx.Lock()
+	defer eventService.mtx.Unlock()
+
+	eventService.removeSubscription(subscription)
+}
+
+func (eventService *eventService) removeSubscription(subscription *Subscription) {
+	if _, ok := eventService.subscriptions[subscription]; !ok {
+		return
+	}
+	delete(eventService.subscriptions, subscription)
+	close(subscription.events)
+}
+
+func (eventService *eventService) bufferSize() int {
+	bufferSize := eventService.scheduler0Config.GetConfigurations().EventBufferSize
+	if bufferSize == 0 {
+		bufferSize = constants.DefaultEventBufferSize
+	}
+	return int(bufferSize)
+}
@@ -0,0 +1,159 @@
+package event
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"scheduler0/pkg/config"
+	"scheduler0/pkg/models"
+	"testing"
+)
+
+func newTestEventService(t *testing.T, bufferSize string) EventService {
+	t.Setenv("SCHEDULER0_EVENT_BUFFER_SIZE", bufferSize)
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "event-service-test",
+		Level: hclog.LevelFromString("DEBUG"),
+	})
+	scheduler0config := config.NewScheduler0Config()
+	return NewEventService(logger, scheduler0config)
+}
+
+func Test_EventService_Subscribe_FiltersByProjectAndJob(t *testing.T) {
+	eventService := newTestEventService(t, "10")
+
+	_, projectSubscription, subscribeErr := eventService.Subscribe(models.EventFilter{ProjectID: 1}, models.EventCursor{})
+	assert.Nil(t, subscribeErr)
+	_, jobSubscription, subscribeErr := eventService.Subscribe(models.EventFilter{JobID: 2}, models.EventCursor{})
+	assert.Nil(t, subscribeErr)
+
+	eventService.Publish(
+		models.Event{Type: models.EventTypeJobCreated, ProjectID: 1, JobID: 1},
+		models.Event{Type: models.EventTypeJobCreated, ProjectID: 2, JobID: 2},
+		models.Event{Type: models.EventTypeLeaderChanged},
+	)
+
+	projectEvents := []models.Event{<-projectSubscription.Events, <-projectSubscription.Events}
+	assert.Equal(t, uint64(1), projectEvents[0].ID)
+	assert.Equal(t, uint64(1), projectEvents[0].JobID)
+	assert.False(t, projectEvents[0].DateCreated.IsZero())
+	assert.Equal(t, models.EventTypeLeaderChanged, projectEvents[1].Type)
+	assert.Equal(t, 0, len(projectSubscription.Events))
+
+	jobEvents := []models.Event{<-jobSubscription.Events, <-jobSubscription.Events}
+	assert.Equal(t, uint64(2), jobEvents[0].ID)
+	assert.Equal(t, uint64(2), jobEvents[0].JobID)
+	assert.Equal(t, uint64(3), jobEvents[1].ID)
+	assert.Equal(t, 0, len(jobSubscription.Events))
+
+	eventService.Unsubscribe(projectSubscription)
+	_, open := <-projectSubscription.Events
+	assert.False(t, open)
+}
+
+func Test_EventService_Subscribe_ResumesFromCursor(t *testing.T) {
+	eventService := newTestEventService(t, "3")
+
+	_, subscription, subscribeErr := eventService.Subscribe(models.EventFilter{}, models.EventCursor{})
+	assert.Nil(t, subscribeErr)
+	for i := 0; i < 5; i++ {
+		eventService.Publish(models.Event{Type: models.EventTypeExecutionSucceeded, ProjectID: 1, JobID: uint64(i + 1)})
+	}
+	publishedEvents := []models.Event{}
+	for i := 0; i < 5; i++ {
+		publishedEvents = append(publishedEvents, <-subscription.Events)
+	}
+	eventService.Unsubscribe(subscription)
+	cursor := func(eventId uint64) models.EventCursor {
+		eventCursor := publishedEvents[0].Cursor()
+		eventCursor.EventID = eventId
+		return eventCursor
+	}
+
+	// Events 3 to 5 are buffered, so a client that received event 2 does not miss any
+	missedEvents, subscription, subscribeErr := eventService.Subscribe(models.EventFilter{}, publishedEvents[1].Cursor())
+	assert.Nil(t, subscribeErr)
+	assert.Equal(t, 3, len(missedEvents))
+	assert.Equal(t, uint64(3), missedEvents[0].ID)
+	assert.Equal(t, uint64(5), missedEvents[2].ID)
+	eventService.Unsubscribe(subscription)
+
+	missedEvents, subscription, subscribeErr = eventService.Subscribe(models.EventFilter{JobID: 4}, cursor(2))
+	assert.Nil(t, subscribeErr)
+	assert.Equal(t, 1, len(missedEvents))
+	assert.Equal(t, uint64(4), missedEvents[0].ID)
+	eventService.Unsubscribe(subscription)
+
+	// Event 2 is no longer buffered
+	_, _, subscribeErr = eventService.Subscribe(models.EventFilter{}, cursor(1))
+	assert.NotNil(t, subscribeErr)
+	assert.Equal(t, http.StatusGone, subscribeErr.Type)
+
+	// The cursor is ahead of the events the node published
+	_, _, subscribeErr = eventService.Subscribe(models.EventFilter{}, cursor(6))
+	assert.NotNil(t, subscribeErr)
+	assert.Equal(t, http.StatusGone, subscribeErr.Type)
+}
+
+func Test_EventService_Subscribe_RejectsCursorsOfOtherRuns(t *testing.T) {
+	eventService := newTestEventService(t, "10")
+
+	_, subscription, subscribeErr := eventService.Subscribe(models.EventFilter{}, models.EventCursor{})
+	assert.Nil(t, subscribeErr)
+	eventService.Publish(models.Event{Type: models.EventTypeLeaderChanged}, models.Event{Type: models.EventTypeLeaderChanged})
+	event := <-subscription.Events
+	eventService.Unsubscribe(subscription)
+
+	_, subscription, subscribeErr = eventService.Subscribe(models.EventFilter{}, event.Cursor())
+	assert.Nil(t, subscribeErr)
+	eventService.Unsubscribe(subscription)
+
+	// The ids of another node, or of the node before it restarted, do not follow the ids of the node
+	otherNodeCursor := event.Cursor()
+	otherNodeCursor.NodeID += 1
+	_, _, subscribeErr = eventService.Subscribe(models.EventFilter{}, otherNodeCursor)
+	assert.NotNil(t, subscribeErr)
+	assert.Equal(t, http.StatusGone, subscribeErr.Type)
+
+	restartedEventService := newTestEventService(t, "10")
+	restartedEventService.Publish(models.Event{Type: models.EventTypeLeaderChanged}, models.Event{Type: models.EventTypeLeaderChanged})
+	_, _, subscribeErr = restartedEventService.Subscribe(models.EventFilter{}, event.Cursor())
+	assert.NotNil(t, subscribeErr)
+	assert.Equal(t, http.StatusGone, subscribeErr.Type)
+}
+
+func Test_ParseEventCursor(t *testing.T) {
+	cursor, err := models.ParseEventCursor(models.EventCursor{NodeID: 2, Epoch: 1760000000000000000, EventID: 7}.String())
+	assert.Nil(t, err)
+	assert.Equal(t, models.EventCursor{NodeID: 2, Epoch: 1760000000000000000, EventID: 7}, cursor)
+
+	for _, value := range []string{"7", "2-7", "2-1760000000000000000-7-1", "2-x-7", " 2-1-7"} {
+		_, err = models.ParseEventCursor(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func Test_EventService_Publish_DisconnectsSlowSubscribers(t *testing.T) {
+	eventService := newTestEventService(t, "1000")
+
+	_, subscription, subscribeErr := eventService.Subscribe(models.EventFilter{}, models.EventCursor{})
+	assert.Nil(t, subscribeErr)
+
+	for i := 0; i < 100; i++ {
+		eventService.Publish(models.Event{Type: models.EventTypeExecutionScheduled, ProjectID: 1, JobID: 1})
+	}
+
+	received := 0
+	var lastEvent models.Event
+	for event := range subscription.Events {
+		received += 1
+		lastEvent = event
+	}
+	assert.Less(t, received, 100)
+
+	// The client resumes from the last event it received
+	missedEvents, subscription, subscribeErr := eventService.Subscribe(models.EventFilter{}, lastEvent.Cursor())
+	assert.Nil(t, subscribeErr)
+	assert.Equal(t, 100-received, len(missedEvents))
+	eventService.Unsubscribe(subscription)
+}
@@ -5,21 +5,33 @@ import (
 	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
+	"go.opentelemetry.io/otel/attribute"
 	"math"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/constants"
+	"scheduler0/pkg/encryption"
 	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/logging"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/models"
 	job_repo "scheduler0/pkg/repository/job"
 	job_execution_repo "scheduler0/pkg/repository/job_execution"
 	job_queue_repo "scheduler0/pkg/repository/job_queue"
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/service/executor/executors"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"sync"
 	"time"
 )
 
+// executionStateLabels are the values of the state label of the job executions metric
+var executionStateLabels = map[models.JobExecutionLogState]string{
+	models.ExecutionLogScheduleState: "scheduled",
+	models.ExecutionLogSuccessState:  "success",
+	models.ExecutionLogFailedState:   "failed",
+}
+
 type jobExecutor struct {
 	raft                  *raft.Raft
 	singleNodeMode        bool
@@ -38,11 +50,12 @@ type jobExecutor struct {
 	scheduledJobs         sync.Map
 	scheduler0Config      config.Scheduler0Config
 	scheduler0Actions     fsm.Scheduler0RaftActions
+	keyring               *encryption.Keyring
 }
 
 //go:generate mockery --name JobExecutorService --output ./ --inpackage
 type JobExecutorService interface {
-	QueueExecutions(lastInsertedId, rowsAffected int64)
+	QueueExecutions(ctx context.Context, lastInsertedId, rowsAffected int64)
 	ScheduleJobs(jobs []models.Job)
 	StopAll()
 	AddJobSchedule(job models.Job)
@@ -54,6 +67,8 @@ type JobExecutorService interface {
 	GetScheduledJobs() *sync.Map
 	GetExecutionsCache() *sync.Map
 	DeleteNewUncommittedExecutionLogs(lastInsertedId, rowsAffected int64)
+	RefreshJobSchedules(jobIds []uint64)
+	ResealJobsData() *utils.GenericError
 }
 
 func NewJobExecutor(
@@ -65,7 +80,8 @@ func NewJobExecutor(
 	executionsRepo job_execution_repo.JobExecutionsRepo,
 	jobQueuesRepo job_queue_repo.JobQueuesRepo,
 	httpExecutionHandler executors.HTTPExecutor,
-	dispatcher *utils.Dispatcher) JobExecutorService {
+	dispatcher *utils.Dispatcher,
+	keyring *encryption.Keyring) JobExecutorService {
 	reCtx, cancel := context.WithCancel(ctx)
 	return &jobExecutor{
 		pendingJobInvocations: []models.Job{},
@@ -82,10 +98,14 @@ func NewJobExecutor(
 		dispatcher:            dispatcher,
 		scheduler0Config:      scheduler0Config,
 		scheduler0Actions:     scheduler0Actions,
+		keyring:               keyring,
 	}
 }
 
-func (jobExecutor *jobExecutor) QueueExecutions(lastInsertedId, rowsAffected int64) {
+func (jobExecutor *jobExecutor) QueueExecutions(ctx context.Context, lastInsertedId, rowsAffected int64) {
+	_, span := tracing.StartSpan(ctx, "JobExecutor.QueueExecutions", attribute.Int64("scheduler0.last_inserted_id", lastInsertedId), attribute.Int64("scheduler0.rows_affected", rowsAffected))
+	defer span.End()
+
 	newJobQueueLogs := jobExecutor.
 		jobQueuesRepo.
 		GetJobQueueByLastInsertedAndRowsAffected(lastInsertedId, rowsAffected)
@@ -157,18 +177,18 @@ func (jobExecutor *jobExecutor) ScheduleJobs(jobs []models.Job) {
 		if _, ok := executionLogsMap[job.ID]; !ok {
 			dateCreatedInLocal, err := jobs[i].ConvertTimeToJobTimezone(job.DateCreated)
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to convert date created time for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to convert date created time", "error", err.Error())
 				continue
 			}
 			jobs[i].LastExecutionDate = *dateCreatedInLocal
 			nextExecutionTime, err := jobs[i].GetNextExecutionTime()
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution time for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution time", "error", err.Error())
 				continue
 			}
 			executionId, err := jobs[i].GetNextExecutionId()
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution id for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution id", "error", err.Error())
 				continue
 			}
 			jobs[i].ExecutionId = executionId
@@ -195,13 +215,13 @@ func (jobExecutor *jobExecutor) ScheduleJobs(jobs []models.Job) {
 			} else {
 				uniqueId, err := jobs[i].GetNextExecutionId()
 				if err != nil {
-					jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution id for job with id %d error=%s", job.ID, err.Error()))
+					jobExecutor.jobLogger(job).Error("failed to get next execution id", "error", err.Error())
 					continue
 				}
 				jobs[i].ExecutionId = uniqueId
 			}
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution time for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution time", "error", err.Error())
 				continue
 			}
 			jobExecutor.AddJobSchedule(jobs[i])
@@ -219,12 +239,12 @@ func (jobExecutor *jobExecutor) ScheduleJobs(jobs []models.Job) {
 			jobs[i].LastExecutionDate = jobLastLog.NextExecutionDatetime
 			nextExecutionTime, err := jobs[i].GetNextExecutionTime()
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution time for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution time", "error", err.Error())
 				continue
 			}
 			uniqueId, err := jobs[i].GetNextExecutionId()
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution id for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution id", "error", err.Error())
 				continue
 			}
 			jobs[i].ExecutionId = uniqueId
@@ -245,7 +265,7 @@ func (jobExecutor *jobExecutor) ScheduleJobs(jobs []models.Job) {
 				jobs[i].LastExecutionDate = jobLastLog.LastExecutionDatetime
 				uniqueId, err := jobs[i].GetNextExecutionId()
 				if err != nil {
-					jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution id for job with id %d error=%s", job.ID, err.Error()))
+					jobExecutor.jobLogger(job).Error("failed to get next execution id", "error", err.Error())
 					continue
 				}
 				jobs[i].ExecutionId = uniqueId
@@ -263,12 +283,12 @@ func (jobExecutor *jobExecutor) ScheduleJobs(jobs []models.Job) {
 			jobs[i].LastExecutionDate = jobLastLog.NextExecutionDatetime
 			nextExecutionTime, err := jobs[i].GetNextExecutionTime()
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution time for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution time", "error", err.Error())
 				continue
 			}
 			uniqueId, err := jobs[i].GetNextExecutionId()
 			if err != nil {
-				jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution id for job with id %d error=%s", job.ID, err.Error()))
+				jobExecutor.jobLogger(job).Error("failed to get next execution id", "error", err.Error())
 				continue
 			}
 			jobs[i].ExecutionId = uniqueId
@@ -304,10 +324,11 @@ func (jobExecutor *jobExecutor) ScheduleJobs(jobs []models.Job) {
 		}
 	}
 
-	jobExecutor.jobExecutionsRepo.BatchInsert(jobs, configs.NodeId, models.ExecutionLogScheduleState, lastVersion, lastExecutionVersions)
+	jobExecutor.jobExecutionsRepo.BatchInsert(jobs, configs.NodeId, models.ExecutionLogScheduleState, lastVersion, lastExecutionVersions, models.JobExecutionResult{})
+	jobExecutor.recordJobExecutions(jobs, models.ExecutionLogScheduleState)
 
 	if jobExecutor.singleNodeMode {
-		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(jobs, models.ExecutionLogScheduleState, lastExecutionVersions, lastVersion, configs.NodeId)
+		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(jobs, models.ExecutionLogScheduleState, lastExecutionVersions, lastVersion, configs.NodeId, models.JobExecutionResult{})
 	}
 
 	jobExecutor.logger.Debug("scheduled jobs", "from", jobs[0].ID, "to", jobs[len(jobs)-1].ID)
@@ -327,7 +348,7 @@ func (jobExecutor *jobExecutor) AddJobSchedule(job models.Job) {
 	schedulerTime := scheduler0time.GetSchedulerTime()
 	nextExecutionDateLocal, err := job.GetNextExecutionTime()
 	if err != nil {
-		jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution time for job with id %d error=%s", job.ID, err.Error()))
+		jobExecutor.jobLogger(job).Error("failed to get next execution time", "error", err.Error())
 		return
 	}
 	jobExecutor.scheduledJobs.Store(job.ID, models.JobSchedule{
@@ -336,6 +357,160 @@ func (jobExecutor *jobExecutor) AddJobSchedule(job models.Job) {
 	})
 }
 
+// RefreshJobSchedules drops the schedules of jobs that no longer exist and
+// brings the schedules of the remaining jobs in line with their stored state
+func (jobExecutor *jobExecutor) RefreshJobSchedules(jobIds []uint64) {
+	// Only the jobs this node schedules need to be read, new jobs are scheduled when they are queued
+	scheduledJobIds := make([]uint64, 0, len(jobIds))
+	for _, jobId := range jobIds {
+		if _, ok := jobExecutor.scheduledJobs.Load(jobId); ok {
+			scheduledJobIds = append(scheduledJobIds, jobId)
+		}
+	}
+	jobIds = scheduledJobIds
+	if len(jobIds) < 1 {
+		return
+	}
+
+	jobs, batchGetError := jobExecutor.jobRepo.BatchGetJobsByID(jobIds)
+	if batchGetError != nil {
+		jobExecutor.logger.Error("failed to get jobs to refresh schedules", "error", batchGetError.Message)
+		return
+	}
+
+	currentJobs := make(map[uint64]models.Job, len(jobs))
+	for _, job := range jobs {
+		currentJobs[job.ID] = job
+	}
+
+	for _, jobId := range jobIds {
+		value, ok := jobExecutor.scheduledJobs.Load(jobId)
+		if !ok {
+			continue
+		}
+		jobSchedule := value.(models.JobSchedule)
+
+		currentJob, exists := currentJobs[jobId]
+		if !exists {
+			jobExecutor.scheduledJobs.Delete(jobId)
+			jobExecutor.jobExecutionsCache.Delete(jobId)
+			continue
+		}
+
+		refreshedJob := withCurrentJobState(jobSchedule.Job, currentJob)
+		if refreshedJob.Timezone != jobSchedule.Job.Timezone {
+			jobExecutor.AddJobSchedule(refreshedJob)
+			continue
+		}
+		jobExecutor.scheduledJobs.Store(jobId, models.JobSchedule{
+			Job:           refreshedJob,
+			ExecutionTime: jobSchedule.ExecutionTime,
+		})
+	}
+
+	jobExecutor.logger.Debug("refreshed job schedules", "jobs", len(jobIds))
+}
+
+// ResealJobsData encrypts with the active key the data of the jobs and job revisions in plain text or encrypted by an older key.
+// Jobs and revisions whose data cannot be decrypted are left as they are.
+func (jobExecutor *jobExecutor) ResealJobsData() *utils.GenericError {
+	activeKeyID := jobExecutor.keyring.ActiveKeyID()
+	if activeKeyID == "" {
+		return nil
+	}
+
+	resealed := 0
+	afterId := uint64(0)
+	for {
+		jobs, listErr := jobExecutor.jobRepo.ListJobsToReseal(activeKeyID, afterId, constants.JobsDataResealBatchSize)
+		if listErr != nil {
+			jobExecutor.logger.Error("failed to list jobs to encrypt with the active data encryption key", "error", listErr.Message)
+			return listErr
+		}
+		if len(jobs) < 1 {
+			break
+		}
+		afterId = jobs[len(jobs)-1].ID
+
+		resealedJobs := make([]models.Job, 0, len(jobs))
+		for _, job := range jobs {
+			data, openErr := jobExecutor.keyring.Open(job.Data, job.DataKeyID)
+			if openErr != nil {
+				jobExecutor.jobLogger(job).Error("failed to decrypt job data", "error", openErr.Error())
+				continue
+			}
+			sealedData, dataKeyID, sealErr := jobExecutor.keyring.Seal(data)
+			if sealErr != nil {
+				jobExecutor.jobLogger(job).Error("failed to encrypt job data", "error", sealErr.Error())
+				continue
+			}
+			job.Data = sealedData
+			job.DataKeyID = dataKeyID
+			resealedJobs = append(resealedJobs, job)
+		}
+
+		if updateErr := jobExecutor.jobRepo.UpdateJobsData(resealedJobs); updateErr != nil {
+			jobExecutor.logger.Error("failed to encrypt jobs data with the active data encryption key", "error", updateErr.Message)
+			return updateErr
+		}
+		resealed += len(resealedJobs)
+	}
+
+	if resealed > 0 {
+		jobExecutor.logger.Info("encrypted jobs data with the active data encryption key", "jobs", resealed, "key-id", activeKeyID)
+	}
+
+	return jobExecutor.resealJobRevisionsData(activeKeyID)
+}
+
+// resealJobRevisionsData encrypts with the active key the data in the snapshots of the job revisions,
+// which keep the data as it was encrypted when they were recorded
+func (jobExecutor *jobExecutor) resealJobRevisionsData(activeKeyID string) *utils.GenericError {
+	resealed := 0
+	afterJobId := uint64(0)
+	afterRevision := uint64(0)
+	for {
+		jobRevisions, listErr := jobExecutor.jobRepo.ListJobRevisionsToReseal(activeKeyID, afterJobId, afterRevision, constants.JobsDataResealBatchSize)
+		if listErr != nil {
+			jobExecutor.logger.Error("failed to list job revisions to encrypt with the active data encryption key", "error", listErr.Message)
+			return listErr
+		}
+		if len(jobRevisions) < 1 {
+			break
+		}
+		afterJobId = jobRevisions[len(jobRevisions)-1].JobID
+		afterRevision = jobRevisions[len(jobRevisions)-1].Revision
+
+		resealedJobRevisions := make([]models.JobRevision, 0, len(jobRevisions))
+		for _, jobRevision := range jobRevisions {
+			data, openErr := jobExecutor.keyring.Open(jobRevision.Job.Data, jobRevision.Job.DataKeyID)
+			if openErr != nil {
+				jobExecutor.jobLogger(models.Job{ID: jobRevision.JobID}).Error("failed to decrypt job revision data", "revision", jobRevision.Revision, "error", openErr.Error())
+				continue
+			}
+			sealedData, dataKeyID, sealErr := jobExecutor.keyring.Seal(data)
+			if sealErr != nil {
+				jobExecutor.jobLogger(models.Job{ID: jobRevision.JobID}).Error("failed to encrypt job revision data", "revision", jobRevision.Revision, "error", sealErr.Error())
+				continue
+			}
+			jobRevision.Job.Data = sealedData
+			jobRevision.Job.DataKeyID = dataKeyID
+			resealedJobRevisions = append(resealedJobRevisions, jobRevision)
+		}
+
+		if updateErr := jobExecutor.jobRepo.UpdateJobRevisionsData(resealedJobRevisions); updateErr != nil {
+			jobExecutor.logger.Error("failed to encrypt job revisions data with the active data encryption key", "error", updateErr.Message)
+			return updateErr
+		}
+		resealed += len(resealedJobRevisions)
+	}
+
+	if resealed > 0 {
+		jobExecutor.logger.Info("encrypted job revisions data with the active data encryption key", "revisions", resealed, "key-id", activeKeyID)
+	}
+	return nil
+}
+
 func (jobExecutor *jobExecutor) ListenForJobsToInvoke() {
 	ticker := time.NewTicker(time.Duration(1) * time.Second)
 	schedulerTime := scheduler0time.GetSchedulerTime()
@@ -425,7 +600,7 @@ func (jobExecutor *jobExecutor) reschedule(jobs []models.Job, newState models.Jo
 		jobs[i].LastExecutionDate = lastExecution.NextExecutionDatetime
 		executionId, err := jobs[i].GetNextExecutionId()
 		if err != nil {
-			jobExecutor.logger.Error(fmt.Sprintf("failed to get next execution id for job with id %d error=%s", job.ID, err.Error()))
+			jobExecutor.jobLogger(job).Error("failed to get next execution id", "error", err.Error())
 			continue
 		}
 		executionTime, err := jobs[i].GetNextExecutionTime()
@@ -457,9 +632,10 @@ func (jobExecutor *jobExecutor) reschedule(jobs []models.Job, newState models.Jo
 		}
 	}
 
-	jobExecutor.jobExecutionsRepo.BatchInsert(jobsToReschedule, configs.NodeId, models.ExecutionLogScheduleState, lastVersion, lastExecutionVersions)
+	jobExecutor.jobExecutionsRepo.BatchInsert(jobsToReschedule, configs.NodeId, models.ExecutionLogScheduleState, lastVersion, lastExecutionVersions, models.JobExecutionResult{})
+	jobExecutor.recordJobExecutions(jobsToReschedule, models.ExecutionLogScheduleState)
 	if jobExecutor.singleNodeMode {
-		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(jobsToReschedule, models.ExecutionLogScheduleState, lastExecutionVersions, lastVersion, configs.NodeId)
+		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(jobsToReschedule, models.ExecutionLogScheduleState, lastExecutionVersions, lastVersion, configs.NodeId, models.JobExecutionResult{})
 	}
 }
 
@@ -531,14 +707,40 @@ func (jobExecutor *jobExecutor) invokeJob(pendingJob models.Job) {
 		jobExecutor.logger.Debug(fmt.Sprintf("batched queried %v", len(jobs)))
 
 		jobsToExecute := make([]models.Job, 0)
+		undecryptableJobs := make([]models.Job, 0)
 
 		for _, job := range jobs {
 			pendingJobInvocation := getPendingJob(job.ID)
-			if pendingJobInvocation != nil {
-				jobsToExecute = append(jobsToExecute, *pendingJobInvocation)
+			if pendingJobInvocation == nil {
+				continue
+			}
+			currentJob := withCurrentJobState(*pendingJobInvocation, job)
+			// Paused jobs keep their place in the schedule without being executed
+			if job.Status == models.JobStatusPaused {
+				jobExecutor.AddJobSchedule(currentJob)
+				continue
+			}
+			// Data encrypted at rest is only decrypted here, right before it is sent to the callback
+			data, openErr := jobExecutor.keyring.Open(currentJob.Data, currentJob.DataKeyID)
+			if openErr != nil {
+				jobExecutor.jobLogger(currentJob).Error("failed to decrypt job data", "error", openErr.Error())
+				undecryptableJobs = append(undecryptableJobs, currentJob)
+				continue
 			}
+			currentJob.Data = data
+			currentJob.DataKeyID = ""
+			jobsToExecute = append(jobsToExecute, currentJob)
+		}
+
+		if len(undecryptableJobs) > 0 {
+			// handleFailedJobs takes the lock held by this invocation
+			go jobExecutor.handleFailedJobs(undecryptableJobs, models.JobExecutionResult{Error: "failed to decrypt job data"})
 		}
 
+		// Invocations are batched across schedules so every batch starts its own trace
+		ctx, span := tracing.StartSpan(context.Background(), "JobExecutor.invokeJobs", attribute.Int("scheduler0.jobs", len(jobsToExecute)))
+		defer span.End()
+
 		jobsByType := make(map[string][]models.Job)
 
 		for _, job := range jobsToExecute {
@@ -548,7 +750,7 @@ func (jobExecutor *jobExecutor) invokeJob(pendingJob models.Job) {
 		for executionType, jobs := range jobsByType {
 			switch executionType {
 			case string(models.ExecutionTypeHTTP):
-				jobExecutor.httpExecutionHandler.ExecuteHTTPJob(jobs, jobExecutor.handleSuccessJobs, jobExecutor.handleFailedJobs)
+				jobExecutor.httpExecutionHandler.ExecuteHTTPJob(ctx, jobs, jobExecutor.handleSuccessJobs, jobExecutor.handleFailedJobs)
 			default:
 				jobExecutor.logger.Error(fmt.Sprintf("unrecognized execution %s", executionType))
 			}
@@ -558,7 +760,7 @@ func (jobExecutor *jobExecutor) invokeJob(pendingJob models.Job) {
 	})
 }
 
-func (jobExecutor *jobExecutor) handleSuccessJobs(successfulJobs []models.Job) {
+func (jobExecutor *jobExecutor) handleSuccessJobs(successfulJobs []models.Job, result models.JobExecutionResult) {
 	configs := jobExecutor.scheduler0Config.GetConfigurations()
 	lastVersion := jobExecutor.jobQueuesRepo.GetLastVersion()
 	jobIds := make([]uint64, 0, len(successfulJobs))
@@ -578,17 +780,18 @@ func (jobExecutor *jobExecutor) handleSuccessJobs(successfulJobs []models.Job) {
 	}
 	jobExecutor.mtx.Unlock()
 
-	jobExecutor.jobExecutionsRepo.BatchInsert(successfulJobs, configs.NodeId, models.ExecutionLogSuccessState, lastVersion, lastExecutionVersions)
+	jobExecutor.jobExecutionsRepo.BatchInsert(successfulJobs, configs.NodeId, models.ExecutionLogSuccessState, lastVersion, lastExecutionVersions, result)
+	jobExecutor.recordJobExecutions(successfulJobs, models.ExecutionLogSuccessState)
 	if jobExecutor.singleNodeMode {
-		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(successfulJobs, models.ExecutionLogSuccessState, lastExecutionVersions, lastVersion, configs.NodeId)
+		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(successfulJobs, models.ExecutionLogSuccessState, lastExecutionVersions, lastVersion, configs.NodeId, result)
 	}
 	jobExecutor.reschedule(successfulJobs, models.ExecutionLogSuccessState)
 }
 
-func (jobExecutor *jobExecutor) handleFailedJobs(erroredJobs []models.Job) {
+func (jobExecutor *jobExecutor) handleFailedJobs(erroredJobs []models.Job, result models.JobExecutionResult) {
 	configs := jobExecutor.scheduler0Config.GetConfigurations()
 	for _, erroredJob := range erroredJobs {
-		jobExecutor.logger.Error(fmt.Sprintf("failed to execute job %v", erroredJob.ID))
+		jobExecutor.jobLogger(erroredJob).Error("failed to execute job", "error", result.Error)
 	}
 	lastVersion := jobExecutor.jobQueuesRepo.GetLastVersion()
 
@@ -609,9 +812,36 @@ func (jobExecutor *jobExecutor) handleFailedJobs(erroredJobs []models.Job) {
 	}
 	jobExecutor.mtx.Unlock()
 
-	jobExecutor.jobExecutionsRepo.BatchInsert(erroredJobs, configs.NodeId, models.ExecutionLogFailedState, lastVersion, lastExecutionVersions)
+	jobExecutor.jobExecutionsRepo.BatchInsert(erroredJobs, configs.NodeId, models.ExecutionLogFailedState, lastVersion, lastExecutionVersions, result)
+	jobExecutor.recordJobExecutions(erroredJobs, models.ExecutionLogFailedState)
 	if jobExecutor.singleNodeMode {
-		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(erroredJobs, models.ExecutionLogFailedState, lastExecutionVersions, lastVersion, configs.NodeId)
+		jobExecutor.jobExecutionsRepo.LogJobExecutionStateInRaft(erroredJobs, models.ExecutionLogFailedState, lastExecutionVersions, lastVersion, configs.NodeId, result)
 	}
 	jobExecutor.reschedule(erroredJobs, models.ExecutionLogFailedState)
 }
+
+// jobLogger returns the logger of the executor labeled with the id and execution id of job
+func (jobExecutor *jobExecutor) jobLogger(job models.Job) hclog.Logger {
+	ctx := logging.ContextWithExecutionID(logging.ContextWithJobID(jobExecutor.context, job.ID), job.ExecutionId)
+	return logging.FromContext(ctx, jobExecutor.logger)
+}
+
+// recordJobExecutions counts the execution logs written for jobs in the job executions metric
+func (jobExecutor *jobExecutor) recordJobExecutions(jobs []models.Job, state models.JobExecutionLogState) {
+	for _, job := range jobs {
+		metrics.JobExecutions.WithLabelValues(executionStateLabels[state], job.ExecutionType).Inc()
+	}
+}
+
+// withCurrentJobState copies the user editable fields of the stored job into the scheduled job,
+// keeping the execution state tracked by the scheduled job
+func withCurrentJobState(scheduledJob models.Job, currentJob models.Job) models.Job {
+	scheduledJob.CallbackUrl = currentJob.CallbackUrl
+	scheduledJob.Data = currentJob.Data
+	scheduledJob.DataKeyID = currentJob.DataKeyID
+	scheduledJob.ExecutionType = currentJob.ExecutionType
+	scheduledJob.Timezone = currentJob.Timezone
+	scheduledJob.TimezoneOffset = currentJob.TimezoneOffset
+	scheduledJob.Status = currentJob.Status
+	return scheduledJob
+}
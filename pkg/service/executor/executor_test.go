@@ -95,7 +95,7 @@ func Test_JobExecutor_QueueExecutions_JobsLessThanJobMaxBatch(t *testing.T) {
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	queueRepo.SetSingleNodeMode(true)
 	// Create a new JobService instance
-	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	httpJobExecutor := executors.NewMockHTTPExecutor(t)
 
@@ -109,6 +109,7 @@ func Test_JobExecutor_QueueExecutions_JobsLessThanJobMaxBatch(t *testing.T) {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
 
 	service.SetSingleNodeMode(true)
@@ -145,7 +146,7 @@ func Test_JobExecutor_QueueExecutions_JobsLessThanJobMaxBatch(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+	_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -159,6 +160,7 @@ func Test_JobExecutor_QueueExecutions_JobsLessThanJobMaxBatch(t *testing.T) {
 	}
 
 	service.QueueExecutions(
+		context.Background(),
 		int64(1),
 		int64(2),
 	)
@@ -233,7 +235,7 @@ func Test_JobExecutor_QueueExecutions_JobsMoreThanJobMaxBatch(t *testing.T) {
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	queueRepo.SetSingleNodeMode(true)
 	// Create a new JobService instance
-	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	httpJobExecutor := executors.NewMockHTTPExecutor(t)
 
@@ -247,6 +249,7 @@ func Test_JobExecutor_QueueExecutions_JobsMoreThanJobMaxBatch(t *testing.T) {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
 
 	asyncTaskManager.SetSingleNodeMode(true)
@@ -278,7 +281,7 @@ func Test_JobExecutor_QueueExecutions_JobsMoreThanJobMaxBatch(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+	_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -291,6 +294,7 @@ func Test_JobExecutor_QueueExecutions_JobsMoreThanJobMaxBatch(t *testing.T) {
 		t.Fatal("failed to set env", serr)
 	}
 	service.QueueExecutions(
+		context.Background(),
 		int64(1),
 		int64(constants.JobMaxBatchSize+100),
 	)
@@ -366,7 +370,7 @@ func Test_JobExecutor_QueueExecutions_DoesNotQueueJobsForOtherServers(t *testing
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	httpJobExecutor := executors.NewMockHTTPExecutor(t)
 
@@ -380,6 +384,7 @@ func Test_JobExecutor_QueueExecutions_DoesNotQueueJobsForOtherServers(t *testing
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
 
 	asyncTaskManager.SetSingleNodeMode(true)
@@ -411,7 +416,7 @@ func Test_JobExecutor_QueueExecutions_DoesNotQueueJobsForOtherServers(t *testing
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+	_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -425,6 +430,7 @@ func Test_JobExecutor_QueueExecutions_DoesNotQueueJobsForOtherServers(t *testing
 	}
 
 	service.QueueExecutions(
+		context.Background(),
 		int64(1),
 		int64(constants.JobMaxBatchSize+100),
 	)
@@ -512,7 +518,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 		queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 		queueRepo.SetSingleNodeMode(true)
 		// Create a new JobService instance
-		jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+		jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 		httpJobExecutor := executors.NewMockHTTPExecutor(t)
 
@@ -526,6 +532,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 			jobQueueRepo,
 			httpJobExecutor,
 			dispatcher,
+			nil,
 		)
 
 		asyncTaskManager.SetSingleNodeMode(true)
@@ -567,7 +574,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 		}
 
 		// Call the BatchInsertJobs method of the job service
-		_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+		_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 		if batchErr != nil {
 			t.Fatalf("Failed to insert jobs: %v", batchErr)
 		}
@@ -613,6 +620,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 			t.Fatal("failed to insert execution logs", err)
 		}
 		service.QueueExecutions(
+			context.Background(),
 			int64(1),
 			int64(100),
 		)
@@ -704,7 +712,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 		queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 		queueRepo.SetSingleNodeMode(true)
 		// Create a new JobService instance
-		jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+		jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 		httpJobExecutor := executors.NewMockHTTPExecutor(t)
 
@@ -718,6 +726,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 			jobQueueRepo,
 			httpJobExecutor,
 			dispatcher,
+			nil,
 		)
 
 		asyncTaskManager.SetSingleNodeMode(true)
@@ -762,7 +771,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 		}
 
 		// Call the BatchInsertJobs method of the job service
-		_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+		_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 		if batchErr != nil {
 			t.Fatalf("Failed to insert jobs: %v", batchErr)
 		}
@@ -808,6 +817,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 			t.Fatal("failed to insert execution logs", err)
 		}
 		service.QueueExecutions(
+			context.Background(),
 			int64(1),
 			int64(100),
 		)
@@ -898,7 +908,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 		queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 		queueRepo.SetSingleNodeMode(true)
 		// Create a new JobService instance
-		jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+		jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 		httpJobExecutor := executors.NewMockHTTPExecutor(t)
 
 		service := NewJobExecutor(
@@ -911,6 +921,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 			jobQueueRepo,
 			httpJobExecutor,
 			dispatcher,
+			nil,
 		)
 
 		asyncTaskManager.SetSingleNodeMode(true)
@@ -955,7 +966,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 		}
 
 		// Call the BatchInsertJobs method of the job service
-		_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+		_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 		if batchErr != nil {
 			t.Fatalf("Failed to insert jobs: %v", batchErr)
 		}
@@ -1001,6 +1012,7 @@ func Test_JobExecutor_ScheduleJobs_WithScheduledStateAsLastKnowState_NextTimeExe
 			t.Fatal("failed to insert execution logs", err)
 		}
 		service.QueueExecutions(
+			context.Background(),
 			int64(1),
 			int64(100),
 		)
@@ -1079,9 +1091,9 @@ func Test_ListenForJobsToInvoke(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 	httpJobExecutor := executors.NewMockHTTPExecutor(t)
-	httpJobExecutor.On("ExecuteHTTPJob", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	httpJobExecutor.On("ExecuteHTTPJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	service := NewJobExecutor(
 		ctx,
 		logger,
@@ -1092,6 +1104,7 @@ func Test_ListenForJobsToInvoke(t *testing.T) {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
 
 	asyncTaskManager.SetSingleNodeMode(true)
@@ -1132,7 +1145,7 @@ func Test_ListenForJobsToInvoke(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+	_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -1218,7 +1231,7 @@ func Test_handleFailedJobs(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := job.NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	httpJobExecutor := executors.NewHTTTPExecutor(logger, ctx, scheduler0config, dispatcher)
 	service := NewJobExecutor(
@@ -1231,6 +1244,7 @@ func Test_handleFailedJobs(t *testing.T) {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
 
 	service.ListenForJobsToInvoke()
@@ -1265,7 +1279,7 @@ func Test_handleFailedJobs(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+	_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -1382,6 +1396,7 @@ func Test_StopAll(t *testing.T) {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
 
 	for i := 0; i < 10; i++ {
@@ -7,9 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
 	"net/http"
 	"scheduler0/pkg/config"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/models"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"strconv"
 	"time"
@@ -24,7 +29,7 @@ type HTTPExecutionHandler struct {
 
 //go:generate mockery --name HTTPExecutor --output ./ --inpackage
 type HTTPExecutor interface {
-	ExecuteHTTPJob(pendingJobs []models.Job, successCallback func(jobs []models.Job), errorCallback func(jobs []models.Job))
+	ExecuteHTTPJob(ctx context.Context, pendingJobs []models.Job, successCallback func(jobs []models.Job, result models.JobExecutionResult), errorCallback func(jobs []models.Job, result models.JobExecutionResult))
 }
 
 func NewHTTTPExecutor(logger hclog.Logger, ctx context.Context, config config.Scheduler0Config, dispatcher *utils.Dispatcher) HTTPExecutor {
@@ -36,7 +41,7 @@ func NewHTTTPExecutor(logger hclog.Logger, ctx context.Context, config config.Sc
 	}
 }
 
-func (httpExecutor *HTTPExecutionHandler) ExecuteHTTPJob(pendingJobs []models.Job, successCallback func(jobs []models.Job), errorCallback func(jobs []models.Job)) {
+func (httpExecutor *HTTPExecutionHandler) ExecuteHTTPJob(ctx context.Context, pendingJobs []models.Job, successCallback func(jobs []models.Job, result models.JobExecutionResult), errorCallback func(jobs []models.Job, result models.JobExecutionResult)) {
 	urlJobCache := map[string][]models.Job{}
 
 	for _, pj := range pendingJobs {
@@ -61,6 +66,15 @@ func (httpExecutor *HTTPExecutionHandler) ExecuteHTTPJob(pendingJobs []models.Jo
 						close(successChannel)
 					}()
 					err := utils.RetryOnError(func() error {
+						spanCtx, span := tracing.StartClientSpan(
+							ctx,
+							"HTTPExecutor.callback",
+							semconv.HTTPMethodKey.String(http.MethodPost),
+							semconv.HTTPURLKey.String(url),
+							attribute.Int("scheduler0.payload_chunk_id", chunkId),
+						)
+						defer span.End()
+
 						httpExecutor.logger.Info(fmt.Sprintf("running job execution for job callback url = %v", url))
 						httpClient := http.Client{
 							Timeout: time.Duration(configs.JobExecutionTimeout) * time.Second,
@@ -69,26 +83,37 @@ func (httpExecutor *HTTPExecutionHandler) ExecuteHTTPJob(pendingJobs []models.Jo
 						req, err := http.NewRequestWithContext(httpExecutor.ctx, http.MethodPost, url, bytes.NewReader(b))
 						if err != nil {
 							httpExecutor.logger.Error("failed to create request: ", "error", err.Error())
-							errorCallback(httpExecutor.unwrapBatch(b))
+							errorCallback(httpExecutor.unwrapBatch(b), models.JobExecutionResult{Error: err.Error()})
 							return err
 						}
 						req.Header.Set("Content-Type", "application/json")
 						req.Header.Set("x-payload-chunk-id", strconv.FormatInt(int64(chunkId), 10))
+						tracing.InjectHTTPHeaders(spanCtx, req.Header)
 
+						startTime := time.Now()
 						res, err := httpClient.Do(req)
+						latency := time.Since(startTime)
 						if err != nil {
+							metrics.CallbackLatency.WithLabelValues(string(models.ExecutionTypeHTTP), "failed").Observe(latency.Seconds())
+							span.SetStatus(codes.Error, err.Error())
 							httpExecutor.logger.Error("request error: ", err.Error())
-							errorCallback(httpExecutor.unwrapBatch(b))
+							errorCallback(httpExecutor.unwrapBatch(b), models.JobExecutionResult{Error: err.Error(), Latency: latency})
 							return err
 						}
+						res.Body.Close()
+						span.SetAttributes(semconv.HTTPStatusCodeKey.Int(res.StatusCode))
 
-						if res.StatusCode >= 200 || res.StatusCode <= 299 {
-							successCallback(httpExecutor.unwrapBatch(b))
+						if res.StatusCode >= 200 && res.StatusCode <= 299 {
+							metrics.CallbackLatency.WithLabelValues(string(models.ExecutionTypeHTTP), "success").Observe(latency.Seconds())
+							successCallback(httpExecutor.unwrapBatch(b), models.JobExecutionResult{Latency: latency})
 							return nil
 						}
 
-						errorCallback(httpExecutor.unwrapBatch(b))
-						return errors.New(fmt.Sprintf("subscriber failed to fully requests status code: %v", res.StatusCode))
+						metrics.CallbackLatency.WithLabelValues(string(models.ExecutionTypeHTTP), "failed").Observe(latency.Seconds())
+						statusErr := errors.New(fmt.Sprintf("subscriber failed to fully requests status code: %v", res.StatusCode))
+						span.SetStatus(codes.Error, statusErr.Error())
+						errorCallback(httpExecutor.unwrapBatch(b), models.JobExecutionResult{Error: statusErr.Error(), Latency: latency})
+						return statusErr
 					}, configs.JobExecutionRetryMax, configs.JobExecutionRetryDelay)
 					if err != nil {
 						httpExecutor.logger.Error("failed to execute jobs after retrying", "error", err)
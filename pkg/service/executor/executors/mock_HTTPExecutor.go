@@ -3,6 +3,7 @@
 package executors
 
 import (
+	"context"
 	models "scheduler0/pkg/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,9 +14,9 @@ type MockHTTPExecutor struct {
 	mock.Mock
 }
 
-// ExecuteHTTPJob provides a mock function with given fields: pendingJobs, successCallback, errorCallback
-func (_m *MockHTTPExecutor) ExecuteHTTPJob(pendingJobs []models.Job, successCallback func([]models.Job), errorCallback func([]models.Job)) {
-	_m.Called(pendingJobs, successCallback, errorCallback)
+// ExecuteHTTPJob provides a mock function with given fields: ctx, pendingJobs, successCallback, errorCallback
+func (_m *MockHTTPExecutor) ExecuteHTTPJob(ctx context.Context, pendingJobs []models.Job, successCallback func([]models.Job, models.JobExecutionResult), errorCallback func([]models.Job, models.JobExecutionResult)) {
+	_m.Called(ctx, pendingJobs, successCallback, errorCallback)
 }
 
 type mockConstructorTestingTNewMockHTTPExecutor interface {
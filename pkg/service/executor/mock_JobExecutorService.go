@@ -3,6 +3,7 @@
 package executor
 
 import (
+	"context"
 	models "scheduler0/pkg/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -10,6 +11,8 @@ import (
 	raft "github.com/hashicorp/raft"
 
 	sync "sync"
+
+	utils "scheduler0/pkg/utils"
 )
 
 // MockJobExecutorService is an autogenerated mock type for the JobExecutorService type
@@ -94,9 +97,30 @@ func (_m *MockJobExecutorService) ListenForJobsToInvoke() {
 	_m.Called()
 }
 
-// QueueExecutions provides a mock function with given fields: lastInsertedId, rowsAffected
-func (_m *MockJobExecutorService) QueueExecutions(lastInsertedId int64, rowsAffected int64) {
-	_m.Called(lastInsertedId, rowsAffected)
+// QueueExecutions provides a mock function with given fields: ctx, lastInsertedId, rowsAffected
+func (_m *MockJobExecutorService) QueueExecutions(ctx context.Context, lastInsertedId int64, rowsAffected int64) {
+	_m.Called(ctx, lastInsertedId, rowsAffected)
+}
+
+// RefreshJobSchedules provides a mock function with given fields: jobIds
+func (_m *MockJobExecutorService) RefreshJobSchedules(jobIds []uint64) {
+	_m.Called(jobIds)
+}
+
+// ResealJobsData provides a mock function with given fields:
+func (_m *MockJobExecutorService) ResealJobsData() *utils.GenericError {
+	ret := _m.Called()
+
+	var r0 *utils.GenericError
+	if rf, ok := ret.Get(0).(func() *utils.GenericError); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.GenericError)
+		}
+	}
+
+	return r0
 }
 
 // ScheduleJobs provides a mock function with given fields: jobs
@@ -2,19 +2,26 @@ package job
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/robfig/cron"
+	"go.opentelemetry.io/otel/attribute"
 	"net/http"
 	"scheduler0/pkg/constants"
+	"scheduler0/pkg/encryption"
+	"scheduler0/pkg/logging"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/repository/job"
 	"scheduler0/pkg/repository/project"
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/service/async_task"
 	"scheduler0/pkg/service/queue"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -26,16 +33,25 @@ type jobService struct {
 	logger           hclog.Logger
 	dispatcher       *utils.Dispatcher
 	asyncTaskManager async_task.AsyncTaskService
+	keyring          *encryption.Keyring
 }
 
 //go:generate mockery --name JobService --output ../mocks
 type JobService interface {
 	GetJobsByProjectID(projectID uint64, offset uint64, limit uint64, orderBy string) (*models.PaginatedJob, *utils.GenericError)
 	GetJob(job models.Job) (*models.Job, *utils.GenericError)
-	BatchInsertJobs(requestId string, jobs []models.Job) ([]uint64, *utils.GenericError)
+	BatchInsertJobs(ctx context.Context, requestId string, jobs []models.Job) ([]uint64, *utils.GenericError)
+	BatchInsertJobsWithIdempotencyKey(ctx context.Context, requestId string, idempotencyKey string, jobs []models.Job) ([]uint64, string, *utils.GenericError)
 	UpdateJob(job models.Job) (*models.Job, *utils.GenericError)
 	DeleteJob(job models.Job) *utils.GenericError
 	QueueJobs(jobs []models.Job)
+	ListJobs(filter models.JobFilter) (*models.PaginatedJob, *utils.GenericError)
+	UpdateJobsStatus(filter models.JobFilter, status models.JobStatus) (uint64, *utils.GenericError)
+	DeleteJobs(filter models.JobFilter) (uint64, *utils.GenericError)
+	BatchUpdateJobs(ctx context.Context, requestId string, jobs []models.Job) ([]uint64, *utils.GenericError)
+	BatchDeleteJobs(ctx context.Context, requestId string, jobIds []uint64, actor models.Actor) ([]uint64, *utils.GenericError)
+	GetJobRevisions(jobId uint64, offset uint64, limit uint64) (*models.PaginatedJobRevisions, *utils.GenericError)
+	RollbackJob(jobId uint64, revision uint64, actor models.Actor) (*models.Job, *utils.GenericError)
 }
 
 func NewJobService(
@@ -46,6 +62,7 @@ func NewJobService(
 	projectRepo project.ProjectRepo,
 	dispatcher *utils.Dispatcher,
 	asyncTaskService async_task.AsyncTaskService,
+	keyring *encryption.Keyring,
 ) JobService {
 	service := &jobService{
 		jobRepo:          jobRepo,
@@ -55,6 +72,7 @@ func NewJobService(
 		logger:           logger,
 		dispatcher:       dispatcher,
 		asyncTaskManager: asyncTaskService,
+		keyring:          keyring,
 	}
 
 	return service
@@ -76,6 +94,8 @@ func (jobService *jobService) GetJobsByProjectID(projectID uint64, offset uint64
 		return nil, err
 	}
 
+	redactJobsData(jobManagers)
+
 	paginatedJobs := models.PaginatedJob{}
 	paginatedJobs.Data = jobManagers
 	paginatedJobs.Limit = limit
@@ -91,32 +111,140 @@ func (jobService *jobService) GetJob(job models.Job) (*models.Job, *utils.Generi
 	if jobMangerGetOneError != nil {
 		return nil, jobMangerGetOneError
 	}
+	redactJobData(&job)
 
 	return &job, nil
 }
 
 // BatchInsertJobs creates jobs in batches
-func (jobService *jobService) BatchInsertJobs(requestId string, jobs []models.Job) ([]uint64, *utils.GenericError) {
+func (jobService *jobService) BatchInsertJobs(ctx context.Context, requestId string, jobs []models.Job) ([]uint64, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "JobService.BatchInsertJobs", attribute.Int("scheduler0.jobs", len(jobs)))
+	defer span.End()
+
 	if len(jobs) < 1 {
 		return nil, nil
 	}
 
+	if validationErr := jobService.validateNewJobs(jobs); validationErr != nil {
+		return nil, validationErr
+	}
+
+	jobs, sealErr := jobService.sealJobsData(jobs)
+	if sealErr != nil {
+		return nil, sealErr
+	}
+
+	jobsBytes, marshalErr := json.Marshal(jobs)
+	if marshalErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to convert json to string"))
+	}
+
+	return jobService.createJobsAsyncTask(ctx, requestId, jobs, jobsBytes)
+}
+
+// BatchInsertJobsWithIdempotencyKey creates jobs in batches at most once per idempotency key.
+// Replaying a key returns the async task ids and the request id of the request that first used it.
+func (jobService *jobService) BatchInsertJobsWithIdempotencyKey(ctx context.Context, requestId string, idempotencyKey string, jobs []models.Job) ([]uint64, string, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "JobService.BatchInsertJobsWithIdempotencyKey", attribute.Int("scheduler0.jobs", len(jobs)))
+	defer span.End()
+
+	if len(jobs) < 1 {
+		return nil, requestId, nil
+	}
+
+	if validationErr := jobService.validateNewJobs(jobs); validationErr != nil {
+		return nil, "", validationErr
+	}
+
+	jobsBytes, marshalErr := json.Marshal(jobs)
+	if marshalErr != nil {
+		return nil, "", utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to convert json to string"))
+	}
+	// The hash is of the jobs as requested, since encrypting their data gives a different ciphertext every time
+	requestHash := fmt.Sprintf("%x", sha256.Sum256(jobsBytes))
+
+	jobs, sealErr := jobService.sealJobsData(jobs)
+	if sealErr != nil {
+		return nil, "", sealErr
+	}
+	jobsBytes, marshalErr = json.Marshal(jobs)
+	if marshalErr != nil {
+		return nil, "", utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to convert json to string"))
+	}
+
+	// Keys are scoped to the credential sending the request so clients cannot collide with or replay each other's keys
+	credentialId := jobs[0].Actor.CredentialID
+	claimed, claimErr := jobService.jobRepo.ClaimIdempotencyKey(models.IdempotencyKey{
+		CredentialId: credentialId,
+		Key:          idempotencyKey,
+		RequestId:    requestId,
+		RequestHash:  requestHash,
+	})
+	if claimErr != nil {
+		return nil, "", claimErr
+	}
+
+	if !claimed {
+		storedKey, getErr := jobService.jobRepo.GetIdempotencyKey(credentialId, idempotencyKey)
+		if getErr != nil {
+			return nil, "", getErr
+		}
+		if storedKey.RequestHash != requestHash {
+			return nil, "", utils.HTTPGenericError(http.StatusUnprocessableEntity, "idempotency key has already been used with a different request payload")
+		}
+		if storedKey.TaskId == 0 {
+			return nil, "", utils.HTTPGenericError(http.StatusConflict, "a request with this idempotency key is still being processed")
+		}
+		return []uint64{storedKey.TaskId}, storedKey.RequestId, nil
+	}
+
+	taskIds, createErr := jobService.createJobsAsyncTask(ctx, requestId, jobs, jobsBytes)
+	if createErr != nil {
+		if releaseErr := jobService.jobRepo.ReleaseIdempotencyKey(credentialId, idempotencyKey); releaseErr != nil {
+			logging.FromContext(ctx, jobService.logger).Error("failed to release idempotency key", "key", idempotencyKey, "error", releaseErr.Message)
+		}
+		return nil, "", createErr
+	}
+
+	if setErr := jobService.jobRepo.SetIdempotencyKeyTaskId(credentialId, idempotencyKey, taskIds[0]); setErr != nil {
+		logging.FromContext(ctx, jobService.logger).Error("failed to save async task id for idempotency key", "key", idempotencyKey, "error", setErr.Message)
+	}
+
+	return taskIds, requestId, nil
+}
+
+// validateNewJobs checks the spec, timezone, status, labels, external key and project of jobs to be created
+func (jobService *jobService) validateNewJobs(jobs []models.Job) *utils.GenericError {
+	externalKeys := map[string]bool{}
+
 	for _, job := range jobs {
 		if job.Spec != "" {
 			if _, err := cron.Parse(job.Spec); err != nil {
-				return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job spec is not valid %s", job.Spec))
+				return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job spec is not valid %s", job.Spec))
 			}
 		} else {
-			return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job spec is not valid %s", job.Spec))
+			return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job spec is not valid %s", job.Spec))
 		}
 
 		if job.Timezone == "" || job.Timezone == "Local" {
-			return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job timezone is not valid, provided timezone is %s", job.Timezone))
+			return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job timezone is not valid, provided timezone is %s", job.Timezone))
 		}
 
 		_, err := time.LoadLocation(job.Timezone)
 		if err != nil {
-			return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job timezone is not valid, provided timezone is %s", job.Timezone))
+			return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job timezone is not valid, provided timezone is %s", job.Timezone))
+		}
+
+		if validationErr := validateJobStatusAndLabels(job); validationErr != nil {
+			return validationErr
+		}
+
+		if job.ExternalKey != "" {
+			scopedKey := fmt.Sprintf("%d/%s", job.ProjectID, job.ExternalKey)
+			if externalKeys[scopedKey] {
+				return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("external key %s is used by more than one job in project %d", job.ExternalKey, job.ProjectID))
+			}
+			externalKeys[scopedKey] = true
 		}
 	}
 
@@ -128,7 +256,7 @@ func (jobService *jobService) BatchInsertJobs(requestId string, jobs []models.Jo
 
 	projects, err := jobService.projectRepo.GetBatchProjectsByIDs(projectIds)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	for _, job := range jobs {
@@ -140,44 +268,70 @@ func (jobService *jobService) BatchInsertJobs(requestId string, jobs []models.Jo
 			}
 		}
 		if !found {
-			return nil, utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("a project in the payload does not exitst. project id %v", job.ProjectID))
+			return utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("a project in the payload does not exitst. project id %v", job.ProjectID))
 		}
 	}
 
-	jobsBytes, marshalErr := json.Marshal(jobs)
-	if marshalErr != nil {
-		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to convert json to string"))
+	return nil
+}
+
+// jobsProjectIds returns the sorted unique project ids of the jobs
+func jobsProjectIds(jobs []models.Job) []uint64 {
+	projectIds := make([]uint64, 0, len(jobs))
+	seen := make(map[uint64]bool, len(jobs))
+	for _, job := range jobs {
+		if seen[job.ProjectID] {
+			continue
+		}
+		seen[job.ProjectID] = true
+		projectIds = append(projectIds, job.ProjectID)
 	}
+	sort.Slice(projectIds, func(i, j int) bool { return projectIds[i] < projectIds[j] })
+	return projectIds
+}
 
-	taskIds, addTaskErr := jobService.asyncTaskManager.AddTasks(string(jobsBytes), requestId, constants.CreateJobAsyncTaskService)
+// createJobsAsyncTask adds an async task for the jobs and inserts them in the background
+func (jobService *jobService) createJobsAsyncTask(ctx context.Context, requestId string, jobs []models.Job, jobsBytes []byte) ([]uint64, *utils.GenericError) {
+	taskIds, addTaskErr := jobService.asyncTaskManager.AddTasks(ctx, string(jobsBytes), requestId, constants.CreateJobAsyncTaskService, jobsProjectIds(jobs))
 	if addTaskErr != nil {
 		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to create async tasks %s", addTaskErr.Message))
 	}
 
+	// The background insert sets ids and dates on its own copy of the payload
+	jobs = append([]models.Job{}, jobs...)
+
 	jobService.dispatcher.NoBlockQueue(func(successChannel chan any, errorChannel chan any) {
+		// The request span has usually ended by now, the background work is still recorded as its child
+		ctx, span := tracing.StartSpan(ctx, "JobService.createJobs", attribute.Int64("scheduler0.async_task_id", int64(taskIds[0])))
 		defer func() {
+			span.End()
 			close(successChannel)
 			close(errorChannel)
 		}()
-		inProgressUpdateTaskErr := jobService.asyncTaskManager.UpdateTasksById(taskIds[0], models.AsyncTaskInProgress, "")
+		logger := logging.FromContext(ctx, jobService.logger)
+		inProgressUpdateTaskErr := jobService.asyncTaskManager.UpdateTasksById(ctx, taskIds[0], models.AsyncTaskInProgress, "")
 		if inProgressUpdateTaskErr != nil {
-			jobService.logger.Error("failed to update an async task", inProgressUpdateTaskErr, "; new state:", models.AsyncTaskInProgress)
+			logger.Error("failed to update an async task", inProgressUpdateTaskErr, "; new state:", models.AsyncTaskInProgress)
 			return
 		}
 
-		insertedIds, iErr := jobService.jobRepo.BatchInsertJobs(jobs)
+		insertedIds, newJobPositions, iErr := jobService.insertJobsWithNewExternalKeys(ctx, jobs)
+		if iErr != nil && strings.Contains(iErr.Message, "UNIQUE constraint failed") {
+			// A concurrent request created a job with one of the external keys, retry to pick up its id
+			insertedIds, newJobPositions, iErr = jobService.insertJobsWithNewExternalKeys(ctx, jobs)
+		}
 		if iErr != nil {
 			errJson, errJsonErr := json.Marshal(utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to batch insert job repository: %v", iErr.Message)))
 			if errJsonErr != nil {
-				jobService.logger.Error("failed to save error out for an async task", errJsonErr)
+				logger.Error("failed to save error out for an async task", errJsonErr)
 				return
 			}
-			updateTaskErr := jobService.asyncTaskManager.UpdateTasksById(taskIds[0], models.AsyncTaskFail, string(errJson))
+			updateTaskErr := jobService.asyncTaskManager.UpdateTasksById(ctx, taskIds[0], models.AsyncTaskFail, string(errJson))
 			if updateTaskErr != nil {
-				jobService.logger.Error("failed to update an async task", updateTaskErr, "; new state:", models.AsyncTaskFail)
+				logger.Error("failed to update an async task", updateTaskErr, "; new state:", models.AsyncTaskFail)
 				return
 			}
-			jobService.logger.Error("failed to batch insert jobs", iErr)
+			logger.Error("failed to batch insert jobs", iErr)
 			return
 		}
 
@@ -190,15 +344,22 @@ func (jobService *jobService) BatchInsertJobs(requestId string, jobs []models.Jo
 			jobs[i].LastExecutionDate = now
 		}
 
-		jobService.QueueJobs(jobs)
+		newJobs := make([]models.Job, 0, len(newJobPositions))
+		for _, position := range newJobPositions {
+			newJobs = append(newJobs, jobs[position])
+		}
+
+		if len(newJobs) > 0 {
+			jobService.QueueJobs(newJobs)
+		}
 		jobsJson, errJsonErr := json.Marshal(jobs)
 		if errJsonErr != nil {
-			jobService.logger.Error("failed to save error out for an async task", errJsonErr)
+			logger.Error("failed to save error out for an async task", errJsonErr)
 			return
 		}
-		updateTaskErr := jobService.asyncTaskManager.UpdateTasksById(taskIds[0], models.AsyncTaskSuccess, string(jobsJson))
+		updateTaskErr := jobService.asyncTaskManager.UpdateTasksById(ctx, taskIds[0], models.AsyncTaskSuccess, string(jobsJson))
 		if updateTaskErr != nil {
-			jobService.logger.Error("failed to update an async task", updateTaskErr, "; new state:", models.AsyncTaskSuccess)
+			logger.Error("failed to update an async task", updateTaskErr, "; new state:", models.AsyncTaskSuccess)
 			return
 		}
 	})
@@ -206,7 +367,52 @@ func (jobService *jobService) BatchInsertJobs(requestId string, jobs []models.Jo
 	return taskIds, nil
 }
 
+// insertJobsWithNewExternalKeys inserts jobs whose external key is not used in their project yet.
+// It returns the id of every job in the payload, existing ones included, and the positions of the inserted jobs.
+func (jobService *jobService) insertJobsWithNewExternalKeys(ctx context.Context, jobs []models.Job) ([]uint64, []int, *utils.GenericError) {
+	existingJobs, getErr := jobService.jobRepo.GetJobsByExternalKeys(jobs)
+	if getErr != nil {
+		return nil, nil, getErr
+	}
+
+	existingIds := map[string]uint64{}
+	for _, existingJob := range existingJobs {
+		existingIds[fmt.Sprintf("%d/%s", existingJob.ProjectID, existingJob.ExternalKey)] = existingJob.ID
+	}
+
+	ids := make([]uint64, len(jobs))
+	newJobs := []models.Job{}
+	newJobPositions := []int{}
+
+	for i, job := range jobs {
+		if job.ExternalKey != "" {
+			if existingId, ok := existingIds[fmt.Sprintf("%d/%s", job.ProjectID, job.ExternalKey)]; ok {
+				ids[i] = existingId
+				continue
+			}
+		}
+		newJobs = append(newJobs, job)
+		newJobPositions = append(newJobPositions, i)
+	}
+
+	if len(newJobs) < 1 {
+		return ids, newJobPositions, nil
+	}
+
+	insertedIds, insertErr := jobService.jobRepo.BatchInsertJobs(ctx, newJobs)
+	if insertErr != nil {
+		return nil, nil, insertErr
+	}
+
+	for i, insertedId := range insertedIds {
+		ids[newJobPositions[i]] = insertedId
+	}
+
+	return ids, newJobPositions, nil
+}
+
 // UpdateJob updates job with ID in transformer. Note that cron expression of job cannot be updated.
+// A job with a version is only updated if it is still at that version.
 func (jobService *jobService) UpdateJob(job models.Job) (*models.Job, *utils.GenericError) {
 	currentJobState := models.Job{
 		ID: job.ID,
@@ -216,7 +422,12 @@ func (jobService *jobService) UpdateJob(job models.Job) (*models.Job, *utils.Gen
 		return nil, getErr
 	}
 	if job.Data != "" {
-		currentJobState.Data = job.Data
+		data, dataKeyID, sealErr := jobService.keyring.Seal(job.Data)
+		if sealErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, sealErr.Error())
+		}
+		currentJobState.Data = data
+		currentJobState.DataKeyID = dataKeyID
 	}
 	if job.CallbackUrl != "" {
 		currentJobState.CallbackUrl = job.CallbackUrl
@@ -224,6 +435,20 @@ func (jobService *jobService) UpdateJob(job models.Job) (*models.Job, *utils.Gen
 	if job.ExecutionType != "" {
 		currentJobState.ExecutionType = job.ExecutionType
 	}
+	if job.Status != "" {
+		currentJobState.Status = job.Status
+	}
+	if job.Labels != nil {
+		currentJobState.Labels = job.Labels
+	}
+	if job.Metadata != nil {
+		currentJobState.Metadata = job.Metadata
+	}
+	currentJobState.Actor = job.Actor
+	currentJobState.Version = job.Version
+	if validationErr := validateJobStatusAndLabels(currentJobState); validationErr != nil {
+		return nil, validationErr
+	}
 	_, jobMangerUpdateOneError := jobService.jobRepo.UpdateOneByID(currentJobState)
 	if jobMangerUpdateOneError != nil {
 		return nil, jobMangerUpdateOneError
@@ -233,19 +458,103 @@ func (jobService *jobService) UpdateJob(job models.Job) (*models.Job, *utils.Gen
 	if getErr != nil {
 		return nil, getErr
 	}
+	redactJobData(&currentJobState)
 
 	return &currentJobState, nil
 }
 
-// DeleteJob deletes a job with ID in transformer
+// GetJobRevisions returns the revisions of a job, latest first
+func (jobService *jobService) GetJobRevisions(jobId uint64, offset uint64, limit uint64) (*models.PaginatedJobRevisions, *utils.GenericError) {
+	total, countErr := jobService.jobRepo.CountJobRevisions(jobId)
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	// Jobs created before revisions were recorded have none, which is only an error if the job does not exist
+	if total < 1 {
+		if getErr := jobService.jobRepo.GetOneByID(&models.Job{ID: jobId}); getErr != nil {
+			return nil, getErr
+		}
+	}
+
+	revisions, getErr := jobService.jobRepo.GetJobRevisions(jobId, offset, limit)
+	if getErr != nil {
+		return nil, getErr
+	}
+	for i := range revisions {
+		redactJobData(&revisions[i].Job)
+	}
+
+	return &models.PaginatedJobRevisions{
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+		Data:   revisions,
+	}, nil
+}
+
+// RollbackJob restores the callback url, data, execution type, status, labels and metadata of a job to one of its revisions
+func (jobService *jobService) RollbackJob(jobId uint64, revision uint64, actor models.Actor) (*models.Job, *utils.GenericError) {
+	jobRevision, getRevisionErr := jobService.jobRepo.GetJobRevision(jobId, revision)
+	if getRevisionErr != nil {
+		return nil, getRevisionErr
+	}
+
+	currentJobState := models.Job{
+		ID: jobId,
+	}
+	getErr := jobService.jobRepo.GetOneByID(&currentJobState)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	snapshot := jobRevision.Job
+	// The data of the revision is encrypted again with the active key, the key it was encrypted with may be retired later
+	data, openErr := jobService.keyring.Open(snapshot.Data, snapshot.DataKeyID)
+	if openErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusConflict, fmt.Sprintf("cannot roll back to revision %d of job %d: %s", revision, jobId, openErr.Error()))
+	}
+	data, dataKeyID, sealErr := jobService.keyring.Seal(data)
+	if sealErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, sealErr.Error())
+	}
+	currentJobState.CallbackUrl = snapshot.CallbackUrl
+	currentJobState.Data = data
+	currentJobState.DataKeyID = dataKeyID
+	currentJobState.ExecutionType = snapshot.ExecutionType
+	currentJobState.Status = snapshot.Status
+	currentJobState.Labels = snapshot.Labels
+	currentJobState.Metadata = snapshot.Metadata
+	currentJobState.Actor = actor
+
+	_, rollbackErr := jobService.jobRepo.RollbackOneByID(currentJobState)
+	if rollbackErr != nil {
+		return nil, rollbackErr
+	}
+
+	getErr = jobService.jobRepo.GetOneByID(&currentJobState)
+	if getErr != nil {
+		return nil, getErr
+	}
+	redactJobData(&currentJobState)
+
+	return &currentJobState, nil
+}
+
+// DeleteJob deletes a job with ID in transformer, a job with a version is only deleted if it is still at that version
 func (jobService *jobService) DeleteJob(job models.Job) *utils.GenericError {
+	version := job.Version
 	err := jobService.jobRepo.GetOneByID(&job)
 	if err != nil {
 		return err
 	}
+	job.Version = version
 
 	count, delError := jobService.jobRepo.DeleteOneByID(job)
 	if delError != nil {
+		if delError.Type == http.StatusPreconditionFailed {
+			return delError
+		}
 		return utils.HTTPGenericError(http.StatusInternalServerError, delError.Message)
 	}
 
@@ -256,6 +565,291 @@ func (jobService *jobService) DeleteJob(job models.Job) *utils.GenericError {
 	return nil
 }
 
+// BatchUpdateJobs updates the jobs in the background and returns the ids of the async task tracking the update.
+// Like UpdateJob only the fields set on a job are changed and the cron spec of a job cannot be updated.
+func (jobService *jobService) BatchUpdateJobs(ctx context.Context, requestId string, jobs []models.Job) ([]uint64, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "JobService.BatchUpdateJobs", attribute.Int("scheduler0.jobs", len(jobs)))
+	defer span.End()
+
+	if len(jobs) < 1 {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "at least one job is required")
+	}
+
+	jobIds := make([]uint64, 0, len(jobs))
+	seenJobIds := map[uint64]bool{}
+	for _, job := range jobs {
+		if job.ID < 1 {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, "job id is required")
+		}
+		if seenJobIds[job.ID] {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job %d is updated more than once", job.ID))
+		}
+		seenJobIds[job.ID] = true
+		jobIds = append(jobIds, job.ID)
+	}
+
+	jobs, sealErr := jobService.sealJobsData(jobs)
+	if sealErr != nil {
+		return nil, sealErr
+	}
+
+	currentJobs, getErr := jobService.jobRepo.BatchGetJobsByID(jobIds)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	currentJobStates := make(map[uint64]models.Job, len(currentJobs))
+	for _, currentJob := range currentJobs {
+		currentJobStates[currentJob.ID] = currentJob
+	}
+
+	updatedJobs := make([]models.Job, 0, len(jobs))
+	for _, job := range jobs {
+		currentJobState, ok := currentJobStates[job.ID]
+		if !ok {
+			return nil, utils.HTTPGenericError(http.StatusNotFound, fmt.Sprintf("cannot find job with id %d", job.ID))
+		}
+		if job.Spec != "" && job.Spec != currentJobState.Spec {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("cannot update cron spec of job %d", job.ID))
+		}
+		if job.Data != "" {
+			currentJobState.Data = job.Data
+			currentJobState.DataKeyID = job.DataKeyID
+		}
+		if job.CallbackUrl != "" {
+			currentJobState.CallbackUrl = job.CallbackUrl
+		}
+		if job.ExecutionType != "" {
+			currentJobState.ExecutionType = job.ExecutionType
+		}
+		if job.Status != "" {
+			currentJobState.Status = job.Status
+		}
+		currentJobState.Labels = job.Labels
+		currentJobState.Metadata = job.Metadata
+		currentJobState.Actor = job.Actor
+		if validationErr := validateJobStatusAndLabels(currentJobState); validationErr != nil {
+			return nil, validationErr
+		}
+		updatedJobs = append(updatedJobs, currentJobState)
+	}
+
+	jobsBytes, marshalErr := json.Marshal(jobs)
+	if marshalErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to convert json to string"))
+	}
+
+	return jobService.runAsyncTask(ctx, requestId, string(jobsBytes), constants.UpdateJobAsyncTaskService, jobsProjectIds(updatedJobs), func() (interface{}, *utils.GenericError) {
+		if updateErr := jobService.jobRepo.BatchUpdateJobs(updatedJobs); updateErr != nil {
+			return nil, updateErr
+		}
+		return updatedJobs, nil
+	})
+}
+
+// BatchDeleteJobs deletes the jobs in the background and returns the ids of the async task tracking the delete
+func (jobService *jobService) BatchDeleteJobs(ctx context.Context, requestId string, jobIds []uint64, actor models.Actor) ([]uint64, *utils.GenericError) {
+	ctx, span := tracing.StartSpan(ctx, "JobService.BatchDeleteJobs", attribute.Int("scheduler0.jobs", len(jobIds)))
+	defer span.End()
+
+	if len(jobIds) < 1 {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "at least one job id is required")
+	}
+
+	for _, jobId := range jobIds {
+		if jobId < 1 {
+			return nil, utils.HTTPGenericError(http.StatusBadRequest, "job id is required")
+		}
+	}
+
+	jobIdsBytes, marshalErr := json.Marshal(jobIds)
+	if marshalErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to convert json to string"))
+	}
+
+	jobIds = append([]uint64{}, jobIds...)
+
+	currentJobs, getErr := jobService.jobRepo.BatchGetJobsByID(jobIds)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	return jobService.runAsyncTask(ctx, requestId, string(jobIdsBytes), constants.DeleteJobAsyncTaskService, jobsProjectIds(currentJobs), func() (interface{}, *utils.GenericError) {
+		affected, deleteErr := jobService.jobRepo.BatchDeleteJobs(jobIds, actor)
+		if deleteErr != nil {
+			return nil, deleteErr
+		}
+		return models.BulkJobsResult{Affected: affected}, nil
+	})
+}
+
+// runAsyncTask adds an async task for the input and runs the task in the background,
+// saving the output of the task or its error as the result of the async task
+func (jobService *jobService) runAsyncTask(ctx context.Context, requestId string, input string, service string, projectIds []uint64, task func() (interface{}, *utils.GenericError)) ([]uint64, *utils.GenericError) {
+	taskIds, addTaskErr := jobService.asyncTaskManager.AddTasks(ctx, input, requestId, service, projectIds)
+	if addTaskErr != nil {
+		return nil, utils.HTTPGenericError(http.StatusInternalServerError, fmt.Sprintf("failed to create async tasks %s", addTaskErr.Message))
+	}
+
+	jobService.dispatcher.NoBlockQueue(func(successChannel chan any, errorChannel chan any) {
+		ctx, span := tracing.StartSpan(ctx, "JobService.runAsyncTask", attribute.String("scheduler0.async_task_service", service), attribute.Int64("scheduler0.async_task_id", int64(taskIds[0])))
+		defer func() {
+			span.End()
+			close(successChannel)
+			close(errorChannel)
+		}()
+		logger := logging.FromContext(ctx, jobService.logger)
+		inProgressUpdateTaskErr := jobService.asyncTaskManager.UpdateTasksById(ctx, taskIds[0], models.AsyncTaskInProgress, "")
+		if inProgressUpdateTaskErr != nil {
+			logger.Error("failed to update an async task", inProgressUpdateTaskErr, "; new state:", models.AsyncTaskInProgress)
+			return
+		}
+
+		output, taskErr := task()
+		if taskErr != nil {
+			errJson, errJsonErr := json.Marshal(taskErr)
+			if errJsonErr != nil {
+				logger.Error("failed to save error out for an async task", errJsonErr)
+				return
+			}
+			updateTaskErr := jobService.asyncTaskManager.UpdateTasksById(ctx, taskIds[0], models.AsyncTaskFail, string(errJson))
+			if updateTaskErr != nil {
+				logger.Error("failed to update an async task", updateTaskErr, "; new state:", models.AsyncTaskFail)
+				return
+			}
+			logger.Error("failed to run async task", "service", service, "error", taskErr.Message)
+			return
+		}
+
+		outputJson, outputJsonErr := json.Marshal(output)
+		if outputJsonErr != nil {
+			logger.Error("failed to save output for an async task", outputJsonErr)
+			return
+		}
+		updateTaskErr := jobService.asyncTaskManager.UpdateTasksById(ctx, taskIds[0], models.AsyncTaskSuccess, string(outputJson))
+		if updateTaskErr != nil {
+			logger.Error("failed to update an async task", updateTaskErr, "; new state:", models.AsyncTaskSuccess)
+			return
+		}
+	})
+
+	return taskIds, nil
+}
+
 func (jobService *jobService) QueueJobs(jobs []models.Job) {
 	jobService.Queue.Queue(jobs)
 }
+
+// ListJobs returns a paginated set of jobs in a project that match the filter.
+// Pages read with a cursor skip counting the jobs.
+func (jobService *jobService) ListJobs(filter models.JobFilter) (*models.PaginatedJob, *utils.GenericError) {
+	paginatedJobs := models.PaginatedJob{}
+
+	if filter.Cursor == "" {
+		count, countErr := jobService.jobRepo.CountJobs(filter)
+		if countErr != nil {
+			return nil, countErr
+		}
+
+		if count < filter.Offset {
+			filter.Offset = count
+		}
+
+		paginatedJobs.Total = count
+		paginatedJobs.Offset = filter.Offset
+	}
+
+	jobs, cursors, err := jobService.jobRepo.ListJobs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	redactJobsData(jobs)
+
+	paginatedJobs.Data = jobs
+	paginatedJobs.Limit = filter.Limit
+	paginatedJobs.Next = cursors.Next
+	paginatedJobs.Prev = cursors.Prev
+
+	return &paginatedJobs, nil
+}
+
+// UpdateJobsStatus pauses or resumes every job in a project that matches the filter.
+// Executors skip paused jobs when their schedule fires, so no node needs to be notified.
+func (jobService *jobService) UpdateJobsStatus(filter models.JobFilter, status models.JobStatus) (uint64, *utils.GenericError) {
+	if status != models.JobStatusActive && status != models.JobStatusPaused {
+		return 0, utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job status is not valid %s", status))
+	}
+
+	if err := validateBulkJobFilter(filter); err != nil {
+		return 0, err
+	}
+
+	return jobService.jobRepo.UpdateStatusByFilter(filter, status)
+}
+
+// DeleteJobs deletes every job in a project that matches the filter.
+// Executors drop deleted jobs the next time their schedule fires.
+func (jobService *jobService) DeleteJobs(filter models.JobFilter) (uint64, *utils.GenericError) {
+	if err := validateBulkJobFilter(filter); err != nil {
+		return 0, err
+	}
+
+	return jobService.jobRepo.DeleteByFilter(filter)
+}
+
+// sealJobsData returns a copy of jobs with their data encrypted by the active data encryption key.
+// The key ids sent with jobs are replaced by the key actually encrypting their data.
+func (jobService *jobService) sealJobsData(jobs []models.Job) ([]models.Job, *utils.GenericError) {
+	sealedJobs := make([]models.Job, len(jobs))
+	for i, job := range jobs {
+		data, dataKeyID, sealErr := jobService.keyring.Seal(job.Data)
+		if sealErr != nil {
+			return nil, utils.HTTPGenericError(http.StatusInternalServerError, sealErr.Error())
+		}
+		job.Data = data
+		job.DataKeyID = dataKeyID
+		sealedJobs[i] = job
+	}
+	return sealedJobs, nil
+}
+
+// redactJobData blanks the data of an encrypted job, which is only decrypted by the node executing it
+func redactJobData(job *models.Job) {
+	if job.DataKeyID != "" {
+		job.Data = ""
+	}
+}
+
+func redactJobsData(jobs []models.Job) {
+	for i := range jobs {
+		redactJobData(&jobs[i])
+	}
+}
+
+// validateBulkJobFilter ensures a bulk operation is scoped to a project and narrowed by at least one criteria
+func validateBulkJobFilter(filter models.JobFilter) *utils.GenericError {
+	if filter.ProjectID < 1 {
+		return utils.HTTPGenericError(http.StatusBadRequest, "project id is required")
+	}
+
+	if len(filter.LabelSelector) < 1 && filter.CallbackUrlPrefix == "" && filter.ExecutionType == "" && filter.Status == "" {
+		return utils.HTTPGenericError(http.StatusBadRequest, "a label selector, callback url prefix, execution type or status is required")
+	}
+
+	return nil
+}
+
+func validateJobStatusAndLabels(job models.Job) *utils.GenericError {
+	if job.Status != "" && job.Status != models.JobStatusActive && job.Status != models.JobStatusPaused {
+		return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("job status is not valid %s", job.Status))
+	}
+
+	for key := range job.Labels {
+		if err := utils.ValidateLabelKey(key); err != nil {
+			return utils.HTTPGenericError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	return nil
+}
@@ -7,9 +7,11 @@ import (
 	"github.com/hashicorp/raft"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/db"
+	"scheduler0/pkg/encryption"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
 	async_task_repo "scheduler0/pkg/repository/async_task"
@@ -82,7 +84,7 @@ func Test_JobService_BatchInsertJobs(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	service := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	service := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	asyncTaskManager.SetSingleNodeMode(true)
 	asyncTaskManager.ListenForNotifications()
@@ -117,7 +119,7 @@ func Test_JobService_BatchInsertJobs(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	taskIds, batchErr := service.BatchInsertJobs("request123", jobs)
+	taskIds, batchErr := service.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -201,7 +203,7 @@ func Test_JobService_UpdateJob(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	// Create a test job
 	job := models.Job{
@@ -225,7 +227,7 @@ func Test_JobService_UpdateJob(t *testing.T) {
 	}
 
 	// Insert the job into the job repo
-	_, insertErr := jobRepo.BatchInsertJobs([]models.Job{job})
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{job})
 	if insertErr != nil {
 		t.Fatalf("Failed to insert job: %v", insertErr)
 	}
@@ -248,6 +250,272 @@ func Test_JobService_UpdateJob(t *testing.T) {
 	}
 }
 
+func Test_JobService_UpdateJob_EncryptsData(t *testing.T) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-service-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+
+	// Create a temporary SQLite database file
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	// Create a new SQLite database connection
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+
+	scheduler0config := config.NewScheduler0Config()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+
+	// Create a new FSM store
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	ctx := context.Background()
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	asyncTaskManagerRepo := async_task_repo.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
+	asyncTaskManager := async_task.NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
+	jobQueueRepo := job_queue_repo.NewJobQueuesRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	dispatcher := utils.NewDispatcher(
+		ctx,
+		int64(1),
+		int64(1),
+	)
+
+	dispatcher.Run()
+
+	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
+	// Create a new JobService instance
+	keyring, keyringErr := encryption.NewKeyring("2026-10", map[string]string{
+		"2026-10": "6368616e676520746869732070617373776f726420746f206120736563726574",
+	})
+	if keyringErr != nil {
+		t.Fatalf("Failed to create keyring: %v", keyringErr)
+	}
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, keyring)
+
+	// Create a test job
+	job := models.Job{
+		ID:          1,
+		Spec:        "* * * * *",
+		Timezone:    "UTC",
+		ProjectID:   1,
+		Data:        "Test data",
+		CallbackUrl: "https://example.com/callback",
+	}
+
+	// Create the project using the project repo
+	project := models.Project{
+		ID:          job.ProjectID,
+		Name:        fmt.Sprintf("Project %d", job.ProjectID),
+		Description: fmt.Sprintf("Project %d description", job.ProjectID),
+	}
+	_, createErr := projectRepo.CreateOne(&project)
+	if createErr != nil {
+		t.Fatalf("Failed to create project: %v", createErr)
+	}
+
+	// Insert the job into the job repo
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{job})
+	if insertErr != nil {
+		t.Fatalf("Failed to insert job: %v", insertErr)
+	}
+
+	// Update the job
+	updatedJob := models.Job{
+		ID:          job.ID,
+		Data:        "Updated test data",
+		CallbackUrl: "https://example.com/updated-callback",
+	}
+	updateResult, updateErr := jobService.UpdateJob(updatedJob)
+	if updateErr != nil {
+		t.Fatalf("Failed to update job: %v", updateErr)
+	}
+	assert.Equal(t, "2026-10", updateResult.DataKeyID)
+	assert.Equal(t, "", updateResult.Data)
+	assert.Equal(t, "https://example.com/updated-callback", updateResult.CallbackUrl)
+
+	// The stored data is encrypted and only opened by the keyring
+	storedJob := models.Job{ID: job.ID}
+	getErr := jobRepo.GetOneByID(&storedJob)
+	if getErr != nil {
+		t.Fatalf("Failed to get job: %v", getErr)
+	}
+	assert.Equal(t, "2026-10", storedJob.DataKeyID)
+	assert.NotContains(t, storedJob.Data, "Updated test data")
+	data, openErr := keyring.Open(storedJob.Data, storedJob.DataKeyID)
+	if openErr != nil {
+		t.Fatalf("Failed to decrypt job data: %v", openErr)
+	}
+	assert.Equal(t, "Updated test data", data)
+
+	fetchedJob, fetchErr := jobService.GetJob(models.Job{ID: job.ID})
+	if fetchErr != nil {
+		t.Fatalf("Failed to get job: %v", fetchErr)
+	}
+	assert.Equal(t, "", fetchedJob.Data)
+}
+
+func Test_JobService_RollbackJob_ReencryptsData(t *testing.T) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-service-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+
+	// Create a temporary SQLite database file
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	// Create a new SQLite database connection
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+
+	scheduler0config := config.NewScheduler0Config()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+
+	// Create a new FSM store
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	ctx := context.Background()
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	asyncTaskManagerRepo := async_task_repo.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
+	asyncTaskManager := async_task.NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
+	jobQueueRepo := job_queue_repo.NewJobQueuesRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	dispatcher := utils.NewDispatcher(
+		ctx,
+		int64(1),
+		int64(1),
+	)
+
+	dispatcher.Run()
+
+	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
+	oldKeyring, keyringErr := encryption.NewKeyring("2026-09", map[string]string{
+		"2026-09": "6368616e676520746869732070617373776f726420746f206120736563726574",
+	})
+	if keyringErr != nil {
+		t.Fatalf("Failed to create keyring: %v", keyringErr)
+	}
+	keyring, keyringErr := encryption.NewKeyring("2026-10", map[string]string{
+		"2026-09": "6368616e676520746869732070617373776f726420746f206120736563726574",
+		"2026-10": "a3c1e5f7092b4d6f8193a5c7e9f10b2d4f6183a5c7e9021b3d5f7a9c1e3f5b7d",
+	})
+	if keyringErr != nil {
+		t.Fatalf("Failed to create keyring: %v", keyringErr)
+	}
+	retiredKeyring, keyringErr := encryption.NewKeyring("2026-10", map[string]string{
+		"2026-10": "a3c1e5f7092b4d6f8193a5c7e9f10b2d4f6183a5c7e9021b3d5f7a9c1e3f5b7d",
+	})
+	if keyringErr != nil {
+		t.Fatalf("Failed to create keyring: %v", keyringErr)
+	}
+
+	// Create the project using the project repo
+	project := models.Project{
+		ID:          1,
+		Name:        "Project 1",
+		Description: "Project 1 description",
+	}
+	_, createErr := projectRepo.CreateOne(&project)
+	if createErr != nil {
+		t.Fatalf("Failed to create project: %v", createErr)
+	}
+
+	// Insert the job into the job repo
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{
+		{
+			ID:          1,
+			Spec:        "* * * * *",
+			Timezone:    "UTC",
+			ProjectID:   project.ID,
+			CallbackUrl: "https://example.com/callback",
+		},
+	})
+	if insertErr != nil {
+		t.Fatalf("Failed to insert job: %v", insertErr)
+	}
+
+	// The second revision has its data encrypted by the old key
+	oldJobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, oldKeyring)
+	if _, updateErr := oldJobService.UpdateJob(models.Job{ID: 1, Data: "Old test data"}); updateErr != nil {
+		t.Fatalf("Failed to update job: %v", updateErr)
+	}
+
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, keyring)
+	if _, updateErr := jobService.UpdateJob(models.Job{ID: 1, Data: "New test data"}); updateErr != nil {
+		t.Fatalf("Failed to update job: %v", updateErr)
+	}
+
+	// Rolling back encrypts the data of the revision with the active key
+	rolledBackJob, rollbackErr := jobService.RollbackJob(1, 2, models.Actor{})
+	if rollbackErr != nil {
+		t.Fatalf("Failed to roll back job: %v", rollbackErr)
+	}
+	assert.Equal(t, "2026-10", rolledBackJob.DataKeyID)
+	assert.Equal(t, "", rolledBackJob.Data)
+
+	storedJob := models.Job{ID: 1}
+	if getErr := jobRepo.GetOneByID(&storedJob); getErr != nil {
+		t.Fatalf("Failed to get job: %v", getErr)
+	}
+	assert.Equal(t, "2026-10", storedJob.DataKeyID)
+	data, openErr := retiredKeyring.Open(storedJob.Data, storedJob.DataKeyID)
+	if openErr != nil {
+		t.Fatalf("Failed to decrypt job data: %v", openErr)
+	}
+	assert.Equal(t, "Old test data", data)
+
+	// The revision cannot be restored once the key encrypting its data is retired
+	retiredJobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, retiredKeyring)
+	_, rollbackErr = retiredJobService.RollbackJob(1, 2, models.Actor{})
+	if assert.NotNil(t, rollbackErr) {
+		assert.Equal(t, http.StatusConflict, rollbackErr.Type)
+	}
+}
+
 func Test_JobService_DeleteJob(t *testing.T) {
 	logger := hclog.New(&hclog.LoggerOptions{
 		Name:  "job-service-test",
@@ -305,7 +573,7 @@ func Test_JobService_DeleteJob(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	// Create a test job
 	job := models.Job{
@@ -329,7 +597,7 @@ func Test_JobService_DeleteJob(t *testing.T) {
 	}
 
 	// Insert the job into the job repo
-	_, insertErr := jobRepo.BatchInsertJobs([]models.Job{job})
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{job})
 	if insertErr != nil {
 		t.Fatalf("Failed to insert job: %v", insertErr)
 	}
@@ -404,7 +672,7 @@ func Test_JobService_GetJobsByProjectID(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	// Create a test project
 	projectID := uint64(1)
@@ -449,7 +717,7 @@ func Test_JobService_GetJobsByProjectID(t *testing.T) {
 	}
 
 	// Insert the jobs into the job repo
-	_, insertErr := jobRepo.BatchInsertJobs(jobs)
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), jobs)
 	if insertErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", insertErr)
 	}
@@ -537,7 +805,7 @@ func Test_JobService_GetJob(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	// Create a test job
 	job := models.Job{
@@ -561,7 +829,7 @@ func Test_JobService_GetJob(t *testing.T) {
 	}
 
 	// Insert the job into the job repo
-	_, insertErr := jobRepo.BatchInsertJobs([]models.Job{job})
+	_, insertErr := jobRepo.BatchInsertJobs(context.Background(), []models.Job{job})
 	if insertErr != nil {
 		t.Fatalf("Failed to insert job: %v", insertErr)
 	}
@@ -638,7 +906,7 @@ func Test_JobService_QueueJobs(t *testing.T) {
 
 	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	// Create a new JobService instance
-	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager)
+	jobService := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
 
 	queueRepo.AddServers([]uint64{1})
 
@@ -682,3 +950,143 @@ func Test_JobService_QueueJobs(t *testing.T) {
 
 	assert.Equal(t, allocations[1], uint64(1))
 }
+
+func Test_JobService_BatchInsertJobsWithIdempotencyKey(t *testing.T) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "job-service-test",
+		Level: hclog.LevelFromString("ERROR"),
+	})
+
+	// Create a temporary SQLite database file
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	// Create a new SQLite database connection
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+
+	scheduler0config := config.NewScheduler0Config()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+
+	// Create a new FSM store
+	scheduler0Store := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, nil)
+
+	// Create a mock raft cluster
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          1,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return scheduler0Store.GetFSM()
+		},
+	})
+	defer cluster.Close()
+	cluster.FullyConnect()
+	scheduler0Store.UpdateRaft(cluster.Leader())
+
+	ctx, canceler := context.WithCancel(context.Background())
+	defer canceler()
+
+	jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+	projectRepo := project_repo.NewProjectRepo(logger, scheduler0RaftActions, scheduler0Store, jobRepo)
+	asyncTaskManagerRepo := async_task_repo.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
+	asyncTaskManager := async_task.NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
+	jobQueueRepo := job_queue_repo.NewJobQueuesRepo(logger, scheduler0RaftActions, scheduler0Store)
+
+	dispatcher := utils.NewDispatcher(
+		ctx,
+		int64(1),
+		int64(1),
+	)
+
+	dispatcher.Run()
+
+	queueRepo := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
+	// Create a new JobService instance
+	service := NewJobService(ctx, logger, jobRepo, queueRepo, projectRepo, dispatcher, asyncTaskManager, nil)
+
+	asyncTaskManager.SetSingleNodeMode(true)
+	asyncTaskManager.ListenForNotifications()
+
+	project := models.Project{
+		ID:          1,
+		Name:        "Project 1",
+		Description: "Project 1 description",
+	}
+	_, createErr := projectRepo.CreateOne(&project)
+	if createErr != nil {
+		t.Fatalf("Failed to create project: %v", createErr)
+	}
+
+	jobs := []models.Job{
+		{
+			Spec:        "* * * * *",
+			Timezone:    "UTC",
+			ProjectID:   1,
+			ExternalKey: "invoice-1",
+		},
+	}
+
+	taskIds, requestId, batchErr := service.BatchInsertJobsWithIdempotencyKey(context.Background(), "request123", "key-1", jobs)
+	if batchErr != nil {
+		t.Fatalf("Failed to insert jobs: %v", batchErr)
+	}
+	assert.Equal(t, "request123", requestId)
+
+	time.Sleep(time.Second * time.Duration(2))
+
+	// Replaying the key returns the original task without inserting the job again
+	replayedTaskIds, replayedRequestId, replayErr := service.BatchInsertJobsWithIdempotencyKey(context.Background(), "request456", "key-1", jobs)
+	if replayErr != nil {
+		t.Fatalf("Failed to replay request: %v", replayErr)
+	}
+	assert.Equal(t, taskIds, replayedTaskIds)
+	assert.Equal(t, "request123", replayedRequestId)
+
+	// Reusing the key with a different payload is rejected
+	_, _, mismatchErr := service.BatchInsertJobsWithIdempotencyKey(context.Background(), "request789", "key-1", []models.Job{
+		{
+			Spec:      "0 0 * * *",
+			Timezone:  "UTC",
+			ProjectID: 1,
+		},
+	})
+	assert.NotNil(t, mismatchErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatchErr.Type)
+
+	// Keys are scoped to the credential, so another credential can use the same key
+	otherTaskIds, otherRequestId, otherErr := service.BatchInsertJobsWithIdempotencyKey(context.Background(), "request789", "key-1", []models.Job{
+		{
+			Spec:        "0 0 * * *",
+			Timezone:    "UTC",
+			ProjectID:   1,
+			ExternalKey: "invoice-2",
+			Actor:       models.Actor{CredentialID: 2},
+		},
+	})
+	if otherErr != nil {
+		t.Fatalf("Failed to insert jobs for another credential: %v", otherErr)
+	}
+	assert.Equal(t, "request789", otherRequestId)
+	assert.NotEqual(t, taskIds, otherTaskIds)
+
+	// A new request with an external key that already exists returns the existing job
+	_, batchErr = service.BatchInsertJobs(context.Background(), "request999", jobs)
+	if batchErr != nil {
+		t.Fatalf("Failed to insert jobs: %v", batchErr)
+	}
+
+	time.Sleep(time.Second * time.Duration(2))
+
+	count, countErr := jobRepo.GetJobsTotalCountByProjectID(1)
+	if countErr != nil {
+		t.Fatalf("Failed to count jobs: %v", countErr)
+	}
+	assert.Equal(t, uint64(2), count)
+}
@@ -0,0 +1,100 @@
+package job_execution
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"scheduler0/pkg/models"
+	job_repo "scheduler0/pkg/repository/job"
+	job_execution_repo "scheduler0/pkg/repository/job_execution"
+	project_repo "scheduler0/pkg/repository/project"
+	"scheduler0/pkg/utils"
+	"time"
+)
+
+// jobExecutionService service layer for reading job execution logs and stats
+type jobExecutionService struct {
+	jobRepo           job_repo.JobRepo
+	projectRepo       project_repo.ProjectRepo
+	jobExecutionsRepo job_execution_repo.JobExecutionsRepo
+	logger            hclog.Logger
+}
+
+//go:generate mockery --name JobExecutionService --output ../mocks
+type JobExecutionService interface {
+	ListExecutions(filter models.JobExecutionLogFilter) (*models.PaginatedJobExecutionLogs, *utils.GenericError)
+	GetJobStats(jobId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError)
+	GetProjectStats(projectId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError)
+}
+
+func NewJobExecutionService(logger hclog.Logger, jobRepo job_repo.JobRepo, projectRepo project_repo.ProjectRepo, jobExecutionsRepo job_execution_repo.JobExecutionsRepo) JobExecutionService {
+	return &jobExecutionService{
+		jobRepo:           jobRepo,
+		projectRepo:       projectRepo,
+		jobExecutionsRepo: jobExecutionsRepo,
+		logger:            logger.Named("job-execution-service"),
+	}
+}
+
+// ListExecutions returns a page of the execution logs matching the filter, latest first
+func (service *jobExecutionService) ListExecutions(filter models.JobExecutionLogFilter) (*models.PaginatedJobExecutionLogs, *utils.GenericError) {
+	if filter.Limit < 1 {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "limit should be greater than zero")
+	}
+
+	if filter.State != nil && *filter.State > models.ExecutionLogFailedState {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "execution state is not valid")
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "from should be before to")
+	}
+
+	if filter.JobId > 0 {
+		if getErr := service.jobRepo.GetOneByID(&models.Job{ID: filter.JobId}); getErr != nil {
+			return nil, getErr
+		}
+	}
+
+	total, countErr := service.jobExecutionsRepo.CountExecutionLogsByFilter(filter)
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	executionLogs, listErr := service.jobExecutionsRepo.ListExecutionLogs(filter)
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	return &models.PaginatedJobExecutionLogs{
+		Total:  total,
+		Offset: filter.Offset,
+		Limit:  filter.Limit,
+		Data:   executionLogs,
+	}, nil
+}
+
+// GetJobStats returns the execution stats of a job between from and to
+func (service *jobExecutionService) GetJobStats(jobId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError) {
+	if to.Before(from) {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "from should be before to")
+	}
+
+	if getErr := service.jobRepo.GetOneByID(&models.Job{ID: jobId}); getErr != nil {
+		return nil, getErr
+	}
+
+	return service.jobExecutionsRepo.GetJobExecutionStats(jobId, from, to)
+}
+
+// GetProjectStats returns the execution stats of the jobs of a project between from and to
+func (service *jobExecutionService) GetProjectStats(projectId uint64, from time.Time, to time.Time) (*models.JobExecutionStats, *utils.GenericError) {
+	if to.Before(from) {
+		return nil, utils.HTTPGenericError(http.StatusBadRequest, "from should be before to")
+	}
+
+	if getErr := service.projectRepo.GetOneByID(&models.Project{ID: projectId}); getErr != nil {
+		return nil, getErr
+	}
+
+	return service.jobExecutionsRepo.GetProjectExecutionStats(projectId, from, to)
+}
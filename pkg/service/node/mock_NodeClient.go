@@ -52,6 +52,44 @@ func (_m *MockNodeClient) FetchUncommittedLogsFromPeersPhase2(ctx context.Contex
 	_m.Called(ctx, node, peerFanIns)
 }
 
+// GetReadIndex provides a mock function with given fields: ctx, node, leader
+func (_m *MockNodeClient) GetReadIndex(ctx context.Context, node *nodeService, leader config.RaftNode) (uint64, error) {
+	ret := _m.Called(ctx, node, leader)
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *nodeService, config.RaftNode) (uint64, error)); ok {
+		return rf(ctx, node, leader)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *nodeService, config.RaftNode) uint64); ok {
+		r0 = rf(ctx, node, leader)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *nodeService, config.RaftNode) error); ok {
+		r1 = rf(ctx, node, leader)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendCredentialUsage provides a mock function with given fields: ctx, node, leader, usages
+func (_m *MockNodeClient) SendCredentialUsage(ctx context.Context, node *nodeService, leader config.RaftNode, usages []models.CredentialUsage) error {
+	ret := _m.Called(ctx, node, leader, usages)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *nodeService, config.RaftNode, []models.CredentialUsage) error); ok {
+		r0 = rf(ctx, node, leader, usages)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // StartJobs provides a mock function with given fields: ctx, node, peer
 func (_m *MockNodeClient) StartJobs(ctx context.Context, node *nodeService, peer config.RaftNode) error {
 	ret := _m.Called(ctx, node, peer)
@@ -9,21 +9,26 @@ import (
 	"github.com/hashicorp/raft"
 	"log"
 	"math/rand"
+	"net/http"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/repository/async_task"
 	"scheduler0/pkg/repository/job"
 	"scheduler0/pkg/repository/job_execution"
 	"scheduler0/pkg/repository/job_queue"
 	"scheduler0/pkg/repository/project"
+	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/secrets"
 	async_task_service "scheduler0/pkg/service/async_task"
+	"scheduler0/pkg/service/event"
 	"scheduler0/pkg/service/executor"
 	"scheduler0/pkg/service/processor"
 	"scheduler0/pkg/service/queue"
 	"scheduler0/pkg/shared_repo"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"strconv"
 	"sync"
@@ -37,9 +42,7 @@ type Status struct {
 	LastConnectionTime time.Duration
 }
 
-type Res struct {
-	IsLeader bool
-}
+type Res = models.PeerHandshake
 
 type Response struct {
 	Data    Res  `json:"data"`
@@ -81,6 +84,7 @@ type nodeService struct {
 	scheduler0Secrets     secrets.Scheduler0Secrets
 	scheduler0RaftActions fsm.Scheduler0RaftActions
 	nodeHTTPClient        NodeClient
+	eventService          event.EventService
 	postProcessingChannel chan models.PostProcess
 }
 
@@ -93,6 +97,9 @@ type NodeService interface {
 	GetRaftLeaderWithId() (raft.ServerAddress, raft.ServerID)
 	GetRaftStats() map[string]string
 	CanAcceptRequest() bool
+	ReadIndex(ctx context.Context) (uint64, *utils.GenericError)
+	EnsureReadConsistency(ctx context.Context, consistency string, maxStaleness time.Duration) *utils.GenericError
+	ForwardCredentialUsage(ctx context.Context, usages []models.CredentialUsage) *utils.GenericError
 }
 
 func NewNode(
@@ -111,6 +118,7 @@ func NewNode(
 	asyncTaskManager async_task_service.AsyncTaskService,
 	dispatcher *utils.Dispatcher,
 	nodeHTTPClient NodeClient,
+	eventService event.EventService,
 	postProcessingChannel chan models.PostProcess,
 	isExistingNode bool,
 ) NodeService {
@@ -141,6 +149,7 @@ func NewNode(
 		scheduler0RaftStore:   fsmStore,
 		sharedRepo:            sharedRepo,
 		nodeHTTPClient:        nodeHTTPClient,
+		eventService:          eventService,
 		postProcessingChannel: postProcessingChannel,
 	}
 }
@@ -162,17 +171,24 @@ func (node *nodeService) Start() {
 	myObserver := raft.NewObserver(node.peerObserverChannels, true, func(o *raft.Observation) bool {
 		_, peerObservation := o.Data.(raft.PeerObservation)
 		_, resumedHeartbeatObservation := o.Data.(raft.ResumedHeartbeatObservation)
-		return peerObservation || resumedHeartbeatObservation
+		_, leaderObservation := o.Data.(raft.LeaderObservation)
+		return peerObservation || resumedHeartbeatObservation || leaderObservation
 	})
 
 	node.scheduler0RaftStore.RegisterObserver(myObserver)
 	node.beginAcceptingClientRequest()
 
 	go node.listenOnInputQueues()
+
+	if configs.ExecutionLogRetentionIntervalSeconds > 0 {
+		node.compactExecutionLogsPeriodically()
+	}
+	node.pruneIdempotencyKeysPeriodically()
+	node.resealJobsDataPeriodically()
 }
 
 func (node *nodeService) GetUncommittedLogs(requestId string) {
-	taskId, addErr := node.asyncTaskManager.AddTasks("", requestId, constants.JobExecutorAsyncTaskService)
+	taskId, addErr := node.asyncTaskManager.AddTasks(node.ctx, "", requestId, constants.JobExecutorAsyncTaskService, nil)
 	if addErr != nil {
 		node.logger.Error("failed to add new async task for job_executor", "error", addErr)
 	}
@@ -184,7 +200,7 @@ func (node *nodeService) GetUncommittedLogs(requestId string) {
 			close(errorChannel)
 		}()
 
-		err := node.asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskInProgress, "")
+		err := node.asyncTaskManager.UpdateTasksByRequestId(node.ctx, requestId, models.AsyncTaskInProgress, "")
 		if err != nil {
 			node.logger.Error("failed to update async task status with request id", requestId, ", error", err)
 			return
@@ -193,7 +209,7 @@ func (node *nodeService) GetUncommittedLogs(requestId string) {
 		uncommittedTasks, err := node.asyncTaskManager.GetUnCommittedTasks()
 		if err != nil {
 			node.logger.Error("failed get uncommitted async tasks request id", requestId, ", error", err.Message)
-			uErr := node.asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskFail, "")
+			uErr := node.asyncTaskManager.UpdateTasksByRequestId(node.ctx, requestId, models.AsyncTaskFail, "")
 			if uErr != nil {
 				node.logger.Error("failed to update async task status with request id", "request id", requestId, ", error", uErr)
 			}
@@ -206,13 +222,13 @@ func (node *nodeService) GetUncommittedLogs(requestId string) {
 		data, mErr := json.Marshal(localData)
 		if mErr != nil {
 			node.logger.Error("failed to marshal async task result with request id", requestId, ", error", mErr)
-			uErr := node.asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskFail, "")
+			uErr := node.asyncTaskManager.UpdateTasksByRequestId(node.ctx, requestId, models.AsyncTaskFail, "")
 			if uErr != nil {
 				node.logger.Error("failed to update async task status with request id", requestId, ", error", uErr)
 			}
 			return
 		}
-		uErr := node.asyncTaskManager.UpdateTasksByRequestId(requestId, models.AsyncTaskSuccess, string(data))
+		uErr := node.asyncTaskManager.UpdateTasksByRequestId(node.ctx, requestId, models.AsyncTaskSuccess, string(data))
 		if uErr != nil {
 			node.logger.Error("failed to update async task status with request id", requestId, ", error", uErr)
 			return
@@ -244,6 +260,106 @@ func (node *nodeService) GetRaftLeaderWithId() (raft.ServerAddress, raft.ServerI
 	return node.scheduler0RaftStore.LeaderWithID()
 }
 
+// ReadIndex returns the index applied by the leader once a barrier committed after every earlier log entry is applied
+func (node *nodeService) ReadIndex(ctx context.Context) (uint64, *utils.GenericError) {
+	rft := node.scheduler0RaftStore.GetRaft()
+
+	timeout := time.Duration(constants.ReadIndexTimeoutMs) * time.Millisecond
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	if err := rft.Barrier(timeout).Error(); err != nil {
+		node.logger.Debug("failed to apply barrier for read index", "error", err.Error())
+		return 0, utils.HTTPGenericError(http.StatusServiceUnavailable, "node is not the leader")
+	}
+
+	return rft.AppliedIndex(), nil
+}
+
+// EnsureReadConsistency returns once the local database can serve a read of the consistency level.
+// Reads of the leader consistency are served by the leader, followers are expected to send them to it.
+func (node *nodeService) EnsureReadConsistency(ctx context.Context, consistency string, maxStaleness time.Duration) *utils.GenericError {
+	rft := node.scheduler0RaftStore.GetRaft()
+	isLeader := rft.State() == raft.Leader
+
+	switch consistency {
+	case constants.ReadConsistencyStale:
+		if isLeader || maxStaleness <= 0 {
+			return nil
+		}
+		if staleness := time.Since(rft.LastContact()); staleness > maxStaleness {
+			return utils.HTTPGenericError(http.StatusServiceUnavailable, fmt.Sprintf("node last heard from the leader %s ago, more than the max staleness of %s", staleness.Round(time.Millisecond), maxStaleness))
+		}
+		return nil
+	case constants.ReadConsistencyLeader:
+		if !isLeader {
+			return utils.HTTPGenericError(http.StatusServiceUnavailable, "node is not the leader")
+		}
+		if err := node.scheduler0RaftStore.VerifyLeader().Error(); err != nil {
+			node.logger.Debug("failed to verify leadership for read", "error", err.Error())
+			return utils.HTTPGenericError(http.StatusServiceUnavailable, "node is not the leader")
+		}
+		return nil
+	case constants.ReadConsistencyLinearizable:
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(constants.ReadIndexTimeoutMs)*time.Millisecond)
+		defer cancel()
+
+		if isLeader {
+			_, readErr := node.ReadIndex(ctx)
+			return readErr
+		}
+
+		leaderAddress, _ := node.scheduler0RaftStore.LeaderWithID()
+		configs := node.scheduler0Config.GetConfigurations()
+		for _, replica := range configs.Replicas {
+			if replica.RaftAddress != string(leaderAddress) || leaderAddress == "" {
+				continue
+			}
+			readIndex, err := node.nodeHTTPClient.GetReadIndex(ctx, node, replica)
+			if err != nil {
+				return utils.HTTPGenericError(http.StatusServiceUnavailable, "failed to get the read index of the leader")
+			}
+			return node.waitForAppliedIndex(ctx, readIndex)
+		}
+		return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+	default:
+		return utils.HTTPGenericError(http.StatusBadRequest, fmt.Sprintf("unsupported read consistency %s", consistency))
+	}
+}
+
+// ForwardCredentialUsage sends the usage of the credentials recorded on this follower to the leader, which writes it
+func (node *nodeService) ForwardCredentialUsage(ctx context.Context, usages []models.CredentialUsage) *utils.GenericError {
+	leaderAddress, _ := node.scheduler0RaftStore.LeaderWithID()
+	configs := node.scheduler0Config.GetConfigurations()
+	for _, replica := range configs.Replicas {
+		if replica.RaftAddress != string(leaderAddress) || leaderAddress == "" {
+			continue
+		}
+		if err := node.nodeHTTPClient.SendCredentialUsage(ctx, node, replica, usages); err != nil {
+			return utils.HTTPGenericError(http.StatusServiceUnavailable, "failed to send the credential usage to the leader")
+		}
+		return nil
+	}
+	return utils.HTTPGenericError(http.StatusServiceUnavailable, "service is unavailable")
+}
+
+func (node *nodeService) waitForAppliedIndex(ctx context.Context, index uint64) *utils.GenericError {
+	rft := node.scheduler0RaftStore.GetRaft()
+	ticker := time.NewTicker(time.Duration(constants.ReadIndexPollIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for rft.AppliedIndex() < index {
+		select {
+		case <-ctx.Done():
+			return utils.HTTPGenericError(http.StatusServiceUnavailable, fmt.Sprintf("timed out applying read index %d", index))
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
 func (node *nodeService) authenticateWithPeersInConfig() map[string]Status {
 	node.logger.Info("authenticating with nodes...")
 
@@ -367,7 +483,7 @@ func (node *nodeService) handleRaftLeadershipChanges(isLeader bool) {
 			}
 
 			if len(uncommittedAsyncTasks) > 0 {
-				_, err := node.asyncTaskRepo.RaftBatchInsert(uncommittedAsyncTasks, node.scheduler0Config.GetConfigurations().NodeId)
+				_, err := node.asyncTaskRepo.RaftBatchInsert(node.ctx, uncommittedAsyncTasks, node.scheduler0Config.GetConfigurations().NodeId)
 				if err != nil {
 					node.logger.Error("failed to insert uncommitted async tasks from", "raft-leader", "error", err)
 				}
@@ -389,6 +505,7 @@ func (node *nodeService) handleRaftLeadershipChanges(isLeader bool) {
 func (node *nodeService) handleRaftObserverChannelChanges(o raft.Observation) {
 	peerObservation, isPeerObservation := o.Data.(raft.PeerObservation)
 	resumedHeartbeatObservation, isResumedHeartbeatObservation := o.Data.(raft.ResumedHeartbeatObservation)
+	leaderObservation, isLeaderObservation := o.Data.(raft.LeaderObservation)
 
 	if isPeerObservation && !peerObservation.Removed {
 		node.logger.Debug("A new node joined the cluster")
@@ -400,6 +517,16 @@ func (node *nodeService) handleRaftObserverChannelChanges(o raft.Observation) {
 		node.logger.Debug(fmt.Sprintf("A node resumed execution. Peer ID %s ", string(resumedHeartbeatObservation.PeerID)))
 		node.startJobsOnWorkerNodes()
 	}
+	if isLeaderObservation {
+		// Every node observes the leader changes, so clients see them whichever node they stream events from
+		node.eventService.Publish(models.Event{
+			Type: models.EventTypeLeaderChanged,
+			Data: models.LeaderEvent{
+				LeaderAddress: string(leaderObservation.LeaderAddr),
+				LeaderId:      string(leaderObservation.LeaderID),
+			},
+		})
+	}
 }
 
 func (node *nodeService) handleUncommittedAsyncTasks(asyncTasks []models.AsyncTask) {
@@ -410,19 +537,19 @@ func (node *nodeService) handleUncommittedAsyncTasks(asyncTasks []models.AsyncTa
 			if err != nil {
 				node.logger.Error("failed to convert jobs payload from async task with id", "id", asyncTask.Id, "error", err.Error())
 			}
-			jobIds, batchInsertErr := node.jobRepo.BatchInsertJobs(jobsPayload)
+			jobIds, batchInsertErr := node.jobRepo.BatchInsertJobs(node.ctx, jobsPayload)
 			if batchInsertErr != nil {
 				node.logger.Error("failed to create jobs from async task with id", "id", asyncTask.Id, "error", batchInsertErr.Error())
 			}
 			resObj := utils.Response{Data: jobIds, Success: true}
-			updateTaskErr := node.asyncTaskManager.UpdateTasksByRequestId(asyncTask.RequestId, models.AsyncTaskSuccess, string(resObj.ToJSON()))
+			updateTaskErr := node.asyncTaskManager.UpdateTasksByRequestId(node.ctx, asyncTask.RequestId, models.AsyncTaskSuccess, string(resObj.ToJSON()))
 			if updateTaskErr != nil {
 				node.logger.Error("failed to update state of uncommitted async task", "error", updateTaskErr)
 			}
 			node.logger.Info("successfully created jobs from async task with id", "id", asyncTask.Id, "job-ids", jobIds)
 		}
 		if asyncTask.State == models.AsyncTaskInProgress && asyncTask.Service == constants.JobExecutorAsyncTaskService {
-			err := node.asyncTaskManager.UpdateTasksByRequestId(asyncTask.RequestId, models.AsyncTaskSuccess, "")
+			err := node.asyncTaskManager.UpdateTasksByRequestId(node.ctx, asyncTask.RequestId, models.AsyncTaskSuccess, "")
 			if err != nil {
 				node.logger.Error("failed to update state of uncommitted job executor async tasks to success", "error", err.Message)
 			}
@@ -438,6 +565,7 @@ func (node *nodeService) handleCompletedPeerFanIn(peerFanIn models.PeerFanIn) {
 		completed += 1
 		return true
 	})
+	metrics.FanInCompletedPeers.Set(float64(completed))
 	if completed == len(configs.Replicas)-1 && !node.CanAcceptClientWriteRequest() {
 		node.jobProcessor.StartJobs()
 		node.beginAcceptingClientWriteRequest()
@@ -532,11 +660,25 @@ func (node *nodeService) listenOnInputQueues() {
 			go node.handleCompletedPeerFanIn(peerFanIn)
 		case postProcess := <-node.postProcessingChannel:
 			{
+				// Every node publishes the events of the job revisions and refreshes the schedules
+				// it holds for the jobs, whatever the target nodes
+				if postProcess.Action == constants.CommandActionRecordJobRevisions {
+					if len(postProcess.JobRevisions) > 0 {
+						node.eventService.Publish(jobRevisionEvents(postProcess.JobRevisions)...)
+					}
+					go node.jobExecutor.RefreshJobSchedules(postProcess.JobIds)
+					continue
+				}
+				// Every node publishes the events of the committed execution logs, the node that
+				// wrote them also removes them from its uncommitted logs
+				if len(postProcess.ExecutionEvents) > 0 {
+					node.eventService.Publish(postProcess.ExecutionEvents...)
+				}
 				for _, postProcessTargetNode := range postProcess.TargetNodes {
 					if postProcessTargetNode == node.scheduler0Config.GetConfigurations().NodeId {
 						switch postProcess.Action {
 						case constants.CommandActionQueueJob:
-							go node.jobExecutor.QueueExecutions(postProcess.Data.LastInsertedId, postProcess.Data.RowsAffected)
+							go node.jobExecutor.QueueExecutions(tracing.ContextWithTraceParent(node.ctx, postProcess.TraceParent), postProcess.Data.LastInsertedId, postProcess.Data.RowsAffected)
 						case constants.CommandActionCleanUncommittedAsyncTasksLogs:
 							go node.asyncTaskManager.DeleteNewUncommittedAsyncLogs(postProcess.Data.LastInsertedId, postProcess.Data.RowsAffected)
 						case constants.CommandActionCleanUncommittedExecutionLogs:
@@ -639,6 +781,8 @@ func (node *nodeService) fanInLocalDataFromPeers() {
 					return true
 				})
 
+				node.recordFanInProgress()
+
 				if len(phase1) > 0 {
 					go node.nodeHTTPClient.FetchUncommittedLogsFromPeersPhase1(ctx, node, phase1)
 				}
@@ -656,15 +800,34 @@ func (node *nodeService) fanInLocalDataFromPeers() {
 	}()
 }
 
+// recordFanInProgress reports the number of peers in each phase of the fan-in
+func (node *nodeService) recordFanInProgress() {
+	phases := map[models.PeerFanInState]float64{
+		models.PeerFanInStateNotStated:         0,
+		models.PeerFanInStateGetRequestId:      0,
+		models.PeerFanInStateGetExecutionsLogs: 0,
+		models.PeerFanInStateComplete:          0,
+	}
+	node.fanIns.Range(func(key, value any) bool {
+		phases[value.(models.PeerFanIn).State] += 1
+		return true
+	})
+	metrics.FanInPeers.WithLabelValues("not_started").Set(phases[models.PeerFanInStateNotStated])
+	metrics.FanInPeers.WithLabelValues("get_request_id").Set(phases[models.PeerFanInStateGetRequestId])
+	metrics.FanInPeers.WithLabelValues("get_execution_logs").Set(phases[models.PeerFanInStateGetExecutionsLogs])
+	metrics.FanInPeers.WithLabelValues("complete").Set(phases[models.PeerFanInStateComplete])
+}
+
 func (node *nodeService) commitFetchedUnCommittedLogs(peerFanIns []models.PeerFanIn) {
 	for _, peerFanIn := range peerFanIns {
 
 		if len(peerFanIn.Data.ExecutionLogs) > 0 {
 			node.jobExecutionRepo.RaftInsertExecutionLogs(peerFanIn.Data.ExecutionLogs, node.scheduler0Config.GetConfigurations().NodeId)
+			metrics.FanInExecutionLogs.Add(float64(len(peerFanIn.Data.ExecutionLogs)))
 		}
 
 		if len(peerFanIn.Data.AsyncTasks) > 0 {
-			_, err := node.asyncTaskRepo.RaftBatchInsert(peerFanIn.Data.AsyncTasks, node.scheduler0Config.GetConfigurations().NodeId)
+			_, err := node.asyncTaskRepo.RaftBatchInsert(node.ctx, peerFanIn.Data.AsyncTasks, node.scheduler0Config.GetConfigurations().NodeId)
 			if err != nil {
 				node.logger.Error("failed to insert uncommitted async tasks from", "peer", peerFanIn.PeerHTTPAddress, "error", err)
 			}
@@ -674,3 +837,115 @@ func (node *nodeService) commitFetchedUnCommittedLogs(peerFanIns []models.PeerFa
 		node.fanInCh <- peerFanIn
 	}
 }
+
+func (node *nodeService) compactExecutionLogsPeriodically() {
+	go func() {
+		configs := node.scheduler0Config.GetConfigurations()
+		ticker := time.NewTicker(time.Duration(configs.ExecutionLogRetentionIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if node.scheduler0RaftStore.GetRaft().State() != raft.Leader {
+					continue
+				}
+				deleted, err := node.jobExecutionRepo.CompactExecutionLogs(node.executionLogRetentionPolicy(), time.Now())
+				if err != nil {
+					node.logger.Error("failed to compact execution logs", "error", err.Message)
+					continue
+				}
+				node.logger.Debug("compacted execution logs", "deleted", deleted)
+			case <-node.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// pruneIdempotencyKeysPeriodically deletes idempotency keys older than constants.IdempotencyKeyTTL while the node is the leader
+func (node *nodeService) pruneIdempotencyKeysPeriodically() {
+	go func() {
+		ticker := time.NewTicker(constants.IdempotencyKeysPruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if node.scheduler0RaftStore.GetRaft().State() != raft.Leader {
+					continue
+				}
+				schedulerTime := scheduler0time.GetSchedulerTime()
+				deleted, err := node.jobRepo.DeleteExpiredIdempotencyKeys(schedulerTime.GetTime(time.Now()).Add(-constants.IdempotencyKeyTTL))
+				if err != nil {
+					node.logger.Error("failed to prune idempotency keys", "error", err.Message)
+					continue
+				}
+				node.logger.Debug("pruned idempotency keys", "deleted", deleted)
+			case <-node.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// resealJobsDataPeriodically encrypts the jobs data with the active key once every time the node becomes the leader
+func (node *nodeService) resealJobsDataPeriodically() {
+	go func() {
+		ticker := time.NewTicker(constants.JobsDataResealInterval)
+		defer ticker.Stop()
+
+		resealed := false
+		for {
+			select {
+			case <-ticker.C:
+				if node.scheduler0RaftStore.GetRaft().State() != raft.Leader {
+					resealed = false
+					continue
+				}
+				if !resealed {
+					resealed = node.jobExecutor.ResealJobsData() == nil
+				}
+			case <-node.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (node *nodeService) executionLogRetentionPolicy() models.ExecutionLogRetentionPolicy {
+	configs := node.scheduler0Config.GetConfigurations()
+
+	return models.ExecutionLogRetentionPolicy{
+		MaxAge:    time.Duration(configs.ExecutionLogRetentionMaxAgeSeconds) * time.Second,
+		MaxPerJob: configs.ExecutionLogRetentionMaxPerJob,
+		MaxPerState: map[models.JobExecutionLogState]uint64{
+			models.ExecutionLogScheduleState: configs.ExecutionLogRetentionMaxScheduledPerJob,
+			models.ExecutionLogSuccessState:  configs.ExecutionLogRetentionMaxSuccessPerJob,
+			models.ExecutionLogFailedState:   configs.ExecutionLogRetentionMaxFailedPerJob,
+		},
+		DailyRollup: configs.ExecutionLogRetentionDailyRollup,
+	}
+}
+
+// jobRevisionEvents returns the job events of the revisions recorded by a raft command
+func jobRevisionEvents(jobRevisions []models.JobRevision) []models.Event {
+	events := make([]models.Event, 0, len(jobRevisions))
+	for _, jobRevision := range jobRevisions {
+		eventType := models.EventTypeJobUpdated
+		switch jobRevision.Action {
+		case models.JobRevisionActionCreate:
+			eventType = models.EventTypeJobCreated
+		case models.JobRevisionActionDelete:
+			eventType = models.EventTypeJobDeleted
+		}
+		events = append(events, models.Event{
+			Type:        eventType,
+			ProjectID:   jobRevision.Job.ProjectID,
+			JobID:       jobRevision.JobID,
+			Data:        jobRevision,
+			DateCreated: jobRevision.DateCreated,
+		})
+	}
+	return events
+}
@@ -13,4 +13,6 @@ type NodeClient interface {
 	ConnectNode(replica config.RaftNode) (*Status, error)
 	StopJobs(ctx context.Context, node *nodeService, peer config.RaftNode) error
 	StartJobs(ctx context.Context, node *nodeService, peer config.RaftNode) error
+	GetReadIndex(ctx context.Context, node *nodeService, leader config.RaftNode) (uint64, error)
+	SendCredentialUsage(ctx context.Context, node *nodeService, leader config.RaftNode, usages []models.CredentialUsage) error
 }
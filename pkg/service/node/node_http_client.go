@@ -1,6 +1,7 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"io"
 	"net/http"
+	"scheduler0/pkg/certificates"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/constants/headers"
@@ -24,12 +26,13 @@ type nodeHTTPClient struct {
 	httpClient        *http.Client
 }
 
-func NewHTTPClient(logger hclog.Logger, scheduler0Configs config.Scheduler0Config, scheduler0Secrets secrets.Scheduler0Secrets) NodeClient {
+// NewHTTPClient returns the client of the peer endpoints, which presents the certificate of the node to peers when certs is not nil
+func NewHTTPClient(logger hclog.Logger, scheduler0Configs config.Scheduler0Config, scheduler0Secrets secrets.Scheduler0Secrets, certs *certificates.Reloader) NodeClient {
 	return nodeHTTPClient{
 		logger:            logger,
 		scheduler0Configs: scheduler0Configs,
 		scheduler0Secrets: scheduler0Secrets,
-		httpClient:        &http.Client{},
+		httpClient:        &http.Client{Transport: certs.Transport()},
 	}
 }
 
@@ -72,7 +75,6 @@ func (client nodeHTTPClient) FetchUncommittedLogsFromPeersPhase1(ctx context.Con
 
 func (client nodeHTTPClient) FetchUncommittedLogsFromPeersPhase2(ctx context.Context, node *nodeService, peerFanIns []models.PeerFanIn) {
 	for _, peerFanIn := range peerFanIns {
-		httpClient := &http.Client{}
 		httpRequest, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s", peerFanIn.PeerHTTPAddress, peerFanIn.RequestId), nil)
 		if reqErr != nil {
 			node.logger.Error("failed to create request to execution logs from", "node address", peerFanIn.PeerHTTPAddress, "error", reqErr.Error())
@@ -81,7 +83,7 @@ func (client nodeHTTPClient) FetchUncommittedLogsFromPeersPhase2(ctx context.Con
 			httpRequest.Header.Set(headers.PeerAddressHeader, utils.GetServerHTTPAddress())
 			secret := node.scheduler0Secrets.GetSecrets()
 			httpRequest.SetBasicAuth(secret.AuthUsername, secret.AuthPassword)
-			res, err := httpClient.Do(httpRequest)
+			res, err := client.httpClient.Do(httpRequest)
 			if err != nil {
 				node.logger.Error("failed to get uncommitted execution logs from", "node address", peerFanIn.PeerHTTPAddress, "error", err.Error())
 				node.fanIns.Delete(peerFanIn.PeerHTTPAddress)
@@ -129,7 +131,8 @@ func (client nodeHTTPClient) FetchUncommittedLogsFromPeersPhase2(ctx context.Con
 func (client nodeHTTPClient) ConnectNode(rep config.RaftNode) (*Status, error) {
 	configs := client.scheduler0Configs.GetConfigurations()
 	httpClient := http.Client{
-		Timeout: time.Duration(configs.PeerAuthRequestTimeoutMs) * time.Millisecond,
+		Timeout:   time.Duration(configs.PeerAuthRequestTimeoutMs) * time.Millisecond,
+		Transport: client.httpClient.Transport,
 	}
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%v/peer-handshake", rep.Address, constants.APIV1Base), nil)
 	if err != nil {
@@ -266,3 +269,66 @@ func (client nodeHTTPClient) StartJobs(ctx context.Context, node *nodeService, p
 	}
 	return nil
 }
+
+func (client nodeHTTPClient) GetReadIndex(ctx context.Context, node *nodeService, leader config.RaftNode) (uint64, error) {
+	httpRequest, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v%v/read-index", leader.Address, constants.APIV1Base), nil)
+	if reqErr != nil {
+		client.logger.Error("failed to create request to read index from", "node address", leader.Address, "error", reqErr.Error())
+		return 0, reqErr
+	}
+	httpRequest.Header.Set(headers.PeerHeader, headers.PeerHeaderValue)
+	httpRequest.Header.Set(headers.PeerAddressHeader, utils.GetServerHTTPAddress())
+	secret := node.scheduler0Secrets.GetSecrets()
+	httpRequest.SetBasicAuth(secret.AuthUsername, secret.AuthPassword)
+	res, err := client.httpClient.Do(httpRequest)
+	if err != nil {
+		client.logger.Error("failed to get read index from", "node address", leader.Address, "error", err.Error())
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		client.logger.Error("failed to get read index from", "node address", leader.Address, "state code", res.StatusCode)
+		return 0, errors.New("failed to get read index")
+	}
+
+	body := struct {
+		Data    models.ReadIndex `json:"data"`
+		Success bool             `json:"success"`
+	}{}
+	if decodeErr := json.NewDecoder(res.Body).Decode(&body); decodeErr != nil {
+		client.logger.Error("failed to decode read index from", "node address", leader.Address, "error", decodeErr.Error())
+		return 0, decodeErr
+	}
+
+	return body.Data.Index, nil
+}
+
+func (client nodeHTTPClient) SendCredentialUsage(ctx context.Context, node *nodeService, leader config.RaftNode, usages []models.CredentialUsage) error {
+	body, marshalErr := json.Marshal(usages)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	httpRequest, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%v%v/credential-usage", leader.Address, constants.APIV1Base), bytes.NewReader(body))
+	if reqErr != nil {
+		client.logger.Error("failed to create request to send credential usage to", "node address", leader.Address, "error", reqErr.Error())
+		return reqErr
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set(headers.PeerHeader, headers.PeerHeaderValue)
+	httpRequest.Header.Set(headers.PeerAddressHeader, utils.GetServerHTTPAddress())
+	secret := node.scheduler0Secrets.GetSecrets()
+	httpRequest.SetBasicAuth(secret.AuthUsername, secret.AuthPassword)
+	res, err := client.httpClient.Do(httpRequest)
+	if err != nil {
+		client.logger.Error("failed to send credential usage to", "node address", leader.Address, "error", err.Error())
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted {
+		client.logger.Error("failed to send credential usage to", "node address", leader.Address, "state code", res.StatusCode)
+		return errors.New("failed to send credential usage")
+	}
+	return nil
+}
@@ -8,9 +8,12 @@ import (
 	"github.com/hashicorp/raft"
 	"github.com/robfig/cron"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"scheduler0/pkg/config"
+	"scheduler0/pkg/constants"
 	"scheduler0/pkg/db"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
@@ -22,6 +25,7 @@ import (
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/secrets"
 	"scheduler0/pkg/service/async_task"
+	"scheduler0/pkg/service/event"
 	"scheduler0/pkg/service/executor"
 	"scheduler0/pkg/service/executor/executors"
 	"scheduler0/pkg/service/job"
@@ -115,7 +119,7 @@ import (
 //		dispatcher,
 //	)
 //
-//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets)
+//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets, nil)
 //	jobProcessor := processor.NewMockJobProcessorService(t)
 //
 //	nodeService := NewNode(
@@ -226,7 +230,7 @@ import (
 //		dispatcher,
 //	)
 //
-//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets)
+//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets, nil)
 //	jobProcessor := processor.NewMockJobProcessorService(t)
 //
 //	nodeService := NewNode(
@@ -339,10 +343,11 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		nil,
 	)
-	jobService := job.NewJobService(ctx, logger, jobRepo, queueService, projectRepo, dispatcher, asyncTaskService)
+	jobService := job.NewJobService(ctx, logger, jobRepo, queueService, projectRepo, dispatcher, asyncTaskService, nil)
 
-	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets)
+	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets, nil)
 	jobProcessor := processor.NewMockJobProcessorService(t)
 
 	nodeService := NewNode(
@@ -361,6 +366,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 		asyncTaskService,
 		dispatcher,
 		nodeHTTPClient,
+		event.NewEventService(logger, scheduler0config),
 		nil,
 		false,
 	)
@@ -393,7 +399,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 	}
 
 	// Call the BatchInsertJobs method of the job service
-	_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+	_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 	if batchErr != nil {
 		t.Fatalf("Failed to insert jobs: %v", batchErr)
 	}
@@ -535,7 +541,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 //		httpJobExecutor,
 //		dispatcher,
 //	)
-//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets)
+//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets, nil)
 //	jobProcessor := processor.NewMockJobProcessorService(t)
 //
 //	nodeService := NewNode(
@@ -651,7 +657,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 //		httpJobExecutor,
 //		dispatcher,
 //	)
-//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets)
+//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets, nil)
 //	jobProcessor := processor.NewMockJobProcessorService(t)
 //
 //	nodeService := NewNode(
@@ -828,7 +834,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 //		httpJobExecutor,
 //		dispatcher,
 //	)
-//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets)
+//	nodeHTTPClient := NewHTTPClient(logger, scheduler0config, scheduler0Secrets, nil)
 //	jobProcessor := processor.NewMockJobProcessorService(t)
 //
 //	nodeService := NewNode(
@@ -1261,7 +1267,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 //		nodeHTTPClient := NewMockNodeClient(t)
 //		nodeHTTPClient.On("StopJobs", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 //
-//		jobService := job.NewJobService(ctx, logger, jobRepo, queueService, projectRepo, dispatcher, asyncTaskService)
+//		jobService := job.NewJobService(ctx, logger, jobRepo, queueService, projectRepo, dispatcher, asyncTaskService, nil)
 //		jobProcessor := processor.NewMockJobProcessorService(t)
 //
 //		nodeService := NewNode(
@@ -1325,7 +1331,7 @@ func Test_ReturnUncommittedLogs(t *testing.T) {
 //		}
 //
 //		// Call the BatchInsertJobs method of the job service
-//		_, batchErr := jobService.BatchInsertJobs("request123", jobs)
+//		_, batchErr := jobService.BatchInsertJobs(context.Background(), "request123", jobs)
 //		if batchErr != nil {
 //			t.Fatalf("Failed to insert jobs: %v", batchErr)
 //		}
@@ -2127,6 +2133,7 @@ func Test_StopJobs(t *testing.T) {
 			asyncTaskService,
 			dispatcher,
 			nodeHTTPClient,
+			event.NewEventService(logger, scheduler0config),
 			nil,
 			false,
 		)
@@ -2222,9 +2229,145 @@ func Test_StartJobs(t *testing.T) {
 			asyncTaskService,
 			dispatcher,
 			nodeHTTPClient,
+			event.NewEventService(logger, scheduler0config),
 			nil,
 			false,
 		)
 		nodeService.StartJobs()
 	})
 }
+
+func Test_EnsureReadConsistency(t *testing.T) {
+	t.Cleanup(func() {
+		err := os.RemoveAll("./raft_data")
+		if err != nil {
+			fmt.Println("failed to remove raft_data dir for test", err)
+		}
+		err = os.RemoveAll("./sqlite_data")
+		if err != nil {
+			fmt.Println("failed to remove sqlite_data dir for test", err)
+		}
+	})
+	ctx, cancelr := context.WithCancel(context.Background())
+	defer cancelr()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "node-service-test",
+		Level: hclog.LevelFromString("trace"),
+	})
+
+	// Create a temporary SQLite database file
+	tempFile, err := ioutil.TempFile("", "test-db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	// Create a new SQLite database connection
+	sqliteDb := db.NewSqliteDbConnection(logger, tempFile.Name())
+	sqliteDb.RunMigration()
+	sqliteDb.OpenConnectionToExistingDB()
+
+	scheduler0config := config.NewScheduler0Config()
+	sharedRepo := shared_repo.NewSharedRepo(logger, scheduler0config)
+	scheduler0RaftActions := fsm.NewScheduler0RaftActions(sharedRepo, nil)
+
+	scheduler0Secrets := secrets.NewScheduler0Secrets()
+	leaderStore := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, sharedRepo)
+	followerStore := fsm.NewFSMStore(logger, scheduler0RaftActions, scheduler0config, sqliteDb, nil, nil, nil, nil, sharedRepo)
+
+	cluster := raft.MakeClusterCustom(t, &raft.MakeClusterOpts{
+		Peers:          2,
+		Bootstrap:      true,
+		Conf:           raft.DefaultConfig(),
+		ConfigStoreFSM: false,
+		MakeFSMFunc: func() raft.FSM {
+			return leaderStore.GetFSM()
+		},
+	})
+	cluster.FullyConnect()
+
+	clusterLeader := cluster.Leader()
+	leaderStore.UpdateRaft(clusterLeader)
+	followerStore.UpdateRaft(cluster.Followers()[0])
+
+	leaderAddress, _ := cluster.Followers()[0].LeaderWithID()
+	replicas, err := json.Marshal([]config.RaftNode{
+		{Address: "http://leader", RaftAddress: string(leaderAddress), NodeId: 1},
+	})
+	assert.Nil(t, err)
+	t.Setenv("SCHEDULER0_REPLICAS", string(replicas))
+
+	newNodeService := func(scheduler0Store fsm.Scheduler0RaftStore, nodeHTTPClient NodeClient) NodeService {
+		jobRepo := job_repo.NewJobRepo(logger, scheduler0RaftActions, scheduler0Store)
+		asyncTaskManagerRepo := async_task_repo.NewAsyncTasksRepo(ctx, logger, scheduler0RaftActions, scheduler0Store)
+		asyncTaskService := async_task.NewAsyncTaskManager(ctx, logger, scheduler0Store, asyncTaskManagerRepo, scheduler0config)
+		jobQueueRepo := job_queue_repo.NewJobQueuesRepo(logger, scheduler0RaftActions, scheduler0Store)
+		jobExecutionsRepo := job_execution_repo.NewExecutionsRepo(logger, scheduler0RaftActions, scheduler0Store)
+		dispatcher := utils.NewDispatcher(ctx, int64(1), int64(1))
+		queueService := queue.NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
+
+		return NewNode(
+			ctx,
+			logger,
+			scheduler0config,
+			scheduler0Secrets,
+			scheduler0Store,
+			scheduler0RaftActions,
+			executor.NewMockJobExecutorService(t),
+			queueService,
+			processor.NewMockJobProcessorService(t),
+			jobRepo,
+			sharedRepo,
+			jobExecutionsRepo,
+			asyncTaskService,
+			dispatcher,
+			nodeHTTPClient,
+			event.NewEventService(logger, scheduler0config),
+			nil,
+			false,
+		)
+	}
+
+	t.Run("leader serves reads of every consistency", func(t *testing.T) {
+		leaderService := newNodeService(leaderStore, NewMockNodeClient(t))
+
+		assert.Nil(t, leaderService.EnsureReadConsistency(ctx, constants.ReadConsistencyStale, time.Nanosecond))
+		assert.Nil(t, leaderService.EnsureReadConsistency(ctx, constants.ReadConsistencyLeader, 0))
+		assert.Nil(t, leaderService.EnsureReadConsistency(ctx, constants.ReadConsistencyLinearizable, 0))
+
+		readIndex, readErr := leaderService.ReadIndex(ctx)
+		assert.Nil(t, readErr)
+		assert.Equal(t, clusterLeader.AppliedIndex(), readIndex)
+
+		unsupportedErr := leaderService.EnsureReadConsistency(ctx, "eventual", 0)
+		assert.NotNil(t, unsupportedErr)
+		assert.Equal(t, http.StatusBadRequest, unsupportedErr.Type)
+	})
+
+	t.Run("follower serves stale and linearizable reads", func(t *testing.T) {
+		nodeHTTPClient := NewMockNodeClient(t)
+		followerService := newNodeService(followerStore, nodeHTTPClient)
+
+		assert.Nil(t, followerService.EnsureReadConsistency(ctx, constants.ReadConsistencyStale, 0))
+		assert.Nil(t, followerService.EnsureReadConsistency(ctx, constants.ReadConsistencyStale, time.Hour))
+
+		staleErr := followerService.EnsureReadConsistency(ctx, constants.ReadConsistencyStale, time.Nanosecond)
+		assert.NotNil(t, staleErr)
+		assert.Equal(t, http.StatusServiceUnavailable, staleErr.Type)
+
+		leaderErr := followerService.EnsureReadConsistency(ctx, constants.ReadConsistencyLeader, 0)
+		assert.NotNil(t, leaderErr)
+		assert.Equal(t, http.StatusServiceUnavailable, leaderErr.Type)
+
+		nodeHTTPClient.On("GetReadIndex", mock.Anything, mock.Anything, mock.Anything).Return(clusterLeader.AppliedIndex(), nil).Once()
+		assert.Nil(t, followerService.EnsureReadConsistency(ctx, constants.ReadConsistencyLinearizable, 0))
+
+		// The follower never applies an index past the last index of the leader
+		nodeHTTPClient.On("GetReadIndex", mock.Anything, mock.Anything, mock.Anything).Return(clusterLeader.LastIndex()+10, nil).Once()
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		linearizableErr := followerService.EnsureReadConsistency(timeoutCtx, constants.ReadConsistencyLinearizable, 0)
+		assert.NotNil(t, linearizableErr)
+		assert.Equal(t, http.StatusServiceUnavailable, linearizableErr.Type)
+	})
+}
@@ -77,7 +77,7 @@ func (jobProcessor *jobProcessor) StartJobs() {
 
 	jobProcessor.logger.Debug("total number of projects: ", "count", totalProjectCount)
 
-	projects, listErr := jobProcessor.projectRepo.List(0, totalProjectCount)
+	projects, _, listErr := jobProcessor.projectRepo.List(models.PageRequest{Limit: totalProjectCount})
 	if listErr != nil {
 		jobProcessor.logger.Error("could not list the number of projects", "message", listErr.Message)
 		log.Fatalln("could not list the number of projects", listErr.Message)
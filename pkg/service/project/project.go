@@ -22,7 +22,7 @@ type ProjectService interface {
 	GetOneByID(project *models.Project) *utils.GenericError
 	GetOneByName(project *models.Project) *utils.GenericError
 	DeleteOneByID(project models.Project) *utils.GenericError
-	List(offset uint64, limit uint64) (*models.PaginatedProject, *utils.GenericError)
+	List(page models.PageRequest) (*models.PaginatedProject, *utils.GenericError)
 	BatchGetProjects(projectIds []uint64) ([]models.Project, *utils.GenericError)
 }
 
@@ -96,24 +96,28 @@ func (projectService *projectService) DeleteOneByID(project models.Project) *uti
 	return nil
 }
 
-// List return a paginated list of projects
-func (projectService *projectService) List(offset uint64, limit uint64) (*models.PaginatedProject, *utils.GenericError) {
-	projects, err := projectService.projectRepo.List(offset, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	count, err := projectService.projectRepo.Count()
+// List return a paginated list of projects, pages read with a cursor skip counting the projects
+func (projectService *projectService) List(page models.PageRequest) (*models.PaginatedProject, *utils.GenericError) {
+	projects, cursors, err := projectService.projectRepo.List(page)
 	if err != nil {
 		return nil, err
 	}
 
 	paginatedProjects := models.PaginatedProject{}
 
-	paginatedProjects.Total = count
+	if page.Cursor == "" {
+		count, err := projectService.projectRepo.Count()
+		if err != nil {
+			return nil, err
+		}
+		paginatedProjects.Total = count
+		paginatedProjects.Offset = page.Offset
+	}
+
 	paginatedProjects.Data = projects
-	paginatedProjects.Limit = limit
-	paginatedProjects.Offset = offset
+	paginatedProjects.Limit = page.Limit
+	paginatedProjects.Next = cursors.Next
+	paginatedProjects.Prev = cursors.Prev
 
 	return &paginatedProjects, nil
 }
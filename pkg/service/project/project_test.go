@@ -462,7 +462,7 @@ func Test_ProjectService_List(t *testing.T) {
 	// Call the List method of the project service
 	offset := uint64(0)
 	limit := uint64(10)
-	paginatedProjects, listErr := projectService.List(offset, limit)
+	paginatedProjects, listErr := projectService.List(models.PageRequest{Offset: offset, Limit: limit})
 	if listErr != nil {
 		t.Fatalf("Failed to retrieve projects: %v", listErr)
 	}
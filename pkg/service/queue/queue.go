@@ -3,11 +3,13 @@ package queue
 import (
 	"context"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
 	"math"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/fsm"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/repository/job_queue"
+	"scheduler0/pkg/tracing"
 	"scheduler0/pkg/utils"
 	"sync"
 )
@@ -103,7 +105,12 @@ func (jobQ *jobQueue) GetJobAllocations() map[uint64]uint64 {
 	jobQ.mtx.Lock()
 	defer jobQ.mtx.Unlock()
 
-	return jobQ.allocations
+	allocations := make(map[uint64]uint64, len(jobQ.allocations))
+	for nodeId, allocation := range jobQ.allocations {
+		allocations[nodeId] = allocation
+	}
+
+	return allocations
 }
 
 func (jobQ *jobQueue) SetSingleNodeMode(singleNodeMode bool) {
@@ -125,6 +132,10 @@ func (jobQ *jobQueue) queue(minId, maxId int64) {
 		return
 	}
 
+	// Queueing is debounced across requests so it starts its own trace
+	ctx, span := tracing.StartSpan(context.Background(), "JobQueue.queue", attribute.Int64("scheduler0.min_job_id", minId), attribute.Int64("scheduler0.max_job_id", maxId))
+	defer span.End()
+
 	serverAllocations := jobQ.assignJobRangeToServers(minId, maxId)
 	lastVersion := jobQ.jobsQueueRepo.GetLastVersion()
 	j := 0
@@ -151,7 +162,7 @@ func (jobQ *jobQueue) queue(minId, maxId int64) {
 		}
 	}
 
-	jobQ.jobsQueueRepo.InsertJobQueueLogs(jobQueueLogs)
+	jobQ.jobsQueueRepo.InsertJobQueueLogs(ctx, jobQueueLogs)
 }
 
 func (jobQ *jobQueue) getNextServerToQueue() uint64 {
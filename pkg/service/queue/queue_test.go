@@ -158,7 +158,7 @@ func Test_Queue_Queue(t *testing.T) {
 
 	jobQueueRepo := job_queue_repo.NewMockJobQueuesRepo(t)
 	jobQueueRepo.On("GetLastVersion").Return(uint64(1))
-	jobQueueRepo.On("InsertJobQueueLogs", mock.Anything)
+	jobQueueRepo.On("InsertJobQueueLogs", mock.Anything, mock.Anything)
 	jobQueue := NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	jobQueue.AddServers([]uint64{1, 2, 3, 4, 5})
 
@@ -176,7 +176,7 @@ func Test_Queue_Queue(t *testing.T) {
 	minNodeId := math.MaxInt
 	minQueueJob := math.MaxInt
 
-	for _, args := range jobQueueRepo.Calls[1].Arguments {
+	for _, args := range jobQueueRepo.Calls[1].Arguments[1:] {
 		logs := args.([]models.JobQueueLog)
 		for _, jobQueueLog := range logs {
 			numQueueJobForNode := int(jobQueueLog.UpperBoundJobId-jobQueueLog.LowerBoundJobId) + 1
@@ -200,7 +200,7 @@ func Test_Queue_Queue(t *testing.T) {
 
 	jobQueue.Queue(jobs)
 
-	assert.Equal(t, uint64(minNodeId), jobQueueRepo.Calls[3].Arguments[0].([]models.JobQueueLog)[0].NodeId)
+	assert.Equal(t, uint64(minNodeId), jobQueueRepo.Calls[3].Arguments[1].([]models.JobQueueLog)[0].NodeId)
 }
 
 func Test_Queue_Queue_SingleNodeMode(t *testing.T) {
@@ -230,7 +230,7 @@ func Test_Queue_Queue_SingleNodeMode(t *testing.T) {
 
 	jobQueueRepo := job_queue_repo.NewMockJobQueuesRepo(t)
 	jobQueueRepo.On("GetLastVersion").Return(uint64(1))
-	jobQueueRepo.On("InsertJobQueueLogs", mock.Anything)
+	jobQueueRepo.On("InsertJobQueueLogs", mock.Anything, mock.Anything)
 	jobQueue := NewJobQueue(ctx, logger, scheduler0config, scheduler0RaftActions, scheduler0Store, jobQueueRepo)
 	jobQueue.SetSingleNodeMode(true)
 	jobQueue.AddServers([]uint64{1, 2, 3, 4})
@@ -248,7 +248,7 @@ func Test_Queue_Queue_SingleNodeMode(t *testing.T) {
 	totalJobsQueued := 0
 	numberOfNodes := 0
 
-	for _, args := range jobQueueRepo.Calls[1].Arguments {
+	for _, args := range jobQueueRepo.Calls[1].Arguments[1:] {
 		logs := args.([]models.JobQueueLog)
 		for _, jobQueueLog := range logs {
 			numQueueJobForNode := int(jobQueueLog.UpperBoundJobId-jobQueueLog.LowerBoundJobId) + 1
@@ -12,13 +12,18 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"scheduler0/pkg/certificates"
 	"scheduler0/pkg/config"
 	"scheduler0/pkg/constants"
 	"scheduler0/pkg/db"
+	"scheduler0/pkg/encryption"
 	"scheduler0/pkg/fsm"
+	"scheduler0/pkg/metrics"
 	"scheduler0/pkg/models"
 	"scheduler0/pkg/network"
+	alert_repo "scheduler0/pkg/repository/alert"
 	async_task_repo "scheduler0/pkg/repository/async_task"
+	audit_repo "scheduler0/pkg/repository/audit"
 	credential_repo "scheduler0/pkg/repository/credential"
 	job_repo "scheduler0/pkg/repository/job"
 	job_execution_repo "scheduler0/pkg/repository/job_execution"
@@ -26,11 +31,15 @@ import (
 	project_repo "scheduler0/pkg/repository/project"
 	"scheduler0/pkg/scheduler0time"
 	"scheduler0/pkg/secrets"
+	"scheduler0/pkg/service/alert"
 	"scheduler0/pkg/service/async_task"
+	"scheduler0/pkg/service/audit"
 	"scheduler0/pkg/service/credential"
+	"scheduler0/pkg/service/event"
 	"scheduler0/pkg/service/executor"
 	"scheduler0/pkg/service/executor/executors"
 	"scheduler0/pkg/service/job"
+	"scheduler0/pkg/service/job_execution"
 	"scheduler0/pkg/service/node"
 	"scheduler0/pkg/service/processor"
 	"scheduler0/pkg/service/project"
@@ -41,17 +50,43 @@ import (
 )
 
 type Service struct {
-	Dispatcher         *utils.Dispatcher
-	JobService         job.JobService
-	ProjectService     project.ProjectService
-	CredentialService  credential.CredentialService
-	JobExecutorService executor.JobExecutorService
-	NodeService        node.NodeService
-	JobQueueService    queue.JobQueueService
-	AsyncTaskService   async_task.AsyncTaskService
+	Dispatcher          *utils.Dispatcher
+	JobService          job.JobService
+	ProjectService      project.ProjectService
+	CredentialService   credential.CredentialService
+	JobExecutorService  executor.JobExecutorService
+	NodeService         node.NodeService
+	JobQueueService     queue.JobQueueService
+	AsyncTaskService    async_task.AsyncTaskService
+	JobExecutionService job_execution.JobExecutionService
+	AlertService        alert.AlertService
+	EventService        event.EventService
+	AuditService        audit.AuditService
+	Certificates        *certificates.Reloader // Certificate of the node, nil when TLS is not configured
 }
 
-func connectRaftLogsAndTransport(scheduler0Config config.Scheduler0Config) (
+// raftSecurity returns how the raft connections are secured, with TLS when RaftTLS is set and with the shared key of the secrets
+func raftSecurity(configs *config.Scheduler0Configurations, scheduler0Secrets secrets.Scheduler0Secrets, certs *certificates.Reloader) network.Security {
+	security := network.Security{}
+	if sharedKey := scheduler0Secrets.GetSecrets().RaftSharedKey; sharedKey != "" {
+		security.SharedKey = []byte(sharedKey)
+	}
+	if !configs.RaftTLS {
+		return security
+	}
+
+	security.Certificates = certs
+	for _, replica := range configs.Replicas {
+		host, _, err := net.SplitHostPort(replica.RaftAddress)
+		if err != nil {
+			log.Fatalf("failed to parse the raft address %v of replica %v: %v", replica.RaftAddress, replica.NodeId, err)
+		}
+		security.PeerHosts = append(security.PeerHosts, host)
+	}
+	return security
+}
+
+func connectRaftLogsAndTransport(scheduler0Config config.Scheduler0Config, security network.Security) (
 	*boltdb.BoltStore,
 	*boltdb.BoltStore,
 	*raft.FileSnapshotStore,
@@ -83,7 +118,7 @@ func connectRaftLogsAndTransport(scheduler0Config config.Scheduler0Config) (
 		Address: configs.NodeAdvAddress,
 	}
 
-	mux := network.NewMux(ln, adv)
+	mux := network.NewSecureMux(ln, adv, security)
 	go func() {
 		err := mux.Serve()
 		if err != nil {
@@ -119,6 +154,16 @@ func NewService(ctx context.Context, logger hclog.Logger) *Service {
 
 	postProcessChannel := make(chan models.PostProcess, 1)
 
+	certs, certsErr := certificates.NewReloaderFromConfig(logger, scheduler0Configs)
+	if certsErr != nil {
+		log.Fatal("failed to load the tls certificates: ", certsErr)
+	}
+
+	keyring, keyringErr := encryption.NewKeyringFromConfig(scheduler0Configs, scheduler0Secrets)
+	if keyringErr != nil {
+		log.Fatal("failed to load the data encryption keys: ", keyringErr)
+	}
+
 	sqliteDb := db.CreateConnectionFromNewDbIfNonExists(logger)
 	sharedRep := shared_repo.NewSharedRepo(logger, scheduler0Configs)
 	fsmActions := fsm.NewScheduler0RaftActions(sharedRep, postProcessChannel)
@@ -128,7 +173,7 @@ func NewService(ctx context.Context, logger hclog.Logger) *Service {
 	dirPath = fmt.Sprintf("%v/%v", constants.RaftDir, configs.NodeId)
 	utils.MakeDirIfNotExist(dirPath)
 
-	ldb, stb, fss, tm := connectRaftLogsAndTransport(scheduler0Configs)
+	ldb, stb, fss, tm := connectRaftLogsAndTransport(scheduler0Configs, raftSecurity(configs, scheduler0Secrets, certs))
 	fsmStr := fsm.NewFSMStore(logger, fsmActions, scheduler0Configs, sqliteDb, ldb, stb, fss, tm, sharedRep)
 	//repository
 	credentialRepo := credential_repo.NewCredentialRepo(logger, fsmActions, fsmStr)
@@ -137,7 +182,10 @@ func NewService(ctx context.Context, logger hclog.Logger) *Service {
 	executionsRepo := job_execution_repo.NewExecutionsRepo(logger, fsmActions, fsmStr)
 	jobQueueRepo := job_queue_repo.NewJobQueuesRepo(logger, fsmActions, fsmStr)
 	asyncTaskRepo := async_task_repo.NewAsyncTasksRepo(serviceCtx, logger, fsmActions, fsmStr)
+	alertRepo := alert_repo.NewAlertRepo(logger, fsmActions, fsmStr)
+	auditRepo := audit_repo.NewAuditRepo(logger, fsmActions, fsmStr)
 
+	eventService := event.NewEventService(logger, scheduler0Configs)
 	asyncTaskService := async_task.NewAsyncTaskManager(serviceCtx, logger, fsmStr, asyncTaskRepo, scheduler0Configs)
 	httpJobExecutor := executors.NewHTTTPExecutor(logger, serviceCtx, scheduler0Configs, dispatcher)
 	jobExecutor := executor.NewJobExecutor(
@@ -150,9 +198,10 @@ func NewService(ctx context.Context, logger hclog.Logger) *Service {
 		jobQueueRepo,
 		httpJobExecutor,
 		dispatcher,
+		keyring,
 	)
 	jobQueueService := queue.NewJobQueue(serviceCtx, logger, scheduler0Configs, fsmActions, fsmStr, jobQueueRepo)
-	nodeHTTPClient := node.NewHTTPClient(logger, scheduler0Configs, scheduler0Secrets)
+	nodeHTTPClient := node.NewHTTPClient(logger, scheduler0Configs, scheduler0Secrets, certs)
 	jobProcessor := processor.NewJobProcessor(
 		ctx,
 		logger,
@@ -181,24 +230,49 @@ func NewService(ctx context.Context, logger hclog.Logger) *Service {
 		asyncTaskService,
 		dispatcher,
 		nodeHTTPClient,
+		eventService,
 		postProcessChannel,
 		exists,
 	)
 
 	service := Service{
-		JobService:         job.NewJobService(serviceCtx, logger, jobRepo, jobQueueService, projectRepo, dispatcher, asyncTaskService),
-		ProjectService:     project.NewProjectService(logger, projectRepo),
-		CredentialService:  credential.NewCredentialService(serviceCtx, logger, scheduler0Secrets, credentialRepo, dispatcher),
-		JobExecutorService: jobExecutor,
-		NodeService:        nodeService,
-		JobQueueService:    jobQueueService,
-		AsyncTaskService:   asyncTaskService,
+		JobService:          job.NewJobService(serviceCtx, logger, jobRepo, jobQueueService, projectRepo, dispatcher, asyncTaskService, keyring),
+		ProjectService:      project.NewProjectService(logger, projectRepo),
+		CredentialService:   credential.NewCredentialService(serviceCtx, logger, scheduler0Configs, scheduler0Secrets, fsmStr, credentialRepo, dispatcher, nodeService),
+		JobExecutorService:  jobExecutor,
+		NodeService:         nodeService,
+		JobQueueService:     jobQueueService,
+		AsyncTaskService:    asyncTaskService,
+		JobExecutionService: job_execution.NewJobExecutionService(logger, jobRepo, projectRepo, executionsRepo),
+		AlertService:        alert.NewAlertService(serviceCtx, logger, scheduler0Configs, fsmStr, alertRepo, projectRepo),
+		EventService:        eventService,
+		AuditService:        audit.NewAuditService(serviceCtx, logger, scheduler0Configs, fsmStr, auditRepo),
+		Certificates:        certs,
 	}
 
+	metrics.Register(
+		configs.NodeId,
+		append(
+			metrics.NewDispatcherCollectors(dispatcher.QueueDepth, dispatcher.BusyWorkers),
+			metrics.NewScheduledJobsCollector(func() int {
+				scheduledJobs := 0
+				jobExecutor.GetScheduledJobs().Range(func(key, value any) bool {
+					scheduledJobs += 1
+					return true
+				})
+				return scheduledJobs
+			}),
+			metrics.NewJobAllocationsCollector(jobQueueService.GetJobAllocations),
+		)...,
+	)
+
 	service.Dispatcher = dispatcher
 	service.Dispatcher.Run()
 	service.JobExecutorService.ListenForJobsToInvoke()
 	service.AsyncTaskService.ListenForNotifications()
+	service.AlertService.EvaluateRulesPeriodically()
+	service.CredentialService.FlushUsagePeriodically()
+	service.AuditService.CompactPeriodically()
 	fsmStr.InitRaft()
 
 	memCheckerCh := make(chan bool, 1)
@@ -212,6 +286,7 @@ func NewService(ctx context.Context, logger hclog.Logger) *Service {
 		for {
 			select {
 			case <-memCheckerCh:
+				metrics.MemoryLimitTrips.Inc()
 				cancelServiceContext()
 				memChecker.StopMemoryUsageChecker()
 				panic(
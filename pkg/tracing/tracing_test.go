@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+	})
+	return recorder
+}
+
+func Test_Middleware_ContinuesTraceOfRequest(t *testing.T) {
+	recorder := setupSpanRecorder(t)
+
+	var handlerTraceParent string
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/api/v1/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartSpan(r.Context(), "JobService.GetJob")
+		handlerTraceParent = TraceParent(r.Context())
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods(http.MethodGet)
+
+	incomingTraceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/1", nil)
+	req.Header.Set(TraceParentHeader, incomingTraceParent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Equal(t, 2, len(spans))
+
+	childSpan, serverSpan := spans[0], spans[1]
+	assert.Equal(t, "GET /api/v1/jobs/{id}", serverSpan.Name())
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, serverSpan.Status().Code)
+
+	assert.Equal(t, "JobService.GetJob", childSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), childSpan.Parent().SpanID())
+	assert.Contains(t, handlerTraceParent, serverSpan.SpanContext().SpanID().String())
+}
+
+func Test_TraceParent_RoundTrip(t *testing.T) {
+	recorder := setupSpanRecorder(t)
+
+	assert.Equal(t, "", TraceParent(context.Background()))
+	assert.Equal(t, context.Background(), ContextWithTraceParent(context.Background(), ""))
+
+	ctx, applySpan := StartSpan(context.Background(), "raft.Apply")
+	traceParent := TraceParent(ctx)
+	applySpan.End()
+
+	// The target node of a post process continues the trace of the raft log entry
+	_, postProcessSpan := StartSpan(ContextWithTraceParent(context.Background(), traceParent), "JobExecutor.QueueExecutions")
+	postProcessSpan.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.True(t, spans[1].Parent().IsRemote())
+}
+
+func Test_InjectHTTPHeaders(t *testing.T) {
+	setupSpanRecorder(t)
+
+	header := http.Header{}
+	InjectHTTPHeaders(context.Background(), header)
+	assert.Equal(t, "", header.Get(TraceParentHeader))
+
+	ctx, span := StartClientSpan(context.Background(), "HTTPExecutor.callback")
+	defer span.End()
+
+	InjectHTTPHeaders(ctx, header)
+	assert.Equal(t, TraceParent(ctx), header.Get(TraceParentHeader))
+	assert.Contains(t, header.Get(TraceParentHeader), span.SpanContext().TraceID().String())
+}
+
+func Test_Init_RejectsUnknownExporter(t *testing.T) {
+	shutdown, err := Init(context.Background(), 1, "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+
+	_, err = Init(context.Background(), 1, "zipkin", "")
+	assert.NotNil(t, err)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"scheduler0/pkg/models"
+	"sync/atomic"
 )
 
 type Dispatcher struct {
@@ -10,6 +11,7 @@ type Dispatcher struct {
 	inputQueue chan models.Work
 	workerPool chan chan models.Work
 	maxWorkers int64
+	waiting    int64 // work items taken off the input queue that are waiting for a free worker
 }
 
 func NewDispatcher(ctx context.Context, maxWorkers int64, maxQueue int64) *Dispatcher {
@@ -35,8 +37,10 @@ func (dispatcher *Dispatcher) dispatch() {
 	for {
 		select {
 		case input := <-dispatcher.inputQueue:
+			atomic.AddInt64(&dispatcher.waiting, 1)
 			go func(i models.Work) {
 				workerQueue := <-dispatcher.workerPool
+				atomic.AddInt64(&dispatcher.waiting, -1)
 				workerQueue <- i
 			}(input)
 		case <-dispatcher.ctx.Done():
@@ -75,3 +79,13 @@ func (dispatcher *Dispatcher) NoBlockQueue(effector func(successChannel chan any
 		ErrorChannel:   errorChannel,
 	}
 }
+
+// QueueDepth returns the number of work items waiting for a free worker
+func (dispatcher *Dispatcher) QueueDepth() int64 {
+	return int64(len(dispatcher.inputQueue)) + atomic.LoadInt64(&dispatcher.waiting)
+}
+
+// BusyWorkers returns the number of workers running a work item. Idle workers wait in the worker pool.
+func (dispatcher *Dispatcher) BusyWorkers() int64 {
+	return dispatcher.maxWorkers - int64(len(dispatcher.workerPool))
+}
@@ -4,75 +4,195 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
+// secretHashPrefix prefix of the secrets hashed by HashSecret, secrets stored without it were stored in plain text
+const secretHashPrefix = "sha256$"
+
+// envelopeSeparator separates the encrypted data key from the encrypted data in an envelope
+const envelopeSeparator = "."
+
 // Encrypt string
 func Encrypt(stringToEncrypt string, keyString string) (encryptedString string) {
+	ciphertext, err := encrypt([]byte(stringToEncrypt), keyString)
+	if err != nil {
+		panic(err.Error())
+	}
+	return fmt.Sprintf("%x", ciphertext)
+}
+
+// Decrypt string
+func Decrypt(encryptedString string, keyString string) (decryptedString string) {
+	enc, _ := hex.DecodeString(encryptedString)
+
+	plaintext, err := decrypt(enc, keyString)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return fmt.Sprintf("%s", plaintext)
+}
+
+// SealEnvelope encrypts the plaintext with a new random data key and returns the envelope holding the data key,
+// encrypted by the key encryption key, and the encrypted plaintext. Keys are hex encoded AES-256 keys.
+func SealEnvelope(plaintext string, keyEncryptionKey string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := encrypt(dataKey, keyEncryptionKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := encrypt([]byte(plaintext), hex.EncodeToString(dataKey))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x%s%x", wrappedKey, envelopeSeparator, ciphertext), nil
+}
+
+// OpenEnvelope returns the plaintext of an envelope returned by SealEnvelope with the key encryption key
+func OpenEnvelope(envelope string, keyEncryptionKey string) (string, error) {
+	parts := strings.Split(envelope, envelopeSeparator)
+	if len(parts) != 2 {
+		return "", errors.New("envelope is malformed")
+	}
+	wrappedKey, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("envelope is malformed: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("envelope is malformed: %w", err)
+	}
+
+	dataKey, err := decrypt(wrappedKey, keyEncryptionKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the data key: %w", err)
+	}
+	plaintext, err := decrypt(ciphertext, hex.EncodeToString(dataKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the data: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// encrypt seals the plaintext with AES-GCM, prefixed by the nonce it used
+func encrypt(plaintext []byte, keyString string) ([]byte, error) {
 	//Since the key is in string, we need to convert decode it to bytes
 	key, _ := hex.DecodeString(keyString)
-	plaintext := []byte(stringToEncrypt)
 
 	//Create a new Cipher Block from the key
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
 	//Create a new GCM - https://en.wikipedia.org/wiki/Galois/Counter_Mode
 	//https://golang.org/pkg/crypto/cipher/#NewGCM
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
 	//Create a nonce. Nonce should be from GCM
 	nonce := make([]byte, aesGCM.NonceSize())
 	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
 	//Encrypt the data using aesGCM.Seal
 	//Since we don't want to save the nonce somewhere else in this case, we add it as a prefix to the encrypted data. The first nonce argument in Seal is the prefix.
-	ciphertext := aesGCM.Seal(nonce, nonce, plaintext, nil)
-	return fmt.Sprintf("%x", ciphertext)
+	return aesGCM.Seal(nonce, nonce, plaintext, nil), nil
 }
 
-// Decrypt string
-func Decrypt(encryptedString string, keyString string) (decryptedString string) {
+// decrypt opens data sealed by encrypt
+func decrypt(enc []byte, keyString string) ([]byte, error) {
 	key, _ := hex.DecodeString(keyString)
-	enc, _ := hex.DecodeString(encryptedString)
 
 	//Create a new Cipher Block from the key
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
 	//Create a new GCM
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
 	//Get the nonce size
 	nonceSize := aesGCM.NonceSize()
+	if len(enc) < nonceSize {
+		return nil, errors.New("ciphertext is too short")
+	}
 
 	//Extract the nonce from the encrypted data
 	nonce, ciphertext := enc[:nonceSize], enc[nonceSize:]
 
 	//Decrypt the data
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	return fmt.Sprintf("%s", plaintext)
+	return aesGCM.Open(nil, nonce, ciphertext, nil)
 }
 
 // GenerateApiAndSecretKey create an api key or api secret
 func GenerateApiAndSecretKey(secretKey string) (string, string) {
 	return Encrypt(GetRandomSha256(), secretKey), Encrypt(GetRandomSha256(), secretKey)
 }
+
+// HashSecret returns a salted sha256 hash of the secret formatted as sha256$<salt>$<hash>.
+// Api secrets are random, a fast hash is enough to keep them from being recovered from the hash.
+func HashSecret(secret string) string {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic(err.Error())
+	}
+	return fmt.Sprintf("%s%x$%x", secretHashPrefix, salt, saltedHash(salt, secret))
+}
+
+// SecretMatches reports in constant time whether secret is the secret hashed by HashSecret into secretHash
+func SecretMatches(secretHash string, secret string) bool {
+	if !IsSecretHash(secretHash) {
+		return false
+	}
+	parts := strings.Split(strings.TrimPrefix(secretHash, secretHashPrefix), "$")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	hash, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash, saltedHash(salt, secret)) == 1
+}
+
+// IsSecretHash reports whether the value was returned by HashSecret
+func IsSecretHash(value string) bool {
+	return strings.HasPrefix(value, secretHashPrefix)
+}
+
+// SecretHashPattern the sql LIKE pattern matching the values returned by HashSecret
+func SecretHashPattern() string {
+	return secretHashPrefix + "%"
+}
+
+func saltedHash(salt []byte, secret string) []byte {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(secret))
+	return hash.Sum(nil)
+}
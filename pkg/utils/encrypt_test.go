@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -37,3 +38,55 @@ func TestGenerateApiAndSecretKey(t *testing.T) {
 		t.Fatalf("Secret key generation failed: decrypted secret key is the same as the encrypted secret key")
 	}
 }
+
+func TestHashSecret(t *testing.T) {
+	secret := "some-api-secret"
+
+	hash := HashSecret(secret)
+	if strings.Contains(hash, secret) || !IsSecretHash(hash) {
+		t.Fatalf("HashSecret returned %s", hash)
+	}
+	if HashSecret(secret) == hash {
+		t.Fatalf("hashes of the same secret should be salted differently")
+	}
+	if !SecretMatches(hash, secret) {
+		t.Fatalf("the secret should match its hash")
+	}
+	if SecretMatches(hash, "other-api-secret") {
+		t.Fatalf("another secret should not match the hash")
+	}
+	if SecretMatches(secret, secret) || IsSecretHash(secret) {
+		t.Fatalf("a secret stored in plain text is not a hash")
+	}
+	if SecretMatches("sha256$zz$00", secret) {
+		t.Fatalf("a malformed hash should not match")
+	}
+}
+
+func TestSealOpenEnvelope(t *testing.T) {
+	originalText := "This is a secret message"
+
+	envelope, err := SealEnvelope(originalText, testKeyString)
+	if err != nil {
+		t.Fatalf("SealEnvelope failed: %v", err)
+	}
+	if strings.Contains(envelope, originalText) || strings.Count(envelope, ".") != 1 {
+		t.Fatalf("SealEnvelope returned %s", envelope)
+	}
+
+	openedText, err := OpenEnvelope(envelope, testKeyString)
+	if err != nil {
+		t.Fatalf("OpenEnvelope failed: %v", err)
+	}
+	if openedText != originalText {
+		t.Fatalf("OpenEnvelope failed: expected '%s', got '%s'", originalText, openedText)
+	}
+
+	otherKey := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	if _, err := OpenEnvelope(envelope, otherKey); err == nil {
+		t.Fatalf("an envelope should not be opened by another key")
+	}
+	if _, err := OpenEnvelope("not-an-envelope", testKeyString); err == nil {
+		t.Fatalf("a malformed envelope should not be opened")
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"scheduler0/pkg/models"
+	"strings"
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// ParseLabelSelector parses a comma separated list of label requirements.
+// Supported terms are key=value, key==value, key!=value, key and !key; all terms must match.
+func ParseLabelSelector(selector string) ([]models.LabelRequirement, error) {
+	requirements := []models.LabelRequirement{}
+
+	if strings.TrimSpace(selector) == "" {
+		return requirements, nil
+	}
+
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("label selector %q contains an empty term", selector)
+		}
+
+		requirement := models.LabelRequirement{}
+
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			requirement.Key = strings.TrimSpace(parts[0])
+			requirement.Value = strings.TrimSpace(parts[1])
+			requirement.Operator = models.LabelSelectorNotEquals
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			requirement.Key = strings.TrimSpace(parts[0])
+			requirement.Value = strings.TrimSpace(parts[1])
+			requirement.Operator = models.LabelSelectorEquals
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			requirement.Key = strings.TrimSpace(parts[0])
+			requirement.Value = strings.TrimSpace(parts[1])
+			requirement.Operator = models.LabelSelectorEquals
+		case strings.HasPrefix(term, "!"):
+			requirement.Key = strings.TrimSpace(term[1:])
+			requirement.Operator = models.LabelSelectorNotExists
+		default:
+			requirement.Key = term
+			requirement.Operator = models.LabelSelectorExists
+		}
+
+		if err := ValidateLabelKey(requirement.Key); err != nil {
+			return nil, err
+		}
+
+		if strings.ContainsAny(requirement.Value, "=!") {
+			return nil, fmt.Errorf("label selector term %q has an invalid value", term)
+		}
+
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements, nil
+}
+
+// ValidateLabelKey checks that a label key is non-empty and only contains alphanumerics, '.', '_', '/' or '-'
+func ValidateLabelKey(key string) error {
+	if len(key) > 253 || !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("label key %q is not valid", key)
+	}
+	return nil
+}
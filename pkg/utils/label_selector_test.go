@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"scheduler0/pkg/models"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	t.Run("should parse all supported operators", func(t *testing.T) {
+		requirements, err := ParseLabelSelector("env=prod, tier!=db,team,!legacy,region==eu")
+		assert.Nil(t, err)
+		assert.Equal(t, []models.LabelRequirement{
+			{Key: "env", Operator: models.LabelSelectorEquals, Value: "prod"},
+			{Key: "tier", Operator: models.LabelSelectorNotEquals, Value: "db"},
+			{Key: "team", Operator: models.LabelSelectorExists},
+			{Key: "legacy", Operator: models.LabelSelectorNotExists},
+			{Key: "region", Operator: models.LabelSelectorEquals, Value: "eu"},
+		}, requirements)
+	})
+
+	t.Run("should return no requirements for an empty selector", func(t *testing.T) {
+		requirements, err := ParseLabelSelector("  ")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(requirements))
+	})
+
+	t.Run("should reject malformed selectors", func(t *testing.T) {
+		for _, selector := range []string{"env=prod,", "=prod", "!", "env=a=b", "bad key=1"} {
+			_, err := ParseLabelSelector(selector)
+			assert.NotNil(t, err, selector)
+		}
+	})
+}